@@ -322,6 +322,7 @@ type ImageList = {
   medium: string;
   large: string;
   xl: string;
+  blurhash?: string;
 };
 type Track = {
   id: number;
@@ -414,6 +415,17 @@ type Stats = {
   tracks_per_artist: number;
   albums_per_artist: number;
   longest_streak: number;
+  busiest_day: BusiestDay | null;
+};
+type BusiestDay = {
+  date: string;
+  listens: number;
+  tracks?: {
+    time: string;
+    track_id: number;
+    title: string;
+    duration: number;
+  }[];
 };
 type SearchResponse = {
   albums: Album[];
@@ -427,7 +439,8 @@ type User = {
 };
 type ApiKey = {
   id: number;
-  key: string;
+  key?: string; // only returned when the key is created
+  hint: string;
   label: string;
   created_at: Date;
 };
@@ -479,6 +492,7 @@ export type {
   Config,
   NowPlaying,
   Stats,
+  BusiestDay,
   RewindStats,
   ImageList,
 };
@@ -17,13 +17,13 @@ export default function ApiKeysModal() {
   const [err, setError] = useState<string>();
   const [displayData, setDisplayData] = useState<ApiKey[]>([]);
   const [copied, setCopied] = useState<CopiedState | null>(null);
-  const [expandedKey, setExpandedKey] = useState<string | null>(null);
-  const textRefs = useRef<Record<string, HTMLDivElement | null>>({});
+  const [expandedKey, setExpandedKey] = useState<number | null>(null);
+  const textRefs = useRef<Record<number, HTMLDivElement | null>>({});
 
-  const handleRevealAndSelect = (key: string) => {
-    setExpandedKey(key);
+  const handleRevealAndSelect = (id: number) => {
+    setExpandedKey(id);
     setTimeout(() => {
-      const el = textRefs.current[key];
+      const el = textRefs.current[id];
       if (el) {
         const range = document.createRange();
         range.selectNodeContents(el);
@@ -124,29 +124,36 @@ export default function ApiKeysModal() {
       <SubHeader>API Keys</SubHeader>
       <div className="flex flex-col gap-4 relative">
         {displayData.map((v) => (
-          <div className="flex gap-2">
-            <div
-              key={v.key}
-              ref={(el) => {
-                textRefs.current[v.key] = el;
-              }}
-              onClick={() => handleRevealAndSelect(v.key)}
-              className={`bg p-3 rounded-md flex-grow cursor-pointer select-text ${
-                expandedKey === v.key ? "" : "truncate"
-              }`}
-              style={{ whiteSpace: "nowrap" }}
-              title={v.key} // optional tooltip
-            >
-              {expandedKey === v.key
-                ? v.key
-                : `${v.key.slice(0, 8)}... ${v.label}`}
-            </div>
-            <button
-              onClick={(e) => handleCopy(e, v.key)}
-              className="large-button px-5 rounded-md"
-            >
-              <Copy size={16} />
-            </button>
+          <div className="flex gap-2" key={v.id}>
+            {v.key ? (
+              <div
+                ref={(el) => {
+                  textRefs.current[v.id] = el;
+                }}
+                onClick={() => handleRevealAndSelect(v.id)}
+                className={`bg p-3 rounded-md flex-grow cursor-pointer select-text ${
+                  expandedKey === v.id ? "" : "truncate"
+                }`}
+                style={{ whiteSpace: "nowrap" }}
+                title="Copy this key now, it will not be shown again"
+              >
+                {expandedKey === v.id
+                  ? v.key
+                  : `${v.key.slice(0, 8)}... ${v.label}`}
+              </div>
+            ) : (
+              <div className="bg p-3 rounded-md flex-grow truncate">
+                {`••••${v.hint} ${v.label}`}
+              </div>
+            )}
+            {v.key && (
+              <button
+                onClick={(e) => handleCopy(e, v.key!)}
+                className="large-button px-5 rounded-md"
+              >
+                <Copy size={16} />
+              </button>
+            )}
             <AsyncButton
               loading={loading}
               onClick={() => handleDeleteApiKey(v.id)}
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE listens ADD COLUMN device TEXT;
+
+-- +goose Down
+ALTER TABLE listens DROP COLUMN device;
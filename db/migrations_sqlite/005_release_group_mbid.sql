@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE releases ADD COLUMN release_group_mbid TEXT;
+CREATE INDEX IF NOT EXISTS idx_releases_release_group_mbid ON releases(release_group_mbid);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_releases_release_group_mbid;
+ALTER TABLE releases DROP COLUMN release_group_mbid;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE releases ADD COLUMN is_single INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE releases DROP COLUMN is_single;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- listens become a view of the listens that have not been deleted, so that deleted listens
+-- are left out of every query without each one having to filter them.
+ALTER TABLE listens RENAME TO all_listens;
+ALTER TABLE all_listens ADD COLUMN deleted_at INTEGER;
+CREATE INDEX IF NOT EXISTS idx_all_listens_deleted_at ON all_listens(deleted_at);
+
+CREATE VIEW IF NOT EXISTS listens AS
+SELECT track_id, listened_at, user_id, client, device
+FROM all_listens
+WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP VIEW IF EXISTS listens;
+DELETE FROM all_listens WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_all_listens_deleted_at;
+ALTER TABLE all_listens DROP COLUMN deleted_at;
+ALTER TABLE all_listens RENAME TO listens;
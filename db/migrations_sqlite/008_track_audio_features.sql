@@ -0,0 +1,13 @@
+-- +goose Up
+-- features are NULL when the track could not be found on Spotify, so that it is not looked up again
+CREATE TABLE IF NOT EXISTS track_audio_features (
+    track_id     INTEGER PRIMARY KEY REFERENCES tracks(id) ON DELETE CASCADE,
+    tempo        REAL,
+    energy       REAL,
+    danceability REAL,
+    valence      REAL,
+    fetched_at   INTEGER NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS track_audio_features;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- one row per successfully imported file, so that importing the same file again can be detected
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    filename     TEXT NOT NULL,
+    content_hash TEXT NOT NULL,
+    imported     INTEGER NOT NULL,
+    finished_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_content_hash ON import_jobs(content_hash);
+
+-- +goose Down
+DROP TABLE IF EXISTS import_jobs;
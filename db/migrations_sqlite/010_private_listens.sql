@@ -0,0 +1,24 @@
+-- +goose Up
+-- private listens are left out of listens, which every shared view reads from, so only queries
+-- scoped to the listen's owner see them through user_listens.
+ALTER TABLE all_listens ADD COLUMN private INTEGER NOT NULL DEFAULT 0;
+
+DROP VIEW IF EXISTS listens;
+CREATE VIEW IF NOT EXISTS listens AS
+SELECT track_id, listened_at, user_id, client, device, private
+FROM all_listens
+WHERE deleted_at IS NULL AND private = 0;
+
+CREATE VIEW IF NOT EXISTS user_listens AS
+SELECT track_id, listened_at, user_id, client, device, private
+FROM all_listens
+WHERE deleted_at IS NULL;
+
+-- +goose Down
+DROP VIEW IF EXISTS user_listens;
+DROP VIEW IF EXISTS listens;
+CREATE VIEW IF NOT EXISTS listens AS
+SELECT track_id, listened_at, user_id, client, device
+FROM all_listens
+WHERE deleted_at IS NULL;
+ALTER TABLE all_listens DROP COLUMN private;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- counts how often each spelling of an artist name is seen in listens, so the display name can
+-- follow the most common casing; names are matched case-insensitively through the index.
+ALTER TABLE artist_aliases ADD COLUMN seen_count INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_artist_aliases_alias_nocase ON artist_aliases(alias COLLATE NOCASE);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_artist_aliases_alias_nocase;
+ALTER TABLE artist_aliases DROP COLUMN seen_count;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- free-form tags, such as genres. a track belongs to a tag when it or one of its artists has it.
+CREATE TABLE IF NOT EXISTS artist_tags (
+    artist_id INTEGER NOT NULL REFERENCES artists(id) ON DELETE CASCADE,
+    tag       TEXT NOT NULL COLLATE NOCASE,
+    PRIMARY KEY (artist_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_artist_tags_tag ON artist_tags(tag);
+
+CREATE TABLE IF NOT EXISTS track_tags (
+    track_id INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    tag      TEXT NOT NULL COLLATE NOCASE,
+    PRIMARY KEY (track_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_track_tags_tag ON track_tags(tag);
+
+-- +goose Down
+DROP TABLE IF EXISTS track_tags;
+DROP TABLE IF EXISTS artist_tags;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- the labels a release was released under, from MusicBrainz. a release can have several labels,
+-- and several catalog numbers on the same label.
+CREATE TABLE IF NOT EXISTS release_labels (
+    release_id     INTEGER NOT NULL REFERENCES releases(id) ON DELETE CASCADE,
+    label          TEXT NOT NULL,
+    catalog_number TEXT NOT NULL DEFAULT '',
+    label_mbid     TEXT,
+    PRIMARY KEY (release_id, label, catalog_number)
+);
+CREATE INDEX IF NOT EXISTS idx_release_labels_label ON release_labels(label COLLATE NOCASE);
+
+-- +goose Down
+DROP TABLE IF EXISTS release_labels;
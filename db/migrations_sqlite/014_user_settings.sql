@@ -0,0 +1,12 @@
+-- +goose Up
+-- per-user preferences. a NULL column means the user uses the instance-wide setting.
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id                     INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    import_ignore_below_ms      INTEGER,
+    import_merge_gap_seconds    INTEGER,
+    import_dedup_window_seconds INTEGER,
+    import_reason_ends          TEXT -- comma separated
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_settings;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- api keys are stored as a sha256 hash along with the last few characters of the key, so they can be
+-- told apart without being readable. keys saved before this migration keep their plaintext key in key
+-- until they are hashed on startup.
+CREATE TABLE api_keys_new (
+    id         INTEGER PRIMARY KEY,
+    key        TEXT UNIQUE,
+    key_hash   TEXT UNIQUE,
+    key_hint   TEXT NOT NULL DEFAULT '',
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at INTEGER NOT NULL,
+    label      TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO api_keys_new (id, key, key_hint, user_id, created_at, label)
+SELECT id, key, substr(key, -4), user_id, created_at, label FROM api_keys;
+DROP TABLE api_keys;
+ALTER TABLE api_keys_new RENAME TO api_keys;
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+-- +goose Down
+-- hashed keys cannot be recovered, so only keys that were never hashed survive.
+CREATE TABLE api_keys_old (
+    id         INTEGER PRIMARY KEY,
+    key        TEXT NOT NULL UNIQUE,
+    user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at INTEGER NOT NULL,
+    label      TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO api_keys_old (id, key, user_id, created_at, label)
+SELECT id, key, user_id, created_at, label FROM api_keys WHERE key IS NOT NULL;
+DROP TABLE api_keys;
+ALTER TABLE api_keys_old RENAME TO api_keys;
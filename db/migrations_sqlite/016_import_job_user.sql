@@ -0,0 +1,11 @@
+-- +goose Up
+-- import jobs belong to the user the file was imported for, so that the same file can be imported
+-- for each user. jobs recorded before this were imported for the default user.
+ALTER TABLE import_jobs ADD COLUMN user_id INTEGER NOT NULL DEFAULT 1;
+DROP INDEX IF EXISTS idx_import_jobs_content_hash;
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user_content_hash ON import_jobs(user_id, content_hash);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_import_jobs_user_content_hash;
+CREATE INDEX IF NOT EXISTS idx_import_jobs_content_hash ON import_jobs(content_hash);
+ALTER TABLE import_jobs DROP COLUMN user_id;
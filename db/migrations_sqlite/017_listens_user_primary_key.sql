@@ -0,0 +1,74 @@
+-- +goose Up
+-- a listen is unique per user, track and second, so that two users listening to the same track in
+-- the same second both keep their listen. sqlite cannot change a primary key in place, so
+-- all_listens is rebuilt and the views reading from it are recreated.
+DROP VIEW IF EXISTS user_listens;
+DROP VIEW IF EXISTS listens;
+
+CREATE TABLE all_listens_new (
+    track_id    INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    listened_at INTEGER NOT NULL,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client      TEXT NOT NULL DEFAULT '',
+    device      TEXT,
+    deleted_at  INTEGER,
+    private     INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, track_id, listened_at)
+);
+INSERT INTO all_listens_new (track_id, listened_at, user_id, client, device, deleted_at, private)
+SELECT track_id, listened_at, user_id, client, device, deleted_at, private FROM all_listens;
+DROP TABLE all_listens;
+ALTER TABLE all_listens_new RENAME TO all_listens;
+
+CREATE INDEX IF NOT EXISTS idx_listens_listened_at       ON all_listens(listened_at);
+CREATE INDEX IF NOT EXISTS idx_listens_track_id          ON all_listens(track_id);
+CREATE INDEX IF NOT EXISTS idx_listens_track_id_listened_at ON all_listens(track_id, listened_at);
+CREATE INDEX IF NOT EXISTS idx_listens_user_id           ON all_listens(user_id);
+CREATE INDEX IF NOT EXISTS idx_all_listens_deleted_at    ON all_listens(deleted_at);
+
+CREATE VIEW IF NOT EXISTS listens AS
+SELECT track_id, listened_at, user_id, client, device, private
+FROM all_listens
+WHERE deleted_at IS NULL AND private = 0;
+
+CREATE VIEW IF NOT EXISTS user_listens AS
+SELECT track_id, listened_at, user_id, client, device, private
+FROM all_listens
+WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- listens of different users to the same track in the same second cannot all be kept under the
+-- old primary key, so only the first of them is.
+DROP VIEW IF EXISTS user_listens;
+DROP VIEW IF EXISTS listens;
+
+CREATE TABLE all_listens_old (
+    track_id    INTEGER NOT NULL REFERENCES tracks(id) ON DELETE CASCADE,
+    listened_at INTEGER NOT NULL,
+    user_id     INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client      TEXT NOT NULL DEFAULT '',
+    device      TEXT,
+    deleted_at  INTEGER,
+    private     INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (track_id, listened_at)
+);
+INSERT OR IGNORE INTO all_listens_old (track_id, listened_at, user_id, client, device, deleted_at, private)
+SELECT track_id, listened_at, user_id, client, device, deleted_at, private FROM all_listens ORDER BY rowid;
+DROP TABLE all_listens;
+ALTER TABLE all_listens_old RENAME TO all_listens;
+
+CREATE INDEX IF NOT EXISTS idx_listens_listened_at       ON all_listens(listened_at);
+CREATE INDEX IF NOT EXISTS idx_listens_track_id          ON all_listens(track_id);
+CREATE INDEX IF NOT EXISTS idx_listens_track_id_listened_at ON all_listens(track_id, listened_at);
+CREATE INDEX IF NOT EXISTS idx_listens_user_id           ON all_listens(user_id);
+CREATE INDEX IF NOT EXISTS idx_all_listens_deleted_at    ON all_listens(deleted_at);
+
+CREATE VIEW IF NOT EXISTS listens AS
+SELECT track_id, listened_at, user_id, client, device, private
+FROM all_listens
+WHERE deleted_at IS NULL AND private = 0;
+
+CREATE VIEW IF NOT EXISTS user_listens AS
+SELECT track_id, listened_at, user_id, client, device, private
+FROM all_listens
+WHERE deleted_at IS NULL;
@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,7 +16,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gabehf/koito/engine/handlers"
 	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/imagecache"
 	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
@@ -25,7 +28,6 @@ import (
 	"github.com/gabehf/koito/internal/logger"
 	mbz "github.com/gabehf/koito/internal/mbz"
 	"github.com/gabehf/koito/internal/models"
-	"github.com/gabehf/koito/internal/utils"
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
@@ -123,28 +125,27 @@ func Run(
 	userCount, _ := store.CountUsers(ctx)
 	if userCount < 1 {
 		l.Info().Msg("Engine: Creating default user")
-		user, err := store.SaveUser(ctx, db.SaveUserOpts{
+		_, apiKey, err := catalog.CreateUser(ctx, store, db.SaveUserOpts{
 			Username: cfg.DefaultUsername(),
 			Password: cfg.DefaultPassword(),
 			Role:     models.UserRoleAdmin,
-		})
-		if err != nil {
-			l.Fatal().Err(err).Msg("Engine: Failed to save default user in database")
-		}
-		apikey, err := utils.GenerateRandomString(48)
-		if err != nil {
-			l.Fatal().Err(err).Msg("Engine: Failed to generate default API key")
-		}
-		label := "Default"
-		_, err = store.SaveApiKey(ctx, db.SaveApiKeyOpts{
-			Key:    apikey,
-			UserID: user.ID,
-			Label:  label,
-		})
+		}, "Default")
 		if err != nil {
-			l.Fatal().Err(err).Msg("Engine: Failed to save default API key in database")
+			l.Fatal().Err(err).Msg("Engine: Failed to create default user")
 		}
 		l.Info().Msgf("Engine: Default user created. Login: %s : %s", cfg.DefaultUsername(), cfg.DefaultPassword())
+		// the key is written to a file readable only by the owner rather than logged, so it does not end up
+		// in log files or collected container logs
+		keyFile := path.Join(cfg.ConfigDir(), "default_api_key")
+		if err := os.WriteFile(keyFile, []byte(apiKey.Key+"\n"), 0600); err != nil {
+			l.Err(err).Msg("Engine: Failed to save default API key; a new key can be created in the settings")
+		} else {
+			l.Info().Msgf("Engine: Default API key saved to %s. Delete the file once the key is copied; new keys can be created in the settings", keyFile)
+		}
+	}
+
+	if err := catalog.HashStoredApiKeys(logger.NewContext(l), store); err != nil {
+		l.Fatal().Err(err).Msg("Engine: Failed to hash stored API keys")
 	}
 
 	if cfg.ForceTZ() != nil {
@@ -189,14 +190,22 @@ func Run(
 
 	l.Debug().Msg("Engine: Initializing image sources")
 	images.Initialize(images.ImageSourceOpts{
-		UserAgent:      cfg.UserAgent(),
-		EnableCAA:      !cfg.CoverArtArchiveDisabled(),
-		EnableDeezer:   !cfg.DeezerDisabled(),
-		EnableSubsonic: cfg.SubsonicEnabled(),
-		EnableSpotify:  !cfg.SpotifyDisabled(),
-		EnableLastFM:   cfg.LastFMApiKey() != "",
+		UserAgent:        cfg.UserAgent(),
+		EnableCAA:        !cfg.CoverArtArchiveDisabled(),
+		EnableDeezer:     !cfg.DeezerDisabled(),
+		EnableSubsonic:   cfg.SubsonicEnabled(),
+		EnableSpotify:    !cfg.SpotifyDisabled(),
+		EnableLastFM:     cfg.LastFMApiKey() != "",
+		ProviderOrder:    cfg.ImageProviderOrder(),
+		ProviderTimeouts: cfg.ImageProviderTimeouts(),
+		BreakerThreshold: cfg.ImageProviderFailureThreshold(),
+		BreakerCooldown:  cfg.ImageProviderCooldown(),
+		PositiveCacheTTL: cfg.ImagePositiveCacheTTL(),
+		NegativeCacheTTL: cfg.ImageNegativeCacheTTL(),
+		LocalImagesDir:   cfg.LocalImagesDir(),
 	})
 	l.Info().Msg("Engine: Image sources initialized")
+	imagecache.Initialize(cfg.ImageDownloadWorkers(), cfg.ImageDownloadRateLimit())
 
 	if len(cfg.AllowedOrigins()) == 0 || cfg.AllowedOrigins()[0] == "" {
 		l.Info().Msgf("Engine: Using default CORS policy")
@@ -220,7 +229,11 @@ func Run(
 	mux.Use(middleware.Logger(l))
 	mux.Use(chimiddleware.Recoverer)
 	mux.Use(chimiddleware.RealIP)
-	bindRoutes(mux, &ready, store, mbzC)
+	var backfill *catalog.ImageBackfill
+	if cfg.ImageBackfillInterval() > 0 {
+		backfill = catalog.NewImageBackfill(store, cfg.ImageBackfillInterval())
+	}
+	bindRoutes(mux, &ready, store, mbzC, backfill)
 
 	httpServer := &http.Server{
 		Addr:    cfg.ListenAddr(),
@@ -241,6 +254,8 @@ func Run(
 	if !cfg.SkipImport() {
 		go func() {
 			RunImporter(l, store, mbzC)
+			RunLastFMImport(l, store, mbzC)
+			RunListenBrainzImport(l, store, mbzC)
 		}()
 	}
 
@@ -250,10 +265,51 @@ func Run(
 	go catalog.MigrateImageCache(logger.NewContext(l), store)
 	l.Info().Msg("Engine: Running duration backfill task")
 	go catalog.BackfillTrackDurationsFromMusicBrainz(ctx, store, mbzC)
-	l.Info().Msg("Engine: Attempting to fetch missing artist images")
-	go catalog.FetchMissingArtistImages(ctx, store)
-	l.Info().Msg("Engine: Attempting to fetch missing album images")
-	go catalog.FetchMissingAlbumImages(ctx, store)
+	if backfill != nil {
+		l.Info().Msg("Engine: Scheduling missing image backfill")
+		backfill.Start(ctx)
+	} else {
+		l.Info().Msg("Engine: Attempting to fetch missing artist images")
+		go catalog.FetchMissingArtistImages(ctx, store)
+		l.Info().Msg("Engine: Attempting to fetch missing album images")
+		go catalog.FetchMissingAlbumImages(ctx, store)
+	}
+
+	if cfg.FetchAudioFeatures() {
+		if cfg.SpotifyDisabled() {
+			l.Warn().Msg("Engine: Audio features can only be fetched when Spotify is enabled")
+		} else {
+			l.Info().Msg("Engine: Scheduling audio features backfill")
+			go func() {
+				ticker := time.NewTicker(time.Hour)
+				defer ticker.Stop()
+				for {
+					catalog.FetchMissingAudioFeatures(logger.NewContext(l), store)
+					<-ticker.C
+				}
+			}()
+		}
+	}
+
+	if cfg.FoldDiacriticsForMatching() && cfg.MergeDuplicateAlbums() {
+		l.Info().Msg("Engine: Merging albums with titles that only differ in case, accents or punctuation")
+		go catalog.MergeDuplicateAlbums(logger.NewContext(l), store)
+	}
+
+	if cfg.ArtistCasingPolicy() != cfg.ArtistCasingPolicyNone {
+		l.Info().Msg("Engine: Updating artist name casing")
+		go catalog.NormalizeArtistNameCasing(logger.NewContext(l), store)
+	}
+
+	l.Info().Msg("Engine: Scheduling purge of deleted listens")
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			catalog.PurgeDeletedListens(logger.NewContext(l), store)
+			<-ticker.C
+		}
+	}()
 
 	l.Info().Msg("Engine: Initialization finished")
 	quit := make(chan os.Signal, 1)
@@ -265,6 +321,10 @@ func Run(
 	defer cancel()
 	l.Info().Msg("Engine: Waiting for all processes to finish")
 	mbzC.Shutdown()
+	if backfill != nil {
+		backfill.Shutdown()
+	}
+	imagecache.Shutdown()
 	if err := httpServer.Shutdown(ctx); err != nil {
 		l.Fatal().Err(err).Msg("Engine: Error during server shutdown")
 		return err
@@ -273,6 +333,35 @@ func Run(
 	return nil
 }
 
+// RunLastFMImport imports the scrobbles of the configured Last.fm user through the Last.fm API.
+func RunLastFMImport(l *zerolog.Logger, store db.DB, mbzc mbz.MusicBrainzCaller) {
+	user := cfg.LastFMImportUser()
+	if user == "" {
+		return
+	}
+	if cfg.LastFMApiKey() == "" {
+		l.Warn().Msgf("Importer: %s is set, but %s is required to import from the Last.fm API", cfg.LASTFM_IMPORT_USER_ENV, cfg.LASTFM_API_KEY_ENV)
+		return
+	}
+	l.Info().Msgf("Importer: Importing scrobbles of Last.fm user %s", user)
+	if err := importer.ImportFromLastfmAPI(logger.NewContext(l), store, mbzc, user, cfg.LastFMApiKey(), catalog.DefaultUserID); err != nil {
+		l.Err(err).Msgf("Importer: Failed to import scrobbles of Last.fm user %s", user)
+	}
+}
+
+// RunListenBrainzImport imports the listens of the configured ListenBrainz user through the ListenBrainz API.
+func RunListenBrainzImport(l *zerolog.Logger, store db.DB, mbzc mbz.MusicBrainzCaller) {
+	user := cfg.ListenBrainzImportUser()
+	if user == "" {
+		return
+	}
+	l.Info().Msgf("Importer: Importing listens of ListenBrainz user %s", user)
+	if err := importer.ImportFromListenBrainzAPI(logger.NewContext(l), store, mbzc, user, cfg.ListenBrainzImportToken(), catalog.DefaultUserID); err != nil {
+		l.Err(err).Msgf("Importer: Failed to import listens of ListenBrainz user %s", user)
+	}
+}
+
+// RunImporter imports the files in the import directory for the default user.
 func RunImporter(l *zerolog.Logger, store db.DB, mbzc mbz.MusicBrainzCaller) {
 	l.Debug().Msg("Importer: Checking for import files...")
 	files, err := os.ReadDir(path.Join(cfg.ConfigDir(), "import"))
@@ -290,41 +379,86 @@ func RunImporter(l *zerolog.Logger, store db.DB, mbzc mbz.MusicBrainzCaller) {
 		}
 	}()
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() && !importer.IsSpotifyDirectory(file.Name()) {
 			continue
 		}
-		if strings.Contains(file.Name(), "Streaming_History_Audio") {
-			l.Info().Msgf("Importer: Import file %s detecting as being Spotify export", file.Name())
-			err := importer.ImportSpotifyFile(logger.NewContext(l), store, mbzc, file.Name())
-			if err != nil {
-				l.Err(err).Msgf("Importer: Failed to import file: %s", file.Name())
-			}
-		} else if strings.Contains(file.Name(), "maloja") {
-			l.Info().Msgf("Importer: Import file %s detecting as being Maloja export", file.Name())
-			err := importer.ImportMalojaFile(logger.NewContext(l), store, mbzc, file.Name())
+		if !cfg.ForceReimport() {
+			prev, err := importer.PreviousImport(context.Background(), store, file.Name(), catalog.DefaultUserID)
 			if err != nil {
-				l.Err(err).Msgf("Importer: Failed to import file: %s", file.Name())
+				l.Err(err).Msgf("Importer: Failed to check whether file %s was already imported", file.Name())
+			} else if prev != nil {
+				l.Warn().Msgf("Importer: File %s has the same contents as %s, which was imported on %s; skipping it. Set %s=true to import it again", file.Name(), prev.Filename, prev.FinishedAt.Format(time.DateOnly), cfg.FORCE_REIMPORT_ENV)
+				continue
 			}
-		} else if strings.Contains(file.Name(), "recenttracks") {
-			l.Info().Msgf("Importer: Import file %s detecting as being ghan.nl LastFM export", file.Name())
-			err := importer.ImportLastFMFile(logger.NewContext(l), store, mbzc, file.Name())
-			if err != nil {
-				l.Err(err).Msgf("Importer: Failed to import file: %s", file.Name())
-			}
-		} else if strings.Contains(file.Name(), "listenbrainz") {
-			l.Info().Msgf("Importer: Import file %s detecting as being ListenBrainz export", file.Name())
-			err := importer.ImportListenBrainzExport(logger.NewContext(l), store, mbzc, file.Name())
+		}
+		err := ImportFile(l, store, mbzc, file.Name(), file.IsDir(), catalog.DefaultUserID)
+		if errors.Is(err, handlers.ErrUnrecognizedImport) {
+			l.Warn().Msgf("Importer: File %s not recognized as a valid import file; make sure it is valid and named correctly", file.Name())
+		} else if err != nil {
+			l.Err(err).Msgf("Importer: Failed to import file: %s", file.Name())
+		}
+	}
+}
+
+// ImportFile imports a file or Spotify export directory in the import directory for the user, detecting
+// the kind of export from its name. Files that are not recognized return handlers.ErrUnrecognizedImport.
+func ImportFile(l *zerolog.Logger, store db.DB, mbzc mbz.MusicBrainzCaller, name string, isDir bool, userID int32) error {
+	ctx := logger.NewContext(l)
+	switch {
+	case isDir:
+		l.Info().Msgf("Importer: Import directory %s detecting as being Spotify export", name)
+		return importer.ImportSpotifyDirectory(ctx, store, mbzc, name, importer.ClientFromFilename(name), userID)
+	case strings.HasSuffix(name, ".zip") && strings.Contains(strings.ToLower(name), "spotify"):
+		l.Info().Msgf("Importer: Import file %s detecting as being zipped Spotify export", name)
+		return importer.ImportSpotifyArchive(ctx, store, mbzc, name, importer.ClientFromFilename(name), userID)
+	case strings.Contains(name, "Streaming_History_Audio"):
+		l.Info().Msgf("Importer: Import file %s detecting as being Spotify export", name)
+		return importer.ImportSpotifyFile(ctx, store, mbzc, name, importer.ClientFromFilename(name), userID)
+	case strings.Contains(name, "maloja"):
+		l.Info().Msgf("Importer: Import file %s detecting as being Maloja export", name)
+		return importer.ImportMalojaFile(ctx, store, mbzc, name, userID)
+	case strings.HasSuffix(name, ".csv") && (strings.Contains(name, "recenttracks") || strings.Contains(strings.ToLower(name), "lastfm")):
+		l.Info().Msgf("Importer: Import file %s detecting as being LastFM CSV export", name)
+		return importer.ImportLastFMCSV(ctx, store, mbzc, name, userID)
+	case strings.Contains(name, "recenttracks"):
+		l.Info().Msgf("Importer: Import file %s detecting as being ghan.nl LastFM export", name)
+		return importer.ImportLastFMFile(ctx, store, mbzc, name, userID)
+	case strings.Contains(name, "listenbrainz"):
+		l.Info().Msgf("Importer: Import file %s detecting as being ListenBrainz export", name)
+		if strings.HasSuffix(name, ".zip") {
+			return importer.ImportListenBrainzExport(ctx, store, mbzc, name, userID)
+		}
+		return importer.ImportListenBrainzJSON(ctx, store, mbzc, name, userID)
+	case strings.HasSuffix(name, "scrobbler.log"):
+		l.Info().Msgf("Importer: Import file %s detecting as being .scrobbler.log file", name)
+		return importer.ImportScrobblerLog(ctx, store, mbzc, name, userID)
+	case strings.Contains(name, "watch-history"):
+		l.Info().Msgf("Importer: Import file %s detecting as being YouTube Music Takeout export", name)
+		return importer.ImportYouTubeMusicTakeout(ctx, store, mbzc, name, userID)
+	case strings.Contains(name, "koito"):
+		l.Info().Msgf("Importer: Import file %s detecting as being Koito export", name)
+		return importer.ImportKoitoFile(ctx, store, name, userID)
+	default:
+		return handlers.ErrUnrecognizedImport
+	}
+}
+
+// uploadedFileImporter imports files uploaded through the import endpoint for the uploading user.
+func uploadedFileImporter(store db.DB, mbzc mbz.MusicBrainzCaller) handlers.ImportFunc {
+	return func(ctx context.Context, filename string, userID int32) error {
+		if !cfg.ForceReimport() {
+			prev, err := importer.PreviousImport(ctx, store, filename, userID)
 			if err != nil {
-				l.Err(err).Msgf("Importer: Failed to import file: %s", file.Name())
+				return fmt.Errorf("uploadedFileImporter: %w", err)
 			}
-		} else if strings.Contains(file.Name(), "koito") {
-			l.Info().Msgf("Importer: Import file %s detecting as being Koito export", file.Name())
-			err := importer.ImportKoitoFile(logger.NewContext(l), store, file.Name())
-			if err != nil {
-				l.Err(err).Msgf("Importer: Failed to import file: %s", file.Name())
+			if prev != nil {
+				return handlers.ErrAlreadyImported
 			}
-		} else {
-			l.Warn().Msgf("Importer: File %s not recognized as a valid import file; make sure it is valid and named correctly", file.Name())
 		}
+		err := ImportFile(logger.FromContext(ctx), store, mbzc, filename, false, userID)
+		if errors.Is(err, importer.ErrAlreadyImported) {
+			return handlers.ErrAlreadyImported
+		}
+		return err
 	}
 }
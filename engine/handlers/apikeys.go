@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -24,26 +26,21 @@ func GenerateApiKeyHandler(store db.UserStore) http.HandlerFunc {
 		body, err := utils.DecodeBody[struct {
 			Label string `json:"label"`
 		}](r)
-		if err != nil || body.Label == "" {
-			l.Debug().Msg("GenerateApiKeyHandler: Invalid or missing label in request body")
+		if err != nil {
+			l.Debug().Msg("GenerateApiKeyHandler: Invalid request body")
 			utils.WriteError(w, "label is required", http.StatusBadRequest)
 			return
 		}
 
-		apiKey, err := utils.GenerateRandomString(48)
-		if err != nil {
-			l.Error().Err(err).Msg("GenerateApiKeyHandler: Failed to generate API key")
-			utils.WriteError(w, "failed to generate api key", http.StatusInternalServerError)
+		// the key itself is only included in this response, since it is saved hashed
+		key, err := catalog.CreateApiKey(ctx, store, user.ID, body.Label)
+		if errors.Is(err, catalog.ErrBlankApiKeyLabel) {
+			l.Debug().Msg("GenerateApiKeyHandler: Missing label in request body")
+			utils.WriteError(w, "label is required", http.StatusBadRequest)
 			return
 		}
-
-		key, err := store.SaveApiKey(ctx, db.SaveApiKeyOpts{
-			UserID: user.ID,
-			Key:    apiKey,
-			Label:  body.Label,
-		})
 		if err != nil {
-			l.Error().Err(err).Msg("GenerateApiKeyHandler: Failed to save API key")
+			l.Error().Err(err).Msg("GenerateApiKeyHandler: Failed to create API key")
 			utils.WriteError(w, "failed to save api key", http.StatusInternalServerError)
 			return
 		}
@@ -72,7 +69,13 @@ func DeleteApiKeyHandler(store db.UserStore) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DeleteApiKey(ctx, apiKeyID); err != nil {
+		err = catalog.RevokeApiKey(ctx, store, user.ID, apiKeyID)
+		if errors.Is(err, db.ErrNotFound) {
+			l.Debug().Msgf("DeleteApiKeyHandler: API key ID %d not found", apiKeyID)
+			utils.WriteError(w, "api key not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
 			l.Error().Err(err).Msg("DeleteApiKeyHandler: Failed to delete API key")
 			utils.WriteError(w, "failed to delete api key", http.StatusInternalServerError)
 			return
@@ -95,7 +98,7 @@ func GetApiKeysHandler(store db.UserStore) http.HandlerFunc {
 			return
 		}
 
-		apiKeys, err := store.GetApiKeysByUserID(ctx, user.ID)
+		apiKeys, err := catalog.ListApiKeys(ctx, store, user.ID)
 		if err != nil {
 			l.Error().Err(err).Msg("GetApiKeysHandler: Failed to retrieve API keys")
 			utils.WriteError(w, "failed to retrieve api keys", http.StatusInternalServerError)
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gabehf/koito/engine/middleware"
@@ -138,8 +139,9 @@ func UpdateUserHandler(store db.UserStore) http.HandlerFunc {
 		}
 
 		body, err := utils.DecodeBody[struct {
-			Username *string `json:"username"`
-			Password *string `json:"password"`
+			Username   *string `json:"username"`
+			Password   *string `json:"password"`
+			Comparable *bool   `json:"comparable"`
 		}](r)
 		if err != nil {
 			l.Debug().Msg("UpdateUserHandler: Invalid request body")
@@ -147,7 +149,7 @@ func UpdateUserHandler(store db.UserStore) http.HandlerFunc {
 			return
 		}
 
-		if body.Username == nil && body.Password == nil {
+		if body.Username == nil && body.Password == nil && body.Comparable == nil {
 			l.Debug().Msg("UpdateUserHandler: No update parameters provided")
 			utils.WriteError(w, "no changes specified", http.StatusBadRequest)
 			return
@@ -160,6 +162,7 @@ func UpdateUserHandler(store db.UserStore) http.HandlerFunc {
 		if body.Password != nil {
 			opts.Password = *body.Password
 		}
+		opts.Comparable = body.Comparable
 
 		if err := store.UpdateUser(ctx, opts); err != nil {
 			l.Error().Err(err).Msg("UpdateUserHandler: Update failed")
@@ -171,3 +174,67 @@ func UpdateUserHandler(store db.UserStore) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// GetImportSettingsHandler returns the import settings the user has saved. Settings that are null
+// use the instance-wide configuration.
+func GetImportSettingsHandler(store db.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		settings, err := store.GetImportSettings(ctx, user.ID)
+		if err != nil {
+			l.Error().Err(err).Msg("GetImportSettingsHandler: Failed to get import settings")
+			utils.WriteError(w, "failed to get import settings", http.StatusInternalServerError)
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, settings)
+	}
+}
+
+// UpdateImportSettingsHandler replaces the user's import settings. Omitted or null settings use the
+// instance-wide configuration.
+func UpdateImportSettingsHandler(store db.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := utils.DecodeBody[db.ImportSettings](r)
+		if err != nil {
+			utils.WriteError(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		for _, n := range []*int{body.IgnoreBelowMs, body.MergeGapSeconds, body.DedupWindowSeconds} {
+			if n != nil && *n < 0 {
+				utils.WriteError(w, "settings must not be negative", http.StatusBadRequest)
+				return
+			}
+		}
+		reasonEnds := body.ReasonEnds[:0]
+		for _, reason := range body.ReasonEnds {
+			if reason = strings.TrimSpace(reason); reason != "" {
+				reasonEnds = append(reasonEnds, reason)
+			}
+		}
+		body.ReasonEnds = reasonEnds
+
+		if err := store.SaveImportSettings(ctx, user.ID, body); err != nil {
+			l.Error().Err(err).Msg("UpdateImportSettingsHandler: Failed to save import settings")
+			utils.WriteError(w, "failed to save import settings", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -47,7 +50,23 @@ func DeleteListenHandler(store db.ListenStore) http.HandlerFunc {
 
 		l.Debug().Msgf("DeleteListenHandler: Deleting listen record for track ID %d at timestamp %d", trackID, unix)
 
-		err = store.DeleteListen(ctx, int32(trackID), time.Unix(unix, 0))
+		u := middleware.GetUserFromContext(ctx)
+		if u == nil {
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		err = catalog.DeleteListen(ctx, store, db.DeleteListenOpts{
+			UserID:     u.ID,
+			TrackID:    int32(trackID),
+			ListenedAt: time.Unix(unix, 0),
+			Purge:      r.URL.Query().Get("purge") == "true",
+		})
+		if errors.Is(err, db.ErrNotFound) {
+			l.Debug().Msgf("DeleteListenHandler: User has no listen to track ID %d at timestamp %d", trackID, unix)
+			utils.WriteError(w, "listen not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			l.Err(err).Msg("DeleteListenHandler: Failed to delete listen record")
 			utils.WriteError(w, "failed to delete listen", http.StatusInternalServerError)
@@ -59,6 +78,162 @@ func DeleteListenHandler(store db.ListenStore) http.HandlerFunc {
 	}
 }
 
+type DeleteListensInRangeResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// DeleteListensInRangeHandler deletes the user's listens between the from and to timestamps, or up to now
+// when to is not given, optionally only those submitted by the given client. Deleted listens can be restored.
+func DeleteListensInRangeHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("DeleteListensInRangeHandler: Received request to delete listens in a time range")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("DeleteListensInRangeHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		from, err := strconv.ParseInt(q.Get("from"), 10, 64)
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("DeleteListensInRangeHandler: Invalid from timestamp")
+			utils.WriteError(w, "invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+		to := time.Now()
+		if toStr := q.Get("to"); toStr != "" {
+			unix, err := strconv.ParseInt(toStr, 10, 64)
+			if err != nil || unix < from {
+				l.Debug().Msg("DeleteListensInRangeHandler: Invalid to timestamp")
+				utils.WriteError(w, "invalid to timestamp", http.StatusBadRequest)
+				return
+			}
+			to = time.Unix(unix, 0)
+		}
+		var client *string
+		if q.Has("client") {
+			c := q.Get("client")
+			client = &c
+		}
+
+		deleted, err := catalog.DeleteListensInRange(ctx, store, user.ID, time.Unix(from, 0), to, client)
+		if err != nil {
+			l.Err(err).Msg("DeleteListensInRangeHandler: Failed to delete listens")
+			utils.WriteError(w, "failed to delete listens", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msgf("DeleteListensInRangeHandler: Deleted %d listens", deleted)
+		utils.WriteJSON(w, http.StatusOK, DeleteListensInRangeResponse{Deleted: deleted})
+	}
+}
+
+type RestoreListensResponse struct {
+	Restored int64 `json:"restored"`
+}
+
+// RestoreListensHandler restores the user's deleted listens. A single listen is restored when track_id
+// and unix are given; otherwise every listen deleted since the optional since timestamp is restored.
+func RestoreListensHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("RestoreListensHandler: Received request to restore deleted listens")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("RestoreListensHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		opts := db.RestoreListensOpts{UserID: user.ID}
+		q := r.URL.Query()
+
+		if trackIDStr := q.Get("track_id"); trackIDStr != "" {
+			trackID, err := strconv.Atoi(trackIDStr)
+			if err != nil {
+				l.Debug().AnErr("error", err).Msg("RestoreListensHandler: Invalid track ID")
+				utils.WriteError(w, "invalid id", http.StatusBadRequest)
+				return
+			}
+			unix, err := strconv.ParseInt(q.Get("unix"), 10, 64)
+			if err != nil {
+				l.Debug().AnErr("error", err).Msg("RestoreListensHandler: Invalid timestamp")
+				utils.WriteError(w, "invalid unix timestamp", http.StatusBadRequest)
+				return
+			}
+			opts.TrackID = int32(trackID)
+			opts.ListenedAt = time.Unix(unix, 0)
+		}
+
+		if sinceStr := q.Get("since"); sinceStr != "" {
+			since, err := strconv.ParseInt(sinceStr, 10, 64)
+			if err != nil {
+				l.Debug().AnErr("error", err).Msg("RestoreListensHandler: Invalid since timestamp")
+				utils.WriteError(w, "invalid since timestamp", http.StatusBadRequest)
+				return
+			}
+			opts.DeletedSince = time.Unix(since, 0)
+		}
+
+		restored, err := catalog.RestoreListens(ctx, store, opts)
+		if err != nil {
+			l.Err(err).Msg("RestoreListensHandler: Failed to restore listens")
+			utils.WriteError(w, "failed to restore listens", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msgf("RestoreListensHandler: Restored %d listens", restored)
+		utils.WriteJSON(w, http.StatusOK, RestoreListensResponse{Restored: restored})
+	}
+}
+
+type DeleteDuplicateListensResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// DeleteDuplicateListensHandler deletes every listen but the first of each cluster of duplicates reported
+// by GetDuplicateListensHandler for the same window. Deleted listens can be restored.
+func DeleteDuplicateListensHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("DeleteDuplicateListensHandler: Received request to delete duplicate listens")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("DeleteDuplicateListensHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		window, ok := duplicateListenWindow(r)
+		if !ok {
+			l.Debug().Msg("DeleteDuplicateListensHandler: Invalid window")
+			utils.WriteError(w, "window must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+
+		deleted, err := catalog.DeleteDuplicateListens(ctx, store, user.ID, window)
+		if err != nil {
+			l.Err(err).Msg("DeleteDuplicateListensHandler: Failed to delete duplicate listens")
+			utils.WriteError(w, "failed to delete duplicate listens", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msgf("DeleteDuplicateListensHandler: Deleted %d duplicate listens", deleted)
+		utils.WriteJSON(w, http.StatusOK, DeleteDuplicateListensResponse{Deleted: deleted})
+	}
+}
+
 func PurgeAllDataHandler(store db.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -58,7 +60,17 @@ func DeleteArtistAliasHandler(store db.ArtistStore) http.HandlerFunc {
 			return
 		}
 
-		if err = store.DeleteArtistAlias(ctx, artistID, body.Alias); err != nil {
+		err = catalog.RemoveArtistAlias(ctx, store, artistID, body.Alias)
+		if errors.Is(err, catalog.ErrPrimaryAlias) {
+			l.Debug().Msg("DeleteArtistAliasHandler: Attempted to delete the primary alias")
+			utils.WriteError(w, "the primary alias cannot be deleted", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			utils.WriteError(w, "alias not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
 			l.Error().Err(err).Msg("DeleteArtistAliasHandler: Failed to delete artist alias")
 			utils.WriteError(w, "failed to delete alias", http.StatusInternalServerError)
 			return
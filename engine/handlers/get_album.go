@@ -57,7 +57,7 @@ func GetAlbumAliasesHandler(store db.AlbumStore) http.HandlerFunc {
 			return
 		}
 
-		utils.WriteJSON(w, http.StatusOK, aliases)
+		utils.WriteJSON(w, http.StatusOK, filterAliasesBySource(r, aliases))
 	}
 }
 
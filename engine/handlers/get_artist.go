@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -57,7 +59,7 @@ func GetArtistAliasesHandler(store db.ArtistStore) http.HandlerFunc {
 			return
 		}
 
-		utils.WriteJSON(w, http.StatusOK, aliases)
+		utils.WriteJSON(w, http.StatusOK, filterAliasesBySource(r, aliases))
 	}
 }
 
@@ -94,3 +96,156 @@ func GetArtistInterestHandler(store db.ListenStore) http.HandlerFunc {
 		utils.WriteJSON(w, http.StatusOK, interest)
 	}
 }
+
+type artistTopItemsHandlerStore interface {
+	db.TrackStore
+	db.AlbumStore
+}
+
+type ArtistTopItemsResponse struct {
+	Tracks []db.ArtistTopItem `json:"tracks"`
+	Albums []db.ArtistTopItem `json:"albums"`
+}
+
+// GetArtistTopItemsHandler retrieves the most listened to tracks and albums by an artist. When the request
+// is authenticated, only the listens of the requesting user are counted.
+func GetArtistTopItemsHandler(store artistTopItemsHandlerStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		artistID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("GetArtistTopItemsHandler: Invalid artist id")
+			utils.WriteError(w, "invalid artist id", http.StatusBadRequest)
+			return
+		}
+
+		limit := 10
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maximumLimit {
+				l.Debug().Msgf("GetArtistTopItemsHandler: Invalid limit '%s'", v)
+				utils.WriteError(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+		tf := TimeframeFromRequest(r)
+
+		tracks, err := catalog.GetArtistTopTracks(ctx, store, userID, artistID, limit, tf)
+		if err != nil {
+			l.Err(err).Msg("GetArtistTopItemsHandler: Failed to get top tracks")
+			utils.WriteError(w, "failed to get top tracks", http.StatusInternalServerError)
+			return
+		}
+		albums, err := catalog.GetArtistTopReleases(ctx, store, userID, artistID, limit, tf)
+		if err != nil {
+			l.Err(err).Msg("GetArtistTopItemsHandler: Failed to get top albums")
+			utils.WriteError(w, "failed to get top albums", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, ArtistTopItemsResponse{Tracks: tracks, Albums: albums})
+	}
+}
+
+// GetNeglectedArtistsHandler retrieves artists with many listens that have not been listened to recently.
+// The days query parameter sets how long an artist must have gone unheard (default 90), and min_listens
+// sets how many listens they need overall (default 10). When the request is authenticated, only the
+// listens of the requesting user are counted.
+func GetNeglectedArtistsHandler(store db.ArtistStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetNeglectedArtistsHandler: Received request to retrieve neglected artists")
+
+		days := 90
+		if v := r.URL.Query().Get("days"); v != "" {
+			var err error
+			days, err = strconv.Atoi(v)
+			if err != nil || days < 1 {
+				l.Debug().Msgf("GetNeglectedArtistsHandler: Invalid days '%s'", v)
+				utils.WriteError(w, "parameter 'days' must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+		minListens := 10
+		if v := r.URL.Query().Get("min_listens"); v != "" {
+			var err error
+			minListens, err = strconv.Atoi(v)
+			if err != nil || minListens < 1 {
+				l.Debug().Msgf("GetNeglectedArtistsHandler: Invalid min_listens '%s'", v)
+				utils.WriteError(w, "parameter 'min_listens' must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		artists, err := catalog.GetNeglectedArtists(ctx, store, userID, days, minListens)
+		if err != nil {
+			l.Err(err).Msg("GetNeglectedArtistsHandler: Failed to retrieve neglected artists")
+			utils.WriteError(w, "failed to retrieve neglected artists", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("GetNeglectedArtistsHandler: Successfully retrieved neglected artists")
+		utils.WriteJSON(w, http.StatusOK, artists)
+	}
+}
+
+// GetFavoriteArtistsHandler retrieves the artists ranked by both their listens and the number of distinct
+// days they were listened to on within the timeframe, as an alternative to the top artists chart. When the
+// request is authenticated, only the listens of the requesting user are counted.
+func GetFavoriteArtistsHandler(store db.ArtistStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetFavoriteArtistsHandler: Received request to retrieve favorite artists")
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		artists, err := catalog.GetFavoriteArtists(ctx, store, userID, TimeframeFromRequest(r))
+		if err != nil {
+			l.Err(err).Msg("GetFavoriteArtistsHandler: Failed to retrieve favorite artists")
+			utils.WriteError(w, "failed to retrieve favorite artists", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("GetFavoriteArtistsHandler: Successfully retrieved favorite artists")
+		utils.WriteJSON(w, http.StatusOK, artists)
+	}
+}
+
+// GetAmbiguousArtistAliasesHandler lists the aliases shared by more than one artist, so they can be
+// resolved by merging the artists.
+func GetAmbiguousArtistAliasesHandler(store db.ArtistStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetAmbiguousArtistAliasesHandler: Received request to retrieve ambiguous artist aliases")
+
+		aliases, err := catalog.GetAmbiguousArtistAliases(ctx, store)
+		if err != nil {
+			l.Err(err).Msg("GetAmbiguousArtistAliasesHandler: Failed to retrieve ambiguous artist aliases")
+			utils.WriteError(w, "failed to retrieve ambiguous artist aliases", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, aliases)
+	}
+}
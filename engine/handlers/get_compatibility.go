@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+type compatibilityHandlerStore interface {
+	db.UserStore
+	db.ArtistStore
+	db.TrackStore
+}
+
+// GetUserCompatibilityHandler compares the listening of the requesting user with the user
+// given by the username query parameter.
+func GetUserCompatibilityHandler(store compatibilityHandlerStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetUserCompatibilityHandler: Received request")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("GetUserCompatibilityHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		username := r.URL.Query().Get("username")
+		if username == "" {
+			l.Debug().Msg("GetUserCompatibilityHandler: Missing username parameter")
+			utils.WriteError(w, "username is required", http.StatusBadRequest)
+			return
+		}
+
+		other, err := store.GetUserByUsername(ctx, username)
+		if err != nil {
+			l.Err(err).Msg("GetUserCompatibilityHandler: Failed to get user")
+			utils.WriteError(w, "failed to get user", http.StatusInternalServerError)
+			return
+		}
+		if other == nil {
+			l.Debug().Msgf("GetUserCompatibilityHandler: User '%s' not found", username)
+			utils.WriteError(w, "user not found", http.StatusNotFound)
+			return
+		}
+
+		result, err := catalog.GetUserCompatibility(ctx, store, user, other, TimeframeFromRequest(r))
+		if errors.Is(err, catalog.ErrUserNotComparable) {
+			l.Debug().Msg("GetUserCompatibilityHandler: One or both users have not opted in to comparisons")
+			utils.WriteError(w, "both users must opt in to comparisons", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			l.Err(err).Msg("GetUserCompatibilityHandler: Failed to compute compatibility")
+			utils.WriteError(w, "failed to compute compatibility", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, result)
+	}
+}
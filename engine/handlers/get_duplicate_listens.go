@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+// listens to the same track within this many seconds of each other are reported as duplicates by default
+const defaultDuplicateListenWindow = 60
+
+// duplicateListenWindow parses the optional window query parameter, in seconds.
+func duplicateListenWindow(r *http.Request) (time.Duration, bool) {
+	windowStr := r.URL.Query().Get("window")
+	if windowStr == "" {
+		return defaultDuplicateListenWindow * time.Second, true
+	}
+	window, err := strconv.Atoi(windowStr)
+	if err != nil || window <= 0 {
+		return 0, false
+	}
+	return time.Duration(window) * time.Second, true
+}
+
+// GetDuplicateListensHandler reports the user's listens to the same track that happened within
+// window seconds of each other, so they can be reviewed before being deleted.
+func GetDuplicateListensHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetDuplicateListensHandler: Received request to find duplicate listens")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("GetDuplicateListensHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		window, ok := duplicateListenWindow(r)
+		if !ok {
+			l.Debug().Msg("GetDuplicateListensHandler: Invalid window")
+			utils.WriteError(w, "window must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+
+		clusters, err := catalog.FindDuplicateListens(ctx, store, user.ID, window)
+		if err != nil {
+			l.Err(err).Msg("GetDuplicateListensHandler: Failed to find duplicate listens")
+			utils.WriteError(w, "failed to find duplicate listens", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, clusters)
+	}
+}
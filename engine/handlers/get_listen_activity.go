@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -126,6 +128,39 @@ func GetListenActivityHandler(store db.ListenStore) func(w http.ResponseWriter,
 	}
 }
 
+// GetListenCountsByWeekdayHandler returns the number of listens on each day of the week within the
+// requested timeframe, in the requester's timezone. When the request is authenticated, only the
+// listens of the requesting user are counted.
+func GetListenCountsByWeekdayHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetListenCountsByWeekdayHandler: Received request to retrieve listen counts by weekday")
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		loc := parseTZ(r)
+		if strings.ToLower(loc.String()) == "local" {
+			loc = time.UTC
+			l.Warn().Msg("GetListenCountsByWeekdayHandler: Timezone is unset, using UTC")
+		}
+
+		counts, err := catalog.GetListenCountsByWeekday(ctx, store, userID, TimeframeFromRequest(r), loc)
+		if err != nil {
+			l.Err(err).Msg("GetListenCountsByWeekdayHandler: Failed to retrieve listen counts by weekday")
+			utils.WriteError(w, "failed to retrieve listen counts by weekday", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("GetListenCountsByWeekdayHandler: Successfully retrieved listen counts by weekday")
+		utils.WriteJSON(w, http.StatusOK, counts)
+	}
+}
+
 // ngl i hate this
 func processActivity(
 	items []db.ListenActivityItem,
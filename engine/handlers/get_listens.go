@@ -1,13 +1,22 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
 )
 
+// GetListensHandler returns a page of listens matching all of the artist_id, album_id, track_id,
+// client and timeframe query parameters that are set. When the request is authenticated, only the
+// listens of the requesting user are included, along with their private listens.
 func GetListensHandler(store db.ListenStore) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -15,10 +24,24 @@ func GetListensHandler(store db.ListenStore) func(w http.ResponseWriter, r *http
 
 		l.Debug().Msg("GetListensHandler: Received request to retrieve listens")
 
-		opts := OptsFromRequest(r)
+		itemOpts := OptsFromRequest(r)
+		if listensNotModified(w, r, store, itemOpts.UserID) {
+			return
+		}
+
+		opts := catalog.GetListensOpts{
+			ArtistID:  int32(itemOpts.ArtistID),
+			ReleaseID: int32(itemOpts.AlbumID),
+			TrackID:   int32(itemOpts.TrackID),
+			Client:    strings.TrimSpace(r.URL.Query().Get("client")),
+			Timeframe: itemOpts.Timeframe,
+			Limit:     itemOpts.Limit,
+			Page:      itemOpts.Page,
+			UserID:    itemOpts.UserID,
+		}
 		l.Debug().Msgf("GetListensHandler: Retrieving listens with options: %+v", opts)
 
-		listens, err := store.GetListensPaginated(ctx, opts)
+		listens, err := catalog.GetListens(ctx, store, opts)
 		if err != nil {
 			l.Err(err).Msg("GetListensHandler: Failed to retrieve listens")
 			utils.WriteError(w, "failed to get listens: "+err.Error(), http.StatusBadRequest)
@@ -29,3 +52,186 @@ func GetListensHandler(store db.ListenStore) func(w http.ResponseWriter, r *http
 		utils.WriteJSON(w, http.StatusOK, listens)
 	}
 }
+
+// GetListensFeedHandler returns a page of listens, newest first, following the listen given by the
+// cursor query parameter. The response includes the cursor of the next page, which stays stable as new
+// listens are submitted. When the request is authenticated, only the listens of the requesting user are included.
+func GetListensFeedHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetListensFeedHandler: Received request to retrieve listens")
+
+		limit := defaultLimitSize
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maximumLimit {
+				l.Debug().Msgf("GetListensFeedHandler: Invalid limit '%s'", v)
+				utils.WriteError(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		if listensNotModified(w, r, store, userID) {
+			return
+		}
+
+		page, err := catalog.GetListensCursor(ctx, store, userID, r.URL.Query().Get("cursor"), limit)
+		if errors.Is(err, catalog.ErrInvalidCursor) {
+			l.Debug().Msg("GetListensFeedHandler: Invalid cursor")
+			utils.WriteError(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			l.Err(err).Msg("GetListensFeedHandler: Failed to retrieve listens")
+			utils.WriteError(w, "failed to get listens", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("GetListensFeedHandler: Successfully retrieved listens")
+		utils.WriteJSON(w, http.StatusOK, page)
+	}
+}
+
+// SearchListensHandler returns a page of the requesting user's listens to tracks whose title, artist
+// or album contains the q query parameter.
+func SearchListensHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("SearchListensHandler: Received request to search listens")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("SearchListensHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		limit := defaultLimitSize
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maximumLimit {
+				l.Debug().Msgf("SearchListensHandler: Invalid limit '%s'", v)
+				utils.WriteError(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+		page := 1
+		if v := r.URL.Query().Get("page"); v != "" {
+			var err error
+			page, err = strconv.Atoi(v)
+			if err != nil || page < 1 {
+				l.Debug().Msgf("SearchListensHandler: Invalid page '%s'", v)
+				utils.WriteError(w, "invalid page", http.StatusBadRequest)
+				return
+			}
+		}
+
+		listens, err := catalog.SearchListens(ctx, store, user.ID, r.URL.Query().Get("q"), limit, page)
+		if errors.Is(err, catalog.ErrInvalidSearch) {
+			l.Debug().Msg("SearchListensHandler: Invalid search query")
+			utils.WriteError(w, catalog.ErrInvalidSearch.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			l.Err(err).Msg("SearchListensHandler: Failed to search listens")
+			utils.WriteError(w, "failed to search listens", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("SearchListensHandler: Successfully searched listens")
+		utils.WriteJSON(w, http.StatusOK, listens)
+	}
+}
+
+// listensNotModified sets the ETag and Last-Modified headers of a response built from the listens
+// the user can see, from the time of the latest listen and the number of listens. When the request's
+// If-None-Match matches, it writes 304 Not Modified and returns true. If-Modified-Since alone is not
+// enough to answer 304, as listens that are imported or deleted need not change the latest listen time.
+// Renaming or merging items does not change the ETag, so a client that revalidates may keep showing
+// the old names until a listen is added or removed.
+func listensNotModified(w http.ResponseWriter, r *http.Request, store db.ListenStore, userID int32) bool {
+	v, err := store.GetListenVersion(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Err(err).Msg("listensNotModified: Failed to get listen version")
+		return false
+	}
+	var latest int64
+	if !v.Latest.IsZero() {
+		latest = v.Latest.Unix()
+		w.Header().Set("Last-Modified", v.Latest.Format(http.TimeFormat))
+	}
+	etag := fmt.Sprintf(`W/"%d-%d"`, latest, v.Count)
+	w.Header().Set("ETag", etag)
+	// If-None-Match uses weak comparison, so the W/ prefix is ignored
+	for tag := range strings.SplitSeq(r.Header.Get("If-None-Match"), ",") {
+		if tag = strings.TrimSpace(tag); strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") || tag == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// GetListensByDeviceHandler returns the number of listens from each device within the requested
+// timeframe. When the request is authenticated, only the listens of the requesting user are counted.
+func GetListensByDeviceHandler(store db.ListenStore) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetListensByDeviceHandler: Received request to retrieve listen counts by device")
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+		tf := TimeframeFromRequest(r)
+
+		devices, err := store.GetListensByDevice(ctx, db.CountOpts{UserID: userID, Timeframe: tf})
+		if err != nil {
+			l.Err(err).Msg("GetListensByDeviceHandler: Failed to retrieve listen counts by device")
+			utils.WriteError(w, "failed to get listens by device", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("GetListensByDeviceHandler: Successfully retrieved listen counts by device")
+		utils.WriteJSON(w, http.StatusOK, devices)
+	}
+}
+
+// GetListeningSessionsHandler groups listens within the requested timeframe into listening sessions.
+// When the request is authenticated, only the listens of the requesting user are included.
+func GetListeningSessionsHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("GetListeningSessionsHandler: Received request to retrieve listening sessions")
+
+		var userID int32
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		sessions, err := catalog.GetListeningSessions(ctx, store, userID, TimeframeFromRequest(r))
+		if err != nil {
+			l.Err(err).Msg("GetListeningSessionsHandler: Failed to retrieve listening sessions")
+			utils.WriteError(w, "failed to get listening sessions", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msg("GetListeningSessionsHandler: Successfully retrieved listening sessions")
+		utils.WriteJSON(w, http.StatusOK, sessions)
+	}
+}
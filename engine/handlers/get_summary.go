@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/summary"
@@ -23,9 +28,14 @@ func SummaryHandler(store summaryHandlerStore) http.HandlerFunc {
 		l.Debug().Msg("SummaryHandler: Received request to retrieve summary")
 		timeframe := TimeframeFromRequest(r)
 
-		summary, err := summary.GenerateSummary(ctx, store, 1, timeframe, "")
+		userID := catalog.DefaultUserID
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		summary, err := summary.GenerateSummary(ctx, store, userID, timeframe, "")
 		if err != nil {
-			l.Err(err).Int("userid", 1).Any("timeframe", timeframe).Msgf("SummaryHandler: Failed to generate summary")
+			l.Err(err).Int32("userid", userID).Any("timeframe", timeframe).Msgf("SummaryHandler: Failed to generate summary")
 			utils.WriteError(w, "failed to generate summary", http.StatusInternalServerError)
 			return
 		}
@@ -33,3 +43,46 @@ func SummaryHandler(store summaryHandlerStore) http.HandlerFunc {
 		utils.WriteJSON(w, http.StatusOK, summary)
 	}
 }
+
+// YearInReviewHandler returns a year in review snapshot of the requesting user's listening for the year
+// given by the year query parameter, defaulting to the current year.
+func YearInReviewHandler(store summaryHandlerStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+		l.Debug().Msg("YearInReviewHandler: Received request to retrieve year in review")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("YearInReviewHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		loc := parseTZ(r)
+		if strings.ToLower(loc.String()) == "local" {
+			loc = time.UTC
+			l.Warn().Msg("YearInReviewHandler: Timezone is unset, using UTC")
+		}
+
+		year := time.Now().In(loc).Year()
+		if v := r.URL.Query().Get("year"); v != "" {
+			var err error
+			year, err = strconv.Atoi(v)
+			if err != nil || year < 1970 || year > time.Now().Year()+1 {
+				l.Debug().Msgf("YearInReviewHandler: Invalid year '%s'", v)
+				utils.WriteError(w, "invalid year", http.StatusBadRequest)
+				return
+			}
+		}
+
+		review, err := catalog.GenerateYearInReview(ctx, store, user.ID, year, loc)
+		if err != nil {
+			l.Err(err).Int("year", year).Msg("YearInReviewHandler: Failed to generate year in review")
+			utils.WriteError(w, "failed to generate year in review", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, review)
+	}
+}
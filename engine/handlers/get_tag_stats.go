@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+type tagStatsResponse struct {
+	Tag        string           `json:"tag"`
+	Listens    int64            `json:"listens"`
+	TopArtists []db.UserTopItem `json:"top_artists"`
+	TopTracks  []db.UserTopItem `json:"top_tracks"`
+}
+
+// GetTagStatsHandler returns the requesting user's top artists and tracks with the tag given by the
+// tag query parameter, along with their total listens to tracks with the tag, within the timeframe.
+func GetTagStatsHandler(store db.TagStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+		l.Debug().Msg("GetTagStatsHandler: Received request to retrieve tag stats")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("GetTagStatsHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+		if tag == "" {
+			l.Debug().Msg("GetTagStatsHandler: Missing tag")
+			utils.WriteError(w, "tag must be provided", http.StatusBadRequest)
+			return
+		}
+		timeframe := TimeframeFromRequest(r)
+
+		artists, err := catalog.GetTopArtistsByTag(ctx, store, user.ID, tag, timeframe)
+		if err != nil {
+			l.Err(err).Str("tag", tag).Msg("GetTagStatsHandler: Failed to get top artists")
+			utils.WriteError(w, "failed to get tag stats", http.StatusInternalServerError)
+			return
+		}
+		tracks, err := catalog.GetTopTracksByTag(ctx, store, user.ID, tag, timeframe)
+		if err != nil {
+			l.Err(err).Str("tag", tag).Msg("GetTagStatsHandler: Failed to get top tracks")
+			utils.WriteError(w, "failed to get tag stats", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, tagStatsResponse{
+			Tag:        tag,
+			Listens:    artists.Listens,
+			TopArtists: artists.Items,
+			TopTracks:  tracks.Items,
+		})
+	}
+}
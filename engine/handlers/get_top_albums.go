@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -16,6 +17,7 @@ func GetTopAlbumsHandler(store db.AlbumStore) func(w http.ResponseWriter, r *htt
 		l.Debug().Msg("GetTopAlbumsHandler: Received request to retrieve top albums")
 
 		opts := OptsFromRequest(r)
+		opts.ExcludeSingles = cfg.SingleReleasePolicy() == cfg.SingleReleasePolicyCollapse
 		l.Debug().Msgf("GetTopAlbumsHandler: Retrieving top albums with options: %+v", opts)
 
 		albums, err := store.GetTopAlbumsPaginated(ctx, opts)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -57,7 +58,7 @@ func GetTrackAliasesHandler(store db.TrackStore) http.HandlerFunc {
 			return
 		}
 
-		utils.WriteJSON(w, http.StatusOK, aliases)
+		utils.WriteJSON(w, http.StatusOK, filterAliasesBySource(r, aliases))
 	}
 }
 
@@ -118,3 +119,30 @@ func GetTrackInterestHandler(store db.ListenStore) http.HandlerFunc {
 		utils.WriteJSON(w, http.StatusOK, interest)
 	}
 }
+
+// GetTrackAudioFeaturesHandler returns the audio features of a track, if they have been fetched from Spotify.
+func GetTrackAudioFeaturesHandler(store db.TrackStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		trackID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("GetTrackAudioFeaturesHandler: Invalid track id")
+			utils.WriteError(w, "invalid track id", http.StatusBadRequest)
+			return
+		}
+
+		features, err := store.GetTrackAudioFeatures(ctx, trackID)
+		if errors.Is(err, db.ErrNotFound) {
+			utils.WriteError(w, "no audio features found for track", http.StatusNotFound)
+			return
+		} else if err != nil {
+			l.Err(err).Msgf("GetTrackAudioFeaturesHandler: Failed to retrieve audio features for track %d", trackID)
+			utils.WriteError(w, "failed to retrieve audio features", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, features)
+	}
+}
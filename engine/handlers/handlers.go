@@ -8,9 +8,11 @@ import (
 	"time"
 	_ "time/tzdata"
 
+	"github.com/gabehf/koito/engine/middleware"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
 )
 
 const defaultLimitSize = 100
@@ -65,13 +67,28 @@ func OptsFromRequest(r *http.Request) db.GetItemsOpts {
 	l.Debug().Msgf("OptsFromRequest: Parsed options: limit=%d, page=%d, week=%d, month=%d, year=%d, from=%d, to=%d, artist_id=%d, album_id=%d, track_id=%d, period=%s",
 		limit, page, tf.Week, tf.Month, tf.Year, tf.FromUnix, tf.ToUnix, artistId, albumId, trackId, period)
 
+	// weight recent listens more heavily, for a "currently into" chart
+	var decayHalfLife float64
+	if strings.ToLower(r.URL.Query().Get("decay")) == "true" {
+		decayHalfLife = float64(cfg.ChartDecayHalfLifeDays())
+	}
+
+	// an authenticated user sees their own listens, private ones included
+	var userID int32
+	if user := middleware.GetUserFromContext(r.Context()); user != nil {
+		userID = user.ID
+	}
+
 	return db.GetItemsOpts{
-		Limit:     limit,
-		Page:      page,
-		Timeframe: tf,
-		ArtistID:  artistId,
-		AlbumID:   albumId,
-		TrackID:   trackId,
+		Limit:             limit,
+		Page:              page,
+		Timeframe:         tf,
+		ArtistID:          artistId,
+		AlbumID:           albumId,
+		TrackID:           trackId,
+		UserID:            userID,
+		DecayHalfLifeDays: decayHalfLife,
+		Label:             strings.TrimSpace(r.URL.Query().Get("label")),
 	}
 }
 
@@ -252,3 +269,23 @@ func parseTZ(r *http.Request) *time.Location {
 
 	return time.Now().Location()
 }
+
+// filterAliasesBySource keeps only the aliases with one of the sources given in the comma separated
+// ?source= query parameter, compared ignoring case. All aliases are kept when it is not set.
+func filterAliasesBySource(r *http.Request, aliases []models.Alias) []models.Alias {
+	param := r.URL.Query().Get("source")
+	if param == "" {
+		return aliases
+	}
+	sources := strings.Split(param, ",")
+	filtered := make([]models.Alias, 0, len(aliases))
+	for _, a := range aliases {
+		for _, source := range sources {
+			if strings.EqualFold(a.Source, strings.TrimSpace(source)) {
+				filtered = append(filtered, a)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"time"
 
 	"github.com/gabehf/koito/imagecache"
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/go-chi/chi/v5"
@@ -16,7 +19,10 @@ import (
 	"golang.org/x/sync/singleflight"
 )
 
-func ImageHandler(store db.ImageStore) http.HandlerFunc {
+// how long a request waits for an image to be fetched on demand before the placeholder is served
+const lazyImageFetchTimeout = 10 * time.Second
+
+func ImageHandler(store catalog.ImageOnDemandStore) http.HandlerFunc {
 	var downloadGroup singleflight.Group
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -34,7 +40,13 @@ func ImageHandler(store db.ImageStore) http.HandlerFunc {
 		}
 
 		image, err := imagecache.GetImage(imgid, filename)
-		if errors.Is(err, fs.ErrNotExist) {
+		if errors.Is(err, fs.ErrNotExist) && cfg.LazyImageFetch() {
+			l.Debug().Err(err).Msgf("ImageHandler: Could not find requested image %s. Attempting to fetch on demand", imgid.String())
+			image, err = imageHandlerLazyFetch(w, r, l, store, &downloadGroup, imgid, filename)
+			if err != nil {
+				return
+			}
+		} else if errors.Is(err, fs.ErrNotExist) {
 			l.Debug().Err(err).Msgf("ImageHandler: Could not find requested image %s. Attempting to download from source", imgid.String())
 			image, err = imageHandlerRedownload(w, r, l, store, &downloadGroup, imgid, filename)
 			if err != nil {
@@ -92,6 +104,46 @@ func imageHandlerRedownload(w http.ResponseWriter, r *http.Request, l *zerolog.L
 	return image, nil
 }
 
+// fetches the missing image from its source or the image providers, serving the placeholder image
+// if the fetch fails or does not finish in time. The fetch is not tied to the request, so an image
+// that takes too long is still cached for later requests.
+func imageHandlerLazyFetch(w http.ResponseWriter, r *http.Request, l *zerolog.Logger, store catalog.ImageOnDemandStore, downloadGroup *singleflight.Group, imgid uuid.UUID, filename string) (*imagecache.ImageInfo, error) {
+	imageSize, err := imagecache.ParseImageSize(filename)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, err
+	}
+
+	ch := downloadGroup.DoChan(imgid.String(), func() (any, error) {
+		ctx := logger.NewContext(l)
+		return nil, catalog.FetchImageOnDemand(ctx, store, imgid)
+	})
+
+	timer := time.NewTimer(lazyImageFetchTimeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			l.Debug().Err(res.Err).Msgf("ImageHandler: Failed to fetch image '%s' on demand", imgid.String())
+			serveDefaultImage(w, imageSize)
+			return nil, res.Err
+		}
+	case <-timer.C:
+		l.Warn().Msgf("ImageHandler: Timed out fetching image '%s' on demand", imgid.String())
+		serveDefaultImage(w, imageSize)
+		return nil, context.DeadlineExceeded
+	case <-r.Context().Done():
+		return nil, r.Context().Err()
+	}
+
+	image, err := imagecache.GetImage(imgid, filename)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, err
+	}
+	return image, nil
+}
+
 func defaultSVG(size imagecache.ImageSize) []byte {
 	px := size.Width()
 
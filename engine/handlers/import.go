@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+var (
+	// ErrUnrecognizedImport is returned by an ImportFunc given a file that is not a supported export.
+	ErrUnrecognizedImport = errors.New("file is not recognized as a supported export")
+	// ErrAlreadyImported is returned by an ImportFunc given a file that was imported before.
+	ErrAlreadyImported = errors.New("file was already imported")
+)
+
+// ImportFunc imports a file in the import directory for the user.
+type ImportFunc func(ctx context.Context, filename string, userID int32) error
+
+// ImportHandler imports an export file uploaded in the file form field for the requesting user. The
+// file is saved to the import directory under its own name, since the kind of export is detected
+// from the name, and is removed again if the import fails so it is not imported for the default
+// user on the next startup.
+func ImportHandler(importFile ImportFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+		l.Debug().Msg("ImportHandler: Received request to import file")
+
+		u := middleware.GetUserFromContext(ctx)
+		if u == nil {
+			l.Debug().Msg("ImportHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("ImportHandler: Invalid file upload")
+			utils.WriteError(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		name := filepath.Base(header.Filename)
+		if name == "." || name == "/" || name == ".." {
+			l.Debug().Msg("ImportHandler: Invalid file name")
+			utils.WriteError(w, "invalid file name", http.StatusBadRequest)
+			return
+		}
+		dest := path.Join(cfg.ConfigDir(), "import", name)
+		if err := os.MkdirAll(path.Dir(dest), 0744); err != nil {
+			l.Err(err).Msg("ImportHandler: Failed to create import directory")
+			utils.WriteError(w, "failed to save file", http.StatusInternalServerError)
+			return
+		}
+		out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			l.Debug().Msgf("ImportHandler: File %s is already in the import directory", name)
+			utils.WriteError(w, "a file with this name is already waiting to be imported", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			l.Err(err).Msg("ImportHandler: Failed to create file in import directory")
+			utils.WriteError(w, "failed to save file", http.StatusInternalServerError)
+			return
+		}
+		_, err = io.Copy(out, file)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(dest)
+			l.Err(err).Msg("ImportHandler: Failed to save file to import directory")
+			utils.WriteError(w, "failed to save file", http.StatusInternalServerError)
+			return
+		}
+
+		err = importFile(ctx, name, u.ID)
+		if err != nil {
+			// a successful import moves the file out of the import directory
+			os.Remove(dest)
+		}
+		switch {
+		case errors.Is(err, ErrUnrecognizedImport):
+			l.Debug().Msgf("ImportHandler: File %s is not a supported export", name)
+			utils.WriteError(w, "file is not recognized as a supported export; make sure it is named like the original export", http.StatusBadRequest)
+		case errors.Is(err, ErrAlreadyImported):
+			l.Debug().Msgf("ImportHandler: File %s was already imported", name)
+			utils.WriteError(w, "file was already imported", http.StatusConflict)
+		case err != nil:
+			l.Err(err).Msgf("ImportHandler: Failed to import file %s", name)
+			utils.WriteError(w, "failed to import file", http.StatusInternalServerError)
+		default:
+			l.Info().Msgf("ImportHandler: Imported file %s for user '%s'", name, u.Username)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/gabehf/koito/internal/utils"
+	"github.com/go-chi/httprate"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// error codes of the Last.fm API
+const (
+	lastFMErrInvalidMethod     = 3
+	lastFMErrAuthFailed        = 4
+	lastFMErrInvalidParams     = 6
+	lastFMErrOperationFailed   = 8
+	lastFMErrInvalidSessionKey = 9
+	lastFMErrInvalidSignature  = 13
+	lastFMErrRateLimitExceeded = 29
+)
+
+// codes of the reasons a scrobble is ignored
+const (
+	lastFMIgnoredNone      = 0
+	lastFMIgnoredArtist    = 1
+	lastFMIgnoredTrack     = 2
+	lastFMIgnoredDuplicate = 91
+)
+
+const (
+	maxScrobblesPerRequest = 50
+	lastFMSessionKeyLength = 32
+	lastFMSessionKeyLabel  = "Last.fm session"
+)
+
+type lastFMScrobbleHandlerStore interface {
+	submitListenHandlerStore
+	db.UserStore
+}
+
+type lastFMCorrectable struct {
+	Corrected int    `xml:"corrected,attr" json:"corrected,string"`
+	Text      string `xml:",chardata" json:"#text"`
+}
+
+type lastFMIgnoredMessage struct {
+	Code int    `xml:"code,attr" json:"code,string"`
+	Text string `xml:",chardata" json:"#text"`
+}
+
+type lastFMError struct {
+	Code    int    `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+type lastFMTrackResult struct {
+	Track          lastFMCorrectable    `xml:"track" json:"track"`
+	Artist         lastFMCorrectable    `xml:"artist" json:"artist"`
+	Album          lastFMCorrectable    `xml:"album" json:"album"`
+	AlbumArtist    lastFMCorrectable    `xml:"albumArtist" json:"albumArtist"`
+	Timestamp      string               `xml:"timestamp,omitempty" json:"timestamp,omitempty"`
+	IgnoredMessage lastFMIgnoredMessage `xml:"ignoredMessage" json:"ignoredMessage"`
+}
+
+type lastFMScrobbleCounts struct {
+	Accepted int `json:"accepted"`
+	Ignored  int `json:"ignored"`
+}
+
+type lastFMScrobblesResult struct {
+	// the counts are attributes of the element in XML, but an object of their own in JSON
+	Accepted  int                  `xml:"accepted,attr" json:"-"`
+	Ignored   int                  `xml:"ignored,attr" json:"-"`
+	Counts    lastFMScrobbleCounts `xml:"-" json:"@attr"`
+	Scrobbles []lastFMTrackResult  `xml:"scrobble" json:"scrobble"`
+}
+
+type lastFMSession struct {
+	Name       string `xml:"name" json:"name"`
+	Key        string `xml:"key" json:"key"`
+	Subscriber int    `xml:"subscriber" json:"subscriber"`
+}
+
+// lastFMScrobble is a scrobble or now playing report read from the parameters of a request.
+type lastFMScrobble struct {
+	artist      string
+	track       string
+	album       string
+	albumArtist string
+	timestamp   string
+	duration    int32
+	mbid        uuid.UUID
+}
+
+// LastFMScrobbleHandler implements the track.scrobble, track.updateNowPlaying and
+// auth.getMobileSession methods of the Last.fm 2.0 API, so that clients made for Last.fm can
+// submit listens to Koito. Session keys are issued as API keys of the user, and the signatures
+// of requests are checked against the shared secret when one is configured.
+func LastFMScrobbleHandler(store lastFMScrobbleHandlerStore, mbzc mbz.MusicBrainzCaller) http.HandlerFunc {
+	var getMobileSession http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastFMGetMobileSession(w, r, store)
+	})
+	if !cfg.RateLimitDisabled() {
+		// logging in checks the user's password, so it is limited like /login
+		getMobileSession = httprate.Limit(
+			10,
+			time.Minute,
+			httprate.WithLimitHandler(func(w http.ResponseWriter, r *http.Request) {
+				writeLastFMError(w, r, http.StatusTooManyRequests, lastFMErrRateLimitExceeded, "Rate Limit Exceeded - Too many requests in a short period")
+			}),
+		)(getMobileSession)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := logger.FromContext(r.Context())
+
+		if err := r.ParseForm(); err != nil {
+			l.Debug().AnErr("error", err).Msg("LastFMScrobbleHandler: Failed to parse form")
+			writeLastFMError(w, r, http.StatusBadRequest, lastFMErrInvalidParams, "Invalid parameters")
+			return
+		}
+		method := strings.ToLower(r.Form.Get("method"))
+		l.Debug().Msgf("LastFMScrobbleHandler: Received request for method '%s'", method)
+
+		if secret := cfg.LastFMScrobbleSecret(); secret != "" {
+			if !strings.EqualFold(r.Form.Get("api_sig"), lastFMSignature(r.Form, secret)) {
+				l.Debug().Msg("LastFMScrobbleHandler: Invalid method signature")
+				writeLastFMError(w, r, http.StatusForbidden, lastFMErrInvalidSignature, "Invalid method signature supplied")
+				return
+			}
+		}
+
+		switch method {
+		case "auth.getmobilesession":
+			getMobileSession.ServeHTTP(w, r)
+		case "track.scrobble":
+			lastFMScrobbleTracks(w, r, store, mbzc)
+		case "track.updatenowplaying":
+			lastFMUpdateNowPlaying(w, r, store, mbzc)
+		default:
+			l.Debug().Msgf("LastFMScrobbleHandler: Unsupported method '%s'", method)
+			writeLastFMError(w, r, http.StatusBadRequest, lastFMErrInvalidMethod, "Invalid Method - No method with that name in this package")
+		}
+	}
+}
+
+// lastFMGetMobileSession issues a session key to a user that logs in with their password, or with
+// one of their API keys in place of it. An API key is used as the session key itself, while every
+// login with the password saves a new API key, which the user can delete to log the client out.
+func lastFMGetMobileSession(w http.ResponseWriter, r *http.Request, store lastFMScrobbleHandlerStore) {
+	ctx := r.Context()
+	l := logger.FromContext(ctx)
+
+	// the credentials are only read from the body, so that they are not left in access logs
+	username := r.PostForm.Get("username")
+	password := r.PostForm.Get("password")
+	if username == "" || password == "" {
+		writeLastFMError(w, r, http.StatusBadRequest, lastFMErrInvalidParams, "Invalid parameters - username and password are required")
+		return
+	}
+
+	user, err := store.GetUserByUsername(ctx, username)
+	if err != nil {
+		l.Err(err).Msg("LastFMScrobbleHandler: Failed to get user")
+		writeLastFMError(w, r, http.StatusInternalServerError, lastFMErrOperationFailed, "Operation failed")
+		return
+	}
+	if user == nil {
+		l.Debug().Msg("LastFMScrobbleHandler: Invalid credentials")
+		writeLastFMError(w, r, http.StatusForbidden, lastFMErrAuthFailed, "Authentication Failed - You do not have permissions to access the service")
+		return
+	}
+	if keyUser, err := catalog.GetUserByAPIKey(ctx, store, password); err == nil && keyUser.ID == user.ID {
+		l.Info().Msgf("LastFMScrobbleHandler: User '%s' logged in with an API key", user.Username)
+		writeLastFMResponse(w, r, "session", lastFMSession{Name: user.Username, Key: password})
+		return
+	}
+	if bcrypt.CompareHashAndPassword(user.Password, []byte(password)) != nil {
+		l.Debug().Msg("LastFMScrobbleHandler: Invalid credentials")
+		writeLastFMError(w, r, http.StatusForbidden, lastFMErrAuthFailed, "Authentication Failed - You do not have permissions to access the service")
+		return
+	}
+
+	key, err := utils.GenerateRandomString(lastFMSessionKeyLength)
+	if err != nil {
+		l.Err(err).Msg("LastFMScrobbleHandler: Failed to generate session key")
+		writeLastFMError(w, r, http.StatusInternalServerError, lastFMErrOperationFailed, "Operation failed")
+		return
+	}
+	_, err = catalog.SaveApiKey(ctx, store, user.ID, key, lastFMSessionKeyLabel)
+	if err != nil {
+		l.Err(err).Msg("LastFMScrobbleHandler: Failed to save session key")
+		writeLastFMError(w, r, http.StatusInternalServerError, lastFMErrOperationFailed, "Operation failed")
+		return
+	}
+
+	l.Info().Msgf("LastFMScrobbleHandler: Issued a session key to user '%s'", user.Username)
+	writeLastFMResponse(w, r, "session", lastFMSession{Name: user.Username, Key: key})
+}
+
+// lastFMSessionUser returns the user the session key of the request was issued to, writing the
+// error response and returning nil when there is none.
+func lastFMSessionUser(w http.ResponseWriter, r *http.Request, store lastFMScrobbleHandlerStore) *models.User {
+	l := logger.FromContext(r.Context())
+	sk := r.Form.Get("sk")
+	if sk == "" {
+		writeLastFMError(w, r, http.StatusForbidden, lastFMErrInvalidSessionKey, "Invalid session key - Please re-authenticate")
+		return nil
+	}
+	user, err := catalog.GetUserByAPIKey(r.Context(), store, sk)
+	if err != nil {
+		l.Debug().AnErr("error", err).Msg("LastFMScrobbleHandler: Invalid session key")
+		writeLastFMError(w, r, http.StatusForbidden, lastFMErrInvalidSessionKey, "Invalid session key - Please re-authenticate")
+		return nil
+	}
+	return user
+}
+
+func lastFMScrobbleTracks(w http.ResponseWriter, r *http.Request, store lastFMScrobbleHandlerStore, mbzc mbz.MusicBrainzCaller) {
+	ctx := r.Context()
+	l := logger.FromContext(ctx)
+
+	user := lastFMSessionUser(w, r, store)
+	if user == nil {
+		return
+	}
+
+	scrobbles := parseLastFMScrobbles(r.Form)
+	if len(scrobbles) == 0 {
+		writeLastFMError(w, r, http.StatusBadRequest, lastFMErrInvalidParams, "Invalid parameters - artist, track and timestamp are required")
+		return
+	}
+	if len(scrobbles) > maxScrobblesPerRequest {
+		writeLastFMError(w, r, http.StatusBadRequest, lastFMErrInvalidParams, fmt.Sprintf("Invalid parameters - at most %d scrobbles can be submitted at once", maxScrobblesPerRequest))
+		return
+	}
+
+	var result lastFMScrobblesResult
+	for _, s := range scrobbles {
+		res := s.result()
+		res.Timestamp = s.timestamp
+		unix, err := strconv.ParseInt(s.timestamp, 10, 64)
+		switch {
+		case s.artist == "":
+			res.IgnoredMessage = lastFMIgnoredMessage{Code: lastFMIgnoredArtist, Text: "Artist was ignored"}
+		case s.track == "" || err != nil:
+			res.IgnoredMessage = lastFMIgnoredMessage{Code: lastFMIgnoredTrack, Text: "Track was ignored"}
+		default:
+			opts := s.listenOpts(mbzc, user.ID)
+			opts.Time = time.Unix(unix, 0)
+			opts.IsLive = true
+			opts.DedupeWindow = time.Duration(cfg.ListenDedupeWindowSeconds()) * time.Second
+			submitted, err := catalog.SubmitListenWithResult(ctx, store, opts)
+			if err != nil {
+				l.Err(err).Msg("LastFMScrobbleHandler: Failed to submit listen")
+				writeLastFMError(w, r, http.StatusInternalServerError, lastFMErrOperationFailed, "Operation failed")
+				return
+			}
+			if submitted.Deduped {
+				res.IgnoredMessage = lastFMIgnoredMessage{Code: lastFMIgnoredDuplicate, Text: "Already scrobbled"}
+			}
+		}
+		if res.IgnoredMessage.Code == lastFMIgnoredNone {
+			result.Accepted++
+		} else {
+			result.Ignored++
+		}
+		result.Scrobbles = append(result.Scrobbles, res)
+	}
+	result.Counts = lastFMScrobbleCounts{Accepted: result.Accepted, Ignored: result.Ignored}
+
+	l.Debug().Msgf("LastFMScrobbleHandler: Accepted %d and ignored %d scrobbles", result.Accepted, result.Ignored)
+	writeLastFMResponse(w, r, "scrobbles", result)
+}
+
+func lastFMUpdateNowPlaying(w http.ResponseWriter, r *http.Request, store lastFMScrobbleHandlerStore, mbzc mbz.MusicBrainzCaller) {
+	ctx := r.Context()
+	l := logger.FromContext(ctx)
+
+	user := lastFMSessionUser(w, r, store)
+	if user == nil {
+		return
+	}
+
+	s := parseLastFMScrobble(r.Form, "")
+	if s.artist == "" || s.track == "" {
+		writeLastFMError(w, r, http.StatusBadRequest, lastFMErrInvalidParams, "Invalid parameters - artist and track are required")
+		return
+	}
+	if err := catalog.SetNowPlaying(ctx, store, nowPlayingOpts(s.listenOpts(mbzc, user.ID))); err != nil {
+		l.Err(err).Msg("LastFMScrobbleHandler: Failed to set now playing")
+		writeLastFMError(w, r, http.StatusInternalServerError, lastFMErrOperationFailed, "Operation failed")
+		return
+	}
+	writeLastFMResponse(w, r, "nowplaying", s.result())
+}
+
+// parseLastFMScrobbles reads the scrobbles of a track.scrobble request, given either as indexed
+// parameters (artist[0], track[0], ...) or, for a single scrobble, as plain ones.
+func parseLastFMScrobbles(form url.Values) []lastFMScrobble {
+	var scrobbles []lastFMScrobble
+	for i := 0; ; i++ {
+		suffix := "[" + strconv.Itoa(i) + "]"
+		if !form.Has("artist"+suffix) && !form.Has("track"+suffix) && !form.Has("timestamp"+suffix) {
+			break
+		}
+		scrobbles = append(scrobbles, parseLastFMScrobble(form, suffix))
+	}
+	if len(scrobbles) == 0 && (form.Has("artist") || form.Has("track")) {
+		scrobbles = append(scrobbles, parseLastFMScrobble(form, ""))
+	}
+	return scrobbles
+}
+
+func parseLastFMScrobble(form url.Values, suffix string) lastFMScrobble {
+	s := lastFMScrobble{
+		artist:      strings.TrimSpace(form.Get("artist" + suffix)),
+		track:       strings.TrimSpace(form.Get("track" + suffix)),
+		album:       strings.TrimSpace(form.Get("album" + suffix)),
+		albumArtist: strings.TrimSpace(form.Get("albumArtist" + suffix)),
+		timestamp:   form.Get("timestamp" + suffix),
+	}
+	if d, err := strconv.Atoi(form.Get("duration" + suffix)); err == nil && d > 0 {
+		s.duration = int32(d)
+	}
+	if id, err := uuid.Parse(form.Get("mbid" + suffix)); err == nil {
+		s.mbid = id
+	}
+	return s
+}
+
+func (s lastFMScrobble) listenOpts(mbzc mbz.MusicBrainzCaller, userID int32) catalog.SubmitListenOpts {
+	return catalog.SubmitListenOpts{
+		MbzCaller:      mbzc,
+		Artist:         s.artist,
+		TrackTitle:     s.track,
+		RecordingMbzID: s.mbid,
+		ReleaseTitle:   s.album,
+		Duration:       s.duration,
+		UserID:         userID,
+	}
+}
+
+func (s lastFMScrobble) result() lastFMTrackResult {
+	return lastFMTrackResult{
+		Track:       lastFMCorrectable{Text: s.track},
+		Artist:      lastFMCorrectable{Text: s.artist},
+		Album:       lastFMCorrectable{Text: s.album},
+		AlbumArtist: lastFMCorrectable{Text: s.albumArtist},
+	}
+}
+
+// lastFMSignature signs the parameters of a request the way Last.fm clients do: the parameters other
+// than format, callback and api_sig are concatenated as name and value in order of their names,
+// followed by the shared secret, and hashed with MD5.
+func lastFMSignature(form url.Values, secret string) string {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		if name == "format" || name == "callback" || name == "api_sig" {
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteString(form.Get(name))
+	}
+	sb.WriteString(secret)
+	sum := md5.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeLastFMResponse writes v as the element or field with the given name of a successful response,
+// in XML unless the request asks for JSON.
+func writeLastFMResponse(w http.ResponseWriter, r *http.Request, name string, v any) {
+	if r.Form.Get("format") == "json" {
+		utils.WriteJSON(w, http.StatusOK, map[string]any{name: v})
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header + `<lfm status="ok">`))
+	xml.NewEncoder(w).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+	w.Write([]byte(`</lfm>`))
+}
+
+func writeLastFMError(w http.ResponseWriter, r *http.Request, status, code int, message string) {
+	if r.Form.Get("format") == "json" {
+		utils.WriteJSON(w, status, map[string]any{"error": code, "message": message})
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header + `<lfm status="failed">`))
+	xml.NewEncoder(w).EncodeElement(lastFMError{Code: code, Message: message}, xml.StartElement{Name: xml.Name{Local: "error"}})
+	w.Write([]byte(`</lfm>`))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	err := cfg.Load(func(env string) string {
+		switch env {
+		case cfg.SQLITE_ENABLED:
+			return "true"
+		case cfg.CONFIG_DIR_ENV:
+			return os.TempDir()
+		default:
+			return ""
+		}
+	}, "test")
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func TestLastFMGetMobileSession_RateLimit(t *testing.T) {
+	store, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	handler := LastFMScrobbleHandler(store, &mbz.MbzMockCaller{})
+
+	login := func(method string) int {
+		body := url.Values{"method": {method}, "username": {"test"}, "password": {"wrong"}, "format": {"json"}}
+		req := httptest.NewRequest(http.MethodPost, "/apis/lastfm/2.0/", strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	for range 10 {
+		assert.NotEqual(t, http.StatusTooManyRequests, login("auth.getMobileSession"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, login("auth.getMobileSession"))
+	// other methods are not limited
+	assert.NotEqual(t, http.StatusTooManyRequests, login("track.scrobble"))
+}
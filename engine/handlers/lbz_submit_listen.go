@@ -12,6 +12,7 @@ import (
 	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/images"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/mbz"
 	"github.com/gabehf/koito/internal/utils"
@@ -69,6 +70,7 @@ type LbzAdditionalInfo struct {
 	Duration                int32    `json:"duration,omitempty"`
 	Tags                    []string `json:"tags,omitempty"`
 	AlbumArtist             string   `json:"albumartist,omitempty"`
+	SpotifyArtistIDs        []string `json:"spotify_artist_ids,omitempty"`
 }
 
 const (
@@ -153,7 +155,7 @@ func LbzSubmitListenHandler(store submitListenHandlerStore, mbzc mbz.MusicBrainz
 			}
 			if len(artistMbzIDs) < 1 {
 				l.Debug().AnErr("error", err).Msg("LbzSubmitListenHandler: Attempting to parse artist UUIDs from mbid_mapping")
-				utils.ParseUUIDSlice(payload.TrackMeta.MBIDMapping.ArtistMBIDs)
+				artistMbzIDs, err = utils.ParseUUIDSlice(payload.TrackMeta.MBIDMapping.ArtistMBIDs)
 				if err != nil {
 					l.Debug().AnErr("error", err).Msg("LbzSubmitListenHandler: Failed to parse one or more UUIDs")
 				}
@@ -208,6 +210,8 @@ func LbzSubmitListenHandler(store submitListenHandlerStore, mbzc mbz.MusicBrainz
 				artistMbidMap = append(artistMbidMap, catalog.ArtistMbidMap{Artist: a.ArtistName, Mbid: mbid})
 			}
 
+			spotifyIDs := spotifyArtistIDs(payload.TrackMeta)
+
 			opts := catalog.SubmitListenOpts{
 				MbzCaller:          mbzc,
 				ArtistNames:        payload.TrackMeta.AdditionalInfo.ArtistNames,
@@ -219,15 +223,19 @@ func LbzSubmitListenHandler(store submitListenHandlerStore, mbzc mbz.MusicBrainz
 				ReleaseMbzID:       releaseMbzID,
 				ReleaseGroupMbzID:  rgMbzID,
 				ArtistMbidMappings: artistMbidMap,
+				ArtistSpotifyIDs:   spotifyIDs,
 				Duration:           duration,
 				Time:               listenedAt,
 				UserID:             u.ID,
 				Client:             client,
-				IsNowPlaying:       req.ListenType == ListenTypePlayingNow,
-				SkipSaveListen:     req.ListenType == ListenTypePlayingNow,
+				IsLive:             req.ListenType != ListenTypeImport,
+				DedupeWindow:       time.Duration(cfg.ListenDedupeWindowSeconds()) * time.Second,
 			}
 
-			_, err, shared := sfGroup.Do(buildCaolescingKey(payload), func() (interface{}, error) {
+			_, err, shared := sfGroup.Do(buildCaolescingKey(u.ID, payload), func() (interface{}, error) {
+				if req.ListenType == ListenTypePlayingNow {
+					return 0, catalog.SetNowPlaying(r.Context(), store, nowPlayingOpts(opts))
+				}
 				return 0, catalog.SubmitListen(r.Context(), store, opts)
 			})
 			if shared {
@@ -315,7 +323,7 @@ func doLbzRelay(requestBytes []byte, l *zerolog.Logger) {
 	}
 }
 
-func buildCaolescingKey(p LbzSubmitListenPayload) string {
+func buildCaolescingKey(userID int32, p LbzSubmitListenPayload) string {
 	// the key not including the listen_type introduces the very rare possibility of a playing_now
 	// request taking precedence over a single, meaning that a listen will not be logged when it
 	// should, however that would require a playing_now request to fire a few seconds before a 'single'
@@ -323,5 +331,51 @@ func buildCaolescingKey(p LbzSubmitListenPayload) string {
 	//
 	// this could be fixed by restructuring the database inserts for idempotency, which would
 	// eliminate the need to coalesce responses, however i'm not gonna do that right now
-	return fmt.Sprintf("%s:%s:%s", p.TrackMeta.ArtistName, p.TrackMeta.TrackName, p.TrackMeta.ReleaseName)
+	//
+	// the user is part of the key so the same track submitted by two users is saved for both
+	return fmt.Sprintf("%d:%s:%s:%s", userID, p.TrackMeta.ArtistName, p.TrackMeta.TrackName, p.TrackMeta.ReleaseName)
+}
+
+// nowPlayingOpts builds the now playing report of a playing_now submission from the listen it would
+// otherwise be submitted as.
+func nowPlayingOpts(opts catalog.SubmitListenOpts) catalog.NowPlayingOpts {
+	return catalog.NowPlayingOpts{
+		MbzCaller:          opts.MbzCaller,
+		ArtistNames:        opts.ArtistNames,
+		Artist:             opts.Artist,
+		ArtistMbzIDs:       opts.ArtistMbzIDs,
+		ArtistMbidMappings: opts.ArtistMbidMappings,
+		ArtistSpotifyIDs:   opts.ArtistSpotifyIDs,
+		TrackTitle:         opts.TrackTitle,
+		RecordingMbzID:     opts.RecordingMbzID,
+		Duration:           opts.Duration,
+		ReleaseTitle:       opts.ReleaseTitle,
+		ReleaseMbzID:       opts.ReleaseMbzID,
+		ReleaseGroupMbzID:  opts.ReleaseGroupMbzID,
+		UserID:             opts.UserID,
+		Client:             opts.Client,
+	}
+}
+
+// spotifyArtistIDs pairs the Spotify artist IDs in the submission with artist names. IDs are matched to
+// artist_names by position, or to the artist name when there is only one of each.
+func spotifyArtistIDs(meta LbzTrackMeta) map[string]string {
+	ids := make([]string, 0, len(meta.AdditionalInfo.SpotifyArtistIDs))
+	for _, s := range meta.AdditionalInfo.SpotifyArtistIDs {
+		ids = append(ids, images.ParseSpotifyArtistID(s))
+	}
+	names := meta.AdditionalInfo.ArtistNames
+	if len(names) != len(ids) {
+		if len(ids) != 1 {
+			return nil
+		}
+		names = []string{meta.ArtistName}
+	}
+	ret := make(map[string]string, len(ids))
+	for i, id := range ids {
+		if id != "" && names[i] != "" {
+			ret[names[i]] = id
+		}
+	}
+	return ret
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpotifyArtistIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     LbzTrackMeta
+		expected map[string]string
+	}{
+		{
+			name: "uri, url and bare id",
+			meta: LbzTrackMeta{AdditionalInfo: LbzAdditionalInfo{
+				ArtistNames: []string{"A", "B", "C"},
+				SpotifyArtistIDs: []string{
+					"spotify:artist:0OdUWJ0sBjDrqHygGUXeCF",
+					"https://open.spotify.com/artist/3WrFJ7ztbogyGnTHbHJFl2",
+					"4Z8W4fKeB5YxbusRsdQVPb",
+				},
+			}},
+			expected: map[string]string{"A": "0OdUWJ0sBjDrqHygGUXeCF", "B": "3WrFJ7ztbogyGnTHbHJFl2", "C": "4Z8W4fKeB5YxbusRsdQVPb"},
+		},
+		{
+			name: "invalid ids are left out",
+			meta: LbzTrackMeta{AdditionalInfo: LbzAdditionalInfo{
+				ArtistNames:      []string{"A", "B"},
+				SpotifyArtistIDs: []string{"spotify:track:6rqhFgbbKwnb9MLmUQDhG6", "4Z8W4fKeB5YxbusRsdQVPb"},
+			}},
+			expected: map[string]string{"B": "4Z8W4fKeB5YxbusRsdQVPb"},
+		},
+		{
+			name: "a single id belongs to the artist name",
+			meta: LbzTrackMeta{ArtistName: "A feat. B", AdditionalInfo: LbzAdditionalInfo{
+				SpotifyArtistIDs: []string{"https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF"},
+			}},
+			expected: map[string]string{"A feat. B": "0OdUWJ0sBjDrqHygGUXeCF"},
+		},
+		{
+			name: "ids that cannot be matched to names",
+			meta: LbzTrackMeta{AdditionalInfo: LbzAdditionalInfo{
+				ArtistNames:      []string{"A", "B", "C"},
+				SpotifyArtistIDs: []string{"0OdUWJ0sBjDrqHygGUXeCF", "3WrFJ7ztbogyGnTHbHJFl2"},
+			}},
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, spotifyArtistIDs(tt.meta))
+		})
+	}
+}
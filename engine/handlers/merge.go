@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -37,7 +39,17 @@ func MergeArtistsHandler(store db.ArtistStore) http.HandlerFunc {
 
 		l.Debug().Msgf("MergeArtistsHandler: Merging artists from ID %d to ID %d", body.MergeFromID, toId)
 
-		err = store.MergeArtists(r.Context(), body.MergeFromID, toId, body.ReplaceImage)
+		err = catalog.MergeArtists(r.Context(), store, toId, body.MergeFromID, body.ReplaceImage)
+		if errors.Is(err, catalog.ErrSelfMerge) {
+			l.Debug().Msg("MergeArtistsHandler: Attempted to merge an artist into itself")
+			utils.WriteError(w, "cannot merge an artist into itself", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			l.Debug().AnErr("error", err).Msg("MergeArtistsHandler: Artist not found")
+			utils.WriteError(w, "artist not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			l.Err(err).Msg("MergeArtistsHandler: Failed to merge artists")
 			utils.WriteError(w, "Failed to merge artists: "+err.Error(), http.StatusInternalServerError)
@@ -78,7 +90,17 @@ func MergeAlbumsHandler(store db.AlbumStore) http.HandlerFunc {
 
 		l.Debug().Msgf("MergeAlbumsHandler: Merging albums from ID %d to ID %d", body.MergeFromID, toId)
 
-		err = store.MergeAlbums(r.Context(), body.MergeFromID, toId, body.ReplaceImage)
+		err = catalog.MergeReleases(r.Context(), store, toId, body.MergeFromID, body.ReplaceImage)
+		if errors.Is(err, catalog.ErrSelfMerge) {
+			l.Debug().Msg("MergeAlbumsHandler: Attempted to merge an album into itself")
+			utils.WriteError(w, "cannot merge an album into itself", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			l.Debug().AnErr("error", err).Msg("MergeAlbumsHandler: Album not found")
+			utils.WriteError(w, "album not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			l.Err(err).Msg("MergeAlbumsHandler: Failed to merge albums")
 			utils.WriteError(w, "Failed to merge albums: "+err.Error(), http.StatusInternalServerError)
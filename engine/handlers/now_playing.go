@@ -3,9 +3,10 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
-	"github.com/gabehf/koito/internal/memkv"
 	"github.com/gabehf/koito/internal/models"
 	"github.com/gabehf/koito/internal/utils"
 )
@@ -22,20 +23,19 @@ func NowPlayingHandler(store db.TrackStore) http.HandlerFunc {
 
 		l.Debug().Msg("NowPlayingHandler: Got request")
 
-		// Hardcoded user id as 1. Not great but it works until (if) multi-user is supported.
-		if trackIdI, ok := memkv.Store.Get("1"); !ok {
+		userID := catalog.DefaultUserID
+		if user := middleware.GetUserFromContext(ctx); user != nil {
+			userID = user.ID
+		}
+
+		playing, err := catalog.GetNowPlaying(ctx, store, userID)
+		if err != nil {
+			l.Error().Err(err).Msg("NowPlayingHandler: Failed to get currently playing track")
+			utils.WriteError(w, "failed to fetch currently playing track from database", http.StatusInternalServerError)
+		} else if playing == nil {
 			utils.WriteJSON(w, http.StatusOK, NowPlayingResponse{CurrentlyPlaying: false})
-		} else if trackId, ok := trackIdI.(int32); !ok {
-			l.Debug().Msg("NowPlayingHandler: Failed type assertion for trackIdI")
-			utils.WriteError(w, "internal server error", http.StatusInternalServerError)
 		} else {
-			track, err := store.GetTrack(ctx, db.GetTrackOpts{ID: trackId})
-			if err != nil {
-				l.Error().Err(err).Msg("NowPlayingHandler: Failed to get track from database")
-				utils.WriteError(w, "failed to fetch currently playing track from database", http.StatusInternalServerError)
-			} else {
-				utils.WriteJSON(w, http.StatusOK, NowPlayingResponse{CurrentlyPlaying: true, Track: *track})
-			}
+			utils.WriteJSON(w, http.StatusOK, NowPlayingResponse{CurrentlyPlaying: true, Track: *playing.Track})
 		}
 	}
 }
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -30,7 +32,16 @@ func SetPrimaryArtistAliasHandler(store db.ArtistStore) http.HandlerFunc {
 			return
 		}
 
-		if err = store.SetPrimaryArtistAlias(ctx, artistID, body.Alias); err != nil {
+		err = catalog.SetPrimaryArtistName(ctx, store, artistID, body.Alias)
+		if errors.Is(err, catalog.ErrBlankAlias) {
+			utils.WriteError(w, "alias must be provided", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			utils.WriteError(w, "artist not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
 			l.Error().Err(err).Msg("SetPrimaryArtistAliasHandler: Failed to set artist primary alias")
 			utils.WriteError(w, "failed to set primary alias", http.StatusInternalServerError)
 			return
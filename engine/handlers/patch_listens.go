@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+type SetListensPrivateResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+// SetListensPrivateHandler marks the user's listens between the from and to timestamps as private, or
+// as shared again when private=false. Private listens are left out of global charts and comparisons.
+func SetListensPrivateHandler(store db.ListenStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msg("SetListensPrivateHandler: Received request to change listen privacy")
+
+		user := middleware.GetUserFromContext(ctx)
+		if user == nil {
+			l.Debug().Msg("SetListensPrivateHandler: Unauthorized access")
+			utils.WriteError(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		from, err := strconv.ParseInt(q.Get("from"), 10, 64)
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("SetListensPrivateHandler: Invalid from timestamp")
+			utils.WriteError(w, "invalid from timestamp", http.StatusBadRequest)
+			return
+		}
+		opts := db.SetListensPrivateOpts{UserID: user.ID, From: time.Unix(from, 0), Private: true}
+
+		if toStr := q.Get("to"); toStr != "" {
+			to, err := strconv.ParseInt(toStr, 10, 64)
+			if err != nil || to < from {
+				l.Debug().Msg("SetListensPrivateHandler: Invalid to timestamp")
+				utils.WriteError(w, "invalid to timestamp", http.StatusBadRequest)
+				return
+			}
+			opts.To = time.Unix(to, 0)
+		}
+
+		if privateStr := q.Get("private"); privateStr != "" {
+			opts.Private, err = strconv.ParseBool(privateStr)
+			if err != nil {
+				l.Debug().AnErr("error", err).Msg("SetListensPrivateHandler: Invalid private value")
+				utils.WriteError(w, "private must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		updated, err := store.SetListensPrivate(ctx, opts)
+		if err != nil {
+			l.Err(err).Msg("SetListensPrivateHandler: Failed to change listen privacy")
+			utils.WriteError(w, "failed to update listens", http.StatusInternalServerError)
+			return
+		}
+
+		l.Debug().Msgf("SetListensPrivateHandler: Updated %d listens", updated)
+		utils.WriteJSON(w, http.StatusOK, SetListensPrivateResponse{Updated: updated})
+	}
+}
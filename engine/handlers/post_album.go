@@ -5,6 +5,7 @@ import (
 
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
 	"github.com/gabehf/koito/internal/utils"
 )
 
@@ -30,7 +31,7 @@ func CreateAlbumAliasHandler(store db.AlbumStore) http.HandlerFunc {
 			return
 		}
 
-		if err = store.SaveAlbumAliases(ctx, albumID, []string{body.Alias}, "Manual"); err != nil {
+		if err = store.SaveAlbumAliases(ctx, albumID, []string{body.Alias}, models.AliasSourceManual); err != nil {
 			l.Error().Err(err).Msg("CreateAlbumAliasHandler: Failed to save album alias")
 			utils.WriteError(w, "failed to save alias", http.StatusInternalServerError)
 			return
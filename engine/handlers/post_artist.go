@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/utils"
@@ -33,7 +35,21 @@ func CreateArtistAliasHandler(store db.ArtistStore) http.HandlerFunc {
 			return
 		}
 
-		if err = store.SaveArtistAliases(ctx, artistID, []string{body.Alias}, "Manual"); err != nil {
+		err = catalog.AddArtistAlias(ctx, store, artistID, body.Alias)
+		if errors.Is(err, catalog.ErrBlankAlias) {
+			utils.WriteError(w, "alias must be provided", http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, catalog.ErrDuplicateAlias) {
+			l.Debug().Msg("CreateArtistAliasHandler: Artist already has the alias")
+			utils.WriteError(w, "artist already has this alias", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			utils.WriteError(w, "artist not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
 			l.Error().Err(err).Msg("CreateArtistAliasHandler: Failed to save artist alias")
 			utils.WriteError(w, "failed to save alias", http.StatusInternalServerError)
 			return
@@ -42,3 +58,35 @@ func CreateArtistAliasHandler(store db.ArtistStore) http.HandlerFunc {
 		w.WriteHeader(http.StatusCreated)
 	}
 }
+
+// SaveArtistTagsHandler adds the tags in the request body to the artist.
+func SaveArtistTagsHandler(store db.TagStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		artistID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("SaveArtistTagsHandler: Invalid artist id")
+			utils.WriteError(w, "invalid artist id", http.StatusBadRequest)
+			return
+		}
+
+		body, err := utils.DecodeBody[struct {
+			Tags []string `json:"tags"`
+		}](r)
+		if err != nil || len(body.Tags) == 0 {
+			l.Debug().Msg("SaveArtistTagsHandler: Invalid or missing tags in request body")
+			utils.WriteError(w, "tags must be provided", http.StatusBadRequest)
+			return
+		}
+
+		if err = store.SaveArtistTags(ctx, artistID, body.Tags); err != nil {
+			l.Error().Err(err).Msg("SaveArtistTagsHandler: Failed to save artist tags")
+			utils.WriteError(w, "failed to save tags", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
@@ -28,6 +28,7 @@ func SubmitListenWithIDHandler(store db.ListenStore) http.HandlerFunc {
 			TrackID int32  `json:"track_id"`
 			Unix    int64  `json:"unix"`
 			Client  string `json:"client"`
+			Private bool   `json:"private"`
 		}](r)
 		if err != nil || body.TrackID == 0 || body.Unix == 0 {
 			l.Debug().Msg("SubmitListenWithIDHandler: Invalid or missing required fields in request body")
@@ -46,17 +47,24 @@ func SubmitListenWithIDHandler(store db.ListenStore) http.HandlerFunc {
 			client = defaultClientStr
 		}
 
-		if err = store.SaveListen(ctx, db.SaveListenOpts{
+		saved, err := store.SaveListen(ctx, db.SaveListenOpts{
 			TrackID: body.TrackID,
 			Time:    time.Unix(body.Unix, 0),
 			UserID:  u.ID,
 			Client:  client,
-		}); err != nil {
+			Private: body.Private,
+		})
+		if err != nil {
 			l.Err(err).Msg("SubmitListenWithIDHandler: Failed to submit listen")
 			utils.WriteError(w, "failed to submit listen", http.StatusInternalServerError)
 			return
 		}
 
+		if !saved {
+			l.Debug().Msg("SubmitListenWithIDHandler: Listen was already saved")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 		w.WriteHeader(http.StatusCreated)
 	}
 }
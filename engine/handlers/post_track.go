@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
 	"github.com/gabehf/koito/internal/utils"
 )
 
@@ -30,7 +33,7 @@ func CreateTrackAliasHandler(store db.TrackStore) http.HandlerFunc {
 			return
 		}
 
-		if err = store.SaveTrackAliases(ctx, trackID, []string{body.Alias}, "Manual"); err != nil {
+		if err = store.SaveTrackAliases(ctx, trackID, []string{body.Alias}, models.AliasSourceManual); err != nil {
 			l.Error().Err(err).Msg("CreateTrackAliasHandler: Failed to save track alias")
 			utils.WriteError(w, "failed to save alias", http.StatusInternalServerError)
 			return
@@ -74,3 +77,73 @@ func AddTrackArtistsHandler(store db.DB) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// SaveTrackTagsHandler adds the tags in the request body to the track.
+func SaveTrackTagsHandler(store db.TagStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		trackID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("SaveTrackTagsHandler: Invalid track id")
+			utils.WriteError(w, "invalid track id", http.StatusBadRequest)
+			return
+		}
+
+		body, err := utils.DecodeBody[struct {
+			Tags []string `json:"tags"`
+		}](r)
+		if err != nil || len(body.Tags) == 0 {
+			l.Debug().Msg("SaveTrackTagsHandler: Invalid or missing tags in request body")
+			utils.WriteError(w, "tags must be provided", http.StatusBadRequest)
+			return
+		}
+
+		if err = store.SaveTrackTags(ctx, trackID, body.Tags); err != nil {
+			l.Error().Err(err).Msg("SaveTrackTagsHandler: Failed to save track tags")
+			utils.WriteError(w, "failed to save tags", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// MoveTrackHandler moves a track, along with its listens, to the release given in the request body,
+// merging it into the release's track of the same title if there is one.
+func MoveTrackHandler(store db.TrackStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		trackID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("MoveTrackHandler: Invalid track id")
+			utils.WriteError(w, "invalid track id", http.StatusBadRequest)
+			return
+		}
+
+		body, err := utils.DecodeBody[struct {
+			ReleaseID int32 `json:"release_id"`
+		}](r)
+		if err != nil || body.ReleaseID == 0 {
+			l.Debug().Msg("MoveTrackHandler: Invalid or missing release_id in request body")
+			utils.WriteError(w, "release_id must be provided", http.StatusBadRequest)
+			return
+		}
+
+		track, err := catalog.MoveTrackToRelease(ctx, store, trackID, body.ReleaseID)
+		if errors.Is(err, db.ErrNotFound) {
+			utils.WriteError(w, "track or release not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			l.Error().Err(err).Msg("MoveTrackHandler: Failed to move track")
+			utils.WriteError(w, "failed to move track", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, track)
+	}
+}
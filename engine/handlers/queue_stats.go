@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gabehf/koito/imagecache"
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/images"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+	"github.com/gabehf/koito/queue"
+)
+
+// GetQueueStatsHandler reports the work done by the image download pool and by each image
+// provider's search queue, and how many artists and albums the image backfill has yet to find
+// images for, if it is running.
+func GetQueueStatsHandler(backfill *catalog.ImageBackfill) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l := logger.FromContext(r.Context())
+		l.Debug().Msg("GetQueueStatsHandler: Received request to retrieve queue stats")
+
+		var remaining *catalog.ImageBackfillStats
+		if backfill != nil {
+			stats := backfill.Remaining()
+			remaining = &stats
+		}
+
+		utils.WriteJSON(w, http.StatusOK, struct {
+			ImageDownloads queue.Stats                 `json:"image_downloads"`
+			ImageSearch    map[string]queue.Stats      `json:"image_search"`
+			ImageBackfill  *catalog.ImageBackfillStats `json:"image_backfill,omitempty"`
+		}{
+			ImageDownloads: imagecache.DownloadStats(),
+			ImageSearch:    images.SearchStats(),
+			ImageBackfill:  remaining,
+		})
+	}
+}
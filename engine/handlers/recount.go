@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+// RecountHandler recounts the plays and listening time of the artist, album or track with the id in
+// the path, returning the corrected counts.
+func RecountHandler(store catalog.RecountStore, entityType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		l.Debug().Msgf("RecountHandler: Received request to recount %s", entityType)
+
+		id, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msgf("RecountHandler: Invalid %s id", entityType)
+			utils.WriteError(w, "invalid "+entityType+" id", http.StatusBadRequest)
+			return
+		}
+
+		counts, err := catalog.RecountEntity(ctx, store, entityType, id)
+		if errors.Is(err, db.ErrNotFound) {
+			l.Debug().Msgf("RecountHandler: %s %d not found", entityType, id)
+			utils.WriteError(w, entityType+" not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			l.Err(err).Msgf("RecountHandler: Failed to recount %s %d", entityType, id)
+			utils.WriteError(w, "failed to recount "+entityType, http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, counts)
+	}
+}
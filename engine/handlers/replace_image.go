@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gabehf/koito/imagecache"
@@ -180,3 +182,164 @@ func parseOldImage(link string) *uuid.UUID {
 		return &parsed
 	}
 }
+
+type imageCandidatesHandlerStore interface {
+	db.ArtistStore
+	db.AlbumStore
+}
+
+// GetArtistImageCandidatesHandler returns images from the image providers that may belong to an artist,
+// without saving anything. A candidate is chosen by passing its URL to ReplaceArtistImageHandler.
+func GetArtistImageCandidatesHandler(store imageCandidatesHandlerStore) http.HandlerFunc {
+	return imageCandidatesHandler(store, "artist")
+}
+
+// GetAlbumImageCandidatesHandler returns images from the image providers that may belong to an album,
+// without saving anything. A candidate is chosen by passing its URL to ReplaceAlbumImageHandler.
+func GetAlbumImageCandidatesHandler(store imageCandidatesHandlerStore) http.HandlerFunc {
+	return imageCandidatesHandler(store, "album")
+}
+
+func imageCandidatesHandler(store imageCandidatesHandlerStore, itemType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		id, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msgf("imageCandidatesHandler: Invalid %s id", itemType)
+			utils.WriteError(w, "invalid "+itemType+" id", http.StatusBadRequest)
+			return
+		}
+
+		limit := 10
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maximumLimit {
+				l.Debug().Msgf("imageCandidatesHandler: Invalid limit '%s'", v)
+				utils.WriteError(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		opts := catalog.PreviewImageOpts{Limit: limit}
+		if itemType == "artist" {
+			opts.ArtistID = id
+		} else {
+			opts.AlbumID = id
+		}
+
+		candidates, err := catalog.PreviewImageCandidates(ctx, store, opts)
+		if errors.Is(err, db.ErrNotFound) {
+			l.Debug().Msgf("imageCandidatesHandler: %s with id %d not found", itemType, id)
+			utils.WriteError(w, itemType+" not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			l.Err(err).Msg("imageCandidatesHandler: Failed to get image candidates")
+			utils.WriteError(w, "failed to get image candidates", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, candidates)
+	}
+}
+
+type RefreshImageResponse struct {
+	Refreshed bool `json:"refreshed"`
+}
+
+// RefreshArtistImageHandler re-fetches the image for an artist from the image providers.
+// Artists with an existing cached image are skipped unless the force query parameter is true.
+func RefreshArtistImageHandler(store db.ArtistStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		artistID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("RefreshArtistImageHandler: Invalid artist id")
+			utils.WriteError(w, "invalid artist id", http.StatusBadRequest)
+			return
+		}
+
+		force := strings.ToLower(r.URL.Query().Get("force")) == "true"
+
+		refreshed, err := catalog.RefreshArtistImage(ctx, store, artistID, force)
+		if err != nil {
+			l.Err(err).Msg("RefreshArtistImageHandler: Failed to refresh artist image")
+			utils.WriteError(w, "failed to refresh artist image", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, RefreshImageResponse{Refreshed: refreshed})
+	}
+}
+
+// RefreshAlbumImageHandler re-fetches the image for an album from the image providers.
+// Albums with an existing cached image are skipped unless the force query parameter is true.
+func RefreshAlbumImageHandler(store db.AlbumStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		albumID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("RefreshAlbumImageHandler: Invalid album id")
+			utils.WriteError(w, "invalid album id", http.StatusBadRequest)
+			return
+		}
+
+		force := strings.ToLower(r.URL.Query().Get("force")) == "true"
+
+		refreshed, err := catalog.RefreshAlbumImage(ctx, store, albumID, force)
+		if err != nil {
+			l.Err(err).Msg("RefreshAlbumImageHandler: Failed to refresh album image")
+			utils.WriteError(w, "failed to refresh album image", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, RefreshImageResponse{Refreshed: refreshed})
+	}
+}
+
+type AssignReleaseGroupImageResponse struct {
+	Updated int `json:"updated"`
+}
+
+// AssignReleaseGroupImageHandler assigns the CoverArtArchive cover of an album's release group to
+// every album in that release group that is missing an image.
+func AssignReleaseGroupImageHandler(store db.AlbumStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		albumID, err := utils.ParseIDParam(r, "id")
+		if err != nil {
+			l.Debug().AnErr("error", err).Msg("AssignReleaseGroupImageHandler: Invalid album id")
+			utils.WriteError(w, "invalid album id", http.StatusBadRequest)
+			return
+		}
+
+		album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: albumID})
+		if err != nil {
+			l.Debug().AnErr("error", err).Msgf("AssignReleaseGroupImageHandler: Album with id %d not found", albumID)
+			utils.WriteError(w, "album not found", http.StatusNotFound)
+			return
+		}
+		if album.ReleaseGroupMbzID == nil {
+			l.Debug().Msgf("AssignReleaseGroupImageHandler: Album '%s' has no release group MusicBrainz ID", album.Title)
+			utils.WriteError(w, "album has no release group MusicBrainz ID", http.StatusBadRequest)
+			return
+		}
+
+		updated, err := catalog.AssignReleaseGroupImage(ctx, store, *album.ReleaseGroupMbzID)
+		if err != nil {
+			l.Err(err).Msg("AssignReleaseGroupImageHandler: Failed to assign release group image")
+			utils.WriteError(w, "failed to assign release group image", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, AssignReleaseGroupImageResponse{Updated: updated})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/memkv"
@@ -22,6 +24,8 @@ type StatsResponse struct {
 	AvgDailyPlays   float32 `json:"avg_daily_plays"`
 	TracksPerArtist float32 `json:"tracks_per_artist"`
 	AlbumsPerArtist float32 `json:"albums_per_artist"`
+	// nil when there are no listens in the timeframe
+	BusiestDay *catalog.BusiestDay `json:"busiest_day"`
 }
 
 type statsStore interface {
@@ -31,6 +35,8 @@ type statsStore interface {
 	db.ArtistStore
 }
 
+// StatsHandler returns listening statistics for the requested timeframe. When the request is
+// authenticated, the statistics are of the requesting user's listens, private ones included.
 func StatsHandler(store statsStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		l := logger.FromContext(r.Context())
@@ -38,10 +44,15 @@ func StatsHandler(store statsStore) http.HandlerFunc {
 		l.Debug().Msg("StatsHandler: Received request to retrieve statistics")
 
 		tf := TimeframeFromRequest(r)
+		var userID int32
+		if user := middleware.GetUserFromContext(r.Context()); user != nil {
+			userID = user.ID
+		}
+		opts := db.CountOpts{UserID: userID, Timeframe: tf}
 
 		l.Debug().Msg("StatsHandler: Fetching statistics")
 
-		cacheKeyString := fmt.Sprintf("stats_%s_%s", r.URL.Query().Encode(), tf.Timezone.String())
+		cacheKeyString := fmt.Sprintf("stats_%d_%s_%s", userID, r.URL.Query().Encode(), tf.Timezone.String())
 
 		if cachedStatsI, ok := memkv.Store.Get(cacheKeyString); ok {
 			if cachedStats, ok := cachedStatsI.(*StatsResponse); ok {
@@ -53,55 +64,63 @@ func StatsHandler(store statsStore) http.HandlerFunc {
 
 		l.Debug().Msg("StatsHandler: cache missed for stats")
 
-		listens, err := store.CountListens(r.Context(), tf)
+		listens, err := store.CountListens(r.Context(), opts)
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch listen count")
 			utils.WriteError(w, "failed to get listens: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		tracks, err := store.CountTracks(r.Context(), tf)
+		tracks, err := store.CountTracks(r.Context(), opts)
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch track count")
 			utils.WriteError(w, "failed to get tracks: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		albums, err := store.CountAlbums(r.Context(), tf)
+		albums, err := store.CountAlbums(r.Context(), opts)
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch album count")
 			utils.WriteError(w, "failed to get albums: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		artists, err := store.CountArtists(r.Context(), tf)
+		artists, err := store.CountArtists(r.Context(), opts)
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch artist count")
 			utils.WriteError(w, "failed to get artists: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		timeListenedS, err := store.CountTimeListened(r.Context(), tf)
+		timeListenedS, err := store.CountTimeListened(r.Context(), opts)
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch time listened")
 			utils.WriteError(w, "failed to get time listened: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		activeDays, err := store.GetActiveDays(r.Context(), tf.Timezone)
+		activeDays, err := store.GetActiveDays(r.Context(), db.ListenActivityOpts{Timezone: tf.Timezone, UserID: userID})
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch active days")
 			utils.WriteError(w, "failed to get active days: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		longestStreak, err := store.GetLongestListenStreak(r.Context(), db.ListenActivityOpts{Timezone: tf.Timezone})
+		longestStreak, err := store.GetLongestListenStreak(r.Context(), db.ListenActivityOpts{Timezone: tf.Timezone, UserID: userID})
 		if err != nil {
 			l.Err(err).Msg("StatsHandler: Failed to fetch longest streak")
 			utils.WriteError(w, "failed to get longest streak: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// the listens of the busiest day are only included when asked for with ?busiest_day_tracks=true
+		busiestDay, err := catalog.GetBusiestDay(r.Context(), store, userID, tf, r.URL.Query().Get("busiest_day_tracks") == "true")
+		if err != nil {
+			l.Err(err).Msg("StatsHandler: Failed to fetch busiest day")
+			utils.WriteError(w, "failed to get busiest day: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		l.Debug().Msg("StatsHandler: Successfully fetched statistics")
 
 		resp := &StatsResponse{
@@ -115,6 +134,7 @@ func StatsHandler(store statsStore) http.HandlerFunc {
 			TracksPerArtist: float32(tracks) / float32(artists),
 			AlbumsPerArtist: float32(albums) / float32(artists),
 			LongestStreak:   longestStreak,
+			BusiestDay:      busiestDay,
 		}
 
 		utils.WriteJSON(w, http.StatusOK, resp)
@@ -123,3 +143,23 @@ func StatsHandler(store statsStore) http.HandlerFunc {
 		memkv.Store.Set(cacheKeyString, resp, 30*time.Minute)
 	}
 }
+
+// AverageAudioFeaturesHandler returns the audio features of the tracks listened to within the
+// timeframe, averaged over each listen.
+func AverageAudioFeaturesHandler(store db.TrackStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		l := logger.FromContext(ctx)
+
+		tf := TimeframeFromRequest(r)
+
+		avg, err := store.GetAverageAudioFeatures(ctx, db.GetAverageAudioFeaturesOpts{Timeframe: tf})
+		if err != nil {
+			l.Err(err).Msg("AverageAudioFeaturesHandler: Failed to fetch average audio features")
+			utils.WriteError(w, "failed to get average audio features", http.StatusInternalServerError)
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, avg)
+	}
+}
@@ -1,6 +1,7 @@
 package engine_test
 
 import (
+	"archive/zip"
 	"context"
 	"os"
 	"path"
@@ -11,8 +12,11 @@ import (
 	"github.com/gabehf/koito/engine"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/importer"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
 	"github.com/gabehf/koito/internal/utils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -64,6 +68,499 @@ func TestImportSpotify(t *testing.T) {
 	// spotify includes duration data, but we only import when reason_end = trackdone
 	// this is the only track with valid duration data
 	assert.EqualValues(t, 181, track.Duration)
+
+	// platform is stored as the listen's device
+	devices, err := store.GetListensByDevice(context.Background(), db.CountOpts{UserID: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "windows", devices[0].Device)
+	assert.EqualValues(t, 1, devices[0].Listens)
+}
+
+func TestImportSpotify_ClientOverride(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_spotify_import_test.json")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	dest := filepath.Join(destDir, "[laptop]Streaming_History_Audio_spotify_import_test.json")
+
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{Client: "laptop"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, listens.TotalCount)
+	listens, err = store.GetListens(context.Background(), db.GetListensOpts{Client: "spotify"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, listens.TotalCount)
+}
+
+func TestImportSpotify_SkipsAlreadyImportedFile(t *testing.T) {
+	store := newTestDB()
+	defer cfg.SetForceReimport(false)
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_spotify_import_test.json")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	countListens := func() int64 {
+		count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+		require.NoError(t, err)
+		return count
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(destDir, "Streaming_History_Audio_spotify_import_test.json"), input, os.ModePerm))
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+	require.EqualValues(t, 1, countListens())
+
+	// the same contents under another name are skipped and left in the import folder
+	again := filepath.Join(destDir, "Streaming_History_Audio_again.json")
+	require.NoError(t, os.WriteFile(again, input, os.ModePerm))
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+	assert.EqualValues(t, 1, countListens())
+	assert.FileExists(t, again)
+
+	cfg.SetForceReimport(true)
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+	assert.NoFileExists(t, again)
+}
+
+func TestImportSpotifyFile_RefusesAlreadyImportedFile(t *testing.T) {
+	store := newTestDB()
+	defer cfg.SetForceReimport(false)
+	ctx := logger.NewContext(logger.Get())
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_spotify_import_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_refuse_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	require.NoError(t, importer.ImportSpotifyFile(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 1))
+
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	err = importer.ImportSpotifyFile(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 1)
+	assert.ErrorIs(t, err, importer.ErrAlreadyImported)
+	count, err := store.CountListens(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	// another user can import the same file, once
+	require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('test2', 0x123)`))
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	require.NoError(t, importer.ImportSpotifyFile(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 2))
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	err = importer.ImportSpotifyFile(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 2)
+	assert.ErrorIs(t, err, importer.ErrAlreadyImported)
+
+	cfg.SetForceReimport(true)
+	require.NoError(t, importer.ImportSpotifyFile(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 1))
+}
+
+func TestImportSpotify_MergeSplitPlays(t *testing.T) {
+	defer cfg.SetImportMergeGapSeconds(0)
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_split_play_test.json")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	dest := filepath.Join(destDir, "Streaming_History_Audio_split_play_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	tests := []struct {
+		gapSeconds    int
+		expected      int64
+		splitDuration int32
+	}{
+		// every item that played to the end is a listen, so only the second part of the split play counts
+		{0, 4, 100},
+		// the split and resumed plays are one listen each with the play time of both parts, the replay
+		// is still two
+		{60, 4, 220},
+	}
+	for _, tt := range tests {
+		store := newTestDB()
+		cfg.SetImportMergeGapSeconds(tt.gapSeconds)
+		require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+		engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+		count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+		require.NoError(t, err)
+		assert.EqualValues(t, tt.expected, count, "gap of %d seconds", tt.gapSeconds)
+		a, err := store.GetArtist(context.Background(), db.GetArtistOpts{Name: "Split Artist"})
+		require.NoError(t, err)
+		r, err := store.GetAlbum(context.Background(), db.GetAlbumOpts{ArtistID: a.ID, Title: "Split Album"})
+		require.NoError(t, err)
+		track, err := store.GetTrack(context.Background(), db.GetTrackOpts{Title: "Split Track", ReleaseID: r.ID, ArtistIDs: []int32{a.ID}})
+		require.NoError(t, err)
+		assert.EqualValues(t, tt.splitDuration, track.Duration, "gap of %d seconds", tt.gapSeconds)
+		assert.EqualValues(t, 1, track.ListenCount, "gap of %d seconds", tt.gapSeconds)
+	}
+}
+
+func TestImportSpotify_MergeSplitPlaysBackToBack(t *testing.T) {
+	defer cfg.SetImportMergeGapSeconds(0)
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_back_to_back_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_back_to_back_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	// the second play starts the moment the first one ends, but both played to the end
+	cfg.SetImportMergeGapSeconds(60)
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestImportSpotify_SkippedAndIncognito(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_endsong_test.json")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	dest := filepath.Join(destDir, "Streaming_History_Audio_endsong_test.json")
+
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	// the skipped and incognito plays are left out, the item from an older export without these
+	// fields is imported
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	a, err := store.GetArtist(context.Background(), db.GetArtistOpts{Name: "Endsong Artist"})
+	require.NoError(t, err)
+	r, err := store.GetAlbum(context.Background(), db.GetAlbumOpts{ArtistID: a.ID, Title: "Endsong Album"})
+	require.NoError(t, err)
+	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{Title: "Endsong Track", ReleaseID: r.ID, ArtistIDs: []int32{a.ID}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, track.ListenCount)
+	_, err = store.GetTrack(context.Background(), db.GetTrackOpts{Title: "Incognito Track", ReleaseID: r.ID, ArtistIDs: []int32{a.ID}})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func TestImportSpotify_DedupeWindow(t *testing.T) {
+	defer cfg.SetImportDedupeWindowSeconds(5)
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_dedupe_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_dedupe_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	tests := []struct {
+		windowSeconds int
+		expected      int64
+	}{
+		// the two plays are 3 seconds apart
+		{0, 2},
+		{5, 1},
+	}
+	for _, tt := range tests {
+		store := newTestDB()
+		cfg.SetImportDedupeWindowSeconds(tt.windowSeconds)
+		require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+		engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+		count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+		require.NoError(t, err)
+		assert.EqualValues(t, tt.expected, count, "window of %d seconds", tt.windowSeconds)
+	}
+}
+
+func TestImportSpotifyArchive(t *testing.T) {
+	store := newTestDB()
+
+	splitPlays, err := os.ReadFile(path.Join("..", "test_assets", "Streaming_History_Audio_split_play_test.json"))
+	require.NoError(t, err)
+	endsong, err := os.ReadFile(path.Join("..", "test_assets", "Streaming_History_Audio_endsong_test.json"))
+	require.NoError(t, err)
+
+	dest := filepath.Join(cfg.ConfigDir(), "import", "my_spotify_data.zip")
+	f, err := os.Create(dest)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for name, data := range map[string][]byte{
+		"Spotify Extended Streaming History/Streaming_History_Audio_2025_1.json":      endsong,
+		"Spotify Extended Streaming History/Streaming_History_Audio_2025_0.json":      splitPlays,
+		"Spotify Extended Streaming History/Streaming_History_Video_2025.json":        []byte(`not an audio history`),
+		"Spotify Extended Streaming History/ReadMeFirst_ExtendedStreamingHistory.pdf": []byte(`%PDF`),
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	// the listens of both audio history files, and none from the other files
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, count)
+	_, err = os.Stat(filepath.Join(cfg.ConfigDir(), "import_complete", "my_spotify_data.zip"))
+	assert.NoError(t, err)
+}
+
+func TestImportSpotifyDirectory(t *testing.T) {
+	store := newTestDB()
+
+	item := func(ts, track string) string {
+		return `{"ts":"` + ts + `","platform":"android","ms_played":200000,"master_metadata_track_name":"` + track +
+			`","master_metadata_album_artist_name":"Directory Artist","master_metadata_album_album_name":"Directory Album","reason_end":"trackdone"}`
+	}
+	dir := filepath.Join(cfg.ConfigDir(), "import", "Spotify Extended Streaming History", "nested")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	// the later file sorts first by name, and repeats the last play of the earlier file within 5 seconds
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Streaming_History_Audio_2024.json"),
+		[]byte(`[`+item("2025-03-01T10:00:03Z", "Repeated Track")+`,`+item("2025-03-01T11:00:00Z", "Later Track")+`]`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Streaming_History_Audio_2025.json"),
+		[]byte(`[`+item("2025-03-01T10:00:00Z", "Repeated Track")+`]`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Streaming_History_Video_2025.json"), []byte(`not an audio history`), 0644))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	_, err = os.Stat(filepath.Join(cfg.ConfigDir(), "import_complete", "Spotify Extended Streaming History"))
+	assert.NoError(t, err)
+}
+
+func TestImportSpotify_ResumesAfterCheckpoint(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_split_play_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_resume_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	// an earlier import failed after importing the items up to the first play of the replayed track
+	checkpoint := filepath.Join(cfg.ConfigDir(), "import_progress", "1", "Streaming_History_Audio_resume_test.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(checkpoint), 0755))
+	require.NoError(t, os.WriteFile(checkpoint, []byte("2025-05-01T10:08:00Z"), 0644))
+	// another user's import of a file of the same name is not resumed from
+	otherCheckpoint := filepath.Join(cfg.ConfigDir(), "import_progress", "2", "Streaming_History_Audio_resume_test.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(otherCheckpoint), 0755))
+	require.NoError(t, os.WriteFile(otherCheckpoint, []byte("2030-01-01T00:00:00Z"), 0644))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	// only the second play of the replayed track and the resumed track are imported
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	_, err = store.GetArtist(context.Background(), db.GetArtistOpts{Name: "Split Artist"})
+	require.NoError(t, err)
+	_, err = store.GetTrack(context.Background(), db.GetTrackOpts{Title: "Split Track"})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// the checkpoint is removed once the import finishes
+	_, err = os.Stat(checkpoint)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(otherCheckpoint)
+	assert.NoError(t, err)
+}
+
+func TestImportSpotify_Progress(t *testing.T) {
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_split_play_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_progress_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	ctx := logger.NewContext(logger.Get())
+
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	progress := make(chan importer.ImportProgress, 10)
+	require.NoError(t, importer.ImportSpotifyFileWithProgress(ctx, newTestDB(), &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 1, progress))
+	require.NotEmpty(t, progress)
+	var last importer.ImportProgress
+	for len(progress) > 0 {
+		last = <-progress
+	}
+	// two of the six items did not play to the end
+	assert.Equal(t, importer.ImportProgress{
+		Filename:  filepath.Base(dest),
+		Processed: 6,
+		Imported:  4,
+		Skipped:   2,
+		Total:     6,
+		Percent:   100,
+	}, last)
+
+	// nothing receiving the updates does not hold up the import
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	require.NoError(t, importer.ImportSpotifyFileWithProgress(ctx, newTestDB(), &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 1, make(chan importer.ImportProgress)))
+}
+
+func TestImportSpotify_DryRun(t *testing.T) {
+	store := newTestDB()
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_split_play_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_dry_run_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	defer os.Remove(dest)
+	ctx := logger.NewContext(logger.Get())
+
+	summary, err := importer.ImportSpotifyFileDryRun(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), 1)
+	require.NoError(t, err)
+	assert.Equal(t, importer.DryRunSummary{Listens: 4, NewArtists: 1, NewAlbums: 1}, summary)
+
+	// nothing is saved
+	count, err := store.CountListens(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+	_, err = store.GetArtist(ctx, db.GetArtistOpts{Name: "Split Artist"})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// the artist and album exist after the real import
+	require.NoError(t, importer.ImportSpotifyFile(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", 1))
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+	summary, err = importer.ImportSpotifyFileDryRun(ctx, store, &mbz.MbzErrorCaller{}, filepath.Base(dest), 1)
+	require.NoError(t, err)
+	assert.Equal(t, importer.DryRunSummary{Listens: 4}, summary)
+}
+
+func TestImportSpotify_IgnoreBelowMs(t *testing.T) {
+	store := newTestDB()
+	cfg.SetImportIgnoreBelowMs(200000)
+	defer cfg.SetImportIgnoreBelowMs(0)
+
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_spotify_import_test.json")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	dest := filepath.Join(destDir, "Streaming_History_Audio_spotify_import_test.json")
+
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	// the only trackdone item was played for 181028 ms, so it is ignored
+	_, err = store.GetArtist(context.Background(), db.GetArtistOpts{Name: "The Story So Far"})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+}
+
+func TestImportSpotify_UserImportSettings(t *testing.T) {
+	ctx := context.Background()
+	src := path.Join("..", "test_assets", "Streaming_History_Audio_split_play_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "Streaming_History_Audio_split_play_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+
+	gap := 60
+	settings := db.ImportSettings{
+		MergeGapSeconds: &gap,
+		ReasonEnds:      []string{"trackdone", "endplay"},
+	}
+
+	// listens are unique by track and time, so each user imports into their own database
+	importAs := func(userID int32) *sqlite.Sqlite {
+		store := newTestDB()
+		require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('test2', 0x123)`))
+		require.NoError(t, store.SaveImportSettings(ctx, 2, settings))
+		require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+		require.NoError(t, importer.ImportSpotifyFile(logger.NewContext(logger.Get()), store, &mbz.MbzErrorCaller{}, filepath.Base(dest), "", userID))
+		return store
+	}
+	countListens := func(store *sqlite.Sqlite, userID int32) int {
+		count, err := store.Count(`SELECT COUNT(*) FROM listens WHERE user_id = ?`, userID)
+		require.NoError(t, err)
+		return count
+	}
+
+	// user 1 has no settings, so every finished item is a listen
+	store := importAs(1)
+	assert.Equal(t, 4, countListens(store, 1))
+
+	// user 2 merges the split and resumed plays, and the resumed play counts since endplay is accepted
+	store = importAs(2)
+	assert.Equal(t, 4, countListens(store, 2))
+	saved, err := store.GetImportSettings(ctx, 2)
+	require.NoError(t, err)
+	require.NotNil(t, saved.MergeGapSeconds)
+	assert.Equal(t, 60, *saved.MergeGapSeconds)
+	assert.Nil(t, saved.IgnoreBelowMs)
+	assert.Equal(t, []string{"trackdone", "endplay"}, saved.ReasonEnds)
+}
+
+func TestImportScrobblerLog(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "rockbox.scrobbler.log")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	dest := filepath.Join(destDir, "rockbox.scrobbler.log")
+
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	a, err := store.GetArtist(context.Background(), db.GetArtistOpts{Name: "Kikuo"})
+	require.NoError(t, err)
+	// the skipped (S) row and the row without an artist are not imported
+	assert.EqualValues(t, 3, a.ListenCount)
+
+	r, err := store.GetAlbum(context.Background(), db.GetAlbumOpts{ArtistID: a.ID, Title: "Kikuo Miku 4"})
+	require.NoError(t, err)
+	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{Title: "Aishite Aishite Aishite", ReleaseID: r.ID, ArtistIDs: []int32{a.ID}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 204, track.Duration)
+
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{Client: "rockbox"})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 3)
+	assert.WithinDuration(t, time.Unix(1717200900, 0), listens.Items[0].Time, 1*time.Second)
+}
+
+func TestImportYouTubeMusicTakeout(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "watch-history.json")
+	destDir := filepath.Join(cfg.ConfigDir(), "import")
+	dest := filepath.Join(destDir, "watch-history.json")
+
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	// the artist comes from the Topic channel for one item, and from the video title for the other
+	a, err := store.GetArtist(context.Background(), db.GetArtistOpts{Name: "Kikuo"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, a.ListenCount)
+	_, err = store.GetArtist(context.Background(), db.GetArtistOpts{Name: "Kikuo - Topic"})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// the regular YouTube video and the removed video are not imported
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{Client: "youtube-music"})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 2)
+	assert.Equal(t, "Aishite Aishite Aishite", listens.Items[0].Track.Title)
+	assert.Equal(t, "Kimi wa Dekinai Ko", listens.Items[1].Track.Title)
+	assert.WithinDuration(t, time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC), listens.Items[0].Time, 1*time.Second)
+
+	count, err := store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
 }
 
 func TestImportLastFM(t *testing.T) {
@@ -101,7 +598,7 @@ func TestImportLastFM(t *testing.T) {
 	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{Title: "放課後の記憶", ReleaseID: album.ID, ArtistIDs: []int32{artist.ID}})
 	require.NoError(t, err)
 	t.Log(track)
-	listens, err := store.GetListensPaginated(context.Background(), db.GetItemsOpts{TrackID: int(track.ID), Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{TrackID: track.ID, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	require.Len(t, listens.Items, 1)
 	assert.WithinDuration(t, time.Unix(1749774900, 0), listens.Items[0].Time, 1*time.Second)
@@ -131,12 +628,36 @@ func TestImportLastFM_MbzDisabled(t *testing.T) {
 	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{Title: "放課後の記憶", ReleaseID: album.ID, ArtistIDs: []int32{artist.ID}})
 	require.NoError(t, err)
 	t.Log(track)
-	listens, err := store.GetListensPaginated(context.Background(), db.GetItemsOpts{TrackID: int(track.ID), Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{TrackID: track.ID, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	require.Len(t, listens.Items, 1)
 	assert.WithinDuration(t, time.Unix(1749774900, 0), listens.Items[0].Time, 1*time.Second)
 }
 
+func TestImportLastFMCSV(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "lastfm_scrobbles_test.csv")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "lastfm_scrobbles_test.csv")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	// the now playing row and the row with a malformed timestamp are skipped
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	a, err := store.GetArtist(context.Background(), db.GetArtistOpts{Name: "CSV Artist"})
+	require.NoError(t, err)
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{ArtistID: a.ID, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 2)
+	assert.WithinDuration(t, time.Date(2021, time.February, 1, 8, 0, 0, 0, time.UTC), listens.Items[0].Time, time.Second)
+	assert.WithinDuration(t, time.Date(2021, time.January, 31, 10, 15, 0, 0, time.UTC), listens.Items[1].Time, time.Second)
+}
+
 func TestImportListenBrainz(t *testing.T) {
 	store := newTestDB()
 
@@ -188,7 +709,7 @@ func TestImportListenBrainz(t *testing.T) {
 	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{MusicBrainzID: uuid.MustParse("08e8f55b-f1a4-46b8-b2d1-fab4c592165c")})
 	require.NoError(t, err)
 	assert.Equal(t, "Desert", track.Title)
-	listens, err := store.GetListensPaginated(context.Background(), db.GetItemsOpts{TrackID: int(track.ID), Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{TrackID: track.ID, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	assert.Len(t, listens.Items, 1)
 	assert.WithinDuration(t, time.Unix(1749780612, 0), listens.Items[0].Time, 1*time.Second)
@@ -222,7 +743,7 @@ func TestImportListenBrainz_MbzDisabled(t *testing.T) {
 	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{MusicBrainzID: uuid.MustParse("08e8f55b-f1a4-46b8-b2d1-fab4c592165c")})
 	require.NoError(t, err)
 	assert.Equal(t, "Desert", track.Title)
-	listens, err := store.GetListensPaginated(context.Background(), db.GetItemsOpts{TrackID: int(track.ID), Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	listens, err := store.GetListens(context.Background(), db.GetListensOpts{TrackID: track.ID, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	assert.Len(t, listens.Items, 1)
 	assert.WithinDuration(t, time.Unix(1749780612, 0), listens.Items[0].Time, 1*time.Second)
@@ -252,6 +773,28 @@ func TestImportListenBrainz_MBIDMapping(t *testing.T) {
 	assert.Equal(t, "Zombie", track.Title)
 }
 
+func TestImportListenBrainz_JSONDump(t *testing.T) {
+	store := newTestDB()
+
+	src := path.Join("..", "test_assets", "listenbrainz_dump_test.json")
+	dest := filepath.Join(cfg.ConfigDir(), "import", "listenbrainz_dump_test.json")
+	input, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dest, input, os.ModePerm))
+
+	engine.RunImporter(logger.Get(), store, &mbz.MbzErrorCaller{})
+
+	count, err := store.CountListens(context.Background(), db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+	// the recording MBID of the mapped listen is kept
+	track, err := store.GetTrack(context.Background(), db.GetTrackOpts{MusicBrainzID: uuid.MustParse("cb9c8de2-bc23-49a9-a476-9bcf9aae086e")})
+	require.NoError(t, err)
+	assert.Equal(t, "March of the Krotites", track.Title)
+	_, err = os.Stat(filepath.Join(cfg.ConfigDir(), "import_complete", "listenbrainz_dump_test.json"))
+	assert.NoError(t, err)
+}
+
 func TestImportKoito(t *testing.T) {
 	store := newTestDB()
 
@@ -291,6 +834,13 @@ func TestImportKoito(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "鈴木雅之", artist.Name)
 	assert.Contains(t, artist.Aliases, "Masayuki Suzuki")
+	aliases, err := store.GetAllArtistAliases(ctx, artist.ID)
+	require.NoError(t, err)
+	for _, a := range aliases {
+		if a.Alias == "Masayuki Suzuki" {
+			assert.Equal(t, models.AliasSourceImport, a.Source)
+		}
+	}
 	_, err = store.GetArtist(ctx, db.GetArtistOpts{Name: "すぅ"})
 	require.NoError(t, err)
 
@@ -303,7 +853,7 @@ func TestImportKoito(t *testing.T) {
 	require.NoError(t, err)
 	album, err = store.GetAlbum(ctx, db.GetAlbumOpts{Title: "虹の色よ鮮やかであれ (NELKE ver.)", ArtistID: artist.ID})
 	require.NoError(t, err)
-	aliases, err := store.GetAllAlbumAliases(ctx, album.ID)
+	aliases, err = store.GetAllAlbumAliases(ctx, album.ID)
 	require.NoError(t, err)
 	assert.Contains(t, utils.FlattenAliases(aliases), "Nijinoiroyo Azayakadeare (NELKE ver.)")
 	// ensure album associations are saved
@@ -330,13 +880,13 @@ func TestImportKoito(t *testing.T) {
 	_, err = store.GetTrack(ctx, db.GetTrackOpts{Title: "GIRI GIRI", ReleaseID: album.ID, ArtistIDs: []int32{artist.ID}})
 	require.NoError(t, err)
 
-	count, err := store.CountTracks(ctx, db.Timeframe{Period: db.PeriodAllTime})
+	count, err := store.CountTracks(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	assert.EqualValues(t, 4, count)
-	count, err = store.CountAlbums(ctx, db.Timeframe{Period: db.PeriodAllTime})
+	count, err = store.CountAlbums(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	assert.EqualValues(t, 3, count)
-	count, err = store.CountArtists(ctx, db.Timeframe{Period: db.PeriodAllTime})
+	count, err = store.CountArtists(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	assert.EqualValues(t, 6, count)
 }
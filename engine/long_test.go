@@ -3,11 +3,14 @@ package engine_test
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"strconv"
@@ -17,6 +20,7 @@ import (
 	"time"
 
 	"github.com/gabehf/koito/engine/handlers"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/db/sqlite"
@@ -57,16 +61,17 @@ func makeAuthRequest(t *testing.T, session, method, endpoint string, body io.Rea
 	return http.DefaultClient.Do(req)
 }
 
-// Expects a valid session
+// Expects a valid session. Keys are saved hashed and cannot be listed, so a new one is created.
 func getApiKey(t *testing.T, session string) {
 	apikeyOnce.Do(func() {
-		resp, err := makeAuthRequest(t, session, "GET", "/apis/web/v1/user/apikeys", nil)
+		resp, err := makeAuthRequest(t, session, "POST", "/apis/web/v1/user/apikeys", strings.NewReader(`{"label":"tests"}`))
 		require.NoError(t, err)
-		var keys []models.ApiKey
-		err = json.NewDecoder(resp.Body).Decode(&keys)
+		require.Equal(t, 201, resp.StatusCode)
+		var key models.ApiKey
+		err = json.NewDecoder(resp.Body).Decode(&key)
 		require.NoError(t, err)
-		require.GreaterOrEqual(t, len(keys), 1)
-		apikey = keys[0].Key
+		require.NotEmpty(t, key.Key)
+		apikey = key.Key
 	})
 }
 
@@ -76,7 +81,7 @@ func getApiKey(t *testing.T, session string) {
 // deletes releases, which cascade to release_aliases, tracks, and track_aliases.
 func truncateTestData(t *testing.T) {
 	t.Helper()
-	require.NoError(t, store.Exec("DELETE FROM listens"))
+	require.NoError(t, store.Exec("DELETE FROM all_listens"))
 	require.NoError(t, store.Exec("DELETE FROM artists"))
 	// Belt-and-suspenders: delete any releases the trigger may have missed
 	// (e.g. releases that were never associated with an artist).
@@ -325,6 +330,55 @@ func TestGetters(t *testing.T) {
 	truncateTestData(t)
 }
 
+func TestGetListens_Filters(t *testing.T) {
+	truncateTestData(t)
+	t.Run("Submit Listens", doSubmitListens)
+
+	// a second listen to track 1 from another client
+	unix := time.Now().Add(-30 * time.Minute).Unix()
+	resp, err := makeAuthRequest(t, session, "POST", "/apis/web/v1/listens", strings.NewReader(fmt.Sprintf(`{"track_id":1,"unix":%d,"client":"web"}`, unix)))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	from := time.Now().Add(-90 * time.Minute).Unix()
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"period=all_time", 4},
+		{"period=all_time&artist_id=1", 2},
+		{"period=all_time&album_id=1", 2},
+		{"period=all_time&track_id=2", 1},
+		{"period=all_time&client=web", 1},
+		{"period=all_time&artist_id=1&client=navidrome", 1},
+		{"period=all_time&artist_id=1&track_id=2", 0},
+		{fmt.Sprintf("from=%d", from), 3},
+		{fmt.Sprintf("from=%d&track_id=1", from), 1},
+		{fmt.Sprintf("from=%d&artist_id=1&client=navidrome", from), 0},
+	}
+	for _, tt := range tests {
+		resp, err := http.DefaultClient.Get(host() + "/apis/web/v1/listens?" + tt.query)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, tt.query)
+		var listens db.PaginatedResponse[models.Listen]
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&listens))
+		resp.Body.Close()
+		assert.Len(t, listens.Items, tt.expected, tt.query)
+		assert.EqualValues(t, tt.expected, listens.TotalCount, tt.query)
+	}
+
+	// filters are paginated together
+	resp, err = http.DefaultClient.Get(host() + "/apis/web/v1/listens?period=all_time&artist_id=1&limit=1&page=2")
+	require.NoError(t, err)
+	var listens db.PaginatedResponse[models.Listen]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listens))
+	require.Len(t, listens.Items, 1)
+	assert.EqualValues(t, 1, listens.Items[0].Track.ID)
+	assert.False(t, listens.HasNextPage)
+
+	truncateTestData(t)
+}
+
 func TestMerge(t *testing.T) {
 	truncateTestData(t)
 	t.Run("Submit Listens", doSubmitListens)
@@ -340,6 +394,21 @@ func TestMerge(t *testing.T) {
 	require.NoError(t, err)
 	assert.EqualValues(t, 2, track.ListenCount)
 
+	// recounts the kept track right after the merge
+	resp, err = makeAuthRequest(t, session, "POST", "/apis/web/v1/track/2/recount", nil)
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	var counts catalog.EntityCounts
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&counts))
+	assert.EqualValues(t, 2, counts.ListenCount)
+	assert.Equal(t, track.TimeListened, counts.TimeListened)
+	resp, err = makeAuthRequest(t, session, "POST", "/apis/web/v1/track/1/recount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+	resp, err = http.DefaultClient.Post(host()+"/apis/web/v1/track/2/recount", "application/json", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode)
+
 	truncateTestData(t)
 
 	t.Run("Submit Listens", doSubmitListens)
@@ -522,6 +591,21 @@ func TestAliasesAndSearch(t *testing.T) {
 		{ID: 1, Alias: "Sayuri", Source: "Manual", Primary: false},
 	}, actual)
 
+	// filter by where the aliases came from
+	resp, err = http.DefaultClient.Get(host() + "/apis/web/v1/artist/1/aliases?source=manual")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	actual = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	assert.Equal(t, []models.Alias{{ID: 1, Alias: "Sayuri", Source: "Manual", Primary: false}}, actual)
+
+	resp, err = http.DefaultClient.Get(host() + "/apis/web/v1/artist/1/aliases?source=MusicBrainz,Import")
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	actual = nil
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+	assert.Empty(t, actual)
+
 	resp, err = makeAuthRequest(t, session, "POST", "/apis/web/v1/album/1/aliases", strings.NewReader(`{"alias":"Sanketsu Girl"}`))
 	require.NoError(t, err)
 	require.Equal(t, 201, resp.StatusCode)
@@ -609,6 +693,102 @@ func TestStats(t *testing.T) {
 	assert.Contains(t, []int{1, 2}, actual.DaysActive)
 }
 
+func TestStats_PrivateListens(t *testing.T) {
+	truncateTestData(t)
+	t.Run("Submit Listens", doSubmitListens)
+
+	// the listen from two hours ago is made private
+	now := time.Now()
+	resp, err := makeAuthRequest(t, session, "PATCH", fmt.Sprintf("/apis/web/v1/listens/private?from=%d&to=%d",
+		now.Add(-3*time.Hour).Unix(), now.Add(-90*time.Minute).Unix()), nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// stats are cached by query, so the timeframe is unique to this test
+	statsQuery := fmt.Sprintf("/apis/web/v1/stats?from=%d", now.Add(-24*time.Hour).Unix())
+	tests := []struct {
+		endpoint string
+		shared   int
+		owner    int
+	}{
+		{statsQuery, 2, 3},
+		{"/apis/web/v1/listens?period=all_time", 2, 3},
+		{"/apis/web/v1/top/tracks?period=all_time", 2, 3},
+		{"/apis/web/v1/top/albums?period=all_time", 2, 3},
+		{"/apis/web/v1/top/artists?period=all_time", 2, 3},
+	}
+	count := func(resp *http.Response) int {
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var body struct {
+			ListenCount int `json:"listen_count"`
+			TotalCount  int `json:"total_record_count"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body.ListenCount + body.TotalCount
+	}
+	for _, tt := range tests {
+		// others do not see the private listen
+		resp, err := http.DefaultClient.Get(host() + tt.endpoint)
+		require.NoError(t, err)
+		assert.Equal(t, tt.shared, count(resp), tt.endpoint)
+
+		// its owner does
+		resp, err = makeAuthRequest(t, session, "GET", tt.endpoint, nil)
+		require.NoError(t, err)
+		assert.Equal(t, tt.owner, count(resp), tt.endpoint)
+	}
+
+	truncateTestData(t)
+}
+
+func TestGetListensByDevice_Users(t *testing.T) {
+	truncateTestData(t)
+	t.Run("Submit Listens", doSubmitListens)
+	ctx := context.Background()
+
+	listens, err := store.GetListens(ctx, db.GetListensOpts{UserID: 1, Limit: 1, Page: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, listens.Items)
+	trackID := listens.Items[0].Track.ID
+
+	other, _, err := catalog.CreateUser(ctx, store, db.SaveUserOpts{
+		Username: "devices",
+		Password: "devices123",
+		Role:     models.UserRoleUser,
+	}, "Default")
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = store.SaveListen(ctx, db.SaveListenOpts{TrackID: trackID, Time: now.Add(-30 * time.Minute), UserID: other.ID, Device: "phone"})
+	require.NoError(t, err)
+	_, err = store.SaveListen(ctx, db.SaveListenOpts{TrackID: trackID, Time: now.Add(-20 * time.Minute), UserID: 1, Device: "laptop", Private: true})
+	require.NoError(t, err)
+
+	devices := func(resp *http.Response) map[string]int64 {
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var body []db.DeviceListenCount
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		ret := make(map[string]int64)
+		for _, d := range body {
+			ret[d.Device] = d.Listens
+		}
+		return ret
+	}
+
+	// a signed in user only sees their own devices, including those of their private listens
+	resp, err := makeAuthRequest(t, session, "GET", "/apis/web/v1/listens/devices?period=all_time", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"": 3, "laptop": 1}, devices(resp))
+
+	// others see every user's devices, without private listens
+	resp, err = http.DefaultClient.Get(host() + "/apis/web/v1/listens/devices?period=all_time")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"": 3, "phone": 1}, devices(resp))
+
+	truncateTestData(t)
+}
+
 func TestListenActivity(t *testing.T) {
 
 	// this test fails when run a bit after midnight
@@ -693,11 +873,10 @@ func TestAuth(t *testing.T) {
 	resp, err = http.DefaultClient.Do(req)
 	require.NoError(t, err)
 	require.Equal(t, 201, resp.StatusCode)
-	var response struct {
-		Key string `json:"key"}`
-	}
+	var response models.ApiKey
 	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
 	require.NotEmpty(t, response.Key)
+	assert.Equal(t, response.Key[len(response.Key)-4:], response.Hint)
 
 	// validates api key
 	req, err = http.NewRequest("GET", host()+"/apis/listenbrainz/1/validate-token", nil)
@@ -716,7 +895,7 @@ func TestAuth(t *testing.T) {
 
 	// changes api key label
 	login(t) // i dont care about using the new session anymore
-	resp, err = makeAuthRequest(t, s, "PATCH", "/apis/web/v1/user/apikeys/2", strings.NewReader(`{"label":"well tested"}`))
+	resp, err = makeAuthRequest(t, s, "PATCH", fmt.Sprintf("/apis/web/v1/user/apikeys/%d", response.ID), strings.NewReader(`{"label":"well tested"}`))
 	require.NoError(t, err)
 	assert.Equal(t, 204, resp.StatusCode)
 	resp, err = makeAuthRequest(t, s, "GET", "/apis/web/v1/user/apikeys", nil)
@@ -725,8 +904,31 @@ func TestAuth(t *testing.T) {
 	err = json.NewDecoder(resp.Body).Decode(&keys)
 	require.NoError(t, err)
 	require.GreaterOrEqual(t, len(keys), 2)
-	require.NotNil(t, keys[1].Label)
-	assert.Equal(t, "well tested", keys[1].Label)
+	found := false
+	for _, k := range keys {
+		// keys are saved hashed, so listing them never returns the key itself
+		assert.Empty(t, k.Key)
+		if k.ID == response.ID {
+			found = true
+			assert.Equal(t, "well tested", k.Label)
+			assert.Equal(t, response.Hint, k.Hint)
+		}
+	}
+	assert.True(t, found)
+
+	// revokes the api key, which can no longer be used
+	resp, err = makeAuthRequest(t, s, "DELETE", fmt.Sprintf("/apis/web/v1/user/apikeys/%d", response.ID), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 204, resp.StatusCode)
+	resp, err = makeAuthRequest(t, s, "DELETE", fmt.Sprintf("/apis/web/v1/user/apikeys/%d", response.ID), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+	req, err = http.NewRequest("GET", host()+"/apis/listenbrainz/1/validate-token", nil)
+	require.NoError(t, err)
+	req.Header.Add("Authorization", fmt.Sprintf("Token %s", response.Key))
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, 401, resp.StatusCode)
 
 	// logs out
 	req, err = http.NewRequest("POST", host()+"/apis/web/v1/logout", nil)
@@ -803,6 +1005,11 @@ func TestDeleteListen(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 204, resp.StatusCode)
 
+	// the user has no listen at this time
+	resp, err = makeAuthRequest(t, session, "DELETE", "/apis/web/v1/listens?track_id=1&unix=1749475720", nil)
+	require.NoError(t, err)
+	require.Equal(t, 404, resp.StatusCode)
+
 	// listen is deleted
 	resp, err = http.DefaultClient.Get(host() + "/apis/web/v1/track/1")
 	require.NoError(t, err)
@@ -1111,6 +1318,9 @@ func TestNowPlaying(t *testing.T) {
 		]
 	}`
 
+	listensBefore, err := store.Count(`SELECT COUNT(*) FROM all_listens`)
+	require.NoError(t, err)
+
 	req, err := http.NewRequest("POST", host()+"/apis/listenbrainz/1/submit-listens", strings.NewReader(body))
 	require.NoError(t, err)
 	req.Header.Add("Authorization", fmt.Sprintf("Token %s", apikey))
@@ -1127,4 +1337,216 @@ func TestNowPlaying(t *testing.T) {
 	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
 	require.True(t, result.CurrentlyPlaying)
 	require.Equal(t, "花の塔", result.Track.Title)
+
+	// a playing_now submission is not a listen
+	listensAfter, err := store.Count(`SELECT COUNT(*) FROM all_listens`)
+	require.NoError(t, err)
+	assert.Equal(t, listensBefore, listensAfter)
+}
+
+func TestLastFMScrobble(t *testing.T) {
+	truncateTestData(t)
+
+	call := func(params url.Values) (int, map[string]any) {
+		params.Set("format", "json")
+		resp, err := http.DefaultClient.PostForm(host()+"/apis/lastfm/2.0/", params)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return resp.StatusCode, result
+	}
+
+	// wrong password
+	status, result := call(url.Values{"method": {"auth.getMobileSession"}, "username": {"test"}, "password": {"wrong"}})
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.EqualValues(t, 4, result["error"])
+
+	status, result = call(url.Values{"method": {"auth.getMobileSession"}, "username": {"test"}, "password": {"testuser123"}})
+	require.Equal(t, http.StatusOK, status)
+	sk, _ := result["session"].(map[string]any)["key"].(string)
+	require.NotEmpty(t, sk)
+
+	// logging in with the session key, or any other API key, reuses it instead of saving a new one
+	keys, err := store.Count(`SELECT COUNT(*) FROM api_keys`)
+	require.NoError(t, err)
+	status, result = call(url.Values{"method": {"auth.getMobileSession"}, "username": {"test"}, "password": {sk}})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, sk, result["session"].(map[string]any)["key"])
+	keysAfter, err := store.Count(`SELECT COUNT(*) FROM api_keys`)
+	require.NoError(t, err)
+	assert.Equal(t, keys, keysAfter)
+
+	// credentials are not accepted in the query string, nor over GET
+	resp, err := http.DefaultClient.PostForm(host()+"/apis/lastfm/2.0/?"+url.Values{"username": {"test"}, "password": {"testuser123"}}.Encode(),
+		url.Values{"method": {"auth.getMobileSession"}, "format": {"json"}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, err = http.DefaultClient.Get(host() + "/apis/lastfm/2.0/?method=track.updateNowPlaying&sk=" + sk + "&artist=Artist&track=Track")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	status, result = call(url.Values{"method": {"track.scrobble"}, "sk": {"invalid"}, "artist": {"Artist"}, "track": {"Track"}, "timestamp": {"1700000000"}})
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.EqualValues(t, 9, result["error"])
+
+	// the second scrobble is a duplicate of the first
+	status, result = call(url.Values{
+		"method":       {"track.scrobble"},
+		"sk":           {sk},
+		"artist[0]":    {"Last.fm Artist"},
+		"track[0]":     {"Last.fm Track"},
+		"album[0]":     {"Last.fm Album"},
+		"timestamp[0]": {"1700000000"},
+		"artist[1]":    {"Last.fm Artist"},
+		"track[1]":     {"Last.fm Track"},
+		"album[1]":     {"Last.fm Album"},
+		"timestamp[1]": {"1700000005"},
+		"artist[2]":    {"Last.fm Artist"},
+		"track[2]":     {"Other Track"},
+		"timestamp[2]": {"1700000300"},
+	})
+	require.Equal(t, http.StatusOK, status)
+	attr := result["scrobbles"].(map[string]any)["@attr"].(map[string]any)
+	assert.EqualValues(t, 2, attr["accepted"])
+	assert.EqualValues(t, 1, attr["ignored"])
+	count, err := store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	status, _ = call(url.Values{"method": {"track.updateNowPlaying"}, "sk": {sk}, "artist": {"Last.fm Artist"}, "track": {"Playing Track"}})
+	require.Equal(t, http.StatusOK, status)
+	resp, err = http.DefaultClient.Get(host() + "/apis/web/v1/now-playing")
+	require.NoError(t, err)
+	var playing handlers.NowPlayingResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&playing))
+	require.True(t, playing.CurrentlyPlaying)
+	assert.Equal(t, "Playing Track", playing.Track.Title)
+
+	// signatures are checked once a shared secret is set
+	cfg.SetLastFMScrobbleSecret("secret")
+	defer cfg.SetLastFMScrobbleSecret("")
+	params := url.Values{"method": {"track.updateNowPlaying"}, "sk": {sk}, "artist": {"Last.fm Artist"}, "track": {"Playing Track"}, "api_sig": {"bad"}}
+	status, result = call(params)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.EqualValues(t, 13, result["error"])
+	// method, sk, artist and track sorted by name, then the secret
+	sum := md5.Sum([]byte("artistLast.fm Artistmethodtrack.updateNowPlayingsk" + sk + "trackPlaying Tracksecret"))
+	params.Set("api_sig", hex.EncodeToString(sum[:]))
+	status, _ = call(params)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestListensETag(t *testing.T) {
+	truncateTestData(t)
+	t.Run("Submit Listens", doSubmitListens)
+
+	get := func(endpoint, etag string) *http.Response {
+		req, err := http.NewRequest("GET", host()+endpoint, nil)
+		require.NoError(t, err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, endpoint := range []string{"/apis/web/v1/listens?period=all_time", "/apis/web/v1/listens/feed"} {
+		resp := get(endpoint, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		etag := resp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+
+		// nothing changed
+		resp = get(endpoint, etag)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, endpoint)
+		resp = get(endpoint, `"bogus", `+etag)
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode, endpoint)
+	}
+
+	etag := get("/apis/web/v1/listens?period=all_time", "").Header.Get("ETag")
+	// a listen older than the latest one still changes the ETag
+	unix := strconv.FormatInt(time.Now().Add(-24*time.Hour).Unix(), 10)
+	resp, err := makeAuthRequest(t, session, "POST", "/apis/web/v1/listens", strings.NewReader(`{"track_id":1,"unix":`+unix+`}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	resp = get("/apis/web/v1/listens?period=all_time", etag)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+}
+
+func TestImportUpload(t *testing.T) {
+	truncateTestData(t)
+	ctx := context.Background()
+
+	// a second user, whose imported listens must not be saved for the default user
+	user, key, err := catalog.CreateUser(ctx, store, db.SaveUserOpts{
+		Username: "importer",
+		Password: "importer123",
+		Role:     models.UserRoleUser,
+	}, "Default")
+	require.NoError(t, err)
+
+	upload := func(name string, content []byte, token string) *http.Response {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", name)
+		require.NoError(t, err)
+		_, err = fw.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, mw.Close())
+		req, err := http.NewRequest("POST", host()+"/apis/web/v1/import", &body)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if token != "" {
+			req.Header.Set("Authorization", "Token "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	input, err := os.ReadFile(path.Join("..", "test_assets", "maloja_import_test.json"))
+	require.NoError(t, err)
+
+	resp := upload("maloja_upload_test.json", input, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = upload("maloja_upload_test.json", input, key.Key)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	count, err := store.Count(`SELECT COUNT(*) FROM listens WHERE user_id = ?`, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 38, count)
+	count, err = store.Count(`SELECT COUNT(*) FROM listens WHERE user_id = 1`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// the same file is refused the second time
+	resp = upload("maloja_upload_test.json", input, key.Key)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// unrecognized files are refused and not left in the import directory
+	resp = upload("unknown.json", []byte(`{}`), key.Key)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_, err = os.Stat(path.Join(cfg.ConfigDir(), "import", "unknown.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDefaultApiKeyFile(t *testing.T) {
+	// the default user's key is saved to a file readable only by its owner, instead of being logged
+	keyFile := path.Join(cfg.ConfigDir(), "default_api_key")
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	key, err := os.ReadFile(keyFile)
+	require.NoError(t, err)
+	user, err := catalog.GetUserByAPIKey(context.Background(), store, strings.TrimSpace(string(key)))
+	require.NoError(t, err)
+	assert.Equal(t, cfg.DefaultUsername(), user.Username)
 }
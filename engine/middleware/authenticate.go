@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
@@ -61,6 +62,15 @@ func Authenticate(store db.UserStore, mode AuthMode) func(http.Handler) http.Han
 						user, err = validateAPIKey(ctx, store, r)
 					}
 				} else {
+					// anyone may read without the login gate, but a signed in user is still resolved
+					// so that they see their own private listens
+					user, err = validateSession(ctx, store, r)
+					if err != nil || user == nil {
+						user, _ = validateAPIKey(ctx, store, r)
+					}
+					if user != nil {
+						r = r.WithContext(context.WithValue(ctx, UserContextKey, user))
+					}
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -145,15 +155,15 @@ func validateAPIKey(ctx context.Context, store db.UserStore, r *http.Request) (*
 		return nil, errors.New("authorization header is invalid")
 	}
 
-	u, err := store.GetUserByApiKey(ctx, token)
+	u, err := catalog.GetUserByAPIKey(ctx, store, token)
+	if errors.Is(err, db.ErrNotFound) {
+		l.Debug().Msg("ValidateApiKey: API key does not exist")
+		return nil, errors.New("authorization token is invalid")
+	}
 	if err != nil {
 		l.Err(err).Msg("ValidateApiKey: Failed to get user from database using api key")
 		return nil, errors.New("internal server error")
 	}
-	if u == nil {
-		l.Debug().Msg("ValidateApiKey: API key does not exist")
-		return nil, errors.New("authorization token is invalid")
-	}
 
 	ctx = context.WithValue(r.Context(), UserContextKey, u)
 	r = r.WithContext(ctx)
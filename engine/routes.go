@@ -11,6 +11,7 @@ import (
 
 	"github.com/gabehf/koito/engine/handlers"
 	"github.com/gabehf/koito/engine/middleware"
+	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	mbz "github.com/gabehf/koito/internal/mbz"
@@ -25,6 +26,7 @@ func bindRoutes(
 	ready *atomic.Bool,
 	db db.DB,
 	mbz mbz.MusicBrainzCaller,
+	backfill *catalog.ImageBackfill,
 ) {
 	if !(len(cfg.AllowedOrigins()) == 0) && !(cfg.AllowedOrigins()[0] == "") {
 		r.Use(cors.Handler(cors.Options{
@@ -45,6 +47,7 @@ func bindRoutes(
 			r.Get("/artist/{id}", handlers.GetArtistHandler(db))                  // done
 			r.Get("/artist/{id}/aliases", handlers.GetArtistAliasesHandler(db))   // done
 			r.Get("/artist/{id}/interest", handlers.GetArtistInterestHandler(db)) // done
+			r.Get("/artist/{id}/top", handlers.GetArtistTopItemsHandler(db))
 
 			r.Get("/album/{id}", handlers.GetAlbumHandler(db))                   // done
 			r.Get("/album/{id}/artists", handlers.GetArtistsForAlbumHandler(db)) // done
@@ -55,16 +58,24 @@ func bindRoutes(
 			r.Get("/track/{id}/artists", handlers.GetArtistsForTrackHandler(db)) // done
 			r.Get("/track/{id}/aliases", handlers.GetTrackAliasesHandler(db))    // done
 			r.Get("/track/{id}/interest", handlers.GetTrackInterestHandler(db))  // done
+			r.Get("/track/{id}/audio-features", handlers.GetTrackAudioFeaturesHandler(db))
 
 			r.Get("/top/tracks", handlers.GetTopTracksHandler(db))
 			r.Get("/top/albums", handlers.GetTopAlbumsHandler(db))
 			r.Get("/top/artists", handlers.GetTopArtistsHandler(db))
+			r.Get("/artists/neglected", handlers.GetNeglectedArtistsHandler(db))
+			r.Get("/artists/favorites", handlers.GetFavoriteArtistsHandler(db))
 
 			r.Get("/listens", handlers.GetListensHandler(db))
+			r.Get("/listens/devices", handlers.GetListensByDeviceHandler(db))
+			r.Get("/listens/feed", handlers.GetListensFeedHandler(db))
+			r.Get("/listens/sessions", handlers.GetListeningSessionsHandler(db))
 			r.Get("/listen-activity", handlers.GetListenActivityHandler(db))
+			r.Get("/listen-activity/weekdays", handlers.GetListenCountsByWeekdayHandler(db))
 			r.Get("/first-activity", handlers.FirstActivityHandler(db))
 			r.Get("/now-playing", handlers.NowPlayingHandler(db))
 			r.Get("/stats", handlers.StatsHandler(db))
+			r.Get("/stats/audio-features", handlers.AverageAudioFeaturesHandler(db))
 			r.Get("/search", handlers.SearchHandler(db))
 			r.Get("/summary", handlers.SummaryHandler(db))
 		})
@@ -95,17 +106,26 @@ func bindRoutes(
 			r.Delete("/artist/{id}", handlers.DeleteArtistHandler(db))
 			r.Delete("/artist/{id}/aliases", handlers.DeleteArtistAliasHandler(db))
 			r.Post("/artist/{id}/merge", handlers.MergeArtistsHandler(db))
+			r.Post("/artist/{id}/recount", handlers.RecountHandler(db, catalog.EntityArtist))
 			r.Post("/artist/{id}/aliases", handlers.CreateArtistAliasHandler(db))
+			r.Post("/artist/{id}/tags", handlers.SaveArtistTagsHandler(db))
 			r.Patch("/artist/{id}", handlers.UpdateArtistHandler(db))
 			r.Patch("/artist/{id}/image", handlers.ReplaceArtistImageHandler(db))
+			r.Post("/artist/{id}/image/refresh", handlers.RefreshArtistImageHandler(db))
+			r.Get("/artist/{id}/image/candidates", handlers.GetArtistImageCandidatesHandler(db))
 			r.Patch("/artist/{id}/aliases/primary", handlers.SetPrimaryArtistAliasHandler(db))
+			r.Get("/artists/ambiguous-aliases", handlers.GetAmbiguousArtistAliasesHandler(db))
 
 			r.Delete("/album/{id}", handlers.DeleteAlbumHandler(db))
 			r.Delete("/album/{id}/aliases", handlers.DeleteAlbumAliasHandler(db))
 			r.Post("/album/{id}/merge", handlers.MergeAlbumsHandler(db))
+			r.Post("/album/{id}/recount", handlers.RecountHandler(db, catalog.EntityAlbum))
 			r.Post("/album/{id}/aliases", handlers.CreateAlbumAliasHandler(db))
 			r.Patch("/album/{id}", handlers.UpdateAlbumHandler(db))
 			r.Patch("/album/{id}/image", handlers.ReplaceAlbumImageHandler(db))
+			r.Post("/album/{id}/image/refresh", handlers.RefreshAlbumImageHandler(db))
+			r.Get("/album/{id}/image/candidates", handlers.GetAlbumImageCandidatesHandler(db))
+			r.Post("/album/{id}/image/release-group", handlers.AssignReleaseGroupImageHandler(db))
 			r.Patch("/album/{id}/aliases/primary", handlers.SetPrimaryAlbumAliasHandler(db))
 			r.Patch("/album/{id}/artists/{artist_id}", handlers.SetPrimaryAlbumArtistHandler(db))
 
@@ -113,7 +133,10 @@ func bindRoutes(
 			r.Delete("/track/{id}/aliases", handlers.DeleteTrackAliasHandler(db))
 			r.Delete("/track/{id}/artists/{artist_id}", handlers.DeleteTrackArtistHandler(db))
 			r.Post("/track/{id}/merge", handlers.MergeTracksHandler(db))
+			r.Post("/track/{id}/recount", handlers.RecountHandler(db, catalog.EntityTrack))
+			r.Post("/track/{id}/move", handlers.MoveTrackHandler(db))
 			r.Post("/track/{id}/aliases", handlers.CreateTrackAliasHandler(db))
+			r.Post("/track/{id}/tags", handlers.SaveTrackTagsHandler(db))
 			r.Post("/track/{id}/artists", handlers.AddTrackArtistsHandler(db))
 			r.Patch("/track/{id}", handlers.UpdateTrackHandler(db))
 			r.Patch("/track/{id}/aliases/primary", handlers.SetPrimaryTrackAliasHandler(db))
@@ -121,6 +144,12 @@ func bindRoutes(
 
 			r.Post("/listens", handlers.SubmitListenWithIDHandler(db))
 			r.Delete("/listens", handlers.DeleteListenHandler(db))
+			r.Delete("/listens/range", handlers.DeleteListensInRangeHandler(db))
+			r.Post("/listens/restore", handlers.RestoreListensHandler(db))
+			r.Patch("/listens/private", handlers.SetListensPrivateHandler(db))
+			r.Get("/listens/search", handlers.SearchListensHandler(db))
+			r.Get("/listens/duplicates", handlers.GetDuplicateListensHandler(db))
+			r.Delete("/listens/duplicates", handlers.DeleteDuplicateListensHandler(db))
 
 			r.Get("/user/apikeys", handlers.GetApiKeysHandler(db))
 			r.Post("/user/apikeys", handlers.GenerateApiKeyHandler(db))
@@ -128,9 +157,17 @@ func bindRoutes(
 			r.Delete("/user/apikeys/{id}", handlers.DeleteApiKeyHandler(db))
 
 			r.Get("/user", handlers.MeHandler())
+			r.Get("/user/compatibility", handlers.GetUserCompatibilityHandler(db))
+			r.Get("/user/year-in-review", handlers.YearInReviewHandler(db))
+			r.Get("/user/tag-stats", handlers.GetTagStatsHandler(db))
 			r.Patch("/user", handlers.UpdateUserHandler(db))
+			r.Get("/user/import-settings", handlers.GetImportSettingsHandler(db))
+			r.Put("/user/import-settings", handlers.UpdateImportSettingsHandler(db))
 
+			r.Get("/queues", handlers.GetQueueStatsHandler(backfill))
 			r.Get("/export", handlers.ExportHandler(db))
+			r.With(chimiddleware.RequestSize(1<<30)).
+				Post("/import", handlers.ImportHandler(uploadedFileImporter(db, mbz)))
 			r.Delete("/data", handlers.PurgeAllDataHandler(db))
 		})
 	})
@@ -147,6 +184,16 @@ func bindRoutes(
 			Get("/validate-token", handlers.LbzValidateTokenHandler())
 	})
 
+	r.Route("/apis/lastfm/2.0", func(r chi.Router) {
+		r.Use(cors.Handler(cors.Options{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"Content-Type"},
+		}))
+
+		// only POST is accepted, so that passwords and session keys are not left in query strings
+		r.Post("/", handlers.LastFMScrobbleHandler(db, mbz))
+	})
+
 	// serve react client
 	workDir, _ := os.Getwd()
 	filesDir := http.Dir(filepath.Join(workDir, "client/build/client"))
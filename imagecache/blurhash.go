@@ -0,0 +1,68 @@
+package imagecache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gabehf/koito/internal/images"
+	"github.com/google/uuid"
+)
+
+// the file in an image's cache directory that holds its BlurHash
+const blurHashFile = "blurhash.txt"
+
+// blurHashes remembers the BlurHash of each image that was looked up, including images without one,
+// so listing many images does not read the cache directory of each of them every time.
+var blurHashes sync.Map
+
+// BlurHash returns the BlurHash placeholder of the cached image, or an empty string if it has none.
+func BlurHash(imgid uuid.UUID) string {
+	if imgid == uuid.Nil {
+		return ""
+	}
+	if hash, ok := blurHashes.Load(imgid); ok {
+		return hash.(string)
+	}
+	b, err := os.ReadFile(blurHashPath(imgid))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ""
+	}
+	hash := strings.TrimSpace(string(b))
+	blurHashes.Store(imgid, hash)
+	return hash
+}
+
+// saveBlurHash computes the BlurHash of the image and saves it alongside the cached image.
+func saveBlurHash(imgid uuid.UUID, data []byte) error {
+	hash, err := images.ComputeBlurHash(data)
+	if err != nil {
+		return fmt.Errorf("saveBlurHash: %w", err)
+	}
+	if err := os.WriteFile(blurHashPath(imgid), []byte(hash), 0644); err != nil {
+		return fmt.Errorf("saveBlurHash: %w", err)
+	}
+	blurHashes.Store(imgid, hash)
+	return nil
+}
+
+// copyBlurHash gives an image copied from another the BlurHash of the original, if it has one.
+func copyBlurHash(from, to uuid.UUID) error {
+	hash := BlurHash(from)
+	if hash == "" {
+		return nil
+	}
+	if err := os.WriteFile(blurHashPath(to), []byte(hash), 0644); err != nil {
+		return fmt.Errorf("copyBlurHash: %w", err)
+	}
+	blurHashes.Store(to, hash)
+	return nil
+}
+
+func blurHashPath(imgid uuid.UUID) string {
+	return filepath.Join(filepath.Dir(BuildImagePath(imgid, ImageSizeSource)), blurHashFile)
+}
@@ -3,18 +3,21 @@ package imagecache
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/images"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/queue"
 	"github.com/google/uuid"
 	"github.com/h2non/bimg"
 	"golang.org/x/sync/singleflight"
@@ -56,26 +59,65 @@ func (s ImageSize) String() string {
 	return string(s)
 }
 
+var (
+	initOnce     sync.Once
+	downloadPool *queue.WorkerPool
+)
+
+// Initialize starts the worker pool used for image downloads. Downloads are run directly
+// on the calling goroutine until it is called.
+func Initialize(workers, rps int) {
+	initOnce.Do(func() {
+		downloadPool = queue.NewWorkerPool(workers, rps)
+	})
+}
+
+func Shutdown() {
+	if downloadPool != nil {
+		downloadPool.Shutdown()
+	}
+}
+
+// DownloadStats returns a snapshot of the image download pool.
+func DownloadStats() queue.Stats {
+	if downloadPool == nil {
+		return queue.Stats{}
+	}
+	return downloadPool.Stats()
+}
+
 // DownloadImage downloads an image from the given URL, then saves it to the cache at source quality.
+// The original is also kept, stored once per content, see images.CacheImage.
 func DownloadImage(imgid uuid.UUID, url string) error {
-	err := images.ValidateImageURL(url)
+	if downloadPool == nil {
+		return downloadImage(imgid, url)
+	}
+	return downloadPool.Do(context.Background(), func() error {
+		return downloadImage(imgid, url)
+	})
+}
+
+func downloadImage(imgid uuid.UUID, url string) error {
+	// the downloaded original is kept, so the image does not depend on the url staying available
+	path, err := images.CacheImage(context.Background(), url)
 	if err != nil {
 		return fmt.Errorf("DownloadImage: %w", err)
 	}
-	resp, err := http.Get(url)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("DownloadImage: http.Get: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("DownloadImage: failed to download image, status: %s", resp.Status)
+		return fmt.Errorf("DownloadImage: %w", err)
 	}
 
-	err = compressAndSaveImage(imgid, ImageSizeSource, resp.Body)
+	err = compressAndSaveImage(imgid, ImageSizeSource, bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("DownloadImage: %w", err)
 	}
+	if cfg.GenerateBlurHash() {
+		// a missing placeholder only affects how the image loads, so it does not fail the download
+		if err := saveBlurHash(imgid, data); err != nil {
+			logger.Get().Warn().Err(err).Msgf("DownloadImage: failed to generate placeholder for image %s", imgid)
+		}
+	}
 	return nil
 }
 
@@ -84,6 +126,22 @@ func SaveImage(imgid uuid.UUID, image io.Reader) error {
 	return compressAndSaveImage(imgid, ImageSizeSource, image)
 }
 
+// CopyImage saves a copy of the cached source image for one image id under another.
+func CopyImage(from, to uuid.UUID) error {
+	src, err := os.Open(BuildImagePath(from, ImageSizeSource))
+	if err != nil {
+		return fmt.Errorf("CopyImage: %w", err)
+	}
+	defer src.Close()
+	if err := saveImage(to, ImageSizeSource, src); err != nil {
+		return fmt.Errorf("CopyImage: %w", err)
+	}
+	if err := copyBlurHash(from, to); err != nil {
+		return fmt.Errorf("CopyImage: %w", err)
+	}
+	return nil
+}
+
 type ImageInfo struct {
 	Path string
 	Mime string
@@ -227,6 +285,7 @@ func DeleteImage(filename uuid.UUID) error {
 	if err != nil {
 		return fmt.Errorf("DeleteImage: %w", err)
 	}
+	blurHashes.Delete(filename)
 
 	return nil
 }
@@ -0,0 +1,126 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+var (
+	ErrBlankAlias     = errors.New("alias cannot be blank")
+	ErrDuplicateAlias = errors.New("artist already has this alias")
+	ErrPrimaryAlias   = errors.New("the primary name of an artist cannot be removed")
+)
+
+// GetAmbiguousArtistAliases returns the aliases shared by more than one artist, which usually means
+// the same artist was created more than once and should be merged. The first artist listed for each
+// alias is the one listens with that artist name are matched to.
+func GetAmbiguousArtistAliases(ctx context.Context, store db.ArtistStore) ([]db.AmbiguousAlias, error) {
+	aliases, err := store.GetAmbiguousArtistAliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("GetAmbiguousArtistAliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// AddArtistAlias adds an alias to the artist, such as a romanization of its name or its name in its
+// native script, which listens with that artist name are then matched to and images are searched
+// with. Aliases are unique ignoring case, so an alias that only differs in case from an existing
+// one is not added and ErrDuplicateAlias is returned.
+func AddArtistAlias(ctx context.Context, store db.ArtistStore, artistID int32, alias string) error {
+	alias = strings.TrimSpace(alias)
+	if alias == "" {
+		return ErrBlankAlias
+	}
+	aliases, err := artistAliases(ctx, store, artistID)
+	if err != nil {
+		return fmt.Errorf("AddArtistAlias: %w", err)
+	}
+	names := make([]string, 0, len(aliases))
+	for _, a := range aliases {
+		names = append(names, a.Alias)
+	}
+	if len(utils.UniqueIgnoringCase(append(names, alias))) == len(utils.UniqueIgnoringCase(names)) {
+		return ErrDuplicateAlias
+	}
+	if err := store.SaveArtistAliases(ctx, artistID, []string{alias}, models.AliasSourceManual); err != nil {
+		return fmt.Errorf("AddArtistAlias: %w", err)
+	}
+	logger.FromContext(ctx).Info().Msgf("AddArtistAlias: Added alias '%s' to artist %d", alias, artistID)
+	return nil
+}
+
+// RemoveArtistAlias removes an alias from the artist, matching it ignoring case when the artist has no
+// alias spelled exactly as given. The primary name of the artist cannot be removed, and
+// ErrPrimaryAlias is returned instead.
+func RemoveArtistAlias(ctx context.Context, store db.ArtistStore, artistID int32, alias string) error {
+	aliases, err := artistAliases(ctx, store, artistID)
+	if err != nil {
+		return fmt.Errorf("RemoveArtistAlias: %w", err)
+	}
+	match := findAlias(aliases, strings.TrimSpace(alias))
+	if match == nil {
+		return fmt.Errorf("RemoveArtistAlias: %w", db.ErrNotFound)
+	}
+	if match.Primary {
+		return ErrPrimaryAlias
+	}
+	if err := store.DeleteArtistAlias(ctx, artistID, match.Alias); err != nil {
+		return fmt.Errorf("RemoveArtistAlias: %w", err)
+	}
+	logger.FromContext(ctx).Info().Msgf("RemoveArtistAlias: Removed alias '%s' from artist %d", match.Alias, artistID)
+	return nil
+}
+
+// SetPrimaryArtistName makes the name the one the artist is shown with. The name is added as an alias
+// when the artist has no alias spelled exactly the same, so that the casing of the name can be
+// changed, with the previous primary name kept as an alias.
+func SetPrimaryArtistName(ctx context.Context, store db.ArtistStore, artistID int32, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrBlankAlias
+	}
+	aliases, err := artistAliases(ctx, store, artistID)
+	if err != nil {
+		return fmt.Errorf("SetPrimaryArtistName: %w", err)
+	}
+	if match := findAlias(aliases, name); match == nil || match.Alias != name {
+		if err := store.SaveArtistAliases(ctx, artistID, []string{name}, models.AliasSourceManual); err != nil {
+			return fmt.Errorf("SetPrimaryArtistName: %w", err)
+		}
+	}
+	if err := store.SetPrimaryArtistAlias(ctx, artistID, name); err != nil {
+		return fmt.Errorf("SetPrimaryArtistName: %w", err)
+	}
+	logger.FromContext(ctx).Info().Msgf("SetPrimaryArtistName: Set the primary name of artist %d to '%s'", artistID, name)
+	return nil
+}
+
+// artistAliases returns the aliases of the artist, or db.ErrNotFound when there is no such artist.
+func artistAliases(ctx context.Context, store db.ArtistStore, artistID int32) ([]models.Alias, error) {
+	if _, err := store.GetArtist(ctx, db.GetArtistOpts{ID: artistID}); err != nil {
+		return nil, err
+	}
+	return store.GetAllArtistAliases(ctx, artistID)
+}
+
+// findAlias returns the alias spelled exactly as given, or else the first one that matches it
+// ignoring case, or nil when none do.
+func findAlias(aliases []models.Alias, alias string) *models.Alias {
+	var match *models.Alias
+	for i := range aliases {
+		if aliases[i].Alias == alias {
+			return &aliases[i]
+		}
+		if match == nil && strings.EqualFold(aliases[i].Alias, alias) {
+			match = &aliases[i]
+		}
+	}
+	return match
+}
@@ -0,0 +1,99 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmbiguousArtistAliases(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	// two artists without MusicBrainz IDs share an alias; the one with more listens is preferred
+	first, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Shared"})
+	require.NoError(t, err)
+	second, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Other Name"})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveArtistAliases(ctx, second.ID, []string{"Shared"}, "Manual"))
+	for i := range 2 {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Other Name",
+			TrackTitle:   "Track",
+			ReleaseTitle: "Release",
+			Time:         time.Date(2024, 1, 1, 12, i, 0, 0, time.UTC),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	a, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Shared"})
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, a.ID)
+
+	// an artist with a MusicBrainz ID is preferred over listen counts
+	mbzID := uuid.New()
+	third, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Third", MusicBrainzID: mbzID})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveArtistAliases(ctx, third.ID, []string{"Shared"}, "Manual"))
+
+	a, err = store.GetArtist(ctx, db.GetArtistOpts{Name: "Shared"})
+	require.NoError(t, err)
+	assert.Equal(t, third.ID, a.ID)
+
+	aliases, err := catalog.GetAmbiguousArtistAliases(ctx, store)
+	require.NoError(t, err)
+	require.Len(t, aliases, 1)
+	assert.Equal(t, "Shared", aliases[0].Alias)
+	require.Len(t, aliases[0].Artists, 3)
+	assert.Equal(t, third.ID, aliases[0].Artists[0].ID)
+	assert.Equal(t, &mbzID, aliases[0].Artists[0].MbzID)
+	assert.Equal(t, second.ID, aliases[0].Artists[1].ID)
+	assert.EqualValues(t, 2, aliases[0].Artists[1].ListenCount)
+	assert.Equal(t, first.ID, aliases[0].Artists[2].ID)
+	assert.Equal(t, "Shared", aliases[0].Artists[2].Name)
+}
+
+func TestArtistAliasManagement(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "BTS"})
+	require.NoError(t, err)
+
+	require.NoError(t, catalog.AddArtistAlias(ctx, store, artist.ID, " 방탄소년단 "))
+	assert.ErrorIs(t, catalog.AddArtistAlias(ctx, store, artist.ID, "bts"), catalog.ErrDuplicateAlias)
+	assert.ErrorIs(t, catalog.AddArtistAlias(ctx, store, artist.ID, "  "), catalog.ErrBlankAlias)
+	assert.ErrorIs(t, catalog.AddArtistAlias(ctx, store, 9999, "Bangtan Boys"), db.ErrNotFound)
+	require.NoError(t, catalog.AddArtistAlias(ctx, store, artist.ID, "Bangtan Boys"))
+
+	a, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "방탄소년단"})
+	require.NoError(t, err)
+	assert.Equal(t, artist.ID, a.ID)
+	assert.ElementsMatch(t, []string{"BTS", "방탄소년단", "Bangtan Boys"}, a.Aliases)
+
+	// the primary name cannot be removed, and other aliases are matched ignoring case
+	assert.ErrorIs(t, catalog.RemoveArtistAlias(ctx, store, artist.ID, "bts"), catalog.ErrPrimaryAlias)
+	assert.ErrorIs(t, catalog.RemoveArtistAlias(ctx, store, artist.ID, "Unknown"), db.ErrNotFound)
+	require.NoError(t, catalog.RemoveArtistAlias(ctx, store, artist.ID, "bangtan boys"))
+
+	require.NoError(t, catalog.SetPrimaryArtistName(ctx, store, artist.ID, "방탄소년단"))
+	a, err = store.GetArtist(ctx, db.GetArtistOpts{ID: artist.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "방탄소년단", a.Name)
+	assert.ElementsMatch(t, []string{"BTS", "방탄소년단"}, a.Aliases)
+
+	// a name that is not an alias yet is added
+	require.NoError(t, catalog.SetPrimaryArtistName(ctx, store, artist.ID, "Bangtan Sonyeondan"))
+	a, err = store.GetArtist(ctx, db.GetArtistOpts{ID: artist.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Bangtan Sonyeondan", a.Name)
+	assert.Len(t, a.Aliases, 3)
+}
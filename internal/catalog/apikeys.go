@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+var ErrBlankApiKeyLabel = errors.New("label is required")
+
+const (
+	apiKeyLength   = 48
+	apiKeyHintSize = 4
+)
+
+// HashApiKey returns the hash api keys are saved and looked up with. Keys are long random strings, so
+// a fast unsalted hash is enough and lets a key be found by its hash.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiKeyHint(key string) string {
+	if len(key) <= apiKeyHintSize {
+		return ""
+	}
+	return key[len(key)-apiKeyHintSize:]
+}
+
+// CreateApiKey generates a new api key for the user. The returned key is the only time the key itself
+// is known, since only its hash is saved.
+func CreateApiKey(ctx context.Context, store db.UserStore, userID int32, label string) (*models.ApiKey, error) {
+	key, err := utils.GenerateRandomString(apiKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("CreateApiKey: %w", err)
+	}
+	apiKey, err := SaveApiKey(ctx, store, userID, key, label)
+	if err != nil {
+		return nil, fmt.Errorf("CreateApiKey: %w", err)
+	}
+	return apiKey, nil
+}
+
+// SaveApiKey saves a key generated by the caller as an api key of the user.
+func SaveApiKey(ctx context.Context, store db.UserStore, userID int32, key, label string) (*models.ApiKey, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return nil, fmt.Errorf("SaveApiKey: %w", ErrBlankApiKeyLabel)
+	}
+	apiKey, err := store.SaveApiKey(ctx, db.SaveApiKeyOpts{
+		KeyHash: HashApiKey(key),
+		KeyHint: apiKeyHint(key),
+		UserID:  userID,
+		Label:   label,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SaveApiKey: %w", err)
+	}
+	apiKey.Key = key
+	return apiKey, nil
+}
+
+// ListApiKeys returns the api keys of the user, without the keys themselves.
+func ListApiKeys(ctx context.Context, store db.UserStore, userID int32) ([]models.ApiKey, error) {
+	keys, err := store.GetApiKeysByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ListApiKeys: %w", err)
+	}
+	if keys == nil {
+		keys = []models.ApiKey{}
+	}
+	return keys, nil
+}
+
+// RevokeApiKey deletes an api key of the user, returning db.ErrNotFound when the user has no key with
+// the id.
+func RevokeApiKey(ctx context.Context, store db.UserStore, userID, id int32) error {
+	deleted, err := store.DeleteApiKey(ctx, userID, id)
+	if err != nil {
+		return fmt.Errorf("RevokeApiKey: %w", err)
+	}
+	if !deleted {
+		return fmt.Errorf("RevokeApiKey: %w", db.ErrNotFound)
+	}
+	return nil
+}
+
+// HashStoredApiKeys replaces the plaintext keys saved before api keys were stored hashed with their
+// hashes. It must run before requests are served, since plaintext keys cannot be looked up.
+func HashStoredApiKeys(ctx context.Context, store db.UserStore) error {
+	l := logger.FromContext(ctx)
+	keys, err := store.GetUnhashedApiKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("HashStoredApiKeys: %w", err)
+	}
+	for _, k := range keys {
+		if err := store.UpdateApiKeyHash(ctx, k.ID, HashApiKey(k.Key), apiKeyHint(k.Key)); err != nil {
+			return fmt.Errorf("HashStoredApiKeys: %w", err)
+		}
+	}
+	if len(keys) > 0 {
+		l.Info().Msgf("HashStoredApiKeys: Hashed %d stored api keys", len(keys))
+	}
+	return nil
+}
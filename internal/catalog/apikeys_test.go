@@ -0,0 +1,81 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiKeys(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	key, err := catalog.CreateApiKey(ctx, store, 1, "Scrobbler")
+	require.NoError(t, err)
+	require.Len(t, key.Key, 48)
+	assert.Equal(t, key.Key[44:], key.Hint)
+
+	// only the hash of the key is saved
+	count, err := store.Count(`SELECT COUNT(*) FROM api_keys WHERE key_hash = ? AND key IS NULL`, catalog.HashApiKey(key.Key))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	user, err := catalog.GetUserByAPIKey(ctx, store, key.Key)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, user.ID)
+	_, err = catalog.GetUserByAPIKey(ctx, store, catalog.HashApiKey(key.Key))
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	keys, err := catalog.ListApiKeys(ctx, store, 1)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Empty(t, keys[0].Key)
+	assert.Equal(t, key.Hint, keys[0].Hint)
+	assert.Equal(t, "Scrobbler", keys[0].Label)
+
+	_, err = catalog.CreateApiKey(ctx, store, 1, " ")
+	assert.ErrorIs(t, err, catalog.ErrBlankApiKeyLabel)
+
+	// keys can only be revoked by the user they belong to
+	other, _, err := catalog.CreateUser(ctx, store, db.SaveUserOpts{
+		Username: "other",
+		Password: "password",
+		Role:     models.UserRoleUser,
+	}, "Default")
+	require.NoError(t, err)
+	assert.ErrorIs(t, catalog.RevokeApiKey(ctx, store, other.ID, key.ID), db.ErrNotFound)
+	require.NoError(t, catalog.RevokeApiKey(ctx, store, 1, key.ID))
+	assert.ErrorIs(t, catalog.RevokeApiKey(ctx, store, 1, key.ID), db.ErrNotFound)
+	_, err = catalog.GetUserByAPIKey(ctx, store, key.Key)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func TestHashStoredApiKeys(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	// a key saved in plaintext before keys were stored hashed
+	require.NoError(t, store.Exec(`INSERT INTO api_keys (key, key_hint, user_id, created_at, label) VALUES ('legacykey1234', '1234', 1, 0, 'Old')`))
+	_, err := catalog.GetUserByAPIKey(ctx, store, "legacykey1234")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	require.NoError(t, catalog.HashStoredApiKeys(ctx, store))
+	user, err := catalog.GetUserByAPIKey(ctx, store, "legacykey1234")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, user.ID)
+	count, err := store.Count(`SELECT COUNT(*) FROM api_keys WHERE key IS NOT NULL`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// hashing again is a no-op
+	require.NoError(t, catalog.HashStoredApiKeys(ctx, store))
+	keys, err := catalog.ListApiKeys(ctx, store, 1)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "1234", keys[0].Hint)
+}
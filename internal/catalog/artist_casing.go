@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// recordArtistNameSeen counts the spelling of the artist's name used in a listen, so the display
+// name can follow the most common casing. Names matched some other way, e.g. ignoring a leading
+// article, are not spellings of the artist's name and are not recorded.
+func recordArtistNameSeen(ctx context.Context, d db.ArtistStore, a *models.Artist, name string) {
+	name = strings.TrimSpace(name)
+	sameName := func(alias string) bool { return strings.EqualFold(alias, name) }
+	if !sameName(a.Name) && !slices.ContainsFunc(a.Aliases, sameName) {
+		return
+	}
+	if err := d.RecordArtistNameSeen(ctx, a.ID, name); err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msgf("Failed to record spelling '%s' of artist %d", name, a.ID)
+	}
+}
+
+// preferredNameCasing returns the casing of the artist's name to display under the policy, or the
+// current primary name when no other casing is preferred.
+func preferredNameCasing(casings db.ArtistNameCasings, policy string) string {
+	current := casings.Casings[0]
+	if policy == cfg.ArtistCasingPolicyMusicBrainz {
+		if current.Source == models.AliasSourceMusicBrainz {
+			return current.Name
+		}
+		for _, c := range casings.Casings {
+			if c.Source == models.AliasSourceMusicBrainz {
+				return c.Name
+			}
+		}
+	}
+	// ties keep the current name, so the display name does not flip between equally common casings
+	best := current
+	for _, c := range casings.Casings {
+		if c.SeenCount > best.SeenCount {
+			best = c
+		}
+	}
+	return best.Name
+}
+
+// NormalizeArtistNameCasing sets the display name of every artist whose name has been seen in more
+// than one casing to the casing preferred by the configured policy, returning the number of artists
+// renamed. It does nothing when no policy is configured.
+func NormalizeArtistNameCasing(ctx context.Context, store db.ArtistStore) (int, error) {
+	l := logger.FromContext(ctx)
+	policy := cfg.ArtistCasingPolicy()
+	if policy == cfg.ArtistCasingPolicyNone {
+		return 0, nil
+	}
+
+	artists, err := store.GetArtistNameCasings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("NormalizeArtistNameCasing: %w", err)
+	}
+	count := 0
+	for _, a := range artists {
+		name := preferredNameCasing(a, policy)
+		if a.Casings[0].Name == name {
+			continue
+		}
+		if err := store.SetPrimaryArtistAlias(ctx, a.ArtistID, name); err != nil {
+			return count, fmt.Errorf("NormalizeArtistNameCasing: %w", err)
+		}
+		l.Debug().Msgf("NormalizeArtistNameCasing: Renamed artist %d to '%s'", a.ArtistID, name)
+		count++
+	}
+	l.Info().Msgf("NormalizeArtistNameCasing: Updated the name casing of %d artists", count)
+	return count, nil
+}
@@ -0,0 +1,83 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func submitArtistSpellings(t *testing.T, store *sqlite.Sqlite, names ...string) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, name := range names {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       name,
+			TrackTitle:   "Strobe",
+			ReleaseTitle: "For Lack of a Better Name",
+			Time:         base.Add(time.Duration(i) * time.Hour),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestSubmitListen_ArtistNameCaseInsensitive(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	submitArtistSpellings(t, store, "Deadmau5", "deadmau5", "DEADMAU5")
+
+	count, err := store.Count(`SELECT COUNT(*) FROM artists`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "deadMAU5"})
+	require.NoError(t, err)
+	assert.Equal(t, "Deadmau5", artist.Name)
+	assert.EqualValues(t, 3, artist.ListenCount)
+}
+
+func TestNormalizeArtistNameCasing(t *testing.T) {
+	defer cfg.SetArtistCasingPolicy(cfg.ArtistCasingPolicyNone)
+	ctx := context.Background()
+	store := newTestDB()
+	submitArtistSpellings(t, store, "Deadmau5", "deadmau5", "deadmau5", "DEADMAU5")
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "deadmau5"})
+	require.NoError(t, err)
+
+	// no policy keeps the name the artist was created with
+	renamed, err := catalog.NormalizeArtistNameCasing(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 0, renamed)
+
+	cfg.SetArtistCasingPolicy(cfg.ArtistCasingPolicyFrequent)
+	renamed, err = catalog.NormalizeArtistNameCasing(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+	artist, err = store.GetArtist(ctx, db.GetArtistOpts{ID: artist.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "deadmau5", artist.Name)
+
+	// running again changes nothing
+	renamed, err = catalog.NormalizeArtistNameCasing(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 0, renamed)
+
+	// the MusicBrainz casing wins over the most frequent one
+	require.NoError(t, store.Exec(`UPDATE artist_aliases SET source = ? WHERE alias = 'DEADMAU5'`, models.AliasSourceMusicBrainz))
+	cfg.SetArtistCasingPolicy(cfg.ArtistCasingPolicyMusicBrainz)
+	renamed, err = catalog.NormalizeArtistNameCasing(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+	artist, err = store.GetArtist(ctx, db.GetArtistOpts{Name: "deadmau5"})
+	require.NoError(t, err)
+	assert.Equal(t, "DEADMAU5", artist.Name)
+}
@@ -0,0 +1,59 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+)
+
+// GetArtistTopTracks returns the tracks featuring the artist ranked by play count within the timeframe,
+// each with the time it was last listened to. When userID is 0, listens from all users are counted.
+// An empty timeframe defaults to all time.
+func GetArtistTopTracks(ctx context.Context, store db.TrackStore, userID, artistID int32, limit int, tf db.Timeframe) ([]db.ArtistTopItem, error) {
+	opts, err := artistTopItemsOpts(userID, artistID, limit, tf)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopTracks: %w", err)
+	}
+	items, err := store.GetArtistTopTracks(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopTracks: %w", err)
+	}
+	return items, nil
+}
+
+// GetArtistTopReleases returns the albums by the artist ranked by play count within the timeframe,
+// each with the time it was last listened to. When userID is 0, listens from all users are counted.
+// An empty timeframe defaults to all time. Singles are left out when the single release policy collapses them.
+func GetArtistTopReleases(ctx context.Context, store db.AlbumStore, userID, artistID int32, limit int, tf db.Timeframe) ([]db.ArtistTopItem, error) {
+	opts, err := artistTopItemsOpts(userID, artistID, limit, tf)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopReleases: %w", err)
+	}
+	opts.ExcludeSingles = cfg.SingleReleasePolicy() == cfg.SingleReleasePolicyCollapse
+	items, err := store.GetArtistTopAlbums(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopReleases: %w", err)
+	}
+	return items, nil
+}
+
+func artistTopItemsOpts(userID, artistID int32, limit int, tf db.Timeframe) (db.GetArtistTopItemsOpts, error) {
+	if artistID == 0 {
+		return db.GetArtistTopItemsOpts{}, errors.New("artist id is required")
+	}
+	if limit < 0 {
+		return db.GetArtistTopItemsOpts{}, errors.New("limit must not be negative")
+	}
+	if _, t2 := db.TimeframeToTimeRange(tf); t2.IsZero() {
+		tf.Period = db.PeriodAllTime
+	}
+	return db.GetArtistTopItemsOpts{
+		ArtistID:  artistID,
+		UserID:    userID,
+		Timeframe: tf,
+		Limit:     limit,
+	}, nil
+}
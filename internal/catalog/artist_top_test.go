@@ -0,0 +1,89 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetArtistTopTracks(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	artistA, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist A"})
+	require.NoError(t, err)
+
+	tracks, err := catalog.GetArtistTopTracks(ctx, store, 1, artistA.ID, 10, db.Timeframe{})
+	require.NoError(t, err)
+	require.Len(t, tracks, 3)
+	assert.Equal(t, "Track 1", tracks[0].Title)
+	assert.EqualValues(t, 2, tracks[0].Listens)
+	EqualTime(t, base.Add(24*time.Hour), tracks[0].LastListenedAt)
+	// ties are broken by the most recent listen
+	assert.Equal(t, "Track 3", tracks[1].Title)
+	assert.Equal(t, "Track 2", tracks[2].Title)
+	EqualTime(t, base.Add(2*24*time.Hour), tracks[2].LastListenedAt)
+
+	// tracks by other artists are never included
+	for _, track := range tracks {
+		assert.NotEqual(t, "Track 4", track.Title)
+	}
+
+	tracks, err = catalog.GetArtistTopTracks(ctx, store, 1, artistA.ID, 1, db.Timeframe{})
+	require.NoError(t, err)
+	require.Len(t, tracks, 1)
+	assert.Equal(t, "Track 1", tracks[0].Title)
+
+	tracks, err = catalog.GetArtistTopTracks(ctx, store, 1, artistA.ID, 10, db.Timeframe{From: base.Add(36 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, tracks, 2)
+	assert.EqualValues(t, 1, tracks[0].Listens)
+
+	// no listens for a user that doesn't exist
+	tracks, err = catalog.GetArtistTopTracks(ctx, store, 2, artistA.ID, 10, db.Timeframe{})
+	require.NoError(t, err)
+	assert.Empty(t, tracks)
+
+	_, err = catalog.GetArtistTopTracks(ctx, store, 1, 0, 10, db.Timeframe{})
+	assert.Error(t, err)
+	_, err = catalog.GetArtistTopTracks(ctx, store, 1, artistA.ID, -1, db.Timeframe{})
+	assert.Error(t, err)
+}
+
+func TestGetArtistTopReleases(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	artistA, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist A"})
+	require.NoError(t, err)
+	artistB, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist B"})
+	require.NoError(t, err)
+
+	releases, err := catalog.GetArtistTopReleases(ctx, store, 1, artistA.ID, 10, db.Timeframe{})
+	require.NoError(t, err)
+	require.Len(t, releases, 2)
+	assert.Equal(t, "Release X", releases[0].Title)
+	assert.EqualValues(t, 3, releases[0].Listens)
+	EqualTime(t, base.Add(2*24*time.Hour), releases[0].LastListenedAt)
+	assert.Equal(t, "Release Y", releases[1].Title)
+	assert.EqualValues(t, 1, releases[1].Listens)
+
+	releases, err = catalog.GetArtistTopReleases(ctx, store, 0, artistB.ID, 10, db.Timeframe{Period: db.PeriodAllTime})
+	require.NoError(t, err)
+	require.Len(t, releases, 1)
+	assert.Equal(t, "Release Z", releases[0].Title)
+	assert.EqualValues(t, 2, releases[0].Listens)
+	EqualTime(t, base.Add(5*24*time.Hour), releases[0].LastListenedAt)
+
+	_, err = catalog.GetArtistTopReleases(ctx, store, 1, 0, 10, db.Timeframe{})
+	assert.Error(t, err)
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/gabehf/koito/imagecache"
+	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/images"
 	"github.com/gabehf/koito/internal/logger"
@@ -22,6 +24,7 @@ type AssociateAlbumOpts struct {
 	ReleaseGroupMbzID uuid.UUID
 	ReleaseName       string
 	TrackName         string // required
+	TrackSpotifyID    string
 	Mbzc              mbz.MusicBrainzCaller
 	SkipCacheImage    bool
 }
@@ -49,11 +52,13 @@ func matchAlbumByMbzReleaseID(ctx context.Context, d db.AlbumStore, opts Associa
 	a, err := d.GetAlbum(ctx, db.GetAlbumOpts{MusicBrainzID: opts.ReleaseMbzID})
 	if err == nil {
 		l.Debug().Msgf("Found release '%s' by MusicBrainz Release ID", a.Title)
+		saveReleaseGroupMbzID(ctx, d, a, opts.ReleaseGroupMbzID)
 		return &models.Album{
 			ID:             a.ID,
 			MbzID:          &opts.ReleaseMbzID,
 			Title:          a.Title,
 			VariousArtists: a.VariousArtists,
+			IsSingle:       a.IsSingle,
 			Image:          a.Image,
 		}, nil
 	} else if !errors.Is(err, db.ErrNotFound) {
@@ -86,8 +91,9 @@ func createOrUpdateAlbumWithMbzReleaseID(ctx context.Context, d db.AlbumStore, o
 	if err == nil {
 		l.Debug().Msgf("Found album %s, updating with MusicBrainz Release ID...", album.Title)
 		err := d.UpdateAlbum(ctx, db.UpdateAlbumOpts{
-			ID:            album.ID,
-			MusicBrainzID: opts.ReleaseMbzID,
+			ID:                album.ID,
+			MusicBrainzID:     opts.ReleaseMbzID,
+			ReleaseGroupMbzID: opts.ReleaseGroupMbzID,
 		})
 		if err != nil {
 			l.Err(err).Msg("createOrUpdateAlbumWithMbzReleaseID: failed to update album with MusicBrainz Release ID")
@@ -99,7 +105,7 @@ func createOrUpdateAlbumWithMbzReleaseID(ctx context.Context, d db.AlbumStore, o
 			aliases, err := opts.Mbzc.GetReleaseTitles(ctx, opts.ReleaseGroupMbzID)
 			if err == nil {
 				l.Debug().Msgf("Associating aliases '%s' with Release '%s'", aliases, album.Title)
-				err = d.SaveAlbumAliases(ctx, album.ID, aliases, "MusicBrainz")
+				err = d.SaveAlbumAliases(ctx, album.ID, aliases, models.AliasSourceMusicBrainz)
 				if err != nil {
 					l.Err(err).Msg("createOrUpdateAlbumWithMbzReleaseID: failed to save aliases")
 				}
@@ -122,35 +128,30 @@ func createOrUpdateAlbumWithMbzReleaseID(ctx context.Context, d db.AlbumStore, o
 		}
 
 		l.Debug().Msg("Searching for album images...")
-		var imgid uuid.UUID
-		imgUrl, err := images.GetAlbumImage(ctx, images.AlbumImageOpts{
-			Artists:      utils.UniqueIgnoringCase(slices.Concat(utils.FlattenMbzArtistCreditNames(release.ArtistCredit), utils.FlattenArtistNames(opts.Artists))),
-			Album:        release.Title,
-			ReleaseMbzID: &opts.ReleaseMbzID,
+		imgid, imgUrl, err := opts.albumImage(ctx, images.AlbumImageOpts{
+			Artists:        utils.UniqueIgnoringCase(slices.Concat(utils.FlattenMbzArtistCreditNames(release.ArtistCredit), utils.FlattenArtistNames(opts.Artists))),
+			Album:          release.Title,
+			ReleaseMbzID:   &opts.ReleaseMbzID,
+			TrackSpotifyID: opts.TrackSpotifyID,
+			Mbzc:           opts.Mbzc,
 		})
-
-		if err == nil && imgUrl != "" {
-			imgid = uuid.New()
-			if !opts.SkipCacheImage {
-				l.Debug().Msg("Downloading album image from source...")
-				err = imagecache.DownloadImage(imgid, imgUrl)
-				if err != nil {
-					l.Err(err).Msg("createOrUpdateAlbumWithMbzReleaseID: failed to cache image")
-				}
+		if errors.Is(err, images.ErrImageNotFound) {
+			if fallback := artistImageFallback(ctx, opts.Artists); fallback != uuid.Nil {
+				imgid, imgUrl = fallback, ImageSourceArtistFallback
 			}
 		}
-
 		if err != nil {
 			l.Debug().Msgf("createOrUpdateAlbumWithMbzReleaseID: failed to get album images for %s: %s", release.Title, err.Error())
 		}
 
 		album, err = d.SaveAlbum(ctx, db.SaveAlbumOpts{
-			Title:          release.Title,
-			MusicBrainzID:  opts.ReleaseMbzID,
-			ArtistIDs:      utils.FlattenArtistIDs(opts.Artists),
-			VariousArtists: variousArtists,
-			Image:          imgid,
-			ImageSrc:       imgUrl,
+			Title:             release.Title,
+			MusicBrainzID:     opts.ReleaseMbzID,
+			ReleaseGroupMbzID: opts.ReleaseGroupMbzID,
+			ArtistIDs:         utils.FlattenArtistIDs(opts.Artists),
+			VariousArtists:    variousArtists,
+			Image:             imgid,
+			ImageSrc:          imgUrl,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("createOrUpdateAlbumWithMbzReleaseID: %w", err)
@@ -160,7 +161,7 @@ func createOrUpdateAlbumWithMbzReleaseID(ctx context.Context, d db.AlbumStore, o
 			aliases, err := opts.Mbzc.GetReleaseTitles(ctx, opts.ReleaseGroupMbzID)
 			if err == nil {
 				l.Debug().Msgf("Associating aliases '%s' with Release '%s'", aliases, album.Title)
-				err = d.SaveAlbumAliases(ctx, album.ID, aliases, "MusicBrainz")
+				err = d.SaveAlbumAliases(ctx, album.ID, aliases, models.AliasSourceMusicBrainz)
 				if err != nil {
 					l.Err(err).Msg("createOrUpdateAlbumWithMbzReleaseID: failed to save aliases")
 				}
@@ -172,14 +173,47 @@ func createOrUpdateAlbumWithMbzReleaseID(ctx context.Context, d db.AlbumStore, o
 		l.Info().Msgf("Created album '%s' with MusicBrainz Release ID", album.Title)
 	}
 
+	if cfg.FetchAlbumLabels() {
+		saveAlbumLabels(ctx, d, opts.Mbzc, album.ID, opts.ReleaseMbzID)
+	}
+
 	return &models.Album{
 		ID:             album.ID,
 		MbzID:          &opts.ReleaseMbzID,
 		Title:          album.Title,
 		VariousArtists: album.VariousArtists,
+		IsSingle:       album.IsSingle,
 	}, nil
 }
 
+// saveAlbumLabels fetches the labels of the MusicBrainz release from MusicBrainz and saves them to the
+// album. Labels are optional detail, so failures are logged rather than returned.
+func saveAlbumLabels(ctx context.Context, d db.AlbumStore, mbzc mbz.MusicBrainzCaller, albumID int32, releaseMbzID uuid.UUID) {
+	l := logger.FromContext(ctx)
+	info, err := mbzc.GetReleaseLabels(ctx, releaseMbzID)
+	if err != nil {
+		l.Info().AnErr("err", err).Msg("saveAlbumLabels: failed to get release labels from MusicBrainz")
+		return
+	}
+	labels := make([]models.AlbumLabel, 0, len(info))
+	for _, li := range info {
+		if li.Label == nil || strings.TrimSpace(li.Label.Name) == "" {
+			continue
+		}
+		label := models.AlbumLabel{Name: li.Label.Name, CatalogNumber: li.CatalogNumber}
+		if id, err := uuid.Parse(li.Label.ID); err == nil {
+			label.MbzID = &id
+		}
+		labels = append(labels, label)
+	}
+	if len(labels) == 0 {
+		return
+	}
+	if err := d.SaveAlbumLabels(ctx, albumID, labels); err != nil {
+		l.Err(err).Msg("saveAlbumLabels: failed to save labels")
+	}
+}
+
 func matchAlbumByTitle(ctx context.Context, d db.AlbumStore, opts AssociateAlbumOpts) (*models.Album, error) {
 	l := logger.FromContext(ctx)
 
@@ -194,6 +228,9 @@ func matchAlbumByTitle(ctx context.Context, d db.AlbumStore, opts AssociateAlbum
 		Title:    releaseName,
 		ArtistID: opts.Artists[0].ID,
 	})
+	if errors.Is(err, db.ErrNotFound) && cfg.FoldDiacriticsForMatching() {
+		a, err = getAlbumByNormalizedTitle(ctx, d, opts.Artists[0].ID, releaseName)
+	}
 	if err == nil {
 		l.Debug().Msgf("Found album '%s' by artist and title", a.Title)
 		if a.MbzID == nil && opts.ReleaseMbzID != uuid.Nil {
@@ -206,23 +243,21 @@ func matchAlbumByTitle(ctx context.Context, d db.AlbumStore, opts AssociateAlbum
 				l.Err(err).Msg("matchAlbumByTitle: failed to associate existing release with MusicBrainz ID")
 			}
 		}
+		saveReleaseGroupMbzID(ctx, d, a, opts.ReleaseGroupMbzID)
 	} else if !errors.Is(err, db.ErrNotFound) {
 		return nil, fmt.Errorf("matchAlbumByTitle: %w", err)
 	} else {
-		var imgid uuid.UUID
-		imgUrl, err := images.GetAlbumImage(ctx, images.AlbumImageOpts{
-			Artists:      utils.FlattenArtistNames(opts.Artists),
-			Album:        opts.ReleaseName,
-			ReleaseMbzID: &opts.ReleaseMbzID,
+		imgid, imgUrl, err := opts.albumImage(ctx, images.AlbumImageOpts{
+			Artists:        utils.FlattenArtistNames(opts.Artists),
+			Album:          opts.ReleaseName,
+			Track:          opts.TrackName,
+			TrackSpotifyID: opts.TrackSpotifyID,
+			ReleaseMbzID:   &opts.ReleaseMbzID,
+			Mbzc:           opts.Mbzc,
 		})
-		if err == nil && imgUrl != "" {
-			imgid = uuid.New()
-			if !opts.SkipCacheImage {
-				l.Debug().Msg("Downloading album image from source...")
-				err = imagecache.DownloadImage(imgid, imgUrl)
-				if err != nil {
-					l.Err(err).Msg("createOrUpdateAlbumWithMbzReleaseID: failed to cache image")
-				}
+		if errors.Is(err, images.ErrImageNotFound) {
+			if fallback := artistImageFallback(ctx, opts.Artists); fallback != uuid.Nil {
+				imgid, imgUrl = fallback, ImageSourceArtistFallback
 			}
 		}
 		if err != nil {
@@ -230,11 +265,12 @@ func matchAlbumByTitle(ctx context.Context, d db.AlbumStore, opts AssociateAlbum
 		}
 
 		a, err = d.SaveAlbum(ctx, db.SaveAlbumOpts{
-			Title:         releaseName,
-			ArtistIDs:     utils.FlattenArtistIDs(opts.Artists),
-			Image:         imgid,
-			MusicBrainzID: opts.ReleaseMbzID,
-			ImageSrc:      imgUrl,
+			Title:             releaseName,
+			ArtistIDs:         utils.FlattenArtistIDs(opts.Artists),
+			Image:             imgid,
+			MusicBrainzID:     opts.ReleaseMbzID,
+			ReleaseGroupMbzID: opts.ReleaseGroupMbzID,
+			ImageSrc:          imgUrl,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("matchAlbumByTitle: %w", err)
@@ -243,7 +279,65 @@ func matchAlbumByTitle(ctx context.Context, d db.AlbumStore, opts AssociateAlbum
 	}
 
 	return &models.Album{
-		ID:    a.ID,
-		Title: a.Title,
+		ID:       a.ID,
+		Title:    a.Title,
+		IsSingle: a.IsSingle,
 	}, nil
 }
+
+// saveReleaseGroupMbzID stores the release group MusicBrainz ID for an existing album that does not have one yet
+func saveReleaseGroupMbzID(ctx context.Context, d db.AlbumStore, a *models.Album, releaseGroupMbzID uuid.UUID) {
+	if releaseGroupMbzID == uuid.Nil || a.ReleaseGroupMbzID != nil {
+		return
+	}
+	l := logger.FromContext(ctx)
+	err := d.UpdateAlbum(ctx, db.UpdateAlbumOpts{
+		ID:                a.ID,
+		ReleaseGroupMbzID: releaseGroupMbzID,
+	})
+	if err != nil {
+		l.Err(err).Msgf("Failed to associate album '%s' with release group MusicBrainz ID", a.Title)
+	}
+}
+
+// getAlbumByNormalizedTitle looks up an album by the artist whose title matches the given one when
+// case, accents and punctuation are ignored.
+func getAlbumByNormalizedTitle(ctx context.Context, d db.AlbumStore, artistID int32, title string) (*models.Album, error) {
+	key := utils.NormalizeForMatching(title)
+	if key == "" {
+		return nil, fmt.Errorf("getAlbumByNormalizedTitle: %w", db.ErrNotFound)
+	}
+	titles, err := d.GetAlbumTitles(ctx, artistID)
+	if err != nil {
+		return nil, fmt.Errorf("getAlbumByNormalizedTitle: %w", err)
+	}
+	for _, t := range titles {
+		if utils.NormalizeForMatching(t.Title) == key {
+			return d.GetAlbum(ctx, db.GetAlbumOpts{ID: t.AlbumID})
+		}
+	}
+	return nil, fmt.Errorf("getAlbumByNormalizedTitle: %w", db.ErrNotFound)
+}
+
+// albumImage looks up the cover of a new album and caches it unless SkipCacheImage is set, returning
+// the image's id and source, or uuid.Nil when no cover was found. When images are fetched lazily the
+// lookup is skipped, and the album gets an image id without a source that the image handler looks up
+// when it is first requested.
+func (opts AssociateAlbumOpts) albumImage(ctx context.Context, imgOpts images.AlbumImageOpts) (uuid.UUID, string, error) {
+	if cfg.LazyImageFetch() {
+		return uuid.New(), "", nil
+	}
+	imgUrl, err := images.GetAlbumImage(ctx, imgOpts)
+	if err != nil || imgUrl == "" {
+		return uuid.Nil, "", err
+	}
+	imgid := uuid.New()
+	if !opts.SkipCacheImage {
+		l := logger.FromContext(ctx)
+		l.Debug().Msg("Downloading album image from source...")
+		if err := imagecache.DownloadImage(imgid, imgUrl); err != nil {
+			l.Err(err).Msg("albumImage: failed to cache image")
+		}
+	}
+	return imgid, imgUrl, nil
+}
@@ -24,6 +24,8 @@ type AssociateArtistsOpts struct {
 	ArtistName    string
 	TrackTitle    string
 	Mbzc          mbz.MusicBrainzCaller
+	// Optional. Spotify artist IDs keyed by artist name, used to look up artist images directly.
+	SpotifyIDs map[string]string
 
 	SkipCacheImage bool
 }
@@ -91,9 +93,7 @@ func matchArtistsByMBIDMappings(ctx context.Context, d db.ArtistStore, opts Asso
 			return nil, fmt.Errorf("matchArtistsByMBIDMappings: %w", err)
 		}
 
-		artist, err = d.GetArtist(ctx, db.GetArtistOpts{
-			Name: a.Artist,
-		})
+		artist, err = getArtistByName(ctx, d, a.Artist)
 		if err == nil {
 			l.Debug().Msgf("Artist '%s' found by Name", a.Artist)
 			if artist.MbzID == nil {
@@ -126,20 +126,11 @@ func matchArtistsByMBIDMappings(ctx context.Context, d db.ArtistStore, opts Asso
 		if err != nil {
 			l.Warn().AnErr("error", err).Msg("matchArtistsByMBIDMappings: MusicBrainz unreachable, creating new artist with provided MusicBrainz ID mapping")
 
-			var imgid uuid.UUID
-			imgUrl, imgErr := images.GetArtistImage(ctx, images.ArtistImageOpts{
-				Aliases: []string{a.Artist},
+			imgid, imgUrl, imgErr := opts.artistImage(ctx, images.ArtistImageOpts{
+				Aliases:   []string{a.Artist},
+				SpotifyID: opts.spotifyID(a.Artist),
 			})
-			if imgErr == nil && imgUrl != "" {
-				imgid = uuid.New()
-				if !opts.SkipCacheImage {
-					l.Debug().Msg("Downloading artist image from source...")
-					err = imagecache.DownloadImage(imgid, imgUrl)
-					if err != nil {
-						l.Err(err).Msg("Failed to cache image")
-					}
-				}
-			} else {
+			if imgErr != nil {
 				l.Err(imgErr).Msgf("matchArtistsByMBIDMappings: Failed to get artist image for artist '%s'", a.Artist)
 			}
 
@@ -212,16 +203,14 @@ func resolveAliasOrCreateArtist(ctx context.Context, mbzID uuid.UUID, names []st
 	l.Debug().Msgf("Got aliases %v from MusicBrainz", aliases)
 
 	for _, alias := range aliases {
-		a, err := d.GetArtist(ctx, db.GetArtistOpts{
-			Name: alias,
-		})
+		a, err := getArtistByName(ctx, d, alias)
 		if err == nil && (a.MbzID == nil || *a.MbzID == uuid.Nil) {
 			a.MbzID = &mbzID
 			l.Debug().Msgf("Alias '%s' found in DB. Associating with MusicBrainz ID...", alias)
 			if updateErr := d.UpdateArtist(ctx, db.UpdateArtistOpts{ID: a.ID, MusicBrainzID: mbzID}); updateErr != nil {
 				return nil, fmt.Errorf("resolveAliasOrCreateArtist: %w", updateErr)
 			}
-			if saveAliasErr := d.SaveArtistAliases(ctx, a.ID, aliases, "MusicBrainz"); saveAliasErr != nil {
+			if saveAliasErr := d.SaveArtistAliases(ctx, a.ID, aliases, models.AliasSourceMusicBrainz); saveAliasErr != nil {
 				return nil, fmt.Errorf("resolveAliasOrCreateArtist: %w", saveAliasErr)
 			}
 			return a, nil
@@ -239,20 +228,11 @@ func resolveAliasOrCreateArtist(ctx context.Context, mbzID uuid.UUID, names []st
 		}
 	}
 
-	var imgid uuid.UUID
-	imgUrl, err := images.GetArtistImage(ctx, images.ArtistImageOpts{
-		Aliases: aliases,
+	imgid, imgUrl, err := opts.artistImage(ctx, images.ArtistImageOpts{
+		Aliases:   aliases,
+		SpotifyID: opts.spotifyID(slices.Concat(names, aliases)...),
 	})
-	if err == nil && imgUrl != "" {
-		imgid = uuid.New()
-		if !opts.SkipCacheImage {
-			l.Debug().Msg("Downloading artist image from source...")
-			err = imagecache.DownloadImage(imgid, imgUrl)
-			if err != nil {
-				l.Err(err).Msg("Failed to cache image")
-			}
-		}
-	} else if err != nil {
+	if err != nil {
 		l.Warn().AnErr("error", err).Msg("Failed to get artist image from ImageSrc")
 	}
 
@@ -279,29 +259,22 @@ func matchArtistsByNames(ctx context.Context, names []string, existing []*models
 			l.Debug().Msgf("Artist '%s' already found, skipping...", name)
 			continue
 		}
-		a, err := d.GetArtist(ctx, db.GetArtistOpts{
-			Name: name,
-		})
+		a, err := getArtistByName(ctx, d, name)
+		if errors.Is(err, db.ErrNotFound) && cfg.IgnoreLeadingThe() {
+			a, err = getArtistIgnoringArticle(ctx, d, name)
+		}
 		if err == nil {
 			l.Debug().Msgf("Artist '%s' found in DB", name)
+			recordArtistNameSeen(ctx, d, a, name)
 			result = append(result, a)
 			continue
 		}
 		if errors.Is(err, db.ErrNotFound) {
-			var imgid uuid.UUID
-			imgUrl, err := images.GetArtistImage(ctx, images.ArtistImageOpts{
-				Aliases: []string{name},
+			imgid, imgUrl, err := opts.artistImage(ctx, images.ArtistImageOpts{
+				Aliases:   []string{name},
+				SpotifyID: opts.spotifyID(name),
 			})
-			if err == nil && imgUrl != "" {
-				imgid = uuid.New()
-				if !opts.SkipCacheImage {
-					l.Debug().Msg("Downloading artist image from source...")
-					err = imagecache.DownloadImage(imgid, imgUrl)
-					if err != nil {
-						l.Err(err).Msg("Failed to cache image")
-					}
-				}
-			} else if err != nil {
+			if err != nil {
 				l.Debug().AnErr("error", err).Msgf("Failed to get artist images for %s", name)
 			}
 			a, err = d.SaveArtist(ctx, db.SaveArtistOpts{Name: name, Image: imgid, ImageSrc: imgUrl})
@@ -309,6 +282,7 @@ func matchArtistsByNames(ctx context.Context, names []string, existing []*models
 				return nil, fmt.Errorf("matchArtistsByNames: %w", err)
 			}
 			l.Info().Msgf("Created artist '%s' with artist name", name)
+			recordArtistNameSeen(ctx, d, a, name)
 			result = append(result, a)
 		} else {
 			return nil, fmt.Errorf("matchArtistsByNames: %w", err)
@@ -317,6 +291,86 @@ func matchArtistsByNames(ctx context.Context, names []string, existing []*models
 	return result, nil
 }
 
+// getArtistByName gets the artist with the given name or alias. When several artists share the
+// alias, the store prefers the artist with a MusicBrainz ID, then the most listened to, and the
+// ambiguity is logged so the artists can be reviewed and merged.
+func getArtistByName(ctx context.Context, d db.ArtistStore, name string) (*models.Artist, error) {
+	a, err := d.GetArtist(ctx, db.GetArtistOpts{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if count, err := d.CountArtistsWithAlias(ctx, name); err == nil && count > 1 {
+		logger.FromContext(ctx).Warn().Msgf("Alias '%s' is shared by %d artists; matched artist '%s' (id %d). Merge the artists if they are the same", name, count, a.Name, a.ID)
+	}
+	return a, nil
+}
+
+// leading articles ignored when matching artist names, when enabled
+var artistNameArticles = []string{"The", "Die", "Les", "Los", "Las", "El", "La", "Le"}
+
+// getArtistIgnoringArticle looks up an artist by the given name with its leading article removed,
+// or with each known article added in front of it.
+func getArtistIgnoringArticle(ctx context.Context, d db.ArtistStore, name string) (*models.Artist, error) {
+	bare := name
+	for _, article := range artistNameArticles {
+		if len(name) > len(article)+1 && strings.EqualFold(name[:len(article)+1], article+" ") {
+			bare = strings.TrimSpace(name[len(article)+1:])
+			break
+		}
+	}
+	candidates := make([]string, 0, len(artistNameArticles)+1)
+	if bare != name {
+		candidates = append(candidates, bare)
+	}
+	for _, article := range artistNameArticles {
+		if c := article + " " + bare; c != name {
+			candidates = append(candidates, c)
+		}
+	}
+	for _, c := range candidates {
+		a, err := getArtistByName(ctx, d, c)
+		if err == nil || !errors.Is(err, db.ErrNotFound) {
+			return a, err
+		}
+	}
+	return nil, fmt.Errorf("getArtistIgnoringArticle: %w", db.ErrNotFound)
+}
+
+// artistImage looks up an image of a new artist and caches it unless SkipCacheImage is set, returning
+// the image's id and source, or uuid.Nil when no image was found. When images are fetched lazily the
+// lookup is skipped, and the artist gets an image id without a source that the image handler looks up
+// when it is first requested.
+func (opts AssociateArtistsOpts) artistImage(ctx context.Context, imgOpts images.ArtistImageOpts) (uuid.UUID, string, error) {
+	if cfg.LazyImageFetch() {
+		return uuid.New(), "", nil
+	}
+	imgUrl, err := images.GetArtistImage(ctx, imgOpts)
+	if err != nil || imgUrl == "" {
+		return uuid.Nil, "", err
+	}
+	imgid := uuid.New()
+	if !opts.SkipCacheImage {
+		l := logger.FromContext(ctx)
+		l.Debug().Msg("Downloading artist image from source...")
+		if err := imagecache.DownloadImage(imgid, imgUrl); err != nil {
+			l.Err(err).Msg("Failed to cache image")
+		}
+	}
+	return imgid, imgUrl, nil
+}
+
+// spotifyID returns the Spotify artist ID known for any of the given names, or an empty string.
+func (opts AssociateArtistsOpts) spotifyID(names ...string) string {
+	for _, name := range names {
+		for artist, id := range opts.SpotifyIDs {
+			if strings.EqualFold(artist, name) {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
 func artistExists(name string, artists []*models.Artist) bool {
 	for _, a := range artists {
 		allAliases := append(a.Aliases, a.Name)
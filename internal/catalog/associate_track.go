@@ -108,6 +108,13 @@ func matchTrackByTrackInfo(ctx context.Context, d db.TrackStore, opts AssociateT
 				}
 			}
 		}
+		track, err = getTrackByBaseTitle(ctx, d, opts)
+		if err == nil {
+			l.Debug().Msgf("Track '%s' found as a version of '%s'", opts.TrackName, track.Title)
+			return track, nil
+		} else if !errors.Is(err, db.ErrNotFound) {
+			return nil, fmt.Errorf("matchTrackByTrackInfo: %w", err)
+		}
 		l.Debug().Msgf("Track '%s' could not be found by title and artist match", opts.TrackName)
 		t, err := d.SaveTrack(ctx, db.SaveTrackOpts{
 			RecordingMbzID: opts.TrackMbzID,
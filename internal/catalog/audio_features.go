@@ -0,0 +1,72 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/images"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+// minimum time between audio feature lookups, each of which makes two requests to Spotify
+const audioFeaturesRequestInterval = time.Second
+
+// FetchMissingAudioFeatures looks up the audio features of every track that has not been looked up
+// yet. Tracks that cannot be found on Spotify are recorded as having no audio features, so each
+// track is only looked up once. Tracks whose lookup fails for another reason are retried on the next run.
+func FetchMissingAudioFeatures(ctx context.Context, store db.TrackStore) error {
+	l := logger.FromContext(ctx)
+	l.Info().Msg("FetchMissingAudioFeatures: Starting backfill of track audio features")
+
+	ticker := time.NewTicker(audioFeaturesRequestInterval)
+	defer ticker.Stop()
+
+	var from int32 = 0
+	count := 0
+
+	for {
+		tracks, err := store.GetTracksWithoutAudioFeatures(ctx, from)
+		if err != nil {
+			return fmt.Errorf("FetchMissingAudioFeatures: failed to fetch tracks for audio features backfill: %w", err)
+		}
+		if len(tracks) == 0 {
+			l.Info().Msgf("FetchMissingAudioFeatures: Fetched audio features for %d tracks", count)
+			return nil
+		}
+
+		for _, track := range tracks {
+			from = track.ID
+			if len(track.Artists) < 1 {
+				continue
+			}
+
+			features, err := images.GetTrackAudioFeatures(ctx, utils.FlattenSimpleArtistNames(track.Artists), track.Title)
+			if errors.Is(err, images.ErrSpotifyDisabled) {
+				return fmt.Errorf("FetchMissingAudioFeatures: %w", err)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("FetchMissingAudioFeatures: %w", ctx.Err())
+			case <-ticker.C:
+			}
+			if errors.Is(err, images.ErrAudioFeaturesNotFound) {
+				l.Debug().Msgf("FetchMissingAudioFeatures: No audio features found for track '%s'", track.Title)
+			} else if err != nil {
+				l.Err(err).Msgf("FetchMissingAudioFeatures: Failed to fetch audio features for track '%s'", track.Title)
+				continue
+			}
+
+			if err := store.SaveTrackAudioFeatures(ctx, track.ID, features); err != nil {
+				l.Err(err).Msgf("FetchMissingAudioFeatures: Failed to save audio features for track '%s'", track.Title)
+				continue
+			}
+			if features != nil {
+				count++
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/images"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackAudioFeatures(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	tracks, err := store.GetTracksWithoutAudioFeatures(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, tracks, 4)
+	require.NotEmpty(t, tracks[0].Artists)
+
+	// Track 1 has two listens, Track 2 has one, Track 3 could not be found
+	require.NoError(t, store.SaveTrackAudioFeatures(ctx, tracks[0].ID, &models.AudioFeatures{Tempo: 120, Energy: 0.9, Danceability: 0.6, Valence: 0.3}))
+	require.NoError(t, store.SaveTrackAudioFeatures(ctx, tracks[1].ID, &models.AudioFeatures{Tempo: 90, Energy: 0.3, Danceability: 0.3, Valence: 0.9}))
+	require.NoError(t, store.SaveTrackAudioFeatures(ctx, tracks[2].ID, nil))
+
+	features, err := store.GetTrackAudioFeatures(ctx, tracks[0].ID)
+	require.NoError(t, err)
+	assert.InDelta(t, 120, features.Tempo, 0.001)
+	_, err = store.GetTrackAudioFeatures(ctx, tracks[2].ID)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = store.GetTrackAudioFeatures(ctx, tracks[3].ID)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// tracks are only looked up once, whether or not they were found
+	remaining, err := store.GetTracksWithoutAudioFeatures(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, tracks[3].ID, remaining[0].ID)
+
+	avg, err := store.GetAverageAudioFeatures(ctx, db.GetAverageAudioFeaturesOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, avg.Listens)
+	assert.InDelta(t, 110, avg.Tempo, 0.001)
+	assert.InDelta(t, 0.5, avg.Valence, 0.001)
+
+	// no providers are enabled in tests
+	err = catalog.FetchMissingAudioFeatures(ctx, store)
+	assert.ErrorIs(t, err, images.ErrSpotifyDisabled)
+}
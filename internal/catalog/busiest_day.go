@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// BusiestDay is the day with the most listens, along with the listens of that day in the order
+// they happened when requested.
+type BusiestDay struct {
+	TopListeningDay
+	Tracks []db.ListenLogEntry `json:"tracks,omitempty"`
+}
+
+// GetBusiestDay returns the day within the timeframe on which the user listened the most, with days
+// determined in the timeframe's timezone. When userID is 0, listens from all users are counted, and
+// an empty timeframe defaults to all time. Returns nil when there are no listens in the timeframe.
+func GetBusiestDay(ctx context.Context, store db.ListenStore, userID int32, tf db.Timeframe, includeTracks bool) (*BusiestDay, error) {
+	if _, t2 := db.TimeframeToTimeRange(tf); t2.IsZero() {
+		tf.Period = db.PeriodAllTime
+	}
+	loc := tf.Timezone
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	days, err := store.GetListenCountsByDay(ctx, db.GetListenCountsByDayOpts{UserID: userID, Timeframe: tf, Timezone: loc})
+	if err != nil {
+		return nil, fmt.Errorf("GetBusiestDay: %w", err)
+	}
+	top, day := topListeningDay(days)
+	if top == nil {
+		return nil, nil
+	}
+	ret := &BusiestDay{TopListeningDay: *top}
+	if !includeTracks {
+		return ret, nil
+	}
+
+	ret.Tracks, err = store.GetListenLog(ctx, db.GetListenLogOpts{
+		UserID:    userID,
+		Timeframe: db.Timeframe{From: day, To: day.AddDate(0, 0, 1).Add(-time.Second)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetBusiestDay: %w", err)
+	}
+	return ret, nil
+}
+
+// topListeningDay returns the day with the most listens from counts keyed by each day's midnight,
+// along with that midnight, or nil when there are no days.
+func topListeningDay(days map[time.Time]int64) (*TopListeningDay, time.Time) {
+	var top *TopListeningDay
+	var topDay time.Time
+	for day, listens := range days {
+		// ties go to the earliest day, so the result does not depend on map order
+		if top == nil || listens > top.Listens || (listens == top.Listens && day.Before(topDay)) {
+			topDay = day
+			top = &TopListeningDay{Date: day.Format("2006-01-02"), Listens: listens}
+		}
+	}
+	return top, topDay
+}
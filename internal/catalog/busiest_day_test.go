@@ -0,0 +1,76 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBusiestDay(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	listens := []struct {
+		track string
+		time  time.Time
+	}{
+		{"Track 1", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"Track 2", time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"Track 3", time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)},
+		{"Track 1", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{"Track 4", time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{"Track 4", time.Date(2024, 1, 3, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, l := range listens {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Artist A",
+			TrackTitle:   l.track,
+			ReleaseTitle: "Release X",
+			Time:         l.time,
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	day, err := catalog.GetBusiestDay(ctx, store, 1, db.Timeframe{}, false)
+	require.NoError(t, err)
+	require.NotNil(t, day)
+	assert.Equal(t, "2024-01-02", day.Date)
+	assert.EqualValues(t, 3, day.Listens)
+	assert.Nil(t, day.Tracks)
+
+	day, err = catalog.GetBusiestDay(ctx, store, 1, db.Timeframe{}, true)
+	require.NoError(t, err)
+	require.Len(t, day.Tracks, 3)
+	assert.Equal(t, "Track 2", day.Tracks[0].Title)
+	assert.Equal(t, "Track 3", day.Tracks[1].Title)
+	assert.Equal(t, "Track 1", day.Tracks[2].Title)
+
+	// the early listens of Jan 2 UTC are still on Jan 1 in New York
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	day, err = catalog.GetBusiestDay(ctx, store, 1, db.Timeframe{Period: db.PeriodAllTime, Timezone: newYork}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01", day.Date)
+	assert.EqualValues(t, 3, day.Listens)
+	require.Len(t, day.Tracks, 3)
+	assert.Equal(t, "Track 1", day.Tracks[0].Title)
+	assert.Equal(t, "Track 3", day.Tracks[2].Title)
+
+	// only Jan 3
+	tf := db.Timeframe{From: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)}
+	day, err = catalog.GetBusiestDay(ctx, store, 1, tf, false)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-03", day.Date)
+	assert.EqualValues(t, 2, day.Listens)
+
+	day, err = catalog.GetBusiestDay(ctx, store, 2, db.Timeframe{}, true)
+	require.NoError(t, err)
+	assert.Nil(t, day)
+}
@@ -8,10 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 	"github.com/gabehf/koito/internal/mbz"
@@ -21,10 +21,15 @@ import (
 )
 
 type GetListensOpts struct {
-	ArtistID       int32
-	ReleaseGroupID int32
-	TrackID        int32
-	Limit          int
+	ArtistID  int32
+	ReleaseID int32
+	TrackID   int32
+	Client    string
+	Timeframe db.Timeframe
+	Limit     int
+	Page      int
+	// When set, only the user's listens are included, along with their private listens
+	UserID int32
 }
 
 type SaveListenOpts struct {
@@ -50,21 +55,52 @@ type SubmitListenOpts struct {
 	Artist             string
 	ArtistMbzIDs       []uuid.UUID
 	ArtistMbidMappings []ArtistMbidMap
+	ArtistSpotifyIDs   map[string]string // optional, artist name to Spotify artist ID
 	TrackTitle         string
+	TrackSpotifyID     string // optional, used to find the album image by the track's Spotify ID
 	RecordingMbzID     uuid.UUID
 	Duration           int32 // in seconds
 	ReleaseTitle       string
 	ReleaseMbzID       uuid.UUID
 	ReleaseGroupMbzID  uuid.UUID
 	Time               time.Time
+	// Optional, for clients that report several plays of the same track at once. When set, one
+	// listen is saved at each time and Time is ignored, while the track is only resolved once.
+	Times []time.Time
 
 	UserID       int32
 	Client       string
+	Device       string // optional, e.g. the platform reported by the source
 	IsNowPlaying bool
+	// Private listens count towards the user's own stats, but are left out of global charts
+	// and comparisons with other users.
+	Private bool
+
+	// Set for listens submitted in real time by a client rather than imported.
+	// Live listens are subject to the configured scrobble quiet hours.
+	IsLive bool
+
+	// Optional, identifies the listen so that a retried submission of it is only saved once. Listens
+	// without one are matched against the saved listens to the same track instead.
+	DedupeKey string
+	// Listens less than this long after or before a saved listen to the same track are not saved, and
+	// neither are listens with the dedupe key of one submitted this long ago. When zero, only listens at
+	// the very same time are left out.
+	DedupeWindow time.Duration
+}
+
+// SubmitListenResult reports what became of a submitted listen.
+type SubmitListenResult struct {
+	// whether the listen was saved, or at least one of them when several times were submitted
+	Inserted bool
+	// whether the listen was left out as a duplicate of one already saved
+	Deduped bool
 }
 
 const (
 	ImageSourceUserUpload = "User Upload"
+	// Image source of album images copied from the album's artist when no album image could be found.
+	ImageSourceArtistFallback = "Artist Fallback"
 )
 
 type submitListenStore interface {
@@ -75,14 +111,130 @@ type submitListenStore interface {
 }
 
 func SubmitListen(ctx context.Context, store submitListenStore, opts SubmitListenOpts) error {
+	_, err := SubmitListenWithResult(ctx, store, opts)
+	return err
+}
+
+// SubmitListenWithResult submits the listen like SubmitListen, reporting whether it was saved or left
+// out as a duplicate.
+func SubmitListenWithResult(ctx context.Context, store submitListenStore, opts SubmitListenOpts) (SubmitListenResult, error) {
 	l := logger.FromContext(ctx)
+	var result SubmitListenResult
 
 	if opts.Artist == "" || opts.TrackTitle == "" {
-		return errors.New("track name and artist are required")
+		return result, errors.New("track name and artist are required")
 	}
 
-	// bandaid to ensure new activity does not have sub-second precision
-	opts.Time = opts.Time.Truncate(time.Second)
+	times := listenTimes(ctx, opts)
+	if len(times) == 0 {
+		return result, nil
+	}
+	if dedupeKeySeen(opts) {
+		l.Info().Msgf("SubmitListen: Ignoring listen '%s' by %s with dedupe key '%s', which was already submitted", opts.TrackTitle, opts.Artist, opts.DedupeKey)
+		result.Deduped = true
+		return result, nil
+	}
+
+	resolved, err := resolveListen(ctx, store, opts)
+	if err != nil {
+		return result, fmt.Errorf("SubmitListenWithResult: %w", err)
+	}
+	track := resolved.track
+	setNowPlaying(opts, track)
+
+	if opts.SkipSaveListen {
+		return result, nil
+	}
+
+	for _, t := range times {
+		if opts.DedupeWindow > 0 {
+			dup, err := store.ListenExistsWithin(ctx, db.ListenExistsWithinOpts{TrackID: track.ID, UserID: opts.UserID, Time: t, Window: opts.DedupeWindow})
+			if err != nil {
+				return result, fmt.Errorf("SubmitListenWithResult: %w", err)
+			}
+			if dup {
+				l.Info().Msgf("SubmitListen: Ignoring listen to '%s' at %s, within %s of a saved listen to it", track.Title, t.Format(time.RFC3339), opts.DedupeWindow)
+				result.Deduped = true
+				continue
+			}
+		}
+		l.Info().Msgf("Received listen: '%s' by %s, from release '%s'", track.Title, buildArtistStr(resolved.artists), resolved.album.Title)
+
+		saved, err := store.SaveListen(ctx, db.SaveListenOpts{
+			TrackID: track.ID,
+			Time:    t,
+			UserID:  opts.UserID,
+			Client:  opts.Client,
+			Device:  opts.Device,
+			Private: opts.Private,
+		})
+		if err != nil {
+			return result, fmt.Errorf("SubmitListenWithResult: %w", err)
+		}
+		// a retried submission is not an error, so the client does not retry it again
+		if !saved {
+			l.Debug().Msgf("Ignored listen to '%s' at %s, which was already saved", track.Title, t)
+			result.Deduped = true
+			continue
+		}
+		result.Inserted = true
+	}
+	if result.Inserted {
+		rememberDedupeKey(opts)
+	}
+	return result, nil
+}
+
+func dedupeKeyName(opts SubmitListenOpts) string {
+	return fmt.Sprintf("listen_dedupe:%d:%s", opts.UserID, opts.DedupeKey)
+}
+
+// dedupeKeySeen reports whether a listen with the same dedupe key was saved within the dedupe window.
+func dedupeKeySeen(opts SubmitListenOpts) bool {
+	if opts.DedupeKey == "" {
+		return false
+	}
+	_, ok := memkv.Store.Get(dedupeKeyName(opts))
+	return ok
+}
+
+// rememberDedupeKey records that the listen with the dedupe key was saved, for the dedupe window, or
+// as long as the store keeps its items by default when there is none.
+func rememberDedupeKey(opts SubmitListenOpts) {
+	if opts.DedupeKey == "" {
+		return
+	}
+	if opts.DedupeWindow > 0 {
+		memkv.Store.Set(dedupeKeyName(opts), true, opts.DedupeWindow)
+	} else {
+		memkv.Store.Set(dedupeKeyName(opts), true)
+	}
+}
+
+// resolvedListen is what the metadata of a listen resolves to.
+type resolvedListen struct {
+	artists []*models.Artist
+	album   *models.Album
+	track   *models.Track
+	// whether the track has a duration, either saved before or updated from the listen
+	hasDuration bool
+}
+
+// resolveListen associates or creates the artists, album and track of the listen, updating the
+// track's duration if it has none.
+func resolveListen(ctx context.Context, store submitListenStore, opts SubmitListenOpts) (*resolvedListen, error) {
+	l := logger.FromContext(ctx)
+
+	// listens to compilation tracks are attributed to the performers, while the album stays with Various Artists
+	var compilationArtist string
+	if performers := compilationTrackArtists(ctx, opts); len(performers) > 0 {
+		l.Debug().Msgf("SubmitListen: Attributing compilation track '%s' to %v", opts.TrackTitle, performers)
+		compilationArtist = opts.Artist
+		opts.Artist = performers[0].Artist
+		opts.ArtistMbidMappings = performers
+		opts.ArtistNames = nil
+		opts.ArtistMbzIDs = nil
+	}
 
 	artists, err := AssociateArtists(
 		ctx,
@@ -92,13 +244,14 @@ func SubmitListen(ctx context.Context, store submitListenStore, opts SubmitListe
 			ArtistNames:    opts.ArtistNames,
 			ArtistName:     opts.Artist,
 			ArtistMbidMap:  opts.ArtistMbidMappings,
+			SpotifyIDs:     opts.ArtistSpotifyIDs,
 			Mbzc:           opts.MbzCaller,
 			TrackTitle:     opts.TrackTitle,
 			SkipCacheImage: opts.SkipCacheImage,
 		})
 	if err != nil {
 		l.Err(err).Msg("Failed to associate artists to listen")
-		return fmt.Errorf("SubmitListen: %w", err)
+		return nil, fmt.Errorf("resolveListen: %w", err)
 	} else if len(artists) < 1 {
 		l.Debug().Msg("Failed to associate any artists to release")
 	}
@@ -109,18 +262,42 @@ func SubmitListen(ctx context.Context, store submitListenStore, opts SubmitListe
 		artistIDs[i] = artist.ID
 		l.Debug().Any("artist", artist).Msg("Matched listen to artist")
 	}
-	rg, err := AssociateAlbum(ctx, store, AssociateAlbumOpts{
-		ReleaseMbzID:      opts.ReleaseMbzID,
-		ReleaseGroupMbzID: opts.ReleaseGroupMbzID,
-		ReleaseName:       opts.ReleaseTitle,
-		TrackName:         opts.TrackTitle,
-		Mbzc:              opts.MbzCaller,
-		Artists:           artists,
-		SkipCacheImage:    opts.SkipCacheImage,
-	})
+	albumArtists := artists
+	if compilationArtist != "" {
+		albumArtists, err = AssociateArtists(ctx, store, AssociateArtistsOpts{
+			ArtistNames:    []string{compilationArtist},
+			Mbzc:           opts.MbzCaller,
+			SkipCacheImage: opts.SkipCacheImage,
+		})
+		if err != nil {
+			l.Err(err).Msg("Failed to associate compilation artist to listen")
+			return nil, fmt.Errorf("resolveListen: %w", err)
+		}
+	}
+	rg, err := matchAlbumByPolicy(ctx, store, opts, artistIDs)
+	if err == nil && rg == nil {
+		rg, err = AssociateAlbum(ctx, store, AssociateAlbumOpts{
+			ReleaseMbzID:      opts.ReleaseMbzID,
+			ReleaseGroupMbzID: opts.ReleaseGroupMbzID,
+			ReleaseName:       opts.ReleaseTitle,
+			TrackName:         opts.TrackTitle,
+			TrackSpotifyID:    opts.TrackSpotifyID,
+			Mbzc:              opts.MbzCaller,
+			Artists:           albumArtists,
+			SkipCacheImage:    opts.SkipCacheImage,
+		})
+	}
 	if err != nil {
 		l.Error().Err(err).Msg("Failed to associate release group to listen")
-		return fmt.Errorf("SubmitListen: %w", err)
+		return nil, fmt.Errorf("resolveListen: %w", err)
+	}
+	if compilationArtist != "" && !rg.VariousArtists {
+		err = store.UpdateAlbum(ctx, db.UpdateAlbumOpts{ID: rg.ID, VariousArtistsUpdate: true, VariousArtistsValue: true})
+		if err != nil {
+			l.Err(err).Msgf("Failed to mark album %s as a compilation", rg.Title)
+		} else {
+			rg.VariousArtists = true
+		}
 	}
 	l.Debug().Any("album", rg).Msg("Matched listen to release")
 
@@ -140,61 +317,165 @@ func SubmitListen(ctx context.Context, store submitListenStore, opts SubmitListe
 	})
 	if err != nil {
 		l.Error().Err(err).Msg("Failed to associate track to listen")
-		return fmt.Errorf("SubmitListen: %w", err)
+		return nil, fmt.Errorf("resolveListen: %w", err)
 	}
 	l.Debug().Any("track", track).Msg("Matched listen to track")
 
-	if track.Duration == 0 {
-		if opts.Duration != 0 {
-			l.Debug().Msg("Updating duration using request information")
-			err := store.UpdateTrack(ctx, db.UpdateTrackOpts{
-				ID:       track.ID,
-				Duration: opts.Duration,
-			})
-			if err != nil {
-				l.Err(err).Msgf("Failed to update duration for track %s", track.Title)
-			} else {
-				l.Info().Msgf("Duration updated to %d for track '%s'", opts.Duration, track.Title)
-			}
-		} else if track.MbzID != nil && *track.MbzID != uuid.Nil {
-			l.Debug().Msg("Attempting to update duration using MusicBrainz ID")
-			mbztrack, err := opts.MbzCaller.GetTrack(ctx, *track.MbzID)
-			if err != nil {
-				l.Err(err).Msg("Failed to make request to MusicBrainz")
-			} else {
-				err = store.UpdateTrack(ctx, db.UpdateTrackOpts{
-					ID:       track.ID,
-					Duration: int32(mbztrack.LengthMs / 1000),
-				})
-				if err != nil {
-					l.Err(err).Msgf("Failed to update duration for track %s", track.Title)
-				} else {
-					l.Info().Msgf("Duration updated to %d for track '%s'", mbztrack.LengthMs/1000, track.Title)
-				}
-			}
-		}
+	if err := tagSingle(ctx, store, rg, track); err != nil {
+		l.Err(err).Msgf("Failed to update single tag for album %s", rg.Title)
 	}
 
-	if opts.IsNowPlaying {
-		if track.Duration == 0 {
-			memkv.Store.Set(strconv.Itoa(int(opts.UserID)), track.ID)
-		} else {
-			memkv.Store.Set(strconv.Itoa(int(opts.UserID)), track.ID, time.Duration(track.Duration)*time.Second)
+	return &resolvedListen{
+		artists:     artists,
+		album:       rg,
+		track:       track,
+		hasDuration: track.Duration != 0 || updateTrackDuration(ctx, store, opts, track),
+	}, nil
+}
+
+// updateTrackDuration saves the duration of the listen as the track's, or the duration MusicBrainz
+// has for the track when the listen has none, reporting whether it was updated.
+func updateTrackDuration(ctx context.Context, store submitListenStore, opts SubmitListenOpts, track *models.Track) bool {
+	l := logger.FromContext(ctx)
+	if opts.Duration != 0 {
+		l.Debug().Msg("Updating duration using request information")
+		err := store.UpdateTrack(ctx, db.UpdateTrackOpts{
+			ID:       track.ID,
+			Duration: opts.Duration,
+		})
+		if err != nil {
+			l.Err(err).Msgf("Failed to update duration for track %s", track.Title)
+			return false
 		}
+		l.Info().Msgf("Duration updated to %d for track '%s'", opts.Duration, track.Title)
+		return true
+	}
+	if track.MbzID == nil || *track.MbzID == uuid.Nil {
+		return false
 	}
+	l.Debug().Msg("Attempting to update duration using MusicBrainz ID")
+	mbztrack, err := opts.MbzCaller.GetTrack(ctx, *track.MbzID)
+	if err != nil {
+		l.Err(err).Msg("Failed to make request to MusicBrainz")
+		return false
+	}
+	err = store.UpdateTrack(ctx, db.UpdateTrackOpts{
+		ID:       track.ID,
+		Duration: int32(mbztrack.LengthMs / 1000),
+	})
+	if err != nil {
+		l.Err(err).Msgf("Failed to update duration for track %s", track.Title)
+		return false
+	}
+	l.Info().Msgf("Duration updated to %d for track '%s'", mbztrack.LengthMs/1000, track.Title)
+	return true
+}
 
-	if opts.SkipSaveListen {
-		return nil
+// setNowPlaying records the track as the user's now playing track, if the listen is one.
+func setNowPlaying(opts SubmitListenOpts, track *models.Track) {
+	if !opts.IsNowPlaying {
+		return
 	}
+	storeNowPlaying(opts.UserID, track.ID, track.Duration, opts.Client)
+}
 
-	l.Info().Msgf("Received listen: '%s' by %s, from release '%s'", track.Title, buildArtistStr(artists), rg.Title)
+// listenTimes returns the times of the listen, to the second, leaving out those in scrobble quiet
+// hours for live listens.
+func listenTimes(ctx context.Context, opts SubmitListenOpts) []time.Time {
+	l := logger.FromContext(ctx)
+	times := opts.Times
+	if len(times) == 0 {
+		times = []time.Time{opts.Time}
+	}
+	ret := make([]time.Time, 0, len(times))
+	for _, t := range times {
+		// bandaid to ensure new activity does not have sub-second precision
+		t = t.Truncate(time.Second)
+		if opts.IsLive && inQuietHours(t) {
+			l.Info().Msgf("SubmitListen: Skipping listen '%s' by %s at %s, as it falls within scrobble quiet hours", opts.TrackTitle, opts.Artist, t.Format(time.RFC3339))
+			continue
+		}
+		ret = append(ret, t)
+	}
+	return ret
+}
 
-	return store.SaveListen(ctx, db.SaveListenOpts{
-		TrackID: track.ID,
-		Time:    opts.Time,
-		UserID:  opts.UserID,
-		Client:  opts.Client,
+// GetListens returns a page of listens matching all of the provided filters.
+// Filters left at their zero value are ignored.
+func GetListens(ctx context.Context, store db.ListenStore, opts GetListensOpts) (*db.PaginatedResponse[*models.Listen], error) {
+	if opts.Limit < 0 || opts.Page < 0 {
+		return nil, errors.New("GetListens: limit and page must not be negative")
+	}
+	listens, err := store.GetListens(ctx, db.GetListensOpts{
+		Limit:     opts.Limit,
+		Page:      opts.Page,
+		Timeframe: opts.Timeframe,
+		ArtistID:  opts.ArtistID,
+		ReleaseID: opts.ReleaseID,
+		TrackID:   opts.TrackID,
+		Client:    opts.Client,
+		UserID:    opts.UserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetListens: %w", err)
+	}
+	return listens, nil
+}
+
+// matchAlbumByPolicy chooses between the albums a track already appears on, according to the
+// configured album match policy. It only applies to listens that carry no release information,
+// and returns a nil album when the policy is disabled or no existing track matches.
+func matchAlbumByPolicy(ctx context.Context, store submitListenStore, opts SubmitListenOpts, artistIDs []int32) (*models.Album, error) {
+	l := logger.FromContext(ctx)
+	policy := cfg.AlbumMatchPolicy()
+	if policy == cfg.AlbumMatchPolicyNone || len(artistIDs) < 1 {
+		return nil, nil
+	}
+	if opts.ReleaseTitle != "" || opts.ReleaseMbzID != uuid.Nil || opts.ReleaseGroupMbzID != uuid.Nil {
+		return nil, nil
+	}
+	candidates, err := store.GetTrackAlbumCandidates(ctx, db.GetTrackAlbumCandidatesOpts{
+		Title:     opts.TrackTitle,
+		ArtistIDs: artistIDs,
+		UserID:    opts.UserID,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("matchAlbumByPolicy: %w", err)
+	}
+	if len(candidates) < 1 {
+		return nil, nil
+	}
+
+	// candidates are ordered by album id, so ties go to the album added first
+	chosen := candidates[0]
+	if policy == cfg.AlbumMatchPolicyMostPlayed {
+		for _, c := range candidates[1:] {
+			if c.Listens > chosen.Listens {
+				chosen = c
+			}
+		}
+	}
+
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: chosen.AlbumID})
+	if err != nil {
+		return nil, fmt.Errorf("matchAlbumByPolicy: %w", err)
+	}
+	l.Debug().Msgf("Matched track '%s' to existing album '%s' using the '%s' album match policy", opts.TrackTitle, album.Title, policy)
+	return album, nil
+}
+
+// inQuietHours reports whether t falls within the configured scrobble quiet hours,
+// evaluated in the forced timezone if set, or the server's local timezone otherwise.
+func inQuietHours(t time.Time) bool {
+	q := cfg.ScrobbleQuietHours()
+	if q == nil {
+		return false
+	}
+	loc := cfg.ForceTZ()
+	if loc == nil {
+		loc = time.Local
+	}
+	return q.Contains(t.In(loc))
 }
 
 func buildArtistStr(artists []*models.Artist) string {
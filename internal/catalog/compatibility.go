@@ -0,0 +1,110 @@
+package catalog
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// number of top artists and tracks from each user considered when computing compatibility
+const compatibilityChartSize = 50
+
+var ErrUserNotComparable = errors.New("user has not opted in to listening comparisons")
+
+type SharedItem struct {
+	ID       int32  `json:"id"`
+	Name     string `json:"name"`
+	ListensA int64  `json:"listens_a"`
+	ListensB int64  `json:"listens_b"`
+}
+
+type UserCompatibility struct {
+	// 0-100, the average overlap of both users' top artists and top tracks
+	Score         int          `json:"score"`
+	SharedArtists []SharedItem `json:"shared_artists"`
+	SharedTracks  []SharedItem `json:"shared_tracks"`
+}
+
+type compatibilityStore interface {
+	GetUserTopArtists(ctx context.Context, opts db.GetUserTopItemsOpts) ([]db.UserTopItem, error)
+	GetUserTopTracks(ctx context.Context, opts db.GetUserTopItemsOpts) ([]db.UserTopItem, error)
+}
+
+// GetUserCompatibility compares the top artists and tracks of two users within the timeframe, returning
+// a compatibility score and the favorites they share. Both users must have opted in to comparisons.
+func GetUserCompatibility(ctx context.Context, store compatibilityStore, userA, userB *models.User, tf db.Timeframe) (*UserCompatibility, error) {
+	if userA == nil || userB == nil {
+		return nil, errors.New("GetUserCompatibility: both users are required")
+	}
+	if !userA.Comparable || !userB.Comparable {
+		return nil, fmt.Errorf("GetUserCompatibility: %w", ErrUserNotComparable)
+	}
+
+	if _, t2 := db.TimeframeToTimeRange(tf); t2.IsZero() {
+		tf.Period = db.PeriodAllTime
+	}
+
+	optsA := db.GetUserTopItemsOpts{UserID: userA.ID, Timeframe: tf, Limit: compatibilityChartSize, ExcludePrivate: true}
+	optsB := db.GetUserTopItemsOpts{UserID: userB.ID, Timeframe: tf, Limit: compatibilityChartSize, ExcludePrivate: true}
+
+	artistsA, err := store.GetUserTopArtists(ctx, optsA)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserCompatibility: %w", err)
+	}
+	artistsB, err := store.GetUserTopArtists(ctx, optsB)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserCompatibility: %w", err)
+	}
+	tracksA, err := store.GetUserTopTracks(ctx, optsA)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserCompatibility: %w", err)
+	}
+	tracksB, err := store.GetUserTopTracks(ctx, optsB)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserCompatibility: %w", err)
+	}
+
+	sharedArtists, artistOverlap := intersectTopItems(artistsA, artistsB)
+	sharedTracks, trackOverlap := intersectTopItems(tracksA, tracksB)
+
+	return &UserCompatibility{
+		Score:         int(math.Round((artistOverlap + trackOverlap) / 2 * 100)),
+		SharedArtists: sharedArtists,
+		SharedTracks:  sharedTracks,
+	}, nil
+}
+
+// intersectTopItems returns the items present in both charts, ordered by combined listens,
+// along with the Jaccard index of the two charts.
+func intersectTopItems(a, b []db.UserTopItem) ([]SharedItem, float64) {
+	inA := make(map[int32]db.UserTopItem, len(a))
+	for _, item := range a {
+		inA[item.ID] = item
+	}
+	shared := make([]SharedItem, 0)
+	for _, item := range b {
+		if match, ok := inA[item.ID]; ok {
+			shared = append(shared, SharedItem{
+				ID:       item.ID,
+				Name:     item.Name,
+				ListensA: match.Listens,
+				ListensB: item.Listens,
+			})
+		}
+	}
+	slices.SortStableFunc(shared, func(x, y SharedItem) int {
+		return cmp.Compare(y.ListensA+y.ListensB, x.ListensA+x.ListensB)
+	})
+
+	union := len(a) + len(b) - len(shared)
+	if union == 0 {
+		return shared, 0
+	}
+	return shared, float64(len(shared)) / float64(union)
+}
@@ -0,0 +1,90 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserCompatibility(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	mbzc := &mbz.MbzMockCaller{}
+
+	_, err := store.SaveUser(ctx, db.SaveUserOpts{Username: "second", Password: "password123"})
+	require.NoError(t, err)
+
+	submit := func(userID int32, artist, track string, at time.Time) {
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    mbzc,
+			Artist:       artist,
+			TrackTitle:   track,
+			ReleaseTitle: track,
+			Time:         at,
+			UserID:       userID,
+		}))
+	}
+	now := time.Now()
+	// user 1: Artist A (Shared Song x2), Artist B
+	submit(1, "Artist A", "Shared Song", now.Add(-1*time.Hour))
+	submit(1, "Artist A", "Shared Song", now.Add(-2*time.Hour))
+	submit(1, "Artist B", "Only Mine", now.Add(-3*time.Hour))
+	// user 2: Artist A (Shared Song), Artist C
+	submit(2, "Artist A", "Shared Song", now.Add(-4*time.Hour))
+	submit(2, "Artist C", "Only Theirs", now.Add(-5*time.Hour))
+
+	userA, err := store.GetUserByUsername(ctx, "test")
+	require.NoError(t, err)
+	userB, err := store.GetUserByUsername(ctx, "second")
+	require.NoError(t, err)
+
+	// neither user has opted in
+	_, err = catalog.GetUserCompatibility(ctx, store, userA, userB, db.Timeframe{})
+	assert.ErrorIs(t, err, catalog.ErrUserNotComparable)
+
+	comparable := true
+	require.NoError(t, store.UpdateUser(ctx, db.UpdateUserOpts{ID: userA.ID, Comparable: &comparable}))
+	userA, err = store.GetUserByUsername(ctx, "test")
+	require.NoError(t, err)
+	assert.True(t, userA.Comparable)
+
+	// only one user has opted in
+	_, err = catalog.GetUserCompatibility(ctx, store, userA, userB, db.Timeframe{})
+	assert.ErrorIs(t, err, catalog.ErrUserNotComparable)
+
+	require.NoError(t, store.UpdateUser(ctx, db.UpdateUserOpts{ID: userB.ID, Comparable: &comparable}))
+	userB, err = store.GetUserByUsername(ctx, "second")
+	require.NoError(t, err)
+
+	result, err := catalog.GetUserCompatibility(ctx, store, userA, userB, db.Timeframe{})
+	require.NoError(t, err)
+	require.Len(t, result.SharedArtists, 1)
+	assert.Equal(t, "Artist A", result.SharedArtists[0].Name)
+	assert.EqualValues(t, 2, result.SharedArtists[0].ListensA)
+	assert.EqualValues(t, 1, result.SharedArtists[0].ListensB)
+	require.Len(t, result.SharedTracks, 1)
+	assert.Equal(t, "Shared Song", result.SharedTracks[0].Name)
+	// artists and tracks each overlap 1 of 3
+	assert.Equal(t, 33, result.Score)
+
+	// timeframe excluding user 2's shared listen
+	result, err = catalog.GetUserCompatibility(ctx, store, userA, userB, db.Timeframe{From: now.Add(-150 * time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, result.SharedArtists)
+	assert.Equal(t, 0, result.Score)
+
+	// private listens are left out of comparisons
+	n, err := store.SetListensPrivate(ctx, db.SetListensPrivateOpts{UserID: userB.ID, From: now.Add(-5 * time.Hour), To: now, Private: true})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	result, err = catalog.GetUserCompatibility(ctx, store, userA, userB, db.Timeframe{})
+	require.NoError(t, err)
+	assert.Empty(t, result.SharedArtists)
+	assert.Equal(t, 0, result.Score)
+}
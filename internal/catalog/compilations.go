@@ -0,0 +1,56 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/memkv"
+	"github.com/google/uuid"
+)
+
+// the catch-all album artist of compilations in MusicBrainz and most services' exports
+const variousArtistsName = "Various Artists"
+
+// how long the performers found for a compilation track are remembered, so that every listen
+// to the same track does not cost another MusicBrainz lookup
+const compilationArtistsCacheTTL = 24 * time.Hour
+
+func isVariousArtists(name string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), variousArtistsName)
+}
+
+// compilationTrackArtists returns the performers of a track credited to Various Artists, as credited
+// on MusicBrainz, or nil when the listen is not to a compilation or its performers could not be found.
+func compilationTrackArtists(ctx context.Context, opts SubmitListenOpts) []ArtistMbidMap {
+	if !cfg.ResolveCompilationArtists() || opts.MbzCaller == nil || opts.ReleaseTitle == "" || !isVariousArtists(opts.Artist) {
+		return nil
+	}
+	l := logger.FromContext(ctx)
+
+	key := fmt.Sprintf("compilation_artists_%s_%s", strings.ToLower(opts.ReleaseTitle), strings.ToLower(opts.TrackTitle))
+	if cached, ok := memkv.Store.Get(key); ok {
+		if performers, ok := cached.([]ArtistMbidMap); ok {
+			return performers
+		}
+	}
+
+	credits, err := opts.MbzCaller.SearchRecordingArtists(ctx, opts.ReleaseTitle, opts.TrackTitle)
+	if err != nil {
+		l.Debug().AnErr("error", err).Msgf("compilationTrackArtists: Failed to find performers of '%s' on %s", opts.TrackTitle, opts.ReleaseTitle)
+		return nil
+	}
+	var performers []ArtistMbidMap
+	for _, credit := range credits {
+		id, err := uuid.Parse(credit.Artist.ID)
+		if err != nil || isVariousArtists(credit.Name) {
+			continue
+		}
+		performers = append(performers, ArtistMbidMap{Artist: credit.Name, Mbid: id})
+	}
+	memkv.Store.Set(key, performers, compilationArtistsCacheTTL)
+	return performers
+}
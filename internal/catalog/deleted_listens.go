@@ -0,0 +1,78 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+// deletedListensCutoff returns the time before which deleted listens are no longer kept
+func deletedListensCutoff() time.Time {
+	return time.Now().AddDate(0, 0, -cfg.SoftDeleteRetentionDays())
+}
+
+// DeleteListen deletes a listen of the user, returning db.ErrNotFound when the user has no such listen.
+// The listen can be restored until it is purged, unless opts.Purge is true, in which case it is deleted
+// permanently along with the track, album and artists that are left without listens.
+func DeleteListen(ctx context.Context, store db.ListenStore, opts db.DeleteListenOpts) error {
+	l := logger.FromContext(ctx)
+	found, err := store.DeleteUserListen(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("DeleteListen: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("DeleteListen: %w", db.ErrNotFound)
+	}
+	l.Info().Msgf("DeleteListen: Deleted listen to track %d at %s", opts.TrackID, opts.ListenedAt.Format(time.RFC3339))
+	return nil
+}
+
+// DeleteListensInRange deletes the user's listens between from and to, such as the listens of an
+// import that went wrong, returning the number deleted. When client is not nil, only the listens
+// submitted by that client are deleted. The listens can be restored until they are purged.
+func DeleteListensInRange(ctx context.Context, store db.ListenStore, userID int32, from, to time.Time, client *string) (int64, error) {
+	l := logger.FromContext(ctx)
+	if to.Before(from) {
+		return 0, errors.New("DeleteListensInRange: the end of the range is before its start")
+	}
+	n, err := store.DeleteListensInRange(ctx, db.DeleteListensOpts{UserID: userID, From: from, To: to, Client: client})
+	if err != nil {
+		return 0, fmt.Errorf("DeleteListensInRange: %w", err)
+	}
+	l.Info().Msgf("DeleteListensInRange: Deleted %d listens between %s and %s", n, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	return n, nil
+}
+
+// RestoreListens restores deleted listens selected by opts, returning the number restored. Only listens
+// deleted within the retention window set by cfg.SoftDeleteRetentionDays can be restored.
+func RestoreListens(ctx context.Context, store db.ListenStore, opts db.RestoreListensOpts) (int64, error) {
+	if opts.TrackID != 0 && opts.ListenedAt.IsZero() {
+		return 0, errors.New("RestoreListens: listen time is required when restoring a single listen")
+	}
+	if cutoff := deletedListensCutoff(); opts.DeletedSince.Before(cutoff) {
+		opts.DeletedSince = cutoff
+	}
+	n, err := store.RestoreListens(ctx, opts)
+	if err != nil {
+		return 0, fmt.Errorf("RestoreListens: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeDeletedListens permanently removes listens that were deleted longer ago than the retention
+// window set by cfg.SoftDeleteRetentionDays.
+func PurgeDeletedListens(ctx context.Context, store db.ListenStore) error {
+	l := logger.FromContext(ctx)
+	n, err := store.PurgeDeletedListens(ctx, deletedListensCutoff())
+	if err != nil {
+		l.Err(err).Msg("PurgeDeletedListens: Failed to purge deleted listens")
+		return fmt.Errorf("PurgeDeletedListens: %w", err)
+	}
+	l.Info().Msgf("PurgeDeletedListens: Purged %d deleted listens", n)
+	return nil
+}
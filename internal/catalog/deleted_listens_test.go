@@ -0,0 +1,215 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteAndRestoreListens(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+	allTime := db.Timeframe{Period: db.PeriodAllTime}
+
+	artistB, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist B"})
+	require.NoError(t, err)
+	listensB, err := catalog.GetListens(ctx, store, catalog.GetListensOpts{ArtistID: artistB.ID})
+	require.NoError(t, err)
+	require.Len(t, listensB.Items, 2)
+
+	// delete both listens of Artist B
+	for _, listen := range listensB.Items {
+		require.NoError(t, store.DeleteListen(ctx, listen.Track.ID, listen.Time))
+	}
+
+	count, err := store.CountListens(ctx, db.CountOpts{Timeframe: allTime})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, count)
+	resp, err := catalog.GetListens(ctx, store, catalog.GetListensOpts{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, resp.TotalCount)
+	artistB, err = store.GetArtist(ctx, db.GetArtistOpts{ID: artistB.ID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, artistB.ListenCount)
+
+	// the rows are kept until they are purged
+	rows, err := store.Count(`SELECT COUNT(*) FROM all_listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 6, rows)
+
+	// restore a single listen
+	deleted := listensB.Items[0]
+	restored, err := catalog.RestoreListens(ctx, store, db.RestoreListensOpts{UserID: 1, TrackID: deleted.Track.ID, ListenedAt: deleted.Time})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, restored)
+	count, err = store.CountListens(ctx, db.CountOpts{Timeframe: allTime})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, count)
+
+	// restore everything else
+	restored, err = catalog.RestoreListens(ctx, store, db.RestoreListensOpts{UserID: 1})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, restored)
+	artistB, err = store.GetArtist(ctx, db.GetArtistOpts{ID: artistB.ID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, artistB.ListenCount)
+
+	_, err = catalog.RestoreListens(ctx, store, db.RestoreListensOpts{TrackID: deleted.Track.ID})
+	assert.Error(t, err)
+}
+
+func TestRestoreAndPurgeOutsideRetention(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	resp, err := catalog.GetListens(ctx, store, catalog.GetListensOpts{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 2)
+	for _, listen := range resp.Items {
+		require.NoError(t, store.DeleteListen(ctx, listen.Track.ID, listen.Time))
+	}
+	// one of the listens was deleted long enough ago to fall outside of the retention window
+	old := resp.Items[0]
+	require.NoError(t, store.Exec(`UPDATE all_listens SET deleted_at = ? WHERE track_id = ? AND listened_at = ?`,
+		time.Now().AddDate(-1, 0, 0).Unix(), old.Track.ID, old.Time.Unix()))
+
+	restored, err := catalog.RestoreListens(ctx, store, db.RestoreListensOpts{TrackID: old.Track.ID, ListenedAt: old.Time})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, restored)
+
+	require.NoError(t, catalog.PurgeDeletedListens(ctx, store))
+	rows, err := store.Count(`SELECT COUNT(*) FROM all_listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 5, rows)
+
+	// the listen deleted within the window is untouched by the purge and can still be restored
+	restored, err = catalog.RestoreListens(ctx, store, db.RestoreListensOpts{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, restored)
+	count, err := store.CountListens(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, count)
+}
+
+func TestDeleteListen(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// two listens to one track, and one to another track of the same album
+	track := submitTrackListens(t, store, "Creep", "Pablo Honey", base, 2)
+	other := submitTrackListens(t, store, "Lurgee", "Pablo Honey", base.Add(24*time.Hour), 1)
+
+	// another user's listen is not found
+	err := catalog.DeleteListen(ctx, store, db.DeleteListenOpts{UserID: 2, TrackID: track.ID, ListenedAt: base})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	err = catalog.DeleteListen(ctx, store, db.DeleteListenOpts{UserID: 1, TrackID: track.ID, ListenedAt: base.Add(time.Minute)})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// deleted listens can still be restored, so the track is kept, and deleting again succeeds
+	opts := db.DeleteListenOpts{UserID: 1, TrackID: track.ID, ListenedAt: base}
+	require.NoError(t, catalog.DeleteListen(ctx, store, opts))
+	require.NoError(t, catalog.DeleteListen(ctx, store, opts))
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: track.ID})
+	require.NoError(t, err)
+
+	// the track is kept while it has a listen left, even one that was deleted but not purged
+	opts.ListenedAt = base.Add(time.Hour)
+	opts.Purge = true
+	require.NoError(t, catalog.DeleteListen(ctx, store, opts))
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: track.ID})
+	require.NoError(t, err)
+
+	opts.ListenedAt = base
+	require.NoError(t, catalog.DeleteListen(ctx, store, opts))
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: track.ID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	// the album and artist still have a listen to the other track
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: other.AlbumID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, album.ListenCount)
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Radiohead"})
+	require.NoError(t, err)
+
+	// a track that is playing has no listens yet
+	require.NoError(t, catalog.SetNowPlaying(ctx, store, catalog.NowPlayingOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Portishead",
+		TrackTitle:   "Roads",
+		ReleaseTitle: "Dummy",
+		UserID:       1,
+	}))
+	playing, err := catalog.GetNowPlaying(ctx, store, 1)
+	require.NoError(t, err)
+	require.NotNil(t, playing)
+
+	// purging the last listen removes the track, album and artist
+	require.NoError(t, catalog.DeleteListen(ctx, store, db.DeleteListenOpts{UserID: 1, TrackID: other.ID, ListenedAt: base.Add(24 * time.Hour), Purge: true}))
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: other.ID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = store.GetAlbum(ctx, db.GetAlbumOpts{ID: other.AlbumID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = store.GetArtist(ctx, db.GetArtistOpts{ID: artist.ID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	// but leaves the unrelated track without listens alone
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: playing.Track.ID})
+	assert.NoError(t, err)
+	_, err = store.GetAlbum(ctx, db.GetAlbumOpts{ID: playing.Track.AlbumID})
+	assert.NoError(t, err)
+	_, err = store.GetArtist(ctx, db.GetArtistOpts{Name: "Portishead"})
+	assert.NoError(t, err)
+}
+
+func TestDeleteListensInRange(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('other', 0x123)`))
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	submit := func(userID int32, client string, at time.Time) {
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Radiohead",
+			TrackTitle:   "Creep",
+			ReleaseTitle: "Pablo Honey",
+			Time:         at,
+			UserID:       userID,
+			Client:       client,
+		}))
+	}
+	for i := range 3 {
+		submit(1, "spotify", base.Add(time.Duration(i)*time.Hour))
+	}
+	submit(1, "navidrome", base.Add(30*time.Minute))
+	// outside of the range, and another user's listen
+	submit(1, "spotify", base.Add(48*time.Hour))
+	submit(2, "spotify", base.Add(time.Hour+time.Minute))
+
+	_, err := catalog.DeleteListensInRange(ctx, store, 1, base.Add(time.Hour), base, nil)
+	assert.Error(t, err)
+
+	spotify := "spotify"
+	n, err := catalog.DeleteListensInRange(ctx, store, 1, base, base.Add(2*time.Hour), &spotify)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+	count, err := store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+
+	n, err = catalog.DeleteListensInRange(ctx, store, 1, base, base.Add(2*time.Hour), nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	// deleted listens can be restored
+	restored, err := catalog.RestoreListens(ctx, store, db.RestoreListensOpts{UserID: 1})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, restored)
+}
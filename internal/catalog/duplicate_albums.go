@@ -0,0 +1,73 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+// MergeDuplicateAlbums merges albums by the same artist whose titles only differ in case, accents or
+// punctuation, returning the number of albums merged away. Each group is merged into the album with a
+// MusicBrainz ID if there is one, otherwise into the oldest album, whose title is kept. Albums with
+// different MusicBrainz IDs are never merged.
+func MergeDuplicateAlbums(ctx context.Context, store db.AlbumStore) (int, error) {
+	l := logger.FromContext(ctx)
+
+	titles, err := store.GetAlbumTitles(ctx, 0)
+	if err != nil {
+		return 0, fmt.Errorf("MergeDuplicateAlbums: %w", err)
+	}
+
+	type groupKey struct {
+		artistID int32
+		title    string
+	}
+	var keys []groupKey
+	groups := make(map[groupKey][]db.AlbumTitle)
+	for _, t := range titles {
+		k := groupKey{t.ArtistID, utils.NormalizeForMatching(t.Title)}
+		if k.title == "" {
+			continue
+		}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], t)
+	}
+
+	// an album with several artists is in several groups, so track where it has already gone
+	mergedInto := make(map[int32]int32)
+	count := 0
+	for _, k := range keys {
+		group := groups[k]
+		if len(group) < 2 {
+			continue
+		}
+		target := group[0]
+		for _, t := range group {
+			if t.MbzID != nil {
+				target = t
+				break
+			}
+		}
+		for _, t := range group {
+			if t.AlbumID == target.AlbumID || mergedInto[t.AlbumID] != 0 || mergedInto[target.AlbumID] != 0 {
+				continue
+			}
+			if t.MbzID != nil && target.MbzID != nil && *t.MbzID != *target.MbzID {
+				continue
+			}
+			l.Debug().Msgf("MergeDuplicateAlbums: Merging album '%s' (%d) into '%s' (%d)", t.Title, t.AlbumID, target.Title, target.AlbumID)
+			if err := store.MergeAlbums(ctx, t.AlbumID, target.AlbumID, false); err != nil {
+				return count, fmt.Errorf("MergeDuplicateAlbums: %w", err)
+			}
+			mergedInto[t.AlbumID] = target.AlbumID
+			count++
+		}
+	}
+	l.Info().Msgf("MergeDuplicateAlbums: Merged %d duplicate albums", count)
+	return count, nil
+}
@@ -0,0 +1,85 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func submitAlbumVariants(t *testing.T, store *sqlite.Sqlite, titles ...string) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, title := range titles {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Artist A",
+			TrackTitle:   "Track 1",
+			ReleaseTitle: title,
+			Time:         base.Add(time.Duration(i) * time.Hour),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestSubmitListen_FoldDiacriticsForMatching(t *testing.T) {
+	defer cfg.SetFoldDiacriticsForMatching(false)
+	ctx := context.Background()
+
+	cfg.SetFoldDiacriticsForMatching(false)
+	store := newTestDB()
+	submitAlbumVariants(t, store, "Café Vol. 1", "Cafe Vol 1", "CAFÉ: vol 1")
+	count, err := store.Count(`SELECT COUNT(*) FROM releases`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	cfg.SetFoldDiacriticsForMatching(true)
+	store = newTestDB()
+	submitAlbumVariants(t, store, "Café Vol. 1", "Cafe Vol 1", "CAFÉ: vol 1", "Café Vol. 2")
+	count, err = store.Count(`SELECT COUNT(*) FROM releases`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist A"})
+	require.NoError(t, err)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Café Vol. 1", ArtistID: artist.ID})
+	require.NoError(t, err)
+	// the first title seen is kept for display
+	assert.Equal(t, "Café Vol. 1", album.Title)
+	assert.EqualValues(t, 3, album.ListenCount)
+}
+
+func TestMergeDuplicateAlbums(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	submitAlbumVariants(t, store, "Café Vol. 1", "Cafe Vol 1", "CAFÉ: vol 1", "Café Vol. 2")
+	count, err := store.Count(`SELECT COUNT(*) FROM releases`)
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	merged, err := catalog.MergeDuplicateAlbums(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, merged)
+	count, err = store.Count(`SELECT COUNT(*) FROM releases`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist A"})
+	require.NoError(t, err)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Café Vol. 1", ArtistID: artist.ID})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, album.ListenCount)
+
+	// running again finds nothing left to merge
+	merged, err = catalog.MergeDuplicateAlbums(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 0, merged)
+}
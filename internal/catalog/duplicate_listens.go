@@ -0,0 +1,89 @@
+package catalog
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+// DuplicateListenCluster is a run of listens to the same track, each within the duplicate window of
+// the one before it. The first listen is the one kept when duplicates are deleted.
+type DuplicateListenCluster struct {
+	TrackID int32       `json:"track_id"`
+	Title   string      `json:"title"`
+	Listens []time.Time `json:"listens"`
+}
+
+// FindDuplicateListens returns the clusters of the user's listens to the same track that are within
+// window of each other, ordered by their first listen. Listens of a track that are further apart than
+// the window are not considered duplicates.
+func FindDuplicateListens(ctx context.Context, store db.ListenStore, userID int32, window time.Duration) ([]DuplicateListenCluster, error) {
+	if userID == 0 {
+		return nil, errors.New("FindDuplicateListens: user id must be provided")
+	}
+	if window <= 0 {
+		return nil, errors.New("FindDuplicateListens: window must be positive")
+	}
+
+	// chronological, so each track's listens are in order as well
+	log, err := store.GetListenLog(ctx, db.GetListenLogOpts{UserID: userID, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	if err != nil {
+		return nil, fmt.Errorf("FindDuplicateListens: %w", err)
+	}
+
+	open := make(map[int32]*DuplicateListenCluster)
+	clusters := make([]DuplicateListenCluster, 0)
+	closeCluster := func(c *DuplicateListenCluster) {
+		if len(c.Listens) > 1 {
+			clusters = append(clusters, *c)
+		}
+	}
+	for _, e := range log {
+		c, ok := open[e.TrackID]
+		if ok && e.Time.Sub(c.Listens[len(c.Listens)-1]) <= window {
+			c.Listens = append(c.Listens, e.Time)
+			continue
+		}
+		if ok {
+			closeCluster(c)
+		}
+		open[e.TrackID] = &DuplicateListenCluster{TrackID: e.TrackID, Title: e.Title, Listens: []time.Time{e.Time}}
+	}
+	for _, c := range open {
+		closeCluster(c)
+	}
+
+	slices.SortFunc(clusters, func(a, b DuplicateListenCluster) int {
+		return cmp.Or(a.Listens[0].Compare(b.Listens[0]), cmp.Compare(a.TrackID, b.TrackID))
+	})
+	return clusters, nil
+}
+
+// DeleteDuplicateListens deletes every listen but the first of each cluster found by FindDuplicateListens
+// in a single transaction, returning the number deleted. Deleted listens can be restored until they are
+// purged.
+func DeleteDuplicateListens(ctx context.Context, store db.ListenStore, userID int32, window time.Duration) (int, error) {
+	l := logger.FromContext(ctx)
+	clusters, err := FindDuplicateListens(ctx, store, userID, window)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteDuplicateListens: %w", err)
+	}
+	var listens []db.DeleteListenOpts
+	for _, c := range clusters {
+		for _, t := range c.Listens[1:] {
+			listens = append(listens, db.DeleteListenOpts{UserID: userID, TrackID: c.TrackID, ListenedAt: t})
+		}
+	}
+	deleted, err := store.DeleteUserListens(ctx, listens)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteDuplicateListens: %w", err)
+	}
+	l.Info().Msgf("DeleteDuplicateListens: Deleted %d duplicate listens from %d clusters", deleted, len(clusters))
+	return int(deleted), nil
+}
@@ -0,0 +1,82 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindDuplicateListens(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	submit := func(track string, offsets ...time.Duration) {
+		times := make([]time.Time, len(offsets))
+		for i, o := range offsets {
+			times[i] = base.Add(o)
+		}
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Artist",
+			TrackTitle:   track,
+			ReleaseTitle: "Release",
+			Times:        times,
+			UserID:       1,
+		}))
+	}
+	// each listen of Track A is within a minute of the previous one, though not of the first
+	submit("Track A", 0, 30*time.Second, 80*time.Second, 10*time.Minute)
+	submit("Track B", 5*time.Second, 2*time.Hour, 3*time.Hour, 3*time.Hour+20*time.Second)
+
+	clusters, err := catalog.FindDuplicateListens(ctx, store, 1, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+	assert.Equal(t, "Track A", clusters[0].Title)
+	require.Len(t, clusters[0].Listens, 3)
+	EqualTime(t, base, clusters[0].Listens[0])
+	EqualTime(t, base.Add(80*time.Second), clusters[0].Listens[2])
+	assert.Equal(t, "Track B", clusters[1].Title)
+	require.Len(t, clusters[1].Listens, 2)
+	EqualTime(t, base.Add(3*time.Hour), clusters[1].Listens[0])
+
+	// a narrower window splits the chain
+	clusters, err = catalog.FindDuplicateListens(ctx, store, 1, 25*time.Second)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, "Track B", clusters[0].Title)
+
+	_, err = catalog.FindDuplicateListens(ctx, store, 1, 0)
+	assert.Error(t, err)
+
+	// deleting keeps the first listen of each cluster
+	deleted, err := catalog.DeleteDuplicateListens(ctx, store, 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	clusters, err = catalog.FindDuplicateListens(ctx, store, 1, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, clusters)
+	count, err := store.CountListens(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, count)
+	deletedCount, err := store.Count(`SELECT COUNT(*) FROM all_listens WHERE deleted_at IS NOT NULL`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, deletedCount)
+
+	// listens are only deleted for the user they belong to
+	log, err := store.GetListenLog(ctx, db.GetListenLogOpts{UserID: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	require.NotEmpty(t, log)
+	n, err := store.DeleteUserListens(ctx, []db.DeleteListenOpts{{UserID: 2, TrackID: log[0].TrackID, ListenedAt: log[0].Time}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, n)
+	count, err = store.CountListens(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, count)
+}
@@ -0,0 +1,25 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+)
+
+// GetFavoriteArtists ranks artists by both how often and on how many distinct days they were listened
+// to within the timeframe, leaving out artists listened to on fewer days than the configured minimum, so
+// that sustained listening ranks above a one-day binge. When userID is 0, listens from all users are counted.
+func GetFavoriteArtists(ctx context.Context, store db.ArtistStore, userID int32, timeframe db.Timeframe) ([]db.FavoriteArtist, error) {
+	artists, err := store.GetFavoriteArtists(ctx, db.GetFavoriteArtistsOpts{
+		UserID:    userID,
+		Timeframe: timeframe,
+		MinDays:   cfg.FavoriteMinDays(),
+		Timezone:  timeframe.Timezone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetFavoriteArtists: %w", err)
+	}
+	return artists, nil
+}
@@ -0,0 +1,71 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFavoriteArtists(t *testing.T) {
+	defer cfg.SetFavoriteMinDays(cfg.FavoriteMinDays())
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	submit := func(artist string, at time.Time) {
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:  &mbz.MbzMockCaller{},
+			Artist:     artist,
+			TrackTitle: artist + " Track",
+			Time:       at,
+			UserID:     1,
+		}))
+	}
+	// a binge of 20 listens on a single day
+	for i := range 20 {
+		submit("Binge Artist", base.Add(time.Duration(i)*10*time.Minute))
+	}
+	// 8 listens spread over 8 days, and 6 over 3 days
+	for i := range 8 {
+		submit("Sustained Artist", base.AddDate(0, 0, i))
+	}
+	for i := range 6 {
+		submit("Occasional Artist", base.AddDate(0, 0, i/2).Add(time.Hour))
+	}
+	allTime := db.Timeframe{Period: db.PeriodAllTime}
+
+	// the binge has the most listens, but was only on one day
+	top, err := store.GetUserTopArtists(ctx, db.GetUserTopItemsOpts{UserID: 1, Timeframe: allTime})
+	require.NoError(t, err)
+	assert.Equal(t, "Binge Artist", top[0].Name)
+
+	cfg.SetFavoriteMinDays(3)
+	artists, err := catalog.GetFavoriteArtists(ctx, store, 1, allTime)
+	require.NoError(t, err)
+	require.Len(t, artists, 2)
+	assert.Equal(t, "Sustained Artist", artists[0].Name)
+	assert.EqualValues(t, 8, artists[0].Listens)
+	assert.EqualValues(t, 8, artists[0].Days)
+	assert.Equal(t, "Occasional Artist", artists[1].Name)
+	assert.EqualValues(t, 3, artists[1].Days)
+
+	// with no minimum, the binge is still ranked below sustained listening
+	cfg.SetFavoriteMinDays(1)
+	artists, err = catalog.GetFavoriteArtists(ctx, store, 1, allTime)
+	require.NoError(t, err)
+	require.Len(t, artists, 3)
+	assert.Equal(t, "Sustained Artist", artists[0].Name)
+	assert.Equal(t, "Binge Artist", artists[1].Name)
+	assert.EqualValues(t, 1, artists[1].Days)
+
+	cfg.SetFavoriteMinDays(10)
+	artists, err = catalog.GetFavoriteArtists(ctx, store, 1, allTime)
+	require.NoError(t, err)
+	assert.Empty(t, artists)
+}
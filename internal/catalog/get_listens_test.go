@@ -0,0 +1,119 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedGetListens submits six listens across two artists, three releases and
+// two clients, spaced one day apart starting at base.
+func seedGetListens(t *testing.T, store *sqlite.Sqlite, base time.Time) {
+	ctx := context.Background()
+	mbzc := &mbz.MbzMockCaller{}
+	listens := []struct {
+		artist, track, release, client string
+	}{
+		{"Artist A", "Track 1", "Release X", "player-a"},
+		{"Artist A", "Track 1", "Release X", "player-b"},
+		{"Artist A", "Track 2", "Release X", "player-a"},
+		{"Artist A", "Track 3", "Release Y", "player-b"},
+		{"Artist B", "Track 4", "Release Z", "player-a"},
+		{"Artist B", "Track 4", "Release Z", "player-a"},
+	}
+	for i, l := range listens {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    mbzc,
+			Artist:       l.artist,
+			TrackTitle:   l.track,
+			ReleaseTitle: l.release,
+			Client:       l.client,
+			Time:         base.Add(time.Duration(i) * 24 * time.Hour),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+}
+
+func TestGetListens_Filters(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	artistA, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist A"})
+	require.NoError(t, err)
+	artistB, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Artist B"})
+	require.NoError(t, err)
+	releaseX, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Release X", ArtistID: artistA.ID})
+	require.NoError(t, err)
+	track1, err := store.GetTrack(ctx, db.GetTrackOpts{Title: "Track 1", ReleaseID: releaseX.ID, ArtistIDs: []int32{artistA.ID}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		opts     catalog.GetListensOpts
+		expected int
+	}{
+		{"no filters", catalog.GetListensOpts{}, 6},
+		{"artist", catalog.GetListensOpts{ArtistID: artistA.ID}, 4},
+		{"release", catalog.GetListensOpts{ReleaseID: releaseX.ID}, 3},
+		{"track", catalog.GetListensOpts{TrackID: track1.ID}, 2},
+		{"client", catalog.GetListensOpts{Client: "player-a"}, 4},
+		{"timeframe", catalog.GetListensOpts{Timeframe: db.Timeframe{From: base, To: base.Add(48 * time.Hour)}}, 3},
+		{"artist and client", catalog.GetListensOpts{ArtistID: artistA.ID, Client: "player-b"}, 2},
+		{"artist and release", catalog.GetListensOpts{ArtistID: artistA.ID, ReleaseID: releaseX.ID}, 3},
+		{"release and track", catalog.GetListensOpts{ReleaseID: releaseX.ID, TrackID: track1.ID}, 2},
+		{"track and client", catalog.GetListensOpts{TrackID: track1.ID, Client: "player-b"}, 1},
+		{"artist and timeframe", catalog.GetListensOpts{ArtistID: artistA.ID, Timeframe: db.Timeframe{From: base.Add(48 * time.Hour)}}, 2},
+		{"release and client and timeframe", catalog.GetListensOpts{ReleaseID: releaseX.ID, Client: "player-a", Timeframe: db.Timeframe{From: base.Add(time.Hour)}}, 1},
+		{"disjoint artist and release", catalog.GetListensOpts{ArtistID: artistB.ID, ReleaseID: releaseX.ID}, 0},
+		{"unknown client", catalog.GetListensOpts{Client: "nope"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := catalog.GetListens(ctx, store, tt.opts)
+			require.NoError(t, err)
+			assert.Len(t, resp.Items, tt.expected)
+			assert.EqualValues(t, tt.expected, resp.TotalCount)
+		})
+	}
+}
+
+func TestGetListens_Pagination(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	resp, err := catalog.GetListens(ctx, store, catalog.GetListensOpts{Limit: 4, Page: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 4)
+	assert.True(t, resp.HasNextPage)
+	assert.EqualValues(t, 6, resp.TotalCount)
+	// newest first
+	EqualTime(t, base.Add(5*24*time.Hour), resp.Items[0].Time)
+
+	resp, err = catalog.GetListens(ctx, store, catalog.GetListensOpts{Limit: 4, Page: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 2)
+	assert.False(t, resp.HasNextPage)
+	EqualTime(t, base, resp.Items[1].Time)
+
+	resp, err = catalog.GetListens(ctx, store, catalog.GetListensOpts{Client: "player-a", Limit: 2, Page: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 2)
+	assert.False(t, resp.HasNextPage)
+	assert.EqualValues(t, 4, resp.TotalCount)
+
+	_, err = catalog.GetListens(ctx, store, catalog.GetListensOpts{Limit: -1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/queue"
+)
+
+// image lookups made by the backfill per second, shared by artists and albums, so the backfill does
+// not compete with listens and imports for the image providers' rate limits
+const imageBackfillRate = 1
+
+type imageBackfillStore interface {
+	db.ArtistStore
+	db.AlbumStore
+}
+
+// ImageBackfill periodically looks up images for the artists and albums that have none, such as those
+// added while an image provider was down. Lookups go through a request queue, so the backfill never
+// makes more than imageBackfillRate lookups per second.
+type ImageBackfill struct {
+	store    imageBackfillStore
+	interval time.Duration
+	queue    *queue.RequestQueue
+	stop     chan struct{}
+	done     chan struct{}
+	start    sync.Once
+	shutdown sync.Once
+	artists  atomic.Int64
+	albums   atomic.Int64
+}
+
+// ImageBackfillStats is the number of artists and albums that had no image after the backfill's last
+// lookups.
+type ImageBackfillStats struct {
+	ArtistsWithoutImages int64 `json:"artists_without_images"`
+	AlbumsWithoutImages  int64 `json:"albums_without_images"`
+}
+
+func NewImageBackfill(store imageBackfillStore, interval time.Duration) *ImageBackfill {
+	return &ImageBackfill{
+		store:    store,
+		interval: interval,
+		queue:    queue.NewRequestQueueWithLimit(imageBackfillRate, 1, 1, nil),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the backfill in the background, right away and then every interval, until ctx is done or
+// Shutdown is called.
+func (b *ImageBackfill) Start(ctx context.Context) {
+	b.start.Do(func() {
+		go b.run(ctx)
+	})
+}
+
+// Shutdown stops the backfill, waiting for the lookup in progress to finish.
+func (b *ImageBackfill) Shutdown() {
+	b.shutdown.Do(func() {
+		close(b.stop)
+		// never started, so there is nothing to wait for
+		b.start.Do(func() { close(b.done) })
+		<-b.done
+		b.queue.Shutdown()
+	})
+}
+
+// Remaining returns the number of artists and albums that had no image after the last lookups.
+func (b *ImageBackfill) Remaining() ImageBackfillStats {
+	return ImageBackfillStats{
+		ArtistsWithoutImages: b.artists.Load(),
+		AlbumsWithoutImages:  b.albums.Load(),
+	}
+}
+
+func (b *ImageBackfill) run(ctx context.Context) {
+	defer close(b.done)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-b.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	l := logger.FromContext(ctx)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		if err := b.runOnce(ctx); err != nil && ctx.Err() == nil {
+			l.Err(err).Msg("ImageBackfill: Failed to fetch missing images")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce looks up an image for every artist and album without one.
+func (b *ImageBackfill) runOnce(ctx context.Context) error {
+	l := logger.FromContext(ctx)
+	if err := b.count(ctx); err != nil {
+		return fmt.Errorf("runOnce: %w", err)
+	}
+	if b.artists.Load() == 0 && b.albums.Load() == 0 {
+		l.Debug().Msg("ImageBackfill: No artists or albums with missing images found")
+		return nil
+	}
+	l.Info().Int64("artists", b.artists.Load()).Int64("albums", b.albums.Load()).Msg("ImageBackfill: Fetching missing images")
+
+	var from int32
+	for {
+		artists, err := b.store.ArtistsWithoutImages(ctx, from)
+		if err != nil {
+			return fmt.Errorf("runOnce: %w", err)
+		}
+		if len(artists) == 0 {
+			break
+		}
+		for _, artist := range artists {
+			from = artist.ID
+			found, err := b.lookup(ctx, func() bool { return fetchMissingArtistImage(ctx, b.store, artist) })
+			if err != nil {
+				return fmt.Errorf("runOnce: %w", err)
+			}
+			if found {
+				b.artists.Add(-1)
+			}
+		}
+	}
+
+	from = 0
+	for {
+		albums, err := b.store.AlbumsWithoutImages(ctx, from)
+		if err != nil {
+			return fmt.Errorf("runOnce: %w", err)
+		}
+		if len(albums) == 0 {
+			break
+		}
+		for _, album := range albums {
+			from = album.ID
+			found, err := b.lookup(ctx, func() bool { return fetchMissingAlbumImage(ctx, b.store, album) })
+			if err != nil {
+				return fmt.Errorf("runOnce: %w", err)
+			}
+			if found {
+				b.albums.Add(-1)
+			}
+		}
+	}
+
+	if err := b.count(ctx); err != nil {
+		return fmt.Errorf("runOnce: %w", err)
+	}
+	l.Info().Int64("artists", b.artists.Load()).Int64("albums", b.albums.Load()).Msg("ImageBackfill: Finished fetching missing images")
+	return nil
+}
+
+// lookup runs fetch through the request queue, returning whether it found an image.
+func (b *ImageBackfill) lookup(ctx context.Context, fetch func() bool) (bool, error) {
+	var found bool
+	result := b.queue.Enqueue(func(_ *http.Client, done chan<- queue.RequestResult) {
+		found = fetch()
+		done <- queue.RequestResult{}
+	})
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-result:
+		return found, nil
+	}
+}
+
+func (b *ImageBackfill) count(ctx context.Context) error {
+	artists, err := b.store.CountArtistsWithoutImages(ctx)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+	albums, err := b.store.CountAlbumsWithoutImages(ctx)
+	if err != nil {
+		return fmt.Errorf("count: %w", err)
+	}
+	b.artists.Store(artists)
+	b.albums.Store(albums)
+	return nil
+}
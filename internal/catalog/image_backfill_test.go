@@ -0,0 +1,51 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageBackfill(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Imageless Artist"})
+	require.NoError(t, err)
+	_, err = store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Imaged Artist", Image: uuid.New()})
+	require.NoError(t, err)
+	_, err = store.SaveAlbum(ctx, db.SaveAlbumOpts{Title: "Imageless Album", ArtistIDs: []int32{artist.ID}})
+	require.NoError(t, err)
+
+	backfill := catalog.NewImageBackfill(store, time.Hour)
+	assert.Equal(t, catalog.ImageBackfillStats{}, backfill.Remaining())
+
+	// no providers are enabled in tests, so nothing is found and both are still without an image
+	backfill.Start(ctx)
+	require.Eventually(t, func() bool {
+		return backfill.Remaining() == catalog.ImageBackfillStats{ArtistsWithoutImages: 1, AlbumsWithoutImages: 1}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		backfill.Shutdown()
+		backfill.Shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+}
+
+func TestImageBackfill_ShutdownWithoutStart(t *testing.T) {
+	backfill := catalog.NewImageBackfill(newTestDB(), time.Hour)
+	backfill.Shutdown()
+}
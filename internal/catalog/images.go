@@ -2,10 +2,12 @@ package catalog
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/gabehf/koito/imagecache"
 	"github.com/gabehf/koito/internal/cfg"
@@ -73,46 +75,52 @@ func FetchMissingArtistImages(ctx context.Context, store db.ArtistStore) error {
 
 		for _, artist := range artists {
 			from = artist.ID
+			fetchMissingArtistImage(ctx, store, artist)
+		}
+	}
+}
 
-			l.Debug().
-				Str("title", artist.Name).
-				Msg("FetchMissingArtistImages: Attempting to fetch missing artist image")
-
-			var aliases []string
-			if aliasrow, err := store.GetAllArtistAliases(ctx, artist.ID); err == nil {
-				aliases = utils.FlattenAliases(aliasrow)
-			} else {
-				aliases = []string{artist.Name}
-			}
+// fetchMissingArtistImage looks up an image for the artist and saves it. Returns true if an image
+// was found.
+func fetchMissingArtistImage(ctx context.Context, store db.ArtistStore, artist *models.Artist) bool {
+	l := logger.FromContext(ctx)
+	l.Debug().
+		Str("title", artist.Name).
+		Msg("FetchMissingArtistImages: Attempting to fetch missing artist image")
+
+	var aliases []string
+	if aliasrow, err := store.GetAllArtistAliases(ctx, artist.ID); err == nil {
+		aliases = utils.FlattenAliases(aliasrow)
+	} else {
+		aliases = []string{artist.Name}
+	}
 
-			var imgid uuid.UUID
-			imgUrl, imgErr := images.GetArtistImage(ctx, images.ArtistImageOpts{
-				Aliases: aliases,
-			})
-			if imgErr == nil && imgUrl != "" {
-				imgid = uuid.New()
-				err = store.UpdateArtist(ctx, db.UpdateArtistOpts{
-					ID:       artist.ID,
-					Image:    imgid,
-					ImageSrc: imgUrl,
-				})
-				if err != nil {
-					l.Err(err).
-						Str("title", artist.Name).
-						Msg("FetchMissingArtistImages: Failed to update artist with image in database")
-					continue
-				}
-				l.Info().
-					Str("name", artist.Name).
-					Msg("FetchMissingArtistImages: Successfully fetched missing artist image")
-			} else {
-				l.Err(err).
-					Str("name", artist.Name).
-					Msg("FetchMissingArtistImages: Failed to fetch artist image")
-			}
-		}
+	imgUrl, err := images.GetArtistImage(ctx, images.ArtistImageOpts{
+		Aliases: aliases,
+	})
+	if err != nil || imgUrl == "" {
+		l.Err(err).
+			Str("name", artist.Name).
+			Msg("FetchMissingArtistImages: Failed to fetch artist image")
+		return false
+	}
+	err = store.UpdateArtist(ctx, db.UpdateArtistOpts{
+		ID:       artist.ID,
+		Image:    uuid.New(),
+		ImageSrc: imgUrl,
+	})
+	if err != nil {
+		l.Err(err).
+			Str("title", artist.Name).
+			Msg("FetchMissingArtistImages: Failed to update artist with image in database")
+		return false
 	}
+	l.Info().
+		Str("name", artist.Name).
+		Msg("FetchMissingArtistImages: Successfully fetched missing artist image")
+	return true
 }
+
 func FetchMissingAlbumImages(ctx context.Context, store db.AlbumStore) error {
 	l := logger.FromContext(ctx)
 	l.Info().Msg("FetchMissingAlbumImages: Starting backfill of missing album images")
@@ -137,40 +145,269 @@ func FetchMissingAlbumImages(ctx context.Context, store db.AlbumStore) error {
 
 		for _, album := range albums {
 			from = album.ID
+			fetchMissingAlbumImage(ctx, store, album)
+		}
+	}
+}
 
-			l.Debug().
-				Str("title", album.Title).
-				Msg("FetchMissingAlbumImages: Attempting to fetch missing album image")
-
-			var imgid uuid.UUID
-			imgUrl, imgErr := images.GetAlbumImage(ctx, images.AlbumImageOpts{
-				Artists:      utils.FlattenSimpleArtistNames(album.Artists),
-				Album:        album.Title,
-				ReleaseMbzID: album.MbzID,
-			})
-			if imgErr == nil && imgUrl != "" {
-				imgid = uuid.New()
-				err = store.UpdateAlbum(ctx, db.UpdateAlbumOpts{
-					ID:       album.ID,
-					Image:    imgid,
-					ImageSrc: imgUrl,
-				})
-				if err != nil {
-					l.Err(err).
-						Str("title", album.Title).
-						Msg("FetchMissingAlbumImages: Failed to update album with image in database")
-					continue
-				}
-				l.Info().
-					Str("name", album.Title).
-					Msg("FetchMissingAlbumImages: Successfully fetched missing album image")
-			} else {
-				l.Err(err).
-					Str("name", album.Title).
-					Msg("FetchMissingAlbumImages: Failed to fetch album image")
+// fetchMissingAlbumImage looks up a cover for the album and saves it, replacing the artist image
+// used as a fallback. Returns true if a cover was found.
+func fetchMissingAlbumImage(ctx context.Context, store db.AlbumStore, album *models.Album) bool {
+	l := logger.FromContext(ctx)
+	l.Debug().
+		Str("title", album.Title).
+		Msg("FetchMissingAlbumImages: Attempting to fetch missing album image")
+
+	imgUrl, err := images.GetAlbumImage(ctx, images.AlbumImageOpts{
+		Artists:      utils.FlattenSimpleArtistNames(album.Artists),
+		Album:        album.Title,
+		ReleaseMbzID: album.MbzID,
+		TrackCount:   albumTrackCount(ctx, store, album.ID),
+	})
+	if err != nil || imgUrl == "" {
+		l.Err(err).
+			Str("name", album.Title).
+			Msg("FetchMissingAlbumImages: Failed to fetch album image")
+		return false
+	}
+	err = store.UpdateAlbum(ctx, db.UpdateAlbumOpts{
+		ID:       album.ID,
+		Image:    uuid.New(),
+		ImageSrc: imgUrl,
+	})
+	if err != nil {
+		l.Err(err).
+			Str("title", album.Title).
+			Msg("FetchMissingAlbumImages: Failed to update album with image in database")
+		return false
+	}
+	if album.ImageIsFallback {
+		if err := imagecache.DeleteImage(imageIDFromList(album.Image)); err != nil {
+			l.Err(err).Msgf("FetchMissingAlbumImages: Failed to delete fallback image for album '%s'", album.Title)
+		}
+	}
+	l.Info().
+		Str("name", album.Title).
+		Msg("FetchMissingAlbumImages: Successfully fetched missing album image")
+	return true
+}
+
+// RefreshArtistImage re-resolves the image for an artist from the enabled image providers.
+// Artists whose current image is already in the image cache are skipped unless force is true.
+// Returns true if a new image was saved.
+func RefreshArtistImage(ctx context.Context, store db.ArtistStore, id int32, force bool) (bool, error) {
+	l := logger.FromContext(ctx)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{ID: id})
+	if err != nil {
+		return false, fmt.Errorf("RefreshArtistImage: %w", err)
+	}
+
+	oldImg := imageIDFromList(artist.Image)
+	if !force && imageIsCached(oldImg) {
+		l.Debug().Msgf("RefreshArtistImage: Artist '%s' already has a cached image, skipping", artist.Name)
+		return false, nil
+	}
+
+	imgUrl, err := images.GetArtistImage(ctx, images.ArtistImageOpts{
+		Aliases: artist.Aliases,
+		MBID:    artist.MbzID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("RefreshArtistImage: %w", err)
+	}
+	if imgUrl == "" {
+		return false, fmt.Errorf("RefreshArtistImage: no image found for artist '%s'", artist.Name)
+	}
+
+	imgid := uuid.New()
+	if err := imagecache.DownloadImage(imgid, imgUrl); err != nil {
+		return false, fmt.Errorf("RefreshArtistImage: %w", err)
+	}
+	if err := store.UpdateArtist(ctx, db.UpdateArtistOpts{
+		ID:       artist.ID,
+		Image:    imgid,
+		ImageSrc: imgUrl,
+	}); err != nil {
+		return false, fmt.Errorf("RefreshArtistImage: %w", err)
+	}
+	if oldImg != uuid.Nil {
+		if err := imagecache.DeleteImage(oldImg); err != nil {
+			l.Err(err).Msgf("RefreshArtistImage: Failed to delete old image for artist '%s'", artist.Name)
+		}
+	}
+
+	l.Info().Msgf("RefreshArtistImage: Refreshed image for artist '%s'", artist.Name)
+	return true, nil
+}
+
+// RefreshAlbumImage re-resolves the image for an album from the enabled image providers.
+// Albums whose current image is already in the image cache are skipped unless force is true,
+// or the image is a fallback copied from the album's artist.
+// Returns true if a new image was saved.
+func RefreshAlbumImage(ctx context.Context, store db.AlbumStore, id int32, force bool) (bool, error) {
+	l := logger.FromContext(ctx)
+
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: id})
+	if err != nil {
+		return false, fmt.Errorf("RefreshAlbumImage: %w", err)
+	}
+
+	oldImg := imageIDFromList(album.Image)
+	if !force && !album.ImageIsFallback && imageIsCached(oldImg) {
+		l.Debug().Msgf("RefreshAlbumImage: Album '%s' already has a cached image, skipping", album.Title)
+		return false, nil
+	}
+	if len(album.Artists) < 1 {
+		return false, fmt.Errorf("RefreshAlbumImage: album '%s' has no artists", album.Title)
+	}
+
+	imgUrl, err := images.GetAlbumImage(ctx, images.AlbumImageOpts{
+		Artists:      utils.FlattenSimpleArtistNames(album.Artists),
+		Album:        album.Title,
+		ReleaseMbzID: album.MbzID,
+		TrackCount:   albumTrackCount(ctx, store, album.ID),
+	})
+	if err != nil {
+		return false, fmt.Errorf("RefreshAlbumImage: %w", err)
+	}
+	if imgUrl == "" {
+		return false, fmt.Errorf("RefreshAlbumImage: no image found for album '%s'", album.Title)
+	}
+
+	imgid := uuid.New()
+	if err := imagecache.DownloadImage(imgid, imgUrl); err != nil {
+		return false, fmt.Errorf("RefreshAlbumImage: %w", err)
+	}
+	if err := store.UpdateAlbum(ctx, db.UpdateAlbumOpts{
+		ID:       album.ID,
+		Image:    imgid,
+		ImageSrc: imgUrl,
+	}); err != nil {
+		return false, fmt.Errorf("RefreshAlbumImage: %w", err)
+	}
+	if oldImg != uuid.Nil {
+		if err := imagecache.DeleteImage(oldImg); err != nil {
+			l.Err(err).Msgf("RefreshAlbumImage: Failed to delete old image for album '%s'", album.Title)
+		}
+	}
+
+	l.Info().Msgf("RefreshAlbumImage: Refreshed image for album '%s'", album.Title)
+	return true, nil
+}
+
+// AssignReleaseGroupImage fetches the front cover of a release group from the CoverArtArchive and assigns
+// it to every album in the group that has no image, or only a fallback image. Albums with an image from
+// a provider or a user upload are left untouched. Returns the number of albums that were updated.
+func AssignReleaseGroupImage(ctx context.Context, store db.AlbumStore, releaseGroupMbzID uuid.UUID) (int, error) {
+	l := logger.FromContext(ctx)
+	if releaseGroupMbzID == uuid.Nil {
+		return 0, errors.New("AssignReleaseGroupImage: release group MusicBrainz ID is required")
+	}
+
+	albums, err := store.ReleaseGroupAlbumsWithoutImages(ctx, releaseGroupMbzID)
+	if err != nil {
+		return 0, fmt.Errorf("AssignReleaseGroupImage: %w", err)
+	}
+	if len(albums) == 0 {
+		l.Debug().Msgf("AssignReleaseGroupImage: No albums in release group %s are missing images", releaseGroupMbzID)
+		return 0, nil
+	}
+
+	imgUrl, err := images.GetReleaseGroupImage(ctx, releaseGroupMbzID)
+	if err != nil {
+		return 0, fmt.Errorf("AssignReleaseGroupImage: %w", err)
+	}
+	// download once, then give every album its own copy
+	srcImg := uuid.New()
+	if err := imagecache.DownloadImage(srcImg, imgUrl); err != nil {
+		return 0, fmt.Errorf("AssignReleaseGroupImage: %w", err)
+	}
+
+	updated := 0
+	for i, album := range albums {
+		imgid := srcImg
+		if i > 0 {
+			imgid = uuid.New()
+			if err := imagecache.CopyImage(srcImg, imgid); err != nil {
+				l.Err(err).Msgf("AssignReleaseGroupImage: Failed to copy image for album '%s'", album.Title)
+				continue
 			}
 		}
+		if err := store.UpdateAlbum(ctx, db.UpdateAlbumOpts{
+			ID:       album.ID,
+			Image:    imgid,
+			ImageSrc: imgUrl,
+		}); err != nil {
+			l.Err(err).Msgf("AssignReleaseGroupImage: Failed to update image for album '%s'", album.Title)
+			continue
+		}
+		if album.ImageIsFallback {
+			if err := imagecache.DeleteImage(imageIDFromList(album.Image)); err != nil {
+				l.Err(err).Msgf("AssignReleaseGroupImage: Failed to delete fallback image for album '%s'", album.Title)
+			}
+		}
+		updated++
+	}
+
+	l.Info().Msgf("AssignReleaseGroupImage: Assigned release group image to %d albums", updated)
+	return updated, nil
+}
+
+// artistImageFallback copies the cached image of the first artist that has one, for use as the image
+// of an album with no image available from any provider. Returns uuid.Nil when the fallback is
+// disabled or none of the artists have a cached image.
+func artistImageFallback(ctx context.Context, artists []*models.Artist) uuid.UUID {
+	l := logger.FromContext(ctx)
+	if !cfg.UseArtistImageForMissingAlbum() {
+		return uuid.Nil
+	}
+	for _, artist := range artists {
+		artistImg := imageIDFromList(artist.Image)
+		if !imageIsCached(artistImg) {
+			continue
+		}
+		imgid := uuid.New()
+		if err := imagecache.CopyImage(artistImg, imgid); err != nil {
+			l.Err(err).Msgf("artistImageFallback: Failed to copy image for artist '%s'", artist.Name)
+			continue
+		}
+		l.Debug().Msgf("artistImageFallback: Using image of artist '%s' as album image", artist.Name)
+		return imgid
 	}
+	return uuid.Nil
+}
+
+// albumTrackCount returns the number of tracks on the album, or 0 if it cannot be counted
+func albumTrackCount(ctx context.Context, store db.AlbumStore, id int32) int {
+	count, err := store.CountAlbumTracks(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Debug().Err(err).Msgf("albumTrackCount: Failed to count tracks of album %d", id)
+		return 0
+	}
+	return int(count)
+}
+
+// imageIsCached reports whether the source image for the id exists in the image cache
+func imageIsCached(id uuid.UUID) bool {
+	if id == uuid.Nil {
+		return false
+	}
+	_, err := os.Stat(imagecache.BuildImagePath(id, imagecache.ImageSizeSource))
+	return err == nil
+}
+
+// parses the image id from an image list built with BuildImageList
+func imageIDFromList(list models.ImageList) uuid.UUID {
+	ss := strings.Split(list.Small, "/")
+	if len(ss) < 4 {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(ss[2])
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
 }
 
 // TODO: move this function into models
@@ -179,11 +416,12 @@ func BuildImageList(imageid *uuid.UUID) models.ImageList {
 		imageid = &uuid.Nil
 	}
 	return models.ImageList{
-		XS:     fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeXS),
-		Small:  fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeSmall),
-		Medium: fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeMedium),
-		Large:  fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeLarge),
-		XL:     fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeXL),
+		XS:       fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeXS),
+		Small:    fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeSmall),
+		Medium:   fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeMedium),
+		Large:    fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeLarge),
+		XL:       fmt.Sprintf("/image/%s/%s.webp", imageid.String(), imagecache.ImageSizeXL),
+		BlurHash: imagecache.BlurHash(*imageid),
 	}
 }
 
@@ -273,3 +511,138 @@ func MigrateImageCache(ctx context.Context, store db.ImageStore) error {
 
 	return nil
 }
+
+type PreviewImageOpts struct {
+	// exactly one of ArtistID and AlbumID must be set
+	ArtistID int32
+	AlbumID  int32
+	Limit    int
+}
+
+type imagePreviewStore interface {
+	db.ArtistStore
+	db.AlbumStore
+}
+
+// PreviewImageCandidates returns images from the enabled image providers that may belong to the artist
+// or album, so that the right one can be picked and saved by replacing the item's image with its URL.
+// Nothing is downloaded or stored.
+func PreviewImageCandidates(ctx context.Context, store imagePreviewStore, opts PreviewImageOpts) ([]images.ImageCandidate, error) {
+	if (opts.ArtistID == 0) == (opts.AlbumID == 0) {
+		return nil, errors.New("PreviewImageCandidates: exactly one of artist id and album id is required")
+	}
+	if opts.Limit < 1 {
+		return nil, errors.New("PreviewImageCandidates: limit must be at least 1")
+	}
+
+	if opts.ArtistID != 0 {
+		artist, err := store.GetArtist(ctx, db.GetArtistOpts{ID: opts.ArtistID})
+		if err != nil {
+			return nil, fmt.Errorf("PreviewImageCandidates: %w", err)
+		}
+		// search by the artist's primary name first
+		aliases := append([]string{artist.Name}, artist.Aliases...)
+		return images.GetArtistImageCandidates(ctx, images.ArtistImageOpts{
+			Aliases: aliases,
+			MBID:    artist.MbzID,
+		}, opts.Limit), nil
+	}
+
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: opts.AlbumID})
+	if err != nil {
+		return nil, fmt.Errorf("PreviewImageCandidates: %w", err)
+	}
+	if len(album.Artists) < 1 {
+		return nil, fmt.Errorf("PreviewImageCandidates: album '%s' has no artists", album.Title)
+	}
+	return images.GetAlbumImageCandidates(ctx, images.AlbumImageOpts{
+		Artists:      utils.FlattenSimpleArtistNames(album.Artists),
+		Album:        album.Title,
+		ReleaseMbzID: album.MbzID,
+	}, opts.Limit), nil
+}
+
+// ImageOnDemandStore is the store needed to fetch an image on demand.
+type ImageOnDemandStore interface {
+	db.ArtistStore
+	db.AlbumStore
+	db.ImageStore
+}
+
+// FetchImageOnDemand downloads the image with the given id into the image cache, so that it can be
+// served on first request. The image's recorded source is tried first; if there is none, or it can no
+// longer be downloaded, the image providers are searched for the artist or album that owns the image.
+// The image is saved under the same id, so the owner's image URLs do not change.
+func FetchImageOnDemand(ctx context.Context, store ImageOnDemandStore, imgid uuid.UUID) error {
+	l := logger.FromContext(ctx)
+
+	if imgid == uuid.Nil {
+		return fmt.Errorf("FetchImageOnDemand: %w", images.ErrImageNotFound)
+	}
+
+	src, err := store.GetImageSource(ctx, imgid)
+	if err != nil {
+		return fmt.Errorf("FetchImageOnDemand: %w", err)
+	}
+	if src == ImageSourceUserUpload {
+		// uploads only exist in the image cache, and must not be replaced by a provider's image
+		return fmt.Errorf("FetchImageOnDemand: %w", images.ErrImageNotFound)
+	}
+	if src != "" && src != ImageSourceArtistFallback {
+		err = imagecache.DownloadImage(imgid, src)
+		if err == nil {
+			return nil
+		}
+		l.Debug().Err(err).Msgf("FetchImageOnDemand: Failed to download image '%s' from its source, searching image providers", imgid)
+	}
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Image: imgid})
+	if err == nil {
+		imgUrl, err := images.GetArtistImage(ctx, images.ArtistImageOpts{
+			Aliases: append([]string{artist.Name}, artist.Aliases...),
+			MBID:    artist.MbzID,
+		})
+		if err != nil {
+			return fmt.Errorf("FetchImageOnDemand: %w", err)
+		}
+		if imgUrl == "" {
+			return fmt.Errorf("FetchImageOnDemand: %w", images.ErrImageNotFound)
+		}
+		if err := imagecache.DownloadImage(imgid, imgUrl); err != nil {
+			return fmt.Errorf("FetchImageOnDemand: %w", err)
+		}
+		if err := store.UpdateArtist(ctx, db.UpdateArtistOpts{ID: artist.ID, Image: imgid, ImageSrc: imgUrl}); err != nil {
+			return fmt.Errorf("FetchImageOnDemand: %w", err)
+		}
+		return nil
+	} else if !errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("FetchImageOnDemand: %w", err)
+	}
+
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Image: imgid})
+	if err != nil {
+		return fmt.Errorf("FetchImageOnDemand: %w", err)
+	}
+	if len(album.Artists) < 1 {
+		return fmt.Errorf("FetchImageOnDemand: album '%s' has no artists", album.Title)
+	}
+	imgUrl, err := images.GetAlbumImage(ctx, images.AlbumImageOpts{
+		Artists:      utils.FlattenSimpleArtistNames(album.Artists),
+		Album:        album.Title,
+		ReleaseMbzID: album.MbzID,
+		TrackCount:   albumTrackCount(ctx, store, album.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("FetchImageOnDemand: %w", err)
+	}
+	if imgUrl == "" {
+		return fmt.Errorf("FetchImageOnDemand: %w", images.ErrImageNotFound)
+	}
+	if err := imagecache.DownloadImage(imgid, imgUrl); err != nil {
+		return fmt.Errorf("FetchImageOnDemand: %w", err)
+	}
+	if err := store.UpdateAlbum(ctx, db.UpdateAlbumOpts{ID: album.ID, Image: imgid, ImageSrc: imgUrl}); err != nil {
+		return fmt.Errorf("FetchImageOnDemand: %w", err)
+	}
+	return nil
+}
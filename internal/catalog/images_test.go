@@ -0,0 +1,283 @@
+package catalog_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/imagecache"
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/images"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCachedImage(t *testing.T, id uuid.UUID) {
+	p := imagecache.BuildImagePath(id, imagecache.ImageSizeSource)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte("img"), 0644))
+}
+
+func TestRefreshArtistImage_SkipAndForce(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	imgid := uuid.New()
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Cached Artist", Image: imgid, ImageSrc: "http://example.com/a.jpg"})
+	require.NoError(t, err)
+	writeCachedImage(t, imgid)
+
+	// image is cached, so providers are not consulted
+	refreshed, err := catalog.RefreshArtistImage(ctx, store, artist.ID, false)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+
+	// forcing goes to the providers; none are enabled in tests, so no image is found
+	refreshed, err = catalog.RefreshArtistImage(ctx, store, artist.ID, true)
+	assert.Error(t, err)
+	assert.False(t, refreshed)
+
+	// the existing image is kept when a forced refresh fails
+	_, err = os.Stat(imagecache.BuildImagePath(imgid, imagecache.ImageSizeSource))
+	assert.NoError(t, err)
+}
+
+func TestRefreshArtistImage_NotCached(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	// image id is set but the file was never downloaded
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Uncached Artist", Image: uuid.New()})
+	require.NoError(t, err)
+
+	_, err = catalog.RefreshArtistImage(ctx, store, artist.ID, false)
+	assert.Error(t, err)
+}
+
+func TestRefreshAlbumImage_SkipAndForce(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Album Artist"})
+	require.NoError(t, err)
+	imgid := uuid.New()
+	album, err := store.SaveAlbum(ctx, db.SaveAlbumOpts{Title: "Cached Album", ArtistIDs: []int32{artist.ID}, Image: imgid})
+	require.NoError(t, err)
+	writeCachedImage(t, imgid)
+
+	refreshed, err := catalog.RefreshAlbumImage(ctx, store, album.ID, false)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+
+	refreshed, err = catalog.RefreshAlbumImage(ctx, store, album.ID, true)
+	assert.Error(t, err)
+	assert.False(t, refreshed)
+}
+
+func TestSubmitListen_ArtistImageFallback(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	cfg.SetUseArtistImageForMissingAlbum(true)
+	defer cfg.SetUseArtistImageForMissingAlbum(false)
+
+	artistImg := uuid.New()
+	_, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Fallback Artist", Image: artistImg, ImageSrc: "http://example.com/a.jpg"})
+	require.NoError(t, err)
+	writeCachedImage(t, artistImg)
+
+	// no image providers are enabled in tests, so the album image can't be found
+	err = catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Fallback Artist",
+		TrackTitle:   "Fallback Track",
+		ReleaseTitle: "Fallback Album",
+		Time:         time.Now(),
+		UserID:       1,
+	})
+	require.NoError(t, err)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Fallback Artist"})
+	require.NoError(t, err)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Fallback Album", ArtistID: artist.ID})
+	require.NoError(t, err)
+	assert.True(t, album.ImageIsFallback)
+	// the album gets its own copy of the artist image
+	assert.NotEqual(t, artist.Image, album.Image)
+	assert.NotEqual(t, catalog.BuildImageList(nil), album.Image)
+	_, err = os.Stat(imagecache.BuildImagePath(artistImg, imagecache.ImageSizeSource))
+	assert.NoError(t, err)
+
+	// fallback images are never skipped when refreshing, even though they are cached
+	_, err = catalog.RefreshAlbumImage(ctx, store, album.ID, false)
+	assert.ErrorIs(t, err, images.ErrImageNotFound)
+}
+
+func TestSubmitListen_ArtistImageFallbackDisabled(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	artistImg := uuid.New()
+	_, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "No Fallback Artist", Image: artistImg})
+	require.NoError(t, err)
+	writeCachedImage(t, artistImg)
+
+	err = catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "No Fallback Artist",
+		TrackTitle:   "Track",
+		ReleaseTitle: "Album Without Image",
+		Time:         time.Now(),
+		UserID:       1,
+	})
+	require.NoError(t, err)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "No Fallback Artist"})
+	require.NoError(t, err)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Album Without Image", ArtistID: artist.ID})
+	require.NoError(t, err)
+	assert.False(t, album.ImageIsFallback)
+	assert.Equal(t, catalog.BuildImageList(nil), album.Image)
+}
+
+func TestSubmitListen_SavesReleaseGroupMbzID(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	rgID := uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+
+	for _, release := range []string{"Grouped Album", "Grouped Album (Deluxe)"} {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:         &mbz.MbzMockCaller{},
+			Artist:            "Grouped Artist",
+			TrackTitle:        "Track",
+			ReleaseTitle:      release,
+			ReleaseGroupMbzID: rgID,
+			Time:              time.Now(),
+			UserID:            1,
+		})
+		require.NoError(t, err)
+	}
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Grouped Artist"})
+	require.NoError(t, err)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Grouped Album (Deluxe)", ArtistID: artist.ID})
+	require.NoError(t, err)
+	require.NotNil(t, album.ReleaseGroupMbzID)
+	assert.Equal(t, rgID, *album.ReleaseGroupMbzID)
+
+	albums, err := store.ReleaseGroupAlbumsWithoutImages(ctx, rgID)
+	require.NoError(t, err)
+	assert.Len(t, albums, 2)
+}
+
+func TestAssignReleaseGroupImage(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	rgID := uuid.MustParse("00000000-0000-0000-0000-0000000000a2")
+
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Release Group Artist"})
+	require.NoError(t, err)
+	_, err = store.SaveAlbum(ctx, db.SaveAlbumOpts{Title: "Uploaded", ArtistIDs: []int32{artist.ID}, ReleaseGroupMbzID: rgID, Image: uuid.New(), ImageSrc: catalog.ImageSourceUserUpload})
+	require.NoError(t, err)
+
+	// albums with a manually uploaded image are never considered missing
+	updated, err := catalog.AssignReleaseGroupImage(ctx, store, rgID)
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+
+	_, err = store.SaveAlbum(ctx, db.SaveAlbumOpts{Title: "Missing", ArtistIDs: []int32{artist.ID}, ReleaseGroupMbzID: rgID})
+	require.NoError(t, err)
+	albums, err := store.ReleaseGroupAlbumsWithoutImages(ctx, rgID)
+	require.NoError(t, err)
+	require.Len(t, albums, 1)
+	assert.Equal(t, "Missing", albums[0].Title)
+
+	// the CoverArtArchive is disabled in tests
+	_, err = catalog.AssignReleaseGroupImage(ctx, store, rgID)
+	assert.Error(t, err)
+
+	_, err = catalog.AssignReleaseGroupImage(ctx, store, uuid.Nil)
+	assert.Error(t, err)
+}
+
+func TestPreviewImageCandidates(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	artist, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Preview Artist"})
+	require.NoError(t, err)
+
+	// no providers are enabled in tests, so there are no candidates
+	candidates, err := catalog.PreviewImageCandidates(ctx, store, catalog.PreviewImageOpts{ArtistID: artist.ID, Limit: 5})
+	require.NoError(t, err)
+	assert.Empty(t, candidates)
+
+	_, err = catalog.PreviewImageCandidates(ctx, store, catalog.PreviewImageOpts{ArtistID: 9999, Limit: 5})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = catalog.PreviewImageCandidates(ctx, store, catalog.PreviewImageOpts{ArtistID: artist.ID, AlbumID: 1, Limit: 5})
+	assert.Error(t, err)
+	_, err = catalog.PreviewImageCandidates(ctx, store, catalog.PreviewImageOpts{ArtistID: artist.ID})
+	assert.Error(t, err)
+}
+
+func TestFetchImageOnDemand(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	// the owner of the image is found, but no providers are enabled in tests
+	imgid := uuid.New()
+	_, err := store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Lazy Artist", Image: imgid})
+	require.NoError(t, err)
+	err = catalog.FetchImageOnDemand(ctx, store, imgid)
+	assert.ErrorIs(t, err, images.ErrImageNotFound)
+
+	// uploads are never replaced with a provider's image
+	uploadID := uuid.New()
+	_, err = store.SaveArtist(ctx, db.SaveArtistOpts{Name: "Uploaded Artist", Image: uploadID, ImageSrc: catalog.ImageSourceUserUpload})
+	require.NoError(t, err)
+	err = catalog.FetchImageOnDemand(ctx, store, uploadID)
+	assert.ErrorIs(t, err, images.ErrImageNotFound)
+
+	err = catalog.FetchImageOnDemand(ctx, store, uuid.New())
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	err = catalog.FetchImageOnDemand(ctx, store, uuid.Nil)
+	assert.ErrorIs(t, err, images.ErrImageNotFound)
+}
+
+func TestSubmitListen_LazyImageFetch(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	cfg.SetLazyImageFetch(true)
+	defer cfg.SetLazyImageFetch(false)
+
+	// no image providers are enabled in tests, so the images would be left out if they were looked up
+	err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Lazy Artist",
+		TrackTitle:   "Lazy Track",
+		ReleaseTitle: "Lazy Album",
+		Time:         time.Now(),
+		UserID:       1,
+	})
+	require.NoError(t, err)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Lazy Artist"})
+	require.NoError(t, err)
+	assert.NotEqual(t, catalog.BuildImageList(nil), artist.Image)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Lazy Album", ArtistID: artist.ID})
+	require.NoError(t, err)
+	assert.NotEqual(t, catalog.BuildImageList(nil), album.Image)
+
+	// the images have no source yet; it is looked up when the image is first requested
+	count, err := store.Count(`SELECT COUNT(*) FROM artists WHERE id = ? AND image IS NOT NULL AND COALESCE(image_source, '') = ''`, artist.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = store.Count(`SELECT COUNT(*) FROM releases WHERE id = ? AND image IS NOT NULL AND COALESCE(image_source, '') = ''`, album.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/models"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type ListensCursorPage struct {
+	Items []*models.Listen `json:"items"`
+	// empty when there are no more listens
+	NextCursor string `json:"next_cursor"`
+}
+
+// GetListensCursor returns the listens following the cursor, newest first, along with the cursor of
+// the next page. An empty cursor starts from the most recent listen. Cursors point at the last listen
+// seen rather than an offset, so listens submitted while paging never cause duplicates or skips.
+// When userID is 0, listens from all users are returned.
+func GetListensCursor(ctx context.Context, store db.ListenStore, userID int32, after string, limit int) (*ListensCursorPage, error) {
+	if limit < 1 {
+		return nil, errors.New("GetListensCursor: limit must be at least 1")
+	}
+	opts := db.GetListensBeforeOpts{
+		UserID: userID,
+		// fetch one extra listen to tell whether there is a next page
+		Limit: limit + 1,
+	}
+	if after != "" {
+		t, trackID, err := decodeListenCursor(after)
+		if err != nil {
+			return nil, fmt.Errorf("GetListensCursor: %w", err)
+		}
+		opts.BeforeTime = t
+		opts.BeforeTrackID = trackID
+	}
+
+	listens, err := store.GetListensBefore(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("GetListensCursor: %w", err)
+	}
+	page := &ListensCursorPage{Items: listens}
+	if len(listens) > limit {
+		page.Items = listens[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeListenCursor(last.Time, last.Track.ID)
+	}
+	return page, nil
+}
+
+func encodeListenCursor(t time.Time, trackID int32) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", t.Unix(), trackID))
+}
+
+func decodeListenCursor(cursor string) (time.Time, int32, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	unix, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	ts, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	trackID, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return time.Unix(ts, 0), int32(trackID), nil
+}
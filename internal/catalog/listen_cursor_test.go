@@ -0,0 +1,91 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetListensCursor(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	seen := make(map[int64]bool)
+	var cursor string
+	for i := 0; ; i++ {
+		page, err := catalog.GetListensCursor(ctx, store, 1, cursor, 2)
+		require.NoError(t, err)
+		for _, l := range page.Items {
+			assert.False(t, seen[l.Time.Unix()], "duplicate listen at %v", l.Time)
+			seen[l.Time.Unix()] = true
+		}
+		if i == 0 {
+			// listens arriving while paging must not shift the remaining pages
+			err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+				MbzCaller:    &mbz.MbzMockCaller{},
+				Artist:       "Artist B",
+				TrackTitle:   "Track 5",
+				ReleaseTitle: "Release Z",
+				Time:         base.Add(30 * 24 * time.Hour),
+				UserID:       1,
+			})
+			require.NoError(t, err)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Len(t, seen, 6)
+	for i := range 6 {
+		assert.True(t, seen[base.Add(time.Duration(i)*24*time.Hour).Unix()])
+	}
+
+	// the new listen is at the top of a fresh feed
+	page, err := catalog.GetListensCursor(ctx, store, 1, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, "Track 5", page.Items[0].Track.Title)
+}
+
+func TestGetListensCursor_SameTime(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, title := range []string{"Track 1", "Track 2", "Track 3"} {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Artist A",
+			TrackTitle:   title,
+			ReleaseTitle: "Release X",
+			Time:         ts,
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	first, err := catalog.GetListensCursor(ctx, store, 1, "", 2)
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	require.NotEmpty(t, first.NextCursor)
+	second, err := catalog.GetListensCursor(ctx, store, 1, first.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Empty(t, second.NextCursor)
+
+	titles := map[string]bool{}
+	for _, l := range append(first.Items, second.Items...) {
+		titles[l.Track.Title] = true
+	}
+	assert.Len(t, titles, 3)
+
+	_, err = catalog.GetListensCursor(ctx, store, 1, "not a cursor", 2)
+	assert.ErrorIs(t, err, catalog.ErrInvalidCursor)
+}
@@ -0,0 +1,58 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+var ErrSelfMerge = errors.New("cannot merge an item into itself")
+
+// MergeArtists merges the artist with mergeID into the one with keepID, such as an artist that was
+// imported under a name in another language. The tracks and releases of the merged artist, and so
+// their listens, are reassigned to the kept artist, which also takes the merged artist's names as
+// aliases. The merged artist is then deleted. When replaceImage is true, the kept artist takes the
+// image of the merged one.
+func MergeArtists(ctx context.Context, store db.ArtistStore, keepID, mergeID int32, replaceImage bool) error {
+	l := logger.FromContext(ctx)
+	if keepID == mergeID {
+		return ErrSelfMerge
+	}
+	for _, id := range []int32{keepID, mergeID} {
+		if _, err := store.GetArtist(ctx, db.GetArtistOpts{ID: id}); err != nil {
+			return fmt.Errorf("MergeArtists: %w", err)
+		}
+	}
+
+	l.Info().Msgf("MergeArtists: Merging artist %d into artist %d", mergeID, keepID)
+	if err := store.MergeArtists(ctx, mergeID, keepID, replaceImage); err != nil {
+		return fmt.Errorf("MergeArtists: %w", err)
+	}
+	return nil
+}
+
+// MergeReleases merges the release with mergeID into the one with keepID, such as a deluxe edition or
+// a remaster of the same album. The tracks of the merged release, along with their listens, are moved
+// to the kept release, and tracks whose titles only differ in case, accents or punctuation from one of
+// the kept release's tracks are merged into it. The kept release keeps its title and other metadata,
+// and its image unless replaceImage is true.
+func MergeReleases(ctx context.Context, store db.AlbumStore, keepID, mergeID int32, replaceImage bool) error {
+	l := logger.FromContext(ctx)
+	if keepID == mergeID {
+		return ErrSelfMerge
+	}
+	for _, id := range []int32{keepID, mergeID} {
+		if _, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: id}); err != nil {
+			return fmt.Errorf("MergeReleases: %w", err)
+		}
+	}
+
+	l.Info().Msgf("MergeReleases: Merging release %d into release %d", mergeID, keepID)
+	if err := store.MergeAlbums(ctx, mergeID, keepID, replaceImage); err != nil {
+		return fmt.Errorf("MergeReleases: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeArtists(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, artist := range []string{"BTS", "방탄소년단"} {
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       artist,
+			TrackTitle:   "Dynamite",
+			ReleaseTitle: "Dynamite",
+			Time:         base.Add(time.Duration(i) * time.Hour),
+			UserID:       1,
+		}))
+	}
+	keep, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "BTS"})
+	require.NoError(t, err)
+	merge, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "방탄소년단"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, catalog.MergeArtists(ctx, store, keep.ID, keep.ID, false), catalog.ErrSelfMerge)
+	assert.ErrorIs(t, catalog.MergeArtists(ctx, store, keep.ID, 9999, false), db.ErrNotFound)
+
+	require.NoError(t, catalog.MergeArtists(ctx, store, keep.ID, merge.ID, false))
+
+	_, err = store.GetArtist(ctx, db.GetArtistOpts{ID: merge.ID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	merged, err := store.GetArtist(ctx, db.GetArtistOpts{ID: keep.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "BTS", merged.Name)
+	assert.Contains(t, merged.Aliases, "방탄소년단")
+	assert.EqualValues(t, 2, merged.ListenCount)
+
+	// the merged name now resolves to the kept artist
+	byName, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "방탄소년단"})
+	require.NoError(t, err)
+	assert.Equal(t, keep.ID, byName.ID)
+}
+
+func TestMergeReleases(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	keep := submitTrackListens(t, store, "Café", "OK Computer", base, 1)
+	// the first listen is at the same time as the listen to the kept track, so is the same listen
+	dup := submitTrackListens(t, store, "Cafe.", "OK Computer (Deluxe)", base, 2)
+	bonus := submitTrackListens(t, store, "Lift", "OK Computer (Deluxe)", base.Add(24*time.Hour), 1)
+
+	assert.ErrorIs(t, catalog.MergeReleases(ctx, store, keep.AlbumID, keep.AlbumID, false), catalog.ErrSelfMerge)
+	assert.ErrorIs(t, catalog.MergeReleases(ctx, store, keep.AlbumID, 9999, false), db.ErrNotFound)
+
+	require.NoError(t, catalog.MergeReleases(ctx, store, keep.AlbumID, dup.AlbumID, false))
+
+	_, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: dup.AlbumID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: keep.AlbumID})
+	require.NoError(t, err)
+	assert.Equal(t, "OK Computer", album.Title)
+	assert.EqualValues(t, 3, album.ListenCount)
+
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: dup.ID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	merged, err := store.GetTrack(ctx, db.GetTrackOpts{ID: keep.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Café", merged.Title)
+	assert.EqualValues(t, 2, merged.ListenCount)
+	moved, err := store.GetTrack(ctx, db.GetTrackOpts{ID: bonus.ID})
+	require.NoError(t, err)
+	assert.Equal(t, keep.AlbumID, moved.AlbumID)
+}
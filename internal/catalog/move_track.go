@@ -0,0 +1,54 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// MoveTrackToRelease moves a track that was matched to the wrong album, along with its listens, to
+// another release. When the release already has a track with the same title, the track is merged
+// into it instead. Returns the track as it is on the target release.
+func MoveTrackToRelease(ctx context.Context, store db.TrackStore, trackID, targetReleaseID int32) (*models.Track, error) {
+	l := logger.FromContext(ctx)
+	track, err := store.GetTrack(ctx, db.GetTrackOpts{ID: trackID})
+	if err != nil {
+		return nil, fmt.Errorf("MoveTrackToRelease: %w", err)
+	}
+	if track.AlbumID == targetReleaseID {
+		return track, nil
+	}
+
+	titles, err := store.GetTrackTitles(ctx, db.GetTrackTitlesOpts{ReleaseID: targetReleaseID})
+	if err != nil {
+		return nil, fmt.Errorf("MoveTrackToRelease: %w", err)
+	}
+	for _, t := range titles {
+		if !strings.EqualFold(t.Title, track.Title) {
+			continue
+		}
+		l.Info().Msgf("MoveTrackToRelease: Merging track %d into track %d, which has the same title on release %d", trackID, t.TrackID, targetReleaseID)
+		if err := store.MergeTracks(ctx, trackID, t.TrackID); err != nil {
+			return nil, fmt.Errorf("MoveTrackToRelease: %w", err)
+		}
+		track, err = store.GetTrack(ctx, db.GetTrackOpts{ID: t.TrackID})
+		if err != nil {
+			return nil, fmt.Errorf("MoveTrackToRelease: %w", err)
+		}
+		return track, nil
+	}
+
+	l.Info().Msgf("MoveTrackToRelease: Moving track %d to release %d", trackID, targetReleaseID)
+	if err := store.MoveTrackToRelease(ctx, trackID, targetReleaseID); err != nil {
+		return nil, fmt.Errorf("MoveTrackToRelease: %w", err)
+	}
+	track, err = store.GetTrack(ctx, db.GetTrackOpts{ID: trackID})
+	if err != nil {
+		return nil, fmt.Errorf("MoveTrackToRelease: %w", err)
+	}
+	return track, nil
+}
@@ -0,0 +1,54 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveTrackToRelease(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	track := submitTrackListens(t, store, "Creep", "Creep (Single)", base, 2)
+	other := submitTrackListens(t, store, "Anyone Can Play Guitar", "Pablo Honey", base.Add(24*time.Hour), 1)
+	wrongAlbum := track.AlbumID
+
+	moved, err := catalog.MoveTrackToRelease(ctx, store, track.ID, other.AlbumID)
+	require.NoError(t, err)
+	assert.Equal(t, track.ID, moved.ID)
+	assert.Equal(t, other.AlbumID, moved.AlbumID)
+	assert.EqualValues(t, 2, moved.ListenCount)
+
+	// nothing is left on the old album, so it is deleted
+	_, err = store.GetAlbum(ctx, db.GetAlbumOpts{ID: wrongAlbum})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	_, err = catalog.MoveTrackToRelease(ctx, store, track.ID, 9999)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+}
+
+func TestMoveTrackToRelease_MergesSameTitle(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	track := submitTrackListens(t, store, "Creep", "Creep (Single)", base, 2)
+	target := submitTrackListens(t, store, "creep", "Pablo Honey", base.Add(24*time.Hour), 1)
+
+	moved, err := catalog.MoveTrackToRelease(ctx, store, track.ID, target.AlbumID)
+	require.NoError(t, err)
+	assert.Equal(t, target.ID, moved.ID)
+	assert.Equal(t, target.AlbumID, moved.AlbumID)
+	assert.EqualValues(t, 3, moved.ListenCount)
+
+	_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: track.ID})
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	count, err := store.Count(`SELECT COUNT(*) FROM tracks`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
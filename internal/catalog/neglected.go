@@ -0,0 +1,31 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// GetNeglectedArtists returns the artists with at least minHistoricalPlays listens that have not been
+// listened to in the last sinceDays days, ordered by listen count. When userID is 0, listens from all
+// users are counted.
+func GetNeglectedArtists(ctx context.Context, store db.ArtistStore, userID int32, sinceDays, minHistoricalPlays int) ([]db.NeglectedArtist, error) {
+	if sinceDays < 1 {
+		return nil, errors.New("GetNeglectedArtists: days must be at least 1")
+	}
+	if minHistoricalPlays < 1 {
+		return nil, errors.New("GetNeglectedArtists: minimum listens must be at least 1")
+	}
+	artists, err := store.GetNeglectedArtists(ctx, db.GetNeglectedArtistsOpts{
+		UserID:     userID,
+		Before:     time.Now().AddDate(0, 0, -sinceDays),
+		MinListens: minHistoricalPlays,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetNeglectedArtists: %w", err)
+	}
+	return artists, nil
+}
@@ -0,0 +1,48 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNeglectedArtists(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	seedGetListens(t, store, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	artists, err := catalog.GetNeglectedArtists(ctx, store, 1, 30, 2)
+	require.NoError(t, err)
+	require.Len(t, artists, 2)
+	assert.Equal(t, "Artist A", artists[0].Name)
+	assert.EqualValues(t, 4, artists[0].Listens)
+	assert.Equal(t, "Artist B", artists[1].Name)
+	EqualTime(t, time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC), artists[1].LastListenedAt)
+
+	// a recent listen means the artist is no longer neglected
+	err = catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Artist B",
+		TrackTitle:   "Track 4",
+		ReleaseTitle: "Release Z",
+		Time:         time.Now().Add(-24 * time.Hour),
+		UserID:       1,
+	})
+	require.NoError(t, err)
+	artists, err = catalog.GetNeglectedArtists(ctx, store, 1, 30, 2)
+	require.NoError(t, err)
+	require.Len(t, artists, 1)
+	assert.Equal(t, "Artist A", artists[0].Name)
+
+	artists, err = catalog.GetNeglectedArtists(ctx, store, 1, 30, 5)
+	require.NoError(t, err)
+	assert.Empty(t, artists)
+
+	_, err = catalog.GetNeglectedArtists(ctx, store, 1, 0, 2)
+	assert.Error(t, err)
+}
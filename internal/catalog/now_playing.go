@@ -0,0 +1,138 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/memkv"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/google/uuid"
+)
+
+// how long a track is now playing when its duration is not known
+const defaultNowPlayingTTL = 10 * time.Minute
+
+// NowPlayingOpts describes the track a client reports as playing. The track is resolved like the
+// track of a listen, but no listen is saved.
+type NowPlayingOpts struct {
+	// When true, skips caching the images and only stores the image url in the db
+	SkipCacheImage bool
+
+	MbzCaller          mbz.MusicBrainzCaller
+	ArtistNames        []string
+	Artist             string
+	ArtistMbzIDs       []uuid.UUID
+	ArtistMbidMappings []ArtistMbidMap
+	ArtistSpotifyIDs   map[string]string
+	TrackTitle         string
+	RecordingMbzID     uuid.UUID
+	Duration           int32 // in seconds
+	ReleaseTitle       string
+	ReleaseMbzID       uuid.UUID
+	ReleaseGroupMbzID  uuid.UUID
+
+	UserID int32
+	Client string
+}
+
+// NowPlaying is the track a user is playing.
+type NowPlaying struct {
+	Track     *models.Track
+	Client    string
+	StartedAt time.Time
+	// when the track stops being reported as playing, unless the client reports it again
+	ExpiresAt time.Time
+}
+
+// nowPlayingState is what is remembered of the track a user is playing.
+type nowPlayingState struct {
+	trackID   int32
+	client    string
+	startedAt time.Time
+	expiresAt time.Time
+}
+
+// SetNowPlaying records the track as the one the user is playing, until the track would have finished
+// playing, or for defaultNowPlayingTTL when its duration is not known. The artists, album and track
+// are associated or created as for a listen, but the listen itself is not saved.
+func SetNowPlaying(ctx context.Context, store submitListenStore, opts NowPlayingOpts) error {
+	if opts.Artist == "" || opts.TrackTitle == "" {
+		return errors.New("SetNowPlaying: track name and artist are required")
+	}
+	resolved, err := resolveListen(ctx, store, SubmitListenOpts{
+		SkipCacheImage:     opts.SkipCacheImage,
+		MbzCaller:          opts.MbzCaller,
+		ArtistNames:        opts.ArtistNames,
+		Artist:             opts.Artist,
+		ArtistMbzIDs:       opts.ArtistMbzIDs,
+		ArtistMbidMappings: opts.ArtistMbidMappings,
+		ArtistSpotifyIDs:   opts.ArtistSpotifyIDs,
+		TrackTitle:         opts.TrackTitle,
+		RecordingMbzID:     opts.RecordingMbzID,
+		Duration:           opts.Duration,
+		ReleaseTitle:       opts.ReleaseTitle,
+		ReleaseMbzID:       opts.ReleaseMbzID,
+		ReleaseGroupMbzID:  opts.ReleaseGroupMbzID,
+		UserID:             opts.UserID,
+		Client:             opts.Client,
+	})
+	if err != nil {
+		return fmt.Errorf("SetNowPlaying: %w", err)
+	}
+	duration := resolved.track.Duration
+	if duration == 0 {
+		duration = opts.Duration
+	}
+	storeNowPlaying(opts.UserID, resolved.track.ID, duration, opts.Client)
+	return nil
+}
+
+// GetNowPlaying returns the track the user is playing, or nil if the user is not playing anything.
+func GetNowPlaying(ctx context.Context, store db.TrackStore, userID int32) (*NowPlaying, error) {
+	v, ok := memkv.Store.Get(nowPlayingKey(userID))
+	if !ok {
+		return nil, nil
+	}
+	state, ok := v.(nowPlayingState)
+	if !ok {
+		return nil, errors.New("GetNowPlaying: unexpected now playing state")
+	}
+	track, err := store.GetTrack(ctx, db.GetTrackOpts{ID: state.trackID})
+	if errors.Is(err, db.ErrNotFound) {
+		// deleted or merged into another track since
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetNowPlaying: %w", err)
+	}
+	return &NowPlaying{
+		Track:     track,
+		Client:    state.client,
+		StartedAt: state.startedAt,
+		ExpiresAt: state.expiresAt,
+	}, nil
+}
+
+func nowPlayingKey(userID int32) string {
+	return "now_playing_" + strconv.Itoa(int(userID))
+}
+
+// storeNowPlaying records the track as the one the user is playing, for its duration in seconds.
+func storeNowPlaying(userID, trackID, duration int32, client string) {
+	ttl := defaultNowPlayingTTL
+	if duration > 0 {
+		ttl = time.Duration(duration) * time.Second
+	}
+	now := time.Now()
+	memkv.Store.Set(nowPlayingKey(userID), nowPlayingState{
+		trackID:   trackID,
+		client:    client,
+		startedAt: now,
+		expiresAt: now.Add(ttl),
+	}, ttl)
+}
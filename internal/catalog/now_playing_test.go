@@ -0,0 +1,49 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetNowPlaying(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	playing, err := catalog.GetNowPlaying(ctx, store, 2)
+	require.NoError(t, err)
+	assert.Nil(t, playing)
+
+	before := time.Now()
+	require.NoError(t, catalog.SetNowPlaying(ctx, store, catalog.NowPlayingOpts{
+		MbzCaller:    &mbz.MbzErrorCaller{},
+		Artist:       "Playing Artist",
+		TrackTitle:   "Playing Track",
+		ReleaseTitle: "Playing Album",
+		Duration:     240,
+		UserID:       2,
+		Client:       "navidrome",
+	}))
+
+	playing, err = catalog.GetNowPlaying(ctx, store, 2)
+	require.NoError(t, err)
+	require.NotNil(t, playing)
+	assert.Equal(t, "Playing Track", playing.Track.Title)
+	assert.Equal(t, "navidrome", playing.Client)
+	assert.WithinDuration(t, before.Add(240*time.Second), playing.ExpiresAt, 5*time.Second)
+
+	// no listen is saved, and other users are not playing anything
+	count, err := store.Count(`SELECT COUNT(*) FROM all_listens`)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	playing, err = catalog.GetNowPlaying(ctx, store, 3)
+	require.NoError(t, err)
+	assert.Nil(t, playing)
+
+	assert.Error(t, catalog.SetNowPlaying(ctx, store, catalog.NowPlayingOpts{Artist: "Playing Artist", UserID: 2}))
+}
@@ -0,0 +1,49 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitListen_QuietHours(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	cfg.SetScrobbleQuietHours(&cfg.QuietHours{Start: 23 * time.Hour, End: 6 * time.Hour})
+	defer cfg.SetScrobbleQuietHours(nil)
+
+	quiet := time.Date(2026, 3, 1, 2, 0, 0, 0, time.Local)
+	submit := func(title string, live bool, at time.Time) {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Quiet Artist",
+			TrackTitle:   title,
+			ReleaseTitle: "Quiet Album",
+			Time:         at,
+			UserID:       1,
+			IsLive:       live,
+		})
+		require.NoError(t, err)
+	}
+	countListens := func(title string) int {
+		count, err := store.Count(`SELECT COUNT(*) FROM listens l JOIN tracks_with_title t ON l.track_id = t.id WHERE t.title = ?`, title)
+		require.NoError(t, err)
+		return count
+	}
+
+	// a live listen during quiet hours is dropped, but an imported one is kept
+	submit("Live At Night", true, quiet)
+	assert.Equal(t, 0, countListens("Live At Night"))
+	submit("Imported At Night", false, quiet)
+	assert.Equal(t, 1, countListens("Imported At Night"))
+
+	// live listens outside of quiet hours are kept
+	submit("Live At Noon", true, time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local))
+	assert.Equal(t, 1, countListens("Live At Noon"))
+}
@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+var ErrInvalidEntityType = errors.New("entity type must be artist, album or track")
+
+const (
+	EntityArtist = "artist"
+	EntityAlbum  = "album"
+	EntityTrack  = "track"
+)
+
+type RecountStore interface {
+	db.ArtistStore
+	db.AlbumStore
+	db.TrackStore
+	db.ListenStore
+}
+
+// EntityCounts is the play count and listening time, in seconds, of an artist, album or track.
+type EntityCounts struct {
+	ListenCount  int64 `json:"listen_count"`
+	TimeListened int64 `json:"time_listened"`
+}
+
+// RecountEntity recounts the plays and listening time of one artist, album or track from its listens,
+// such as right after a merge. Counts are not stored, but computed from the listens whenever an item
+// is read, so the recount is the value every read returns from then on and there is nothing to write
+// back; it returns db.ErrNotFound when the item does not exist.
+func RecountEntity(ctx context.Context, store RecountStore, entityType string, id int32) (*EntityCounts, error) {
+	l := logger.FromContext(ctx)
+	opts := db.TimeListenedOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}}
+	var err error
+	switch entityType {
+	case EntityArtist:
+		_, err = store.GetArtist(ctx, db.GetArtistOpts{ID: id})
+		opts.ArtistID = id
+	case EntityAlbum:
+		_, err = store.GetAlbum(ctx, db.GetAlbumOpts{ID: id})
+		opts.AlbumID = id
+	case EntityTrack:
+		_, err = store.GetTrack(ctx, db.GetTrackOpts{ID: id})
+		opts.TrackID = id
+	default:
+		return nil, fmt.Errorf("RecountEntity: %w", ErrInvalidEntityType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("RecountEntity: %w", err)
+	}
+
+	count, err := store.CountListensToItem(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("RecountEntity: %w", err)
+	}
+	seconds, err := store.CountTimeListenedToItem(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("RecountEntity: %w", err)
+	}
+	l.Debug().Msgf("RecountEntity: %s %d has %d listens and %d seconds listened", entityType, id, count, seconds)
+	return &EntityCounts{ListenCount: count, TimeListened: seconds}, nil
+}
@@ -0,0 +1,72 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// submitTrackListens submits listens to a track by Radiohead on the given album, one hour apart.
+func submitTrackListens(t *testing.T, store *sqlite.Sqlite, title, album string, start time.Time, n int) *models.Track {
+	ctx := context.Background()
+	for i := range n {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Radiohead",
+			TrackTitle:   title,
+			ReleaseTitle: album,
+			Time:         start.Add(time.Duration(i) * time.Hour),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Radiohead"})
+	require.NoError(t, err)
+	a, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: album, ArtistID: artist.ID})
+	require.NoError(t, err)
+	track, err := store.GetTrack(ctx, db.GetTrackOpts{Title: title, ReleaseID: a.ID, ArtistIDs: []int32{artist.ID}})
+	require.NoError(t, err)
+	return track
+}
+
+func TestRecountEntity(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	keep := submitTrackListens(t, store, "Reckoner", "In Rainbows", base, 3)
+	merge := submitTrackListens(t, store, "Reckoner (Live)", "In Rainbows", base.Add(24*time.Hour), 2)
+	require.NoError(t, store.Exec(`UPDATE tracks SET duration = 290`))
+
+	counts, err := catalog.RecountEntity(ctx, store, catalog.EntityTrack, keep.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &catalog.EntityCounts{ListenCount: 3, TimeListened: 3 * 290}, counts)
+
+	// the recount right after a merge includes the merged track's listens
+	require.NoError(t, store.MergeTracks(ctx, merge.ID, keep.ID))
+	counts, err = catalog.RecountEntity(ctx, store, catalog.EntityTrack, keep.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &catalog.EntityCounts{ListenCount: 5, TimeListened: 5 * 290}, counts)
+
+	counts, err = catalog.RecountEntity(ctx, store, catalog.EntityAlbum, keep.AlbumID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, counts.ListenCount)
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Radiohead"})
+	require.NoError(t, err)
+	counts, err = catalog.RecountEntity(ctx, store, catalog.EntityArtist, artist.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, counts.ListenCount)
+
+	_, err = catalog.RecountEntity(ctx, store, catalog.EntityTrack, merge.ID)
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = catalog.RecountEntity(ctx, store, "playlist", keep.ID)
+	assert.ErrorIs(t, err, catalog.ErrInvalidEntityType)
+}
@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/gabehf/koito/internal/romanizer"
+)
+
+const maxListenSearchLength = 100
+
+// ErrInvalidSearch is returned for a listen search query that is empty or too long.
+var ErrInvalidSearch = fmt.Errorf("search query must be between 1 and %d characters", maxListenSearchLength)
+
+// SearchListens returns a page of the user's listens, including private ones, to tracks whose
+// title, artist or album contains the query. Matching ignores case for Latin letters and also
+// tries the romanized query, so "アイドル" finds an artist saved as "Aidoru". Names saved in their
+// original script are found by a romanized query through their aliases.
+func SearchListens(ctx context.Context, store db.ListenStore, userID int32, query string, limit, page int) (*db.PaginatedResponse[*models.Listen], error) {
+	if userID == 0 {
+		return nil, errors.New("SearchListens: user id must be provided")
+	}
+	if limit < 0 || page < 0 {
+		return nil, errors.New("SearchListens: limit and page must not be negative")
+	}
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxListenSearchLength {
+		return nil, fmt.Errorf("SearchListens: %w", ErrInvalidSearch)
+	}
+
+	terms := []string{query}
+	if romanized := strings.TrimSpace(romanizer.Romanize(query)); romanized != "" && !strings.EqualFold(romanized, query) {
+		terms = append(terms, romanized)
+	}
+
+	listens, err := store.GetListens(ctx, db.GetListensOpts{
+		Limit:     limit,
+		Page:      page,
+		Timeframe: db.Timeframe{Period: db.PeriodAllTime},
+		UserID:    userID,
+		Search:    terms,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("SearchListens: %w", err)
+	}
+	return listens, nil
+}
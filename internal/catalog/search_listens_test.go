@@ -0,0 +1,99 @@
+package catalog_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchListens(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	mbzc := &mbz.MbzMockCaller{}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	second, err := store.SaveUser(ctx, db.SaveUserOpts{Username: "second", Password: "password123"})
+	require.NoError(t, err)
+
+	listens := []struct {
+		artist, track, release string
+		userID                 int32
+		private                bool
+	}{
+		{"Aidoru Band", "Intro", "First Album", 1, false},
+		{"YOASOBI", "夜に駆ける", "THE BOOK", 1, false},
+		{"Some Artist", "Song (Remix)", "Remixes", 1, true},
+		{"Other Artist", "Another Song", "Singles", 1, false},
+		{"Other Artist", "Second Remix", "Singles", second.ID, false},
+	}
+	for i, l := range listens {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    mbzc,
+			Artist:       l.artist,
+			TrackTitle:   l.track,
+			ReleaseTitle: l.release,
+			Time:         base.Add(time.Duration(i) * time.Hour),
+			UserID:       l.userID,
+			Private:      l.private,
+		})
+		require.NoError(t, err)
+	}
+
+	yoasobi, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "YOASOBI"})
+	require.NoError(t, err)
+	book, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "THE BOOK", ArtistID: yoasobi.ID})
+	require.NoError(t, err)
+	yoru, err := store.GetTrack(ctx, db.GetTrackOpts{Title: "夜に駆ける", ReleaseID: book.ID, ArtistIDs: []int32{yoasobi.ID}})
+	require.NoError(t, err)
+	require.NoError(t, store.SaveTrackAliases(ctx, yoru.ID, []string{"Yoru ni Kakeru"}, models.AliasSourceManual))
+
+	titles := func(resp *db.PaginatedResponse[*models.Listen]) []string {
+		var out []string
+		for _, l := range resp.Items {
+			out = append(out, l.Track.Title)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		userID int32
+		want   []string
+	}{
+		{"track title, including private listens", "remix", 1, []string{"Song (Remix)"}},
+		{"album title", "first album", 1, []string{"Intro"}},
+		{"artist name", "other", 1, []string{"Another Song"}},
+		{"original script query matches romanized name", "アイドル", 1, []string{"Intro"}},
+		{"original script query matches original title", "駆け", 1, []string{"夜に駆ける"}},
+		{"romanized query matches alias", "yoru ni", 1, []string{"夜に駆ける"}},
+		{"scoped to the user", "remix", second.ID, []string{"Second Remix"}},
+		{"no match", "nothing like this", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := catalog.SearchListens(ctx, store, tt.userID, tt.query, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, titles(resp))
+			assert.EqualValues(t, len(tt.want), resp.TotalCount)
+		})
+	}
+
+	// wildcards in the query are matched literally
+	resp, err := catalog.SearchListens(ctx, store, 1, "%", 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Items)
+
+	_, err = catalog.SearchListens(ctx, store, 1, "   ", 0, 0)
+	assert.ErrorIs(t, err, catalog.ErrInvalidSearch)
+	_, err = catalog.SearchListens(ctx, store, 1, strings.Repeat("a", 101), 0, 0)
+	assert.ErrorIs(t, err, catalog.ErrInvalidSearch)
+}
@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+)
+
+// A ListeningSession is a run of listens where each starts within the configured session gap
+// of the end of the previous one.
+type ListeningSession struct {
+	Start    time.Time           `json:"start"`
+	End      time.Time           `json:"end"`
+	Duration int64               `json:"duration"` // in seconds
+	Tracks   []db.ListenLogEntry `json:"tracks"`
+}
+
+// GetListeningSessions groups the user's listens within the timeframe into listening sessions,
+// returning the most recent session first. When userID is 0, listens from all users are grouped.
+// An empty timeframe defaults to all time.
+func GetListeningSessions(ctx context.Context, store db.ListenStore, userID int32, tf db.Timeframe) ([]ListeningSession, error) {
+	if _, t2 := db.TimeframeToTimeRange(tf); t2.IsZero() {
+		tf.Period = db.PeriodAllTime
+	}
+	log, err := store.GetListenLog(ctx, db.GetListenLogOpts{UserID: userID, Timeframe: tf})
+	if err != nil {
+		return nil, fmt.Errorf("GetListeningSessions: %w", err)
+	}
+	sessions := groupSessions(log, time.Duration(cfg.SessionGapMinutes())*time.Minute)
+	slices.Reverse(sessions)
+	return sessions, nil
+}
+
+// groupSessions splits a chronological listen log into sessions. A listen ends once its
+// track's duration has passed, or immediately when the duration is unknown.
+func groupSessions(log []db.ListenLogEntry, gap time.Duration) []ListeningSession {
+	sessions := make([]ListeningSession, 0)
+	var current *ListeningSession
+	for _, entry := range log {
+		end := entry.Time.Add(time.Duration(entry.Duration) * time.Second)
+		if current != nil && entry.Time.Sub(current.End) <= gap {
+			current.Tracks = append(current.Tracks, entry)
+			if end.After(current.End) {
+				current.End = end
+			}
+			continue
+		}
+		if current != nil {
+			sessions = append(sessions, *current)
+		}
+		current = &ListeningSession{
+			Start:  entry.Time,
+			End:    end,
+			Tracks: []db.ListenLogEntry{entry},
+		}
+	}
+	if current != nil {
+		sessions = append(sessions, *current)
+	}
+	for i := range sessions {
+		sessions[i].Duration = int64(sessions[i].End.Sub(sessions[i].Start).Seconds())
+	}
+	return sessions
+}
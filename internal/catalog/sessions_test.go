@@ -0,0 +1,77 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetListeningSessions(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+
+	// default session gap is 30 minutes
+	listens := []struct {
+		track    string
+		offset   time.Duration
+		duration int32
+	}{
+		// first session: back to back, then a 20 minute pause
+		{"Track 1", 0, 240},
+		{"Track 2", 4 * time.Minute, 240},
+		{"Track 3", 28 * time.Minute, 300},
+		// second session starts more than 30 minutes after the previous track ended
+		{"Track 4", 2 * time.Hour, 0},
+		{"Track 5", 2*time.Hour + 10*time.Minute, 180},
+	}
+	for _, l := range listens {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Session Artist",
+			TrackTitle:   l.track,
+			ReleaseTitle: "Session Album",
+			Duration:     l.duration,
+			Time:         base.Add(l.offset),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	sessions, err := catalog.GetListeningSessions(ctx, store, 1, db.Timeframe{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+
+	// most recent first
+	latest := sessions[0]
+	require.Len(t, latest.Tracks, 2)
+	assert.Equal(t, "Track 4", latest.Tracks[0].Title)
+	assert.Equal(t, "Track 5", latest.Tracks[1].Title)
+	EqualTime(t, base.Add(2*time.Hour), latest.Start)
+	EqualTime(t, base.Add(2*time.Hour+13*time.Minute), latest.End)
+	assert.EqualValues(t, 13*60, latest.Duration)
+
+	first := sessions[1]
+	require.Len(t, first.Tracks, 3)
+	assert.Equal(t, "Track 1", first.Tracks[0].Title)
+	EqualTime(t, base, first.Start)
+	EqualTime(t, base.Add(33*time.Minute), first.End)
+	assert.EqualValues(t, 33*60, first.Duration)
+
+	// a timeframe limits which listens are grouped
+	sessions, err = catalog.GetListeningSessions(ctx, store, 1, db.Timeframe{From: base.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Len(t, sessions[0].Tracks, 2)
+
+	// no sessions for a user without listens
+	sessions, err = catalog.GetListeningSessions(ctx, store, 2, db.Timeframe{})
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
@@ -0,0 +1,50 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// tagSingle marks the album as a single when its only track shares its title, and clears the mark
+// once the album gains another track. It does nothing when the single release policy is disabled.
+func tagSingle(ctx context.Context, store db.AlbumStore, album *models.Album, track *models.Track) error {
+	l := logger.FromContext(ctx)
+	if cfg.SingleReleasePolicy() == cfg.SingleReleasePolicyNone {
+		return nil
+	}
+	sameTitle := strings.EqualFold(strings.TrimSpace(album.Title), strings.TrimSpace(track.Title))
+	if !sameTitle && !album.IsSingle {
+		return nil
+	}
+
+	count, err := store.CountAlbumTracks(ctx, album.ID)
+	if err != nil {
+		return fmt.Errorf("tagSingle: %w", err)
+	}
+	single := sameTitle && count == 1
+	if single == album.IsSingle {
+		return nil
+	}
+
+	err = store.UpdateAlbum(ctx, db.UpdateAlbumOpts{
+		ID:           album.ID,
+		SingleUpdate: true,
+		SingleValue:  single,
+	})
+	if err != nil {
+		return fmt.Errorf("tagSingle: %w", err)
+	}
+	album.IsSingle = single
+	if single {
+		l.Info().Msgf("Tagged album '%s' as a single of track '%s'", album.Title, track.Title)
+	} else {
+		l.Info().Msgf("Album '%s' is no longer tagged as a single", album.Title)
+	}
+	return nil
+}
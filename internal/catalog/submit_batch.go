@@ -0,0 +1,151 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+// BatchResult is the outcome of each listen of a batch.
+type BatchResult struct {
+	// the error each listen failed with, or nil for the listens that did not fail, in the order they
+	// were given
+	Errors []error
+	// the number of listens that were saved, left out as duplicates of saved listens, and that failed
+	Submitted int
+	Deduped   int
+	Failed    int
+}
+
+// SubmitListensBatch submits the listens like SubmitListen does for each of them, but resolves the
+// artists, album and track of listens with the same metadata only once, and saves all the listens in
+// a single transaction. Listens whose metadata cannot be resolved fail on their own and are reported
+// in the result, while an error is returned when the listens cannot be saved, in which case none are.
+// Duplicates are found among the listens of the batch as well as the saved ones.
+func SubmitListensBatch(ctx context.Context, store submitListenStore, opts []SubmitListenOpts) (BatchResult, error) {
+	l := logger.FromContext(ctx)
+	result := BatchResult{Errors: make([]error, len(opts))}
+	// whether each listen was left out as a duplicate, which is changed to false once any of its
+	// times are saved
+	deduped := make([]bool, len(opts))
+
+	type resolution struct {
+		listen *resolvedListen
+		err    error
+	}
+	type userTrack struct{ userID, trackID int32 }
+	resolved := make(map[string]*resolution)
+	// the times of the listens of the batch to each track
+	batched := make(map[userTrack][]time.Time)
+	keys := make(map[string]bool)
+	var saves []db.SaveListenOpts
+	// the listen each save is of
+	var owners []int
+	for i, o := range opts {
+		if o.Artist == "" || o.TrackTitle == "" {
+			result.Errors[i] = errors.New("track name and artist are required")
+			continue
+		}
+		times := listenTimes(ctx, o)
+		if len(times) == 0 {
+			continue
+		}
+		if o.DedupeKey != "" && (keys[dedupeKeyName(o)] || dedupeKeySeen(o)) {
+			l.Info().Msgf("SubmitListensBatch: Ignoring listen '%s' by %s with dedupe key '%s', which was already submitted", o.TrackTitle, o.Artist, o.DedupeKey)
+			deduped[i] = true
+			continue
+		}
+		key := listenMetadataKey(o)
+		r, ok := resolved[key]
+		if !ok {
+			listen, err := resolveListen(ctx, store, o)
+			r = &resolution{listen: listen, err: err}
+			resolved[key] = r
+		} else if r.err == nil && !r.listen.hasDuration && o.Duration != 0 {
+			r.listen.hasDuration = updateTrackDuration(ctx, store, o, r.listen.track)
+		}
+		if r.err != nil {
+			result.Errors[i] = fmt.Errorf("SubmitListensBatch: %w", r.err)
+			continue
+		}
+		track := r.listen.track
+		setNowPlaying(o, track)
+		if o.SkipSaveListen {
+			continue
+		}
+		deduped[i] = true
+		for _, t := range times {
+			if o.DedupeWindow > 0 {
+				dup := slices.ContainsFunc(batched[userTrack{o.UserID, track.ID}], func(prev time.Time) bool {
+					return t.Sub(prev).Abs() < o.DedupeWindow
+				})
+				if !dup {
+					var err error
+					dup, err = store.ListenExistsWithin(ctx, db.ListenExistsWithinOpts{TrackID: track.ID, UserID: o.UserID, Time: t, Window: o.DedupeWindow})
+					if err != nil {
+						result.Errors[i] = fmt.Errorf("SubmitListensBatch: %w", err)
+						break
+					}
+				}
+				if dup {
+					l.Info().Msgf("SubmitListensBatch: Ignoring listen to '%s' at %s, within %s of a saved listen to it", track.Title, t.Format(time.RFC3339), o.DedupeWindow)
+					continue
+				}
+			}
+			l.Info().Msgf("Received listen: '%s' by %s, from release '%s'", track.Title, buildArtistStr(r.listen.artists), r.listen.album.Title)
+			batched[userTrack{o.UserID, track.ID}] = append(batched[userTrack{o.UserID, track.ID}], t)
+			saves = append(saves, db.SaveListenOpts{
+				TrackID: track.ID,
+				Time:    t,
+				UserID:  o.UserID,
+				Client:  o.Client,
+				Device:  o.Device,
+				Private: o.Private,
+			})
+			owners = append(owners, i)
+		}
+		if o.DedupeKey != "" {
+			keys[dedupeKeyName(o)] = true
+		}
+	}
+
+	if len(saves) > 0 {
+		saved, err := store.SaveListens(ctx, saves)
+		if err != nil {
+			return BatchResult{}, fmt.Errorf("SubmitListensBatch: %w", err)
+		}
+		for i, ok := range saved {
+			if !ok {
+				l.Debug().Msgf("Ignored listen to track %d at %s, which was already saved", saves[i].TrackID, saves[i].Time.Format(time.RFC3339))
+				continue
+			}
+			deduped[owners[i]] = false
+		}
+	}
+	for i, err := range result.Errors {
+		switch {
+		case err != nil:
+			result.Failed++
+		case deduped[i]:
+			result.Deduped++
+		default:
+			result.Submitted++
+			rememberDedupeKey(opts[i])
+		}
+	}
+	return result, nil
+}
+
+// listenMetadataKey identifies the metadata a listen's artists, album and track are resolved from.
+// The duration is left out, as it only updates tracks that have none.
+func listenMetadataKey(o SubmitListenOpts) string {
+	return fmt.Sprintf("%q %q %v %v %v %q %q %v %q %v %v %v",
+		o.Artist, o.ArtistNames, o.ArtistMbzIDs, o.ArtistMbidMappings, o.ArtistSpotifyIDs,
+		o.TrackTitle, o.TrackSpotifyID, o.RecordingMbzID,
+		o.ReleaseTitle, o.ReleaseMbzID, o.ReleaseGroupMbzID, o.SkipCacheImage)
+}
@@ -0,0 +1,97 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitListensBatch(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	start := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	listen := func(track string, minutes int) catalog.SubmitListenOpts {
+		return catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzErrorCaller{},
+			Artist:       "Batch Artist",
+			TrackTitle:   track,
+			ReleaseTitle: "Batch Album",
+			Duration:     180,
+			Time:         start.Add(time.Duration(minutes) * time.Minute),
+			UserID:       1,
+		}
+	}
+	opts := []catalog.SubmitListenOpts{
+		listen("First Track", 0),
+		listen("Second Track", 4),
+		listen("First Track", 8),
+		listen("", 12),
+	}
+
+	result, err := catalog.SubmitListensBatch(ctx, store, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Submitted)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Errors, 4)
+	assert.NoError(t, result.Errors[0])
+	assert.NoError(t, result.Errors[2])
+	assert.Error(t, result.Errors[3])
+
+	count, err := store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	count, err = store.Count(`SELECT COUNT(*) FROM tracks`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = store.Count(`SELECT COUNT(*) FROM artists`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = store.Count(`SELECT COUNT(*) FROM releases`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// submitting the batch again saves no duplicate listens
+	_, err = catalog.SubmitListensBatch(ctx, store, opts)
+	require.NoError(t, err)
+	count, err = store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestSubmitListensBatch_Dedupe(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	start := time.Date(2025, time.March, 2, 12, 0, 0, 0, time.UTC)
+	listen := func(seconds int) catalog.SubmitListenOpts {
+		return catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzErrorCaller{},
+			Artist:       "Batch Artist",
+			TrackTitle:   "Repeated Track",
+			ReleaseTitle: "Batch Album",
+			Time:         start.Add(time.Duration(seconds) * time.Second),
+			UserID:       1,
+			DedupeWindow: 5 * time.Second,
+		}
+	}
+
+	// repeats within the batch are duplicates
+	result, err := catalog.SubmitListensBatch(ctx, store, []catalog.SubmitListenOpts{listen(0), listen(3), listen(60)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Submitted)
+	assert.Equal(t, 1, result.Deduped)
+
+	// and so are repeats of listens saved before
+	result, err = catalog.SubmitListensBatch(ctx, store, []catalog.SubmitListenOpts{listen(62), listen(120)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Submitted)
+	assert.Equal(t, 1, result.Deduped)
+
+	count, err := store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -6,8 +6,11 @@ import (
 	"time"
 
 	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
 	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -63,7 +66,7 @@ func TestSubmitListen_CreateAllMbzIDs(t *testing.T) {
 	assert.True(t, exists, "expected listen row to exist")
 
 	// Verify that listen time is correct
-	p, err := store.GetListensPaginated(ctx, db.GetItemsOpts{Limit: 1, Page: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	p, err := store.GetListens(ctx, db.GetListensOpts{Limit: 1, Page: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
 	require.NoError(t, err)
 	require.Len(t, p.Items, 1)
 	l := p.Items[0]
@@ -1050,3 +1053,563 @@ func TestSubmitListen_MusicBrainzUnreachableMBIDMappings(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, exists, "expected artist to have correct musicbrainz id")
 }
+
+// seedAlbumMatchPolicy creates the track 'Song' on two albums: first on 'Song - Single'
+// with one listen, then on 'The Album' with two listens.
+func seedAlbumMatchPolicy(t *testing.T) *sqlite.Sqlite {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, release := range []string{"Song - Single", "The Album", "The Album"} {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Policy Artist",
+			TrackTitle:   "Song",
+			ReleaseTitle: release,
+			Time:         base.Add(time.Duration(i) * time.Hour),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+	return store
+}
+
+func TestSubmitListen_AlbumMatchPolicy(t *testing.T) {
+	ctx := context.Background()
+	defer cfg.SetAlbumMatchPolicy(cfg.AlbumMatchPolicyNone)
+
+	tests := []struct {
+		policy        string
+		expectedAlbum string
+	}{
+		{cfg.AlbumMatchPolicyFirstAdded, "Song - Single"},
+		{cfg.AlbumMatchPolicyMostPlayed, "The Album"},
+		// without a policy, the release title falls back to the track title
+		{cfg.AlbumMatchPolicyNone, "Song"},
+	}
+
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			store := seedAlbumMatchPolicy(t)
+			cfg.SetAlbumMatchPolicy(tt.policy)
+
+			err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+				MbzCaller:  &mbz.MbzMockCaller{},
+				Artist:     "Policy Artist",
+				TrackTitle: "Song",
+				Time:       time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC),
+				UserID:     1,
+			})
+			require.NoError(t, err)
+
+			resp, err := store.GetListens(ctx, db.GetListensOpts{Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, resp.Items, 1)
+			track, err := store.GetTrack(ctx, db.GetTrackOpts{ID: resp.Items[0].Track.ID})
+			require.NoError(t, err)
+			album, err := store.GetAlbum(ctx, db.GetAlbumOpts{ID: track.AlbumID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAlbum, album.Title)
+		})
+	}
+}
+
+func TestSubmitListen_AlbumMatchPolicyIgnoredWithReleaseTitle(t *testing.T) {
+	ctx := context.Background()
+	store := seedAlbumMatchPolicy(t)
+	cfg.SetAlbumMatchPolicy(cfg.AlbumMatchPolicyMostPlayed)
+	defer cfg.SetAlbumMatchPolicy(cfg.AlbumMatchPolicyNone)
+
+	err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Policy Artist",
+		TrackTitle:   "Song",
+		ReleaseTitle: "Song - Single",
+		Time:         time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC),
+		UserID:       1,
+	})
+	require.NoError(t, err)
+
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Policy Artist"})
+	require.NoError(t, err)
+	candidates, err := store.GetTrackAlbumCandidates(ctx, db.GetTrackAlbumCandidatesOpts{Title: "Song", ArtistIDs: []int32{artist.ID}, UserID: 1})
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	assert.EqualValues(t, 2, candidates[0].Listens)
+	assert.EqualValues(t, 2, candidates[1].Listens)
+}
+
+func TestSubmitListen_SingleReleasePolicy(t *testing.T) {
+	ctx := context.Background()
+	defer cfg.SetSingleReleasePolicy(cfg.SingleReleasePolicyNone)
+
+	tests := []struct {
+		policy         string
+		expectedSingle bool
+	}{
+		{cfg.SingleReleasePolicyNone, false},
+		{cfg.SingleReleasePolicyTag, true},
+		{cfg.SingleReleasePolicyCollapse, true},
+	}
+
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			store := newTestDB()
+			cfg.SetSingleReleasePolicy(tt.policy)
+
+			for i := range 2 {
+				err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+					MbzCaller:    &mbz.MbzMockCaller{},
+					Artist:       "Single Artist",
+					TrackTitle:   "Song",
+					ReleaseTitle: "Song",
+					Time:         time.Date(2024, 1, 1, 12+i, 0, 0, 0, time.UTC),
+					UserID:       1,
+				})
+				require.NoError(t, err)
+			}
+
+			artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Single Artist"})
+			require.NoError(t, err)
+			album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Song", ArtistID: artist.ID})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSingle, album.IsSingle)
+
+			// listens stay attributed to the track either way
+			track, err := store.GetTrack(ctx, db.GetTrackOpts{Title: "Song", ReleaseID: album.ID, ArtistIDs: []int32{artist.ID}})
+			require.NoError(t, err)
+			assert.EqualValues(t, 2, track.ListenCount)
+
+			albums, err := store.GetTopAlbumsPaginated(ctx, db.GetItemsOpts{
+				Limit:          10,
+				Page:           1,
+				Timeframe:      db.Timeframe{Period: db.PeriodAllTime},
+				ExcludeSingles: tt.policy == cfg.SingleReleasePolicyCollapse,
+			})
+			require.NoError(t, err)
+			if tt.policy == cfg.SingleReleasePolicyCollapse {
+				assert.Empty(t, albums.Items)
+			} else {
+				require.Len(t, albums.Items, 1)
+				assert.Equal(t, tt.expectedSingle, albums.Items[0].Item.IsSingle)
+			}
+			tracks, err := store.GetTopTracksPaginated(ctx, db.GetItemsOpts{Limit: 10, Page: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+			require.NoError(t, err)
+			require.Len(t, tracks.Items, 1)
+			assert.EqualValues(t, 2, tracks.Items[0].Item.ListenCount)
+		})
+	}
+}
+
+func TestSubmitListen_SingleReleasePolicyUntagsOnSecondTrack(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	cfg.SetSingleReleasePolicy(cfg.SingleReleasePolicyTag)
+	defer cfg.SetSingleReleasePolicy(cfg.SingleReleasePolicyNone)
+
+	submit := func(title string, hour int) {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Single Artist",
+			TrackTitle:   title,
+			ReleaseTitle: "Song",
+			Time:         time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	submit("Song", 12)
+	artist, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Single Artist"})
+	require.NoError(t, err)
+	album, err := store.GetAlbum(ctx, db.GetAlbumOpts{Title: "Song", ArtistID: artist.ID})
+	require.NoError(t, err)
+	assert.True(t, album.IsSingle)
+
+	submit("Song (Instrumental)", 13)
+	album, err = store.GetAlbum(ctx, db.GetAlbumOpts{ID: album.ID})
+	require.NoError(t, err)
+	assert.False(t, album.IsSingle)
+
+	// a different title on its own is never a single
+	submit("B-Side", 14)
+	count, err := store.CountAlbumTracks(ctx, album.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestSubmitListen_MultipleTimes(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	opts := catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Repeat Artist",
+		TrackTitle:   "Repeat Track",
+		ReleaseTitle: "Repeat Release",
+		Time:         base,
+		UserID:       1,
+	}
+	require.NoError(t, catalog.SubmitListen(ctx, store, opts))
+
+	// the first time duplicates the listen above, and the last two only differ by sub-second precision
+	opts.Times = []time.Time{
+		base,
+		base.Add(4 * time.Minute),
+		base.Add(8 * time.Minute),
+		base.Add(8*time.Minute + 500*time.Millisecond),
+	}
+	require.NoError(t, catalog.SubmitListen(ctx, store, opts))
+
+	resp, err := store.GetListens(ctx, db.GetListensOpts{})
+	require.NoError(t, err)
+	require.Len(t, resp.Items, 3)
+	for _, l := range resp.Items {
+		assert.Equal(t, "Repeat Track", l.Track.Title)
+	}
+	EqualTime(t, base.Add(8*time.Minute), resp.Items[0].Time)
+
+	count, err := store.Count(`SELECT COUNT(*) FROM tracks`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
+
+func TestSubmitListen_Private(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	submit := func(artist string, at time.Time, private bool) {
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       artist,
+			TrackTitle:   artist + " Track",
+			ReleaseTitle: artist + " Release",
+			Time:         at,
+			UserID:       1,
+			Private:      private,
+		}))
+	}
+	submit("Public Artist", base, false)
+	submit("Public Artist", base.Add(time.Hour), false)
+	submit("Private Artist", base.Add(2*time.Hour), true)
+	submit("Private Artist", base.Add(3*time.Hour), true)
+	submit("Private Artist", base.Add(4*time.Hour), true)
+
+	count, err := store.Count(`SELECT COUNT(*) FROM all_listens WHERE private = 1`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+
+	// global charts and counts only include the public listens
+	allTime := db.GetItemsOpts{Limit: 10, Page: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}}
+	artists, err := store.GetTopArtistsPaginated(ctx, allTime)
+	require.NoError(t, err)
+	require.Len(t, artists.Items, 1)
+	assert.Equal(t, "Public Artist", artists.Items[0].Item.Name)
+	tracks, err := store.GetTopTracksPaginated(ctx, allTime)
+	require.NoError(t, err)
+	require.Len(t, tracks.Items, 1)
+	listens, err := store.CountListens(ctx, db.CountOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, listens)
+
+	// the owner's personal stats still count them
+	top, err := store.GetUserTopArtists(ctx, db.GetUserTopItemsOpts{UserID: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}})
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, "Private Artist", top[0].Name)
+	assert.EqualValues(t, 3, top[0].Listens)
+
+	// making them shared again returns them to the charts
+	n, err := store.SetListensPrivate(ctx, db.SetListensPrivateOpts{UserID: 1, From: base.Add(3 * time.Hour), To: base.Add(4 * time.Hour)})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	artists, err = store.GetTopArtistsPaginated(ctx, allTime)
+	require.NoError(t, err)
+	require.Len(t, artists.Items, 2)
+	assert.Equal(t, "Private Artist", artists.Items[1].Item.Name)
+	assert.EqualValues(t, 2, artists.Items[1].Item.ListenCount)
+}
+
+func TestSubmitListen_IgnoreLeadingThe(t *testing.T) {
+	ctx := context.Background()
+	defer cfg.SetIgnoreLeadingThe(false)
+
+	tests := []struct {
+		enabled         bool
+		first, second   string
+		expectedArtists int
+	}{
+		{true, "The Strokes", "Strokes", 1},
+		{true, "Strokes", "The Strokes", 1},
+		{false, "The Strokes", "Strokes", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.first+" then "+tt.second, func(t *testing.T) {
+			store := newTestDB()
+			cfg.SetIgnoreLeadingThe(tt.enabled)
+
+			for i, artist := range []string{tt.first, tt.second} {
+				err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+					MbzCaller:    &mbz.MbzMockCaller{},
+					Artist:       artist,
+					TrackTitle:   "Last Nite",
+					ReleaseTitle: "Is This It",
+					Time:         time.Date(2024, 4, 1, 12, i, 0, 0, time.UTC),
+					UserID:       1,
+				})
+				require.NoError(t, err)
+			}
+
+			count, err := store.Count(`SELECT COUNT(*) FROM artists`)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedArtists, count)
+
+			// the artist keeps the name it was first submitted with
+			a, err := store.GetArtist(ctx, db.GetArtistOpts{Name: tt.first})
+			require.NoError(t, err)
+			assert.Equal(t, tt.first, a.Name)
+		})
+	}
+}
+
+func TestSubmitListen_ResolveCompilationArtists(t *testing.T) {
+	ctx := context.Background()
+	defer cfg.SetResolveCompilationArtists(false)
+
+	mbzc := &mbz.MbzMockCaller{
+		RecordingArtists: map[string]map[string][]mbz.MusicBrainzArtistCredit{
+			"Greatest Hits of the 70s": {
+				"Bohemian Rhapsody": {{Name: "Queen", Artist: mbz.MusicBrainzArtist{ID: "00000000-0000-0000-0000-000000000811", Name: "Queen"}}},
+				"Dancing Queen":     {{Name: "ABBA", Artist: mbz.MusicBrainzArtist{ID: "00000000-0000-0000-0000-000000000812", Name: "ABBA"}}},
+			},
+		},
+	}
+	submit := func(t *testing.T, store *sqlite.Sqlite, release string) {
+		for i, title := range []string{"Bohemian Rhapsody", "Dancing Queen", "Not On MusicBrainz"} {
+			err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+				MbzCaller:    mbzc,
+				Artist:       "Various Artists",
+				TrackTitle:   title,
+				ReleaseTitle: release,
+				Time:         time.Date(2024, 5, 1, 12, i, 0, 0, time.UTC),
+				UserID:       1,
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		store := newTestDB()
+		cfg.SetResolveCompilationArtists(false)
+		submit(t, store, "Greatest Hits of the 70s")
+
+		va, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Various Artists"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, va.ListenCount)
+		_, err = store.GetArtist(ctx, db.GetArtistOpts{Name: "Queen"})
+		assert.ErrorIs(t, err, db.ErrNotFound)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		store := newTestDB()
+		cfg.SetResolveCompilationArtists(true)
+		submit(t, store, "Greatest Hits of the 70s")
+
+		for _, name := range []string{"Queen", "ABBA"} {
+			a, err := store.GetArtist(ctx, db.GetArtistOpts{Name: name})
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, a.ListenCount, name)
+			require.NotNil(t, a.MbzID)
+		}
+		// tracks that can not be found on MusicBrainz stay with Various Artists
+		va, err := store.GetArtist(ctx, db.GetArtistOpts{Name: "Various Artists"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, va.ListenCount)
+
+		// all tracks are on the same compilation album
+		count, err := store.Count(`SELECT COUNT(*) FROM releases WHERE various_artists = 1`)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		count, err = store.Count(`SELECT COUNT(*) FROM releases`)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestSubmitListen_AlbumLabels(t *testing.T) {
+	defer cfg.SetFetchAlbumLabels(false)
+	ctx := context.Background()
+	releaseMbzID := uuid.MustParse("00000000-0000-0000-0000-000000000201")
+	mbzc := &mbz.MbzMockCaller{
+		Releases: map[uuid.UUID]*mbz.MusicBrainzRelease{
+			releaseMbzID: {
+				Title:        "Kind of Blue",
+				ID:           releaseMbzID.String(),
+				ArtistCredit: []mbz.MusicBrainzArtistCredit{{Name: "Miles Davis"}},
+				LabelInfo: []mbz.MusicBrainzLabelInfo{
+					{CatalogNumber: "CL 1355", Label: &mbz.MusicBrainzLabel{ID: "011d1192-6f65-45bd-85c4-0400dd45693e", Name: "Columbia"}},
+					{CatalogNumber: "CS 8163", Label: &mbz.MusicBrainzLabel{Name: "Columbia"}},
+					{CatalogNumber: "XYZ 1"}, // unknown label
+					{Label: &mbz.MusicBrainzLabel{Name: "Sony"}},
+				},
+			},
+		},
+	}
+	submit := func(store *sqlite.Sqlite) *models.Album {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    mbzc,
+			Artist:       "Miles Davis",
+			TrackTitle:   "So What",
+			ReleaseTitle: "Kind of Blue",
+			ReleaseMbzID: releaseMbzID,
+			Time:         time.Now(),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+		album, err := store.GetAlbum(ctx, db.GetAlbumOpts{MusicBrainzID: releaseMbzID})
+		require.NoError(t, err)
+		return album
+	}
+
+	// labels are only fetched when enabled
+	cfg.SetFetchAlbumLabels(false)
+	album := submit(newTestDB())
+	assert.Empty(t, album.Labels)
+
+	cfg.SetFetchAlbumLabels(true)
+	store := newTestDB()
+	album = submit(store)
+	require.Len(t, album.Labels, 3)
+	assert.Equal(t, "Columbia", album.Labels[0].Name)
+	assert.Equal(t, "CL 1355", album.Labels[0].CatalogNumber)
+	require.NotNil(t, album.Labels[0].MbzID)
+	assert.Equal(t, "CS 8163", album.Labels[1].CatalogNumber)
+	assert.Nil(t, album.Labels[1].MbzID)
+	assert.Equal(t, "Sony", album.Labels[2].Name)
+
+	// top albums can be filtered by any of the album's labels
+	for label, want := range map[string]int{"columbia": 1, "Sony": 1, "Blue Note": 0, "": 1} {
+		top, err := store.GetTopAlbumsPaginated(ctx, db.GetItemsOpts{Timeframe: db.Timeframe{Period: db.PeriodAllTime}, Page: 1, Label: label})
+		require.NoError(t, err)
+		assert.Len(t, top.Items, want, "label %q", label)
+	}
+}
+
+func TestSubmitListen_ExactDuplicateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	opts := catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzMockCaller{},
+		Artist:       "Kendrick Lamar",
+		TrackTitle:   "Alright",
+		ReleaseTitle: "To Pimp a Butterfly",
+		Time:         time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		UserID:       1,
+	}
+	require.NoError(t, catalog.SubmitListen(ctx, store, opts))
+
+	second, err := store.SaveUser(ctx, db.SaveUserOpts{Username: "second", Password: "password123"})
+	require.NoError(t, err)
+
+	// two users' clients retrying the same submission many times at once
+	opts.Time = opts.Time.Add(time.Hour)
+	otherOpts := opts
+	otherOpts.UserID = second.ID
+	errs := make(chan error, 20)
+	for range 10 {
+		go func() { errs <- catalog.SubmitListen(ctx, store, opts) }()
+		go func() { errs <- catalog.SubmitListen(ctx, store, otherOpts) }()
+	}
+	for range 20 {
+		require.NoError(t, <-errs)
+	}
+	count, err := store.Count(`SELECT COUNT(*) FROM all_listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	// both users keep their listen to the track at that second
+	for _, userID := range []int32{1, second.ID} {
+		count, err = store.Count(`SELECT COUNT(*) FROM all_listens WHERE user_id = ? AND listened_at = ?`, userID, opts.Time.Unix())
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "user %d", userID)
+	}
+
+	// saving the same listen again reports that it is not new
+	listens, err := store.GetListens(ctx, db.GetListensOpts{})
+	require.NoError(t, err)
+	require.NotEmpty(t, listens.Items)
+	saved, err := store.SaveListen(ctx, db.SaveListenOpts{TrackID: listens.Items[0].Track.ID, Time: opts.Time, UserID: 1})
+	require.NoError(t, err)
+	assert.False(t, saved)
+	saved, err = store.SaveListen(ctx, db.SaveListenOpts{TrackID: listens.Items[0].Track.ID, Time: opts.Time.Add(time.Second), UserID: 1})
+	require.NoError(t, err)
+	assert.True(t, saved)
+}
+
+func TestSubmitListenWithResult_Dedupe(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	start := time.Date(2025, time.April, 1, 12, 0, 0, 0, time.UTC)
+	opts := catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzErrorCaller{},
+		Artist:       "Dedupe Artist",
+		TrackTitle:   "Dedupe Track",
+		ReleaseTitle: "Dedupe Album",
+		Time:         start,
+		UserID:       1,
+		DedupeWindow: 30 * time.Second,
+	}
+
+	result, err := catalog.SubmitListenWithResult(ctx, store, opts)
+	require.NoError(t, err)
+	assert.Equal(t, catalog.SubmitListenResult{Inserted: true}, result)
+
+	// a retry at the same time or a few seconds later is a duplicate
+	for _, offset := range []time.Duration{0, 10 * time.Second, -10 * time.Second} {
+		opts.Time = start.Add(offset)
+		result, err = catalog.SubmitListenWithResult(ctx, store, opts)
+		require.NoError(t, err)
+		assert.Equal(t, catalog.SubmitListenResult{Deduped: true}, result, offset)
+	}
+	// a play after the window is not
+	opts.Time = start.Add(time.Minute)
+	result, err = catalog.SubmitListenWithResult(ctx, store, opts)
+	require.NoError(t, err)
+	assert.True(t, result.Inserted)
+
+	count, err := store.Count(`SELECT COUNT(*) FROM listens`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestSubmitListenWithResult_DedupeKey(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	opts := catalog.SubmitListenOpts{
+		MbzCaller:    &mbz.MbzErrorCaller{},
+		Artist:       "Dedupe Artist",
+		TrackTitle:   "Keyed Track",
+		ReleaseTitle: "Dedupe Album",
+		Time:         time.Date(2025, time.April, 2, 12, 0, 0, 0, time.UTC),
+		UserID:       1,
+		DedupeKey:    "submission-1",
+		DedupeWindow: time.Minute,
+	}
+	result, err := catalog.SubmitListenWithResult(ctx, store, opts)
+	require.NoError(t, err)
+	assert.True(t, result.Inserted)
+
+	// a retry with the same key is a duplicate, even when the client reports another time
+	opts.Time = opts.Time.Add(time.Hour)
+	result, err = catalog.SubmitListenWithResult(ctx, store, opts)
+	require.NoError(t, err)
+	assert.Equal(t, catalog.SubmitListenResult{Deduped: true}, result)
+
+	// another key is not
+	opts.DedupeKey = "submission-2"
+	result, err = catalog.SubmitListenWithResult(ctx, store, opts)
+	require.NoError(t, err)
+	assert.True(t, result.Inserted)
+}
@@ -0,0 +1,61 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// TagTopItems is a chart of the user's listening scoped to a tag, along with the total number of
+// listens to tracks with the tag.
+type TagTopItems struct {
+	Tag     string           `json:"tag"`
+	Listens int64            `json:"listens"`
+	Items   []db.UserTopItem `json:"items"`
+}
+
+// GetTopArtistsByTag returns the user's most listened to artists with the tag within the timeframe.
+func GetTopArtistsByTag(ctx context.Context, store db.TagStore, userID int32, tag string, timeframe db.Timeframe) (*TagTopItems, error) {
+	ret, err := getTagTopItems(ctx, store, userID, tag, timeframe, store.GetUserTopArtistsByTag)
+	if err != nil {
+		return nil, fmt.Errorf("GetTopArtistsByTag: %w", err)
+	}
+	return ret, nil
+}
+
+// GetTopTracksByTag returns the user's most listened to tracks within the timeframe that have the
+// tag, either themselves or through one of their artists.
+func GetTopTracksByTag(ctx context.Context, store db.TagStore, userID int32, tag string, timeframe db.Timeframe) (*TagTopItems, error) {
+	ret, err := getTagTopItems(ctx, store, userID, tag, timeframe, store.GetUserTopTracksByTag)
+	if err != nil {
+		return nil, fmt.Errorf("GetTopTracksByTag: %w", err)
+	}
+	return ret, nil
+}
+
+func getTagTopItems(
+	ctx context.Context,
+	store db.TagStore,
+	userID int32,
+	tag string,
+	timeframe db.Timeframe,
+	top func(context.Context, db.GetUserTagItemsOpts) ([]db.UserTopItem, error),
+) (*TagTopItems, error) {
+	tag = strings.TrimSpace(tag)
+	if userID == 0 || tag == "" {
+		return nil, errors.New("user id and tag are required")
+	}
+	opts := db.GetUserTagItemsOpts{UserID: userID, Tag: tag, Timeframe: timeframe}
+	items, err := top(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	listens, err := store.CountUserListensWithTag(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TagTopItems{Tag: tag, Listens: listens, Items: items}, nil
+}
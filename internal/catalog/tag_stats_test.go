@@ -0,0 +1,89 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTopItemsByTag(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	listens := []struct {
+		artist, track string
+		n             int
+	}{
+		{"Miles Davis", "So What", 3},
+		{"Miles Davis", "Blue in Green", 1},
+		{"John Coltrane", "Naima", 2},
+		{"Daft Punk", "Around the World", 5},
+		{"Daft Punk", "Digital Love", 1},
+	}
+	i := 0
+	for _, l := range listens {
+		for range l.n {
+			require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+				MbzCaller:  &mbz.MbzMockCaller{},
+				Artist:     l.artist,
+				TrackTitle: l.track,
+				Time:       base.Add(time.Duration(i) * time.Hour),
+				UserID:     1,
+			}))
+			i++
+		}
+	}
+	artistID := func(artist string) int32 {
+		a, err := store.GetArtist(ctx, db.GetArtistOpts{Name: artist})
+		require.NoError(t, err)
+		return a.ID
+	}
+	require.NoError(t, store.SaveArtistTags(ctx, artistID("Miles Davis"), []string{"Jazz"}))
+	require.NoError(t, store.SaveArtistTags(ctx, artistID("John Coltrane"), []string{"jazz", "Bebop"}))
+	// a single track can be tagged without its artist
+	allTime := db.Timeframe{Period: db.PeriodAllTime}
+	top, err := store.GetUserTopTracks(ctx, db.GetUserTopItemsOpts{UserID: 1, Timeframe: allTime})
+	require.NoError(t, err)
+	for _, track := range top {
+		if track.Name == "Digital Love" {
+			require.NoError(t, store.SaveTrackTags(ctx, track.ID, []string{"Jazz"}))
+		}
+	}
+	artists, err := catalog.GetTopArtistsByTag(ctx, store, 1, "JAZZ", allTime)
+	require.NoError(t, err)
+	require.Len(t, artists.Items, 2)
+	assert.Equal(t, "Miles Davis", artists.Items[0].Name)
+	assert.EqualValues(t, 4, artists.Items[0].Listens)
+	assert.Equal(t, "John Coltrane", artists.Items[1].Name)
+	assert.EqualValues(t, 7, artists.Listens)
+
+	tracks, err := catalog.GetTopTracksByTag(ctx, store, 1, "jazz", allTime)
+	require.NoError(t, err)
+	require.Len(t, tracks.Items, 4)
+	assert.Equal(t, "So What", tracks.Items[0].Name)
+	assert.Equal(t, "Naima", tracks.Items[1].Name)
+	assert.EqualValues(t, 7, tracks.Listens)
+
+	// the timeframe scopes both the chart and the total
+	tracks, err = catalog.GetTopTracksByTag(ctx, store, 1, "Jazz", db.Timeframe{
+		FromUnix: base.Unix(),
+		ToUnix:   base.Add(2 * time.Hour).Unix(),
+	})
+	require.NoError(t, err)
+	require.Len(t, tracks.Items, 1)
+	assert.EqualValues(t, 3, tracks.Listens)
+
+	bebop, err := catalog.GetTopTracksByTag(ctx, store, 1, "Bebop", allTime)
+	require.NoError(t, err)
+	require.Len(t, bebop.Items, 1)
+	assert.Equal(t, "Naima", bebop.Items[0].Name)
+
+	_, err = catalog.GetTopArtistsByTag(ctx, store, 1, " ", allTime)
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopCharts_Decay(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	now := time.Now()
+
+	listens := []struct {
+		artist, track, release string
+		at                     time.Time
+	}{
+		{"Old Favorite", "Old Song", "Old Album", now.AddDate(-1, 0, 0)},
+		{"Old Favorite", "Old Song", "Old Album", now.AddDate(-1, 0, 1)},
+		{"Old Favorite", "Old Song", "Old Album", now.AddDate(-1, 0, 2)},
+		{"New Favorite", "New Song", "New Album", now.AddDate(0, 0, -2)},
+		{"New Favorite", "New Song", "New Album", now.AddDate(0, 0, -1)},
+	}
+	for _, l := range listens {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       l.artist,
+			TrackTitle:   l.track,
+			ReleaseTitle: l.release,
+			Time:         l.at,
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	flat := db.GetItemsOpts{Limit: 10, Page: 1, Timeframe: db.Timeframe{Period: db.PeriodAllTime}}
+	decayed := flat
+	decayed.DecayHalfLifeDays = 30
+
+	artists, err := store.GetTopArtistsPaginated(ctx, flat)
+	require.NoError(t, err)
+	require.Len(t, artists.Items, 2)
+	assert.Equal(t, "Old Favorite", artists.Items[0].Item.Name)
+	artists, err = store.GetTopArtistsPaginated(ctx, decayed)
+	require.NoError(t, err)
+	require.Len(t, artists.Items, 2)
+	assert.Equal(t, "New Favorite", artists.Items[0].Item.Name)
+	assert.EqualValues(t, 1, artists.Items[0].Rank)
+	// listen counts are still reported unweighted
+	assert.EqualValues(t, 2, artists.Items[0].Item.ListenCount)
+	assert.EqualValues(t, 3, artists.Items[1].Item.ListenCount)
+
+	albums, err := store.GetTopAlbumsPaginated(ctx, flat)
+	require.NoError(t, err)
+	require.Len(t, albums.Items, 2)
+	assert.Equal(t, "Old Album", albums.Items[0].Item.Title)
+	albums, err = store.GetTopAlbumsPaginated(ctx, decayed)
+	require.NoError(t, err)
+	require.Len(t, albums.Items, 2)
+	assert.Equal(t, "New Album", albums.Items[0].Item.Title)
+
+	tracks, err := store.GetTopTracksPaginated(ctx, flat)
+	require.NoError(t, err)
+	require.Len(t, tracks.Items, 2)
+	assert.Equal(t, "Old Song", tracks.Items[0].Item.Title)
+	tracks, err = store.GetTopTracksPaginated(ctx, decayed)
+	require.NoError(t, err)
+	require.Len(t, tracks.Items, 2)
+	assert.Equal(t, "New Song", tracks.Items[0].Item.Title)
+}
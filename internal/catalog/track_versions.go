@@ -0,0 +1,57 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// stripVersionSuffixes removes the configured version suffixes, e.g. " - 2011 Remaster" or
+// " (Radio Edit)", from the end of a track title until none are left. A title that would be
+// stripped to nothing, such as a song named "Live", is returned unchanged.
+func stripVersionSuffixes(title string) string {
+	base := title
+	for changed := true; changed; {
+		changed = false
+		for _, re := range cfg.TrackVersionSuffixes() {
+			if stripped := strings.TrimSpace(re.ReplaceAllString(base, "")); stripped != base && stripped != "" {
+				base = stripped
+				changed = true
+			}
+		}
+	}
+	return base
+}
+
+// getTrackByBaseTitle returns the track on the album by the same artists whose title is the same as
+// the given one once version suffixes are removed from both. The title is saved as an alias of the
+// track, which keeps its display name.
+func getTrackByBaseTitle(ctx context.Context, d db.TrackStore, opts AssociateTrackOpts) (*models.Track, error) {
+	if len(cfg.TrackVersionSuffixes()) == 0 {
+		return nil, fmt.Errorf("getTrackByBaseTitle: %w", db.ErrNotFound)
+	}
+	base := stripVersionSuffixes(opts.TrackName)
+	titles, err := d.GetTrackTitles(ctx, db.GetTrackTitlesOpts{ReleaseID: opts.AlbumID, ArtistIDs: opts.ArtistIDs})
+	if err != nil {
+		return nil, fmt.Errorf("getTrackByBaseTitle: %w", err)
+	}
+	for _, t := range titles {
+		if !strings.EqualFold(stripVersionSuffixes(t.Title), base) {
+			continue
+		}
+		track, err := d.GetTrack(ctx, db.GetTrackOpts{ID: t.TrackID})
+		if err != nil {
+			return nil, fmt.Errorf("getTrackByBaseTitle: %w", err)
+		}
+		if err := d.SaveTrackAliases(ctx, track.ID, []string{opts.TrackName}, models.AliasSourceListen); err != nil {
+			logger.FromContext(ctx).Warn().Err(err).Msgf("Failed to save '%s' as an alias of track %d", opts.TrackName, track.ID)
+		}
+		return track, nil
+	}
+	return nil, fmt.Errorf("getTrackByBaseTitle: %w", db.ErrNotFound)
+}
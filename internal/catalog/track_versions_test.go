@@ -0,0 +1,85 @@
+package catalog_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitListen_TrackVersionSuffixes(t *testing.T) {
+	ctx := context.Background()
+	defer cfg.SetTrackVersionSuffixes(nil)
+	cfg.SetTrackVersionSuffixes([]*regexp.Regexp{
+		regexp.MustCompile(`(?i)\s+-\s+(\d{4}\s+)?remaster(ed)?(\s+\d{4})?$`),
+		regexp.MustCompile(`(?i)\s+-\s+live$`),
+		regexp.MustCompile(`(?i)\s+\((radio edit|live)\)$`),
+	})
+	store := newTestDB()
+
+	submit := func(title string, i int) {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "The Beatles",
+			TrackTitle:   title,
+			ReleaseTitle: "Abbey Road",
+			Time:         time.Date(2024, 1, 1, 12, i, 0, 0, time.UTC),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+	for i, title := range []string{
+		"Something - 2009 Remaster",
+		"Something",
+		"Something - Live",
+		"Something (Radio Edit)",
+		"Something (Radio Edit) - Remastered 2019",
+		// stripping would leave nothing, so this is a song of its own
+		"Live",
+		"Live - Live",
+	} {
+		submit(title, i)
+	}
+
+	count, err := store.Count(`SELECT COUNT(*) FROM tracks`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// the track keeps the title it was first seen with
+	track, err := store.GetTrack(ctx, db.GetTrackOpts{Title: "Something - 2009 Remaster", ReleaseID: 1, ArtistIDs: []int32{1}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, track.ListenCount)
+	aliases, err := store.GetAllTrackAliases(ctx, track.ID)
+	require.NoError(t, err)
+	assert.Len(t, aliases, 5)
+
+	track, err = store.GetTrack(ctx, db.GetTrackOpts{Title: "Live", ReleaseID: 1, ArtistIDs: []int32{1}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, track.ListenCount)
+}
+
+func TestSubmitListen_TrackVersionSuffixesDisabled(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDB()
+	for i, title := range []string{"Something - 2009 Remaster", "Something"} {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "The Beatles",
+			TrackTitle:   title,
+			ReleaseTitle: "Abbey Road",
+			Time:         time.Date(2024, 1, 1, 12, i, 0, 0, time.UTC),
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+	count, err := store.Count(`SELECT COUNT(*) FROM tracks`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
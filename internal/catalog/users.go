@@ -0,0 +1,48 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// DefaultUserID is the user listens are saved for when no user is authenticated, such as for the
+// imports of the files in the import directory, which keeps single user instances working as before.
+const DefaultUserID int32 = 1
+
+// CreateUser saves a new user along with a first API key, which the user's scrobblers submit listens
+// with, labelled with the given label. The returned key is the only time the key itself is known.
+func CreateUser(ctx context.Context, store db.UserStore, opts db.SaveUserOpts, keyLabel string) (*models.User, *models.ApiKey, error) {
+	l := logger.FromContext(ctx)
+	user, err := store.SaveUser(ctx, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CreateUser: %w", err)
+	}
+	apiKey, err := CreateApiKey(ctx, store, user.ID, keyLabel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CreateUser: %w", err)
+	}
+	l.Info().Msgf("CreateUser: Created user '%s'", user.Username)
+	return user, apiKey, nil
+}
+
+// GetUserByAPIKey returns the user the API key belongs to, or db.ErrNotFound when it does not belong to
+// any user.
+func GetUserByAPIKey(ctx context.Context, store db.UserStore, key string) (*models.User, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, fmt.Errorf("GetUserByAPIKey: %w", db.ErrNotFound)
+	}
+	user, err := store.GetUserByApiKey(ctx, HashApiKey(key))
+	if err != nil {
+		return nil, fmt.Errorf("GetUserByAPIKey: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("GetUserByAPIKey: %w", db.ErrNotFound)
+	}
+	return user, nil
+}
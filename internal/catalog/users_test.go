@@ -0,0 +1,57 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUser(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+
+	user, key, err := catalog.CreateUser(ctx, store, db.SaveUserOpts{
+		Username: "Second",
+		Password: "password",
+		Role:     models.UserRoleUser,
+	}, "Scrobbler")
+	require.NoError(t, err)
+	assert.Equal(t, "second", user.Username)
+	assert.Equal(t, "Scrobbler", key.Label)
+	assert.NotEmpty(t, key.Key)
+
+	// the scrobble token of the new user maps to that user
+	found, err := catalog.GetUserByAPIKey(ctx, store, key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, err = catalog.GetUserByAPIKey(ctx, store, "not a key")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+	_, err = catalog.GetUserByAPIKey(ctx, store, " ")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// listens are saved for the user they are submitted as
+	for i, userID := range []int32{catalog.DefaultUserID, user.ID, user.ID} {
+		require.NoError(t, catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       "Artist",
+			TrackTitle:   "Track",
+			ReleaseTitle: "Release",
+			Time:         time.Date(2024, 1, 1, 12, i, 0, 0, time.UTC),
+			UserID:       userID,
+		}))
+	}
+	count, err := store.Count(`SELECT COUNT(*) FROM listens WHERE user_id = 1`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = store.Count(`SELECT COUNT(*) FROM listens WHERE user_id = ?`, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
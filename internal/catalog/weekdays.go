@@ -0,0 +1,46 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// WeekdayListenCount is the number of listens on one day of the week. Weekday is the day's
+// index starting from Sunday at 0, matching JavaScript's Date.getDay, so clients can reorder
+// the days to match the first day of the week in their locale.
+type WeekdayListenCount struct {
+	Weekday int    `json:"weekday"`
+	Name    string `json:"name"`
+	Listens int64  `json:"listens"`
+}
+
+// GetListenCountsByWeekday returns the user's listen counts within the timeframe for each day of
+// the week, from Monday to Sunday, with days determined in the given timezone. When userID is 0,
+// listens from all users are counted. An empty timeframe defaults to all time.
+func GetListenCountsByWeekday(ctx context.Context, store db.ListenStore, userID int32, tf db.Timeframe, loc *time.Location) ([]WeekdayListenCount, error) {
+	if _, t2 := db.TimeframeToTimeRange(tf); t2.IsZero() {
+		tf.Period = db.PeriodAllTime
+	}
+	counts, err := store.GetListenCountsByWeekday(ctx, db.GetListenCountsByWeekdayOpts{
+		UserID:    userID,
+		Timeframe: tf,
+		Timezone:  loc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetListenCountsByWeekday: %w", err)
+	}
+
+	ret := make([]WeekdayListenCount, 0, 7)
+	for i := range 7 {
+		day := (time.Monday + time.Weekday(i)) % 7
+		ret = append(ret, WeekdayListenCount{
+			Weekday: int(day),
+			Name:    day.String(),
+			Listens: counts[day],
+		})
+	}
+	return ret, nil
+}
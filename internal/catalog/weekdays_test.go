@@ -0,0 +1,53 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetListenCountsByWeekday(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	// a Monday, so the six seeded listens fall on Monday through Saturday
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	seedGetListens(t, store, base)
+
+	counts, err := catalog.GetListenCountsByWeekday(ctx, store, 1, db.Timeframe{}, time.UTC)
+	require.NoError(t, err)
+	require.Len(t, counts, 7)
+	assert.Equal(t, int(time.Monday), counts[0].Weekday)
+	assert.Equal(t, "Monday", counts[0].Name)
+	assert.Equal(t, int(time.Sunday), counts[6].Weekday)
+	assert.Equal(t, []int64{1, 1, 1, 1, 1, 1, 0}, listensOf(counts))
+
+	// noon UTC is already the next day at UTC+14
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati")
+	require.NoError(t, err)
+	counts, err = catalog.GetListenCountsByWeekday(ctx, store, 1, db.Timeframe{}, kiritimati)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 1, 1, 1, 1, 1, 1}, listensOf(counts))
+
+	// only Wednesday and Thursday
+	tf := db.Timeframe{From: base.Add(48 * time.Hour), To: base.Add(72 * time.Hour)}
+	counts, err = catalog.GetListenCountsByWeekday(ctx, store, 1, tf, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 0, 1, 1, 0, 0, 0}, listensOf(counts))
+
+	counts, err = catalog.GetListenCountsByWeekday(ctx, store, 2, db.Timeframe{}, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 0, 0, 0, 0, 0, 0}, listensOf(counts))
+}
+
+func listensOf(counts []catalog.WeekdayListenCount) []int64 {
+	ret := make([]int64, len(counts))
+	for i, c := range counts {
+		ret[i] = c.Listens
+	}
+	return ret
+}
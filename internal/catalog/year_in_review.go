@@ -0,0 +1,108 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/memkv"
+)
+
+// number of top artists, albums, tracks and new artists included in a year in review
+const yearInReviewChartSize = 10
+
+// how long the year in review of a past year is cached. Listens imported later can still
+// change a past year, so the snapshot is not kept forever.
+const yearInReviewCacheTTL = 24 * time.Hour
+
+type YearInReview struct {
+	Year            int              `json:"year"`
+	TopArtists      []db.UserTopItem `json:"top_artists"`
+	TopAlbums       []db.UserTopItem `json:"top_albums"`
+	TopTracks       []db.UserTopItem `json:"top_tracks"`
+	Listens         int64            `json:"listens"`
+	MinutesListened int64            `json:"minutes_listened"`
+	// nil when there are no listens in the year
+	TopDay *TopListeningDay `json:"top_day"`
+	// the number of artists first listened to during the year, and the most listened to of them
+	NewArtistCount int              `json:"new_artist_count"`
+	NewArtists     []db.UserTopItem `json:"new_artists"`
+}
+
+type TopListeningDay struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Listens int64  `json:"listens"`
+}
+
+type yearInReviewStore interface {
+	db.ArtistStore
+	db.AlbumStore
+	db.TrackStore
+	db.ListenStore
+}
+
+// GenerateYearInReview returns a snapshot of the user's listening during the calendar year in the
+// given timezone. Snapshots of years that have ended are cached, as they rarely change.
+func GenerateYearInReview(ctx context.Context, store yearInReviewStore, userID int32, year int, loc *time.Location) (*YearInReview, error) {
+	if userID == 0 {
+		return nil, errors.New("GenerateYearInReview: user id is required")
+	}
+	if year < 1970 || year > time.Now().Year()+1 {
+		return nil, fmt.Errorf("GenerateYearInReview: invalid year %d", year)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	complete := year < time.Now().In(loc).Year()
+	key := fmt.Sprintf("year_in_review_%d_%d_%s", userID, year, loc.String())
+	if complete {
+		if cached, ok := memkv.Store.Get(key); ok {
+			if ret, ok := cached.(*YearInReview); ok {
+				return ret, nil
+			}
+		}
+	}
+
+	tf := db.Timeframe{Year: year, Timezone: loc}
+	opts := db.GetUserTopItemsOpts{UserID: userID, Timeframe: tf, Limit: yearInReviewChartSize}
+	ret := &YearInReview{Year: year}
+	var err error
+
+	if ret.TopArtists, err = store.GetUserTopArtists(ctx, opts); err != nil {
+		return nil, fmt.Errorf("GenerateYearInReview: %w", err)
+	}
+	if ret.TopAlbums, err = store.GetUserTopAlbums(ctx, opts); err != nil {
+		return nil, fmt.Errorf("GenerateYearInReview: %w", err)
+	}
+	if ret.TopTracks, err = store.GetUserTopTracks(ctx, opts); err != nil {
+		return nil, fmt.Errorf("GenerateYearInReview: %w", err)
+	}
+
+	totals, err := store.GetUserListenTotals(ctx, db.GetUserListenTotalsOpts{UserID: userID, Timeframe: tf})
+	if err != nil {
+		return nil, fmt.Errorf("GenerateYearInReview: %w", err)
+	}
+	ret.Listens = totals.Listens
+	ret.MinutesListened = totals.SecondsListened / 60
+
+	days, err := store.GetListenCountsByDay(ctx, db.GetListenCountsByDayOpts{UserID: userID, Timeframe: tf, Timezone: loc})
+	if err != nil {
+		return nil, fmt.Errorf("GenerateYearInReview: %w", err)
+	}
+	ret.TopDay, _ = topListeningDay(days)
+
+	newArtists, err := store.GetUserNewArtists(ctx, db.GetUserTopItemsOpts{UserID: userID, Timeframe: tf, Limit: -1})
+	if err != nil {
+		return nil, fmt.Errorf("GenerateYearInReview: %w", err)
+	}
+	ret.NewArtistCount = len(newArtists)
+	ret.NewArtists = newArtists[:min(len(newArtists), yearInReviewChartSize)]
+
+	if complete {
+		memkv.Store.Set(key, ret, yearInReviewCacheTTL)
+	}
+	return ret, nil
+}
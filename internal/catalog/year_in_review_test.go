@@ -0,0 +1,73 @@
+package catalog_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateYearInReview(t *testing.T) {
+	store := newTestDB()
+	ctx := context.Background()
+	submit := func(artist, track string, ts time.Time) {
+		err := catalog.SubmitListen(ctx, store, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzMockCaller{},
+			Artist:       artist,
+			TrackTitle:   track,
+			ReleaseTitle: "Release Z",
+			Duration:     240,
+			Time:         ts,
+			UserID:       1,
+		})
+		require.NoError(t, err)
+	}
+
+	// Artist B was discovered the year before
+	submit("Artist B", "Track 4", time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC))
+	seedGetListens(t, store, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	submit("Artist C", "Track 5", time.Date(2024, 1, 3, 18, 0, 0, 0, time.UTC))
+
+	review, err := catalog.GenerateYearInReview(ctx, store, 1, 2024, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, 2024, review.Year)
+	assert.EqualValues(t, 7, review.Listens)
+	// only the listens of Track 4 and Track 5 have a duration
+	assert.EqualValues(t, 12, review.MinutesListened)
+
+	require.Len(t, review.TopArtists, 3)
+	assert.Equal(t, "Artist A", review.TopArtists[0].Name)
+	assert.EqualValues(t, 4, review.TopArtists[0].Listens)
+	require.NotEmpty(t, review.TopAlbums)
+	assert.Equal(t, "Release X", review.TopAlbums[0].Name)
+	require.NotEmpty(t, review.TopTracks)
+	assert.EqualValues(t, 2, review.TopTracks[0].Listens)
+
+	require.NotNil(t, review.TopDay)
+	assert.Equal(t, "2024-01-03", review.TopDay.Date)
+	assert.EqualValues(t, 2, review.TopDay.Listens)
+
+	assert.Equal(t, 2, review.NewArtistCount)
+	require.Len(t, review.NewArtists, 2)
+	assert.Equal(t, "Artist A", review.NewArtists[0].Name)
+	assert.Equal(t, "Artist C", review.NewArtists[1].Name)
+
+	// past years are served from the cache
+	submit("Artist C", "Track 5", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	cached, err := catalog.GenerateYearInReview(ctx, store, 1, 2024, time.UTC)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, cached.Listens)
+
+	empty, err := catalog.GenerateYearInReview(ctx, store, 1, 2022, time.UTC)
+	require.NoError(t, err)
+	assert.Zero(t, empty.Listens)
+	assert.Nil(t, empty.TopDay)
+	assert.Empty(t, empty.TopArtists)
+
+	_, err = catalog.GenerateYearInReview(ctx, store, 0, 2024, time.UTC)
+	assert.Error(t, err)
+}
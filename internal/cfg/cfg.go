@@ -3,6 +3,7 @@ package cfg
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,8 +14,30 @@ const (
 	// defaultBaseUrl        = "http://127.0.0.1"
 	defaultListenPort     = 4110
 	defaultMusicBrainzUrl = "https://musicbrainz.org"
+	defaultSessionGapMins = 30
+	defaultDecayHalfLife  = 30
+	defaultImageWorkers   = 4
+	defaultSoftDeleteDays = 30
+	defaultFavoriteDays   = 3
+	defaultImportDedupe   = 5
+	defaultListenDedupe   = 30
+	// image provider requests time out after this many seconds unless configured otherwise
+	defaultImageProviderTimeout = 10
+	defaultProviderFailures     = 5
+	defaultProviderCooldown     = 300
+	defaultImagePositiveTTL     = 24 * 60 * 60
+	defaultImageNegativeTTL     = 60 * 60
 )
 
+// image providers, in the order they are tried by default
+var defaultImageProviderOrder = []string{"spotify", "subsonic", "caa", "lastfm", "deezer"}
+
+// DefaultImageProviderOrder returns the image providers in the order they are tried when
+// KOITO_IMAGE_PROVIDER_ORDER is not set.
+func DefaultImageProviderOrder() []string {
+	return slices.Clone(defaultImageProviderOrder)
+}
+
 const (
 	// BASE_URL_ENV                  = "KOITO_BASE_URL"
 	DATABASE_URL_ENV               = "KOITO_DATABASE_URL"
@@ -29,6 +52,8 @@ const (
 	ENABLE_LBZ_RELAY_ENV           = "KOITO_ENABLE_LBZ_RELAY"
 	LBZ_RELAY_URL_ENV              = "KOITO_LBZ_RELAY_URL"
 	LBZ_RELAY_TOKEN_ENV            = "KOITO_LBZ_RELAY_TOKEN"
+	LBZ_IMPORT_USER_ENV            = "KOITO_LBZ_IMPORT_USER"
+	LBZ_IMPORT_TOKEN_ENV           = "KOITO_LBZ_IMPORT_TOKEN"
 	CONFIG_DIR_ENV                 = "KOITO_CONFIG_DIR"
 	DEFAULT_USERNAME_ENV           = "KOITO_DEFAULT_USERNAME"
 	DEFAULT_PASSWORD_ENV           = "KOITO_DEFAULT_PASSWORD"
@@ -42,7 +67,10 @@ const (
 	SUBSONIC_URL_ENV               = "KOITO_SUBSONIC_URL"
 	SUBSONIC_PARAMS_ENV            = "KOITO_SUBSONIC_PARAMS"
 	LASTFM_API_KEY_ENV             = "KOITO_LASTFM_API_KEY"
+	LASTFM_IMPORT_USER_ENV         = "KOITO_LASTFM_IMPORT_USER"
+	LASTFM_SCROBBLE_SECRET_ENV     = "KOITO_LASTFM_SCROBBLE_SECRET"
 	SKIP_IMPORT_ENV                = "KOITO_SKIP_IMPORT"
+	FORCE_REIMPORT_ENV             = "KOITO_FORCE_REIMPORT"
 	ALLOWED_HOSTS_ENV              = "KOITO_ALLOWED_HOSTS"
 	CORS_ORIGINS_ENV               = "KOITO_CORS_ALLOWED_ORIGINS"
 	DISABLE_RATE_LIMIT_ENV         = "KOITO_DISABLE_RATE_LIMIT"
@@ -51,8 +79,43 @@ const (
 	IMPORT_AFTER_UNIX_ENV          = "KOITO_IMPORT_AFTER_UNIX"
 	FETCH_IMAGES_DURING_IMPORT_ENV = "KOITO_FETCH_IMAGES_DURING_IMPORT"
 	ARTIST_SEPARATORS_ENV          = "KOITO_ARTIST_SEPARATORS_REGEX"
+	TRACK_VERSION_SUFFIXES_ENV     = "KOITO_TRACK_VERSION_SUFFIXES_REGEX"
 	LOGIN_GATE_ENV                 = "KOITO_LOGIN_GATE"
 	FORCE_TZ                       = "KOITO_FORCE_TZ"
+	IMAGE_PROVIDER_ORDER_ENV       = "KOITO_IMAGE_PROVIDER_ORDER"
+	IMAGE_PROVIDER_TIMEOUTS_ENV    = "KOITO_IMAGE_PROVIDER_TIMEOUTS"
+	PROVIDER_FAILURE_THRESHOLD_ENV = "KOITO_IMAGE_PROVIDER_FAILURE_THRESHOLD"
+	PROVIDER_COOLDOWN_SECONDS_ENV  = "KOITO_IMAGE_PROVIDER_COOLDOWN_SECONDS"
+	IMAGE_POSITIVE_CACHE_TTL_ENV   = "KOITO_IMAGE_POSITIVE_CACHE_TTL_SECONDS"
+	IMAGE_NEGATIVE_CACHE_TTL_ENV   = "KOITO_IMAGE_NEGATIVE_CACHE_TTL_SECONDS"
+	LOCAL_IMAGES_DIR_ENV           = "KOITO_LOCAL_IMAGES_DIR"
+	SCROBBLE_QUIET_HOURS_ENV       = "KOITO_SCROBBLE_QUIET_HOURS"
+	ALBUM_MATCH_POLICY_ENV         = "KOITO_ALBUM_MATCH_POLICY"
+	USE_ARTIST_IMAGE_FALLBACK_ENV  = "KOITO_USE_ARTIST_IMAGE_FOR_MISSING_ALBUM"
+	SESSION_GAP_MINUTES_ENV        = "KOITO_SESSION_GAP_MINUTES"
+	CHART_DECAY_HALF_LIFE_DAYS_ENV = "KOITO_CHART_DECAY_HALF_LIFE_DAYS"
+	SINGLE_RELEASE_POLICY_ENV      = "KOITO_SINGLE_RELEASE_POLICY"
+	IMPORT_IGNORE_BELOW_MS_ENV     = "KOITO_IMPORT_IGNORE_BELOW_MS"
+	ARTIST_CASING_POLICY_ENV       = "KOITO_ARTIST_CASING_POLICY"
+	FAVORITE_MIN_DAYS_ENV          = "KOITO_FAVORITE_MIN_DAYS"
+	IMPORT_MERGE_GAP_SECONDS_ENV   = "KOITO_IMPORT_MERGE_GAP_SECONDS"
+	IMPORT_DEDUPE_WINDOW_ENV       = "KOITO_IMPORT_DEDUPE_WINDOW_SECONDS"
+	LISTEN_DEDUPE_WINDOW_ENV       = "KOITO_LISTEN_DEDUPE_WINDOW_SECONDS"
+	IMPORT_PARTIAL_PLAYS_ENV       = "KOITO_IMPORT_PARTIAL_PLAYS"
+	IMPORT_TIMEZONE_ENV            = "KOITO_IMPORT_TIMEZONE"
+	IMAGE_DOWNLOAD_WORKERS_ENV     = "KOITO_IMAGE_DOWNLOAD_WORKERS"
+	IMAGE_DOWNLOAD_RATE_LIMIT_ENV  = "KOITO_IMAGE_DOWNLOAD_RATE_LIMIT"
+	STRICT_ALBUM_IMAGE_MATCH_ENV   = "KOITO_STRICT_ALBUM_IMAGE_MATCH"
+	IGNORE_LEADING_THE_ENV         = "KOITO_IGNORE_LEADING_THE"
+	LAZY_IMAGE_FETCH_ENV           = "KOITO_LAZY_IMAGE_FETCH"
+	SOFT_DELETE_RETENTION_DAYS_ENV = "KOITO_SOFT_DELETE_RETENTION_DAYS"
+	FETCH_AUDIO_FEATURES_ENV       = "KOITO_FETCH_AUDIO_FEATURES"
+	FETCH_ALBUM_LABELS_ENV         = "KOITO_FETCH_ALBUM_LABELS"
+	FOLD_DIACRITICS_ENV            = "KOITO_FOLD_DIACRITICS_FOR_MATCHING"
+	MERGE_DUPLICATE_ALBUMS_ENV     = "KOITO_MERGE_DUPLICATE_ALBUMS"
+	RESOLVE_COMPILATIONS_ENV       = "KOITO_RESOLVE_COMPILATION_ARTISTS"
+	GENERATE_BLURHASH_ENV          = "KOITO_GENERATE_BLURHASH"
+	IMAGE_BACKFILL_INTERVAL_ENV    = "KOITO_IMAGE_BACKFILL_INTERVAL_MINUTES"
 )
 
 type config struct {
@@ -69,6 +132,8 @@ type config struct {
 	lbzRelayEnabled        bool
 	lbzRelayUrl            string
 	lbzRelayToken          string
+	lbzImportUser          string
+	lbzImportToken         string
 	defaultPw              string
 	defaultUsername        string
 	defaultTheme           string
@@ -81,20 +146,106 @@ type config struct {
 	subsonicUrl            string
 	subsonicParams         string
 	lastfmApiKey           string
+	lastfmImportUser       string
+	lastfmScrobbleSecret   string
 	subsonicEnabled        bool
 	skipImport             bool
+	forceReimport          bool
 	fetchImageDuringImport bool
 	allowedHosts           []string
 	allowAllHosts          bool
 	allowedOrigins         []string
 	disableRateLimit       bool
 	importThrottleMs       int
+	importIgnoreBelowMs    int
+	importMergeGapSeconds  int
+	importDedupeSeconds    int
+	listenDedupeSeconds    int
+	importPartialPlays     bool
+	importTZ               *time.Location
 	userAgent              string
 	importBefore           time.Time
 	importAfter            time.Time
 	artistSeparators       []*regexp.Regexp
+	trackVersionSuffixes   []*regexp.Regexp
 	loginGate              bool
 	forceTZ                *time.Location
+	imageProviderOrder     []string
+	imageProviderTimeouts  map[string]time.Duration
+	providerFailures       int
+	providerCooldown       time.Duration
+	imagePositiveCacheTTL  time.Duration
+	imageNegativeCacheTTL  time.Duration
+	localImagesDir         string
+	quietHours             *QuietHours
+	albumMatchPolicy       string
+	artistImageFallback    bool
+	strictAlbumImageMatch  bool
+	ignoreLeadingThe       bool
+	lazyImageFetch         bool
+	fetchAudioFeatures     bool
+	fetchAlbumLabels       bool
+	foldDiacritics         bool
+	mergeDuplicateAlbums   bool
+	resolveCompilations    bool
+	generateBlurHash       bool
+	sessionGapMinutes      int
+	chartDecayHalfLifeDays int
+	singleReleasePolicy    string
+	artistCasingPolicy     string
+	favoriteMinDays        int
+	imageDownloadWorkers   int
+	imageBackfillInterval  time.Duration
+	imageDownloadRateLimit int
+	softDeleteRetention    int
+}
+
+// Policies for choosing an album when a listen without release information
+// matches a track that already exists on one or more albums.
+const (
+	// AlbumMatchPolicyNone resolves the album from the listen alone.
+	AlbumMatchPolicyNone = ""
+	// AlbumMatchPolicyMostPlayed attaches the listen to the album the user has played the track from most.
+	AlbumMatchPolicyMostPlayed = "most_played"
+	// AlbumMatchPolicyFirstAdded attaches the listen to the album that was added to Koito first, which
+	// is not necessarily the album that was released first, as release dates are not stored.
+	AlbumMatchPolicyFirstAdded = "first_added"
+)
+
+// Policies for albums whose only track shares the album's title.
+const (
+	// SingleReleasePolicyNone treats singles like any other album.
+	SingleReleasePolicyNone = ""
+	// SingleReleasePolicyTag marks singles so clients can tell them apart from full albums.
+	SingleReleasePolicyTag = "tag"
+	// SingleReleasePolicyCollapse marks singles and leaves them out of album charts, so only the track is shown.
+	SingleReleasePolicyCollapse = "collapse"
+)
+
+// Policies for choosing which casing of an artist's name is displayed.
+const (
+	// ArtistCasingPolicyNone keeps the casing the artist was created with.
+	ArtistCasingPolicyNone = ""
+	// ArtistCasingPolicyMusicBrainz uses the casing from MusicBrainz when the artist is resolved, otherwise the most seen.
+	ArtistCasingPolicyMusicBrainz = "musicbrainz"
+	// ArtistCasingPolicyFrequent uses the casing seen most often in listens.
+	ArtistCasingPolicyFrequent = "frequent"
+)
+
+// QuietHours is a daily range of local time during which live scrobbles are ignored.
+// Start and End are offsets from midnight. The range wraps past midnight when End is before Start.
+type QuietHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains reports whether the local time of day of t falls within the quiet hours.
+func (q QuietHours) Contains(t time.Time) bool {
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if q.Start <= q.End {
+		return tod >= q.Start && tod < q.End
+	}
+	return tod >= q.Start || tod < q.End
 }
 
 var (
@@ -139,6 +290,29 @@ func loadConfig(getenv func(string) string, version string) (*config, error) {
 	if err != nil {
 		cfg.musicBrainzRateLimit = 1
 	}
+	cfg.sessionGapMinutes, err = strconv.Atoi(getenv(SESSION_GAP_MINUTES_ENV))
+	if err != nil || cfg.sessionGapMinutes < 1 {
+		cfg.sessionGapMinutes = defaultSessionGapMins
+	}
+	cfg.chartDecayHalfLifeDays, err = strconv.Atoi(getenv(CHART_DECAY_HALF_LIFE_DAYS_ENV))
+	if err != nil || cfg.chartDecayHalfLifeDays < 1 {
+		cfg.chartDecayHalfLifeDays = defaultDecayHalfLife
+	}
+	cfg.favoriteMinDays, err = strconv.Atoi(getenv(FAVORITE_MIN_DAYS_ENV))
+	if err != nil || cfg.favoriteMinDays < 1 {
+		cfg.favoriteMinDays = defaultFavoriteDays
+	}
+	cfg.softDeleteRetention, err = strconv.Atoi(getenv(SOFT_DELETE_RETENTION_DAYS_ENV))
+	if err != nil || cfg.softDeleteRetention < 1 {
+		cfg.softDeleteRetention = defaultSoftDeleteDays
+	}
+	cfg.imageDownloadWorkers, err = strconv.Atoi(getenv(IMAGE_DOWNLOAD_WORKERS_ENV))
+	if err != nil || cfg.imageDownloadWorkers < 1 {
+		cfg.imageDownloadWorkers = defaultImageWorkers
+	}
+	cfg.imageDownloadRateLimit, _ = strconv.Atoi(getenv(IMAGE_DOWNLOAD_RATE_LIMIT_ENV))
+	backfillMinutes, _ := strconv.Atoi(getenv(IMAGE_BACKFILL_INTERVAL_ENV))
+	cfg.imageBackfillInterval = time.Duration(max(backfillMinutes, 0)) * time.Minute
 	cfg.musicBrainzUrl = getenv(MUSICBRAINZ_URL_ENV)
 	if cfg.musicBrainzUrl == "" {
 		cfg.musicBrainzUrl = defaultMusicBrainzUrl
@@ -165,11 +339,38 @@ func loadConfig(getenv func(string) string, version string) (*config, error) {
 	}
 
 	cfg.importThrottleMs, _ = strconv.Atoi(getenv(THROTTLE_IMPORTS_MS))
+	cfg.importIgnoreBelowMs, _ = strconv.Atoi(getenv(IMPORT_IGNORE_BELOW_MS_ENV))
+	cfg.importMergeGapSeconds, _ = strconv.Atoi(getenv(IMPORT_MERGE_GAP_SECONDS_ENV))
+	cfg.importDedupeSeconds, err = strconv.Atoi(getenv(IMPORT_DEDUPE_WINDOW_ENV))
+	if err != nil || cfg.importDedupeSeconds < 0 {
+		cfg.importDedupeSeconds = defaultImportDedupe
+	}
+	cfg.listenDedupeSeconds, err = strconv.Atoi(getenv(LISTEN_DEDUPE_WINDOW_ENV))
+	if err != nil || cfg.listenDedupeSeconds < 0 {
+		cfg.listenDedupeSeconds = defaultListenDedupe
+	}
+	cfg.importPartialPlays = parseBool(getenv(IMPORT_PARTIAL_PLAYS_ENV))
+	if getenv(IMPORT_TIMEZONE_ENV) != "" {
+		cfg.importTZ, err = time.LoadLocation(getenv(IMPORT_TIMEZONE_ENV))
+		if err != nil {
+			return nil, fmt.Errorf("import timezone '%s' is not a valid timezone", getenv(IMPORT_TIMEZONE_ENV))
+		}
+	}
 
 	cfg.disableRateLimit = parseBool(getenv(DISABLE_RATE_LIMIT_ENV))
 
 	cfg.structuredLogging = parseBool(getenv(ENABLE_STRUCTURED_LOGGING_ENV))
 	cfg.fetchImageDuringImport = parseBool(getenv(FETCH_IMAGES_DURING_IMPORT_ENV))
+	cfg.artistImageFallback = parseBool(getenv(USE_ARTIST_IMAGE_FALLBACK_ENV))
+	cfg.strictAlbumImageMatch = parseBool(getenv(STRICT_ALBUM_IMAGE_MATCH_ENV))
+	cfg.ignoreLeadingThe = parseBool(getenv(IGNORE_LEADING_THE_ENV))
+	cfg.lazyImageFetch = parseBool(getenv(LAZY_IMAGE_FETCH_ENV))
+	cfg.fetchAudioFeatures = parseBool(getenv(FETCH_AUDIO_FEATURES_ENV))
+	cfg.fetchAlbumLabels = parseBool(getenv(FETCH_ALBUM_LABELS_ENV))
+	cfg.foldDiacritics = parseBool(getenv(FOLD_DIACRITICS_ENV))
+	cfg.mergeDuplicateAlbums = parseBool(getenv(MERGE_DUPLICATE_ALBUMS_ENV))
+	cfg.resolveCompilations = parseBool(getenv(RESOLVE_COMPILATIONS_ENV))
+	cfg.generateBlurHash = parseBool(getenv(GENERATE_BLURHASH_ENV))
 
 	cfg.disableDeezer = parseBool(getenv(DISABLE_DEEZER_ENV))
 	cfg.disableCAA = parseBool(getenv(DISABLE_COVER_ART_ARCHIVE_ENV))
@@ -184,7 +385,12 @@ func loadConfig(getenv func(string) string, version string) (*config, error) {
 		return nil, fmt.Errorf("loadConfig: invalid configuration: both %s and %s must be set in order to use subsonic image fetching", SUBSONIC_URL_ENV, SUBSONIC_PARAMS_ENV)
 	}
 	cfg.lastfmApiKey = getenv(LASTFM_API_KEY_ENV)
+	cfg.lastfmImportUser = getenv(LASTFM_IMPORT_USER_ENV)
+	cfg.lastfmScrobbleSecret = getenv(LASTFM_SCROBBLE_SECRET_ENV)
+	cfg.lbzImportUser = getenv(LBZ_IMPORT_USER_ENV)
+	cfg.lbzImportToken = getenv(LBZ_IMPORT_TOKEN_ENV)
 	cfg.skipImport = parseBool(getenv(SKIP_IMPORT_ENV))
+	cfg.forceReimport = parseBool(getenv(FORCE_REIMPORT_ENV))
 
 	cfg.userAgent = fmt.Sprintf("Koito %s (contact@koito.io)", version)
 
@@ -225,6 +431,16 @@ func loadConfig(getenv func(string) string, version string) (*config, error) {
 		cfg.artistSeparators = []*regexp.Regexp{regexp.MustCompile(`\s+·\s+`)}
 	}
 
+	if getenv(TRACK_VERSION_SUFFIXES_ENV) != "" {
+		for pattern := range strings.SplitSeq(getenv(TRACK_VERSION_SUFFIXES_ENV), ";;") {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile regex pattern %s", pattern)
+			}
+			cfg.trackVersionSuffixes = append(cfg.trackVersionSuffixes, regex)
+		}
+	}
+
 	if strings.ToLower(getenv(LOGIN_GATE_ENV)) == "true" {
 		cfg.loginGate = true
 	}
@@ -236,6 +452,64 @@ func loadConfig(getenv func(string) string, version string) (*config, error) {
 		}
 	}
 
+	cfg.imageProviderOrder, err = parseImageProviderOrder(getenv(IMAGE_PROVIDER_ORDER_ENV))
+	if err != nil {
+		return nil, fmt.Errorf("loadConfig: %w", err)
+	}
+	cfg.imageProviderTimeouts, err = parseImageProviderTimeouts(getenv(IMAGE_PROVIDER_TIMEOUTS_ENV))
+	if err != nil {
+		return nil, fmt.Errorf("loadConfig: %w", err)
+	}
+	cfg.providerFailures, err = strconv.Atoi(getenv(PROVIDER_FAILURE_THRESHOLD_ENV))
+	if err != nil || cfg.providerFailures < 1 {
+		cfg.providerFailures = defaultProviderFailures
+	}
+	cooldown, err := strconv.Atoi(getenv(PROVIDER_COOLDOWN_SECONDS_ENV))
+	if err != nil || cooldown < 1 {
+		cooldown = defaultProviderCooldown
+	}
+	cfg.providerCooldown = time.Duration(cooldown) * time.Second
+	// zero disables the cache, so only a missing or invalid value falls back to the default
+	positiveTTL, err := strconv.Atoi(getenv(IMAGE_POSITIVE_CACHE_TTL_ENV))
+	if err != nil || positiveTTL < 0 {
+		positiveTTL = defaultImagePositiveTTL
+	}
+	cfg.imagePositiveCacheTTL = time.Duration(positiveTTL) * time.Second
+	negativeTTL, err := strconv.Atoi(getenv(IMAGE_NEGATIVE_CACHE_TTL_ENV))
+	if err != nil || negativeTTL < 0 {
+		negativeTTL = defaultImageNegativeTTL
+	}
+	cfg.imageNegativeCacheTTL = time.Duration(negativeTTL) * time.Second
+	cfg.localImagesDir = getenv(LOCAL_IMAGES_DIR_ENV)
+
+	if getenv(SCROBBLE_QUIET_HOURS_ENV) != "" {
+		cfg.quietHours, err = parseQuietHours(getenv(SCROBBLE_QUIET_HOURS_ENV))
+		if err != nil {
+			return nil, fmt.Errorf("loadConfig: %w", err)
+		}
+	}
+
+	switch p := strings.ToLower(getenv(ALBUM_MATCH_POLICY_ENV)); p {
+	case AlbumMatchPolicyNone, AlbumMatchPolicyMostPlayed, AlbumMatchPolicyFirstAdded:
+		cfg.albumMatchPolicy = p
+	default:
+		return nil, fmt.Errorf("loadConfig: unknown %s '%s'", ALBUM_MATCH_POLICY_ENV, p)
+	}
+
+	switch p := strings.ToLower(getenv(SINGLE_RELEASE_POLICY_ENV)); p {
+	case SingleReleasePolicyNone, SingleReleasePolicyTag, SingleReleasePolicyCollapse:
+		cfg.singleReleasePolicy = p
+	default:
+		return nil, fmt.Errorf("loadConfig: unknown %s '%s'", SINGLE_RELEASE_POLICY_ENV, p)
+	}
+
+	switch p := strings.ToLower(getenv(ARTIST_CASING_POLICY_ENV)); p {
+	case ArtistCasingPolicyNone, ArtistCasingPolicyMusicBrainz, ArtistCasingPolicyFrequent:
+		cfg.artistCasingPolicy = p
+	default:
+		return nil, fmt.Errorf("loadConfig: unknown %s '%s'", ARTIST_CASING_POLICY_ENV, p)
+	}
+
 	switch strings.ToLower(getenv(LOG_LEVEL_ENV)) {
 	case "debug":
 		cfg.logLevel = 0
@@ -251,6 +525,79 @@ func loadConfig(getenv func(string) string, version string) (*config, error) {
 	return cfg, nil
 }
 
+// parseImageProviderOrder parses a comma separated list of image providers. Providers
+// that are not listed are appended afterwards in their default order.
+func parseImageProviderOrder(s string) ([]string, error) {
+	order := make([]string, 0, len(defaultImageProviderOrder))
+	for p := range strings.SplitSeq(s, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if !slices.Contains(defaultImageProviderOrder, p) {
+			return nil, fmt.Errorf("unknown image provider '%s' in %s", p, IMAGE_PROVIDER_ORDER_ENV)
+		}
+		if !slices.Contains(order, p) {
+			order = append(order, p)
+		}
+	}
+	for _, p := range defaultImageProviderOrder {
+		if !slices.Contains(order, p) {
+			order = append(order, p)
+		}
+	}
+	return order, nil
+}
+
+// parseImageProviderTimeouts parses a comma separated list of provider=seconds pairs. Providers
+// that are not listed use the default timeout.
+func parseImageProviderTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(defaultImageProviderOrder))
+	for _, p := range defaultImageProviderOrder {
+		timeouts[p] = defaultImageProviderTimeout * time.Second
+	}
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		p, secs, ok := strings.Cut(pair, "=")
+		p = strings.ToLower(strings.TrimSpace(p))
+		if !ok || !slices.Contains(defaultImageProviderOrder, p) {
+			return nil, fmt.Errorf("invalid image provider timeout '%s' in %s", pair, IMAGE_PROVIDER_TIMEOUTS_ENV)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(secs))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid image provider timeout '%s' in %s", pair, IMAGE_PROVIDER_TIMEOUTS_ENV)
+		}
+		timeouts[p] = time.Duration(n) * time.Second
+	}
+	return timeouts, nil
+}
+
+// parseQuietHours parses a range in the form HH:MM-HH:MM
+func parseQuietHours(s string) (*QuietHours, error) {
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("%s must be in the form HH:MM-HH:MM", SCROBBLE_QUIET_HOURS_ENV)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(startStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time in %s: %w", SCROBBLE_QUIET_HOURS_ENV, err)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(endStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time in %s: %w", SCROBBLE_QUIET_HOURS_ENV, err)
+	}
+	q := &QuietHours{
+		Start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+		End:   time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+	}
+	if q.Start == q.End {
+		return nil, fmt.Errorf("%s start and end times must differ", SCROBBLE_QUIET_HOURS_ENV)
+	}
+	return q, nil
+}
+
 func parseBool(s string) bool {
 	if strings.ToLower(s) == "true" {
 		return true
@@ -0,0 +1,59 @@
+package cfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	q, err := parseQuietHours("01:30-07:00")
+	require.NoError(t, err)
+	assert.Equal(t, QuietHours{Start: 90 * time.Minute, End: 7 * time.Hour}, *q)
+
+	q, err = parseQuietHours(" 23:00 - 06:15 ")
+	require.NoError(t, err)
+	assert.Equal(t, QuietHours{Start: 23 * time.Hour, End: 6*time.Hour + 15*time.Minute}, *q)
+
+	for _, s := range []string{"", "01:00", "1am-7am", "01:00-25:00", "03:00-03:00"} {
+		_, err := parseQuietHours(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestQuietHoursContains(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 1, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	q := QuietHours{Start: 1 * time.Hour, End: 7 * time.Hour}
+	assert.False(t, q.Contains(at(0, 59)))
+	assert.True(t, q.Contains(at(1, 0)))
+	assert.True(t, q.Contains(at(6, 59)))
+	assert.False(t, q.Contains(at(7, 0)))
+
+	// the range wraps past midnight
+	q = QuietHours{Start: 23 * time.Hour, End: 6 * time.Hour}
+	assert.False(t, q.Contains(at(22, 59)))
+	assert.True(t, q.Contains(at(23, 0)))
+	assert.True(t, q.Contains(at(0, 0)))
+	assert.True(t, q.Contains(at(5, 59)))
+	assert.False(t, q.Contains(at(6, 0)))
+	assert.False(t, q.Contains(at(12, 0)))
+}
+
+func TestParseImageProviderOrder(t *testing.T) {
+	order, err := parseImageProviderOrder("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultImageProviderOrder(), order)
+
+	// listed providers come first, and the rest follow in their default order
+	order, err = parseImageProviderOrder(" Deezer,caa,deezer ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deezer", "caa", "spotify", "subsonic", "lastfm"}, order)
+
+	_, err = parseImageProviderOrder("caa,flickr")
+	assert.Error(t, err)
+}
@@ -156,12 +156,51 @@ func LastFMApiKey() string {
 	return globalConfig.lastfmApiKey
 }
 
+// LastFMImportUser returns the Last.fm user whose scrobbles are imported through the Last.fm API
+// on startup, or an empty string when none is set.
+func LastFMImportUser() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.lastfmImportUser
+}
+
+// LastFMScrobbleSecret returns the shared secret the signatures of requests to the Last.fm
+// compatible scrobble API are checked against, or an empty string when they are not checked.
+func LastFMScrobbleSecret() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.lastfmScrobbleSecret
+}
+
+// ListenBrainzImportUser returns the ListenBrainz user whose listens are imported through the
+// ListenBrainz API on startup, or an empty string when none is set.
+func ListenBrainzImportUser() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.lbzImportUser
+}
+
+// ListenBrainzImportToken returns the optional token sent with ListenBrainz API import requests.
+func ListenBrainzImportToken() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.lbzImportToken
+}
+
 func SkipImport() bool {
 	lock.RLock()
 	defer lock.RUnlock()
 	return globalConfig.skipImport
 }
 
+// ForceReimport reports whether import files should be imported even when a file with the same
+// contents was already imported.
+func ForceReimport() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.forceReimport
+}
+
 func AllowedHosts() []string {
 	lock.RLock()
 	defer lock.RUnlock()
@@ -192,6 +231,68 @@ func ThrottleImportMs() int {
 	return globalConfig.importThrottleMs
 }
 
+// ImageDownloadWorkers returns the number of images that can be downloaded to the image cache at once.
+func ImageDownloadWorkers() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imageDownloadWorkers
+}
+
+// ImageDownloadRateLimit returns the most image downloads started per second, or 0 for no limit.
+func ImageDownloadRateLimit() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imageDownloadRateLimit
+}
+
+// ImportIgnoreBelowMs returns the play time, in milliseconds, below which imported Spotify items are dropped.
+// 0 disables the filter.
+func ImportIgnoreBelowMs() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.importIgnoreBelowMs
+}
+
+// ImportMergeGapSeconds returns the longest pause, in seconds, between two consecutive imported Spotify
+// items of the same track for them to be merged into a single play. 0 disables merging.
+func ImportMergeGapSeconds() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.importMergeGapSeconds
+}
+
+// ImportDedupeWindowSeconds returns the time, in seconds, within which a repeat of the same imported
+// Spotify track is dropped as a duplicate. 0 disables deduplication.
+func ImportDedupeWindowSeconds() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.importDedupeSeconds
+}
+
+// ListenDedupeWindowSeconds returns the time, in seconds, within which a listen submitted by a client
+// to a track that already has a listen is dropped as a duplicate. 0 disables deduplication.
+func ListenDedupeWindowSeconds() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.listenDedupeSeconds
+}
+
+// ImportPartialPlays reports whether imported Spotify items are kept whatever the reason they ended,
+// rather than only when the track played to the end.
+func ImportPartialPlays() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.importPartialPlays
+}
+
+// ImportTimezone returns the timezone of import file timestamps that are written in local time without
+// an offset, or nil if it is not set.
+func ImportTimezone() *time.Location {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.importTZ
+}
+
 // returns the before, after times, in that order
 func ImportWindow() (time.Time, time.Time) {
 	lock.RLock()
@@ -211,14 +312,217 @@ func ArtistSeparators() []*regexp.Regexp {
 	return globalConfig.artistSeparators
 }
 
+// TrackVersionSuffixes returns the patterns of version suffixes, such as remaster or live notes,
+// that are ignored when matching track titles. Empty when version suffixes are not ignored.
+func TrackVersionSuffixes() []*regexp.Regexp {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.trackVersionSuffixes
+}
+
 func LoginGate() bool {
 	lock.RLock()
 	defer lock.RUnlock()
 	return globalConfig.loginGate
 }
 
+// UseArtistImageForMissingAlbum reports whether albums with no image available from any provider
+// should use a copy of their artist's image instead.
+func UseArtistImageForMissingAlbum() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.artistImageFallback
+}
+
+// StrictAlbumImageMatch reports whether album images should only be accepted from search results
+// whose title exactly matches the album, rather than from results that contain the album's title.
+func StrictAlbumImageMatch() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.strictAlbumImageMatch
+}
+
+// IgnoreLeadingThe reports whether a leading article, like "The", should be ignored when matching
+// artists by name, so that e.g. "The Beatles" and "Beatles" are the same artist.
+func IgnoreLeadingThe() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.ignoreLeadingThe
+}
+
+// LazyImageFetch reports whether images should be looked up and downloaded when they are first
+// requested instead of when their artist or album is created.
+func LazyImageFetch() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.lazyImageFetch
+}
+
+// GenerateBlurHash reports whether a BlurHash placeholder should be computed for images as they are
+// downloaded.
+func GenerateBlurHash() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.generateBlurHash
+}
+
+// ImageBackfillInterval returns how often artists and albums without an image are looked up again,
+// or 0 if they should not be.
+func ImageBackfillInterval() time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imageBackfillInterval
+}
+
+// FetchAudioFeatures reports whether the audio features of tracks, like tempo and energy, should be
+// fetched from Spotify.
+func FetchAudioFeatures() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.fetchAudioFeatures
+}
+
+// FetchAlbumLabels reports whether the labels and catalog numbers of albums should be fetched from MusicBrainz.
+func FetchAlbumLabels() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.fetchAlbumLabels
+}
+
+// FoldDiacriticsForMatching reports whether album titles should be matched ignoring case, accents
+// and punctuation, so that e.g. "Café Vol. 1" and "Cafe Vol 1" are treated as the same album.
+func FoldDiacriticsForMatching() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.foldDiacritics
+}
+
+// MergeDuplicateAlbums reports whether existing albums whose titles only differ in case, accents or
+// punctuation should be merged on startup. Merges can not be undone, so this is separate from
+// FoldDiacriticsForMatching.
+func MergeDuplicateAlbums() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.mergeDuplicateAlbums
+}
+
+// ResolveCompilationArtists reports whether listens to tracks credited to "Various Artists" should be
+// attributed to the track's performers, as found on MusicBrainz.
+func ResolveCompilationArtists() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.resolveCompilations
+}
+
 func ForceTZ() *time.Location {
 	lock.RLock()
 	defer lock.RUnlock()
 	return globalConfig.forceTZ
 }
+
+func ImageProviderOrder() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imageProviderOrder
+}
+
+// ImageProviderTimeouts returns how long a request to each image provider may take, keyed by provider name.
+func ImageProviderTimeouts() map[string]time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imageProviderTimeouts
+}
+
+// ImageProviderFailureThreshold returns the number of consecutive failed requests after which an image provider is paused.
+func ImageProviderFailureThreshold() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.providerFailures
+}
+
+// ImageProviderCooldown returns how long an image provider is paused for after repeated failures.
+func ImageProviderCooldown() time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.providerCooldown
+}
+
+// ImagePositiveCacheTTL returns how long an image found by the image providers is remembered for
+// the same lookup. Zero means found images are not remembered.
+func ImagePositiveCacheTTL() time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imagePositiveCacheTTL
+}
+
+// ImageNegativeCacheTTL returns how long a lookup that found no image is remembered, so the image
+// providers are not queried again for the same artist or album. Zero means such lookups are not remembered.
+func ImageNegativeCacheTTL() time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.imageNegativeCacheTTL
+}
+
+// LocalImagesDir returns the directory of user supplied artist and album images, which take precedence
+// over the image providers. Empty when not configured.
+func LocalImagesDir() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.localImagesDir
+}
+
+// ScrobbleQuietHours returns the configured quiet hours for live scrobbles, or nil if disabled.
+func ScrobbleQuietHours() *QuietHours {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.quietHours
+}
+
+// AlbumMatchPolicy returns the policy used to pick an existing album for listens without release information.
+func AlbumMatchPolicy() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.albumMatchPolicy
+}
+
+// SingleReleasePolicy returns how albums consisting of a single same-named track are handled.
+func SingleReleasePolicy() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.singleReleasePolicy
+}
+
+// ArtistCasingPolicy returns the policy used to pick which casing of an artist's name is displayed.
+func ArtistCasingPolicy() string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.artistCasingPolicy
+}
+
+// SoftDeleteRetentionDays returns the number of days deleted listens are kept, and can be restored, before they are purged.
+func SoftDeleteRetentionDays() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.softDeleteRetention
+}
+
+// SessionGapMinutes returns the longest gap between listens, in minutes, for them to be part of the same listening session.
+func SessionGapMinutes() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.sessionGapMinutes
+}
+
+// FavoriteMinDays returns the number of distinct days an artist must have been listened to on to count as a favorite.
+func FavoriteMinDays() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.favoriteMinDays
+}
+
+// ChartDecayHalfLifeDays returns the half-life, in days, of a listen's weight in recency weighted top charts.
+func ChartDecayHalfLifeDays() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return globalConfig.chartDecayHalfLifeDays
+}
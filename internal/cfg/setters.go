@@ -1,7 +1,138 @@
 package cfg
 
+import (
+	"regexp"
+	"time"
+)
+
 func SetLoginGate(val bool) {
 	lock.Lock()
 	defer lock.Unlock()
 	globalConfig.loginGate = val
 }
+
+func SetAlbumMatchPolicy(val string) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.albumMatchPolicy = val
+}
+
+func SetArtistCasingPolicy(val string) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.artistCasingPolicy = val
+}
+
+func SetUseArtistImageForMissingAlbum(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.artistImageFallback = val
+}
+
+func SetSingleReleasePolicy(val string) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.singleReleasePolicy = val
+}
+
+func SetImportIgnoreBelowMs(val int) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.importIgnoreBelowMs = val
+}
+
+func SetImportMergeGapSeconds(val int) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.importMergeGapSeconds = val
+}
+
+func SetListenDedupeWindowSeconds(val int) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.listenDedupeSeconds = val
+}
+
+func SetImportDedupeWindowSeconds(val int) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.importDedupeSeconds = val
+}
+
+func SetImportPartialPlays(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.importPartialPlays = val
+}
+
+func SetImportTimezone(loc *time.Location) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.importTZ = loc
+}
+
+func SetIgnoreLeadingThe(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.ignoreLeadingThe = val
+}
+
+func SetLazyImageFetch(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.lazyImageFetch = val
+}
+
+func SetFoldDiacriticsForMatching(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.foldDiacritics = val
+}
+
+func SetMergeDuplicateAlbums(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.mergeDuplicateAlbums = val
+}
+
+func SetForceReimport(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.forceReimport = val
+}
+
+func SetResolveCompilationArtists(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.resolveCompilations = val
+}
+
+func SetFavoriteMinDays(val int) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.favoriteMinDays = val
+}
+
+func SetFetchAlbumLabels(val bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.fetchAlbumLabels = val
+}
+
+func SetTrackVersionSuffixes(val []*regexp.Regexp) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.trackVersionSuffixes = val
+}
+
+func SetLastFMScrobbleSecret(val string) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.lastfmScrobbleSecret = val
+}
+
+func SetScrobbleQuietHours(q *QuietHours) {
+	lock.Lock()
+	defer lock.Unlock()
+	globalConfig.quietHours = q
+}
@@ -25,9 +25,18 @@ type ArtistStore interface {
 	DeleteArtistAlias(ctx context.Context, id int32, alias string) error
 	MergeArtists(ctx context.Context, fromId, toId int32, replaceImage bool) error
 	SearchArtists(ctx context.Context, q string) ([]*models.Artist, error)
-	CountArtists(ctx context.Context, timeframe Timeframe) (int64, error)
+	CountArtists(ctx context.Context, opts CountOpts) (int64, error)
 	CountNewArtists(ctx context.Context, timeframe Timeframe) (int64, error)
 	ArtistsWithoutImages(ctx context.Context, from int32) ([]*models.Artist, error)
+	CountArtistsWithoutImages(ctx context.Context) (int64, error)
+	GetUserTopArtists(ctx context.Context, opts GetUserTopItemsOpts) ([]UserTopItem, error)
+	GetNeglectedArtists(ctx context.Context, opts GetNeglectedArtistsOpts) ([]NeglectedArtist, error)
+	GetFavoriteArtists(ctx context.Context, opts GetFavoriteArtistsOpts) ([]FavoriteArtist, error)
+	GetUserNewArtists(ctx context.Context, opts GetUserTopItemsOpts) ([]UserTopItem, error)
+	CountArtistsWithAlias(ctx context.Context, alias string) (int, error)
+	GetAmbiguousArtistAliases(ctx context.Context) ([]AmbiguousAlias, error)
+	RecordArtistNameSeen(ctx context.Context, id int32, name string) error
+	GetArtistNameCasings(ctx context.Context) ([]ArtistNameCasings, error)
 }
 
 type AlbumStore interface {
@@ -37,6 +46,7 @@ type AlbumStore interface {
 	GetAllAlbumAliases(ctx context.Context, id int32) ([]models.Alias, error)
 	SaveAlbum(ctx context.Context, opts SaveAlbumOpts) (*models.Album, error)
 	SaveAlbumAliases(ctx context.Context, id int32, aliases []string, source string) error
+	SaveAlbumLabels(ctx context.Context, id int32, labels []models.AlbumLabel) error
 	UpdateAlbum(ctx context.Context, opts UpdateAlbumOpts) error
 	SetPrimaryAlbumAlias(ctx context.Context, id int32, alias string) error
 	AddArtistsToAlbum(ctx context.Context, opts AddArtistsToAlbumOpts) error
@@ -44,9 +54,15 @@ type AlbumStore interface {
 	DeleteAlbumAlias(ctx context.Context, id int32, alias string) error
 	MergeAlbums(ctx context.Context, fromId, toId int32, replaceImage bool) error
 	SearchAlbums(ctx context.Context, q string) ([]*models.Album, error)
-	CountAlbums(ctx context.Context, timeframe Timeframe) (int64, error)
+	CountAlbums(ctx context.Context, opts CountOpts) (int64, error)
 	CountNewAlbums(ctx context.Context, timeframe Timeframe) (int64, error)
 	AlbumsWithoutImages(ctx context.Context, from int32) ([]*models.Album, error)
+	CountAlbumsWithoutImages(ctx context.Context) (int64, error)
+	ReleaseGroupAlbumsWithoutImages(ctx context.Context, releaseGroupMbzID uuid.UUID) ([]*models.Album, error)
+	GetArtistTopAlbums(ctx context.Context, opts GetArtistTopItemsOpts) ([]ArtistTopItem, error)
+	CountAlbumTracks(ctx context.Context, id int32) (int64, error)
+	GetAlbumTitles(ctx context.Context, artistID int32) ([]AlbumTitle, error)
+	GetUserTopAlbums(ctx context.Context, opts GetUserTopItemsOpts) ([]UserTopItem, error)
 }
 
 type TrackStore interface {
@@ -61,24 +77,48 @@ type TrackStore interface {
 	DeleteTrack(ctx context.Context, id int32) error
 	DeleteTrackAlias(ctx context.Context, id int32, alias string) error
 	MergeTracks(ctx context.Context, fromId, toId int32) error
+	MoveTrackToRelease(ctx context.Context, trackID, releaseID int32) error
 	SearchTracks(ctx context.Context, q string) ([]*models.Track, error)
-	CountTracks(ctx context.Context, timeframe Timeframe) (int64, error)
+	CountTracks(ctx context.Context, opts CountOpts) (int64, error)
 	CountNewTracks(ctx context.Context, timeframe Timeframe) (int64, error)
 	AddArtistsToAlbum(ctx context.Context, opts AddArtistsToAlbumOpts) error
+	GetUserTopTracks(ctx context.Context, opts GetUserTopItemsOpts) ([]UserTopItem, error)
+	GetTrackAlbumCandidates(ctx context.Context, opts GetTrackAlbumCandidatesOpts) ([]TrackAlbumCandidate, error)
+	GetTrackTitles(ctx context.Context, opts GetTrackTitlesOpts) ([]TrackTitle, error)
+	GetArtistTopTracks(ctx context.Context, opts GetArtistTopItemsOpts) ([]ArtistTopItem, error)
+	GetTracksWithoutAudioFeatures(ctx context.Context, from int32) ([]*models.Track, error)
+	GetTrackAudioFeatures(ctx context.Context, id int32) (*models.AudioFeatures, error)
+	SaveTrackAudioFeatures(ctx context.Context, id int32, features *models.AudioFeatures) error
+	GetAverageAudioFeatures(ctx context.Context, opts GetAverageAudioFeaturesOpts) (*AverageAudioFeatures, error)
 }
 
 type ListenStore interface {
-	GetListensPaginated(ctx context.Context, opts GetItemsOpts) (*PaginatedResponse[*models.Listen], error)
+	GetListens(ctx context.Context, opts GetListensOpts) (*PaginatedResponse[*models.Listen], error)
+	GetListensBefore(ctx context.Context, opts GetListensBeforeOpts) ([]*models.Listen, error)
+	GetListensByDevice(ctx context.Context, opts CountOpts) ([]DeviceListenCount, error)
+	GetListenVersion(ctx context.Context, userID int32) (ListenVersion, error)
+	GetListenLog(ctx context.Context, opts GetListenLogOpts) ([]ListenLogEntry, error)
+	GetListenCountsByWeekday(ctx context.Context, opts GetListenCountsByWeekdayOpts) (map[time.Weekday]int64, error)
+	GetListenCountsByDay(ctx context.Context, opts GetListenCountsByDayOpts) (map[time.Time]int64, error)
+	GetUserListenTotals(ctx context.Context, opts GetUserListenTotalsOpts) (ListenTotals, error)
 	GetListenActivity(ctx context.Context, opts ListenActivityOpts) ([]ListenActivityItem, error)
 	GetInterest(ctx context.Context, opts GetInterestOpts) ([]InterestBucket, error)
-	SaveListen(ctx context.Context, opts SaveListenOpts) error
+	SaveListen(ctx context.Context, opts SaveListenOpts) (bool, error)
+	SaveListens(ctx context.Context, opts []SaveListenOpts) ([]bool, error)
+	ListenExistsWithin(ctx context.Context, opts ListenExistsWithinOpts) (bool, error)
 	DeleteListen(ctx context.Context, trackId int32, listenedAt time.Time) error
-	CountListens(ctx context.Context, timeframe Timeframe) (int64, error)
+	DeleteUserListen(ctx context.Context, opts DeleteListenOpts) (bool, error)
+	DeleteUserListens(ctx context.Context, opts []DeleteListenOpts) (int64, error)
+	DeleteListensInRange(ctx context.Context, opts DeleteListensOpts) (int64, error)
+	RestoreListens(ctx context.Context, opts RestoreListensOpts) (int64, error)
+	SetListensPrivate(ctx context.Context, opts SetListensPrivateOpts) (int64, error)
+	PurgeDeletedListens(ctx context.Context, deletedBefore time.Time) (int64, error)
+	CountListens(ctx context.Context, opts CountOpts) (int64, error)
 	CountListensToItem(ctx context.Context, opts TimeListenedOpts) (int64, error)
-	CountTimeListened(ctx context.Context, timeframe Timeframe) (int64, error)
+	CountTimeListened(ctx context.Context, opts CountOpts) (int64, error)
 	CountTimeListenedToItem(ctx context.Context, opts TimeListenedOpts) (int64, error)
 	GetFirstListenUnix(ctx context.Context) (int64, error)
-	GetActiveDays(ctx context.Context, tz *time.Location) (int, error)
+	GetActiveDays(ctx context.Context, opts ListenActivityOpts) (int, error)
 	GetListenStreak(ctx context.Context, opts ListenActivityOpts) (int, error)
 	GetLongestListenStreak(ctx context.Context, opts ListenActivityOpts) (int, error)
 }
@@ -86,18 +126,22 @@ type ListenStore interface {
 type UserStore interface {
 	GetUserBySession(ctx context.Context, sessionId uuid.UUID) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
-	GetUserByApiKey(ctx context.Context, key string) (*models.User, error)
+	GetUserByApiKey(ctx context.Context, keyHash string) (*models.User, error)
 	GetAdminUser(ctx context.Context) (*models.User, error)
 	GetApiKeysByUserID(ctx context.Context, id int32) ([]models.ApiKey, error)
+	GetUnhashedApiKeys(ctx context.Context) ([]models.ApiKey, error)
 	SaveUser(ctx context.Context, opts SaveUserOpts) (*models.User, error)
 	SaveApiKey(ctx context.Context, opts SaveApiKeyOpts) (*models.ApiKey, error)
 	SaveSession(ctx context.Context, userId int32, expiresAt time.Time, persistent bool) (*models.Session, error)
 	UpdateUser(ctx context.Context, opts UpdateUserOpts) error
 	UpdateApiKeyLabel(ctx context.Context, opts UpdateApiKeyLabelOpts) error
+	UpdateApiKeyHash(ctx context.Context, id int32, keyHash, keyHint string) error
 	RefreshSession(ctx context.Context, sessionId uuid.UUID, expiresAt time.Time) error
 	DeleteSession(ctx context.Context, sessionId uuid.UUID) error
-	DeleteApiKey(ctx context.Context, id int32) error
+	DeleteApiKey(ctx context.Context, userID, id int32) (bool, error)
 	CountUsers(ctx context.Context) (int64, error)
+	GetImportSettings(ctx context.Context, userID int32) (*ImportSettings, error)
+	SaveImportSettings(ctx context.Context, userID int32, settings ImportSettings) error
 }
 
 type ImageStore interface {
@@ -110,6 +154,19 @@ type ExportStore interface {
 	GetExportPage(ctx context.Context, opts GetExportPageOpts) ([]*ExportItem, error)
 }
 
+type ImportJobStore interface {
+	GetImportJobByHash(ctx context.Context, userID int32, hash string) (*ImportJob, error)
+	SaveImportJob(ctx context.Context, opts SaveImportJobOpts) error
+}
+
+type TagStore interface {
+	SaveArtistTags(ctx context.Context, id int32, tags []string) error
+	SaveTrackTags(ctx context.Context, id int32, tags []string) error
+	GetUserTopArtistsByTag(ctx context.Context, opts GetUserTagItemsOpts) ([]UserTopItem, error)
+	GetUserTopTracksByTag(ctx context.Context, opts GetUserTagItemsOpts) ([]UserTopItem, error)
+	CountUserListensWithTag(ctx context.Context, opts GetUserTagItemsOpts) (int64, error)
+}
+
 type DB interface {
 	ArtistStore
 	AlbumStore
@@ -118,6 +175,8 @@ type DB interface {
 	UserStore
 	ImageStore
 	ExportStore
+	ImportJobStore
+	TagStore
 	Ping(ctx context.Context) error
 	Close(ctx context.Context)
 	PurgeAllData(ctx context.Context) error
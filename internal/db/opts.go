@@ -31,6 +31,13 @@ type GetTrackOpts struct {
 	ArtistIDs     []int32
 }
 
+// GetTrackTitlesOpts selects the tracks on a release by exactly the given artists, or by any
+// artists when ArtistIDs is empty
+type GetTrackTitlesOpts struct {
+	ReleaseID int32
+	ArtistIDs []int32
+}
+
 type SaveTrackOpts struct {
 	Title          string
 	AlbumID        int32
@@ -40,20 +47,23 @@ type SaveTrackOpts struct {
 }
 
 type SaveAlbumOpts struct {
-	Title          string
-	MusicBrainzID  uuid.UUID
-	Type           string
-	ArtistIDs      []int32
-	VariousArtists bool
-	Image          uuid.UUID
-	ImageSrc       string
-	Aliases        []string
+	Title             string
+	MusicBrainzID     uuid.UUID
+	ReleaseGroupMbzID uuid.UUID
+	Type              string
+	ArtistIDs         []int32
+	VariousArtists    bool
+	Image             uuid.UUID
+	ImageSrc          string
+	Aliases           []string
+	AliasSource       string // source of Aliases, models.AliasSourceMusicBrainz if empty
 }
 
 type SaveArtistOpts struct {
 	Name          string
 	MusicBrainzID uuid.UUID
 	Aliases       []string
+	AliasSource   string // source of Aliases, models.AliasSourceMusicBrainz if empty
 	Image         uuid.UUID
 	ImageSrc      string
 }
@@ -71,9 +81,10 @@ type SaveUserOpts struct {
 }
 
 type SaveApiKeyOpts struct {
-	Key    string
-	UserID int32
-	Label  string
+	KeyHash string // hash of the key, the key itself is never saved
+	KeyHint string // the last few characters of the key, to tell keys apart
+	UserID  int32
+	Label   string
 }
 
 type SaveListenOpts struct {
@@ -81,6 +92,16 @@ type SaveListenOpts struct {
 	Time    time.Time
 	UserID  int32
 	Client  string
+	Device  string
+	Private bool // private listens are only counted in queries scoped to their user
+}
+
+// ListenExistsWithinOpts finds a user's listen to a track less than Window away from Time.
+type ListenExistsWithinOpts struct {
+	TrackID int32
+	UserID  int32
+	Time    time.Time
+	Window  time.Duration
 }
 
 type UpdateTrackOpts struct {
@@ -101,16 +122,95 @@ type UpdateArtistOpts struct {
 type UpdateAlbumOpts struct {
 	ID                   int32
 	MusicBrainzID        uuid.UUID
+	ReleaseGroupMbzID    uuid.UUID
 	Image                uuid.UUID
 	ImageSrc             string
 	VariousArtistsUpdate bool
 	VariousArtistsValue  bool
+	SingleUpdate         bool
+	SingleValue          bool
 }
 
 type UpdateUserOpts struct {
-	ID       int32
-	Username string
-	Password string
+	ID         int32
+	Username   string
+	Password   string
+	Comparable *bool
+}
+
+type GetUserTopItemsOpts struct {
+	UserID    int32
+	Timeframe Timeframe
+	Limit     int
+	// When true, the user's private listens are not counted, e.g. when comparing with other users
+	ExcludePrivate bool
+}
+
+// GetUserTagItemsOpts scopes a user's listens to the tracks with a tag, either directly or through
+// one of their artists.
+type GetUserTagItemsOpts struct {
+	UserID    int32
+	Tag       string
+	Timeframe Timeframe
+	Limit     int
+}
+
+type GetListenLogOpts struct {
+	UserID    int32 // when 0, listens from all users are returned
+	Timeframe Timeframe
+}
+
+type GetListenCountsByWeekdayOpts struct {
+	UserID    int32 // when 0, listens from all users are counted
+	Timeframe Timeframe
+	Timezone  *time.Location // the timezone used to determine the day of each listen, UTC if nil
+}
+
+type GetListenCountsByDayOpts struct {
+	UserID    int32 // when 0, listens from all users are counted
+	Timeframe Timeframe
+	Timezone  *time.Location // the timezone used to determine the day of each listen, UTC if nil
+}
+
+type GetUserListenTotalsOpts struct {
+	UserID    int32
+	Timeframe Timeframe
+}
+
+type GetAverageAudioFeaturesOpts struct {
+	UserID    int32 // when 0, listens from all users are averaged
+	Timeframe Timeframe
+}
+
+type GetArtistTopItemsOpts struct {
+	ArtistID  int32
+	UserID    int32 // when 0, listens from all users are counted
+	Timeframe Timeframe
+	Limit     int
+
+	// Leave out albums tagged as singles
+	ExcludeSingles bool
+}
+
+type GetNeglectedArtistsOpts struct {
+	UserID     int32     // when 0, listens from all users are counted
+	Before     time.Time // only artists last listened to before this time are returned
+	MinListens int
+	Limit      int
+}
+
+type GetFavoriteArtistsOpts struct {
+	UserID    int32 // when 0, listens from all users are counted
+	Timeframe Timeframe
+	MinDays   int            // artists listened to on fewer distinct days are left out
+	Timezone  *time.Location // the timezone used to determine the day of each listen, UTC if nil
+	Limit     int
+}
+
+type GetTrackAlbumCandidatesOpts struct {
+	Title     string
+	ArtistIDs []int32
+	UserID    int32 // listens are counted for this user only
 }
 
 type AddArtistsToAlbumOpts struct {
@@ -134,6 +234,83 @@ type GetItemsOpts struct {
 
 	// Used for getting listens
 	TrackID int
+
+	// When 0, listens from all users are counted, leaving out private listens. When set, only the
+	// user's listens are counted, along with their private listens.
+	UserID int32
+
+	// When greater than 0, top charts weight each listen by its age, halving
+	// its weight every DecayHalfLifeDays days, instead of counting listens.
+	DecayHalfLifeDays float64
+
+	// Used only for getting top albums, leaves out albums tagged as singles
+	ExcludeSingles bool
+
+	// Used only for getting top albums, only includes albums released on this label
+	Label string
+}
+
+// GetListensOpts filters listens. Every non-zero field narrows the result,
+// and all filters are combined with AND. A zero Timeframe matches all time.
+type GetListensOpts struct {
+	Limit     int
+	Page      int
+	Timeframe Timeframe
+
+	ArtistID  int32
+	ReleaseID int32
+	TrackID   int32
+	Client    string
+	// When set, only the user's listens are included, along with their private listens
+	UserID int32
+	// When set, only listens to tracks whose title, artist or album contains one of the terms
+	Search []string
+}
+
+// GetListensBeforeOpts selects listens older than a position in the listen log, newest first.
+// Listens with the same time are ordered by track ID, so the position is both a time and a track ID.
+// When BeforeTime is zero, listens are returned from the most recent.
+type GetListensBeforeOpts struct {
+	UserID        int32 // when 0, listens from all users are returned
+	BeforeTime    time.Time
+	BeforeTrackID int32
+	Limit         int
+}
+
+// RestoreListensOpts selects deleted listens to restore. When TrackID is set, only the listen with that
+// track ID and ListenedAt is restored. When DeletedSince is set, only listens deleted at or after it are restored.
+// DeleteListenOpts selects a listen of a user to delete.
+type DeleteListenOpts struct {
+	UserID     int32
+	TrackID    int32
+	ListenedAt time.Time
+	// when true, the listen is deleted permanently instead of being kept until it is purged, and the
+	// track, album and artists left without any listens are deleted as well
+	Purge bool
+}
+
+// DeleteListensOpts selects the listens of a user within a time range to delete.
+type DeleteListensOpts struct {
+	UserID int32
+	From   time.Time
+	To     time.Time
+	// when not nil, only the listens submitted by this client are deleted
+	Client *string
+}
+
+type RestoreListensOpts struct {
+	UserID       int32 // when 0, listens from all users are restored
+	TrackID      int32
+	ListenedAt   time.Time
+	DeletedSince time.Time
+}
+
+// SetListensPrivateOpts selects the listens of a user within a time range whose privacy is changed.
+type SetListensPrivateOpts struct {
+	UserID  int32
+	From    time.Time
+	To      time.Time
+	Private bool
 }
 
 type ListenActivityOpts struct {
@@ -145,6 +322,14 @@ type ListenActivityOpts struct {
 	AlbumID  int32
 	ArtistID int32
 	TrackID  int32
+	UserID   int32 // when 0, listens from all users are counted, leaving out private listens
+}
+
+// CountOpts counts the listens within Timeframe. When UserID is set, only the user's listens are
+// counted, along with their private listens.
+type CountOpts struct {
+	UserID    int32
+	Timeframe Timeframe
 }
 
 type TimeListenedOpts struct {
@@ -161,6 +346,13 @@ type GetExportPageOpts struct {
 	Limit      int32
 }
 
+type SaveImportJobOpts struct {
+	UserID      int32
+	Filename    string
+	ContentHash string
+	Imported    int
+}
+
 type GetInterestOpts struct {
 	Buckets  int
 	AlbumID  int32
@@ -50,6 +50,17 @@ func (s *Sqlite) GetAlbum(ctx context.Context, opts db.GetAlbumOpts) (*models.Al
 			return nil, fmt.Errorf("GetAlbum: by artist+titles: %w", err)
 		}
 		opts.ID = id
+	} else if opts.Image != uuid.Nil {
+		var id int32
+		err := s.db.QueryRowContext(ctx,
+			`SELECT id FROM releases WHERE image = ? ORDER BY id LIMIT 1`, opts.Image.String()).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("GetAlbum: by image: %w", db.ErrNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GetAlbum: by image: %w", err)
+		}
+		opts.ID = id
 	}
 
 	return s.getAlbumByID(ctx, opts.ID)
@@ -57,12 +68,12 @@ func (s *Sqlite) GetAlbum(ctx context.Context, opts db.GetAlbumOpts) (*models.Al
 
 func (s *Sqlite) getAlbumByID(ctx context.Context, id int32) (*models.Album, error) {
 	var ret models.Album
-	var mbzID, image, imageSrc sql.NullString
-	var variousArtists int
+	var mbzID, rgMbzID, image, imageSrc sql.NullString
+	var variousArtists, single int
 	err := s.db.QueryRowContext(ctx, `
-		SELECT id, musicbrainz_id, image, image_source, various_artists, title
+		SELECT id, musicbrainz_id, release_group_mbid, image, image_source, various_artists, is_single, title
 		FROM releases_with_title WHERE id = ? LIMIT 1`, id).
-		Scan(&ret.ID, &mbzID, &image, &imageSrc, &variousArtists, &ret.Title)
+		Scan(&ret.ID, &mbzID, &rgMbzID, &image, &imageSrc, &variousArtists, &single, &ret.Title)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("getAlbumByID: %w", db.ErrNotFound)
 	}
@@ -70,8 +81,11 @@ func (s *Sqlite) getAlbumByID(ctx context.Context, id int32) (*models.Album, err
 		return nil, fmt.Errorf("getAlbumByID: %w", err)
 	}
 	ret.MbzID = parseNullableUUID(mbzID)
+	ret.ReleaseGroupMbzID = parseNullableUUID(rgMbzID)
 	ret.Image = catalog.BuildImageList(parseNullableUUID(image))
+	ret.ImageIsFallback = imageSrc.String == catalog.ImageSourceArtistFallback
 	ret.VariousArtists = variousArtists == 1
+	ret.IsSingle = single == 1
 
 	artists, err := s.artistsForRelease(ctx, id)
 	if err != nil {
@@ -79,6 +93,10 @@ func (s *Sqlite) getAlbumByID(ctx context.Context, id int32) (*models.Album, err
 	}
 	ret.Artists = artists
 
+	if ret.Labels, err = s.labelsForRelease(ctx, id); err != nil {
+		return nil, fmt.Errorf("getAlbumByID: labels: %w", err)
+	}
+
 	var listenCount int64
 	s.db.QueryRowContext(ctx, `
 		SELECT COUNT(*) FROM listens l JOIN tracks t ON l.track_id = t.id WHERE t.release_id = ?`,
@@ -173,8 +191,8 @@ func (s *Sqlite) SaveAlbum(ctx context.Context, opts db.SaveAlbumOpts) (*models.
 		variousArtistsInt = 1
 	}
 	res, err := tx.ExecContext(ctx,
-		`INSERT INTO releases (musicbrainz_id, various_artists, image, image_source) VALUES (?,?,?,?)`,
-		nullableUUID(&opts.MusicBrainzID), variousArtistsInt,
+		`INSERT INTO releases (musicbrainz_id, release_group_mbid, various_artists, image, image_source) VALUES (?,?,?,?,?)`,
+		nullableUUID(&opts.MusicBrainzID), nullableUUID(&opts.ReleaseGroupMbzID), variousArtistsInt,
 		nullableUUID(&opts.Image),
 		sql.NullString{String: opts.ImageSrc, Valid: opts.ImageSrc != ""},
 	)
@@ -198,7 +216,7 @@ func (s *Sqlite) SaveAlbum(ctx context.Context, opts db.SaveAlbumOpts) (*models.
 
 	if _, err := tx.ExecContext(ctx,
 		`INSERT INTO release_aliases (release_id, alias, source, is_primary) VALUES (?,?,?,1)`,
-		id, opts.Title, "Canonical"); err != nil {
+		id, opts.Title, models.AliasSourceCanonical); err != nil {
 		return nil, fmt.Errorf("SaveAlbum: canonical alias: %w", err)
 	}
 
@@ -207,7 +225,11 @@ func (s *Sqlite) SaveAlbum(ctx context.Context, opts db.SaveAlbumOpts) (*models.
 	}
 
 	if len(opts.Aliases) > 0 {
-		s.SaveAlbumAliases(ctx, id, opts.Aliases, "MusicBrainz")
+		source := opts.AliasSource
+		if source == "" {
+			source = models.AliasSourceMusicBrainz
+		}
+		s.SaveAlbumAliases(ctx, id, opts.Aliases, source)
 	}
 
 	ret := &models.Album{
@@ -219,6 +241,10 @@ func (s *Sqlite) SaveAlbum(ctx context.Context, opts db.SaveAlbumOpts) (*models.
 		u := opts.MusicBrainzID
 		ret.MbzID = &u
 	}
+	if opts.ReleaseGroupMbzID != uuid.Nil {
+		u := opts.ReleaseGroupMbzID
+		ret.ReleaseGroupMbzID = &u
+	}
 	if opts.Image != uuid.Nil {
 		u := opts.Image
 		ret.Image = catalog.BuildImageList(&u)
@@ -279,6 +305,13 @@ func (s *Sqlite) UpdateAlbum(ctx context.Context, opts db.UpdateAlbumOpts) error
 			return fmt.Errorf("UpdateAlbum: mbzid: %w", err)
 		}
 	}
+	if opts.ReleaseGroupMbzID != uuid.Nil {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE releases SET release_group_mbid = ? WHERE id = ?`,
+			opts.ReleaseGroupMbzID.String(), opts.ID); err != nil {
+			return fmt.Errorf("UpdateAlbum: release group mbzid: %w", err)
+		}
+	}
 	if opts.Image != uuid.Nil {
 		if opts.ImageSrc == "" {
 			return errors.New("UpdateAlbum: image source must be provided when updating an image")
@@ -299,6 +332,16 @@ func (s *Sqlite) UpdateAlbum(ctx context.Context, opts db.UpdateAlbumOpts) error
 			return fmt.Errorf("UpdateAlbum: various_artists: %w", err)
 		}
 	}
+	if opts.SingleUpdate {
+		v := 0
+		if opts.SingleValue {
+			v = 1
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE releases SET is_single = ? WHERE id = ?`, v, opts.ID); err != nil {
+			return fmt.Errorf("UpdateAlbum: is_single: %w", err)
+		}
+	}
 	return tx.Commit()
 }
 
@@ -406,6 +449,11 @@ func (s *Sqlite) GetTopAlbumsPaginated(ctx context.Context, opts db.GetItemsOpts
 	}
 	offset := (opts.Page - 1) * opts.Limit
 	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	score, scoreArgs := chartScore(opts)
+	excludeSingles := 0
+	if opts.ExcludeSingles {
+		excludeSingles = 1
+	}
 
 	var rows *sql.Rows
 	var err error
@@ -413,50 +461,56 @@ func (s *Sqlite) GetTopAlbumsPaginated(ctx context.Context, opts db.GetItemsOpts
 	if opts.ArtistID != 0 {
 		query := `
 			WITH AlbumCounts AS (
-				SELECT t.release_id, COUNT(*) AS listen_count
-				FROM listens l
+				SELECT t.release_id, COUNT(*) AS listen_count, ` + score + ` AS score
+				FROM user_listens l
 				JOIN tracks t ON l.track_id = t.id
 				JOIN artist_releases ar ON t.release_id = ar.release_id
-				WHERE ar.artist_id = ? AND l.listened_at BETWEEN ? AND ?
+				JOIN releases rel ON rel.id = t.release_id
+				WHERE ar.artist_id = ? AND l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)
+					AND (? = 0 OR rel.is_single = 0)
+					AND (? = '' OR t.release_id IN (SELECT release_id FROM release_labels WHERE label = ? COLLATE NOCASE))
 				GROUP BY t.release_id
 			),
 			RankedAlbums AS (
 				SELECT release_id, listen_count,
-					   RANK() OVER (ORDER BY listen_count DESC) AS rank,
+					   RANK() OVER (ORDER BY score DESC) AS rank,
 					   COUNT(*) OVER () AS total_count
 				FROM AlbumCounts
-				ORDER BY listen_count DESC, release_id
+				ORDER BY score DESC, release_id
 				LIMIT ? OFFSET ?
 			)
-			SELECT r.release_id, rwt.title, rwt.musicbrainz_id, rwt.image, rwt.various_artists, r.listen_count, r.rank, r.total_count
+			SELECT r.release_id, rwt.title, rwt.musicbrainz_id, rwt.image, rwt.various_artists, rwt.is_single, r.listen_count, r.rank, r.total_count
 			FROM RankedAlbums r
 			JOIN releases_with_title rwt ON rwt.id = r.release_id
 			ORDER BY r.rank, r.release_id`
 
-		rows, err = s.db.QueryContext(ctx, query, opts.ArtistID, t1.Unix(), t2.Unix(), opts.Limit, offset)
+		rows, err = s.db.QueryContext(ctx, query, append(scoreArgs, opts.ArtistID, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, excludeSingles, opts.Label, opts.Label, opts.Limit, offset)...)
 	} else {
 		query := `
 			WITH AlbumCounts AS (
-				SELECT t.release_id, COUNT(*) AS listen_count
-				FROM listens l
+				SELECT t.release_id, COUNT(*) AS listen_count, ` + score + ` AS score
+				FROM user_listens l
 				JOIN tracks t ON l.track_id = t.id
-				WHERE l.listened_at BETWEEN ? AND ?
+				JOIN releases rel ON rel.id = t.release_id
+				WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)
+					AND (? = 0 OR rel.is_single = 0)
+					AND (? = '' OR t.release_id IN (SELECT release_id FROM release_labels WHERE label = ? COLLATE NOCASE))
 				GROUP BY t.release_id
 			),
 			RankedAlbums AS (
 				SELECT release_id, listen_count,
-					   RANK() OVER (ORDER BY listen_count DESC) AS rank,
+					   RANK() OVER (ORDER BY score DESC) AS rank,
 					   COUNT(*) OVER () AS total_count
 				FROM AlbumCounts
-				ORDER BY listen_count DESC, release_id
+				ORDER BY score DESC, release_id
 				LIMIT ? OFFSET ?
 			)
-			SELECT r.release_id, rwt.title, rwt.musicbrainz_id, rwt.image, rwt.various_artists, r.listen_count, r.rank, r.total_count
+			SELECT r.release_id, rwt.title, rwt.musicbrainz_id, rwt.image, rwt.various_artists, rwt.is_single, r.listen_count, r.rank, r.total_count
 			FROM RankedAlbums r
 			JOIN releases_with_title rwt ON rwt.id = r.release_id
 			ORDER BY r.rank, r.release_id`
 
-		rows, err = s.db.QueryContext(ctx, query, t1.Unix(), t2.Unix(), opts.Limit, offset)
+		rows, err = s.db.QueryContext(ctx, query, append(scoreArgs, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, excludeSingles, opts.Label, opts.Label, opts.Limit, offset)...)
 	}
 
 	if err != nil {
@@ -471,23 +525,17 @@ func (s *Sqlite) GetTopAlbumsPaginated(ctx context.Context, opts db.GetItemsOpts
 	for rows.Next() {
 		var a models.Album
 		var mbzID, image sql.NullString
-		var variousArtists int
+		var variousArtists, single int
 		var item db.RankedItem[*models.Album]
 
-		if err := rows.Scan(&a.ID, &a.Title, &mbzID, &image, &variousArtists, &a.ListenCount, &item.Rank, &totalCount); err != nil {
+		if err := rows.Scan(&a.ID, &a.Title, &mbzID, &image, &variousArtists, &single, &a.ListenCount, &item.Rank, &totalCount); err != nil {
 			return nil, err
 		}
 
 		a.MbzID = parseNullableUUID(mbzID)
 		a.Image = catalog.BuildImageList(parseNullableUUID(image))
 		a.VariousArtists = variousArtists == 1
-
-		// Fetch artists for the release (Note: This is still an N+1 query.
-		// If performance allows in the future, consider batching this or using JSON_GROUP_ARRAY in SQL).
-		a.Artists, err = s.artistsForRelease(ctx, a.ID)
-		if err != nil {
-			return nil, err
-		}
+		a.IsSingle = single == 1
 
 		item.Item = &a
 		albums = append(albums, item)
@@ -496,6 +544,17 @@ func (s *Sqlite) GetTopAlbumsPaginated(ctx context.Context, opts db.GetItemsOpts
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	// close before the artist lookups so they don't wait on this connection
+	rows.Close()
+
+	for _, item := range albums {
+		// Fetch artists for the release (Note: This is still an N+1 query.
+		// If performance allows in the future, consider batching this or using JSON_GROUP_ARRAY in SQL).
+		item.Item.Artists, err = s.artistsForRelease(ctx, item.Item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &db.PaginatedResponse[db.RankedItem[*models.Album]]{
 		Items:        albums,
@@ -510,9 +569,9 @@ func (s *Sqlite) AlbumsWithoutImages(ctx context.Context, from int32) ([]*models
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, musicbrainz_id, image, image_source, various_artists, title
 		FROM releases_with_title
-		WHERE image IS NULL AND id > ?
+		WHERE (image IS NULL OR image_source = ?) AND id > ?
 		ORDER BY id ASC LIMIT 20`,
-		from)
+		catalog.ImageSourceArtistFallback, from)
 	if err != nil {
 		return nil, fmt.Errorf("AlbumsWithoutImages: %w", err)
 	}
@@ -530,6 +589,7 @@ func (s *Sqlite) AlbumsWithoutImages(ctx context.Context, from int32) ([]*models
 		}
 		r.album.MbzID = parseNullableUUID(mbzID)
 		r.album.Image = catalog.BuildImageList(parseNullableUUID(image))
+		r.album.ImageIsFallback = imageSrc.String == catalog.ImageSourceArtistFallback
 		raw = append(raw, r)
 	}
 	if err := rows.Close(); err != nil {
@@ -551,19 +611,54 @@ func (s *Sqlite) AlbumsWithoutImages(ctx context.Context, from int32) ([]*models
 	return albums, rows.Err()
 }
 
-func (s *Sqlite) MergeAlbums(ctx context.Context, fromId, toId int32, replaceImage bool) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+// ReleaseGroupAlbumsWithoutImages returns the albums in a release group that have no image,
+// or only a fallback image copied from their artist.
+func (s *Sqlite) ReleaseGroupAlbumsWithoutImages(ctx context.Context, releaseGroupMbzID uuid.UUID) ([]*models.Album, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, musicbrainz_id, image, image_source, various_artists, title
+		FROM releases_with_title
+		WHERE release_group_mbid = ? AND (image IS NULL OR image_source = ?)
+		ORDER BY id ASC`,
+		releaseGroupMbzID.String(), catalog.ImageSourceArtistFallback)
 	if err != nil {
-		return fmt.Errorf("MergeAlbums: BeginTx: %w", err)
+		return nil, fmt.Errorf("ReleaseGroupAlbumsWithoutImages: %w", err)
 	}
-	defer tx.Rollback()
+	defer rows.Close()
 
-	// fetch artists from fromId before moving tracks (for re-association)
+	albums := make([]*models.Album, 0)
+	for rows.Next() {
+		a := &models.Album{ReleaseGroupMbzID: &releaseGroupMbzID}
+		var mbzID, image, imageSrc sql.NullString
+		var variousArtists int
+		if err := rows.Scan(&a.ID, &mbzID, &image, &imageSrc, &variousArtists, &a.Title); err != nil {
+			return nil, fmt.Errorf("ReleaseGroupAlbumsWithoutImages: scan: %w", err)
+		}
+		a.MbzID = parseNullableUUID(mbzID)
+		a.Image = catalog.BuildImageList(parseNullableUUID(image))
+		a.ImageIsFallback = imageSrc.String == catalog.ImageSourceArtistFallback
+		a.VariousArtists = variousArtists == 1
+		albums = append(albums, a)
+	}
+	return albums, rows.Err()
+}
+
+func (s *Sqlite) MergeAlbums(ctx context.Context, fromId, toId int32, replaceImage bool) error {
+	if fromId == toId {
+		return errors.New("MergeAlbums: cannot merge an album into itself")
+	}
+	// fetch artists from fromId before moving tracks (for re-association), outside of
+	// the transaction so that it does not wait on the connection the transaction holds
 	fromArtists, err := s.artistsForRelease(ctx, fromId)
 	if err != nil {
 		return fmt.Errorf("MergeAlbums: fetch from artists: %w", err)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("MergeAlbums: BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
 	if replaceImage {
 		var image, imageSrc sql.NullString
 		tx.QueryRowContext(ctx, `SELECT image, image_source FROM releases WHERE id = ?`, fromId).
@@ -577,6 +672,15 @@ func (s *Sqlite) MergeAlbums(ctx context.Context, fromId, toId int32, replaceIma
 		}
 	}
 
+	duplicates, err := duplicateMergedTracks(ctx, tx, fromId, toId)
+	if err != nil {
+		return fmt.Errorf("MergeAlbums: %w", err)
+	}
+	for from, to := range duplicates {
+		if err := mergeDuplicateTrack(ctx, tx, from, to); err != nil {
+			return fmt.Errorf("MergeAlbums: %w", err)
+		}
+	}
 	if _, err := tx.ExecContext(ctx,
 		`UPDATE tracks SET release_id = ? WHERE release_id = ?`, toId, fromId); err != nil {
 		return fmt.Errorf("MergeAlbums: move tracks: %w", err)
@@ -596,14 +700,84 @@ func (s *Sqlite) MergeAlbums(ctx context.Context, fromId, toId int32, replaceIma
 	return tx.Commit()
 }
 
-func (s *Sqlite) CountAlbums(ctx context.Context, timeframe db.Timeframe) (int64, error) {
-	t1, t2 := db.TimeframeToTimeRange(timeframe)
+// duplicateMergedTracks maps each track of the release being merged to the track of the release it is
+// merged into that has the same title, ignoring case, accents and punctuation.
+func duplicateMergedTracks(ctx context.Context, tx *sql.Tx, fromId, toId int32) (map[int32]int32, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, title, release_id FROM tracks_with_title WHERE release_id IN (?, ?) ORDER BY id`, fromId, toId)
+	if err != nil {
+		return nil, fmt.Errorf("duplicateMergedTracks: %w", err)
+	}
+	defer rows.Close()
+	// the normalized titles of the tracks of each release
+	kept := make(map[string]int32)
+	merged := make(map[int32]string)
+	for rows.Next() {
+		var id, releaseID int32
+		var title string
+		if err := rows.Scan(&id, &title, &releaseID); err != nil {
+			return nil, fmt.Errorf("duplicateMergedTracks: %w", err)
+		}
+		key := utils.NormalizeForMatching(title)
+		if releaseID == fromId {
+			merged[id] = key
+		} else if _, ok := kept[key]; !ok {
+			kept[key] = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("duplicateMergedTracks: %w", err)
+	}
+	ret := make(map[int32]int32)
+	for id, key := range merged {
+		if to, ok := kept[key]; ok {
+			ret[id] = to
+		}
+	}
+	return ret, nil
+}
+
+// mergeDuplicateTrack merges a track into another one with the same title, moving its listens, tags,
+// aliases and artists. Listens at the same time as a listen to the other track are dropped, as they
+// are the same listen.
+func mergeDuplicateTrack(ctx context.Context, tx *sql.Tx, fromId, toId int32) error {
+	for _, stmt := range []string{
+		`UPDATE OR IGNORE all_listens SET track_id = ?1 WHERE track_id = ?2`,
+		`DELETE FROM all_listens WHERE track_id = ?2`,
+		`INSERT OR IGNORE INTO track_tags (track_id, tag) SELECT ?1, tag FROM track_tags WHERE track_id = ?2`,
+		`INSERT OR IGNORE INTO track_aliases (track_id, alias, source, is_primary) SELECT ?1, alias, source, 0 FROM track_aliases WHERE track_id = ?2`,
+		`INSERT OR IGNORE INTO artist_tracks (artist_id, track_id, is_primary) SELECT artist_id, ?1, 0 FROM artist_tracks WHERE track_id = ?2`,
+		`UPDATE tracks SET duration = (SELECT duration FROM tracks WHERE id = ?2) WHERE id = ?1 AND duration = 0`,
+		`DELETE FROM tracks WHERE id = ?2`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, toId, fromId); err != nil {
+			return fmt.Errorf("mergeDuplicateTrack: %w", err)
+		}
+	}
+	return nil
+}
+
+// CountAlbumsWithoutImages returns the number of albums AlbumsWithoutImages returns over all pages.
+func (s *Sqlite) CountAlbumsWithoutImages(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM releases_with_title
+		WHERE image IS NULL OR image_source = ?`,
+		catalog.ImageSourceArtistFallback).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountAlbumsWithoutImages: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Sqlite) CountAlbums(ctx context.Context, opts db.CountOpts) (int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
 	var count int64
 	err := s.db.QueryRowContext(ctx, `
 		SELECT COUNT(DISTINCT t.release_id)
-		FROM listens l JOIN tracks t ON l.track_id = t.id
-		WHERE l.listened_at BETWEEN ? AND ?`,
-		t1.Unix(), t2.Unix()).Scan(&count)
+		FROM user_listens l JOIN tracks t ON l.track_id = t.id
+		WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID).Scan(&count)
 	return count, err
 }
 
@@ -619,3 +793,157 @@ func (s *Sqlite) CountNewAlbums(ctx context.Context, timeframe db.Timeframe) (in
 		t1.Unix(), t2.Unix()).Scan(&count)
 	return count, err
 }
+
+func (s *Sqlite) GetUserTopAlbums(ctx context.Context, opts db.GetUserTopItemsOpts) ([]db.UserTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	excludePrivate := 0
+	if opts.ExcludePrivate {
+		excludePrivate = 1
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.release_id, rwt.title, COUNT(*) AS listen_count
+		FROM user_listens l
+		JOIN tracks t ON t.id = l.track_id
+		JOIN releases_with_title rwt ON rwt.id = t.release_id
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ? AND (? = 0 OR l.private = 0)
+		GROUP BY t.release_id
+		ORDER BY listen_count DESC, t.release_id
+		LIMIT ?`,
+		opts.UserID, t1.Unix(), t2.Unix(), excludePrivate, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTopAlbums: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.UserTopItem, 0, opts.Limit)
+	for rows.Next() {
+		var item db.UserTopItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Listens); err != nil {
+			return nil, fmt.Errorf("GetUserTopAlbums: scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetArtistTopAlbums returns the most listened to albums by the artist within the timeframe,
+// optionally limited to the listens of a single user.
+func (s *Sqlite) GetArtistTopAlbums(ctx context.Context, opts db.GetArtistTopItemsOpts) ([]db.ArtistTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	excludeSingles := 0
+	if opts.ExcludeSingles {
+		excludeSingles = 1
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.release_id, rwt.title, COUNT(*) AS listen_count, MAX(l.listened_at) AS last_listened_at
+		FROM user_listens l
+		JOIN tracks t ON t.id = l.track_id
+		JOIN releases_with_title rwt ON rwt.id = t.release_id
+		JOIN artist_releases ar ON ar.release_id = t.release_id
+		WHERE ar.artist_id = ? AND l.listened_at BETWEEN ? AND ?
+			AND ((? = 0 AND l.private = 0) OR l.user_id = ?)
+			AND (? = 0 OR rwt.is_single = 0)
+		GROUP BY t.release_id
+		ORDER BY listen_count DESC, last_listened_at DESC, t.release_id
+		LIMIT ?`,
+		opts.ArtistID, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, excludeSingles, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopAlbums: %w", err)
+	}
+	defer rows.Close()
+
+	items, err := scanArtistTopItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopAlbums: %w", err)
+	}
+	return items, nil
+}
+
+// CountAlbumTracks returns the number of tracks on the album.
+func (s *Sqlite) CountAlbumTracks(ctx context.Context, id int32) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tracks WHERE release_id = ?`, id).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountAlbumTracks: %w", err)
+	}
+	return count, nil
+}
+
+// GetAlbumTitles returns the title of every album by the artist, or of every album when artistID
+// is 0, with one entry for each of the album's artists.
+func (s *Sqlite) GetAlbumTitles(ctx context.Context, artistID int32) ([]db.AlbumTitle, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT r.id, ar.artist_id, r.title, r.musicbrainz_id
+		FROM releases_with_title r
+		JOIN artist_releases ar ON ar.release_id = r.id
+		WHERE ? = 0 OR ar.artist_id = ?
+		ORDER BY r.id ASC`, artistID, artistID)
+	if err != nil {
+		return nil, fmt.Errorf("GetAlbumTitles: %w", err)
+	}
+	defer rows.Close()
+	var titles []db.AlbumTitle
+	for rows.Next() {
+		var t db.AlbumTitle
+		var mbzID sql.NullString
+		if err := rows.Scan(&t.AlbumID, &t.ArtistID, &t.Title, &mbzID); err != nil {
+			return nil, fmt.Errorf("GetAlbumTitles: %w", err)
+		}
+		t.MbzID = parseNullableUUID(mbzID)
+		titles = append(titles, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetAlbumTitles: %w", err)
+	}
+	return titles, nil
+}
+
+// SaveAlbumLabels saves the labels the album was released under, ignoring any it already has.
+func (s *Sqlite) SaveAlbumLabels(ctx context.Context, id int32, labels []models.AlbumLabel) error {
+	if id == 0 {
+		return errors.New("SaveAlbumLabels: album id not specified")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("SaveAlbumLabels: BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+	for _, label := range labels {
+		name := strings.TrimSpace(label.Name)
+		if name == "" {
+			return errors.New("SaveAlbumLabels: label name cannot be blank")
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO release_labels (release_id, label, catalog_number, label_mbid) VALUES (?, ?, ?, ?)`,
+			id, name, strings.TrimSpace(label.CatalogNumber), nullableUUID(label.MbzID)); err != nil {
+			return fmt.Errorf("SaveAlbumLabels: insert: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Sqlite) labelsForRelease(ctx context.Context, id int32) ([]models.AlbumLabel, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT label, catalog_number, label_mbid FROM release_labels WHERE release_id = ? ORDER BY label, catalog_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var labels []models.AlbumLabel
+	for rows.Next() {
+		var label models.AlbumLabel
+		var mbzID sql.NullString
+		if err := rows.Scan(&label.Name, &label.CatalogNumber, &mbzID); err != nil {
+			return nil, err
+		}
+		label.MbzID = parseNullableUUID(mbzID)
+		labels = append(labels, label)
+	}
+	return labels, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
@@ -28,8 +29,14 @@ func (s *Sqlite) GetArtist(ctx context.Context, opts db.GetArtistOpts) (*models.
 		opts.ID = id
 	} else if opts.Name != "" {
 		var id int32
-		err := s.db.QueryRowContext(ctx,
-			`SELECT artist_id FROM artist_aliases WHERE alias = ? LIMIT 1`, opts.Name).Scan(&id)
+		// names match regardless of case, preferring an exact match. an alias may be shared by several
+		// artists, so then prefer the one with a MusicBrainz ID, then the most listened to
+		err := s.db.QueryRowContext(ctx, `
+			SELECT aa.artist_id FROM artist_aliases aa
+			JOIN artists a ON a.id = aa.artist_id
+			WHERE aa.alias = ? COLLATE NOCASE
+			ORDER BY aa.alias = ? DESC, a.musicbrainz_id IS NULL, `+artistListenCountExpr+` DESC, aa.artist_id
+			LIMIT 1`, opts.Name, opts.Name).Scan(&id)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("GetArtist: by name: %w", db.ErrNotFound)
 		}
@@ -37,6 +44,17 @@ func (s *Sqlite) GetArtist(ctx context.Context, opts db.GetArtistOpts) (*models.
 			return nil, fmt.Errorf("GetArtist: by name: %w", err)
 		}
 		opts.ID = id
+	} else if opts.Image != uuid.Nil {
+		var id int32
+		err := s.db.QueryRowContext(ctx,
+			`SELECT id FROM artists WHERE image = ? ORDER BY id LIMIT 1`, opts.Image.String()).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("GetArtist: by image: %w", db.ErrNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("GetArtist: by image: %w", err)
+		}
+		opts.ID = id
 	}
 
 	var mbzID, image, imageSrc sql.NullString
@@ -134,7 +152,7 @@ func (s *Sqlite) SaveArtist(ctx context.Context, opts db.SaveArtistOpts) (*model
 
 	if _, err := tx.ExecContext(ctx,
 		`INSERT INTO artist_aliases (artist_id, alias, source, is_primary) VALUES (?,?,?,1)`,
-		id, opts.Name, "Canonical"); err != nil {
+		id, opts.Name, models.AliasSourceCanonical); err != nil {
 		return nil, fmt.Errorf("SaveArtist: canonical alias: %w", err)
 	}
 
@@ -153,7 +171,11 @@ func (s *Sqlite) SaveArtist(ctx context.Context, opts db.SaveArtistOpts) (*model
 	}
 
 	if len(opts.Aliases) > 0 {
-		if err := s.SaveArtistAliases(ctx, id, opts.Aliases, "MusicBrainz"); err != nil {
+		source := opts.AliasSource
+		if source == "" {
+			source = models.AliasSourceMusicBrainz
+		}
+		if err := s.SaveArtistAliases(ctx, id, opts.Aliases, source); err != nil {
 			return nil, fmt.Errorf("SaveArtist: SaveArtistAliases: %w", err)
 		}
 		artist.Aliases = opts.Aliases
@@ -373,23 +395,24 @@ func (s *Sqlite) GetTopArtistsPaginated(ctx context.Context, opts db.GetItemsOpt
 	}
 	offset := (opts.Page - 1) * opts.Limit
 	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	score, scoreArgs := chartScore(opts)
 
 	// Unified query using CTEs, deferred joins, and a total_count window function
 	query := `
 		WITH ArtistCounts AS (
-			SELECT at2.artist_id, COUNT(*) AS listen_count
-			FROM listens l
+			SELECT at2.artist_id, COUNT(*) AS listen_count, ` + score + ` AS score
+			FROM user_listens l
 			JOIN artist_tracks at2 ON l.track_id = at2.track_id
-			WHERE l.listened_at BETWEEN ? AND ?
+			WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)
 			GROUP BY at2.artist_id
 		),
 		RankedArtists AS (
 			SELECT artist_id,
 			       listen_count,
-			       RANK() OVER (ORDER BY listen_count DESC) AS rank,
+			       RANK() OVER (ORDER BY score DESC) AS rank,
 			       COUNT(*) OVER () AS total_count
 			FROM ArtistCounts
-			ORDER BY listen_count DESC, artist_id
+			ORDER BY score DESC, artist_id
 			LIMIT ? OFFSET ?
 		)
 		SELECT r.artist_id, awn.name, a.musicbrainz_id, a.image, r.listen_count, r.rank, r.total_count
@@ -398,7 +421,7 @@ func (s *Sqlite) GetTopArtistsPaginated(ctx context.Context, opts db.GetItemsOpt
 		JOIN artists_with_name awn ON awn.id = r.artist_id
 		ORDER BY r.rank, r.artist_id`
 
-	rows, err := s.db.QueryContext(ctx, query, t1.Unix(), t2.Unix(), opts.Limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, append(scoreArgs, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, opts.Limit, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("GetTopArtistsPaginated: %w", err)
 	}
@@ -437,6 +460,194 @@ func (s *Sqlite) GetTopArtistsPaginated(ctx context.Context, opts db.GetItemsOpt
 	}, nil
 }
 
+func (s *Sqlite) GetUserTopArtists(ctx context.Context, opts db.GetUserTopItemsOpts) ([]db.UserTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	excludePrivate := 0
+	if opts.ExcludePrivate {
+		excludePrivate = 1
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at2.artist_id, awn.name, COUNT(*) AS listen_count
+		FROM user_listens l
+		JOIN artist_tracks at2 ON l.track_id = at2.track_id
+		JOIN artists_with_name awn ON awn.id = at2.artist_id
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ? AND (? = 0 OR l.private = 0)
+		GROUP BY at2.artist_id
+		ORDER BY listen_count DESC, at2.artist_id
+		LIMIT ?`,
+		opts.UserID, t1.Unix(), t2.Unix(), excludePrivate, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTopArtists: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.UserTopItem, 0, opts.Limit)
+	for rows.Next() {
+		var item db.UserTopItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Listens); err != nil {
+			return nil, fmt.Errorf("GetUserTopArtists: scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetUserNewArtists returns the artists the user first listened to within the timeframe, ordered by
+// the user's listens to them within the timeframe. A negative limit returns every artist.
+func (s *Sqlite) GetUserNewArtists(ctx context.Context, opts db.GetUserTopItemsOpts) ([]db.UserTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at2.artist_id, awn.name, COUNT(*) AS listen_count
+		FROM user_listens l
+		JOIN artist_tracks at2 ON l.track_id = at2.track_id
+		JOIN artists_with_name awn ON awn.id = at2.artist_id
+		WHERE l.user_id = ? AND l.listened_at <= ?
+		GROUP BY at2.artist_id
+		HAVING MIN(l.listened_at) >= ?
+		ORDER BY listen_count DESC, at2.artist_id
+		LIMIT ?`,
+		opts.UserID, t2.Unix(), t1.Unix(), opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserNewArtists: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.UserTopItem, 0)
+	for rows.Next() {
+		var item db.UserTopItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Listens); err != nil {
+			return nil, fmt.Errorf("GetUserNewArtists: scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// artistListenCountExpr counts the listens of the artist with id aa.artist_id, for ordering aliases
+const artistListenCountExpr = `(SELECT COUNT(*) FROM listens l JOIN artist_tracks at2 ON l.track_id = at2.track_id WHERE at2.artist_id = aa.artist_id)`
+
+// CountArtistsWithAlias returns the number of artists that have the alias, in any casing.
+func (s *Sqlite) CountArtistsWithAlias(ctx context.Context, alias string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(DISTINCT artist_id) FROM artist_aliases WHERE alias = ? COLLATE NOCASE`, alias).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountArtistsWithAlias: %w", err)
+	}
+	return count, nil
+}
+
+// GetAmbiguousArtistAliases returns every alias shared by more than one artist, with the artists
+// ordered the same way GetArtist chooses between them.
+func (s *Sqlite) GetAmbiguousArtistAliases(ctx context.Context) ([]db.AmbiguousAlias, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT aa.alias, aa.artist_id, awn.name, awn.musicbrainz_id, `+artistListenCountExpr+` AS listen_count
+		FROM artist_aliases aa
+		JOIN artists_with_name awn ON awn.id = aa.artist_id
+		WHERE aa.alias IN (SELECT alias FROM artist_aliases GROUP BY alias HAVING COUNT(*) > 1)
+		ORDER BY aa.alias, awn.musicbrainz_id IS NULL, listen_count DESC, aa.artist_id`)
+	if err != nil {
+		return nil, fmt.Errorf("GetAmbiguousArtistAliases: %w", err)
+	}
+	defer rows.Close()
+
+	ret := make([]db.AmbiguousAlias, 0)
+	for rows.Next() {
+		var alias string
+		var artist db.AmbiguousAliasArtist
+		var mbzID sql.NullString
+		if err := rows.Scan(&alias, &artist.ID, &artist.Name, &mbzID, &artist.ListenCount); err != nil {
+			return nil, fmt.Errorf("GetAmbiguousArtistAliases: scan: %w", err)
+		}
+		artist.MbzID = parseNullableUUID(mbzID)
+		if len(ret) == 0 || ret[len(ret)-1].Alias != alias {
+			ret = append(ret, db.AmbiguousAlias{Alias: alias})
+		}
+		ret[len(ret)-1].Artists = append(ret[len(ret)-1].Artists, artist)
+	}
+	return ret, rows.Err()
+}
+
+// GetNeglectedArtists returns artists with at least MinListens listens whose most recent listen is
+// before the cutoff, ordered by listen count.
+func (s *Sqlite) GetNeglectedArtists(ctx context.Context, opts db.GetNeglectedArtistsOpts) ([]db.NeglectedArtist, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at2.artist_id, awn.name, awn.image, COUNT(*) AS listen_count, MAX(l.listened_at) AS last_listened_at
+		FROM user_listens l
+		JOIN artist_tracks at2 ON l.track_id = at2.track_id
+		JOIN artists_with_name awn ON awn.id = at2.artist_id
+		WHERE ((? = 0 AND l.private = 0) OR l.user_id = ?)
+		GROUP BY at2.artist_id
+		HAVING last_listened_at < ? AND listen_count >= ?
+		ORDER BY listen_count DESC, last_listened_at DESC, at2.artist_id
+		LIMIT ?`,
+		opts.UserID, opts.UserID, opts.Before.Unix(), opts.MinListens, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetNeglectedArtists: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.NeglectedArtist, 0)
+	for rows.Next() {
+		var item db.NeglectedArtist
+		var image sql.NullString
+		var lastListened int64
+		if err := rows.Scan(&item.ID, &item.Name, &image, &item.Listens, &lastListened); err != nil {
+			return nil, fmt.Errorf("GetNeglectedArtists: scan: %w", err)
+		}
+		item.Image = catalog.BuildImageList(parseNullableUUID(image))
+		item.LastListenedAt = time.Unix(lastListened, 0)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetFavoriteArtists returns the artists listened to on at least MinDays distinct days within the
+// timeframe, ranked by their listen count multiplied by the number of days they were listened to on.
+func (s *Sqlite) GetFavoriteArtists(ctx context.Context, opts db.GetFavoriteArtistsOpts) ([]db.FavoriteArtist, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at2.artist_id, awn.name, awn.image, COUNT(*) AS listen_count,
+		       COUNT(DISTINCT (l.listened_at + ?) / 86400) AS days
+		FROM user_listens l
+		JOIN artist_tracks at2 ON l.track_id = at2.track_id
+		JOIN artists_with_name awn ON awn.id = at2.artist_id
+		WHERE ((? = 0 AND l.private = 0) OR l.user_id = ?) AND l.listened_at BETWEEN ? AND ?
+		GROUP BY at2.artist_id
+		HAVING days >= ?
+		ORDER BY listen_count * days DESC, days DESC, at2.artist_id
+		LIMIT ?`,
+		tzOffset(opts.Timezone), opts.UserID, opts.UserID, t1.Unix(), t2.Unix(), opts.MinDays, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetFavoriteArtists: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.FavoriteArtist, 0)
+	for rows.Next() {
+		var item db.FavoriteArtist
+		var image sql.NullString
+		if err := rows.Scan(&item.ID, &item.Name, &image, &item.Listens, &item.Days); err != nil {
+			return nil, fmt.Errorf("GetFavoriteArtists: scan: %w", err)
+		}
+		item.Image = catalog.BuildImageList(parseNullableUUID(image))
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
 func (s *Sqlite) ArtistsWithoutImages(ctx context.Context, from int32) ([]*models.Artist, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, musicbrainz_id, image, image_source, name
@@ -462,6 +673,16 @@ func (s *Sqlite) ArtistsWithoutImages(ctx context.Context, from int32) ([]*model
 	return artists, rows.Err()
 }
 
+// CountArtistsWithoutImages returns the number of artists ArtistsWithoutImages returns over all pages.
+func (s *Sqlite) CountArtistsWithoutImages(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM artists_with_name WHERE image IS NULL`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountArtistsWithoutImages: %w", err)
+	}
+	return count, nil
+}
+
 func (s *Sqlite) SetPrimaryAlbumArtist(ctx context.Context, id int32, artistId int32, value bool) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -530,14 +751,14 @@ func (s *Sqlite) SetPrimaryTrackArtist(ctx context.Context, id int32, artistId i
 	return tx.Commit()
 }
 
-func (s *Sqlite) CountArtists(ctx context.Context, timeframe db.Timeframe) (int64, error) {
-	t1, t2 := db.TimeframeToTimeRange(timeframe)
+func (s *Sqlite) CountArtists(ctx context.Context, opts db.CountOpts) (int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
 	var count int64
 	err := s.db.QueryRowContext(ctx, `
 		SELECT COUNT(DISTINCT at2.artist_id)
-		FROM listens l JOIN artist_tracks at2 ON l.track_id = at2.track_id
-		WHERE l.listened_at BETWEEN ? AND ?`,
-		t1.Unix(), t2.Unix()).Scan(&count)
+		FROM user_listens l JOIN artist_tracks at2 ON l.track_id = at2.track_id
+		WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID).Scan(&count)
 	return count, err
 }
 
@@ -558,6 +779,9 @@ func (s *Sqlite) CountNewArtists(ctx context.Context, timeframe db.Timeframe) (i
 }
 
 func (s *Sqlite) MergeArtists(ctx context.Context, fromId, toId int32, replaceImage bool) error {
+	if fromId == toId {
+		return errors.New("MergeArtists: cannot merge an artist into itself")
+	}
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("MergeArtists: BeginTx: %w", err)
@@ -597,6 +821,18 @@ func (s *Sqlite) MergeArtists(ctx context.Context, fromId, toId int32, replaceIm
 		`UPDATE artist_releases SET artist_id = ? WHERE artist_id = ?`, toId, fromId); err != nil {
 		return fmt.Errorf("MergeArtists: update releases: %w", err)
 	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO artist_tags (artist_id, tag) SELECT ?, tag FROM artist_tags WHERE artist_id = ?`, toId, fromId); err != nil {
+		return fmt.Errorf("MergeArtists: move tags: %w", err)
+	}
+	// the names of the merged artist, including its primary one, are kept as aliases
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO artist_aliases (artist_id, alias, source, is_primary, seen_count)
+		SELECT ?, alias, source, 0, seen_count FROM artist_aliases WHERE artist_id = ?
+		ON CONFLICT (artist_id, alias) DO UPDATE SET seen_count = seen_count + excluded.seen_count`,
+		toId, fromId); err != nil {
+		return fmt.Errorf("MergeArtists: move aliases: %w", err)
+	}
 	if _, err := tx.ExecContext(ctx, `DELETE FROM artists WHERE id = ?`, fromId); err != nil {
 		return fmt.Errorf("MergeArtists: delete from: %w", err)
 	}
@@ -605,3 +841,56 @@ func (s *Sqlite) MergeArtists(ctx context.Context, fromId, toId int32, replaceIm
 	}
 	return tx.Commit()
 }
+
+// RecordArtistNameSeen counts a sighting of the artist's name spelled exactly as given, saving the
+// spelling as an alias of the artist if it is new.
+func (s *Sqlite) RecordArtistNameSeen(ctx context.Context, id int32, name string) error {
+	name = strings.TrimSpace(name)
+	if id == 0 || name == "" {
+		return errors.New("RecordArtistNameSeen: artist id and name must be provided")
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO artist_aliases (artist_id, alias, source, is_primary, seen_count) VALUES (?, ?, ?, 0, 1)
+		ON CONFLICT (artist_id, alias) DO UPDATE SET seen_count = seen_count + 1`,
+		id, name, models.AliasSourceListen)
+	if err != nil {
+		return fmt.Errorf("RecordArtistNameSeen: %w", err)
+	}
+	return nil
+}
+
+// GetArtistNameCasings returns, for every artist with more than one casing of its primary name,
+// each of those casings.
+func (s *Sqlite) GetArtistNameCasings(ctx context.Context) ([]db.ArtistNameCasings, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT aa.artist_id, aa.alias, aa.source, aa.is_primary, aa.seen_count
+		FROM artist_aliases aa
+		JOIN artist_aliases p ON p.artist_id = aa.artist_id AND p.is_primary = 1
+		WHERE aa.alias = p.alias COLLATE NOCASE
+		  AND aa.artist_id IN (
+			SELECT a2.artist_id FROM artist_aliases a2
+			JOIN artist_aliases p2 ON p2.artist_id = a2.artist_id AND p2.is_primary = 1
+			WHERE a2.alias = p2.alias COLLATE NOCASE
+			GROUP BY a2.artist_id HAVING COUNT(*) > 1)
+		ORDER BY aa.artist_id, aa.is_primary DESC, aa.alias`)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistNameCasings: %w", err)
+	}
+	defer rows.Close()
+
+	ret := make([]db.ArtistNameCasings, 0)
+	for rows.Next() {
+		var id int32
+		var c db.NameCasing
+		var isPrimary int
+		if err := rows.Scan(&id, &c.Name, &c.Source, &isPrimary, &c.SeenCount); err != nil {
+			return nil, fmt.Errorf("GetArtistNameCasings: %w", err)
+		}
+		c.Primary = isPrimary == 1
+		if len(ret) == 0 || ret[len(ret)-1].ArtistID != id {
+			ret = append(ret, db.ArtistNameCasings{ArtistID: id})
+		}
+		ret[len(ret)-1].Casings = append(ret[len(ret)-1].Casings, c)
+	}
+	return ret, rows.Err()
+}
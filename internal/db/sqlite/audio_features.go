@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/models"
+)
+
+// GetTracksWithoutAudioFeatures returns up to 20 tracks with an ID greater than from whose audio
+// features have not been looked up yet, along with their artists.
+func (s *Sqlite) GetTracksWithoutAudioFeatures(ctx context.Context, from int32) ([]*models.Track, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.id, t.title
+		FROM tracks_with_title t
+		LEFT JOIN track_audio_features f ON f.track_id = t.id
+		WHERE f.track_id IS NULL AND t.id > ?
+		ORDER BY t.id ASC LIMIT 20`,
+		from)
+	if err != nil {
+		return nil, fmt.Errorf("GetTracksWithoutAudioFeatures: %w", err)
+	}
+	var tracks []*models.Track
+	for rows.Next() {
+		var t models.Track
+		if err := rows.Scan(&t.ID, &t.Title); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("GetTracksWithoutAudioFeatures: %w", err)
+		}
+		tracks = append(tracks, &t)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("GetTracksWithoutAudioFeatures: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetTracksWithoutAudioFeatures: %w", err)
+	}
+	for _, t := range tracks {
+		t.Artists, err = s.artistsForTrack(ctx, t.ID)
+		if err != nil {
+			return nil, fmt.Errorf("GetTracksWithoutAudioFeatures: %w", err)
+		}
+	}
+	return tracks, nil
+}
+
+// GetTrackAudioFeatures returns the audio features of the track. db.ErrNotFound is returned when
+// they have not been looked up yet, or the track could not be found on Spotify.
+func (s *Sqlite) GetTrackAudioFeatures(ctx context.Context, id int32) (*models.AudioFeatures, error) {
+	var tempo, energy, danceability, valence sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT tempo, energy, danceability, valence FROM track_audio_features WHERE track_id = ?`,
+		id).Scan(&tempo, &energy, &danceability, &valence)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !tempo.Valid) {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", db.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", err)
+	}
+	return &models.AudioFeatures{
+		Tempo:        tempo.Float64,
+		Energy:       energy.Float64,
+		Danceability: danceability.Float64,
+		Valence:      valence.Float64,
+	}, nil
+}
+
+// SaveTrackAudioFeatures saves the audio features of the track, replacing any saved before. A nil
+// features records that the track has no audio features, so that it is not looked up again.
+func (s *Sqlite) SaveTrackAudioFeatures(ctx context.Context, id int32, features *models.AudioFeatures) error {
+	if id == 0 {
+		return errors.New("SaveTrackAudioFeatures: required parameter 'id' missing")
+	}
+	var tempo, energy, danceability, valence sql.NullFloat64
+	if features != nil {
+		tempo = sql.NullFloat64{Float64: features.Tempo, Valid: true}
+		energy = sql.NullFloat64{Float64: features.Energy, Valid: true}
+		danceability = sql.NullFloat64{Float64: features.Danceability, Valid: true}
+		valence = sql.NullFloat64{Float64: features.Valence, Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO track_audio_features (track_id, tempo, energy, danceability, valence, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		id, tempo, energy, danceability, valence, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("SaveTrackAudioFeatures: %w", err)
+	}
+	return nil
+}
+
+func (s *Sqlite) GetAverageAudioFeatures(ctx context.Context, opts db.GetAverageAudioFeaturesOpts) (*db.AverageAudioFeatures, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	var ret db.AverageAudioFeatures
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(AVG(f.tempo), 0), COALESCE(AVG(f.energy), 0),
+		       COALESCE(AVG(f.danceability), 0), COALESCE(AVG(f.valence), 0)
+		FROM user_listens l
+		JOIN track_audio_features f ON f.track_id = l.track_id
+		WHERE f.tempo IS NOT NULL AND l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID,
+	).Scan(&ret.Listens, &ret.Tempo, &ret.Energy, &ret.Danceability, &ret.Valence)
+	if err != nil {
+		return nil, fmt.Errorf("GetAverageAudioFeatures: %w", err)
+	}
+	return &ret, nil
+}
@@ -8,12 +8,13 @@ import (
 	"github.com/gabehf/koito/internal/db"
 )
 
-func (s *Sqlite) CountListens(ctx context.Context, timeframe db.Timeframe) (int64, error) {
-	t1, t2 := db.TimeframeToTimeRange(timeframe)
+func (s *Sqlite) CountListens(ctx context.Context, opts db.CountOpts) (int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
 	var count int64
-	err := s.db.QueryRowContext(ctx,
-		`SELECT COUNT(*) FROM listens WHERE listened_at BETWEEN ? AND ?`,
-		t1.Unix(), t2.Unix()).Scan(&count)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_listens
+		WHERE listened_at BETWEEN ? AND ? AND ((? = 0 AND private = 0) OR user_id = ?)`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID).Scan(&count)
 	return count, err
 }
 
@@ -48,14 +49,14 @@ func (s *Sqlite) CountListensToItem(ctx context.Context, opts db.TimeListenedOpt
 	return count, nil
 }
 
-func (s *Sqlite) CountTimeListened(ctx context.Context, timeframe db.Timeframe) (int64, error) {
-	t1, t2 := db.TimeframeToTimeRange(timeframe)
+func (s *Sqlite) CountTimeListened(ctx context.Context, opts db.CountOpts) (int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
 	var seconds int64
 	err := s.db.QueryRowContext(ctx, `
 		SELECT COALESCE(SUM(t.duration), 0)
-		FROM listens l JOIN tracks t ON l.track_id = t.id
-		WHERE l.listened_at BETWEEN ? AND ?`,
-		t1.Unix(), t2.Unix()).Scan(&seconds)
+		FROM user_listens l JOIN tracks t ON l.track_id = t.id
+		WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID).Scan(&seconds)
 	return seconds, err
 }
 
@@ -15,7 +15,7 @@ func (s *Sqlite) GetExportPage(ctx context.Context, opts db.GetExportPageOpts) (
 		       t.id AS track_id, t.musicbrainz_id AS track_mbid, t.duration,
 		       t.release_id,
 		       r.musicbrainz_id AS release_mbid, r.image, r.image_source, r.various_artists
-		FROM listens l
+		FROM user_listens l
 		JOIN tracks t ON l.track_id = t.id
 		JOIN releases r ON t.release_id = r.id
 		WHERE l.user_id = ?
@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// GetImportJobByHash returns the user's most recent import of a file with the given content hash.
+func (s *Sqlite) GetImportJobByHash(ctx context.Context, userID int32, hash string) (*db.ImportJob, error) {
+	var job db.ImportJob
+	var finishedAt int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, filename, content_hash, imported, finished_at
+		FROM import_jobs
+		WHERE user_id = ? AND content_hash = ?
+		ORDER BY finished_at DESC, id DESC
+		LIMIT 1`, userID, hash).
+		Scan(&job.ID, &job.UserID, &job.Filename, &job.ContentHash, &job.Imported, &finishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("GetImportJobByHash: %w", db.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetImportJobByHash: %w", err)
+	}
+	job.FinishedAt = time.Unix(finishedAt, 0).UTC()
+	return &job, nil
+}
+
+func (s *Sqlite) SaveImportJob(ctx context.Context, opts db.SaveImportJobOpts) error {
+	if opts.ContentHash == "" {
+		return errors.New("SaveImportJob: required parameter 'ContentHash' missing")
+	}
+	if opts.UserID == 0 {
+		return errors.New("SaveImportJob: required parameter 'UserID' missing")
+	}
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO import_jobs (user_id, filename, content_hash, imported, finished_at) VALUES (?,?,?,?,?)`,
+		opts.UserID, opts.Filename, opts.ContentHash, opts.Imported, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("SaveImportJob: %w", err)
+	}
+	return nil
+}
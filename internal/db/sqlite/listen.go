@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gabehf/koito/internal/catalog"
@@ -13,9 +14,11 @@ import (
 	"github.com/google/uuid"
 )
 
-func (s *Sqlite) SaveListen(ctx context.Context, opts db.SaveListenOpts) error {
+// SaveListen saves a listen, reporting whether it is new. A listen of the same user to the same track
+// at the same second already exists when a client retries a submission, and is left as it is.
+func (s *Sqlite) SaveListen(ctx context.Context, opts db.SaveListenOpts) (bool, error) {
 	if opts.TrackID == 0 {
-		return errors.New("SaveListen: required parameter TrackID missing")
+		return false, errors.New("SaveListen: required parameter TrackID missing")
 	}
 	if opts.Time.IsZero() {
 		opts.Time = time.Now()
@@ -24,24 +27,263 @@ func (s *Sqlite) SaveListen(ctx context.Context, opts db.SaveListenOpts) error {
 	if opts.Client != "" {
 		client = opts.Client
 	}
-	_, err := s.db.ExecContext(ctx,
-		`INSERT OR IGNORE INTO listens (track_id, listened_at, user_id, client) VALUES (?,?,?,?)`,
+	res, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO all_listens (track_id, listened_at, user_id, client, device, private) VALUES (?,?,?,?,?,?)`,
 		opts.TrackID, opts.Time.Unix(), opts.UserID, client,
+		sql.NullString{String: opts.Device, Valid: opts.Device != ""}, opts.Private,
 	)
-	return err
+	if err != nil {
+		return false, fmt.Errorf("SaveListen: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("SaveListen: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SaveListens saves the listens in a single transaction, reporting for each of them whether it is
+// new like SaveListen does. No listen is saved when any of them cannot be.
+func (s *Sqlite) SaveListens(ctx context.Context, opts []db.SaveListenOpts) ([]bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("SaveListens: BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT OR IGNORE INTO all_listens (track_id, listened_at, user_id, client, device, private) VALUES (?,?,?,?,?,?)`)
+	if err != nil {
+		return nil, fmt.Errorf("SaveListens: prepare: %w", err)
+	}
+	defer stmt.Close()
+	saved := make([]bool, len(opts))
+	for i, o := range opts {
+		if o.TrackID == 0 {
+			return nil, errors.New("SaveListens: required parameter TrackID missing")
+		}
+		if o.Time.IsZero() {
+			o.Time = time.Now()
+		}
+		res, err := stmt.ExecContext(ctx,
+			o.TrackID, o.Time.Unix(), o.UserID, o.Client,
+			sql.NullString{String: o.Device, Valid: o.Device != ""}, o.Private,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("SaveListens: insert: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("SaveListens: %w", err)
+		}
+		saved[i] = n > 0
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("SaveListens: commit: %w", err)
+	}
+	return saved, nil
+}
+
+// ListenExistsWithin reports whether the user has a listen to the track less than the window away
+// from the time, either before or after it.
+func (s *Sqlite) ListenExistsWithin(ctx context.Context, opts db.ListenExistsWithinOpts) (bool, error) {
+	if opts.TrackID == 0 {
+		return false, errors.New("ListenExistsWithin: required parameter TrackID missing")
+	}
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM all_listens
+			WHERE track_id = ? AND user_id = ? AND listened_at > ? AND listened_at < ? AND deleted_at IS NULL
+		)`,
+		opts.TrackID, opts.UserID, opts.Time.Add(-opts.Window).Unix(), opts.Time.Add(opts.Window).Unix(),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("ListenExistsWithin: %w", err)
+	}
+	return exists, nil
 }
 
 func (s *Sqlite) DeleteListen(ctx context.Context, trackId int32, listenedAt time.Time) error {
 	if trackId == 0 {
 		return errors.New("DeleteListen: required parameter 'trackId' missing")
 	}
+	// listens are only marked as deleted, so they can be restored until they are purged
 	_, err := s.db.ExecContext(ctx,
-		`DELETE FROM listens WHERE track_id = ? AND listened_at = ?`,
-		trackId, listenedAt.Unix(),
+		`UPDATE all_listens SET deleted_at = ? WHERE track_id = ? AND listened_at = ? AND deleted_at IS NULL`,
+		time.Now().Unix(), trackId, listenedAt.Unix(),
 	)
 	return err
 }
 
+// DeleteUserListen deletes the listen of the user, returning false when the user has no such listen.
+// Deleting a listen that was already deleted, but not yet purged, succeeds.
+func (s *Sqlite) DeleteUserListen(ctx context.Context, opts db.DeleteListenOpts) (bool, error) {
+	if opts.TrackID == 0 || opts.UserID == 0 {
+		return false, errors.New("DeleteUserListen: track id and user id must be provided")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("DeleteUserListen: BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM all_listens WHERE track_id = ? AND listened_at = ? AND user_id = ?)`,
+		opts.TrackID, opts.ListenedAt.Unix(), opts.UserID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("DeleteUserListen: %w", err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	if opts.Purge {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM all_listens WHERE track_id = ? AND listened_at = ? AND user_id = ?`,
+			opts.TrackID, opts.ListenedAt.Unix(), opts.UserID); err != nil {
+			return false, fmt.Errorf("DeleteUserListen: delete: %w", err)
+		}
+		if err := cleanOrphanedTrack(ctx, tx, opts.TrackID); err != nil {
+			return false, fmt.Errorf("DeleteUserListen: clean: %w", err)
+		}
+	} else if _, err := tx.ExecContext(ctx,
+		`UPDATE all_listens SET deleted_at = ? WHERE track_id = ? AND listened_at = ? AND user_id = ? AND deleted_at IS NULL`,
+		time.Now().Unix(), opts.TrackID, opts.ListenedAt.Unix(), opts.UserID); err != nil {
+		return false, fmt.Errorf("DeleteUserListen: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// DeleteUserListens deletes the listens of their users in a single transaction, returning the number
+// deleted. Listens that do not exist or were already deleted are not counted. No listen is deleted when
+// any of them cannot be. Listens are only marked as deleted, so Purge must not be set.
+func (s *Sqlite) DeleteUserListens(ctx context.Context, opts []db.DeleteListenOpts) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteUserListens: BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx,
+		`UPDATE all_listens SET deleted_at = ? WHERE track_id = ? AND listened_at = ? AND user_id = ? AND deleted_at IS NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteUserListens: prepare: %w", err)
+	}
+	defer stmt.Close()
+	now := time.Now().Unix()
+	var deleted int64
+	for _, o := range opts {
+		if o.TrackID == 0 || o.UserID == 0 {
+			return 0, errors.New("DeleteUserListens: track id and user id must be provided")
+		}
+		if o.Purge {
+			return 0, errors.New("DeleteUserListens: listens cannot be purged")
+		}
+		res, err := stmt.ExecContext(ctx, now, o.TrackID, o.ListenedAt.Unix(), o.UserID)
+		if err != nil {
+			return 0, fmt.Errorf("DeleteUserListens: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("DeleteUserListens: %w", err)
+		}
+		deleted += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("DeleteUserListens: commit: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteListensInRange deletes the user's listens between opts.From and opts.To, returning the number
+// deleted. The listens can be restored until they are purged.
+func (s *Sqlite) DeleteListensInRange(ctx context.Context, opts db.DeleteListensOpts) (int64, error) {
+	if opts.UserID == 0 {
+		return 0, errors.New("DeleteListensInRange: required parameter UserID missing")
+	}
+	query := `UPDATE all_listens SET deleted_at = ?
+		WHERE user_id = ? AND listened_at BETWEEN ? AND ? AND deleted_at IS NULL`
+	args := []any{time.Now().Unix(), opts.UserID, opts.From.Unix(), opts.To.Unix()}
+	if opts.Client != nil {
+		query += ` AND client = ?`
+		args = append(args, *opts.Client)
+	}
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("DeleteListensInRange: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("DeleteListensInRange: %w", err)
+	}
+	return n, nil
+}
+
+// RestoreListens restores deleted listens that have not yet been purged, returning the number restored.
+func (s *Sqlite) RestoreListens(ctx context.Context, opts db.RestoreListensOpts) (int64, error) {
+	where := []string{"deleted_at IS NOT NULL"}
+	args := []any{}
+	if opts.TrackID != 0 {
+		where = append(where, "track_id = ?", "listened_at = ?")
+		args = append(args, opts.TrackID, opts.ListenedAt.Unix())
+	}
+	if !opts.DeletedSince.IsZero() {
+		where = append(where, "deleted_at >= ?")
+		args = append(args, opts.DeletedSince.Unix())
+	}
+	if opts.UserID != 0 {
+		where = append(where, "user_id = ?")
+		args = append(args, opts.UserID)
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE all_listens SET deleted_at = NULL WHERE `+strings.Join(where, " AND "), args...)
+	if err != nil {
+		return 0, fmt.Errorf("RestoreListens: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("RestoreListens: %w", err)
+	}
+	return n, nil
+}
+
+// SetListensPrivate marks the user's listens within the time range as private or shared, returning the
+// number of listens changed.
+func (s *Sqlite) SetListensPrivate(ctx context.Context, opts db.SetListensPrivateOpts) (int64, error) {
+	if opts.UserID == 0 {
+		return 0, errors.New("SetListensPrivate: required parameter UserID missing")
+	}
+	if opts.To.IsZero() {
+		opts.To = time.Now()
+	}
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE all_listens SET private = ?
+		WHERE user_id = ? AND listened_at BETWEEN ? AND ? AND private != ? AND deleted_at IS NULL`,
+		opts.Private, opts.UserID, opts.From.Unix(), opts.To.Unix(), opts.Private)
+	if err != nil {
+		return 0, fmt.Errorf("SetListensPrivate: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("SetListensPrivate: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeDeletedListens permanently removes listens deleted before the given time, returning the number removed.
+func (s *Sqlite) PurgeDeletedListens(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM all_listens WHERE deleted_at IS NOT NULL AND deleted_at < ?`, deletedBefore.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("PurgeDeletedListens: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("PurgeDeletedListens: %w", err)
+	}
+	return n, nil
+}
+
 // listenRow is an intermediate scan target used to decouple the main rows
 // query from the per-row artistsForTrack sub-query. With MaxOpenConns(1),
 // both the count query and the artistsForTrack call would deadlock if
@@ -52,82 +294,87 @@ type listenRow struct {
 	title      string
 }
 
-func (s *Sqlite) GetListensPaginated(ctx context.Context, opts db.GetItemsOpts) (*db.PaginatedResponse[*models.Listen], error) {
+// GetListens returns listens matching every filter set in opts. The filters are
+// not mutually exclusive, so the WHERE clause is assembled from whichever of them
+// are provided.
+func (s *Sqlite) GetListens(ctx context.Context, opts db.GetListensOpts) (*db.PaginatedResponse[*models.Listen], error) {
 	if opts.Limit == 0 {
 		opts.Limit = defaultItemsPerPage
 	}
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
 	offset := (opts.Page - 1) * opts.Limit
-	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
 
-	// Count queries run first, before any main rows query is opened, so
-	// they never compete with an open *sql.Rows for the single connection.
+	var where []string
+	var args []any
+	if t1, t2 := db.TimeframeToTimeRange(opts.Timeframe); !t2.IsZero() {
+		where = append(where, "l.listened_at BETWEEN ? AND ?")
+		args = append(args, t1.Unix(), t2.Unix())
+	}
+	if opts.TrackID > 0 {
+		where = append(where, "l.track_id = ?")
+		args = append(args, opts.TrackID)
+	}
+	if opts.ReleaseID > 0 {
+		where = append(where, "t.release_id = ?")
+		args = append(args, opts.ReleaseID)
+	}
+	if opts.ArtistID > 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM artist_tracks at2 WHERE at2.track_id = l.track_id AND at2.artist_id = ?)")
+		args = append(args, opts.ArtistID)
+	}
+	if opts.Client != "" {
+		where = append(where, "l.client = ?")
+		args = append(args, opts.Client)
+	}
+	view := "listens"
+	if opts.UserID > 0 {
+		view = "user_listens"
+		where = append(where, "l.user_id = ?")
+		args = append(args, opts.UserID)
+	}
+	if len(opts.Search) > 0 {
+		// the matching tracks are found once, rather than for every listen
+		var matches []string
+		for _, term := range opts.Search {
+			pattern := "%" + escapeLike(term) + "%"
+			matches = append(matches, `
+				SELECT track_id FROM track_aliases WHERE alias LIKE ? ESCAPE '\'
+				UNION SELECT at2.track_id FROM artist_tracks at2
+					JOIN artist_aliases aa ON aa.artist_id = at2.artist_id
+					WHERE aa.alias LIKE ? ESCAPE '\'
+				UNION SELECT t2.id FROM tracks t2
+					JOIN release_aliases ra ON ra.release_id = t2.release_id
+					WHERE ra.alias LIKE ? ESCAPE '\'`)
+			args = append(args, pattern, pattern, pattern)
+		}
+		where = append(where, "l.track_id IN ("+strings.Join(matches, " UNION ")+")")
+	}
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = "WHERE " + strings.Join(where, " AND ")
+	}
+
 	var count int64
-	switch {
-	case opts.TrackID > 0:
-		s.db.QueryRowContext(ctx,
-			`SELECT COUNT(*) FROM listens WHERE listened_at BETWEEN ? AND ? AND track_id = ?`,
-			t1.Unix(), t2.Unix(), opts.TrackID).Scan(&count)
-	case opts.AlbumID > 0:
-		s.db.QueryRowContext(ctx, `
-			SELECT COUNT(*) FROM listens l JOIN tracks t ON l.track_id = t.id
-			WHERE l.listened_at BETWEEN ? AND ? AND t.release_id = ?`,
-			t1.Unix(), t2.Unix(), opts.AlbumID).Scan(&count)
-	case opts.ArtistID > 0:
-		s.db.QueryRowContext(ctx, `
-			SELECT COUNT(*) FROM listens l JOIN artist_tracks at2 ON l.track_id = at2.track_id
-			WHERE l.listened_at BETWEEN ? AND ? AND at2.artist_id = ?`,
-			t1.Unix(), t2.Unix(), opts.ArtistID).Scan(&count)
-	default:
-		s.db.QueryRowContext(ctx,
-			`SELECT COUNT(*) FROM listens WHERE listened_at BETWEEN ? AND ?`,
-			t1.Unix(), t2.Unix()).Scan(&count)
-	}
-
-	// Open and fully drain the main rows query, then close before any
-	// sub-queries so the connection is free for artistsForTrack.
-	var rows *sql.Rows
-	var err error
-	switch {
-	case opts.TrackID > 0:
-		rows, err = s.db.QueryContext(ctx, `
-			SELECT l.listened_at, l.track_id, t.title
-			FROM listens l
-			JOIN tracks_with_title t ON l.track_id = t.id
-			WHERE l.listened_at BETWEEN ? AND ? AND t.id = ?
-			ORDER BY l.listened_at DESC LIMIT ? OFFSET ?`,
-			t1.Unix(), t2.Unix(), opts.TrackID, opts.Limit, offset,
-		)
-	case opts.AlbumID > 0:
-		rows, err = s.db.QueryContext(ctx, `
-			SELECT l.listened_at, l.track_id, t.title
-			FROM listens l
-			JOIN tracks_with_title t ON l.track_id = t.id
-			WHERE l.listened_at BETWEEN ? AND ? AND t.release_id = ?
-			ORDER BY l.listened_at DESC LIMIT ? OFFSET ?`,
-			t1.Unix(), t2.Unix(), opts.AlbumID, opts.Limit, offset,
-		)
-	case opts.ArtistID > 0:
-		rows, err = s.db.QueryContext(ctx, `
-			SELECT l.listened_at, l.track_id, t.title
-			FROM listens l
-			JOIN tracks_with_title t ON l.track_id = t.id
-			JOIN artist_tracks at2 ON t.id = at2.track_id
-			WHERE l.listened_at BETWEEN ? AND ? AND at2.artist_id = ?
-			ORDER BY l.listened_at DESC LIMIT ? OFFSET ?`,
-			t1.Unix(), t2.Unix(), opts.ArtistID, opts.Limit, offset,
-		)
-	default:
-		rows, err = s.db.QueryContext(ctx, `
-			SELECT l.listened_at, l.track_id, t.title
-			FROM listens l
-			JOIN tracks_with_title t ON l.track_id = t.id
-			WHERE l.listened_at BETWEEN ? AND ?
-			ORDER BY l.listened_at DESC LIMIT ? OFFSET ?`,
-			t1.Unix(), t2.Unix(), opts.Limit, offset,
-		)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM `+view+` l
+		JOIN tracks t ON l.track_id = t.id
+		`+whereClause, args...).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("GetListens: count: %w", err)
 	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.listened_at, l.track_id, t.title
+		FROM `+view+` l
+		JOIN tracks_with_title t ON l.track_id = t.id
+		`+whereClause+`
+		ORDER BY l.listened_at DESC LIMIT ? OFFSET ?`,
+		append(args, opts.Limit, offset)...,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("GetListensPaginated: %w", err)
+		return nil, fmt.Errorf("GetListens: %w", err)
 	}
 
 	var raw []listenRow
@@ -135,18 +382,107 @@ func (s *Sqlite) GetListensPaginated(ctx context.Context, opts db.GetItemsOpts)
 		var r listenRow
 		if err := rows.Scan(&r.listenedAt, &r.trackID, &r.title); err != nil {
 			rows.Close()
-			return nil, err
+			return nil, fmt.Errorf("GetListens: scan: %w", err)
 		}
 		raw = append(raw, r)
 	}
-	// Explicitly close before calling artistsForTrack so the connection is free.
 	if err := rows.Close(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("GetListens: %w", err)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("GetListens: %w", err)
+	}
+
+	listens, err := s.hydrateListens(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("GetListens: %w", err)
 	}
 
+	return &db.PaginatedResponse[*models.Listen]{
+		Items:        listens,
+		TotalCount:   count,
+		ItemsPerPage: int32(opts.Limit),
+		HasNextPage:  int64(offset+len(listens)) < count,
+		CurrentPage:  int32(opts.Page),
+	}, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// GetListenVersion returns the time of the latest listen and the number of listens that the user
+// can see. When userID is 0, only the listens visible to everyone are included.
+func (s *Sqlite) GetListenVersion(ctx context.Context, userID int32) (db.ListenVersion, error) {
+	var v db.ListenVersion
+	var latest int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(listened_at), 0), COUNT(*)
+		FROM user_listens
+		WHERE (? = 0 AND private = 0) OR user_id = ?`, userID, userID).Scan(&latest, &v.Count)
+	if err != nil {
+		return v, fmt.Errorf("GetListenVersion: %w", err)
+	}
+	if latest > 0 {
+		v.Latest = time.Unix(latest, 0).UTC()
+	}
+	return v, nil
+}
+
+// GetListensBefore returns up to Limit listens older than the given position, newest first. Because
+// the position is a listen rather than an offset, listens saved between calls do not shift the results.
+func (s *Sqlite) GetListensBefore(ctx context.Context, opts db.GetListensBeforeOpts) ([]*models.Listen, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+
+	where := "WHERE ((? = 0 AND l.private = 0) OR l.user_id = ?)"
+	args := []any{opts.UserID, opts.UserID}
+	if !opts.BeforeTime.IsZero() {
+		where += " AND (l.listened_at < ? OR (l.listened_at = ? AND l.track_id < ?))"
+		args = append(args, opts.BeforeTime.Unix(), opts.BeforeTime.Unix(), opts.BeforeTrackID)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.listened_at, l.track_id, t.title
+		FROM user_listens l
+		JOIN tracks_with_title t ON l.track_id = t.id
+		`+where+`
+		ORDER BY l.listened_at DESC, l.track_id DESC LIMIT ?`,
+		append(args, opts.Limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetListensBefore: %w", err)
+	}
+
+	var raw []listenRow
+	for rows.Next() {
+		var r listenRow
+		if err := rows.Scan(&r.listenedAt, &r.trackID, &r.title); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("GetListensBefore: scan: %w", err)
+		}
+		raw = append(raw, r)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("GetListensBefore: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetListensBefore: %w", err)
+	}
+
+	listens, err := s.hydrateListens(ctx, raw)
+	if err != nil {
+		return nil, fmt.Errorf("GetListensBefore: %w", err)
+	}
+	return listens, nil
+}
+
+// hydrateListens builds listen models from drained rows, attaching artists and
+// the release image. The outer rows must already be closed.
+func (s *Sqlite) hydrateListens(ctx context.Context, raw []listenRow) ([]*models.Listen, error) {
+	var err error
 	listens := make([]*models.Listen, 0, len(raw))
 	for _, r := range raw {
 		l := &models.Listen{
@@ -167,14 +503,78 @@ func (s *Sqlite) GetListensPaginated(ctx context.Context, opts db.GetItemsOpts)
 		l.Track.Image = catalog.BuildImageList(imgid)
 		listens = append(listens, l)
 	}
+	return listens, nil
+}
 
-	return &db.PaginatedResponse[*models.Listen]{
-		Items:        listens,
-		TotalCount:   count,
-		ItemsPerPage: int32(opts.Limit),
-		HasNextPage:  int64(offset+len(listens)) < count,
-		CurrentPage:  int32(opts.Page),
-	}, nil
+// GetListensByDevice returns the number of listens from each device within the timeframe,
+// ordered by most listens. Listens with no recorded device are grouped under an empty name.
+func (s *Sqlite) GetListensByDevice(ctx context.Context, opts db.CountOpts) ([]db.DeviceListenCount, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	if t2.IsZero() {
+		t2 = time.Now()
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(device, '') AS device, COUNT(*) AS listens
+		FROM user_listens
+		WHERE listened_at BETWEEN ? AND ?
+			AND ((? = 0 AND private = 0) OR user_id = ?)
+		GROUP BY COALESCE(device, '')
+		ORDER BY listens DESC, device ASC`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetListensByDevice: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.DeviceListenCount, 0)
+	for rows.Next() {
+		var item db.DeviceListenCount
+		if err := rows.Scan(&item.Device, &item.Listens); err != nil {
+			return nil, fmt.Errorf("GetListensByDevice: scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetListensByDevice: %w", err)
+	}
+	return items, nil
+}
+
+// GetListenLog returns every listen within the timeframe in chronological order,
+// optionally limited to a single user.
+func (s *Sqlite) GetListenLog(ctx context.Context, opts db.GetListenLogOpts) ([]db.ListenLogEntry, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	if t2.IsZero() {
+		t2 = time.Now()
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.listened_at, l.track_id, t.title, t.duration
+		FROM user_listens l
+		JOIN tracks_with_title t ON l.track_id = t.id
+		WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?)
+		ORDER BY l.listened_at ASC`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetListenLog: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]db.ListenLogEntry, 0)
+	for rows.Next() {
+		var e db.ListenLogEntry
+		var listenedAt int64
+		if err := rows.Scan(&listenedAt, &e.TrackID, &e.Title, &e.Duration); err != nil {
+			return nil, fmt.Errorf("GetListenLog: scan: %w", err)
+		}
+		e.Time = time.Unix(listenedAt, 0).UTC()
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetListenLog: %w", err)
+	}
+	return entries, nil
 }
 
 func (s *Sqlite) imageForTrack(ctx context.Context, trackId int32) (*uuid.UUID, error) {
@@ -208,7 +208,11 @@ func (s *Sqlite) GetListenStreak(ctx context.Context, opts db.ListenActivityOpts
 	return streak, nil
 }
 
-func (s *Sqlite) GetActiveDays(ctx context.Context, tz *time.Location) (int, error) {
+func (s *Sqlite) GetActiveDays(ctx context.Context, opts db.ListenActivityOpts) (int, error) {
+	tz := opts.Timezone
+	if tz == nil {
+		tz = time.UTC
+	}
 
 	now := time.Now()
 	eodUnix := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, tz).Unix()
@@ -216,10 +220,10 @@ func (s *Sqlite) GetActiveDays(ctx context.Context, tz *time.Location) (int, err
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT (listened_at / 86400) * 86400 AS day_bucket,
 		COUNT(*) AS listen_count
-		FROM listens
-		WHERE listened_at <= ?
+		FROM user_listens
+		WHERE listened_at <= ? AND ((? = 0 AND private = 0) OR user_id = ?)
 		GROUP BY day_bucket
-		ORDER BY day_bucket DESC`, eodUnix)
+		ORDER BY day_bucket DESC`, eodUnix, opts.UserID, opts.UserID)
 
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return 0, fmt.Errorf("GetListenStreak: %w", err)
@@ -251,40 +255,40 @@ func (s *Sqlite) GetLongestListenStreak(ctx context.Context, opts db.ListenActiv
 	case opts.ArtistID > 0:
 		rows, err = s.db.QueryContext(ctx, `
 			SELECT (l.listened_at / 3600) * 3600 AS hour_bucket
-			FROM listens l
+			FROM user_listens l
 			JOIN artist_tracks at2 ON l.track_id = at2.track_id
-			WHERE l.listened_at <= ? AND at2.artist_id = ?
+			WHERE l.listened_at <= ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?) AND at2.artist_id = ?
 			GROUP BY hour_bucket
 			ORDER BY hour_bucket DESC`,
-			endOfToday.Unix(), opts.ArtistID,
+			endOfToday.Unix(), opts.UserID, opts.UserID, opts.ArtistID,
 		)
 	case opts.AlbumID > 0:
 		rows, err = s.db.QueryContext(ctx, `
 			SELECT (l.listened_at / 3600) * 3600 AS hour_bucket
-			FROM listens l
+			FROM user_listens l
 			JOIN tracks t ON l.track_id = t.id
-			WHERE l.listened_at <= ? AND t.release_id = ?
+			WHERE l.listened_at <= ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?) AND t.release_id = ?
 			GROUP BY hour_bucket
 			ORDER BY hour_bucket DESC`,
-			endOfToday.Unix(), opts.AlbumID,
+			endOfToday.Unix(), opts.UserID, opts.UserID, opts.AlbumID,
 		)
 	case opts.TrackID > 0:
 		rows, err = s.db.QueryContext(ctx, `
 			SELECT (listened_at / 3600) * 3600 AS hour_bucket
-			FROM listens
-			WHERE listened_at <= ? AND track_id = ?
+			FROM user_listens
+			WHERE listened_at <= ? AND ((? = 0 AND private = 0) OR user_id = ?) AND track_id = ?
 			GROUP BY hour_bucket
 			ORDER BY hour_bucket DESC`,
-			endOfToday.Unix(), opts.TrackID,
+			endOfToday.Unix(), opts.UserID, opts.UserID, opts.TrackID,
 		)
 	default:
 		rows, err = s.db.QueryContext(ctx, `
 			SELECT (listened_at / 3600) * 3600 AS hour_bucket
-			FROM listens
-			WHERE listened_at <= ?
+			FROM user_listens
+			WHERE listened_at <= ? AND ((? = 0 AND private = 0) OR user_id = ?)
 			GROUP BY hour_bucket
 			ORDER BY hour_bucket DESC`,
-			endOfToday.Unix(),
+			endOfToday.Unix(), opts.UserID, opts.UserID,
 		)
 	}
 	if err != nil {
@@ -336,3 +340,90 @@ func (s *Sqlite) GetLongestListenStreak(ctx context.Context, opts db.ListenActiv
 
 	return longest, nil
 }
+
+// GetListenCountsByWeekday counts listens within the timeframe by the day of the week they
+// happened on in the given timezone.
+func (s *Sqlite) GetListenCountsByWeekday(ctx context.Context, opts db.GetListenCountsByWeekdayOpts) (map[time.Weekday]int64, error) {
+	loc := opts.Timezone
+	if loc == nil {
+		loc = time.UTC
+	}
+	buckets, err := s.listenCountBuckets(ctx, opts.UserID, opts.Timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("GetListenCountsByWeekday: %w", err)
+	}
+	counts := make(map[time.Weekday]int64, 7)
+	for bucket, count := range buckets {
+		counts[bucket.In(loc).Weekday()] += count
+	}
+	return counts, nil
+}
+
+// GetListenCountsByDay counts listens within the timeframe by the day they happened on in the
+// given timezone. Days are keyed by their midnight in that timezone, and days without listens are left out.
+func (s *Sqlite) GetListenCountsByDay(ctx context.Context, opts db.GetListenCountsByDayOpts) (map[time.Time]int64, error) {
+	loc := opts.Timezone
+	if loc == nil {
+		loc = time.UTC
+	}
+	buckets, err := s.listenCountBuckets(ctx, opts.UserID, opts.Timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("GetListenCountsByDay: %w", err)
+	}
+	counts := make(map[time.Time]int64)
+	for bucket, count := range buckets {
+		t := bucket.In(loc)
+		counts[time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)] += count
+	}
+	return counts, nil
+}
+
+// listenCountBuckets counts listens within the timeframe in 15 minute buckets. Every timezone offset
+// is a multiple of 15 minutes, so no bucket spans two local days in any timezone.
+func (s *Sqlite) listenCountBuckets(ctx context.Context, userID int32, tf db.Timeframe) (map[time.Time]int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(tf)
+	if t2.IsZero() {
+		t2 = time.Now()
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT (listened_at / 900) * 900 AS bucket, COUNT(*) AS listen_count
+		FROM user_listens
+		WHERE listened_at BETWEEN ? AND ? AND ((? = 0 AND private = 0) OR user_id = ?)
+		GROUP BY bucket`,
+		t1.Unix(), t2.Unix(), userID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make(map[time.Time]int64)
+	for rows.Next() {
+		var bucketUnix, count int64
+		if err := rows.Scan(&bucketUnix, &count); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		buckets[time.Unix(bucketUnix, 0)] = count
+	}
+	return buckets, rows.Err()
+}
+
+// GetUserListenTotals returns the number of listens and time listened of a user within the timeframe.
+func (s *Sqlite) GetUserListenTotals(ctx context.Context, opts db.GetUserListenTotalsOpts) (db.ListenTotals, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	if t2.IsZero() {
+		t2 = time.Now()
+	}
+	var totals db.ListenTotals
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(t.duration), 0)
+		FROM user_listens l JOIN tracks t ON l.track_id = t.id
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ?`,
+		opts.UserID, t1.Unix(), t2.Unix(),
+	).Scan(&totals.Listens, &totals.SecondsListened)
+	if err != nil {
+		return db.ListenTotals{}, fmt.Errorf("GetUserListenTotals: %w", err)
+	}
+	return totals, nil
+}
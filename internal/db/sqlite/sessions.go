@@ -50,11 +50,11 @@ func (s *Sqlite) GetUserBySession(ctx context.Context, sessionId uuid.UUID) (*mo
 	var u models.User
 	var role string
 	err := s.db.QueryRowContext(ctx, `
-		SELECT u.id, u.username, u.role, u.password
+		SELECT u.id, u.username, u.role, u.password, u.comparable
 		FROM users u JOIN sessions se ON u.id = se.user_id
 		WHERE se.id = ? AND se.expires_at > ?
 		LIMIT 1`,
-		sessionId.String(), time.Now().Unix()).Scan(&u.ID, &u.Username, &role, &u.Password)
+		sessionId.String(), time.Now().Unix()).Scan(&u.ID, &u.Username, &role, &u.Password, &u.Comparable)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	} else if err != nil {
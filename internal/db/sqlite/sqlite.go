@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"path"
 	"time"
@@ -232,6 +233,17 @@ func tzOffset(loc *time.Location) int {
 }
 
 // nullableUUID converts a *uuid.UUID to a sql.NullString for storage.
+// chartScore returns the SQL expression used to rank top chart items and its arguments.
+// Items are ranked by listen count unless a decay half-life is set, in which case each
+// listen is weighted by 0.5^(age / half-life).
+func chartScore(opts db.GetItemsOpts) (string, []any) {
+	if opts.DecayHalfLifeDays <= 0 {
+		return "COUNT(*)", nil
+	}
+	halfLife := opts.DecayHalfLifeDays * 24 * 60 * 60
+	return "SUM(POWER(0.5, (? - listened_at) / ?))", []any{time.Now().Unix(), halfLife}
+}
+
 func nullableUUID(u *uuid.UUID) sql.NullString {
 	if u == nil || *u == uuid.Nil {
 		return sql.NullString{}
@@ -255,9 +267,10 @@ func parseNullableUUID(s sql.NullString) *uuid.UUID {
 // associations where the artist has no tracks in the release, and removes
 // artists with no tracks. Mirrors PG CleanOrphanedEntries + the orphan trigger.
 func cleanOrphanedEntries(ctx context.Context, tx *sql.Tx) error {
-	// delete tracks with no listens (e.g. the "from" track after a merge)
+	// delete tracks with no listens (e.g. the "from" track after a merge). Deleted listens that
+	// have not been purged yet still count, so that they can be restored.
 	if _, err := tx.ExecContext(ctx,
-		`DELETE FROM tracks WHERE id NOT IN (SELECT DISTINCT track_id FROM listens)`); err != nil {
+		`DELETE FROM tracks WHERE id NOT IN (SELECT DISTINCT track_id FROM all_listens)`); err != nil {
 		return err
 	}
 	// delete artist_releases where the artist has no tracks in that release;
@@ -280,6 +293,66 @@ func cleanOrphanedEntries(ctx context.Context, tx *sql.Tx) error {
 	return nil
 }
 
+// cleanOrphanedTrack removes the track when it has no listens left, then its release and artists when
+// they have no tracks left. Unlike cleanOrphanedEntries, the rest of the catalog is left as it is, such
+// as tracks saved by a now playing report that have no listens yet.
+func cleanOrphanedTrack(ctx context.Context, tx *sql.Tx, trackID int32) error {
+	var releaseID int32
+	err := tx.QueryRowContext(ctx, `
+		SELECT release_id FROM tracks
+		WHERE id = ?1 AND NOT EXISTS (SELECT 1 FROM all_listens WHERE track_id = ?1)`, trackID).Scan(&releaseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// the artists of the track and of its release, read before the track's associations are removed
+	rows, err := tx.QueryContext(ctx, `
+		SELECT artist_id FROM artist_tracks WHERE track_id = ?
+		UNION
+		SELECT artist_id FROM artist_releases WHERE release_id = ?`, trackID, releaseID)
+	if err != nil {
+		return err
+	}
+	var artistIDs []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		artistIDs = append(artistIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM tracks WHERE id = ?`, trackID); err != nil {
+		return err
+	}
+	// the trigger trg_delete_orphan_releases removes the release once none of its artists are left
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM artist_releases
+		WHERE release_id = ? AND NOT EXISTS (
+			SELECT 1 FROM artist_tracks at2
+			JOIN tracks t ON at2.track_id = t.id
+			WHERE at2.artist_id = artist_releases.artist_id
+			  AND t.release_id = artist_releases.release_id
+		)`, releaseID); err != nil {
+		return err
+	}
+	for _, id := range artistIDs {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM artists
+			WHERE id = ?1 AND NOT EXISTS (SELECT 1 FROM artist_tracks WHERE artist_id = ?1)`, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *Sqlite) PurgeAllData(ctx context.Context) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -289,10 +362,11 @@ func (s *Sqlite) PurgeAllData(ctx context.Context) error {
 	// Order respects foreign-key dependencies; cascades clean up junction and
 	// alias tables automatically.
 	for _, stmt := range []string{
-		`DELETE FROM listens`,
+		`DELETE FROM all_listens`,
 		`DELETE FROM tracks`,
 		`DELETE FROM releases`,
 		`DELETE FROM artists`,
+		`DELETE FROM import_jobs`,
 	} {
 		if _, err := tx.ExecContext(ctx, stmt); err != nil {
 			return fmt.Errorf("PurgeAllData: %w", err)
@@ -0,0 +1,128 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// tagTracksExpr selects the ids of the tracks with the tag given as its two parameters, either directly
+// or through one of their artists
+const tagTracksExpr = `(
+	SELECT track_id FROM track_tags WHERE tag = ?
+	UNION
+	SELECT at3.track_id FROM artist_tracks at3 JOIN artist_tags ag ON ag.artist_id = at3.artist_id WHERE ag.tag = ?
+)`
+
+func (s *Sqlite) SaveArtistTags(ctx context.Context, id int32, tags []string) error {
+	if err := s.saveTags(ctx, `INSERT OR IGNORE INTO artist_tags (artist_id, tag) VALUES (?, ?)`, id, tags); err != nil {
+		return fmt.Errorf("SaveArtistTags: %w", err)
+	}
+	return nil
+}
+
+func (s *Sqlite) SaveTrackTags(ctx context.Context, id int32, tags []string) error {
+	if err := s.saveTags(ctx, `INSERT OR IGNORE INTO track_tags (track_id, tag) VALUES (?, ?)`, id, tags); err != nil {
+		return fmt.Errorf("SaveTrackTags: %w", err)
+	}
+	return nil
+}
+
+func (s *Sqlite) saveTags(ctx context.Context, insert string, id int32, tags []string) error {
+	if id == 0 {
+		return errors.New("id not specified")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return errors.New("tags cannot be blank")
+		}
+		if _, err := tx.ExecContext(ctx, insert, id, tag); err != nil {
+			return fmt.Errorf("insert: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetUserTopArtistsByTag returns the artists with the tag, ordered by the user's listens to them
+// within the timeframe.
+func (s *Sqlite) GetUserTopArtistsByTag(ctx context.Context, opts db.GetUserTagItemsOpts) ([]db.UserTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT at2.artist_id, awn.name, COUNT(*) AS listen_count
+		FROM user_listens l
+		JOIN artist_tracks at2 ON l.track_id = at2.track_id
+		JOIN artist_tags ag ON ag.artist_id = at2.artist_id
+		JOIN artists_with_name awn ON awn.id = at2.artist_id
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ? AND ag.tag = ?
+		GROUP BY at2.artist_id
+		ORDER BY listen_count DESC, at2.artist_id
+		LIMIT ?`,
+		opts.UserID, t1.Unix(), t2.Unix(), opts.Tag, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTopArtistsByTag: %w", err)
+	}
+	return scanUserTopItems(rows, opts.Limit, "GetUserTopArtistsByTag")
+}
+
+// GetUserTopTracksByTag returns the tracks with the tag, directly or through one of their artists,
+// ordered by the user's listens to them within the timeframe.
+func (s *Sqlite) GetUserTopTracksByTag(ctx context.Context, opts db.GetUserTagItemsOpts) ([]db.UserTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.track_id, t.title, COUNT(*) AS listen_count
+		FROM user_listens l
+		JOIN tracks_with_title t ON t.id = l.track_id
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ? AND l.track_id IN `+tagTracksExpr+`
+		GROUP BY l.track_id
+		ORDER BY listen_count DESC, l.track_id
+		LIMIT ?`,
+		opts.UserID, t1.Unix(), t2.Unix(), opts.Tag, opts.Tag, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTopTracksByTag: %w", err)
+	}
+	return scanUserTopItems(rows, opts.Limit, "GetUserTopTracksByTag")
+}
+
+// CountUserListensWithTag returns the number of the user's listens within the timeframe to tracks with
+// the tag, directly or through one of their artists.
+func (s *Sqlite) CountUserListensWithTag(ctx context.Context, opts db.GetUserTagItemsOpts) (int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	var count int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM user_listens l
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ? AND l.track_id IN `+tagTracksExpr,
+		opts.UserID, t1.Unix(), t2.Unix(), opts.Tag, opts.Tag).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("CountUserListensWithTag: %w", err)
+	}
+	return count, nil
+}
+
+func scanUserTopItems(rows *sql.Rows, limit int, caller string) ([]db.UserTopItem, error) {
+	defer rows.Close()
+	items := make([]db.UserTopItem, 0, limit)
+	for rows.Next() {
+		var item db.UserTopItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Listens); err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", caller, err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/db"
@@ -121,6 +122,46 @@ func (s *Sqlite) trackByTrackInfo(ctx context.Context, title string, releaseID i
 	return id, s.db.QueryRowContext(ctx, query, args...).Scan(&id)
 }
 
+// GetTrackTitles returns the titles of the tracks on the release by exactly the given artists, or
+// of all tracks on the release when no artists are given.
+func (s *Sqlite) GetTrackTitles(ctx context.Context, opts db.GetTrackTitlesOpts) ([]db.TrackTitle, error) {
+	query := `SELECT t.id, t.title FROM tracks_with_title t WHERE t.release_id = ? ORDER BY t.id ASC`
+	args := []any{opts.ReleaseID}
+	if len(opts.ArtistIDs) > 0 {
+		placeholders := strings.Repeat("?,", len(opts.ArtistIDs))
+		placeholders = placeholders[:len(placeholders)-1]
+		query = fmt.Sprintf(`
+			SELECT t.id, t.title FROM tracks_with_title t
+			JOIN artist_tracks at2 ON at2.track_id = t.id
+			WHERE t.release_id = ? AND at2.artist_id IN (%s)
+			GROUP BY t.id
+			HAVING COUNT(DISTINCT at2.artist_id) = ?
+			ORDER BY t.id ASC`, placeholders)
+		for _, id := range opts.ArtistIDs {
+			args = append(args, id)
+		}
+		args = append(args, len(opts.ArtistIDs))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetTrackTitles: %w", err)
+	}
+	defer rows.Close()
+	var titles []db.TrackTitle
+	for rows.Next() {
+		var t db.TrackTitle
+		if err := rows.Scan(&t.TrackID, &t.Title); err != nil {
+			return nil, fmt.Errorf("GetTrackTitles: %w", err)
+		}
+		titles = append(titles, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("GetTrackTitles: %w", err)
+	}
+	return titles, nil
+}
+
 func (s *Sqlite) SaveTrack(ctx context.Context, opts db.SaveTrackOpts) (*models.Track, error) {
 	if len(opts.ArtistIDs) < 1 {
 		return nil, errors.New("SaveTrack: required parameter 'ArtistIDs' missing")
@@ -167,7 +208,7 @@ func (s *Sqlite) SaveTrack(ctx context.Context, opts db.SaveTrackOpts) (*models.
 
 	if _, err := tx.ExecContext(ctx,
 		`INSERT INTO track_aliases (track_id, alias, source, is_primary) VALUES (?,?,?,1)`,
-		id, opts.Title, "Canonical"); err != nil {
+		id, opts.Title, models.AliasSourceCanonical); err != nil {
 		return nil, fmt.Errorf("SaveTrack: canonical alias: %w", err)
 	}
 
@@ -427,6 +468,7 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 	}
 	offset := (opts.Page - 1) * opts.Limit
 	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	score, scoreArgs := chartScore(opts)
 
 	var rows *sql.Rows
 	var err error
@@ -435,18 +477,18 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 	case opts.AlbumID > 0:
 		query := `
 			WITH TrackCounts AS (
-				SELECT l.track_id, COUNT(*) AS listen_count
-				FROM listens l
+				SELECT l.track_id, COUNT(*) AS listen_count, ` + score + ` AS score
+				FROM user_listens l
 				JOIN tracks t ON l.track_id = t.id
-				WHERE l.listened_at BETWEEN ? AND ? AND t.release_id = ?
+				WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?) AND t.release_id = ?
 				GROUP BY l.track_id
 			),
 			RankedTracks AS (
 				SELECT track_id, listen_count,
-					   RANK() OVER (ORDER BY listen_count DESC) AS rank,
+					   RANK() OVER (ORDER BY score DESC) AS rank,
 					   COUNT(*) OVER () AS total_count
 				FROM TrackCounts
-				ORDER BY listen_count DESC, track_id
+				ORDER BY score DESC, track_id
 				LIMIT ? OFFSET ?
 			)
 			SELECT r.track_id, twt.title, twt.musicbrainz_id, twt.release_id, rls.image, r.listen_count, r.rank, r.total_count
@@ -455,23 +497,23 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 			JOIN releases rls ON twt.release_id = rls.id
 			ORDER BY r.rank, r.track_id`
 
-		rows, err = s.db.QueryContext(ctx, query, t1.Unix(), t2.Unix(), opts.AlbumID, opts.Limit, offset)
+		rows, err = s.db.QueryContext(ctx, query, append(scoreArgs, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, opts.AlbumID, opts.Limit, offset)...)
 
 	case opts.ArtistID > 0:
 		query := `
 			WITH TrackCounts AS (
-				SELECT l.track_id, COUNT(*) AS listen_count
-				FROM listens l
+				SELECT l.track_id, COUNT(*) AS listen_count, ` + score + ` AS score
+				FROM user_listens l
 				JOIN artist_tracks at2 ON l.track_id = at2.track_id
-				WHERE l.listened_at BETWEEN ? AND ? AND at2.artist_id = ?
+				WHERE l.listened_at BETWEEN ? AND ? AND ((? = 0 AND l.private = 0) OR l.user_id = ?) AND at2.artist_id = ?
 				GROUP BY l.track_id
 			),
 			RankedTracks AS (
 				SELECT track_id, listen_count,
-					   RANK() OVER (ORDER BY listen_count DESC) AS rank,
+					   RANK() OVER (ORDER BY score DESC) AS rank,
 					   COUNT(*) OVER () AS total_count
 				FROM TrackCounts
-				ORDER BY listen_count DESC, track_id
+				ORDER BY score DESC, track_id
 				LIMIT ? OFFSET ?
 			)
 			SELECT r.track_id, twt.title, twt.musicbrainz_id, twt.release_id, rls.image, r.listen_count, r.rank, r.total_count
@@ -480,22 +522,22 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 			JOIN releases rls ON twt.release_id = rls.id
 			ORDER BY r.rank, r.track_id`
 
-		rows, err = s.db.QueryContext(ctx, query, t1.Unix(), t2.Unix(), opts.ArtistID, opts.Limit, offset)
+		rows, err = s.db.QueryContext(ctx, query, append(scoreArgs, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, opts.ArtistID, opts.Limit, offset)...)
 
 	default:
 		query := `
 			WITH TrackCounts AS (
-				SELECT track_id, COUNT(*) AS listen_count
-				FROM listens
-				WHERE listened_at BETWEEN ? AND ?
+				SELECT track_id, COUNT(*) AS listen_count, ` + score + ` AS score
+				FROM user_listens
+				WHERE listened_at BETWEEN ? AND ? AND ((? = 0 AND private = 0) OR user_id = ?)
 				GROUP BY track_id
 			),
 			RankedTracks AS (
 				SELECT track_id, listen_count,
-					   RANK() OVER (ORDER BY listen_count DESC) AS rank,
+					   RANK() OVER (ORDER BY score DESC) AS rank,
 					   COUNT(*) OVER () AS total_count
 				FROM TrackCounts
-				ORDER BY listen_count DESC, track_id
+				ORDER BY score DESC, track_id
 				LIMIT ? OFFSET ?
 			)
 			SELECT r.track_id, twt.title, twt.musicbrainz_id, twt.release_id, rls.image, r.listen_count, r.rank, r.total_count
@@ -504,7 +546,7 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 			JOIN releases rls ON twt.release_id = rls.id
 			ORDER BY r.rank, r.track_id`
 
-		rows, err = s.db.QueryContext(ctx, query, t1.Unix(), t2.Unix(), opts.Limit, offset)
+		rows, err = s.db.QueryContext(ctx, query, append(scoreArgs, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, opts.Limit, offset)...)
 	}
 
 	if err != nil {
@@ -530,12 +572,6 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 		t.MbzID = parseNullableUUID(mbzID)
 		t.Image = catalog.BuildImageList(parseNullableUUID(image))
 
-		// N+1 Query (acceptable if volume is low, otherwise consider batching)
-		t.Artists, err = s.artistsForTrack(ctx, t.ID)
-		if err != nil {
-			return nil, err
-		}
-
 		item.Item = &t
 		tracks = append(tracks, item)
 	}
@@ -543,6 +579,16 @@ func (s *Sqlite) GetTopTracksPaginated(ctx context.Context, opts db.GetItemsOpts
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	// close before the artist lookups so they don't wait on this connection
+	rows.Close()
+
+	for _, item := range tracks {
+		// N+1 Query (acceptable if volume is low, otherwise consider batching)
+		item.Item.Artists, err = s.artistsForTrack(ctx, item.Item.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &db.PaginatedResponse[db.RankedItem[*models.Track]]{
 		Items:        tracks,
@@ -567,9 +613,13 @@ func (s *Sqlite) MergeTracks(ctx context.Context, fromId, toId int32) error {
 
 	// redirect all listens (ignore conflicts — same timestamp already exists for toId)
 	if _, err := tx.ExecContext(ctx,
-		`UPDATE OR IGNORE listens SET track_id = ? WHERE track_id = ?`, toId, fromId); err != nil {
+		`UPDATE OR IGNORE all_listens SET track_id = ? WHERE track_id = ?`, toId, fromId); err != nil {
 		return fmt.Errorf("MergeTracks: redirect listens: %w", err)
 	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO track_tags (track_id, tag) SELECT ?, tag FROM track_tags WHERE track_id = ?`, toId, fromId); err != nil {
+		return fmt.Errorf("MergeTracks: move tags: %w", err)
+	}
 
 	if fromRelease != toRelease {
 		// associate fromId's artists with toId's release
@@ -600,12 +650,46 @@ func (s *Sqlite) MergeTracks(ctx context.Context, fromId, toId int32) error {
 	return tx.Commit()
 }
 
-func (s *Sqlite) CountTracks(ctx context.Context, timeframe db.Timeframe) (int64, error) {
-	t1, t2 := db.TimeframeToTimeRange(timeframe)
+// MoveTrackToRelease moves the track, along with its listens, to another release. The track's artists
+// are added to the release, and the track's old release is removed if nothing is left on it.
+func (s *Sqlite) MoveTrackToRelease(ctx context.Context, trackID, releaseID int32) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("MoveTrackToRelease: BeginTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM releases WHERE id = ?)`, releaseID).Scan(&exists); err != nil {
+		return fmt.Errorf("MoveTrackToRelease: %w", err)
+	} else if !exists {
+		return fmt.Errorf("MoveTrackToRelease: release: %w", db.ErrNotFound)
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE tracks SET release_id = ? WHERE id = ?`, releaseID, trackID)
+	if err != nil {
+		return fmt.Errorf("MoveTrackToRelease: update: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("MoveTrackToRelease: track: %w", db.ErrNotFound)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO artist_releases (artist_id, release_id, is_primary)
+		SELECT artist_id, ?, 0 FROM artist_tracks WHERE track_id = ?`, releaseID, trackID); err != nil {
+		return fmt.Errorf("MoveTrackToRelease: associate artists to release: %w", err)
+	}
+	if err := cleanOrphanedEntries(ctx, tx); err != nil {
+		return fmt.Errorf("MoveTrackToRelease: clean: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *Sqlite) CountTracks(ctx context.Context, opts db.CountOpts) (int64, error) {
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
 	var count int64
-	err := s.db.QueryRowContext(ctx,
-		`SELECT COUNT(DISTINCT track_id) FROM listens WHERE listened_at BETWEEN ? AND ?`,
-		t1.Unix(), t2.Unix()).Scan(&count)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT track_id) FROM user_listens
+		WHERE listened_at BETWEEN ? AND ? AND ((? = 0 AND private = 0) OR user_id = ?)`,
+		t1.Unix(), t2.Unix(), opts.UserID, opts.UserID).Scan(&count)
 	return count, err
 }
 
@@ -673,3 +757,123 @@ func (s *Sqlite) SearchTracks(ctx context.Context, q string) ([]*models.Track, e
 	}
 	return out, nil
 }
+
+func (s *Sqlite) GetUserTopTracks(ctx context.Context, opts db.GetUserTopItemsOpts) ([]db.UserTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	excludePrivate := 0
+	if opts.ExcludePrivate {
+		excludePrivate = 1
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.track_id, twt.title, COUNT(*) AS listen_count
+		FROM user_listens l
+		JOIN tracks_with_title twt ON twt.id = l.track_id
+		WHERE l.user_id = ? AND l.listened_at BETWEEN ? AND ? AND (? = 0 OR l.private = 0)
+		GROUP BY l.track_id
+		ORDER BY listen_count DESC, l.track_id
+		LIMIT ?`,
+		opts.UserID, t1.Unix(), t2.Unix(), excludePrivate, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetUserTopTracks: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]db.UserTopItem, 0, opts.Limit)
+	for rows.Next() {
+		var item db.UserTopItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.Listens); err != nil {
+			return nil, fmt.Errorf("GetUserTopTracks: scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetTrackAlbumCandidates returns every track with the given title and exactly the given artists,
+// one per album it appears on, along with the user's listen count. Ordered by album id.
+func (s *Sqlite) GetTrackAlbumCandidates(ctx context.Context, opts db.GetTrackAlbumCandidatesOpts) ([]db.TrackAlbumCandidate, error) {
+	if len(opts.ArtistIDs) == 0 {
+		return nil, errors.New("GetTrackAlbumCandidates: no artist IDs provided")
+	}
+	placeholders := strings.Repeat("?,", len(opts.ArtistIDs))
+	placeholders = placeholders[:len(placeholders)-1]
+	query := fmt.Sprintf(`
+		SELECT t.id, t.release_id,
+			(SELECT COUNT(*) FROM user_listens l WHERE l.track_id = t.id AND l.user_id = ?) AS listen_count
+		FROM tracks_with_title t
+		JOIN artist_tracks at2 ON at2.track_id = t.id
+		WHERE t.title = ? AND at2.artist_id IN (%s)
+		GROUP BY t.id
+		HAVING COUNT(DISTINCT at2.artist_id) = ?
+		ORDER BY t.release_id, t.id`, placeholders)
+
+	args := make([]any, 0, len(opts.ArtistIDs)+3)
+	args = append(args, opts.UserID, opts.Title)
+	for _, id := range opts.ArtistIDs {
+		args = append(args, id)
+	}
+	args = append(args, len(opts.ArtistIDs))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("GetTrackAlbumCandidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := make([]db.TrackAlbumCandidate, 0)
+	for rows.Next() {
+		var c db.TrackAlbumCandidate
+		if err := rows.Scan(&c.TrackID, &c.AlbumID, &c.Listens); err != nil {
+			return nil, fmt.Errorf("GetTrackAlbumCandidates: scan: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// GetArtistTopTracks returns the most listened to tracks featuring the artist within the timeframe,
+// optionally limited to the listens of a single user.
+func (s *Sqlite) GetArtistTopTracks(ctx context.Context, opts db.GetArtistTopItemsOpts) ([]db.ArtistTopItem, error) {
+	if opts.Limit == 0 {
+		opts.Limit = defaultItemsPerPage
+	}
+	t1, t2 := db.TimeframeToTimeRange(opts.Timeframe)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT l.track_id, twt.title, COUNT(*) AS listen_count, MAX(l.listened_at) AS last_listened_at
+		FROM user_listens l
+		JOIN tracks_with_title twt ON twt.id = l.track_id
+		JOIN artist_tracks at2 ON at2.track_id = l.track_id
+		WHERE at2.artist_id = ? AND l.listened_at BETWEEN ? AND ?
+			AND ((? = 0 AND l.private = 0) OR l.user_id = ?)
+		GROUP BY l.track_id
+		ORDER BY listen_count DESC, last_listened_at DESC, l.track_id
+		LIMIT ?`,
+		opts.ArtistID, t1.Unix(), t2.Unix(), opts.UserID, opts.UserID, opts.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopTracks: %w", err)
+	}
+	defer rows.Close()
+
+	items, err := scanArtistTopItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("GetArtistTopTracks: %w", err)
+	}
+	return items, nil
+}
+
+func scanArtistTopItems(rows *sql.Rows) ([]db.ArtistTopItem, error) {
+	items := make([]db.ArtistTopItem, 0)
+	for rows.Next() {
+		var item db.ArtistTopItem
+		var lastListened int64
+		if err := rows.Scan(&item.ID, &item.Title, &item.Listens, &lastListened); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		item.LastListenedAt = time.Unix(lastListened, 0)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
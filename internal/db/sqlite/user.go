@@ -51,8 +51,8 @@ func (s *Sqlite) GetUserByUsername(ctx context.Context, username string) (*model
 	var u models.User
 	var role string
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, username, role, password FROM users WHERE username = ?`,
-		strings.ToLower(username)).Scan(&u.ID, &u.Username, &role, &u.Password)
+		`SELECT id, username, role, password, comparable FROM users WHERE username = ?`,
+		strings.ToLower(username)).Scan(&u.ID, &u.Username, &role, &u.Password, &u.Comparable)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	} else if err != nil {
@@ -66,8 +66,8 @@ func (s *Sqlite) GetAdminUser(ctx context.Context) (*models.User, error) {
 	var u models.User
 	var role string
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, username, role, password FROM users WHERE role = 'admin' LIMIT 1`,
-	).Scan(&u.ID, &u.Username, &role, &u.Password)
+		`SELECT id, username, role, password, comparable FROM users WHERE role = 'admin' LIMIT 1`,
+	).Scan(&u.ID, &u.Username, &role, &u.Password, &u.Comparable)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	} else if err != nil {
@@ -77,13 +77,13 @@ func (s *Sqlite) GetAdminUser(ctx context.Context) (*models.User, error) {
 	return &u, nil
 }
 
-func (s *Sqlite) GetUserByApiKey(ctx context.Context, key string) (*models.User, error) {
+func (s *Sqlite) GetUserByApiKey(ctx context.Context, keyHash string) (*models.User, error) {
 	var u models.User
 	var role string
 	err := s.db.QueryRowContext(ctx, `
-		SELECT u.id, u.username, u.role, u.password
+		SELECT u.id, u.username, u.role, u.password, u.comparable
 		FROM users u JOIN api_keys ak ON u.id = ak.user_id
-		WHERE ak.key = ? LIMIT 1`, key).Scan(&u.ID, &u.Username, &role, &u.Password)
+		WHERE ak.key_hash = ? LIMIT 1`, keyHash).Scan(&u.ID, &u.Username, &role, &u.Password, &u.Comparable)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	} else if err != nil {
@@ -157,14 +157,20 @@ func (s *Sqlite) UpdateUser(ctx context.Context, opts db.UpdateUserOpts) error {
 			return fmt.Errorf("UpdateUser: password: %w", err)
 		}
 	}
+	if opts.Comparable != nil {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users SET comparable = ? WHERE id = ?`, *opts.Comparable, opts.ID); err != nil {
+			return fmt.Errorf("UpdateUser: comparable: %w", err)
+		}
+	}
 	return tx.Commit()
 }
 
 func (s *Sqlite) SaveApiKey(ctx context.Context, opts db.SaveApiKeyOpts) (*models.ApiKey, error) {
 	now := time.Now().Unix()
 	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO api_keys (key, user_id, created_at, label) VALUES (?,?,?,?)`,
-		opts.Key, opts.UserID, now, opts.Label,
+		`INSERT INTO api_keys (key_hash, key_hint, user_id, created_at, label) VALUES (?,?,?,?,?)`,
+		opts.KeyHash, opts.KeyHint, opts.UserID, now, opts.Label,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("SaveApiKey: %w", err)
@@ -172,7 +178,7 @@ func (s *Sqlite) SaveApiKey(ctx context.Context, opts db.SaveApiKeyOpts) (*model
 	id64, _ := res.LastInsertId()
 	return &models.ApiKey{
 		ID:        int32(id64),
-		Key:       opts.Key,
+		Hint:      opts.KeyHint,
 		UserID:    opts.UserID,
 		Label:     opts.Label,
 		CreatedAt: time.Unix(now, 0).UTC(),
@@ -181,7 +187,7 @@ func (s *Sqlite) SaveApiKey(ctx context.Context, opts db.SaveApiKeyOpts) (*model
 
 func (s *Sqlite) GetApiKeysByUserID(ctx context.Context, id int32) ([]models.ApiKey, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, key, user_id, label, created_at FROM api_keys WHERE user_id = ?`, id)
+		`SELECT id, key_hint, user_id, label, created_at FROM api_keys WHERE user_id = ? ORDER BY id`, id)
 	if err != nil {
 		return nil, fmt.Errorf("GetApiKeysByUserID: %w", err)
 	}
@@ -190,7 +196,7 @@ func (s *Sqlite) GetApiKeysByUserID(ctx context.Context, id int32) ([]models.Api
 	for rows.Next() {
 		var k models.ApiKey
 		var createdAt int64
-		if err := rows.Scan(&k.ID, &k.Key, &k.UserID, &k.Label, &createdAt); err != nil {
+		if err := rows.Scan(&k.ID, &k.Hint, &k.UserID, &k.Label, &createdAt); err != nil {
 			return nil, err
 		}
 		k.CreatedAt = time.Unix(createdAt, 0).UTC()
@@ -199,6 +205,27 @@ func (s *Sqlite) GetApiKeysByUserID(ctx context.Context, id int32) ([]models.Api
 	return keys, rows.Err()
 }
 
+// GetUnhashedApiKeys returns the keys saved before keys were stored hashed, with their plaintext key.
+func (s *Sqlite) GetUnhashedApiKeys(ctx context.Context) ([]models.ApiKey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, key, user_id, label, created_at FROM api_keys WHERE key IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("GetUnhashedApiKeys: %w", err)
+	}
+	defer rows.Close()
+	var keys []models.ApiKey
+	for rows.Next() {
+		var k models.ApiKey
+		var createdAt int64
+		if err := rows.Scan(&k.ID, &k.Key, &k.UserID, &k.Label, &createdAt); err != nil {
+			return nil, fmt.Errorf("GetUnhashedApiKeys: %w", err)
+		}
+		k.CreatedAt = time.Unix(createdAt, 0).UTC()
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
 func (s *Sqlite) UpdateApiKeyLabel(ctx context.Context, opts db.UpdateApiKeyLabelOpts) error {
 	_, err := s.db.ExecContext(ctx,
 		`UPDATE api_keys SET label = ? WHERE id = ? AND user_id = ?`,
@@ -206,9 +233,28 @@ func (s *Sqlite) UpdateApiKeyLabel(ctx context.Context, opts db.UpdateApiKeyLabe
 	return err
 }
 
-func (s *Sqlite) DeleteApiKey(ctx context.Context, id int32) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
-	return err
+// UpdateApiKeyHash replaces the plaintext key of an api key with its hash.
+func (s *Sqlite) UpdateApiKeyHash(ctx context.Context, id int32, keyHash, keyHint string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET key = NULL, key_hash = ?, key_hint = ? WHERE id = ?`,
+		keyHash, keyHint, id)
+	if err != nil {
+		return fmt.Errorf("UpdateApiKeyHash: %w", err)
+	}
+	return nil
+}
+
+// DeleteApiKey deletes an api key of the user, returning false when the user has no key with the id.
+func (s *Sqlite) DeleteApiKey(ctx context.Context, userID, id int32) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("DeleteApiKey: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("DeleteApiKey: %w", err)
+	}
+	return n > 0, nil
 }
 
 func (s *Sqlite) CountUsers(ctx context.Context) (int64, error) {
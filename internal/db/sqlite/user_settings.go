@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/db"
+)
+
+// GetImportSettings returns the user's import settings. A user who has not saved any settings
+// gets empty settings, i.e. uses the instance-wide ones.
+func (s *Sqlite) GetImportSettings(ctx context.Context, userID int32) (*db.ImportSettings, error) {
+	var ignoreBelowMs, mergeGap, dedupWindow sql.NullInt64
+	var reasonEnds sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT import_ignore_below_ms, import_merge_gap_seconds, import_dedup_window_seconds, import_reason_ends
+		FROM user_settings WHERE user_id = ?`, userID).
+		Scan(&ignoreBelowMs, &mergeGap, &dedupWindow, &reasonEnds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &db.ImportSettings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetImportSettings: %w", err)
+	}
+	settings := &db.ImportSettings{
+		IgnoreBelowMs:      parseNullableInt(ignoreBelowMs),
+		MergeGapSeconds:    parseNullableInt(mergeGap),
+		DedupWindowSeconds: parseNullableInt(dedupWindow),
+	}
+	if reasonEnds.Valid {
+		settings.ReasonEnds = strings.Split(reasonEnds.String, ",")
+	}
+	return settings, nil
+}
+
+// SaveImportSettings replaces the user's import settings.
+func (s *Sqlite) SaveImportSettings(ctx context.Context, userID int32, settings db.ImportSettings) error {
+	if userID == 0 {
+		return errors.New("SaveImportSettings: required parameter 'userID' missing")
+	}
+	var reasonEnds sql.NullString
+	if len(settings.ReasonEnds) > 0 {
+		reasonEnds = sql.NullString{String: strings.Join(settings.ReasonEnds, ","), Valid: true}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_settings (user_id, import_ignore_below_ms, import_merge_gap_seconds, import_dedup_window_seconds, import_reason_ends)
+		VALUES (?,?,?,?,?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			import_ignore_below_ms = excluded.import_ignore_below_ms,
+			import_merge_gap_seconds = excluded.import_merge_gap_seconds,
+			import_dedup_window_seconds = excluded.import_dedup_window_seconds,
+			import_reason_ends = excluded.import_reason_ends`,
+		userID, settings.IgnoreBelowMs, settings.MergeGapSeconds, settings.DedupWindowSeconds, reasonEnds)
+	if err != nil {
+		return fmt.Errorf("SaveImportSettings: %w", err)
+	}
+	return nil
+}
+
+func parseNullableInt(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
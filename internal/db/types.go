@@ -50,6 +50,141 @@ type ExportItem struct {
 	Artists            []models.ArtistWithFullAliases
 }
 
+// ListenVersion identifies the state of a set of listens. It changes when a listen is added to or
+// removed from the set, but not when the artists, albums or tracks of the listens are renamed or
+// merged, so it cannot tell that a response built from the listens shows outdated names.
+type ListenVersion struct {
+	Latest time.Time // time of the most recent listen, or zero when there are none
+	Count  int64
+}
+
+// TrackTitle is the title of a track
+type TrackTitle struct {
+	TrackID int32
+	Title   string
+}
+
+// AlbumTitle is the title of an album along with one of its artists
+type AlbumTitle struct {
+	AlbumID  int32
+	ArtistID int32
+	Title    string
+	MbzID    *uuid.UUID
+}
+
+// ImportJob is an import file that was successfully imported for a user
+type ImportJob struct {
+	ID          int32
+	UserID      int32
+	Filename    string
+	ContentHash string // hex encoded SHA-256 of the file
+	Imported    int
+	FinishedAt  time.Time
+}
+
+// ImportSettings are a user's overrides of the instance-wide import settings. A nil field means
+// the user uses the instance-wide setting.
+type ImportSettings struct {
+	IgnoreBelowMs      *int     `json:"ignore_below_ms"`
+	MergeGapSeconds    *int     `json:"merge_gap_seconds"`
+	DedupWindowSeconds *int     `json:"dedup_window_seconds"`
+	ReasonEnds         []string `json:"reason_ends"` // Spotify reason_end values of items to import
+}
+
+// UserTopItem is an artist, album or track with its listen count for a single user
+type UserTopItem struct {
+	ID      int32  `json:"id"`
+	Name    string `json:"name"`
+	Listens int64  `json:"listens"`
+}
+
+// ArtistTopItem is a track or album by an artist with its listen count and the time it was last listened to
+type ArtistTopItem struct {
+	ID             int32     `json:"id"`
+	Title          string    `json:"title"`
+	Listens        int64     `json:"listens"`
+	LastListenedAt time.Time `json:"last_listened_at"`
+}
+
+// AmbiguousAlias is an alias shared by more than one artist. Artists are in the order
+// they are preferred when matching the alias, so the first artist is the one matched.
+type AmbiguousAlias struct {
+	Alias   string                 `json:"alias"`
+	Artists []AmbiguousAliasArtist `json:"artists"`
+}
+
+type AmbiguousAliasArtist struct {
+	ID          int32      `json:"id"`
+	Name        string     `json:"name"`
+	MbzID       *uuid.UUID `json:"musicbrainz_id"`
+	ListenCount int64      `json:"listen_count"`
+}
+
+// ArtistNameCasings is every casing of an artist's primary name that is an alias of the artist.
+// The current primary name is first.
+type ArtistNameCasings struct {
+	ArtistID int32
+	Casings  []NameCasing
+}
+
+type NameCasing struct {
+	Name      string
+	Source    string
+	Primary   bool
+	SeenCount int64
+}
+
+// ListenTotals is the number of listens and the total time listened within a timeframe
+type ListenTotals struct {
+	Listens         int64 `json:"listens"`
+	SecondsListened int64 `json:"seconds_listened"`
+}
+
+// AverageAudioFeatures are the audio features of listened to tracks averaged over each listen,
+// along with the number of listens averaged. Listens to tracks without audio features are left out.
+type AverageAudioFeatures struct {
+	models.AudioFeatures
+	Listens int64 `json:"listens"`
+}
+
+// NeglectedArtist is an artist with many listens overall, none of which are recent
+type NeglectedArtist struct {
+	ID             int32            `json:"id"`
+	Name           string           `json:"name"`
+	Image          models.ImageList `json:"image"`
+	Listens        int64            `json:"listens"`
+	LastListenedAt time.Time        `json:"last_listened_at"`
+}
+
+// FavoriteArtist is an artist with its listen count and the number of distinct days it was listened to on
+type FavoriteArtist struct {
+	ID      int32            `json:"id"`
+	Name    string           `json:"name"`
+	Image   models.ImageList `json:"image"`
+	Listens int64            `json:"listens"`
+	Days    int64            `json:"days"`
+}
+
+// TrackAlbumCandidate is an existing track with a given title and artists, and the album it belongs to
+type TrackAlbumCandidate struct {
+	TrackID int32
+	AlbumID int32
+	Listens int64
+}
+
+// ListenLogEntry is a single listen with the title and duration of its track
+type ListenLogEntry struct {
+	Time     time.Time `json:"time"`
+	TrackID  int32     `json:"track_id"`
+	Title    string    `json:"title"`
+	Duration int32     `json:"duration"` // in seconds, 0 if unknown
+}
+
+type DeviceListenCount struct {
+	Device  string `json:"device"`
+	Listens int64  `json:"listens"`
+}
+
 type InterestBucket struct {
 	BucketStart time.Time `json:"bucket_start"`
 	BucketEnd   time.Time `json:"bucket_end"`
@@ -0,0 +1,33 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces the file with data, so readers never see a partly written file. The file is
+// only readable by its owner.
+func writeFileAtomic(name string, data []byte) error {
+	// CreateTemp creates the file with 0600 permissions
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"_*")
+	if err != nil {
+		return fmt.Errorf("writeFileAtomic: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writeFileAtomic: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("writeFileAtomic: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writeFileAtomic: %w", err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("writeFileAtomic: %w", err)
+	}
+	return nil
+}
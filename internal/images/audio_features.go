@@ -0,0 +1,85 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/models"
+	"github.com/zmb3/spotify/v2"
+)
+
+// Audio features are only available from Spotify, so they are looked up with the Spotify image
+// provider's client.
+
+var (
+	// ErrSpotifyDisabled is returned when audio features are requested without Spotify enabled.
+	ErrSpotifyDisabled = errors.New("spotify is not enabled")
+	// ErrAudioFeaturesNotFound is returned when the track, or its audio features, are not on Spotify.
+	ErrAudioFeaturesNotFound = errors.New("audio features not found")
+)
+
+// GetTrackAudioFeatures finds the track on Spotify by its artists and title, and returns its audio features.
+func GetTrackAudioFeatures(ctx context.Context, artists []string, title string) (*models.AudioFeatures, error) {
+	if !imgsrc.spotifyEnabled {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", ErrSpotifyDisabled)
+	}
+	if len(artists) < 1 || title == "" {
+		return nil, errors.New("GetTrackAudioFeatures: artist and title are required")
+	}
+	return imgsrc.spotifyC.GetTrackAudioFeatures(ctx, artists, title)
+}
+
+func (c *SpotifyClient) GetTrackAudioFeatures(ctx context.Context, artists []string, title string) (*models.AudioFeatures, error) {
+	l := logger.FromContext(ctx)
+
+	results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\" track:\"%s\"", sanitizeSpotifyQuery(artists[0]), sanitizeSpotifyQuery(title)), spotify.SearchTypeTrack)
+	if err != nil {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", err)
+	}
+	if results.Tracks == nil {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", ErrAudioFeaturesNotFound)
+	}
+	var id spotify.ID
+	for _, track := range results.Tracks.Tracks {
+		if spotifyTrackMatches(track, artists, title) {
+			id = track.ID
+			break
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", ErrAudioFeaturesNotFound)
+	}
+
+	features, err := c.client.GetAudioFeatures(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", err)
+	}
+	if len(features) < 1 || features[0] == nil {
+		return nil, fmt.Errorf("GetTrackAudioFeatures: %w", ErrAudioFeaturesNotFound)
+	}
+	l.Debug().Msgf("Found audio features for '%s' with Spotify ID %s", title, id)
+	return &models.AudioFeatures{
+		Tempo:        float64(features[0].Tempo),
+		Energy:       float64(features[0].Energy),
+		Danceability: float64(features[0].Danceability),
+		Valence:      float64(features[0].Valence),
+	}, nil
+}
+
+// spotifyTrackMatches reports whether a search result has the track's title and credits one of its artists.
+func spotifyTrackMatches(track spotify.FullTrack, artists []string, title string) bool {
+	if !strings.EqualFold(strings.Join(strings.Fields(track.Name), " "), strings.Join(strings.Fields(title), " ")) {
+		return false
+	}
+	for _, credited := range track.Artists {
+		for _, artist := range artists {
+			if strings.EqualFold(credited.Name, artist) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestComputeBlurHash(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	for y := range 60 {
+		for x := range 100 {
+			white.Set(x, y, color.White)
+		}
+	}
+	hash, err := ComputeBlurHash(encodePNG(t, white))
+	require.NoError(t, err)
+	// 4x3 components, then the average color after one character for the scale of the detail
+	require.Len(t, hash, 28)
+	assert.Equal(t, "L", hash[:1])
+	assert.Equal(t, "TSUA", hash[2:6])
+
+	// half black and half white has another average color and more detail
+	split := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	for y := range 60 {
+		for x := range 100 {
+			if x < 50 {
+				split.Set(x, y, color.Black)
+			} else {
+				split.Set(x, y, color.White)
+			}
+		}
+	}
+	splitHash, err := ComputeBlurHash(encodePNG(t, split))
+	require.NoError(t, err)
+	assert.Len(t, splitHash, 28)
+	assert.NotEqual(t, hash[2:6], splitHash[2:6])
+	assert.NotEqual(t, hash[6:], splitHash[6:])
+
+	_, err = ComputeBlurHash([]byte("not an image"))
+	assert.Error(t, err)
+}
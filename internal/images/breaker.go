@@ -0,0 +1,148 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gabehf/koito/internal/logger"
+)
+
+// ErrProviderUnavailable is returned for requests to an image provider that has failed too many
+// times in a row and is paused until its cooldown has passed.
+var ErrProviderUnavailable = errors.New("image provider is temporarily unavailable")
+
+const (
+	defaultProviderTimeout  = 10 * time.Second
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 5 * time.Minute
+)
+
+var (
+	breakerMu        sync.Mutex
+	breakers         = make(map[string]*circuitBreaker)
+	providerTimeouts = map[string]time.Duration{}
+	breakerThreshold = defaultBreakerThreshold
+	breakerCooldown  = defaultBreakerCooldown
+)
+
+// circuitBreaker stops requests to a provider after a number of consecutive failures, so a
+// provider that is down does not hold up every lookup until its requests time out.
+type circuitBreaker struct {
+	provider  string
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+}
+
+// breakerFor returns the circuit breaker of the provider, creating it on first use.
+func breakerFor(provider string) *circuitBreaker {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	b, ok := breakers[provider]
+	if !ok {
+		b = &circuitBreaker{provider: provider, threshold: breakerThreshold, cooldown: breakerCooldown}
+		breakers[provider] = b
+	}
+	return b
+}
+
+// allow reports whether a request may be made. Once the cooldown has passed a request is let
+// through to probe the provider; the breaker stays open until it succeeds.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.open || !time.Now().Before(b.openUntil)
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.open {
+		logger.Get().Info().Str("provider", b.provider).Msg("Image provider recovered, resuming requests")
+	}
+	b.failures = 0
+	b.open = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures < b.threshold {
+		return
+	}
+	if !b.open {
+		logger.Get().Warn().Str("provider", b.provider).Int("failures", b.failures).
+			Msgf("Image provider failed too many times in a row, pausing requests for %s", b.cooldown)
+	}
+	b.open = true
+	b.openUntil = time.Now().Add(b.cooldown)
+}
+
+// breakerTransport short-circuits requests to a provider while its circuit breaker is open.
+// Network errors, timeouts, rate limiting and server errors count as failures; any other
+// response, including not found, means the provider is up.
+type breakerTransport struct {
+	breaker *circuitBreaker
+	next    http.RoundTripper
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		return nil, fmt.Errorf("%s: %w", t.breaker.provider, ErrProviderUnavailable)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		t.breaker.failure()
+	} else {
+		t.breaker.success()
+	}
+	return resp, err
+}
+
+// providerHTTPClient returns an HTTP client for requests to the provider that uses the provider's
+// configured timeout and circuit breaker. next is the underlying transport, or
+// http.DefaultTransport when nil.
+func providerHTTPClient(provider string, next http.RoundTripper) *http.Client {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &http.Client{
+		Timeout:   providerTimeout(provider),
+		Transport: &breakerTransport{breaker: breakerFor(provider), next: next},
+	}
+}
+
+// providerTimeout returns how long a request to the provider may take.
+func providerTimeout(provider string) time.Duration {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	if timeout, ok := providerTimeouts[provider]; ok {
+		return timeout
+	}
+	return defaultProviderTimeout
+}
+
+// configureBreakers applies the provider timeouts and circuit breaker settings to clients created
+// afterwards. Zero values keep the defaults.
+func configureBreakers(timeouts map[string]time.Duration, threshold int, cooldown time.Duration) {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	for p, t := range timeouts {
+		if t > 0 {
+			providerTimeouts[p] = t
+		}
+	}
+	if threshold > 0 {
+		breakerThreshold = threshold
+	}
+	if cooldown > 0 {
+		breakerCooldown = cooldown
+	}
+}
@@ -0,0 +1,61 @@
+package images
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerTransport(t *testing.T) {
+	status := http.StatusInternalServerError
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	b := &circuitBreaker{provider: "test", threshold: 3, cooldown: time.Hour}
+	client := &http.Client{Transport: &breakerTransport{breaker: b, next: http.DefaultTransport}}
+	get := func() (*http.Response, error) {
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	// not found means the provider is up, so it never trips the breaker
+	status = http.StatusNotFound
+	for range 5 {
+		_, err := get()
+		require.NoError(t, err)
+	}
+
+	status = http.StatusInternalServerError
+	for range 3 {
+		_, err := get()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 8, requests)
+
+	// the breaker is open, so requests never reach the provider
+	_, err := get()
+	assert.True(t, errors.Is(err, ErrProviderUnavailable))
+	assert.Equal(t, 8, requests)
+
+	// after the cooldown one request probes the provider, and a success closes the breaker
+	b.openUntil = time.Now()
+	status = http.StatusOK
+	_, err = get()
+	require.NoError(t, err)
+	_, err = get()
+	require.NoError(t, err)
+	assert.Equal(t, 10, requests)
+	assert.False(t, b.open)
+}
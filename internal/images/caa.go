@@ -0,0 +1,75 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/google/uuid"
+)
+
+const caaBaseUrl = "https://coverartarchive.org"
+
+// CoverArtArchiveClient looks up front covers on the Cover Art Archive by MusicBrainz ID.
+type CoverArtArchiveClient struct {
+	url       string
+	userAgent string
+	client    *http.Client
+}
+
+func NewCoverArtArchiveClient() *CoverArtArchiveClient {
+	return &CoverArtArchiveClient{
+		url:       caaBaseUrl,
+		userAgent: cfg.UserAgent(),
+		client:    providerHTTPClient(ProviderCAA, nil),
+	}
+}
+
+// GetAlbumImageByMBID returns the url of the front cover of the release with the MusicBrainz ID, or
+// of the release group with the ID when there is no such release.
+func (c *CoverArtArchiveClient) GetAlbumImageByMBID(ctx context.Context, mbid string) (string, error) {
+	id, err := uuid.Parse(mbid)
+	if err != nil {
+		return "", fmt.Errorf("GetAlbumImageByMBID: %w", err)
+	}
+	img, err := c.frontCover(ctx, "release", id)
+	if errors.Is(err, ErrImageNotFound) {
+		img, err = c.frontCover(ctx, "release-group", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("GetAlbumImageByMBID: %w", err)
+	}
+	return img, nil
+}
+
+// frontCover returns the url of the front cover of the release or release group, or ErrImageNotFound
+// when it has none. The Cover Art Archive answers with a temporary redirect to the image on the
+// Internet Archive, which is followed to check that the image exists. The Cover Art Archive's url is
+// returned rather than the redirect's, as it keeps pointing at the current image if it is replaced.
+func (c *CoverArtArchiveClient) frontCover(ctx context.Context, entity string, id uuid.UUID) (string, error) {
+	l := logger.FromContext(ctx)
+	url := fmt.Sprintf("%s/%s/%s/front", c.url, entity, id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("frontCover: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		l.Debug().Err(err).Str("url", url).Msg("frontCover: Failed to contact CoverArtArchive")
+		return "", fmt.Errorf("frontCover: %w", err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return url, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return "", ErrImageNotFound
+	default:
+		l.Debug().Int("status", resp.StatusCode).Str("url", url).Msg("frontCover: Got non-OK response from CoverArtArchive")
+		return "", fmt.Errorf("frontCover: received non-ok status from CoverArtArchive: %s", resp.Status)
+	}
+}
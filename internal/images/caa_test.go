@@ -0,0 +1,82 @@
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverArtArchiveClient(t *testing.T) {
+	release := uuid.New()
+	releaseGroup := uuid.New()
+	broken := uuid.New()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		// like the Cover Art Archive, covers redirect to the Internet Archive
+		case "/release/" + release.String() + "/front", "/release-group/" + releaseGroup.String() + "/front":
+			http.Redirect(w, r, srv.URL+"/ia/front.jpg", http.StatusTemporaryRedirect)
+		case "/ia/front.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+		case "/release/" + broken.String() + "/front":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := &CoverArtArchiveClient{url: srv.URL, client: srv.Client()}
+	ctx := context.Background()
+
+	img, err := c.GetAlbumImageByMBID(ctx, release.String())
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/release/"+release.String()+"/front", img)
+
+	// an id that is not a release is tried as a release group
+	img, err = c.GetAlbumImageByMBID(ctx, releaseGroup.String())
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/release-group/"+releaseGroup.String()+"/front", img)
+
+	_, err = c.GetAlbumImageByMBID(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, ErrImageNotFound)
+
+	_, err = c.GetAlbumImageByMBID(ctx, broken.String())
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrImageNotFound)
+
+	_, err = c.GetAlbumImageByMBID(ctx, "not an mbid")
+	assert.Error(t, err)
+}
+
+func TestAlbumImageFromCAA_ResolvesMBID(t *testing.T) {
+	defer func(prev ImageSource) { imgsrc = prev }(imgsrc)
+	release := uuid.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/release/"+release.String()+"/front" {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	imgsrc = ImageSource{caaEnabled: true, caaC: &CoverArtArchiveClient{url: srv.URL, client: srv.Client()}}
+	mbzc := &mbz.MbzMockCaller{Releases: map[uuid.UUID]*mbz.MusicBrainzRelease{
+		release: {Title: "Album", ArtistCredit: []mbz.MusicBrainzArtistCredit{{Name: "Artist"}}},
+	}}
+	ctx := context.Background()
+
+	// without a known MBID, the release is searched for on MusicBrainz
+	img, err := albumImageFromCAA(ctx, AlbumImageOpts{Artists: []string{"Artist"}, Album: "Album", Mbzc: mbzc})
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/release/"+release.String()+"/front", img)
+
+	img, err = albumImageFromCAA(ctx, AlbumImageOpts{Artists: []string{"Artist"}, Album: "Other Album", Mbzc: mbzc})
+	require.NoError(t, err)
+	assert.Empty(t, img)
+}
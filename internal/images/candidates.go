@@ -0,0 +1,131 @@
+package images
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+// ImageCandidate is an image found by an image provider, which may or may not belong to the
+// artist or album that was searched for.
+type ImageCandidate struct {
+	URL string `json:"url"`
+	// Name of the artist or album the image belongs to, according to the provider
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// GetArtistImageCandidates returns up to limit images from the enabled image providers that may
+// belong to the artist, without applying the name matching used by GetArtistImage. Subsonic is
+// not searched, as its image URLs contain the server's credentials.
+func GetArtistImageCandidates(ctx context.Context, opts ArtistImageOpts, limit int) []ImageCandidate {
+	l := logger.FromContext(ctx)
+	ret := make([]ImageCandidate, 0)
+	if len(opts.Aliases) < 1 {
+		return ret
+	}
+	name := opts.Aliases[0]
+
+	if imgsrc.spotifyEnabled {
+		if opts.SpotifyID != "" {
+			img, err := imgsrc.spotifyC.GetArtistImageByID(ctx, opts.SpotifyID)
+			if err != nil {
+				l.Debug().Err(err).Msg("GetArtistImageCandidates: Could not get artist image from Spotify by ID")
+			} else {
+				ret = appendCandidates(ret, ImageCandidate{URL: img, Name: name, Source: ProviderSpotify})
+			}
+		}
+		found, err := imgsrc.spotifyC.SearchArtistImages(ctx, name)
+		if err != nil {
+			l.Debug().Err(err).Msg("GetArtistImageCandidates: Could not search Spotify")
+		}
+		ret = appendCandidates(ret, found...)
+	}
+	if imgsrc.deezerEnabled {
+		found, err := imgsrc.deezerC.SearchArtistImages(ctx, name)
+		if err != nil {
+			l.Debug().Err(err).Msg("GetArtistImageCandidates: Could not search Deezer")
+		}
+		ret = appendCandidates(ret, found...)
+	}
+	if imgsrc.lastfmEnabled {
+		img, err := imgsrc.lastfmC.GetArtistImage(ctx, opts.MBID, name)
+		if err != nil {
+			l.Debug().Err(err).Msg("GetArtistImageCandidates: Could not get artist image from LastFM")
+		} else {
+			ret = appendCandidates(ret, ImageCandidate{URL: img, Name: name, Source: ProviderLastFM})
+		}
+	}
+
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+// GetAlbumImageCandidates returns up to limit images from the enabled image providers that may
+// belong to the album, in the configured provider order and without applying the title matching
+// used by GetAlbumImage. Subsonic is not searched, as its image URLs contain the server's credentials.
+func GetAlbumImageCandidates(ctx context.Context, opts AlbumImageOpts, limit int) []ImageCandidate {
+	l := logger.FromContext(ctx)
+	ret := make([]ImageCandidate, 0)
+	if len(opts.Artists) < 1 {
+		return ret
+	}
+	order := imgsrc.providerOrder
+	if len(order) == 0 {
+		order = cfg.DefaultImageProviderOrder()
+	}
+
+	for _, provider := range order {
+		var found []ImageCandidate
+		var err error
+		switch provider {
+		case ProviderSpotify:
+			if imgsrc.spotifyEnabled {
+				found, err = imgsrc.spotifyC.SearchAlbumImages(ctx, opts.Artists[0], opts.Album)
+			}
+		case ProviderDeezer:
+			if imgsrc.deezerEnabled {
+				found, err = imgsrc.deezerC.SearchAlbumImages(ctx, opts.Artists[0], opts.Album)
+			}
+		case ProviderCAA:
+			var img string
+			img, err = albumImageFromCAA(ctx, opts)
+			if img != "" {
+				found = []ImageCandidate{{URL: img, Name: opts.Album, Source: ProviderCAA}}
+			}
+		case ProviderLastFM:
+			var img string
+			img, err = albumImageFromLastFM(ctx, opts)
+			if img != "" {
+				found = []ImageCandidate{{URL: img, Name: opts.Album, Source: ProviderLastFM}}
+			}
+		}
+		if err != nil {
+			l.Debug().Err(err).Msgf("GetAlbumImageCandidates: Could not search %s", provider)
+		}
+		ret = appendCandidates(ret, found...)
+	}
+
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+// appendCandidates appends the candidates that have an image not already in the list.
+func appendCandidates(list []ImageCandidate, candidates ...ImageCandidate) []ImageCandidate {
+	for _, c := range candidates {
+		if c.URL == "" {
+			continue
+		}
+		if slices.ContainsFunc(list, func(existing ImageCandidate) bool { return existing.URL == c.URL }) {
+			continue
+		}
+		list = append(list, c)
+	}
+	return list
+}
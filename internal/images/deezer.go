@@ -3,7 +3,6 @@ package images
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -32,6 +31,12 @@ type DeezerAlbum struct {
 	CoverMd  string `json:"cover_medium"`
 	CoverBig string `json:"cover_big"`
 }
+
+// image returns the largest cover of the album, preferring the xl size.
+func (a DeezerAlbum) image() string {
+	return firstNonEmpty(a.CoverXL, a.CoverBig, a.CoverMd, a.CoverSm)
+}
+
 type DeezerArtistResponse struct {
 	Data []DeezerArtist `json:"data"`
 }
@@ -43,6 +48,20 @@ type DeezerArtist struct {
 	PictureBig string `json:"picture_big"`
 }
 
+// image returns the largest picture of the artist, preferring the xl size.
+func (a DeezerArtist) image() string {
+	return firstNonEmpty(a.PictureXL, a.PictureBig, a.PictureMd, a.PictureSm)
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 const (
 	deezerBaseUrl       = "https://api.deezer.com"
 	albumImageEndpoint  = "/search/album?q=%s"
@@ -53,7 +72,7 @@ func NewDeezerClient() *DeezerClient {
 	ret := new(DeezerClient)
 	ret.url = deezerBaseUrl
 	ret.userAgent = cfg.UserAgent()
-	ret.requestQueue = queue.NewRequestQueue(5, 5)
+	ret.requestQueue = queue.NewRequestQueueWithClient(5, 5, providerHTTPClient(ProviderDeezer, nil))
 	return ret
 }
 
@@ -73,8 +92,10 @@ func (c *DeezerClient) queue(ctx context.Context, req *http.Request) ([]byte, er
 			done <- queue.RequestResult{Err: err}
 			return
 		} else if resp.StatusCode >= 300 || resp.StatusCode < 200 {
+			resp.Body.Close()
 			err = fmt.Errorf("received non-ok status from Deezer: %s", resp.Status)
 			done <- queue.RequestResult{Body: nil, Err: err}
+			return
 		}
 		defer resp.Body.Close()
 
@@ -88,7 +109,7 @@ func (c *DeezerClient) queue(ctx context.Context, req *http.Request) ([]byte, er
 
 func (c *DeezerClient) getEntity(ctx context.Context, endpoint string, result any) error {
 	l := logger.FromContext(ctx)
-	url := deezerBaseUrl + endpoint
+	url := c.url + endpoint
 	l.Debug().Msgf("Sending request to ImageSrc: GET %s", url)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -117,7 +138,10 @@ func (c *DeezerClient) GetArtistImages(ctx context.Context, aliases []string) (s
 	l := logger.FromContext(ctx)
 	resp := new(DeezerArtistResponse)
 
-	aliasesUniq := utils.UniqueIgnoringCase(aliases)
+	aliasesUniq := usableAliases(aliases)
+	if len(aliasesUniq) == 0 {
+		return "", fmt.Errorf("GetArtistImages: %w", ErrImageNotFound)
+	}
 	aliasesAscii := utils.RemoveNonAscii(aliasesUniq)
 
 	// Deezer very often uses romanized names for foreign artists, so check those first
@@ -127,11 +151,11 @@ func (c *DeezerClient) GetArtistImages(ctx context.Context, aliases []string) (s
 			return "", fmt.Errorf("GetArtistImages: %w", err)
 		}
 		if len(resp.Data) < 1 {
-			return "", errors.New("GetArtistImages: artist image not found")
+			return "", fmt.Errorf("GetArtistImages: %w", ErrImageNotFound)
 		}
 		for _, v := range resp.Data {
 			if strings.EqualFold(v.Name, a) {
-				img := v.PictureXL
+				img := v.image()
 				l.Debug().Msgf("Found artist images for %s: %v", a, img)
 				return img, nil
 			}
@@ -145,17 +169,17 @@ func (c *DeezerClient) GetArtistImages(ctx context.Context, aliases []string) (s
 			return "", fmt.Errorf("GetArtistImages: %w", err)
 		}
 		if len(resp.Data) < 1 {
-			return "", errors.New("GetArtistImages: artist image not found")
+			return "", fmt.Errorf("GetArtistImages: %w", ErrImageNotFound)
 		}
 		for _, v := range resp.Data {
 			if strings.EqualFold(v.Name, a) {
-				img := v.PictureXL
+				img := v.image()
 				l.Debug().Msgf("Found artist images for %s: %v", a, img)
 				return img, nil
 			}
 		}
 	}
-	return "", errors.New("GetArtistImages: artist image not found")
+	return "", fmt.Errorf("GetArtistImages: %w", ErrImageNotFound)
 }
 
 func (c *DeezerClient) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
@@ -171,7 +195,7 @@ func (c *DeezerClient) GetAlbumImages(ctx context.Context, artists []string, alb
 		if len(resp.Data) > 0 {
 			for _, v := range resp.Data {
 				if strings.EqualFold(v.Title, album) {
-					img := v.CoverXL
+					img := v.image()
 					l.Debug().Msgf("Found album images for %s: %v", album, img)
 					return img, nil
 				}
@@ -186,11 +210,43 @@ func (c *DeezerClient) GetAlbumImages(ctx context.Context, artists []string, alb
 	}
 	for _, v := range resp.Data {
 		if strings.EqualFold(v.Title, album) {
-			img := v.CoverXL
+			img := v.image()
 			l.Debug().Msgf("Found album images for %s: %v", album, img)
 			return img, nil
 		}
 	}
 
-	return "", errors.New("GetAlbumImages: album image not found")
+	return "", fmt.Errorf("GetAlbumImages: %w", ErrImageNotFound)
+}
+
+// SearchArtistImages returns the image of every artist found by searching Deezer for the name.
+func (c *DeezerClient) SearchArtistImages(ctx context.Context, name string) ([]ImageCandidate, error) {
+	resp := new(DeezerArtistResponse)
+	err := c.getEntity(ctx, fmt.Sprintf(artistImageEndpoint, url.QueryEscape(name)), resp)
+	if err != nil {
+		return nil, fmt.Errorf("SearchArtistImages: %w", err)
+	}
+	ret := make([]ImageCandidate, 0, len(resp.Data))
+	for _, v := range resp.Data {
+		if img := v.image(); img != "" {
+			ret = append(ret, ImageCandidate{URL: img, Name: v.Name, Source: ProviderDeezer})
+		}
+	}
+	return ret, nil
+}
+
+// SearchAlbumImages returns the cover of every album found by searching Deezer for the artist and album title.
+func (c *DeezerClient) SearchAlbumImages(ctx context.Context, artist, album string) ([]ImageCandidate, error) {
+	resp := new(DeezerAlbumResponse)
+	err := c.getEntity(ctx, fmt.Sprintf(albumImageEndpoint, url.QueryEscape(fmt.Sprintf("artist:\"%s\"album:\"%s\"", artist, album))), resp)
+	if err != nil {
+		return nil, fmt.Errorf("SearchAlbumImages: %w", err)
+	}
+	ret := make([]ImageCandidate, 0, len(resp.Data))
+	for _, v := range resp.Data {
+		if img := v.image(); img != "" {
+			ret = append(ret, ImageCandidate{URL: img, Name: v.Title, Source: ProviderDeezer})
+		}
+	}
+	return ret, nil
 }
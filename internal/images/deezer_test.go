@@ -0,0 +1,61 @@
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabehf/koito/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeezerClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/artist":
+			if r.URL.Query().Get("q") == "Broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"data":[
+				{"name":"Other Artist","picture_xl":"https://deezer.test/other-xl.jpg"},
+				{"name":"Artist","picture_xl":"https://deezer.test/artist-xl.jpg","picture_big":"https://deezer.test/artist-big.jpg"}
+			]}`))
+		case "/search/album":
+			// no xl cover, so the next largest size is used
+			w.Write([]byte(`{"data":[
+				{"title":"Album","cover_big":"https://deezer.test/album-big.jpg","cover_medium":"https://deezer.test/album-md.jpg"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := &DeezerClient{url: srv.URL, requestQueue: queue.NewRequestQueue(100, 100)}
+	defer c.Shutdown()
+	ctx := context.Background()
+
+	img, err := c.GetArtistImages(ctx, []string{"artist"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://deezer.test/artist-xl.jpg", img)
+
+	img, err = c.GetAlbumImages(ctx, []string{"Artist"}, "album")
+	require.NoError(t, err)
+	assert.Equal(t, "https://deezer.test/album-big.jpg", img)
+
+	_, err = c.GetArtistImages(ctx, []string{"Broken"})
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrImageNotFound)
+
+	_, err = c.GetArtistImages(ctx, []string{"Nobody"})
+	assert.ErrorIs(t, err, ErrImageNotFound)
+	_, err = c.GetAlbumImages(ctx, []string{"Artist"}, "Other Album")
+	assert.ErrorIs(t, err, ErrImageNotFound)
+
+	found, err := c.SearchArtistImages(ctx, "Artist")
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+}
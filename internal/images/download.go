@@ -0,0 +1,90 @@
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabehf/koito/internal/cfg"
+)
+
+// the directory in the config directory that downloaded images are stored in
+const downloadedImagesDir = "images"
+
+// images larger than this are not downloaded
+const maxDownloadedImageSize = 20 << 20
+
+var downloadedImageExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// CacheImage downloads the image at url and stores it in the images directory of the config
+// directory, so it no longer depends on the remote url staying available. Images are named by the
+// hash of their content, so the same cover shared by several albums is only stored once. Returns the
+// path of the stored image. Local images are not copied, and their own path is returned.
+func CacheImage(ctx context.Context, url string) (string, error) {
+	if path, ok := LocalImagePath(url); ok {
+		return path, nil
+	}
+	path, err := cacheImageTo(ctx, filepath.Join(cfg.ConfigDir(), downloadedImagesDir), url)
+	if err != nil {
+		return "", fmt.Errorf("CacheImage: %w", err)
+	}
+	return path, nil
+}
+
+func cacheImageTo(ctx context.Context, dir, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("cacheImageTo: %w", err)
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("cacheImageTo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cacheImageTo: failed to download image, status: %s", resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", fmt.Errorf("cacheImageTo: URL does not point to an image, content type: %s", contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadedImageSize+1))
+	if err != nil {
+		return "", fmt.Errorf("cacheImageTo: %w", err)
+	}
+	if len(data) > maxDownloadedImageSize {
+		return "", fmt.Errorf("cacheImageTo: image is larger than %d bytes", maxDownloadedImageSize)
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + downloadedImageExts[strings.TrimSpace(strings.Split(contentType, ";")[0])]
+	path := filepath.Join(dir, name)
+	// the same content was already stored, possibly from another url
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("cacheImageTo: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		return "", fmt.Errorf("cacheImageTo: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return "", fmt.Errorf("cacheImageTo: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,61 @@
+package images
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheImage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.jpg", "/same-as-a.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("cover"))
+		case "/b.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("other cover"))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	a, err := cacheImageTo(ctx, dir, srv.URL+"/a.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, ".jpg", filepath.Ext(a))
+	data, err := os.ReadFile(a)
+	require.NoError(t, err)
+	assert.Equal(t, "cover", string(data))
+
+	// the same image from another url is stored once
+	same, err := cacheImageTo(ctx, dir, srv.URL+"/same-as-a.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, a, same)
+
+	b, err := cacheImageTo(ctx, dir, srv.URL+"/b.png")
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, ".png", filepath.Ext(b))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	_, err = cacheImageTo(ctx, dir, srv.URL+"/page")
+	assert.Error(t, err)
+	_, err = cacheImageTo(ctx, dir, srv.URL+"/missing.jpg")
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/google/uuid"
+	"github.com/zmb3/spotify/v2"
+)
+
+// maxFoundImages bounds the number of remembered lookups, so a large import cannot grow the cache
+// without limit.
+const maxFoundImages = 10000
+
+// the file in the config directory that remembered lookups are saved to between restarts
+const foundImagesFile = "image_lookups.json"
+
+// how often remembered lookups are saved while new ones are being added
+const foundImagesSaveInterval = time.Minute
+
+var foundImages = &foundImageCache{entries: make(map[string]foundImage)}
+
+// foundImage is the result of a lookup. An empty URL means no provider had an image.
+type foundImage struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// foundImageCache remembers the result of a lookup, so the same artist or album looked up again, such
+// as during a later import, does not query the providers again. Lookups that found nothing are
+// remembered for a shorter time than found images, so images added to the providers later are still
+// picked up.
+type foundImageCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	negativeTTL time.Duration
+	entries     map[string]foundImage
+	// Where lookups are saved between restarts. Not saved when empty.
+	file    string
+	dirty   bool
+	savedAt time.Time
+}
+
+// get returns the remembered result of the lookup. A result with an empty url means no provider
+// had an image.
+func (c *foundImageCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !time.Now().Before(e.Expires) {
+		delete(c.entries, key)
+		c.dirty = true
+		return "", false
+	}
+	// entries saved while the cache was configured differently
+	if (e.URL == "" && c.negativeTTL <= 0) || (e.URL != "" && c.ttl <= 0) {
+		return "", false
+	}
+	return e.URL, true
+}
+
+// remember saves the result of a lookup. Lookups that failed because a provider could not be reached
+// are not remembered, as they say nothing about whether the image exists.
+func (c *foundImageCache) remember(key, url string, err error) {
+	switch {
+	case err == nil && url != "":
+		c.set(key, url)
+	case isTransient(err):
+		return
+	case err == nil || errors.Is(err, ErrImageNotFound):
+		c.set(key, "")
+	}
+}
+
+func (c *foundImageCache) set(key, url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ttl := c.ttl
+	if url == "" {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxFoundImages {
+		for k, e := range c.entries {
+			if !now.Before(e.Expires) {
+				delete(c.entries, k)
+			}
+		}
+		// still full of live entries, so make room by dropping an arbitrary one
+		for k := range c.entries {
+			if len(c.entries) < maxFoundImages {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = foundImage{URL: url, Expires: now.Add(ttl)}
+	c.dirty = true
+	if c.file != "" && now.Sub(c.savedAt) >= foundImagesSaveInterval {
+		if err := c.saveLocked(); err != nil {
+			logger.Get().Warn().Err(err).Msg("Failed to save image lookups")
+		}
+	}
+}
+
+// load restores the lookups saved by a previous run that have not expired.
+func (c *foundImageCache) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == "" {
+		return nil
+	}
+	b, err := os.ReadFile(c.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load: %w", err)
+	}
+	var saved map[string]foundImage
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return fmt.Errorf("load: %w", err)
+	}
+	now := time.Now()
+	for k, e := range saved {
+		if now.Before(e.Expires) && len(c.entries) < maxFoundImages {
+			c.entries[k] = e
+		}
+	}
+	// the cache was just read, so there is no need to write it again right away
+	c.savedAt = now
+	return nil
+}
+
+// save writes the lookups to the cache file when any have changed since it was last written.
+func (c *foundImageCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saveLocked()
+}
+
+func (c *foundImageCache) saveLocked() error {
+	if c.file == "" || !c.dirty {
+		return nil
+	}
+	b, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	if err := writeFileAtomic(c.file, b); err != nil {
+		return fmt.Errorf("save: %w", err)
+	}
+	c.dirty = false
+	c.savedAt = time.Now()
+	return nil
+}
+
+// isTransient reports whether a lookup failed because a provider could not be reached or was
+// rate limited, rather than because it has no image.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrProviderUnavailable) || errors.Is(err, ErrSpotifyTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var spotifyErr spotify.Error
+	if errors.As(err, &spotifyErr) {
+		return spotifyErr.Status == http.StatusTooManyRequests || spotifyErr.Status >= 500
+	}
+	return false
+}
+
+// artistImageKey identifies an artist image lookup. Aliases are normalized the same way as for the
+// lookup itself, so lookups that would search for the same names share an entry.
+func artistImageKey(opts ArtistImageOpts) string {
+	return strings.Join([]string{
+		"artist",
+		opts.SpotifyID,
+		idString(opts.MBID),
+		normalizedNames(opts.Aliases),
+	}, "\x00")
+}
+
+// albumImageKey identifies an album image lookup, normalized like artistImageKey.
+func albumImageKey(opts AlbumImageOpts) string {
+	return strings.Join([]string{
+		"album",
+		normalizedNames(opts.Artists),
+		strings.ToLower(strings.TrimSpace(opts.Album)),
+		strings.ToLower(strings.TrimSpace(opts.Track)),
+		opts.TrackSpotifyID,
+		idString(opts.ReleaseMbzID),
+		idString(opts.ReleaseGroupMbzID),
+		strconv.Itoa(opts.TrackCount),
+	}, "\x00")
+}
+
+func normalizedNames(names []string) string {
+	return strings.ToLower(strings.Join(usableAliases(names), "\x00"))
+}
+
+func idString(id *uuid.UUID) string {
+	if isNilID(id) {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,126 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zmb3/spotify/v2"
+)
+
+func newTestFoundImageCache() *foundImageCache {
+	return &foundImageCache{ttl: time.Hour, negativeTTL: time.Minute, entries: make(map[string]foundImage)}
+}
+
+func TestFoundImageCache(t *testing.T) {
+	c := newTestFoundImageCache()
+
+	key := artistImageKey(ArtistImageOpts{Aliases: []string{"Artist", " artist ", "Other Name"}})
+	// the same names as the lookup would search for share an entry
+	assert.Equal(t, key, artistImageKey(ArtistImageOpts{Aliases: []string{"ARTIST", "", "other name"}}))
+	assert.NotEqual(t, key, artistImageKey(ArtistImageOpts{Aliases: []string{"Other Name", "Artist"}}))
+	assert.NotEqual(t, key, artistImageKey(ArtistImageOpts{Aliases: []string{"Artist", "Other Name"}, SpotifyID: "abc"}))
+
+	var nilID *uuid.UUID
+	id := uuid.New()
+	albumKey := albumImageKey(AlbumImageOpts{Artists: []string{"Artist"}, Album: "Album ", ReleaseMbzID: nilID})
+	assert.Equal(t, albumKey, albumImageKey(AlbumImageOpts{Artists: []string{"artist"}, Album: "album"}))
+	assert.NotEqual(t, albumKey, albumImageKey(AlbumImageOpts{Artists: []string{"Artist"}, Album: "Album", ReleaseMbzID: &id}))
+
+	_, ok := c.get(key)
+	assert.False(t, ok)
+
+	c.remember(key, "https://example.com/artist.jpg", nil)
+	img, ok := c.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/artist.jpg", img)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), c.entries[key].Expires, time.Second)
+
+	c.entries[key] = foundImage{URL: img, Expires: time.Now()}
+	_, ok = c.get(key)
+	assert.False(t, ok, "expired entries are not returned")
+	assert.Empty(t, c.entries)
+
+	// a zero ttl disables remembering found images
+	c.ttl = 0
+	c.remember(key, "https://example.com/artist.jpg", nil)
+	_, ok = c.get(key)
+	assert.False(t, ok)
+}
+
+func TestFoundImageCache_NotFound(t *testing.T) {
+	c := newTestFoundImageCache()
+
+	// lookups that found nothing are remembered for the shorter negative ttl
+	c.remember("missing", "", fmt.Errorf("GetAlbumImage: %w", ErrImageNotFound))
+	img, ok := c.get("missing")
+	assert.True(t, ok)
+	assert.Empty(t, img)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), c.entries["missing"].Expires, time.Second)
+
+	c.remember("missing artist", "", nil)
+	_, ok = c.get("missing artist")
+	assert.True(t, ok)
+
+	// lookups that failed because a provider could not be reached are not remembered
+	transient := []error{
+		errors.Join(ErrImageNotFound, fmt.Errorf("spotify: %w", ErrProviderUnavailable)),
+		errors.Join(ErrImageNotFound, &net.OpError{Op: "dial", Err: errors.New("connection refused")}),
+		errors.Join(ErrImageNotFound, spotify.Error{Status: 429, Message: "rate limited"}),
+	}
+	for i, err := range transient {
+		key := fmt.Sprintf("transient %d", i)
+		c.remember(key, "", err)
+		_, ok = c.get(key)
+		assert.False(t, ok, err.Error())
+	}
+
+	// a zero negative ttl disables remembering lookups that found nothing
+	c.negativeTTL = 0
+	c.remember("disabled", "", ErrImageNotFound)
+	_, ok = c.get("disabled")
+	assert.False(t, ok)
+	_, ok = c.get("missing")
+	assert.False(t, ok, "remembered misses are ignored once disabled")
+}
+
+func TestFoundImageCache_SavedBetweenRestarts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), foundImagesFile)
+	c := newTestFoundImageCache()
+	c.file = file
+	c.remember("found", "https://example.com/album.jpg", nil)
+	c.remember("missing", "", ErrImageNotFound)
+	c.entries["expired"] = foundImage{URL: "https://example.com/old.jpg", Expires: time.Now().Add(-time.Minute)}
+	require.NoError(t, c.save())
+
+	restarted := newTestFoundImageCache()
+	restarted.file = file
+	require.NoError(t, restarted.load())
+	img, ok := restarted.get("found")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/album.jpg", img)
+	img, ok = restarted.get("missing")
+	assert.True(t, ok)
+	assert.Empty(t, img)
+	assert.NotContains(t, restarted.entries, "expired")
+
+	// no file is not an error
+	empty := newTestFoundImageCache()
+	empty.file = filepath.Join(t.TempDir(), foundImagesFile)
+	require.NoError(t, empty.load())
+	assert.Empty(t, empty.entries)
+}
+
+func TestFoundImageCache_Bounded(t *testing.T) {
+	c := newTestFoundImageCache()
+	for i := range maxFoundImages + 10 {
+		c.remember(albumImageKey(AlbumImageOpts{Artists: []string{"Artist"}, Album: "Album", TrackCount: i}), "https://example.com/album.jpg", nil)
+	}
+	assert.Len(t, c.entries, maxFoundImages)
+}
@@ -3,12 +3,19 @@ package images
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/gabehf/koito/internal/utils"
+	"github.com/gabehf/koito/queue"
 	"github.com/google/uuid"
 )
 
@@ -16,12 +23,15 @@ type ImageSource struct {
 	deezerEnabled   bool
 	deezerC         *DeezerClient
 	caaEnabled      bool
+	caaC            *CoverArtArchiveClient
 	spotifyEnabled  bool
 	spotifyC        *SpotifyClient
 	subsonicEnabled bool
 	subsonicC       *SubsonicClient
 	lastfmEnabled   bool
 	lastfmC         *LastFMClient
+	localC          *LocalImageProvider
+	providerOrder   []string
 }
 type ImageSourceOpts struct {
 	UserAgent      string
@@ -30,14 +40,34 @@ type ImageSourceOpts struct {
 	EnableSpotify  bool
 	EnableSubsonic bool
 	EnableLastFM   bool
+	// Order in which album image providers are tried. Uses cfg.DefaultImageProviderOrder when empty.
+	ProviderOrder []string
+	// Optional. Request timeout of each provider, keyed by provider name.
+	ProviderTimeouts map[string]time.Duration
+	// Optional. Consecutive failures after which a provider is paused, and for how long.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Optional. How long found images, and lookups that found no image, are remembered for the same
+	// lookup. Zero disables remembering them.
+	PositiveCacheTTL time.Duration
+	NegativeCacheTTL time.Duration
+	// Optional. Directory of user supplied images, which are used instead of any provider's.
+	LocalImagesDir string
 }
 
 var once sync.Once
 var imgsrc ImageSource
 
+// ErrImageNotFound is returned when none of the enabled image providers have an image. The clients of
+// the providers wrap it when they have no image, and return any other error when they could not be
+// asked, so callers can tell a missing image from a provider being unavailable.
+var ErrImageNotFound = errors.New("image not found")
+
 type ArtistImageOpts struct {
 	Aliases []string
 	MBID    *uuid.UUID
+	// Optional. When set, the artist image is fetched from Spotify by ID before searching by name.
+	SpotifyID string
 }
 
 type AlbumImageOpts struct {
@@ -45,15 +75,42 @@ type AlbumImageOpts struct {
 	Album             string
 	ReleaseMbzID      *uuid.UUID
 	ReleaseGroupMbzID *uuid.UUID
+	// Optional. Used to resolve a release MBID for Cover Art Archive lookups when none is known.
+	Mbzc mbz.MusicBrainzCaller
+	// Optional. The number of known tracks on the album, used to prefer the matching edition.
+	TrackCount int
+	// Optional. A track on the album, used to find the cover by the track when Album is empty.
+	Track string
+	// Optional. The Spotify ID of a track on the album. When set, the cover is fetched from Spotify
+	// by the track's ID before searching by name.
+	TrackSpotifyID string
 }
 
-const caaBaseUrl = "https://coverartarchive.org"
+const (
+	ProviderSpotify  = "spotify"
+	ProviderSubsonic = "subsonic"
+	ProviderCAA      = "caa"
+	ProviderLastFM   = "lastfm"
+	ProviderDeezer   = "deezer"
+)
 
 // all functions are no-op if no providers are enabled
 func Initialize(opts ImageSourceOpts) {
 	once.Do(func() {
+		imgsrc.providerOrder = opts.ProviderOrder
+		configureBreakers(opts.ProviderTimeouts, opts.BreakerThreshold, opts.BreakerCooldown)
+		foundImages.ttl = opts.PositiveCacheTTL
+		foundImages.negativeTTL = opts.NegativeCacheTTL
+		foundImages.file = filepath.Join(cfg.ConfigDir(), foundImagesFile)
+		if err := foundImages.load(); err != nil {
+			logger.Get().Warn().Err(err).Msg("Failed to load saved image lookups")
+		}
+		if opts.LocalImagesDir != "" {
+			imgsrc.localC = NewLocalImageProvider(opts.LocalImagesDir)
+		}
 		if opts.EnableCAA {
 			imgsrc.caaEnabled = true
+			imgsrc.caaC = NewCoverArtArchiveClient()
 		}
 		if opts.EnableDeezer {
 			imgsrc.deezerEnabled = true
@@ -75,6 +132,9 @@ func Initialize(opts ImageSourceOpts) {
 }
 
 func Shutdown() {
+	if err := foundImages.save(); err != nil {
+		logger.Get().Warn().Err(err).Msg("Failed to save image lookups")
+	}
 	if imgsrc.deezerC != nil {
 		imgsrc.deezerC.Shutdown()
 	}
@@ -83,134 +143,240 @@ func Shutdown() {
 	}
 }
 
+// SearchStats returns a snapshot of the request queue of each enabled image provider that searches
+// through one, keyed by provider name.
+func SearchStats() map[string]queue.Stats {
+	ret := make(map[string]queue.Stats)
+	if imgsrc.deezerC != nil {
+		ret[ProviderDeezer] = imgsrc.deezerC.requestQueue.Stats()
+	}
+	if imgsrc.lastfmC != nil {
+		ret[ProviderLastFM] = imgsrc.lastfmC.requestQueue.Stats()
+	}
+	if imgsrc.spotifyC != nil {
+		ret[ProviderSpotify] = imgsrc.spotifyC.requestQueue.Stats()
+	}
+	return ret
+}
+
+// GetArtistImage returns the url of an image of the artist from the first enabled provider that has
+// one. Recent lookups of the same artist are answered without querying the providers. Not finding an
+// image is not an error, but failing to reach the providers is.
 func GetArtistImage(ctx context.Context, opts ArtistImageOpts) (string, error) {
 	l := logger.FromContext(ctx)
+	// local images always win, and are not remembered so that images added later are picked up
+	if img := localImage(ctx, func(p *LocalImageProvider) (string, error) {
+		return p.GetArtistImages(ctx, opts.Aliases)
+	}); img != "" {
+		return img, nil
+	}
 	if !imgsrc.spotifyEnabled && !imgsrc.deezerEnabled && !imgsrc.subsonicEnabled && !imgsrc.lastfmEnabled {
 		l.Warn().Msg("GetArtistImage: No image providers are enabled")
 		return "", nil
 	}
-	if imgsrc.subsonicEnabled {
-		img, err := imgsrc.subsonicC.GetArtistImage(ctx, opts.MBID, opts.Aliases[0])
+	key := artistImageKey(opts)
+	if img, ok := foundImages.get(key); ok {
+		l.Debug().Bool("found", img != "").Msg("GetArtistImage: Using result of previous lookup")
+		return img, nil
+	}
+	img, err := findArtistImage(ctx, opts)
+	foundImages.remember(key, img, err)
+	if err != nil && !isTransient(err) {
+		// not finding an artist image is not an error for callers
+		l.Debug().Err(err).Msg("GetArtistImage: Could not find artist image from any provider")
+		return "", nil
+	}
+	return img, err
+}
+
+func findArtistImage(ctx context.Context, opts ArtistImageOpts) (string, error) {
+	l := logger.FromContext(ctx)
+	// a known Spotify ID identifies the artist exactly, so it is tried before any name search
+	if imgsrc.spotifyEnabled && opts.SpotifyID != "" {
+		img, err := imgsrc.spotifyC.GetArtistImageByID(ctx, opts.SpotifyID)
 		if err != nil {
-			l.Debug().Err(err).Msg("GetArtistImage: Could not find artist image from Subsonic")
+			l.Debug().Err(err).Msg("GetArtistImage: Could not find artist image from Spotify by ID, falling back to search")
 		} else if img != "" {
 			return img, nil
 		}
-	} else {
-		l.Debug().Msg("GetArtistImage: Subsonic image fetching is disabled")
 	}
-	if imgsrc.spotifyEnabled {
-		img, err := imgsrc.spotifyC.GetArtistImages(ctx, opts.Aliases)
+	opts.Aliases = usableAliases(opts.Aliases)
+	if len(opts.Aliases) == 0 {
+		l.Debug().Msg("GetArtistImage: No usable aliases to search for")
+		return "", ErrImageNotFound
+	}
+	img, err := newProviderChain(opts.MBID, AlbumImageOpts{}).GetArtistImages(ctx, opts.Aliases)
+	if err != nil {
+		return "", fmt.Errorf("GetArtistImage: %w", err)
+	}
+	return img, nil
+}
+
+// GetAlbumImage returns the url of the album's cover from the first provider in the configured order
+// that has one. Recent lookups of the same album are answered without querying the providers.
+func GetAlbumImage(ctx context.Context, opts AlbumImageOpts) (string, error) {
+	l := logger.FromContext(ctx)
+	if img := localImage(ctx, func(p *LocalImageProvider) (string, error) {
+		album := opts.Album
+		if album == "" {
+			album = opts.Track
+		}
+		return p.GetAlbumImages(ctx, opts.Artists, album)
+	}); img != "" {
+		return img, nil
+	}
+	if !imgsrc.spotifyEnabled && !imgsrc.subsonicEnabled && !imgsrc.caaEnabled && !imgsrc.lastfmEnabled && !imgsrc.deezerEnabled {
+		l.Warn().Msg("GetAlbumImage: No image providers are enabled")
+		return "", ErrImageNotFound
+	}
+	key := albumImageKey(opts)
+	if img, ok := foundImages.get(key); ok {
+		l.Debug().Bool("found", img != "").Msg("GetAlbumImage: Using result of previous lookup")
+		if img == "" {
+			return "", fmt.Errorf("GetAlbumImage: %w", ErrImageNotFound)
+		}
+		return img, nil
+	}
+	img, err := findAlbumImage(ctx, opts)
+	foundImages.remember(key, img, err)
+	return img, err
+}
+
+func findAlbumImage(ctx context.Context, opts AlbumImageOpts) (string, error) {
+	l := logger.FromContext(ctx)
+	var skip []string
+	// a known MusicBrainz ID identifies the cover exactly, so the Cover Art Archive is tried before
+	// any name search
+	if imgsrc.caaEnabled && (!isNilID(opts.ReleaseMbzID) || !isNilID(opts.ReleaseGroupMbzID)) {
+		img, err := albumImageFromCAA(ctx, opts)
 		if err != nil {
-			l.Warn().Err(err).Msg("Failed to get artist image from Spotify, retrying")
-			img, err = imgsrc.spotifyC.GetArtistImages(ctx, opts.Aliases)
-			if err != nil {
-				l.Debug().Err(err).Msg("GetArtistImage: Could not find artist image from Spotify after retry")
-			} else if img != "" {
-				return img, nil
-			}
+			l.Debug().Err(err).Msg("GetAlbumImage: Could not find album image from CoverArtArchive by MBID, falling back to search")
 		} else if img != "" {
 			return img, nil
 		}
-	} else {
-		l.Debug().Msg("GetArtistImage: Spotify image fetching is disabled")
+		skip = append(skip, ProviderCAA)
 	}
-	if imgsrc.deezerEnabled {
-		img, err := imgsrc.deezerC.GetArtistImages(ctx, opts.Aliases)
+	// as does a known Spotify track ID, whose album is the cover's
+	if imgsrc.spotifyEnabled && opts.TrackSpotifyID != "" {
+		img, err := imgsrc.spotifyC.GetTrackImageByID(ctx, opts.TrackSpotifyID)
 		if err != nil {
-			l.Debug().Err(err).Msg("GetArtistImage: Could not find artist image from Deezer")
+			l.Debug().Err(err).Msg("GetAlbumImage: Could not find album image from Spotify by track ID, falling back to search")
 		} else if img != "" {
 			return img, nil
 		}
-	} else {
-		l.Debug().Msg("GetArtistImage: Deezer image fetching is disabled")
 	}
-	if imgsrc.lastfmEnabled {
-		img, err := imgsrc.lastfmC.GetArtistImage(ctx, opts.MBID, opts.Aliases[0])
-		if err != nil {
-			l.Debug().Err(err).Msg("GetArtistImage: Could not find artist image from LastFM")
-		} else if img != "" {
+	// without an album title there is nothing to search the providers for, but Spotify can find the
+	// album by one of its tracks
+	if opts.Album == "" && opts.Track != "" {
+		if img, err := albumImageFromSpotifyTrack(ctx, opts); err == nil && img != "" {
 			return img, nil
+		} else if err != nil {
+			l.Debug().Err(err).Msg("GetAlbumImage: Could not find album image from Spotify by track")
 		}
-	} else {
-		l.Debug().Msg("GetArtistImage: LastFM image fetching is disabled")
 	}
-	return "", nil
+	img, err := newProviderChain(opts.ReleaseMbzID, opts, skip...).GetAlbumImages(ctx, opts.Artists, opts.Album)
+	if err != nil {
+		l.Debug().Err(err).Msg("GetAlbumImage: Could not find album image from any provider")
+		return "", fmt.Errorf("GetAlbumImage: %w", err)
+	}
+	return img, nil
 }
 
-func GetAlbumImage(ctx context.Context, opts AlbumImageOpts) (string, error) {
+// localImage returns the user supplied image found by get, or an empty string when local images are
+// not enabled or there is none.
+func localImage(ctx context.Context, get func(*LocalImageProvider) (string, error)) string {
+	if imgsrc.localC == nil {
+		return ""
+	}
+	img, err := get(imgsrc.localC)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("Failed to look up local image")
+	}
+	return img
+}
+
+func albumImageFromSpotifyTrack(ctx context.Context, opts AlbumImageOpts) (string, error) {
+	if !imgsrc.spotifyEnabled {
+		return "", nil
+	}
+	return imgsrc.spotifyC.GetTrackImages(ctx, opts.Artists, opts.Track)
+}
+
+func albumImageFromLastFM(ctx context.Context, opts AlbumImageOpts) (string, error) {
+	if !imgsrc.lastfmEnabled {
+		return "", nil
+	}
+	return imgsrc.lastfmC.GetAlbumImage(ctx, opts.ReleaseMbzID, opts.Artists[0], opts.Album)
+}
+
+// albumImageFromCAA looks up the front cover on the Cover Art Archive, trying the release before the
+// release group. When the album has no MusicBrainz IDs and a MusicBrainz caller is provided, the
+// release ID is first resolved by searching MusicBrainz with the album title and artist.
+func albumImageFromCAA(ctx context.Context, opts AlbumImageOpts) (string, error) {
+	if !imgsrc.caaEnabled {
+		return "", nil
+	}
 	l := logger.FromContext(ctx)
-	if imgsrc.spotifyEnabled {
-		l.Debug().Msg("Attempting to find album image from Spotify")
-		img, err := imgsrc.spotifyC.GetAlbumImages(ctx, opts.Artists, opts.Album)
+	l.Debug().Msg("Attempting to find album image from CoverArtArchive")
+	releaseMbzID := opts.ReleaseMbzID
+	if isNilID(releaseMbzID) && isNilID(opts.ReleaseGroupMbzID) && opts.Mbzc != nil && len(opts.Artists) > 0 {
+		id, err := opts.Mbzc.SearchReleaseID(ctx, opts.Artists[0], opts.Album)
 		if err != nil {
-			l.Warn().Err(err).Msg("Failed to get album image from Spotify, retrying")
-			img, err = imgsrc.spotifyC.GetAlbumImages(ctx, opts.Artists, opts.Album)
-			if err != nil {
-				return "", err
-			}
+			l.Debug().Err(err).Msg("albumImageFromCAA: Could not resolve release MBID from MusicBrainz")
+		} else {
+			releaseMbzID = &id
 		}
-		return img, nil
 	}
-	if imgsrc.subsonicEnabled {
-		img, err := imgsrc.subsonicC.GetAlbumImage(ctx, opts.ReleaseMbzID, opts.Artists[0], opts.Album)
-		if err != nil {
-			l.Debug().Err(err).Msg("GetAlbumImage: Could not find artist image from Subsonic")
+	var lookupErr error
+	for _, lookup := range []struct {
+		entity string
+		id     *uuid.UUID
+	}{{"release", releaseMbzID}, {"release-group", opts.ReleaseGroupMbzID}} {
+		if isNilID(lookup.id) {
+			continue
 		}
-		if img != "" {
+		img, err := imgsrc.caaC.frontCover(ctx, lookup.entity, *lookup.id)
+		if err == nil {
 			return img, nil
 		}
-		l.Debug().Msg("Could not find album cover from Subsonic")
-	}
-	if imgsrc.caaEnabled {
-		l.Debug().Msg("Attempting to find album image from CoverArtArchive")
-		if opts.ReleaseMbzID != nil && *opts.ReleaseMbzID != uuid.Nil {
-			url := fmt.Sprintf(caaBaseUrl+"/release/%s/front", opts.ReleaseMbzID.String())
-			resp, err := http.DefaultClient.Head(url)
-			if err != nil {
-				l.Debug().Err(err).Msg("GetAlbumImage: Could not find artist image from CoverArtArchive with Release MBID")
-			} else {
-				if resp.StatusCode == 200 {
-					return url, nil
-				} else {
-					l.Debug().Int("status", resp.StatusCode).Msg("GetAlbumImage: Got non-OK response from CoverArtArchive")
-				}
-			}
-		}
-		if opts.ReleaseGroupMbzID != nil && *opts.ReleaseGroupMbzID != uuid.Nil {
-			url := fmt.Sprintf(caaBaseUrl+"/release-group/%s/front", opts.ReleaseGroupMbzID.String())
-			resp, err := http.DefaultClient.Head(url)
-			if err != nil {
-				l.Debug().Err(err).Msg("GetAlbumImage: Could not find artist image from CoverArtArchive with Release Group MBID")
-			}
-			if resp.StatusCode == 200 {
-				return url, nil
-			}
-		}
-	}
-	if imgsrc.lastfmEnabled {
-		img, err := imgsrc.lastfmC.GetAlbumImage(ctx, opts.ReleaseMbzID, opts.Artists[0], opts.Album)
-		if err != nil {
-			l.Debug().Err(err).Msg("GetAlbumImage: Could not find artist image from Subsonic")
-		}
-		if img != "" {
-			return img, nil
+		if !errors.Is(err, ErrImageNotFound) {
+			lookupErr = err
 		}
-		l.Debug().Msg("Could not find album cover from Subsonic")
 	}
-	if imgsrc.deezerEnabled {
-		l.Debug().Msg("Attempting to find album image from Deezer")
-		img, err := imgsrc.deezerC.GetAlbumImages(ctx, opts.Artists, opts.Album)
-		if err != nil {
-			l.Debug().Err(err).Msg("GetAlbumImage: Could not find artist image from Deezer")
-			return "", err
-		}
-		return img, nil
+	if lookupErr != nil {
+		return "", fmt.Errorf("albumImageFromCAA: %w", lookupErr)
 	}
-	l.Warn().Msg("GetAlbumImage: No image providers are enabled")
 	return "", nil
 }
 
+// GetReleaseGroupImage returns the url of the front cover for a release group from the CoverArtArchive.
+func GetReleaseGroupImage(ctx context.Context, releaseGroupMbzID uuid.UUID) (string, error) {
+	if !imgsrc.caaEnabled {
+		return "", errors.New("GetReleaseGroupImage: CoverArtArchive is disabled")
+	}
+	img, err := imgsrc.caaC.frontCover(ctx, "release-group", releaseGroupMbzID)
+	if err != nil {
+		return "", fmt.Errorf("GetReleaseGroupImage: %w", err)
+	}
+	return img, nil
+}
+
+// usableAliases returns the unique aliases that are worth searching for, leaving out
+// empty and whitespace-only names that malformed data can produce.
+func usableAliases(aliases []string) []string {
+	usable := make([]string, 0, len(aliases))
+	for _, a := range aliases {
+		if a = strings.TrimSpace(a); a != "" {
+			usable = append(usable, a)
+		}
+	}
+	return utils.UniqueIgnoringCase(usable)
+}
+
+func isNilID(id *uuid.UUID) bool {
+	return id == nil || *id == uuid.Nil
+}
+
 // ValidateImageURL checks if the URL points to a valid image by performing a HEAD request.
 func ValidateImageURL(url string) error {
 	resp, err := http.Head(url)
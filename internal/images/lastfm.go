@@ -58,7 +58,7 @@ func NewLastFMClient() *LastFMClient {
 	ret.apiKey = cfg.LastFMApiKey()
 	ret.baseUrl = lastFMApiBaseUrl
 	ret.userAgent = cfg.UserAgent()
-	ret.requestQueue = queue.NewRequestQueue(5, 5)
+	ret.requestQueue = queue.NewRequestQueueWithClient(5, 5, providerHTTPClient(ProviderLastFM, nil))
 	return ret
 }
 
@@ -106,7 +106,7 @@ func (c *LastFMClient) getEntity(ctx context.Context, params url.Values, result
 
 	l.Debug().Msgf("Sending request to LastFM: GET %s", reqUrl.String())
 
-	req, err := http.NewRequest("GET", reqUrl.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqUrl.String(), nil)
 	if err != nil {
 		return fmt.Errorf("getEntity: %w", err)
 	}
@@ -131,6 +131,9 @@ func (c *LastFMClient) getEntity(ctx context.Context, params url.Values, result
 // Last.fm stopped serving real artist images years ago and returns this for nearly every artist.
 const lastFMPlaceholderHash = "2a96cbd8b46e442fc41c2b86b821562f"
 
+// lastFMErrNotFound is the API error Last.fm answers with when it does not know the artist or album.
+const lastFMErrNotFound = 6
+
 // selectBestImage picks the largest available image from the LastFM slice
 func (c *LastFMClient) selectBestImage(images []lastFMImage) string {
 	// Rank preference: mega > extralarge > large > medium > small
@@ -225,16 +228,20 @@ func (c *LastFMClient) GetAlbumImage(ctx context.Context, mbid *uuid.UUID, artis
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("GetAlbumImage: %v", err)
+		return "", fmt.Errorf("GetAlbumImage: %w", err)
 	}
 
+	if resp.Error == lastFMErrNotFound {
+		return "", fmt.Errorf("GetAlbumImage: %w", ErrImageNotFound)
+	}
 	if resp.Error != 0 {
 		return "", fmt.Errorf("GetAlbumImage: LastFM API error %d: %s", resp.Error, resp.Message)
 	}
 
+	// only the placeholder is the same as no image
 	best := c.selectBestImage(resp.Album.Image)
 	if best == "" {
-		return "", fmt.Errorf("GetAlbumImage: no suitable image found")
+		return "", fmt.Errorf("GetAlbumImage: %w", ErrImageNotFound)
 	}
 
 	return best, nil
@@ -280,20 +287,23 @@ func (c *LastFMClient) GetArtistImage(ctx context.Context, mbid *uuid.UUID, arti
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("GetArtistImage: %v", err)
+		return "", fmt.Errorf("GetArtistImage: %w", err)
 	}
 
+	if resp.Error == lastFMErrNotFound {
+		return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
+	}
 	if resp.Error != 0 {
 		return "", fmt.Errorf("GetArtistImage: LastFM API error %d: %s", resp.Error, resp.Message)
 	}
 
 	best := c.selectBestImage(resp.Artist.Image)
 	if best == "" {
-		return "", fmt.Errorf("GetArtistImage: no suitable image found")
+		return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
 	}
 
 	if err := ValidateImageURL(best); err != nil {
-		return "", fmt.Errorf("GetArtistImage: failed to validate image url")
+		return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
 	}
 
 	return best, nil
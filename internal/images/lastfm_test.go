@@ -0,0 +1,53 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabehf/koito/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastFMClient_GetAlbumImage(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			// the images themselves
+			w.Header().Set("Content-Type", "image/jpeg")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		placeholder := fmt.Sprintf(`{"#text":"%s/i/u/300x300/%s.png","size":"extralarge"}`, srv.URL, lastFMPlaceholderHash)
+		switch r.URL.Query().Get("album") {
+		case "Album":
+			fmt.Fprintf(w, `{"album":{"name":"Album","image":[
+				{"#text":"%[1]s/i/u/34s/cover.jpg","size":"small"},
+				{"#text":"%[1]s/i/u/300x300/cover.jpg","size":"extralarge"}
+			]}}`, srv.URL)
+		case "Placeholder":
+			fmt.Fprintf(w, `{"album":{"name":"Placeholder","image":[%s]}}`, placeholder)
+		default:
+			w.Write([]byte(`{"error":6,"message":"Album not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := &LastFMClient{apiKey: "key", baseUrl: srv.URL + "/", requestQueue: queue.NewRequestQueueWithClient(1000, 1000, srv.Client())}
+	defer c.requestQueue.Shutdown()
+	ctx := context.Background()
+
+	img, err := c.GetAlbumImage(ctx, nil, "Artist", "Album")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/i/u/600x600/cover.jpg", img)
+
+	// the placeholder Last.fm returns for missing art is not an image
+	_, err = c.GetAlbumImage(ctx, nil, "Artist", "Placeholder")
+	assert.ErrorIs(t, err, ErrImageNotFound)
+
+	_, err = c.GetAlbumImage(ctx, nil, "Artist", "Unknown")
+	assert.ErrorIs(t, err, ErrImageNotFound)
+}
@@ -0,0 +1,134 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/utils"
+)
+
+const (
+	localArtistsDir = "artists"
+	localAlbumsDir  = "albums"
+)
+
+var localImageExts = []string{".jpg", ".jpeg", ".png", ".webp", ".gif"}
+
+// LocalImageProvider finds user supplied images in a directory, which take precedence over the image
+// providers. Artist images are named after the artist in the artists subdirectory, and album covers
+// after the artist and album, separated by a dash, in the albums subdirectory. Names are compared as
+// slugs, so file names only need to match ignoring case, accents and punctuation.
+type LocalImageProvider struct {
+	dir string
+}
+
+func NewLocalImageProvider(dir string) *LocalImageProvider {
+	return &LocalImageProvider{dir: filepath.Clean(dir)}
+}
+
+// GetArtistImages returns the file url of the image of the first alias that has one.
+func (p *LocalImageProvider) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	keys := make([]string, 0, len(aliases))
+	for _, a := range aliases {
+		if slug := slugify(a); slug != "" {
+			keys = append(keys, slug)
+		}
+	}
+	return p.find(ctx, localArtistsDir, keys)
+}
+
+// GetAlbumImages returns the file url of the cover of the album by the first artist that has one.
+func (p *LocalImageProvider) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	albumSlug := slugify(album)
+	if albumSlug == "" {
+		return "", nil
+	}
+	keys := make([]string, 0, len(artists))
+	for _, a := range artists {
+		if slug := slugify(a); slug != "" {
+			keys = append(keys, slug+"-"+albumSlug)
+		}
+	}
+	return p.find(ctx, localAlbumsDir, keys)
+}
+
+// find returns the file url of the image in the subdirectory whose slugified name is the first of keys
+// to match, or an empty string when there is none.
+func (p *LocalImageProvider) find(ctx context.Context, subdir string, keys []string) (string, error) {
+	dir := filepath.Join(p.dir, subdir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("find: %w", err)
+	}
+	files := make(map[string]string, len(entries))
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || !slices.Contains(localImageExts, ext) {
+			continue
+		}
+		slug := slugify(strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())))
+		if _, ok := files[slug]; !ok {
+			files[slug] = filepath.Join(dir, e.Name())
+		}
+	}
+	for _, key := range keys {
+		if path, ok := files[key]; ok {
+			logger.FromContext(ctx).Debug().Msgf("Found local image %s", path)
+			return localImageURL(path)
+		}
+	}
+	return "", nil
+}
+
+// Path returns the path of the file a url returned by the provider points to. Only files inside the
+// provider's directory are returned.
+func (p *LocalImageProvider) Path(u string) (string, bool) {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Scheme != "file" {
+		return "", false
+	}
+	abs, err := filepath.Abs(p.dir)
+	if err != nil {
+		return "", false
+	}
+	path := filepath.Clean(filepath.FromSlash(parsed.Path))
+	rel, err := filepath.Rel(abs, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return path, true
+}
+
+// LocalImagePath returns the path of the local image a url points to, when local images are enabled
+// and the url is one of theirs.
+func LocalImagePath(u string) (string, bool) {
+	if imgsrc.localC == nil {
+		return "", false
+	}
+	return imgsrc.localC.Path(u)
+}
+
+func localImageURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("localImageURL: %w", err)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// slugify returns the name in the form local image files are named by, e.g. "Café Vol. 1" becomes
+// "cafe-vol-1".
+func slugify(name string) string {
+	return strings.ReplaceAll(utils.NormalizeForMatching(name), " ", "-")
+}
@@ -0,0 +1,66 @@
+package images
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalImageProvider(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"artists/Sigur Rós.jpg",
+		"artists/notes.txt",
+		"albums/radiohead-ok-computer.jpg",
+		"albums/Björk - Vespertine.PNG",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("image"), 0644))
+	}
+	p := NewLocalImageProvider(dir)
+	ctx := context.Background()
+
+	// names match ignoring case, accents and punctuation
+	img, err := p.GetArtistImages(ctx, []string{"Jónsi", "sigur ros"})
+	require.NoError(t, err)
+	path, ok := p.Path(img)
+	require.True(t, ok, img)
+	assert.Equal(t, filepath.Join(dir, "artists", "Sigur Rós.jpg"), path)
+
+	img, err = p.GetAlbumImages(ctx, []string{"Thom Yorke", "Radiohead"}, "OK Computer")
+	require.NoError(t, err)
+	path, ok = p.Path(img)
+	require.True(t, ok, img)
+	assert.Equal(t, filepath.Join(dir, "albums", "radiohead-ok-computer.jpg"), path)
+
+	img, err = p.GetAlbumImages(ctx, []string{"bjork"}, "Vespertine")
+	require.NoError(t, err)
+	assert.NotEmpty(t, img)
+
+	// files that are not images are ignored
+	img, err = p.GetArtistImages(ctx, []string{"Notes"})
+	require.NoError(t, err)
+	assert.Empty(t, img)
+
+	img, err = p.GetAlbumImages(ctx, []string{"Radiohead"}, "Kid A")
+	require.NoError(t, err)
+	assert.Empty(t, img)
+
+	// no subdirectory is not an error
+	img, err = NewLocalImageProvider(t.TempDir()).GetArtistImages(ctx, []string{"Radiohead"})
+	require.NoError(t, err)
+	assert.Empty(t, img)
+
+	// only files inside the directory are read
+	_, ok = p.Path("file:///etc/passwd")
+	assert.False(t, ok)
+	_, ok = p.Path("file://" + filepath.ToSlash(filepath.Join(dir, "..", "other.jpg")))
+	assert.False(t, ok)
+	_, ok = p.Path("https://example.com" + filepath.ToSlash(filepath.Join(dir, "artists", "Sigur Rós.jpg")))
+	assert.False(t, ok)
+}
@@ -0,0 +1,169 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/google/uuid"
+)
+
+// ImageProvider finds artist and album images by name. A provider that has no image returns an
+// empty url, or an error describing why it could not find one.
+type ImageProvider interface {
+	GetArtistImages(ctx context.Context, aliases []string) (string, error)
+	GetAlbumImages(ctx context.Context, artists []string, album string) (string, error)
+}
+
+var (
+	_ ImageProvider = (*SpotifyClient)(nil)
+	_ ImageProvider = (*DeezerClient)(nil)
+	_ ImageProvider = (*ProviderChain)(nil)
+	_ ImageProvider = (*LocalImageProvider)(nil)
+)
+
+type namedProvider struct {
+	name     string
+	provider ImageProvider
+}
+
+// ProviderChain tries each of its providers in order, returning the first image found.
+type ProviderChain struct {
+	providers []namedProvider
+}
+
+// Add appends a provider to the end of the chain.
+func (c *ProviderChain) Add(name string, p ImageProvider) {
+	c.providers = append(c.providers, namedProvider{name: name, provider: p})
+}
+
+// GetArtistImages returns the first artist image found by the providers. When none of them have
+// one, the error wraps ErrImageNotFound along with the error of every provider that failed.
+func (c *ProviderChain) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	return c.find(ctx, "artist", func(p ImageProvider) (string, error) {
+		return p.GetArtistImages(ctx, aliases)
+	})
+}
+
+// GetAlbumImages returns the first album image found by the providers, with errors as for GetArtistImages.
+func (c *ProviderChain) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	return c.find(ctx, "album", func(p ImageProvider) (string, error) {
+		return p.GetAlbumImages(ctx, artists, album)
+	})
+}
+
+func (c *ProviderChain) find(ctx context.Context, kind string, get func(ImageProvider) (string, error)) (string, error) {
+	l := logger.FromContext(ctx)
+	errs := []error{ErrImageNotFound}
+	for _, p := range c.providers {
+		img, err := get(p.provider)
+		if err != nil {
+			l.Debug().Err(err).Msgf("Could not find %s image from %s", kind, p.name)
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+			continue
+		}
+		if img != "" {
+			l.Debug().Msgf("Found %s image from %s", kind, p.name)
+			return img, nil
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+// newProviderChain returns a chain of the enabled providers in the configured order. mbid is the
+// MusicBrainz ID of the artist or release being looked up, and album holds the details of an album
+// lookup that only some providers use. Providers in skip are left out.
+func newProviderChain(mbid *uuid.UUID, album AlbumImageOpts, skip ...string) *ProviderChain {
+	order := imgsrc.providerOrder
+	if len(order) == 0 {
+		order = cfg.DefaultImageProviderOrder()
+	}
+	chain := new(ProviderChain)
+	for _, name := range order {
+		if slices.Contains(skip, name) {
+			continue
+		}
+		switch {
+		case name == ProviderSpotify && imgsrc.spotifyEnabled:
+			chain.Add(name, spotifyLookup{c: imgsrc.spotifyC, trackCount: album.TrackCount})
+		case name == ProviderSubsonic && imgsrc.subsonicEnabled:
+			chain.Add(name, subsonicLookup{c: imgsrc.subsonicC, mbid: mbid})
+		case name == ProviderCAA && imgsrc.caaEnabled:
+			chain.Add(name, caaLookup{opts: album})
+		case name == ProviderLastFM && imgsrc.lastfmEnabled:
+			chain.Add(name, lastfmLookup{c: imgsrc.lastfmC, mbid: mbid})
+		case name == ProviderDeezer && imgsrc.deezerEnabled:
+			chain.Add(name, imgsrc.deezerC)
+		}
+	}
+	return chain
+}
+
+// spotifyLookup retries a failed Spotify search once, and prefers the album edition with the known
+// number of tracks.
+type spotifyLookup struct {
+	c          *SpotifyClient
+	trackCount int
+}
+
+func (s spotifyLookup) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	img, err := s.c.GetArtistImages(ctx, aliases)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("Failed to get artist image from Spotify, retrying")
+		return s.c.GetArtistImages(ctx, aliases)
+	}
+	return img, nil
+}
+
+func (s spotifyLookup) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	img, err := s.c.GetAlbumImagesForTrackCount(ctx, artists, album, s.trackCount)
+	if err != nil {
+		logger.FromContext(ctx).Warn().Err(err).Msg("Failed to get album image from Spotify, retrying")
+		return s.c.GetAlbumImagesForTrackCount(ctx, artists, album, s.trackCount)
+	}
+	return img, nil
+}
+
+// subsonicLookup searches Subsonic for the first name, using the MusicBrainz ID when known.
+type subsonicLookup struct {
+	c    *SubsonicClient
+	mbid *uuid.UUID
+}
+
+func (s subsonicLookup) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	return s.c.GetArtistImage(ctx, s.mbid, aliases[0])
+}
+
+func (s subsonicLookup) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	return s.c.GetAlbumImage(ctx, s.mbid, artists[0], album)
+}
+
+// lastfmLookup searches Last.fm for the first name, using the MusicBrainz ID when known.
+type lastfmLookup struct {
+	c    *LastFMClient
+	mbid *uuid.UUID
+}
+
+func (s lastfmLookup) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	return s.c.GetArtistImage(ctx, s.mbid, aliases[0])
+}
+
+func (s lastfmLookup) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	return s.c.GetAlbumImage(ctx, s.mbid, artists[0], album)
+}
+
+// caaLookup looks up album covers on the Cover Art Archive, which has no artist images.
+type caaLookup struct {
+	opts AlbumImageOpts
+}
+
+func (s caaLookup) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	return "", nil
+}
+
+func (s caaLookup) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	return albumImageFromCAA(ctx, s.opts)
+}
@@ -0,0 +1,81 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProvider struct {
+	img   string
+	err   error
+	calls int
+}
+
+func (p *stubProvider) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	p.calls++
+	return p.img, p.err
+}
+
+func (p *stubProvider) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	p.calls++
+	return p.img, p.err
+}
+
+func TestProviderChain(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("provider is down")
+	failing := &stubProvider{err: errDown}
+	empty := &stubProvider{}
+	found := &stubProvider{img: "https://example.com/found.jpg"}
+	unused := &stubProvider{img: "https://example.com/unused.jpg"}
+
+	chain := new(ProviderChain)
+	chain.Add("failing", failing)
+	chain.Add("empty", empty)
+	chain.Add("found", found)
+	chain.Add("unused", unused)
+
+	img, err := chain.GetArtistImages(ctx, []string{"Artist"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/found.jpg", img)
+	img, err = chain.GetAlbumImages(ctx, []string{"Artist"}, "Album")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/found.jpg", img)
+	assert.Equal(t, 2, failing.calls)
+	assert.Equal(t, 0, unused.calls, "providers after the one that found an image are not tried")
+
+	// when no provider has an image, the error includes why each one failed
+	chain = new(ProviderChain)
+	chain.Add("failing", failing)
+	chain.Add("empty", empty)
+	_, err = chain.GetAlbumImages(ctx, []string{"Artist"}, "Album")
+	assert.ErrorIs(t, err, ErrImageNotFound)
+	assert.ErrorIs(t, err, errDown)
+	assert.Contains(t, err.Error(), "failing: provider is down")
+
+	_, err = new(ProviderChain).GetArtistImages(ctx, []string{"Artist"})
+	assert.ErrorIs(t, err, ErrImageNotFound)
+}
+
+func TestNewProviderChain_Order(t *testing.T) {
+	defer func(prev ImageSource) { imgsrc = prev }(imgsrc)
+	names := func(c *ProviderChain) []string {
+		ret := make([]string, len(c.providers))
+		for i, p := range c.providers {
+			ret[i] = p.name
+		}
+		return ret
+	}
+
+	// disabled providers are left out of the default order
+	imgsrc = ImageSource{caaEnabled: true, deezerEnabled: true, deezerC: &DeezerClient{}, lastfmEnabled: true, lastfmC: &LastFMClient{}}
+	assert.Equal(t, []string{ProviderCAA, ProviderLastFM, ProviderDeezer}, names(newProviderChain(nil, AlbumImageOpts{})))
+
+	imgsrc.providerOrder = []string{ProviderDeezer, ProviderSpotify, ProviderCAA, ProviderSubsonic, ProviderLastFM}
+	assert.Equal(t, []string{ProviderDeezer, ProviderCAA, ProviderLastFM}, names(newProviderChain(nil, AlbumImageOpts{})))
+	assert.Equal(t, []string{ProviderDeezer, ProviderLastFM}, names(newProviderChain(nil, AlbumImageOpts{}, ProviderCAA)))
+}
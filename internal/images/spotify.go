@@ -9,28 +9,81 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gabehf/koito/internal/cfg"
 	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/romanizer"
 	"github.com/gabehf/koito/internal/utils"
 	"github.com/gabehf/koito/queue"
-	"github.com/gosimple/unidecode"
 	"github.com/zmb3/spotify/v2"
 )
 
-// authTransport adds Authorization header to HTTP requests
+// the most Spotify searches that are in flight at once
+const spotifyMaxConcurrent = 5
+
+// rate limited requests are retried this many times before the rate limited response is returned
+const spotifyMaxRetries = 3
+
+// the longest a rate limited request waits before it is retried, whatever Spotify asks for
+var spotifyMaxRetryWait = 60 * time.Second
+
+// authTransport adds Authorization header to HTTP requests, and retries requests that are rate limited
+// once Spotify's Retry-After has passed.
 type authTransport struct {
 	client *SpotifyClient
 }
 
 func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if t.client.accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+t.client.accessToken)
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if t.client.accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+t.client.accessToken)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == spotifyMaxRetries {
+			return resp, err
+		}
+		// a request body that was already sent cannot be sent again
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		wait := retryAfter(resp.Header.Get("Retry-After"))
+		resp.Body.Close()
+		logger.FromContext(ctx).Debug().Dur("wait", wait).Int("attempt", attempt+1).Msg("Spotify request was rate limited, retrying")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	return http.DefaultTransport.RoundTrip(req)
+}
+
+// retryAfter returns how long to wait according to a Retry-After header, which holds either a
+// number of seconds or a date. The wait is at most spotifyMaxRetryWait, and one second when the
+// header is missing or invalid.
+func retryAfter(v string) time.Duration {
+	wait := time.Second
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		wait = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		wait = time.Until(at)
+	}
+	return max(0, min(wait, spotifyMaxRetryWait))
 }
 
 type SpotifyClient struct {
@@ -42,6 +95,8 @@ type SpotifyClient struct {
 	accessToken  string
 	tokenExpiry  time.Time
 	tokenMutex   sync.Mutex
+	// Where the access token is saved between restarts. Not saved when empty.
+	tokenFile string
 }
 
 const (
@@ -52,15 +107,14 @@ func NewSpotifyClient() *SpotifyClient {
 	ret := new(SpotifyClient)
 	ret.url = spotifyBaseUrl
 	ret.userAgent = cfg.UserAgent()
-	ret.requestQueue = queue.NewRequestQueue(5, 5)
+	ret.tokenFile = filepath.Join(cfg.ConfigDir(), spotifyTokenFile)
 
 	// Create authenticated HTTP client
-	ret.httpClient = &http.Client{
-		Transport: &authTransport{client: ret},
-	}
+	ret.httpClient = providerHTTPClient(ProviderSpotify, &authTransport{client: ret})
+	ret.requestQueue = queue.NewRequestQueueWithLimit(5, 5, spotifyMaxConcurrent, ret.httpClient)
 
-	// Authenticate with Spotify
-	err := ret.authenticate()
+	// Authenticate with Spotify, reusing the token of the previous run when it is still valid
+	err := ret.ensureToken(context.Background())
 	if err != nil {
 		// Log error but don't fail - client will work without auth for now
 		// This allows the system to continue working even if Spotify auth fails
@@ -72,7 +126,7 @@ func NewSpotifyClient() *SpotifyClient {
 	return ret
 }
 
-func (c *SpotifyClient) authenticate() error {
+func (c *SpotifyClient) authenticate(ctx context.Context) error {
 	clientID := cfg.SpotifyClientId()
 	clientSecret := cfg.SpotifyClientSecret()
 
@@ -88,7 +142,7 @@ func (c *SpotifyClient) authenticate() error {
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
 
-	req, err := http.NewRequest("POST", "https://accounts.spotify.com/api/token", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://accounts.spotify.com/api/token", strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create auth request: %w", err)
 	}
@@ -130,6 +184,10 @@ func (c *SpotifyClient) authenticate() error {
 
 	logger.Get().Debug().Time("token_expiry", c.tokenExpiry).Msg("Spotify token updated successfully")
 
+	if err := c.saveToken(); err != nil {
+		logger.Get().Warn().Err(err).Msg("Failed to save Spotify token")
+	}
+
 	return nil
 }
 
@@ -138,12 +196,15 @@ func (c *SpotifyClient) ensureToken(ctx context.Context) error {
 	defer c.tokenMutex.Unlock()
 
 	l := logger.FromContext(ctx)
-	l.Debug().Str("access_token", c.accessToken).Time("token_expiry", c.tokenExpiry).Msg("Checking token status")
+	if c.accessToken == "" {
+		c.loadToken()
+	}
+	l.Debug().Bool("token_set", c.accessToken != "").Time("token_expiry", c.tokenExpiry).Msg("Checking token status")
 
-	if c.accessToken == "" || time.Now().After(c.tokenExpiry.Add(-10*time.Minute)) {
+	if !c.tokenValid() {
 		// Token is missing or will expire in less than 10 minutes
 		l.Debug().Msg("Token needs refresh, calling authenticate")
-		return c.authenticate()
+		return c.authenticate(ctx)
 	}
 	l.Debug().Msg("Token is valid")
 	return nil
@@ -153,17 +214,46 @@ func (c *SpotifyClient) Shutdown() {
 	c.requestQueue.Shutdown()
 }
 
+// spotifyQueryReplacer removes the characters that end a quoted phrase, start a field filter or group
+// terms in a Spotify search query. Spotify ignores punctuation when matching names, so dropping them
+// does not change what a name matches.
+var spotifyQueryReplacer = strings.NewReplacer(`"`, " ", `:`, " ", `(`, " ", `)`, " ", `\`, " ")
+
+// sanitizeSpotifyQuery makes a name safe to use as the value of a field filter in a Spotify search query.
+func sanitizeSpotifyQuery(s string) string {
+	return strings.Join(strings.Fields(spotifyQueryReplacer.Replace(s)), " ")
+}
+
 func (c *SpotifyClient) searchEntity(ctx context.Context, query string, searchType spotify.SearchType) (*spotify.SearchResult, error) {
 	l := logger.FromContext(ctx)
 	l.Debug().Msgf("Searching Spotify for: %s", query)
 
 	// Ensure we have a valid token
-	if err := c.ensureToken(ctx); err != nil {
+	timeout := providerTimeout(ProviderSpotify)
+	tokenCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := withSpotifyTimeout(ctx, tokenCtx, timeout, c.ensureToken(tokenCtx))
+	cancel()
+	if err != nil {
 		l.Err(err).Msg("Failed to ensure valid Spotify token")
 		return nil, fmt.Errorf("searchEntity: %w", err)
 	}
 
-	results, err := c.client.Search(ctx, query, searchType)
+	// searches go through the request queue, so concurrent lookups during an import are rate limited.
+	// The timeout starts once the search is sent, so time spent waiting in the queue does not count.
+	var results *spotify.SearchResult
+	resultChan := c.requestQueue.Enqueue(func(_ *http.Client, done chan<- queue.RequestResult) {
+		searchCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		var err error
+		results, err = c.client.Search(searchCtx, query, searchType)
+		done <- queue.RequestResult{Err: withSpotifyTimeout(ctx, searchCtx, timeout, err)}
+	})
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case result := <-resultChan:
+		err = result.Err
+	}
 	if err != nil {
 		l.Err(err).Msg("Spotify search failed")
 		return nil, fmt.Errorf("searchEntity: %w", err)
@@ -172,27 +262,118 @@ func (c *SpotifyClient) searchEntity(ctx context.Context, query string, searchTy
 	return results, nil
 }
 
+// ErrSpotifyTimeout is returned when a request to Spotify takes longer than the configured timeout.
+var ErrSpotifyTimeout = errors.New("spotify request timed out")
+
+// withSpotifyTimeout wraps err with ErrSpotifyTimeout when it was caused by the request's own timeout
+// rather than by ctx, the context of the caller, being done.
+func withSpotifyTimeout(ctx, reqCtx context.Context, timeout time.Duration, err error) error {
+	if err == nil || ctx.Err() != nil || !errors.Is(reqCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w after %s: %w", ErrSpotifyTimeout, timeout, err)
+}
+
+// GetArtistImageByID fetches the artist with the given Spotify ID directly, returning its largest image.
+func (c *SpotifyClient) GetArtistImageByID(ctx context.Context, spotifyID string) (string, error) {
+	l := logger.FromContext(ctx)
+
+	if err := c.ensureToken(ctx); err != nil {
+		l.Err(err).Msg("Failed to ensure valid Spotify token")
+		return "", fmt.Errorf("GetArtistImageByID: %w", err)
+	}
+
+	artist, err := c.client.GetArtist(ctx, spotify.ID(spotifyID))
+	if err != nil {
+		return "", fmt.Errorf("GetArtistImageByID: %w", err)
+	}
+	img := bestImage(artist.Images)
+	if img == "" {
+		return "", errors.New("GetArtistImageByID: artist has no images")
+	}
+	l.Debug().Msgf("Found artist image for Spotify ID %s: %v", spotifyID, img)
+	return img, nil
+}
+
+// GetTrackImageByID fetches the track with the given Spotify ID directly, returning the largest image
+// of its album.
+func (c *SpotifyClient) GetTrackImageByID(ctx context.Context, spotifyID string) (string, error) {
+	l := logger.FromContext(ctx)
+
+	if err := c.ensureToken(ctx); err != nil {
+		l.Err(err).Msg("Failed to ensure valid Spotify token")
+		return "", fmt.Errorf("GetTrackImageByID: %w", err)
+	}
+
+	track, err := c.client.GetTrack(ctx, spotify.ID(spotifyID))
+	if err != nil {
+		return "", fmt.Errorf("GetTrackImageByID: %w", err)
+	}
+	img := bestImage(track.Album.Images)
+	if img == "" {
+		return "", fmt.Errorf("GetTrackImageByID: %w", ErrImageNotFound)
+	}
+	l.Debug().Msgf("Found album image for Spotify track ID %s: %v", spotifyID, img)
+	return img, nil
+}
+
+// ParseSpotifyArtistID returns the artist ID from a Spotify artist URI (spotify:artist:...),
+// an open.spotify.com artist URL, or a bare ID. It returns an empty string when no ID is found.
+func ParseSpotifyArtistID(s string) string {
+	return parseSpotifyID(s, "artist")
+}
+
+// ParseSpotifyTrackID returns the track ID from a Spotify track URI (spotify:track:...), an
+// open.spotify.com track URL, or a bare ID. It returns an empty string when no ID is found.
+func ParseSpotifyTrackID(s string) string {
+	return parseSpotifyID(s, "track")
+}
+
+func parseSpotifyID(s, kind string) string {
+	s = strings.TrimSpace(s)
+	if id, ok := strings.CutPrefix(s, "spotify:"+kind+":"); ok {
+		return id
+	}
+	if u, err := url.Parse(s); err == nil && u.Host != "" {
+		if id, ok := strings.CutPrefix(u.Path, "/"+kind+"/"); ok {
+			return strings.Trim(id, "/")
+		}
+		return ""
+	}
+	if strings.ContainsAny(s, ":/") {
+		return ""
+	}
+	return s
+}
+
+// GetArtistImages searches Spotify for the aliases, returning the largest image of the result that best
+// matches them.
 func (c *SpotifyClient) GetArtistImages(ctx context.Context, aliases []string) (string, error) {
+	return c.GetArtistImagesWithHint(ctx, aliases, nil)
+}
+
+// GetArtistImagesWithHint is GetArtistImages, preferring results with any of the expected genres, so
+// that an artist is not confused with a tribute band or another artist of the same name. genreHints is
+// optional.
+func (c *SpotifyClient) GetArtistImagesWithHint(ctx context.Context, aliases []string, genreHints []string) (string, error) {
 	l := logger.FromContext(ctx)
-	aliasesUniq := utils.UniqueIgnoringCase(aliases)
+	aliasesUniq := usableAliases(aliases)
+	if len(aliasesUniq) == 0 {
+		return "", fmt.Errorf("GetArtistImages: %w", ErrImageNotFound)
+	}
 
 	// First try romanized names with exact quotes
 	for _, a := range aliasesUniq {
-		romanized := unidecode.Unidecode(a)
+		romanized := romanizer.Romanize(a)
 		if romanized != "" {
-			results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\"", romanized), spotify.SearchTypeArtist)
+			results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\"", sanitizeSpotifyQuery(romanized)), spotify.SearchTypeArtist)
 			if err != nil {
 				return "", fmt.Errorf("GetArtistImages: %w", err)
 			}
-			if results.Artists != nil && len(results.Artists.Artists) > 0 {
-				for _, artist := range results.Artists.Artists {
-					if strings.EqualFold(artist.Name, romanized) || strings.EqualFold(artist.Name, a) || strings.Contains(strings.ToLower(artist.Name), strings.ToLower(a)) {
-						if len(artist.Images) > 0 {
-							img := artist.Images[0].URL
-							l.Debug().Msgf("Found artist images for %s (romanized: %s): %v", a, romanized, img)
-							return img, nil
-						}
-					}
+			if results.Artists != nil {
+				if img := bestSpotifyArtistImage(results.Artists.Artists, []string{romanized, a}, genreHints); img != "" {
+					l.Debug().Msgf("Found artist images for %s (romanized: %s): %v", a, romanized, img)
+					return img, nil
 				}
 			}
 		}
@@ -200,38 +381,28 @@ func (c *SpotifyClient) GetArtistImages(ctx context.Context, aliases []string) (
 
 	// Then try original names with exact quotes
 	for _, a := range aliasesUniq {
-		results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\"", a), spotify.SearchTypeArtist)
+		results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\"", sanitizeSpotifyQuery(a)), spotify.SearchTypeArtist)
 		if err != nil {
 			return "", fmt.Errorf("GetArtistImages: %w", err)
 		}
-		if results.Artists != nil && len(results.Artists.Artists) > 0 {
-			for _, artist := range results.Artists.Artists {
-				if strings.EqualFold(artist.Name, a) || strings.Contains(strings.ToLower(artist.Name), strings.ToLower(a)) {
-					if len(artist.Images) > 0 {
-						img := artist.Images[0].URL
-						l.Debug().Msgf("Found artist images for %s: %v", a, img)
-						return img, nil
-					}
-				}
+		if results.Artists != nil {
+			if img := bestSpotifyArtistImage(results.Artists.Artists, []string{a}, genreHints); img != "" {
+				l.Debug().Msgf("Found artist images for %s: %v", a, img)
+				return img, nil
 			}
 		}
 	}
 
 	// Try without quotes for broader matching
 	for _, a := range aliasesUniq {
-		results, err := c.searchEntity(ctx, fmt.Sprintf("artist:%s", a), spotify.SearchTypeArtist)
+		results, err := c.searchEntity(ctx, fmt.Sprintf("artist:%s", sanitizeSpotifyQuery(a)), spotify.SearchTypeArtist)
 		if err != nil {
 			return "", fmt.Errorf("GetArtistImages: %w", err)
 		}
-		if results.Artists != nil && len(results.Artists.Artists) > 0 {
-			for _, artist := range results.Artists.Artists {
-				if strings.EqualFold(artist.Name, a) || strings.Contains(strings.ToLower(artist.Name), strings.ToLower(a)) {
-					if len(artist.Images) > 0 {
-						img := artist.Images[0].URL
-						l.Debug().Msgf("Found artist images for %s (no quotes): %v", a, img)
-						return img, nil
-					}
-				}
+		if results.Artists != nil {
+			if img := bestSpotifyArtistImage(results.Artists.Artists, []string{a}, genreHints); img != "" {
+				l.Debug().Msgf("Found artist images for %s (no quotes): %v", a, img)
+				return img, nil
 			}
 		}
 	}
@@ -240,32 +411,87 @@ func (c *SpotifyClient) GetArtistImages(ctx context.Context, aliases []string) (
 	if len(aliasesUniq) > 1 {
 		queryParts := make([]string, len(aliasesUniq))
 		for i, a := range aliasesUniq {
-			queryParts[i] = fmt.Sprintf("artist:\"%s\"", a)
+			queryParts[i] = fmt.Sprintf("artist:\"%s\"", sanitizeSpotifyQuery(a))
 		}
 		combinedQuery := strings.Join(queryParts, " OR ")
 		results, err := c.searchEntity(ctx, combinedQuery, spotify.SearchTypeArtist)
 		if err != nil {
 			return "", fmt.Errorf("GetArtistImages: %w", err)
 		}
-		if results.Artists != nil && len(results.Artists.Artists) > 0 {
-			for _, artist := range results.Artists.Artists {
-				for _, a := range aliasesUniq {
-					if strings.EqualFold(artist.Name, a) || strings.Contains(strings.ToLower(artist.Name), strings.ToLower(a)) {
-						if len(artist.Images) > 0 {
-							img := artist.Images[0].URL
-							l.Debug().Msgf("Found artist images for combined aliases %v: %v", aliasesUniq, img)
-							return img, nil
-						}
-					}
-				}
+		if results.Artists != nil {
+			if img := bestSpotifyArtistImage(results.Artists.Artists, aliasesUniq, genreHints); img != "" {
+				l.Debug().Msgf("Found artist images for combined aliases %v: %v", aliasesUniq, img)
+				return img, nil
 			}
 		}
 	}
 
-	return "", errors.New("GetArtistImages: artist image not found")
+	return "", fmt.Errorf("GetArtistImages: %w", ErrImageNotFound)
+}
+
+// bestSpotifyArtistImage returns the largest image of the search result that best matches the names, or
+// an empty string if no result with an image has a name containing any of them. Results whose name is
+// one of the names are preferred over those that only contain one. When genreHints are given, results
+// with any of the genres are preferred before that. Remaining ties go to the more popular artist, then
+// to Spotify's order.
+func bestSpotifyArtistImage(results []spotify.FullArtist, names []string, genreHints []string) string {
+	var best *spotify.FullArtist
+	var bestScore [3]int
+	for i := range results {
+		artist := &results[i]
+		exact, matched := false, false
+		for _, n := range names {
+			if strings.EqualFold(artist.Name, n) {
+				exact, matched = true, true
+				break
+			}
+			if strings.Contains(strings.ToLower(artist.Name), strings.ToLower(n)) {
+				matched = true
+			}
+		}
+		if !matched || bestImage(artist.Images) == "" {
+			continue
+		}
+		score := [3]int{boolToInt(hasGenre(artist.Genres, genreHints)), boolToInt(exact), int(artist.Popularity)}
+		if best == nil || slices.Compare(score[:], bestScore[:]) > 0 {
+			best, bestScore = artist, score
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return bestImage(best.Images)
 }
 
+// hasGenre reports whether any of the artist's genres contains any of the hints, e.g. the hint "rock"
+// matches the genre "indie rock".
+func hasGenre(genres []string, hints []string) bool {
+	for _, g := range genres {
+		for _, h := range hints {
+			if h = strings.TrimSpace(h); h != "" && strings.Contains(strings.ToLower(g), strings.ToLower(h)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// GetAlbumImages searches Spotify for the album, returning the largest cover of the result that best
+// matches the artists.
 func (c *SpotifyClient) GetAlbumImages(ctx context.Context, artists []string, album string) (string, error) {
+	return c.GetAlbumImagesForTrackCount(ctx, artists, album, 0)
+}
+
+// GetAlbumImagesForTrackCount is GetAlbumImages, preferring the edition of the album with trackCount
+// tracks. trackCount is optional, and ignored when 0.
+func (c *SpotifyClient) GetAlbumImagesForTrackCount(ctx context.Context, artists []string, album string, trackCount int) (string, error) {
 	l := logger.FromContext(ctx)
 	l.Debug().Msgf("Finding album image for %s from artist(s) %v", album, artists)
 
@@ -273,32 +499,32 @@ func (c *SpotifyClient) GetAlbumImages(ctx context.Context, artists []string, al
 
 	// Try to find artist + album match for all artists with more query combinations
 	for _, artist := range artistsUniq {
-		romanizedArtist := unidecode.Unidecode(artist)
-		romanizedAlbum := unidecode.Unidecode(album)
+		romanizedArtist := romanizer.Romanize(artist)
+		romanizedAlbum := romanizer.Romanize(album)
 
 		queries := []string{}
 
 		// Original combinations
 		if romanizedAlbum != "" {
-			queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", artist, romanizedAlbum))
+			queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(romanizedAlbum)))
 		}
 		if romanizedArtist != "" {
-			queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", romanizedArtist, album))
+			queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", sanitizeSpotifyQuery(romanizedArtist), sanitizeSpotifyQuery(album)))
 			if romanizedAlbum != "" {
-				queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", romanizedArtist, romanizedAlbum))
+				queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", sanitizeSpotifyQuery(romanizedArtist), sanitizeSpotifyQuery(romanizedAlbum)))
 			}
 		}
-		queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", artist, album))
+		queries = append(queries, fmt.Sprintf("artist:\"%s\" album:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(album)))
 
 		// Additional combinations without quotes for broader matching
-		queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", artist, album))
+		queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(album)))
 		if romanizedAlbum != "" {
-			queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", artist, romanizedAlbum))
+			queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(romanizedAlbum)))
 		}
 		if romanizedArtist != "" {
-			queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", romanizedArtist, album))
+			queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", sanitizeSpotifyQuery(romanizedArtist), sanitizeSpotifyQuery(album)))
 			if romanizedAlbum != "" {
-				queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", romanizedArtist, romanizedAlbum))
+				queries = append(queries, fmt.Sprintf("artist:%s album:\"%s\"", sanitizeSpotifyQuery(romanizedArtist), sanitizeSpotifyQuery(romanizedAlbum)))
 			}
 		}
 
@@ -307,15 +533,10 @@ func (c *SpotifyClient) GetAlbumImages(ctx context.Context, artists []string, al
 			if err != nil {
 				return "", fmt.Errorf("GetAlbumImages: %w", err)
 			}
-			if results.Albums != nil && len(results.Albums.Albums) > 0 {
-				for _, alb := range results.Albums.Albums {
-					if strings.EqualFold(alb.Name, album) || strings.Contains(strings.ToLower(alb.Name), strings.ToLower(album)) {
-						if len(alb.Images) > 0 {
-							img := alb.Images[0].URL
-							l.Debug().Msgf("Found album images for %s: %v", album, img)
-							return img, nil
-						}
-					}
+			if results.Albums != nil {
+				if img := bestSpotifyAlbumImage(results.Albums.Albums, artists, album, trackCount); img != "" {
+					l.Debug().Msgf("Found album images for %s: %v", album, img)
+					return img, nil
 				}
 			}
 		}
@@ -325,15 +546,15 @@ func (c *SpotifyClient) GetAlbumImages(ctx context.Context, artists []string, al
 	if len(artistsUniq) > 1 {
 		artistQueryParts := make([]string, len(artistsUniq))
 		for i, artist := range artistsUniq {
-			artistQueryParts[i] = fmt.Sprintf("artist:\"%s\"", artist)
+			artistQueryParts[i] = fmt.Sprintf("artist:\"%s\"", sanitizeSpotifyQuery(artist))
 		}
 		combinedArtistQuery := strings.Join(artistQueryParts, " OR ")
 		queries := []string{
-			fmt.Sprintf("(%s) album:\"%s\"", combinedArtistQuery, album),
+			fmt.Sprintf("(%s) album:\"%s\"", combinedArtistQuery, sanitizeSpotifyQuery(album)),
 		}
-		romanizedAlbum := unidecode.Unidecode(album)
+		romanizedAlbum := romanizer.Romanize(album)
 		if romanizedAlbum != "" {
-			queries = append(queries, fmt.Sprintf("(%s) album:\"%s\"", combinedArtistQuery, romanizedAlbum))
+			queries = append(queries, fmt.Sprintf("(%s) album:\"%s\"", combinedArtistQuery, sanitizeSpotifyQuery(romanizedAlbum)))
 		}
 
 		for _, query := range queries {
@@ -341,15 +562,10 @@ func (c *SpotifyClient) GetAlbumImages(ctx context.Context, artists []string, al
 			if err != nil {
 				return "", fmt.Errorf("GetAlbumImages: %w", err)
 			}
-			if results.Albums != nil && len(results.Albums.Albums) > 0 {
-				for _, alb := range results.Albums.Albums {
-					if strings.EqualFold(alb.Name, album) || strings.Contains(strings.ToLower(alb.Name), strings.ToLower(album)) {
-						if len(alb.Images) > 0 {
-							img := alb.Images[0].URL
-							l.Debug().Msgf("Found album images for %s with combined artists: %v", album, img)
-							return img, nil
-						}
-					}
+			if results.Albums != nil {
+				if img := bestSpotifyAlbumImage(results.Albums.Albums, artists, album, trackCount); img != "" {
+					l.Debug().Msgf("Found album images for %s with combined artists: %v", album, img)
+					return img, nil
 				}
 			}
 		}
@@ -357,31 +573,220 @@ func (c *SpotifyClient) GetAlbumImages(ctx context.Context, artists []string, al
 
 	// If none found, try album title only with more variations
 	queries := []string{}
-	romanizedAlbum := unidecode.Unidecode(album)
+	romanizedAlbum := romanizer.Romanize(album)
 	if romanizedAlbum != "" {
-		queries = append(queries, fmt.Sprintf("album:\"%s\"", romanizedAlbum))
-		queries = append(queries, fmt.Sprintf("album:%s", romanizedAlbum))
+		queries = append(queries, fmt.Sprintf("album:\"%s\"", sanitizeSpotifyQuery(romanizedAlbum)))
+		queries = append(queries, fmt.Sprintf("album:%s", sanitizeSpotifyQuery(romanizedAlbum)))
 	}
-	queries = append(queries, fmt.Sprintf("album:\"%s\"", album))
-	queries = append(queries, fmt.Sprintf("album:%s", album))
+	queries = append(queries, fmt.Sprintf("album:\"%s\"", sanitizeSpotifyQuery(album)))
+	queries = append(queries, fmt.Sprintf("album:%s", sanitizeSpotifyQuery(album)))
 
 	for _, query := range queries {
 		results, err := c.searchEntity(ctx, query, spotify.SearchTypeAlbum)
 		if err != nil {
 			return "", fmt.Errorf("GetAlbumImages: %w", err)
 		}
-		if results.Albums != nil && len(results.Albums.Albums) > 0 {
-			for _, alb := range results.Albums.Albums {
-				if strings.EqualFold(alb.Name, album) || strings.Contains(strings.ToLower(alb.Name), strings.ToLower(album)) {
-					if len(alb.Images) > 0 {
-						img := alb.Images[0].URL
-						l.Debug().Msgf("Found album images for %s (album only): %v", album, img)
-						return img, nil
+		if results.Albums != nil {
+			if img := bestSpotifyAlbumImage(results.Albums.Albums, artists, album, trackCount); img != "" {
+				l.Debug().Msgf("Found album images for %s (album only): %v", album, img)
+				return img, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("GetAlbumImages: %w", ErrImageNotFound)
+}
+
+// bestSpotifyAlbumImage returns the largest cover of the search result with the highest
+// scoreSpotifyAlbum, or an empty string if no result with a cover matches the album's title.
+// Results with the same score keep Spotify's order.
+func bestSpotifyAlbumImage(results []spotify.SimpleAlbum, artists []string, album string, trackCount int) string {
+	var best *spotify.SimpleAlbum
+	bestScore := 0
+	for i := range results {
+		alb := &results[i]
+		if len(alb.Images) == 0 || !albumTitleMatches(alb.Name, album) {
+			continue
+		}
+		score := scoreSpotifyAlbum(*alb, artists, album, trackCount)
+		if best == nil || score > bestScore {
+			best, bestScore = alb, score
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return bestImage(best.Images)
+}
+
+// scoreSpotifyAlbum scores how well a search result matches the album being searched for, where a
+// higher score is a better match. Results are rewarded for each of the album's artists they credit
+// and penalized for crediting other artists. An exact title is preferred over one that only contains
+// the album's title. When trackCount is known, a result with exactly that many tracks is preferred,
+// and one with fewer tracks, which cannot be the same edition, is penalized.
+func scoreSpotifyAlbum(alb spotify.SimpleAlbum, artists []string, album string, trackCount int) int {
+	score := 0
+	for _, credited := range alb.Artists {
+		matched := false
+		for _, artist := range artists {
+			if strings.EqualFold(credited.Name, artist) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			score += 4
+		} else {
+			score--
+		}
+	}
+	if strings.EqualFold(strings.Join(strings.Fields(alb.Name), " "), strings.Join(strings.Fields(album), " ")) {
+		score += 2
+	}
+	if trackCount > 0 {
+		switch total := int(alb.TotalTracks); {
+		case total == trackCount:
+			score += 3
+		case total < trackCount:
+			score -= 3
+		}
+	}
+	return score
+}
+
+// bestImage returns the URL of the image with the highest resolution, as Spotify does not order images
+// by size. Images without dimensions are ignored unless no image has them, in which case the first is
+// returned. Of images with the same resolution, the first is returned.
+func bestImage(imgs []spotify.Image) string {
+	best := -1
+	for i, img := range imgs {
+		if img.URL == "" || img.Width <= 0 || img.Height <= 0 {
+			continue
+		}
+		if best < 0 || img.Width*img.Height > imgs[best].Width*imgs[best].Height {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return imgs[best].URL
+	}
+	for _, img := range imgs {
+		if img.URL != "" {
+			return img.URL
+		}
+	}
+	return ""
+}
+
+// albumTitleMatches reports whether a search result's title matches the album being searched for.
+// Unless strict album image matching is enabled, titles containing the album's title also match.
+func albumTitleMatches(candidate, album string) bool {
+	candidate = strings.Join(strings.Fields(candidate), " ")
+	album = strings.Join(strings.Fields(album), " ")
+	if strings.EqualFold(candidate, album) {
+		return true
+	}
+	if cfg.StrictAlbumImageMatch() {
+		return false
+	}
+	return strings.Contains(strings.ToLower(candidate), strings.ToLower(album))
+}
+
+// GetTrackImages searches Spotify for the track, returning the largest cover of the album of the first
+// result with the track's title that credits one of the artists. Used to find the cover of a track whose
+// album is unknown.
+func (c *SpotifyClient) GetTrackImages(ctx context.Context, artists []string, track string) (string, error) {
+	l := logger.FromContext(ctx)
+	l.Debug().Msgf("Finding album image for track %s from artist(s) %v", track, artists)
+
+	artistsUniq := utils.UniqueIgnoringCase(artists)
+
+	for _, artist := range artistsUniq {
+		romanizedArtist := romanizer.Romanize(artist)
+		romanizedTrack := romanizer.Romanize(track)
+
+		queries := []string{}
+		if romanizedTrack != "" {
+			queries = append(queries, fmt.Sprintf("artist:\"%s\" track:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(romanizedTrack)))
+		}
+		if romanizedArtist != "" {
+			queries = append(queries, fmt.Sprintf("artist:\"%s\" track:\"%s\"", sanitizeSpotifyQuery(romanizedArtist), sanitizeSpotifyQuery(track)))
+			if romanizedTrack != "" {
+				queries = append(queries, fmt.Sprintf("artist:\"%s\" track:\"%s\"", sanitizeSpotifyQuery(romanizedArtist), sanitizeSpotifyQuery(romanizedTrack)))
+			}
+		}
+		queries = append(queries, fmt.Sprintf("artist:\"%s\" track:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(track)))
+
+		for _, query := range queries {
+			results, err := c.searchEntity(ctx, query, spotify.SearchTypeTrack)
+			if err != nil {
+				return "", fmt.Errorf("GetTrackImages: %w", err)
+			}
+			if results.Tracks != nil {
+				if img := spotifyTrackImage(results.Tracks.Tracks, artists, track); img != "" {
+					l.Debug().Msgf("Found album images for track %s: %v", track, img)
+					return img, nil
+				}
+			}
+		}
+	}
+
+	return "", fmt.Errorf("GetTrackImages: %w", ErrImageNotFound)
+}
+
+// spotifyTrackImage returns the largest album cover of the first search result whose title is the
+// track's and that credits any of the artists, or an empty string if none match.
+func spotifyTrackImage(results []spotify.FullTrack, artists []string, track string) string {
+	track = strings.Join(strings.Fields(track), " ")
+	for _, t := range results {
+		if !strings.EqualFold(strings.Join(strings.Fields(t.Name), " "), track) {
+			continue
+		}
+		for _, credited := range t.Artists {
+			for _, artist := range artists {
+				if strings.EqualFold(credited.Name, artist) {
+					if img := bestImage(t.Album.Images); img != "" {
+						return img
 					}
 				}
 			}
 		}
 	}
+	return ""
+}
 
-	return "", errors.New("GetAlbumImages: album image not found")
+// SearchArtistImages returns the largest image of every artist found by searching Spotify for the name.
+func (c *SpotifyClient) SearchArtistImages(ctx context.Context, name string) ([]ImageCandidate, error) {
+	results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\"", sanitizeSpotifyQuery(name)), spotify.SearchTypeArtist)
+	if err != nil {
+		return nil, fmt.Errorf("SearchArtistImages: %w", err)
+	}
+	ret := make([]ImageCandidate, 0)
+	if results.Artists == nil {
+		return ret, nil
+	}
+	for _, artist := range results.Artists.Artists {
+		if img := bestImage(artist.Images); img != "" {
+			ret = append(ret, ImageCandidate{URL: img, Name: artist.Name, Source: ProviderSpotify})
+		}
+	}
+	return ret, nil
+}
+
+// SearchAlbumImages returns the largest cover of every album found by searching Spotify for the artist and album title.
+func (c *SpotifyClient) SearchAlbumImages(ctx context.Context, artist, album string) ([]ImageCandidate, error) {
+	results, err := c.searchEntity(ctx, fmt.Sprintf("artist:\"%s\" album:\"%s\"", sanitizeSpotifyQuery(artist), sanitizeSpotifyQuery(album)), spotify.SearchTypeAlbum)
+	if err != nil {
+		return nil, fmt.Errorf("SearchAlbumImages: %w", err)
+	}
+	ret := make([]ImageCandidate, 0)
+	if results.Albums == nil {
+		return ret, nil
+	}
+	for _, alb := range results.Albums.Albums {
+		if img := bestImage(alb.Images); img != "" {
+			ret = append(ret, ImageCandidate{URL: img, Name: alb.Name, Source: ProviderSpotify})
+		}
+	}
+	return ret, nil
 }
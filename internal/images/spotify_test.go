@@ -0,0 +1,441 @@
+package images
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestMain(m *testing.M) {
+	err := cfg.Load(func(env string) string {
+		switch env {
+		case cfg.SQLITE_ENABLED:
+			return "true"
+		case cfg.CONFIG_DIR_ENV:
+			return os.TempDir()
+		default:
+			return ""
+		}
+	}, "test")
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func spotifyAlbum(name string, tracks int, artists []string, imgs ...spotify.Image) spotify.SimpleAlbum {
+	alb := spotify.SimpleAlbum{Name: name, TotalTracks: spotify.Numeric(tracks), Images: imgs}
+	for _, a := range artists {
+		alb.Artists = append(alb.Artists, spotify.SimpleArtist{Name: a})
+	}
+	return alb
+}
+
+func TestScoreSpotifyAlbum(t *testing.T) {
+	artists := []string{"Artist A", "Artist B"}
+
+	exact := spotifyAlbum("Album", 10, []string{"Artist A", "Artist B"})
+	oneArtist := spotifyAlbum("Album", 10, []string{"Artist A"})
+	otherArtist := spotifyAlbum("Album", 10, []string{"Artist A", "Someone Else"})
+	deluxe := spotifyAlbum("Album (Deluxe)", 14, []string{"Artist A", "Artist B"})
+	tooShort := spotifyAlbum("Album", 4, []string{"Artist A", "Artist B"})
+
+	assert.Greater(t, scoreSpotifyAlbum(exact, artists, "Album", 10), scoreSpotifyAlbum(oneArtist, artists, "Album", 10))
+	assert.Greater(t, scoreSpotifyAlbum(oneArtist, artists, "Album", 10), scoreSpotifyAlbum(otherArtist, artists, "Album", 10))
+	assert.Greater(t, scoreSpotifyAlbum(exact, artists, "Album", 10), scoreSpotifyAlbum(deluxe, artists, "Album", 10))
+	assert.Greater(t, scoreSpotifyAlbum(deluxe, artists, "Album", 10), scoreSpotifyAlbum(tooShort, artists, "Album", 10))
+	// track count is ignored when unknown
+	assert.Equal(t, scoreSpotifyAlbum(exact, artists, "Album", 0), scoreSpotifyAlbum(tooShort, artists, "Album", 0))
+}
+
+func TestBestSpotifyAlbumImage(t *testing.T) {
+	small := spotify.Image{URL: "small", Width: 64, Height: 64}
+	medium := spotify.Image{URL: "medium", Width: 300, Height: 300}
+	large := spotify.Image{URL: "large", Width: 640, Height: 640}
+
+	results := []spotify.SimpleAlbum{
+		spotifyAlbum("Other Album", 10, []string{"Artist A"}, large),
+		spotifyAlbum("Album", 3, []string{"Artist A"}, large),
+		spotifyAlbum("Album", 10, []string{"Artist A"}, small, large, medium),
+		spotifyAlbum("Album", 10, []string{"Artist A"}),
+	}
+	assert.Equal(t, "large", bestSpotifyAlbumImage(results, []string{"Artist A"}, "Album", 10))
+
+	// equal scores keep Spotify's order
+	results = []spotify.SimpleAlbum{
+		spotifyAlbum("Album", 10, []string{"Artist A"}, medium),
+		spotifyAlbum("Album", 10, []string{"Artist A"}, large),
+	}
+	assert.Equal(t, "medium", bestSpotifyAlbumImage(results, []string{"Artist A"}, "Album", 0))
+
+	assert.Empty(t, bestSpotifyAlbumImage(results, []string{"Artist A"}, "Nothing", 0))
+}
+
+func spotifyArtist(name string, popularity int, genres []string, img string) spotify.FullArtist {
+	a := spotify.FullArtist{Popularity: spotify.Numeric(popularity), Genres: genres}
+	a.Name = name
+	if img != "" {
+		a.Images = []spotify.Image{{URL: img, Width: 640, Height: 640}}
+	}
+	return a
+}
+
+func TestBestSpotifyArtistImage(t *testing.T) {
+	results := []spotify.FullArtist{
+		spotifyArtist("Queen Tribute", 20, []string{"tribute"}, "tribute"),
+		spotifyArtist("Queen", 10, []string{"classical"}, "namesake"),
+		spotifyArtist("Queen", 90, []string{"classic rock", "glam rock"}, "queen"),
+		spotifyArtist("Queen", 95, nil, ""),
+	}
+	// exact names beat names that only contain the alias, then the more popular artist wins
+	assert.Equal(t, "queen", bestSpotifyArtistImage(results, []string{"Queen"}, nil))
+	// the genre hint prefers the artist with the genre, however popular the others are
+	assert.Equal(t, "namesake", bestSpotifyArtistImage(results, []string{"Queen"}, []string{"Classical"}))
+	assert.Equal(t, "tribute", bestSpotifyArtistImage(results, []string{"Queen"}, []string{"tribute"}))
+	// a hint no result has is ignored
+	assert.Equal(t, "queen", bestSpotifyArtistImage(results, []string{"Queen"}, []string{"jazz"}))
+
+	// equal results keep Spotify's order
+	results = []spotify.FullArtist{
+		spotifyArtist("Artist", 50, nil, "first"),
+		spotifyArtist("Artist", 50, nil, "second"),
+	}
+	assert.Equal(t, "first", bestSpotifyArtistImage(results, []string{"Artist"}, nil))
+	assert.Empty(t, bestSpotifyArtistImage(results, []string{"Someone"}, nil))
+}
+
+func TestGetArtistImages_NoUsableAliases(t *testing.T) {
+	assert.Equal(t, []string{"Artist", "Other"}, usableAliases([]string{"", " Artist ", "\t", "artist", "Other"}))
+	assert.Empty(t, usableAliases([]string{"", "   ", "\n"}))
+
+	// the clients are never used, so no searches are made
+	ctx := context.Background()
+	_, err := (&SpotifyClient{}).GetArtistImages(ctx, []string{"", "  "})
+	assert.ErrorIs(t, err, ErrImageNotFound)
+	_, err = (&DeezerClient{}).GetArtistImages(ctx, []string{" "})
+	assert.ErrorIs(t, err, ErrImageNotFound)
+}
+
+func TestBestImage(t *testing.T) {
+	small := spotify.Image{URL: "small", Width: 64, Height: 64}
+	large := spotify.Image{URL: "large", Width: 640, Height: 640}
+	wide := spotify.Image{URL: "wide", Width: 1280, Height: 320}
+	unsized := spotify.Image{URL: "unsized"}
+
+	assert.Equal(t, "large", bestImage([]spotify.Image{small, large}))
+	assert.Equal(t, "large", bestImage([]spotify.Image{large, small}))
+	assert.Equal(t, "large", bestImage([]spotify.Image{unsized, small, large}), "images without dimensions are ignored")
+	assert.Equal(t, "large", bestImage([]spotify.Image{large, wide}), "equal resolutions keep the first")
+	assert.Equal(t, "wide", bestImage([]spotify.Image{wide, large}))
+	assert.Equal(t, "unsized", bestImage([]spotify.Image{{}, unsized}), "without any dimensions the first image is used")
+	assert.Empty(t, bestImage(nil))
+}
+
+func TestAuthTransport_RetriesRateLimitedRequests(t *testing.T) {
+	defer func(d time.Duration) { spotifyMaxRetryWait = d }(spotifyMaxRetryWait)
+	spotifyMaxRetryWait = 10 * time.Millisecond
+
+	limited := 2
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if requests <= limited {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &authTransport{client: &SpotifyClient{accessToken: "token"}}}
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, requests)
+	// the waits are capped, rather than taking the 30 seconds asked for
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// after three retries the rate limited response is returned
+	requests, limited = 0, 10
+	resp, err = client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 4, requests)
+
+	// waiting stops when the request is cancelled
+	spotifyMaxRetryWait = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	start = time.Now()
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 5*time.Second, retryAfter("5"))
+	assert.Equal(t, time.Second, retryAfter(""))
+	assert.Equal(t, spotifyMaxRetryWait, retryAfter("3600"))
+	assert.Equal(t, time.Duration(0), retryAfter("-1"))
+	assert.Equal(t, time.Duration(0), retryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+}
+
+func TestSanitizeSpotifyQuery(t *testing.T) {
+	tests := map[string]string{
+		"AC/DC":                       "AC/DC",
+		"f(x)":                        "f x",
+		"Godspeed You! Black Emperor": "Godspeed You! Black Emperor",
+		`Say "Hello"`:                 "Say Hello",
+		"Sunn O))):":                  "Sunn O",
+		`Back\Slash`:                  "Back Slash",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, sanitizeSpotifyQuery(name), name)
+	}
+}
+
+func TestParseSpotifyArtistID(t *testing.T) {
+	tests := map[string]string{
+		"spotify:artist:0OdUWJ0sBjDrqHygGUXeCF":                          "0OdUWJ0sBjDrqHygGUXeCF",
+		"https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF":         "0OdUWJ0sBjDrqHygGUXeCF",
+		"https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF/?si=abc": "0OdUWJ0sBjDrqHygGUXeCF",
+		" 0OdUWJ0sBjDrqHygGUXeCF ":                                       "0OdUWJ0sBjDrqHygGUXeCF",
+		"spotify:track:6rqhFgbbKwnb9MLmUQDhG6":                           "",
+		"https://open.spotify.com/track/6rqhFgbbKwnb9MLmUQDhG6":          "",
+		"https://open.spotify.com/":                                      "",
+		"spotify:artist":                                                 "",
+		"":                                                               "",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, ParseSpotifyArtistID(input), input)
+	}
+}
+
+func TestGetArtistImages_SanitizesQueries(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+		w.Header().Set("Content-Type", "application/json")
+		if q != `artist:"f x"` {
+			w.Write([]byte(`{"artists":{"items":[]}}`))
+			return
+		}
+		w.Write([]byte(`{"artists":{"items":[{"name":"f(x)","images":[
+			{"url":"https://spotify.test/small.jpg","width":64,"height":64},
+			{"url":"https://spotify.test/large.jpg","width":640,"height":640}
+		]}]}}`))
+	}))
+	defer srv.Close()
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	img, err := c.GetArtistImages(context.Background(), []string{"f(x)"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://spotify.test/large.jpg", img)
+	for _, q := range queries {
+		assert.NotContains(t, q, "(", q)
+		assert.Equal(t, 0, strings.Count(q, `"`)%2, q)
+	}
+}
+
+func TestGetTrackImages(t *testing.T) {
+	var types []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		types = append(types, r.URL.Query().Get("type"))
+		w.Header().Set("Content-Type", "application/json")
+		// a track of the same name by another artist comes first
+		w.Write([]byte(`{"tracks":{"items":[
+			{"name":"Song","artists":[{"name":"Someone Else"}],"album":{"images":[{"url":"https://spotify.test/other.jpg","width":640,"height":640}]}},
+			{"name":"Song (Remix)","artists":[{"name":"Artist"}],"album":{"images":[{"url":"https://spotify.test/remix.jpg","width":640,"height":640}]}},
+			{"name":"song","artists":[{"name":"Featured"},{"name":"Alias"}],"album":{"images":[
+				{"url":"https://spotify.test/small.jpg","width":64,"height":64},
+				{"url":"https://spotify.test/large.jpg","width":640,"height":640}
+			]}}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	img, err := c.GetTrackImages(context.Background(), []string{"Artist", "Alias"}, "Song")
+	require.NoError(t, err)
+	assert.Equal(t, "https://spotify.test/large.jpg", img)
+	for _, typ := range types {
+		assert.Equal(t, "track", typ)
+	}
+
+	_, err = c.GetTrackImages(context.Background(), []string{"Nobody"}, "Song")
+	assert.ErrorIs(t, err, ErrImageNotFound)
+}
+
+func TestGetTrackImageByID(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"6rqhFgbbKwnb9MLmUQDhG6","name":"Song","album":{"images":[
+			{"url":"https://spotify.test/small.jpg","width":64,"height":64},
+			{"url":"https://spotify.test/large.jpg","width":640,"height":640}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	img, err := c.GetTrackImageByID(context.Background(), "6rqhFgbbKwnb9MLmUQDhG6")
+	require.NoError(t, err)
+	assert.Equal(t, "https://spotify.test/large.jpg", img)
+	// the track is fetched directly instead of searched for
+	assert.Equal(t, []string{"/tracks/6rqhFgbbKwnb9MLmUQDhG6"}, paths)
+}
+
+func TestParseSpotifyTrackID(t *testing.T) {
+	assert.Equal(t, "6rqhFgbbKwnb9MLmUQDhG6", ParseSpotifyTrackID("spotify:track:6rqhFgbbKwnb9MLmUQDhG6"))
+	assert.Equal(t, "6rqhFgbbKwnb9MLmUQDhG6", ParseSpotifyTrackID("https://open.spotify.com/track/6rqhFgbbKwnb9MLmUQDhG6"))
+	assert.Equal(t, "6rqhFgbbKwnb9MLmUQDhG6", ParseSpotifyTrackID("6rqhFgbbKwnb9MLmUQDhG6"))
+	assert.Empty(t, ParseSpotifyTrackID("spotify:artist:6rqhFgbbKwnb9MLmUQDhG6"))
+	assert.Empty(t, ParseSpotifyTrackID(""))
+}
+
+func TestGetArtistImages_UnavailableIsNotNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	_, err := c.GetArtistImages(context.Background(), []string{"Artist"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrImageNotFound)
+}
+
+// newTestSpotifyClient returns a client with a valid token that searches srv, without rate limiting.
+func newTestSpotifyClient(srv *httptest.Server) *SpotifyClient {
+	c := &SpotifyClient{accessToken: "token", tokenExpiry: time.Now().Add(time.Hour)}
+	c.client = spotify.New(srv.Client(), spotify.WithBaseURL(srv.URL+"/"))
+	c.requestQueue = queue.NewRequestQueueWithLimit(1000, 1000, spotifyMaxConcurrent, srv.Client())
+	return c
+}
+
+func TestSearchEntity_LimitsConcurrentSearches(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"artists":{"items":[{"name":"Artist"}]}}`))
+	}))
+	defer srv.Close()
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := c.searchEntity(context.Background(), `artist:"Artist"`, spotify.SearchTypeArtist)
+			if err == nil && (results.Artists == nil || len(results.Artists.Artists) != 1) {
+				err = errors.New("search returned no artists")
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	assert.LessOrEqual(t, maxInFlight.Load(), int64(spotifyMaxConcurrent))
+	assert.Positive(t, maxInFlight.Load())
+}
+
+func TestSearchEntity_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.searchEntity(ctx, `artist:"Artist"`, spotify.SearchTypeArtist)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSearchEntity_TimesOut(t *testing.T) {
+	breakerMu.Lock()
+	providerTimeouts[ProviderSpotify] = 100 * time.Millisecond
+	breakerMu.Unlock()
+	t.Cleanup(func() {
+		breakerMu.Lock()
+		delete(providerTimeouts, ProviderSpotify)
+		breakerMu.Unlock()
+	})
+
+	// a connection that never answers
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestSpotifyClient(srv)
+	defer c.Shutdown()
+
+	start := time.Now()
+	_, err := c.searchEntity(context.Background(), `artist:"Artist"`, spotify.SearchTypeArtist)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrSpotifyTimeout)
+	assert.True(t, isTransient(err))
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the caller's own deadline is not reported as a Spotify timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = c.searchEntity(ctx, `artist:"Artist"`, spotify.SearchTypeArtist)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrSpotifyTimeout)
+}
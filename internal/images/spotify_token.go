@@ -0,0 +1,84 @@
+package images
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+const spotifyTokenFile = "spotify_token.json"
+
+// tokens are refreshed this long before they expire, so requests in flight do not fail
+const spotifyTokenMargin = 10 * time.Minute
+
+// spotifyToken is the access token saved between restarts. The client ID it was issued to is saved
+// along with it, so a token is not reused after the credentials are changed.
+type spotifyToken struct {
+	ClientID    string    `json:"client_id"`
+	AccessToken string    `json:"access_token"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// tokenValid reports whether the current access token can be used. Must be called with tokenMutex held.
+func (c *SpotifyClient) tokenValid() bool {
+	return c.accessToken != "" && time.Now().Before(c.tokenExpiry.Add(-spotifyTokenMargin))
+}
+
+// loadToken restores the access token saved by a previous run, unless it has expired or was issued
+// for other credentials. Must be called with tokenMutex held.
+func (c *SpotifyClient) loadToken() {
+	if c.tokenFile == "" {
+		return
+	}
+	l := logger.Get()
+	b, err := os.ReadFile(c.tokenFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		l.Warn().Err(err).Msg("Failed to read saved Spotify token")
+		return
+	}
+	var t spotifyToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		l.Warn().Err(err).Msg("Failed to parse saved Spotify token, ignoring it")
+		return
+	}
+	if t.ClientID != cfg.SpotifyClientId() || t.AccessToken == "" {
+		l.Debug().Msg("Saved Spotify token was issued for other credentials, ignoring it")
+		return
+	}
+	c.accessToken = t.AccessToken
+	c.tokenExpiry = t.Expiry
+	if !c.tokenValid() {
+		l.Debug().Time("token_expiry", t.Expiry).Msg("Saved Spotify token has expired")
+		return
+	}
+	l.Debug().Time("token_expiry", t.Expiry).Msg("Using saved Spotify token")
+}
+
+// saveToken writes the current access token to the token file. The file is replaced atomically and is
+// only readable by its owner, as the token grants access to the Spotify API.
+func (c *SpotifyClient) saveToken() error {
+	if c.tokenFile == "" {
+		return nil
+	}
+	b, err := json.Marshal(spotifyToken{
+		ClientID:    cfg.SpotifyClientId(),
+		AccessToken: c.accessToken,
+		Expiry:      c.tokenExpiry,
+	})
+	if err != nil {
+		return fmt.Errorf("saveToken: %w", err)
+	}
+	if err := writeFileAtomic(c.tokenFile, b); err != nil {
+		return fmt.Errorf("saveToken: %w", err)
+	}
+	return nil
+}
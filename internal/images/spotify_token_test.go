@@ -0,0 +1,46 @@
+package images
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotifyToken_SavedBetweenRestarts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), spotifyTokenFile)
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	c := &SpotifyClient{tokenFile: file, accessToken: "token", tokenExpiry: expiry}
+	require.NoError(t, c.saveToken())
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	restarted := &SpotifyClient{tokenFile: file}
+	restarted.loadToken()
+	assert.True(t, restarted.tokenValid())
+	assert.Equal(t, "token", restarted.accessToken)
+	assert.True(t, expiry.Equal(restarted.tokenExpiry))
+
+	// an expired token is loaded but not valid, so it is replaced by authenticating again
+	c.tokenExpiry = time.Now().Add(time.Minute)
+	require.NoError(t, c.saveToken())
+	restarted = &SpotifyClient{tokenFile: file}
+	restarted.loadToken()
+	assert.False(t, restarted.tokenValid())
+
+	// a token issued for other credentials is ignored
+	require.NoError(t, os.WriteFile(file, []byte(`{"client_id":"other","access_token":"token","expiry":"2999-01-01T00:00:00Z"}`), 0600))
+	restarted = &SpotifyClient{tokenFile: file}
+	restarted.loadToken()
+	assert.Empty(t, restarted.accessToken)
+
+	// no file is not an error
+	restarted = &SpotifyClient{tokenFile: filepath.Join(t.TempDir(), spotifyTokenFile)}
+	restarted.loadToken()
+	assert.Empty(t, restarted.accessToken)
+}
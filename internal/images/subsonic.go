@@ -56,7 +56,7 @@ func NewSubsonicClient() *SubsonicClient {
 	ret.url = cfg.SubsonicUrl()
 	ret.userAgent = cfg.UserAgent()
 	ret.authParams = cfg.SubsonicParams()
-	ret.requestQueue = queue.NewRequestQueue(5, 5)
+	ret.requestQueue = queue.NewRequestQueueWithClient(5, 5, providerHTTPClient(ProviderSubsonic, nil))
 	return ret
 }
 
@@ -118,7 +118,7 @@ func (c *SubsonicClient) GetAlbumImage(ctx context.Context, mbid *uuid.UUID, art
 		l.Debug().Str("mbid", mbid.String()).Msg("Searching album image by MBID")
 		err := c.getEntity(ctx, fmt.Sprintf(subsonicAlbumSearchFmtStr, c.authParams, url.QueryEscape(mbid.String())), resp)
 		if err != nil {
-			return "", fmt.Errorf("GetAlbumImage: %v", err)
+			return "", fmt.Errorf("GetAlbumImage: %w", err)
 		}
 		l.Debug().Any("subsonic_response", resp).Msg("")
 		if len(resp.SubsonicResponse.SearchResult3.Album) >= 1 {
@@ -129,18 +129,18 @@ func (c *SubsonicClient) GetAlbumImage(ctx context.Context, mbid *uuid.UUID, art
 	l.Debug().Str("title", album).Str("artist", artist).Msg("Searching album image by title and artist")
 	err := c.getEntity(ctx, fmt.Sprintf(subsonicAlbumSearchFmtStr, c.authParams, url.QueryEscape(album)), resp)
 	if err != nil {
-		return "", fmt.Errorf("GetAlbumImage: %v", err)
+		return "", fmt.Errorf("GetAlbumImage: %w", err)
 	}
 	l.Debug().Any("subsonic_response", resp).Msg("")
 	if len(resp.SubsonicResponse.SearchResult3.Album) < 1 {
-		return "", fmt.Errorf("GetAlbumImage: failed to get album art from subsonic")
+		return "", fmt.Errorf("GetAlbumImage: %w", ErrImageNotFound)
 	}
 	for _, album := range resp.SubsonicResponse.SearchResult3.Album {
 		if album.Artist == artist {
 			return cfg.SubsonicUrl() + fmt.Sprintf(subsonicCoverArtFmtStr, c.authParams, url.QueryEscape(resp.SubsonicResponse.SearchResult3.Album[0].CoverArt)), nil
 		}
 	}
-	return "", fmt.Errorf("GetAlbumImage: failed to get album art from subsonic")
+	return "", fmt.Errorf("GetAlbumImage: %w", ErrImageNotFound)
 }
 
 func (c *SubsonicClient) GetArtistImage(ctx context.Context, mbid *uuid.UUID, artist string) (string, error) {
@@ -152,29 +152,29 @@ func (c *SubsonicClient) GetArtistImage(ctx context.Context, mbid *uuid.UUID, ar
 		l.Debug().Str("mbid", mbid.String()).Msg("Searching artist image by MBID")
 		err := c.getEntity(ctx, fmt.Sprintf(subsonicArtistSearchFmtStr, c.authParams, url.QueryEscape(mbid.String())), resp)
 		if err != nil {
-			return "", fmt.Errorf("GetArtistImage: %v", err)
+			return "", fmt.Errorf("GetArtistImage: %w", err)
 		}
 		l.Debug().Any("subsonic_response", resp).Msg("")
 		if len(resp.SubsonicResponse.SearchResult3.Artist) < 1 || resp.SubsonicResponse.SearchResult3.Artist[0].ArtistImageUrl == "" {
-			return "", fmt.Errorf("GetArtistImage: failed to get artist art")
+			return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
 		}
 		// Subsonic seems to have a tendency to return an artist image even though the url is a 404
 		if err = ValidateImageURL(resp.SubsonicResponse.SearchResult3.Artist[0].ArtistImageUrl); err != nil {
-			return "", fmt.Errorf("GetArtistImage: failed to get validate image url")
+			return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
 		}
 	}
 	l.Debug().Str("artist", artist).Msg("Searching artist image by name")
 	err := c.getEntity(ctx, fmt.Sprintf(subsonicArtistSearchFmtStr, c.authParams, url.QueryEscape(artist)), resp)
 	if err != nil {
-		return "", fmt.Errorf("GetArtistImage: %v", err)
+		return "", fmt.Errorf("GetArtistImage: %w", err)
 	}
 	l.Debug().Any("subsonic_response", resp).Msg("")
 	if len(resp.SubsonicResponse.SearchResult3.Artist) < 1 || resp.SubsonicResponse.SearchResult3.Artist[0].ArtistImageUrl == "" {
-		return "", fmt.Errorf("GetArtistImage: failed to get artist art")
+		return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
 	}
 	// Subsonic seems to have a tendency to return an artist image even though the url is a 404
 	if err = ValidateImageURL(resp.SubsonicResponse.SearchResult3.Artist[0].ArtistImageUrl); err != nil {
-		return "", fmt.Errorf("GetArtistImage: failed to get validate image url")
+		return "", fmt.Errorf("GetArtistImage: %w", ErrImageNotFound)
 	}
 	return resp.SubsonicResponse.SearchResult3.Artist[0].ArtistImageUrl, nil
 }
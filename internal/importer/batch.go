@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/logger"
+)
+
+// listens are submitted in batches of this many, so the artists, albums and tracks of each batch are
+// only resolved once and its listens are saved together
+const importBatchSize = 500
+
+// submitListensFunc saves a batch of listens of an import.
+type submitListensFunc func(ctx context.Context, opts []catalog.SubmitListenOpts) (catalog.BatchResult, error)
+
+// submitListens submits the batches with catalog.SubmitListensBatch.
+func submitListens(store importStore) submitListensFunc {
+	return func(ctx context.Context, opts []catalog.SubmitListenOpts) (catalog.BatchResult, error) {
+		return catalog.SubmitListensBatch(ctx, store, opts)
+	}
+}
+
+// listenBatcher collects the listens of an import into batches, counting the listens that were
+// imported, left out as duplicates and those that failed. Listens that fail are logged, and only an error that every listen
+// after them would fail with too is returned.
+type listenBatcher struct {
+	submit   submitListensFunc
+	pending  []catalog.SubmitListenOpts
+	imported int
+	deduped  int
+	failed   int
+}
+
+func newListenBatcher(submit submitListensFunc) *listenBatcher {
+	return &listenBatcher{submit: submit}
+}
+
+// add queues the listen, submitting the batch once it is full.
+func (b *listenBatcher) add(ctx context.Context, opts catalog.SubmitListenOpts) error {
+	b.pending = append(b.pending, opts)
+	if len(b.pending) >= importBatchSize {
+		return b.flush(ctx)
+	}
+	return nil
+}
+
+// flush submits the listens queued so far.
+func (b *listenBatcher) flush(ctx context.Context) error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	l := logger.FromContext(ctx)
+	pending := b.pending
+	b.pending = nil
+	result, err := b.submit(ctx, pending)
+	if err != nil {
+		if isFatalImportError(err) {
+			return fmt.Errorf("flush: %w", err)
+		}
+		l.Err(err).Msgf("Failed to import a batch of %d listens; continuing", len(pending))
+		b.failed += len(pending)
+		return nil
+	}
+	b.imported += result.Submitted
+	b.deduped += result.Deduped
+	var fatal error
+	for i, err := range result.Errors {
+		if err == nil {
+			continue
+		}
+		b.failed++
+		if isFatalImportError(err) {
+			fatal = err
+			continue
+		}
+		l.Err(err).Msgf("Failed to import listen of '%s' by '%s' played at %s; continuing", pending[i].TrackTitle, pending[i].Artist, pending[i].Time.Format(time.RFC3339))
+	}
+	if fatal != nil {
+		return fmt.Errorf("flush: %w", fatal)
+	}
+	return nil
+}
+
+// queued returns the number of listens waiting to be submitted.
+func (b *listenBatcher) queued() int {
+	return len(b.pending)
+}
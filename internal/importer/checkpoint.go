@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+)
+
+// the directory in the config directory holding the progress of unfinished imports
+const checkpointDir = "import_progress"
+
+// loadCheckpoint returns the time of the last item imported from the file for the user by an import
+// that did not finish, or the zero time if there is none.
+func loadCheckpoint(filename string, userID int32) (time.Time, error) {
+	b, err := os.ReadFile(checkpointPath(filename, userID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("loadCheckpoint: %w", err)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("loadCheckpoint: %w", err)
+	}
+	return ts, nil
+}
+
+// saveCheckpoint records the time of the last item imported from the file for the user, so that an
+// import that fails can resume after it.
+func saveCheckpoint(filename string, userID int32, ts time.Time) error {
+	p := checkpointPath(filename, userID)
+	if err := os.MkdirAll(path.Dir(p), 0744); err != nil {
+		return fmt.Errorf("saveCheckpoint: %w", err)
+	}
+	// written to a temporary file first, so a crash while writing leaves the previous checkpoint
+	if err := os.WriteFile(p+".tmp", []byte(ts.Format(time.RFC3339Nano)), 0644); err != nil {
+		return fmt.Errorf("saveCheckpoint: %w", err)
+	}
+	if err := os.Rename(p+".tmp", p); err != nil {
+		return fmt.Errorf("saveCheckpoint: %w", err)
+	}
+	return nil
+}
+
+// clearCheckpoint removes the progress of the file's import for the user, once it has finished.
+func clearCheckpoint(filename string, userID int32) error {
+	err := os.Remove(checkpointPath(filename, userID))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("clearCheckpoint: %w", err)
+	}
+	return nil
+}
+
+// checkpointPath is kept per user, as users can import files of the same name, such as the files of
+// their own Spotify exports.
+func checkpointPath(filename string, userID int32) string {
+	return path.Join(cfg.ConfigDir(), checkpointDir, strconv.Itoa(int(userID)), filename)
+}
@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db"
+)
+
+// DryRunSummary is what an import would create.
+type DryRunSummary struct {
+	Listens    int `json:"listens"`
+	NewArtists int `json:"new_artists"`
+	NewAlbums  int `json:"new_albums"`
+}
+
+// dryRunTally counts the listens submitted to it, and the distinct artists and albums of them that
+// are not in the database yet, in place of saving them.
+type dryRunTally struct {
+	store   importStore
+	listens int
+	// artist ids by lowercased name, 0 for artists that would be created
+	artists map[string]int32
+	albums  map[string]bool
+	// the time of the last listen to each track, to leave out repeats within the dedupe window
+	lastListen map[string]time.Time
+}
+
+func newDryRunTally(store importStore) *dryRunTally {
+	return &dryRunTally{
+		store:      store,
+		artists:    make(map[string]int32),
+		albums:     make(map[string]bool),
+		lastListen: make(map[string]time.Time),
+	}
+}
+
+// submit tallies a batch of listens of the import.
+func (t *dryRunTally) submit(ctx context.Context, opts []catalog.SubmitListenOpts) (catalog.BatchResult, error) {
+	result := catalog.BatchResult{Errors: make([]error, len(opts))}
+	for _, o := range opts {
+		trackKey := strings.ToLower(o.Artist + "|" + o.TrackTitle + "|" + o.ReleaseTitle)
+		if prev, ok := t.lastListen[trackKey]; ok && o.DedupeWindow > 0 && o.Time.Sub(prev).Abs() < o.DedupeWindow {
+			result.Deduped++
+			continue
+		}
+		t.lastListen[trackKey] = o.Time
+		if err := t.add(ctx, o); err != nil {
+			return catalog.BatchResult{}, fmt.Errorf("submit: %w", err)
+		}
+		result.Submitted++
+	}
+	return result, nil
+}
+
+func (t *dryRunTally) add(ctx context.Context, opts catalog.SubmitListenOpts) error {
+	artistKey := strings.ToLower(opts.Artist)
+	artistID, ok := t.artists[artistKey]
+	if !ok {
+		artist, err := t.store.GetArtist(ctx, db.GetArtistOpts{Name: opts.Artist})
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("add: %w", err)
+		}
+		if artist != nil {
+			artistID = artist.ID
+		}
+		t.artists[artistKey] = artistID
+	}
+
+	albumKey := artistKey + "|" + strings.ToLower(opts.ReleaseTitle)
+	if _, ok := t.albums[albumKey]; !ok && opts.ReleaseTitle != "" {
+		exists := false
+		if artistID != 0 {
+			album, err := t.store.GetAlbum(ctx, db.GetAlbumOpts{ArtistID: artistID, Title: opts.ReleaseTitle})
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				return fmt.Errorf("add: %w", err)
+			}
+			exists = album != nil
+		}
+		t.albums[albumKey] = exists
+	}
+	t.listens++
+	return nil
+}
+
+func (t *dryRunTally) summary() DryRunSummary {
+	s := DryRunSummary{Listens: t.listens}
+	for _, id := range t.artists {
+		if id == 0 {
+			s.NewArtists++
+		}
+	}
+	for _, exists := range t.albums {
+		if !exists {
+			s.NewAlbums++
+		}
+	}
+	return s
+}
@@ -2,18 +2,48 @@ package importer
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
 	"github.com/gabehf/koito/internal/logger"
 )
 
+// ImportSummary counts what became of the items of an import file.
+type ImportSummary struct {
+	Imported int `json:"imported"`
+	// items that are not listens or were left out by the import settings
+	Skipped int `json:"skipped"`
+	// items that could not be imported because of an error
+	Failed int `json:"failed"`
+}
+
 // runs after every importer
-func finishImport(ctx context.Context, filename string, numImported int) error {
+func finishImport(ctx context.Context, store db.ImportJobStore, filename string, userID int32, summary ImportSummary) error {
 	l := logger.FromContext(ctx)
-	_, err := os.Stat(path.Join(cfg.ConfigDir(), "import_complete"))
+	// recorded before the file is moved, so that importing the same file again can be detected
+	hash, err := fileHash(filename)
+	if err == nil {
+		err = store.SaveImportJob(ctx, db.SaveImportJobOpts{UserID: userID, Filename: filename, ContentHash: hash, Imported: summary.Imported})
+	}
+	if err != nil {
+		l.Err(err).Msgf("Failed to record import of %s; importing it again will not be detected", filename)
+	}
+	if err := clearCheckpoint(filename, userID); err != nil {
+		l.Err(err).Msgf("Failed to clear the progress of the import of %s", filename)
+	}
+	_, err = os.Stat(path.Join(cfg.ConfigDir(), "import_complete"))
 	if err != nil {
 		err = os.Mkdir(path.Join(cfg.ConfigDir(), "import_complete"), 0744)
 		if err != nil {
@@ -24,12 +54,63 @@ func finishImport(ctx context.Context, filename string, numImported int) error {
 	if err != nil {
 		l.Err(err).Msg("Failed to move file to import_complete dir! Import files must be removed from the import directory manually, or else the importer will run on every app start")
 	}
-	if numImported != 0 {
-		l.Info().Msgf("Finished importing %s; imported %d items", filename, numImported)
+	if summary.Failed > 0 {
+		l.Warn().Msgf("Finished importing %s; imported %d items, skipped %d and failed to import %d", filename, summary.Imported, summary.Skipped, summary.Failed)
+	} else if summary.Imported != 0 || summary.Skipped != 0 {
+		l.Info().Msgf("Finished importing %s; imported %d items and skipped %d", filename, summary.Imported, summary.Skipped)
 	}
 	return nil
 }
 
+// isFatalImportError reports whether the error that importing an item failed with will fail every
+// item after it too, like the database having been closed, so that the import should stop.
+func isFatalImportError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, sql.ErrConnDone) || strings.Contains(err.Error(), "database is closed")
+}
+
+// importSettings are the settings that apply to an import into a user's account
+type importSettings struct {
+	ignoreBelowMs int
+	mergeGap      time.Duration
+	dedupWindow   time.Duration
+	reasonEnds    []string // empty to import items that ended for any reason
+}
+
+var defaultReasonEnds = []string{"trackdone"}
+
+// userImportSettings returns the instance-wide import settings, overridden by those the user has
+// saved. When the user's settings cannot be read, the instance-wide ones are used.
+func userImportSettings(ctx context.Context, store db.UserStore, userID int32) importSettings {
+	settings := importSettings{
+		ignoreBelowMs: cfg.ImportIgnoreBelowMs(),
+		mergeGap:      time.Duration(cfg.ImportMergeGapSeconds()) * time.Second,
+		dedupWindow:   time.Duration(cfg.ImportDedupeWindowSeconds()) * time.Second,
+		reasonEnds:    defaultReasonEnds,
+	}
+	if cfg.ImportPartialPlays() {
+		settings.reasonEnds = nil
+	}
+	user, err := store.GetImportSettings(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Err(err).Msgf("Failed to get import settings of user %d; using the defaults", userID)
+		return settings
+	}
+	if user.IgnoreBelowMs != nil {
+		settings.ignoreBelowMs = *user.IgnoreBelowMs
+	}
+	if user.MergeGapSeconds != nil {
+		settings.mergeGap = time.Duration(*user.MergeGapSeconds) * time.Second
+	}
+	if user.DedupWindowSeconds != nil {
+		settings.dedupWindow = time.Duration(*user.DedupWindowSeconds) * time.Second
+	}
+	if len(user.ReasonEnds) > 0 {
+		settings.reasonEnds = user.ReasonEnds
+	}
+	return settings
+}
+
 // from https://stackoverflow.com/a/55093788 with modification to use cfg and check for zero values
 func inImportTimeWindow(check time.Time) bool {
 	end, start := cfg.ImportWindow()
@@ -44,3 +125,99 @@ func inImportTimeWindow(check time.Time) bool {
 	}
 	return !check.Before(start) && !check.After(end)
 }
+
+// ClientFromFilename returns the client label given in square brackets at the start of an import
+// file's name, e.g. "laptop" for "[laptop]Streaming_History_Audio_2023.json", or an empty string.
+func ClientFromFilename(filename string) string {
+	if !strings.HasPrefix(filename, "[") {
+		return ""
+	}
+	end := strings.Index(filename, "]")
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimSpace(filename[1:end])
+}
+
+// ErrAlreadyImported is returned by importers given a file with the same contents as one imported
+// before, unless KOITO_FORCE_REIMPORT is set.
+var ErrAlreadyImported = errors.New("file was already imported")
+
+// checkNotImported returns ErrAlreadyImported if a file with the same contents as the file in the
+// import directory was imported for the user before, and reimports are not forced.
+func checkNotImported(ctx context.Context, store db.ImportJobStore, filename string, userID int32) error {
+	if cfg.ForceReimport() {
+		return nil
+	}
+	prev, err := PreviousImport(ctx, store, filename, userID)
+	if err != nil {
+		logger.FromContext(ctx).Err(err).Msgf("Failed to check whether %s was already imported", filename)
+		return nil
+	}
+	if prev != nil {
+		return fmt.Errorf("%w: %s has the same contents as %s, imported on %s; set %s=true to import it again",
+			ErrAlreadyImported, filename, prev.Filename, prev.FinishedAt.Format(time.DateOnly), cfg.FORCE_REIMPORT_ENV)
+	}
+	return nil
+}
+
+// PreviousImport returns the user's earlier import of a file in the import directory with the same
+// contents, or nil if the file has not been imported for the user before. Other users may import
+// the same file.
+func PreviousImport(ctx context.Context, store db.ImportJobStore, filename string, userID int32) (*db.ImportJob, error) {
+	hash, err := fileHash(filename)
+	if err != nil {
+		return nil, fmt.Errorf("PreviousImport: %w", err)
+	}
+	job, err := store.GetImportJobByHash(ctx, userID, hash)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PreviousImport: %w", err)
+	}
+	return job, nil
+}
+
+// fileHash returns the hex encoded SHA-256 of a file in the import directory, or of all the files in a
+// directory of it
+func fileHash(filename string) (string, error) {
+	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if info.IsDir() {
+		// the names and contents of the files in the directory, which are walked in lexical order
+		err := filepath.WalkDir(file.Name(), func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(file.Name(), p)
+			if err != nil {
+				return err
+			}
+			h.Write([]byte(rel + "\x00"))
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(h, f)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsFatalImportError(t *testing.T) {
+	assert.True(t, isFatalImportError(fmt.Errorf("SubmitListen: %w", context.Canceled)))
+	assert.True(t, isFatalImportError(fmt.Errorf("SubmitListen: %w", sql.ErrConnDone)))
+	assert.True(t, isFatalImportError(errors.New("SaveListen: sql: database is closed")))
+	assert.False(t, isFatalImportError(errors.New("AssociateArtists: no artist name")))
+}
+
+func TestListenBatcher(t *testing.T) {
+	ctx := context.Background()
+	var batches [][]catalog.SubmitListenOpts
+	batch := newListenBatcher(func(_ context.Context, opts []catalog.SubmitListenOpts) (catalog.BatchResult, error) {
+		batches = append(batches, opts)
+		result := catalog.BatchResult{Errors: make([]error, len(opts)), Submitted: len(opts) - 1, Failed: 1}
+		// the first listen of every batch fails
+		result.Errors[0] = errors.New("AssociateArtists: no artist name")
+		return result, nil
+	})
+	for range importBatchSize + 1 {
+		require.NoError(t, batch.add(ctx, catalog.SubmitListenOpts{}))
+	}
+	assert.Len(t, batches, 1)
+	assert.Equal(t, 1, batch.queued())
+	require.NoError(t, batch.flush(ctx))
+	assert.Len(t, batches, 2)
+	assert.Equal(t, importBatchSize-1, batch.imported)
+	assert.Equal(t, 2, batch.failed)
+
+	// an error every listen after it would fail with too stops the import
+	batch = newListenBatcher(func(_ context.Context, opts []catalog.SubmitListenOpts) (catalog.BatchResult, error) {
+		return catalog.BatchResult{}, fmt.Errorf("SaveListens: %w", sql.ErrConnDone)
+	})
+	require.NoError(t, batch.add(ctx, catalog.SubmitListenOpts{}))
+	assert.ErrorIs(t, batch.flush(ctx), sql.ErrConnDone)
+}
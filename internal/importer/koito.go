@@ -18,7 +18,7 @@ import (
 	"github.com/google/uuid"
 )
 
-func ImportKoitoFile(ctx context.Context, store importStore, filename string) error {
+func ImportKoitoFile(ctx context.Context, store importStore, filename string, userID int32) error {
 	l := logger.FromContext(ctx)
 	l.Info().Msgf("Beginning Koito import on file: %s", filename)
 	data := new(export.KoitoExport)
@@ -72,6 +72,7 @@ func ImportKoitoFile(ctx context.Context, store importStore, filename string) er
 					ImageSrc:      ia.ImageUrl,
 					MusicBrainzID: mbid,
 					Aliases:       utils.FlattenAliases(ia.Aliases),
+					AliasSource:   models.AliasSourceImport,
 				})
 				if err != nil {
 					return fmt.Errorf("ImportKoitoFile: %w", err)
@@ -108,6 +109,7 @@ func ImportKoitoFile(ctx context.Context, store importStore, filename string) er
 				ImageSrc:       data.Listens[i].Album.ImageUrl,
 				MusicBrainzID:  mbid,
 				Aliases:        utils.FlattenAliases(data.Listens[i].Album.Aliases),
+				AliasSource:    models.AliasSourceImport,
 				ArtistIDs:      artistIds,
 				VariousArtists: data.Listens[i].Album.VariousArtists,
 			})
@@ -145,7 +147,7 @@ func ImportKoitoFile(ctx context.Context, store importStore, filename string) er
 				return fmt.Errorf("ImportKoitoFile: %w", err)
 			}
 			// save track aliases
-			err = store.SaveTrackAliases(ctx, track.ID, utils.FlattenAliases(data.Listens[i].Track.Aliases), "Import")
+			err = store.SaveTrackAliases(ctx, track.ID, utils.FlattenAliases(data.Listens[i].Track.Aliases), models.AliasSourceImport)
 			if err != nil {
 				return fmt.Errorf("ImportKoitoFile: %w", err)
 			}
@@ -154,21 +156,25 @@ func ImportKoitoFile(ctx context.Context, store importStore, filename string) er
 		}
 
 		// save listen
-		err = store.SaveListen(ctx, db.SaveListenOpts{
+		saved, err := store.SaveListen(ctx, db.SaveListenOpts{
 			TrackID: track.ID,
 			Time:    data.Listens[i].ListenedAt,
 			Client:  data.Listens[i].Client,
-			UserID:  1,
+			UserID:  userID,
 		})
 		if err != nil {
 			return fmt.Errorf("ImportKoitoFile: %w", err)
 		}
+		if !saved {
+			l.Debug().Msgf("ImportKoitoFile: Listen for track %s was already imported", track.Title)
+			continue
+		}
 
 		l.Info().Msgf("ImportKoitoFile: Imported listen for track %s", track.Title)
 		count++
 	}
 
-	return finishImport(ctx, filename, count)
+	return finishImport(ctx, store, filename, userID, ImportSummary{Imported: count})
 }
 func getPrimaryAliasFromAliasSlice(aliases []models.Alias) string {
 	for _, a := range aliases {
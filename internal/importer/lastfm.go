@@ -40,7 +40,7 @@ type LastFMImage struct {
 	Url  string `json:"#text"`
 }
 
-func ImportLastFMFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string) error {
+func ImportLastFMFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
 	l := logger.FromContext(ctx)
 	l.Info().Msgf("Beginning LastFM import on file: %s", filename)
 	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
@@ -60,72 +60,100 @@ func ImportLastFMFile(ctx context.Context, store importStore, mbzc mbz.MusicBrai
 	if err != nil {
 		return fmt.Errorf("ImportLastFMFile: %w", err)
 	}
-	count := 0
+	batch := newListenBatcher(submitListens(store))
+	skipped := 0
 	for _, item := range export {
 		for _, track := range item.Track {
-			album := track.Album.Text
-			if album == "" {
-				album = track.Name
-			}
-			if track.Name == "" || track.Artist.Text == "" {
-				l.Debug().Msg("Skipping invalid LastFM import item")
+			opts, ok := lastFMListenOpts(ctx, mbzc, track, userID)
+			if !ok {
+				skipped++
 				continue
 			}
-			albumMbzID, err := uuid.Parse(track.Album.MBID)
-			if err != nil {
-				albumMbzID = uuid.Nil
-			}
-			artistMbzID, err := uuid.Parse(track.Artist.MBID)
-			if err != nil {
-				artistMbzID = uuid.Nil
-			}
-			trackMbzID, err := uuid.Parse(track.MBID)
-			if err != nil {
-				trackMbzID = uuid.Nil
-			}
-			var ts time.Time
-			unix, err := strconv.ParseInt(track.Date.Unix, 10, 64)
-			if err != nil {
-				ts, err = time.Parse("02 Jan 2006, 15:04", track.Date.Text)
-				if err != nil {
-					l.Err(err).Msg("Could not parse time from listen activity, skipping...")
-					continue
-				}
-			} else {
-				ts = time.Unix(unix, 0).UTC()
-			}
-			if !inImportTimeWindow(ts) {
-				l.Debug().Msgf("Skipping import due to import time rules")
-				continue
-			}
-
-			var artistMbidMap []catalog.ArtistMbidMap
-			if artistMbzID != uuid.Nil {
-				artistMbidMap = append(artistMbidMap, catalog.ArtistMbidMap{Artist: track.Artist.Text, Mbid: artistMbzID})
-			}
-
-			opts := catalog.SubmitListenOpts{
-				MbzCaller:          mbzc,
-				Artist:             track.Artist.Text,
-				ArtistMbzIDs:       []uuid.UUID{artistMbzID},
-				TrackTitle:         track.Name,
-				RecordingMbzID:     trackMbzID,
-				ReleaseTitle:       album,
-				ReleaseMbzID:       albumMbzID,
-				ArtistMbidMappings: artistMbidMap,
-				Client:             "lastfm",
-				Time:               ts,
-				UserID:             1,
-				SkipCacheImage:     !cfg.FetchImagesDuringImport(),
-			}
-			err = catalog.SubmitListen(ctx, store, opts)
-			if err != nil {
-				l.Err(err).Msg("Failed to import LastFM playback item")
+			if err := batch.add(ctx, opts); err != nil {
+				l.Err(err).Msg("Failed to import LastFM playback items")
 				return fmt.Errorf("ImportLastFMFile: %w", err)
 			}
-			count++
 			throttleFunc()
 		}
 	}
-	return finishImport(ctx, filename, count)
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import LastFM playback items")
+		return fmt.Errorf("ImportLastFMFile: %w", err)
+	}
+	return finishImport(ctx, store, filename, userID, ImportSummary{Imported: batch.imported, Skipped: skipped, Failed: batch.failed})
+}
+
+// submitLastFMTrack submits a single Last.fm scrobble as a listen, returning false when the
+// scrobble was skipped because it is invalid or outside of the import window.
+func submitLastFMTrack(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, track LastFMTrack, userID int32) (bool, error) {
+	opts, ok := lastFMListenOpts(ctx, mbzc, track, userID)
+	if !ok {
+		return false, nil
+	}
+	if err := catalog.SubmitListen(ctx, store, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// lastFMListenOpts returns the listen of a Last.fm scrobble, or false when the scrobble is invalid or
+// outside of the import window.
+func lastFMListenOpts(ctx context.Context, mbzc mbz.MusicBrainzCaller, track LastFMTrack, userID int32) (catalog.SubmitListenOpts, bool) {
+	l := logger.FromContext(ctx)
+	album := track.Album.Text
+	if album == "" {
+		album = track.Name
+	}
+	if track.Name == "" || track.Artist.Text == "" {
+		l.Debug().Msg("Skipping invalid LastFM import item")
+		return catalog.SubmitListenOpts{}, false
+	}
+	albumMbzID, err := uuid.Parse(track.Album.MBID)
+	if err != nil {
+		albumMbzID = uuid.Nil
+	}
+	artistMbzID, err := uuid.Parse(track.Artist.MBID)
+	if err != nil {
+		artistMbzID = uuid.Nil
+	}
+	trackMbzID, err := uuid.Parse(track.MBID)
+	if err != nil {
+		trackMbzID = uuid.Nil
+	}
+	var ts time.Time
+	unix, err := strconv.ParseInt(track.Date.Unix, 10, 64)
+	if err != nil {
+		ts, err = time.Parse("02 Jan 2006, 15:04", track.Date.Text)
+		if err != nil {
+			l.Err(err).Msg("Could not parse time from listen activity, skipping...")
+			return catalog.SubmitListenOpts{}, false
+		}
+	} else {
+		ts = time.Unix(unix, 0).UTC()
+	}
+	if !inImportTimeWindow(ts) {
+		l.Debug().Msgf("Skipping import due to import time rules")
+		return catalog.SubmitListenOpts{}, false
+	}
+
+	var artistMbidMap []catalog.ArtistMbidMap
+	if artistMbzID != uuid.Nil {
+		artistMbidMap = append(artistMbidMap, catalog.ArtistMbidMap{Artist: track.Artist.Text, Mbid: artistMbzID})
+	}
+
+	opts := catalog.SubmitListenOpts{
+		MbzCaller:          mbzc,
+		Artist:             track.Artist.Text,
+		ArtistMbzIDs:       []uuid.UUID{artistMbzID},
+		TrackTitle:         track.Name,
+		RecordingMbzID:     trackMbzID,
+		ReleaseTitle:       album,
+		ReleaseMbzID:       albumMbzID,
+		ArtistMbidMappings: artistMbidMap,
+		Client:             "lastfm",
+		Time:               ts,
+		UserID:             userID,
+		SkipCacheImage:     !cfg.FetchImagesDuringImport(),
+	}
+	return opts, true
 }
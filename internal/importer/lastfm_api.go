@@ -0,0 +1,193 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+)
+
+const (
+	lastFMRecentTracksPageSize = 200
+	// Last.fm error code returned when the API key has made too many requests
+	lastFMErrRateLimited = 29
+	lastFMMaxAttempts    = 3
+)
+
+var (
+	lastFMApiUrl = "https://ws.audioscrobbler.com/2.0/"
+	// Last.fm allows an average of 5 requests per second per API key
+	lastFMRequestInterval = 250 * time.Millisecond
+	lastFMRetryDelay      = 5 * time.Second
+)
+
+type lastFMRecentTracksResponse struct {
+	RecentTracks struct {
+		// a single track is returned as an object rather than an array
+		Track json.RawMessage `json:"track"`
+		Attr  struct {
+			Page       string `json:"page"`
+			TotalPages string `json:"totalPages"`
+		} `json:"@attr"`
+	} `json:"recenttracks"`
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+}
+
+type lastFMRecentTrack struct {
+	LastFMTrack
+	Attr struct {
+		NowPlaying string `json:"nowplaying"`
+	} `json:"@attr"`
+}
+
+// ImportFromLastfmAPI imports the scrobbles of a Last.fm user through the user.getRecentTracks API. Only
+// scrobbles newer than the latest listen the user already imported from Last.fm are fetched, so an
+// interrupted import resumes where it stopped.
+func ImportFromLastfmAPI(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, username, apiKey string, userID int32) error {
+	l := logger.FromContext(ctx)
+	if username == "" || apiKey == "" {
+		return errors.New("ImportFromLastfmAPI: a Last.fm username and API key are required")
+	}
+
+	var from time.Time
+	latest, err := store.GetListens(ctx, db.GetListensOpts{Client: "lastfm", UserID: userID, Limit: 1, Page: 1})
+	if err != nil {
+		return fmt.Errorf("ImportFromLastfmAPI: %w", err)
+	}
+	if len(latest.Items) > 0 {
+		from = latest.Items[0].Time.Add(time.Second)
+		l.Info().Msgf("Resuming Last.fm import for user %s from %s", username, from.Format(time.RFC3339))
+	} else {
+		l.Info().Msgf("Beginning Last.fm import for user %s", username)
+	}
+	// fixing the end of the range keeps the pages stable while new scrobbles come in
+	to := time.Now()
+
+	// pages are ordered newest first, so they are walked from the last page to the first to import
+	// the oldest scrobbles first, which is what allows resuming from the latest imported listen
+	firstPage, totalPages, err := getLastFMRecentTracks(ctx, username, apiKey, from, to, 1)
+	if err != nil {
+		return fmt.Errorf("ImportFromLastfmAPI: %w", err)
+	}
+	count := 0
+	for page := totalPages; page >= 1; page-- {
+		tracks := firstPage
+		if page > 1 {
+			time.Sleep(lastFMRequestInterval)
+			if tracks, _, err = getLastFMRecentTracks(ctx, username, apiKey, from, to, page); err != nil {
+				return fmt.Errorf("ImportFromLastfmAPI: %w", err)
+			}
+		}
+		for _, track := range slices.Backward(tracks) {
+			if track.Attr.NowPlaying == "true" {
+				l.Debug().Msg("Skipping Last.fm track that is currently playing")
+				continue
+			}
+			imported, err := submitLastFMTrack(ctx, store, mbzc, track.LastFMTrack, userID)
+			if err != nil {
+				l.Err(err).Msg("Failed to import Last.fm scrobble")
+				return fmt.Errorf("ImportFromLastfmAPI: %w", err)
+			}
+			if imported {
+				count++
+			}
+		}
+		l.Debug().Msgf("Imported page %d of %d of Last.fm scrobbles", totalPages-page+1, totalPages)
+	}
+	l.Info().Msgf("Finished importing Last.fm scrobbles for user %s; imported %d items", username, count)
+	return nil
+}
+
+// getLastFMRecentTracks fetches a page of the user's scrobbles between from and to, newest first,
+// returning the scrobbles and the total number of pages. Rate limited requests are retried.
+func getLastFMRecentTracks(ctx context.Context, username, apiKey string, from, to time.Time, page int) ([]lastFMRecentTrack, int, error) {
+	params := url.Values{}
+	params.Set("method", "user.getrecenttracks")
+	params.Set("user", username)
+	params.Set("api_key", apiKey)
+	params.Set("format", "json")
+	params.Set("extended", "0")
+	params.Set("limit", strconv.Itoa(lastFMRecentTracksPageSize))
+	params.Set("page", strconv.Itoa(page))
+	params.Set("to", strconv.FormatInt(to.Unix(), 10))
+	if !from.IsZero() {
+		params.Set("from", strconv.FormatInt(from.Unix(), 10))
+	}
+
+	var resp *lastFMRecentTracksResponse
+	var err error
+	for attempt := 1; attempt <= lastFMMaxAttempts; attempt++ {
+		var retry bool
+		resp, retry, err = doLastFMRequest(ctx, lastFMApiUrl+"?"+params.Encode())
+		if err == nil || !retry || attempt == lastFMMaxAttempts {
+			break
+		}
+		delay := lastFMRetryDelay * time.Duration(attempt)
+		logger.FromContext(ctx).Debug().Err(err).Msgf("Last.fm request failed, retrying in %s", delay)
+		time.Sleep(delay)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("getLastFMRecentTracks: %w", err)
+	}
+
+	var tracks []lastFMRecentTrack
+	if raw := bytes.TrimSpace(resp.RecentTracks.Track); len(raw) > 0 {
+		if raw[0] == '{' {
+			var track lastFMRecentTrack
+			if err := json.Unmarshal(raw, &track); err != nil {
+				return nil, 0, fmt.Errorf("getLastFMRecentTracks: %w", err)
+			}
+			tracks = append(tracks, track)
+		} else if err := json.Unmarshal(raw, &tracks); err != nil {
+			return nil, 0, fmt.Errorf("getLastFMRecentTracks: %w", err)
+		}
+	}
+	totalPages, _ := strconv.Atoi(resp.RecentTracks.Attr.TotalPages)
+	return tracks, totalPages, nil
+}
+
+// doLastFMRequest performs a Last.fm API request, reporting whether a failed request may be retried.
+func doLastFMRequest(ctx context.Context, reqUrl string) (*lastFMRecentTracksResponse, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent())
+	req.Header.Set("Accept", "application/json")
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	resp := new(lastFMRecentTracksResponse)
+	if err := json.Unmarshal(body, resp); err != nil {
+		retry := httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= 500
+		return nil, retry, fmt.Errorf("unexpected response with status %d: %w", httpResp.StatusCode, err)
+	}
+	if resp.Error != 0 {
+		return nil, resp.Error == lastFMErrRateLimited, fmt.Errorf("Last.fm error %d: %s", resp.Error, resp.Message)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= 500,
+			fmt.Errorf("unexpected status %d", httpResp.StatusCode)
+	}
+	return resp, false, nil
+}
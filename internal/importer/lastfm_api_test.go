@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	err := cfg.Load(func(env string) string {
+		switch env {
+		case cfg.SQLITE_ENABLED:
+			return "true"
+		case cfg.CONFIG_DIR_ENV:
+			return os.TempDir()
+		case cfg.DISABLE_DEEZER_ENV, cfg.DISABLE_COVER_ART_ARCHIVE_ENV, cfg.DISABLE_MUSICBRAINZ_ENV:
+			return "true"
+		default:
+			return ""
+		}
+	}, "test")
+	if err != nil {
+		panic(err)
+	}
+	lastFMRequestInterval = 0
+	lastFMRetryDelay = 0
+	listenBrainzRetryDelay = 0
+	os.Exit(m.Run())
+}
+
+type testScrobble struct {
+	track string
+	uts   int64 // 0 when the track is currently playing
+}
+
+func (s testScrobble) json() string {
+	if s.uts == 0 {
+		return fmt.Sprintf(`{"artist":{"mbid":"","#text":"Artist"},"album":{"mbid":"","#text":"Album"},"name":%q,"mbid":"","@attr":{"nowplaying":"true"}}`, s.track)
+	}
+	return fmt.Sprintf(`{"artist":{"mbid":"","#text":"Artist"},"album":{"mbid":"","#text":"Album"},"name":%q,"mbid":"","date":{"uts":"%d","#text":""}}`, s.track, s.uts)
+}
+
+func TestImportFromLastfmAPI(t *testing.T) {
+	store, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('test', 0x123)`))
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Unix()
+
+	// newest first, with the currently playing track at the top
+	scrobbles := []testScrobble{
+		{"Playing Now", 0},
+		{"Track 4", base + 400},
+		{"Track 3", base + 300},
+		{"Track 2", base + 200},
+		{"Track 1", base + 100},
+	}
+	var requests []string
+	rateLimited := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		requests = append(requests, q.Get("page")+":"+q.Get("from"))
+		if !rateLimited {
+			rateLimited = true
+			fmt.Fprint(w, `{"error":29,"message":"Rate Limit Exceeded"}`)
+			return
+		}
+		from, _ := strconv.ParseInt(q.Get("from"), 10, 64)
+		var tracks []string
+		for _, s := range scrobbles {
+			if s.uts == 0 || s.uts >= from {
+				tracks = append(tracks, s.json())
+			}
+		}
+		// two per page, where a page with a single track is not wrapped in an array
+		page, _ := strconv.Atoi(q.Get("page"))
+		start, end := (page-1)*2, min(page*2, len(tracks))
+		body := "[" + strings.Join(tracks[start:end], ",") + "]"
+		if end-start == 1 {
+			body = tracks[start]
+		}
+		fmt.Fprintf(w, `{"recenttracks":{"track":%s,"@attr":{"page":"%d","totalPages":"%d"}}}`, body, page, (len(tracks)+1)/2)
+	}))
+	defer server.Close()
+	defer func(url string) { lastFMApiUrl = url }(lastFMApiUrl)
+	lastFMApiUrl = server.URL
+
+	require.NoError(t, ImportFromLastfmAPI(ctx, store, &mbz.MbzMockCaller{}, "someone", "key", 1))
+
+	listens, err := store.GetListens(ctx, db.GetListensOpts{})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 4)
+	assert.Equal(t, "Track 4", listens.Items[0].Track.Title)
+	assert.EqualValues(t, base+400, listens.Items[0].Time.Unix())
+	assert.Equal(t, "Track 1", listens.Items[3].Track.Title)
+	// the rate limited first request is retried, then the pages are walked from the oldest
+	assert.Equal(t, []string{"1:", "1:", "3:", "2:"}, requests)
+
+	// a second import only asks for scrobbles after the latest one imported
+	requests = nil
+	scrobbles = append([]testScrobble{{"Track 5", base + 500}}, scrobbles[1:]...)
+	require.NoError(t, ImportFromLastfmAPI(ctx, store, &mbz.MbzMockCaller{}, "someone", "key", 1))
+	assert.Equal(t, []string{"1:" + strconv.FormatInt(base+401, 10)}, requests)
+	listens, err = store.GetListens(ctx, db.GetListensOpts{})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 5)
+	assert.Equal(t, "Track 5", listens.Items[0].Track.Title)
+
+	// another user's import starts from the beginning, rather than after the first user's scrobbles
+	require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('test2', 0x123)`))
+	requests = nil
+	require.NoError(t, ImportFromLastfmAPI(ctx, store, &mbz.MbzMockCaller{}, "someone", "key", 2))
+	assert.Equal(t, []string{"1:", "3:", "2:"}, requests)
+}
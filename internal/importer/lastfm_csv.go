@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+)
+
+// lastFMCSVColumns are the columns of a Last.fm CSV export. Exports without a header, like those of
+// lastfm-to-csv, have the artist, album, track and timestamp columns in that order.
+type lastFMCSVColumns struct {
+	artist, album, track, timestamp        int
+	artistMbid, albumMbid, trackMbid, unix int
+}
+
+var defaultLastFMCSVColumns = lastFMCSVColumns{
+	artist: 0, album: 1, track: 2, timestamp: 3,
+	artistMbid: -1, albumMbid: -1, trackMbid: -1, unix: -1,
+}
+
+// layouts of the timestamps found in Last.fm CSV exports. Those without an offset are in UTC, unless
+// KOITO_IMPORT_TIMEZONE is set for exports written in local time.
+var lastFMCSVTimeLayouts = []string{
+	"02 Jan 2006 15:04",
+	"02 Jan 2006, 15:04",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006, 15:04",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ImportLastFMCSV imports a CSV export of a Last.fm scrobble history, like those made by lastfm-to-csv
+// or ghan.nl. Rows without a timestamp, like the now playing rows some exports include, are skipped,
+// and malformed rows are logged and counted but do not stop the import.
+func ImportLastFMCSV(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
+	l := logger.FromContext(ctx)
+	l.Info().Msgf("Beginning LastFM CSV import on file: %s", filename)
+	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import file: %s", filename)
+		return fmt.Errorf("ImportLastFMCSV: %w", err)
+	}
+	defer file.Close()
+	var throttleFunc = func() {}
+	if ms := cfg.ThrottleImportMs(); ms > 0 {
+		throttleFunc = func() {
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+		}
+	}
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	cols := defaultLastFMCSVColumns
+	batch := newListenBatcher(submitListens(store))
+	skipped, malformed := 0, 0
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			l.Warn().Err(err).Msgf("Skipping malformed row %d of %s", row, filename)
+			malformed++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ImportLastFMCSV: %w", err)
+		}
+		if row == 1 {
+			if header, ok := parseLastFMCSVHeader(record); ok {
+				cols = header
+				continue
+			}
+		}
+
+		track, ok, err := cols.parse(record)
+		if err != nil {
+			l.Warn().Err(err).Msgf("Skipping malformed row %d of %s", row, filename)
+			malformed++
+			continue
+		}
+		if !ok {
+			l.Debug().Msgf("Skipping row %d of %s without a timestamp", row, filename)
+			skipped++
+			continue
+		}
+		opts, ok := lastFMListenOpts(ctx, mbzc, track, userID)
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import LastFM playback items")
+			return fmt.Errorf("ImportLastFMCSV: %w", err)
+		}
+		throttleFunc()
+	}
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import LastFM playback items")
+		return fmt.Errorf("ImportLastFMCSV: %w", err)
+	}
+	if malformed > 0 {
+		l.Warn().Msgf("Skipped %d malformed rows of %s", malformed, filename)
+	}
+	return finishImport(ctx, store, filename, userID, ImportSummary{Imported: batch.imported, Skipped: skipped, Failed: batch.failed + malformed})
+}
+
+// parseLastFMCSVHeader returns the columns named by the header row, or false if the row is not a
+// header.
+func parseLastFMCSVHeader(record []string) (lastFMCSVColumns, bool) {
+	cols := lastFMCSVColumns{-1, -1, -1, -1, -1, -1, -1, -1}
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "artist", "artist_name":
+			cols.artist = i
+		case "album", "album_name":
+			cols.album = i
+		case "track", "track_name", "name", "title":
+			cols.track = i
+		case "utc_time", "date", "time", "timestamp", "played_at":
+			cols.timestamp = i
+		case "uts":
+			cols.unix = i
+		case "artist_mbid":
+			cols.artistMbid = i
+		case "album_mbid":
+			cols.albumMbid = i
+		case "track_mbid":
+			cols.trackMbid = i
+		}
+	}
+	return cols, cols.artist >= 0 && cols.track >= 0 && (cols.timestamp >= 0 || cols.unix >= 0)
+}
+
+// parse returns the scrobble of the row, or false if the row has no timestamp.
+func (c lastFMCSVColumns) parse(record []string) (LastFMTrack, bool, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	track := LastFMTrack{
+		Artist: LastFMItem{Text: field(c.artist), MBID: field(c.artistMbid)},
+		Album:  LastFMItem{Text: field(c.album), MBID: field(c.albumMbid)},
+		Name:   field(c.track),
+		MBID:   field(c.trackMbid),
+	}
+	if track.Artist.Text == "" || track.Name == "" {
+		return LastFMTrack{}, false, fmt.Errorf("row is missing the artist or track")
+	}
+
+	unix, text := field(c.unix), field(c.timestamp)
+	if unix == "" && text == "" {
+		return LastFMTrack{}, false, nil
+	}
+	if _, err := strconv.ParseInt(unix, 10, 64); unix != "" && err != nil {
+		return LastFMTrack{}, false, fmt.Errorf("unrecognized timestamp '%s'", unix)
+	}
+	if unix == "" {
+		ts, err := parseLastFMCSVTime(text)
+		if err != nil {
+			return LastFMTrack{}, false, err
+		}
+		unix = strconv.FormatInt(ts.Unix(), 10)
+	}
+	track.Date = LastFMDate{Unix: unix, Text: text}
+	return track, true, nil
+}
+
+func parseLastFMCSVTime(s string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	loc := cfg.ImportTimezone()
+	if loc == nil {
+		loc = time.UTC
+	}
+	for _, layout := range lastFMCSVTimeLayouts {
+		if ts, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return ts.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parseLastFMCSVTime: unrecognized timestamp '%s'", s)
+}
@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLastFMCSVTime(t *testing.T) {
+	want := time.Date(2021, time.January, 31, 10, 15, 0, 0, time.UTC)
+	for _, s := range []string{"31 Jan 2021 10:15", "31 Jan 2021, 10:15", "2021-01-31T10:15:00Z", "2021-01-31 10:15:00", "1612088100"} {
+		ts, err := parseLastFMCSVTime(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, ts, s)
+	}
+	_, err := parseLastFMCSVTime("yesterday")
+	assert.Error(t, err)
+}
+
+func TestParseLastFMCSVTime_ImportTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	cfg.SetImportTimezone(tokyo)
+	defer cfg.SetImportTimezone(nil)
+
+	// timestamps without an offset are shifted from the import timezone to UTC
+	ts, err := parseLastFMCSVTime("31 Jan 2021 19:15")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2021, time.January, 31, 10, 15, 0, 0, time.UTC), ts)
+
+	// those with an offset and unix timestamps are not
+	for _, s := range []string{"2021-01-31T10:15:00Z", "1612088100"} {
+		ts, err := parseLastFMCSVTime(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, time.Date(2021, time.January, 31, 10, 15, 0, 0, time.UTC), ts, s)
+	}
+}
+
+func TestLastFMCSVColumns(t *testing.T) {
+	// the header of a ghan.nl export
+	cols, ok := parseLastFMCSVHeader([]string{"uts", "utc_time", "artist", "artist_mbid", "album", "album_mbid", "track", "track_mbid"})
+	require.True(t, ok)
+	track, ok, err := cols.parse([]string{"1612088100", "31 Jan 2021, 10:15", "Artist", "", "Album", "", "Track", ""})
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "Artist", track.Artist.Text)
+	assert.Equal(t, "Album", track.Album.Text)
+	assert.Equal(t, "Track", track.Name)
+	assert.Equal(t, "1612088100", track.Date.Unix)
+
+	// rows of exports without a header are not headers
+	_, ok = parseLastFMCSVHeader([]string{"Artist", "Album", "Track", "31 Jan 2021 10:15"})
+	assert.False(t, ok)
+
+	// now playing rows have no timestamp
+	_, ok, err = defaultLastFMCSVColumns.parse([]string{"Artist", "Album", "Track", ""})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, _, err = defaultLastFMCSVColumns.parse([]string{"Artist", "Album", "", "31 Jan 2021 10:15"})
+	assert.Error(t, err)
+}
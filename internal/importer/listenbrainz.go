@@ -7,9 +7,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gabehf/koito/engine/handlers"
 	"github.com/gabehf/koito/internal/catalog"
@@ -20,7 +22,7 @@ import (
 	"github.com/google/uuid"
 )
 
-func ImportListenBrainzExport(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string) error {
+func ImportListenBrainzExport(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
 	l := logger.FromContext(ctx)
 
 	r, err := zip.OpenReader(path.Join(path.Join(cfg.ConfigDir(), "import", filename)))
@@ -45,7 +47,7 @@ func ImportListenBrainzExport(ctx context.Context, store importStore, mbzc mbz.M
 				continue
 			}
 
-			err = ImportListenBrainzFile(ctx, store, mbzc, rc, f.Name)
+			err = ImportListenBrainzFile(ctx, store, mbzc, rc, f.Name, userID)
 			if err != nil {
 				l.Err(err).Msgf("Failed to import listens from file: %s", f.Name)
 			}
@@ -53,10 +55,10 @@ func ImportListenBrainzExport(ctx context.Context, store importStore, mbzc mbz.M
 			rc.Close()
 		}
 	}
-	return finishImport(ctx, filename, 0)
+	return finishImport(ctx, store, filename, userID, ImportSummary{})
 }
 
-func ImportListenBrainzFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, r io.Reader, filename string) error {
+func ImportListenBrainzFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, r io.Reader, filename string, userID int32) error {
 	l := logger.FromContext(ctx)
 	l.Info().Msgf("Beginning ListenBrainz import on file: %s", filename)
 
@@ -68,7 +70,7 @@ func ImportListenBrainzFile(ctx context.Context, store importStore, mbzc mbz.Mus
 			time.Sleep(time.Duration(ms) * time.Millisecond)
 		}
 	}
-	count := 0
+	batch := newListenBatcher(submitListens(store))
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		payload := new(handlers.LbzSubmitListenPayload)
@@ -77,92 +79,191 @@ func ImportListenBrainzFile(ctx context.Context, store importStore, mbzc mbz.Mus
 			l.Err(err).Msg("Error unmarshaling JSON")
 			continue
 		}
-		ts := time.Unix(payload.ListenedAt, 0)
-		if !inImportTimeWindow(ts) {
-			l.Debug().Msgf("Skipping import due to import time rules")
+		opts, ok := listenBrainzListenOpts(ctx, mbzc, payload, userID)
+		if !ok {
 			continue
 		}
-		artistMbzIDs, err := utils.ParseUUIDSlice(payload.TrackMeta.AdditionalInfo.ArtistMBIDs)
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import ListenBrainz listens")
+			return fmt.Errorf("ImportListenBrainzFile: %w", err)
+		}
+		throttleFunc()
+	}
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import ListenBrainz listens")
+		return fmt.Errorf("ImportListenBrainzFile: %w", err)
+	}
+	l.Info().Msgf("Finished importing %s; imported %d items and failed to import %d", filename, batch.imported, batch.failed)
+	return nil
+}
+
+// ImportListenBrainzJSON imports a ListenBrainz listen dump from the import directory, either a JSON
+// array of listens, like the one downloaded from a user's ListenBrainz settings, or JSON lines like
+// the files in a full export.
+func ImportListenBrainzJSON(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
+	l := logger.FromContext(ctx)
+	f, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import file: %s", filename)
+		return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	first, err := firstNonSpace(r)
+	if err != nil {
+		return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+	}
+	if first != '[' {
+		if err := ImportListenBrainzFile(ctx, store, mbzc, r, filename, userID); err != nil {
+			return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+		}
+		return finishImport(ctx, store, filename, userID, ImportSummary{})
+	}
+
+	l.Info().Msgf("Beginning ListenBrainz import on file: %s", filename)
+	var throttleFunc = func() {}
+	if ms := cfg.ThrottleImportMs(); ms > 0 {
+		throttleFunc = func() {
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+		}
+	}
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+	}
+	batch := newListenBatcher(submitListens(store))
+	skipped := 0
+	for dec.More() {
+		payload := new(handlers.LbzSubmitListenPayload)
+		if err := dec.Decode(payload); err != nil {
+			return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+		}
+		opts, ok := listenBrainzListenOpts(ctx, mbzc, payload, userID)
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import ListenBrainz listens")
+			return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+		}
+		throttleFunc()
+	}
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import ListenBrainz listens")
+		return fmt.Errorf("ImportListenBrainzJSON: %w", err)
+	}
+	return finishImport(ctx, store, filename, userID, ImportSummary{Imported: batch.imported, Skipped: skipped, Failed: batch.failed})
+}
+
+// firstNonSpace returns the first byte of r that is not white space, without consuming it.
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
 		if err != nil {
-			l.Debug().AnErr("error", err).Msg("ImportListenBrainzFile: Failed to parse one or more UUIDs")
+			return 0, err
 		}
-		if len(artistMbzIDs) < 1 {
-			l.Debug().AnErr("error", err).Msg("ImportListenBrainzFile: Attempting to parse artist UUIDs from mbid_mapping")
-			utils.ParseUUIDSlice(payload.TrackMeta.MBIDMapping.ArtistMBIDs)
-			if err != nil {
-				l.Debug().AnErr("error", err).Msg("ImportListenBrainzFile: Failed to parse one or more UUIDs")
-			}
+		if !unicode.IsSpace(rune(b)) {
+			return b, r.UnreadByte()
 		}
-		rgMbzID, err := uuid.Parse(payload.TrackMeta.AdditionalInfo.ReleaseGroupMBID)
+	}
+}
+
+// submitListenBrainzListen submits a single ListenBrainz listen, returning false when the listen
+// was skipped because it is outside of the import window.
+func submitListenBrainzListen(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, payload *handlers.LbzSubmitListenPayload, userID int32) (bool, error) {
+	opts, ok := listenBrainzListenOpts(ctx, mbzc, payload, userID)
+	if !ok {
+		return false, nil
+	}
+	if err := catalog.SubmitListen(ctx, store, opts); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// listenBrainzListenOpts returns the listen of a ListenBrainz listen payload, or false when it is
+// outside of the import window.
+func listenBrainzListenOpts(ctx context.Context, mbzc mbz.MusicBrainzCaller, payload *handlers.LbzSubmitListenPayload, userID int32) (catalog.SubmitListenOpts, bool) {
+	l := logger.FromContext(ctx)
+	ts := time.Unix(payload.ListenedAt, 0)
+	if !inImportTimeWindow(ts) {
+		l.Debug().Msgf("Skipping import due to import time rules")
+		return catalog.SubmitListenOpts{}, false
+	}
+	artistMbzIDs, err := utils.ParseUUIDSlice(payload.TrackMeta.AdditionalInfo.ArtistMBIDs)
+	if err != nil {
+		l.Debug().AnErr("error", err).Msg("ImportListenBrainzFile: Failed to parse one or more UUIDs")
+	}
+	if len(artistMbzIDs) < 1 {
+		l.Debug().AnErr("error", err).Msg("ImportListenBrainzFile: Attempting to parse artist UUIDs from mbid_mapping")
+		utils.ParseUUIDSlice(payload.TrackMeta.MBIDMapping.ArtistMBIDs)
 		if err != nil {
-			rgMbzID = uuid.Nil
+			l.Debug().AnErr("error", err).Msg("ImportListenBrainzFile: Failed to parse one or more UUIDs")
 		}
-		releaseMbzID, err := uuid.Parse(payload.TrackMeta.AdditionalInfo.ReleaseMBID)
+	}
+	rgMbzID, err := uuid.Parse(payload.TrackMeta.AdditionalInfo.ReleaseGroupMBID)
+	if err != nil {
+		rgMbzID = uuid.Nil
+	}
+	releaseMbzID, err := uuid.Parse(payload.TrackMeta.AdditionalInfo.ReleaseMBID)
+	if err != nil {
+		releaseMbzID, err = uuid.Parse(payload.TrackMeta.MBIDMapping.ReleaseMBID)
 		if err != nil {
-			releaseMbzID, err = uuid.Parse(payload.TrackMeta.MBIDMapping.ReleaseMBID)
-			if err != nil {
-				releaseMbzID = uuid.Nil
-			}
+			releaseMbzID = uuid.Nil
 		}
-		recordingMbzID, err := uuid.Parse(payload.TrackMeta.AdditionalInfo.RecordingMBID)
+	}
+	recordingMbzID, err := uuid.Parse(payload.TrackMeta.AdditionalInfo.RecordingMBID)
+	if err != nil {
+		recordingMbzID, err = uuid.Parse(payload.TrackMeta.MBIDMapping.RecordingMBID)
 		if err != nil {
-			recordingMbzID, err = uuid.Parse(payload.TrackMeta.MBIDMapping.RecordingMBID)
-			if err != nil {
-				recordingMbzID = uuid.Nil
-			}
+			recordingMbzID = uuid.Nil
 		}
+	}
 
-		var client string
-		if payload.TrackMeta.AdditionalInfo.MediaPlayer != "" {
-			client = payload.TrackMeta.AdditionalInfo.MediaPlayer
-		} else if payload.TrackMeta.AdditionalInfo.SubmissionClient != "" {
-			client = payload.TrackMeta.AdditionalInfo.SubmissionClient
-		}
+	var client string
+	if payload.TrackMeta.AdditionalInfo.MediaPlayer != "" {
+		client = payload.TrackMeta.AdditionalInfo.MediaPlayer
+	} else if payload.TrackMeta.AdditionalInfo.SubmissionClient != "" {
+		client = payload.TrackMeta.AdditionalInfo.SubmissionClient
+	}
 
-		var duration int32
-		if payload.TrackMeta.AdditionalInfo.Duration != 0 {
-			duration = payload.TrackMeta.AdditionalInfo.Duration
-		} else if payload.TrackMeta.AdditionalInfo.DurationMs != 0 {
-			duration = payload.TrackMeta.AdditionalInfo.DurationMs / 1000
-		}
+	var duration int32
+	if payload.TrackMeta.AdditionalInfo.Duration != 0 {
+		duration = payload.TrackMeta.AdditionalInfo.Duration
+	} else if payload.TrackMeta.AdditionalInfo.DurationMs != 0 {
+		duration = payload.TrackMeta.AdditionalInfo.DurationMs / 1000
+	}
 
-		var artistMbidMap []catalog.ArtistMbidMap
-		for _, a := range payload.TrackMeta.MBIDMapping.Artists {
-			if a.ArtistMBID == "" || a.ArtistName == "" {
-				continue
-			}
-			mbid, err := uuid.Parse(a.ArtistMBID)
-			if err != nil {
-				l.Err(err).Msgf("LbzSubmitListenHandler: Failed to parse UUID for artist '%s'", a.ArtistName)
-			}
-			artistMbidMap = append(artistMbidMap, catalog.ArtistMbidMap{Artist: a.ArtistName, Mbid: mbid})
-		}
-
-		opts := catalog.SubmitListenOpts{
-			MbzCaller:          mbzc,
-			ArtistNames:        payload.TrackMeta.AdditionalInfo.ArtistNames,
-			Artist:             payload.TrackMeta.ArtistName,
-			ArtistMbzIDs:       artistMbzIDs,
-			TrackTitle:         payload.TrackMeta.TrackName,
-			RecordingMbzID:     recordingMbzID,
-			ReleaseTitle:       payload.TrackMeta.ReleaseName,
-			ReleaseMbzID:       releaseMbzID,
-			ReleaseGroupMbzID:  rgMbzID,
-			ArtistMbidMappings: artistMbidMap,
-			Duration:           duration,
-			Time:               ts,
-			UserID:             1,
-			Client:             client,
-			SkipCacheImage:     !cfg.FetchImagesDuringImport(),
-		}
-		err = catalog.SubmitListen(ctx, store, opts)
+	var artistMbidMap []catalog.ArtistMbidMap
+	for _, a := range payload.TrackMeta.MBIDMapping.Artists {
+		if a.ArtistMBID == "" || a.ArtistName == "" {
+			continue
+		}
+		mbid, err := uuid.Parse(a.ArtistMBID)
 		if err != nil {
-			l.Err(err).Msg("Failed to import LastFM playback item")
-			return fmt.Errorf("ImportListenBrainzFile: %w", err)
+			l.Err(err).Msgf("LbzSubmitListenHandler: Failed to parse UUID for artist '%s'", a.ArtistName)
 		}
-		count++
-		throttleFunc()
+		artistMbidMap = append(artistMbidMap, catalog.ArtistMbidMap{Artist: a.ArtistName, Mbid: mbid})
 	}
-	l.Info().Msgf("Finished importing %s; imported %d items", filename, count)
-	return nil
+
+	opts := catalog.SubmitListenOpts{
+		MbzCaller:          mbzc,
+		ArtistNames:        payload.TrackMeta.AdditionalInfo.ArtistNames,
+		Artist:             payload.TrackMeta.ArtistName,
+		ArtistMbzIDs:       artistMbzIDs,
+		TrackTitle:         payload.TrackMeta.TrackName,
+		RecordingMbzID:     recordingMbzID,
+		ReleaseTitle:       payload.TrackMeta.ReleaseName,
+		ReleaseMbzID:       releaseMbzID,
+		ReleaseGroupMbzID:  rgMbzID,
+		ArtistMbidMappings: artistMbidMap,
+		Duration:           duration,
+		Time:               ts,
+		UserID:             userID,
+		Client:             client,
+		SkipCacheImage:     !cfg.FetchImagesDuringImport(),
+	}
+	return opts, true
 }
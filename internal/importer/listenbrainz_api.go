@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gabehf/koito/engine/handlers"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+)
+
+// the most listens ListenBrainz returns in a single request
+const listenBrainzListensPageSize = 1000
+
+var (
+	listenBrainzApiUrl = "https://api.listenbrainz.org"
+	// used when a rate limited response does not say when the limit resets
+	listenBrainzRetryDelay = 10 * time.Second
+)
+
+type listenBrainzListensResponse struct {
+	Payload struct {
+		Count   int                               `json:"count"`
+		Listens []handlers.LbzSubmitListenPayload `json:"listens"`
+	} `json:"payload"`
+}
+
+// ImportFromListenBrainzAPI imports the listens of a ListenBrainz user through the ListenBrainz API. Only
+// listens newer than the latest existing listen are fetched, so it can be run repeatedly to stay in sync.
+// The token is optional, but raises the rate limits of the requests.
+func ImportFromListenBrainzAPI(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, username, token string, userID int32) error {
+	l := logger.FromContext(ctx)
+	if username == "" {
+		return errors.New("ImportFromListenBrainzAPI: a ListenBrainz username is required")
+	}
+
+	var since int64
+	latest, err := store.GetListensBefore(ctx, db.GetListensBeforeOpts{UserID: userID, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("ImportFromListenBrainzAPI: %w", err)
+	}
+	if len(latest) > 0 {
+		since = latest[0].Time.Unix()
+		l.Info().Msgf("Syncing ListenBrainz listens of user %s since %s", username, latest[0].Time.Format(time.RFC3339))
+	} else {
+		l.Info().Msgf("Beginning ListenBrainz import for user %s", username)
+	}
+
+	// listens are returned newest first, and are all fetched before any are submitted so that they can
+	// be submitted oldest first; an interrupted import then never leaves a gap behind the latest listen
+	var listens []handlers.LbzSubmitListenPayload
+	maxTs := time.Now().Unix() + 1
+	for {
+		page, err := getListenBrainzListens(ctx, username, token, maxTs)
+		if err != nil {
+			return fmt.Errorf("ImportFromListenBrainzAPI: %w", err)
+		}
+		done := len(page) == 0
+		for _, listen := range page {
+			if listen.ListenedAt <= since {
+				done = true
+				break
+			}
+			listens = append(listens, listen)
+		}
+		if done {
+			break
+		}
+		maxTs = page[len(page)-1].ListenedAt
+	}
+
+	count := 0
+	for _, listen := range slices.Backward(listens) {
+		imported, err := submitListenBrainzListen(ctx, store, mbzc, &listen, userID)
+		if err != nil {
+			l.Err(err).Msg("Failed to import ListenBrainz listen")
+			return fmt.Errorf("ImportFromListenBrainzAPI: %w", err)
+		}
+		if imported {
+			count++
+		}
+	}
+	l.Info().Msgf("Finished importing ListenBrainz listens for user %s; imported %d items", username, count)
+	return nil
+}
+
+// getListenBrainzListens fetches the user's listens from before maxTs, newest first. When the rate limit
+// is reached, it waits until the limit resets, retrying the request once.
+func getListenBrainzListens(ctx context.Context, username, token string, maxTs int64) ([]handlers.LbzSubmitListenPayload, error) {
+	params := url.Values{}
+	params.Set("count", strconv.Itoa(listenBrainzListensPageSize))
+	params.Set("max_ts", strconv.FormatInt(maxTs, 10))
+	reqUrl := fmt.Sprintf("%s/1/user/%s/listens?%s", listenBrainzApiUrl, url.PathEscape(username), params.Encode())
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+		if err != nil {
+			return nil, fmt.Errorf("getListenBrainzListens: %w", err)
+		}
+		req.Header.Set("User-Agent", cfg.UserAgent())
+		if token != "" {
+			req.Header.Set("Authorization", "Token "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("getListenBrainzListens: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("getListenBrainzListens: %w", err)
+		}
+
+		wait := listenBrainzRateLimitWait(resp.Header)
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 1 {
+			if wait == 0 {
+				wait = listenBrainzRetryDelay
+			}
+			logger.FromContext(ctx).Debug().Msgf("ListenBrainz rate limit reached, retrying in %s", wait)
+			time.Sleep(wait)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("getListenBrainzListens: unexpected status %d: %s", resp.StatusCode, body)
+		}
+
+		listens := new(listenBrainzListensResponse)
+		if err := json.Unmarshal(body, listens); err != nil {
+			return nil, fmt.Errorf("getListenBrainzListens: %w", err)
+		}
+		// no requests are left until the limit resets, so wait now rather than be rate limited next time
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			time.Sleep(wait)
+		}
+		return listens.Payload.Listens, nil
+	}
+}
+
+// listenBrainzRateLimitWait returns how long until the rate limit of the response resets.
+func listenBrainzRateLimitWait(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(h.Get("X-RateLimit-Reset-In"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
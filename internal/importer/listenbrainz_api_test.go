@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/db"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportFromListenBrainzAPI(t *testing.T) {
+	store, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('test', 0x123)`))
+	ctx := context.Background()
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Unix()
+
+	// newest first
+	listenedAt := []int64{base + 300, base + 200, base + 100}
+	var maxTs []int64
+	rateLimited := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/1/user/someone/listens", r.URL.Path)
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		if !rateLimited {
+			rateLimited = true
+			w.Header().Set("X-RateLimit-Reset-In", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		before, _ := strconv.ParseInt(r.URL.Query().Get("max_ts"), 10, 64)
+		maxTs = append(maxTs, before)
+		// at most two listens per request
+		var listens []string
+		for _, ts := range listenedAt {
+			if ts < before && len(listens) < 2 {
+				listens = append(listens, fmt.Sprintf(`{"listened_at":%d,"track_metadata":{"artist_name":"Artist","track_name":"Track %d","release_name":"Album"}}`, ts, (ts-base)/100))
+			}
+		}
+		fmt.Fprintf(w, `{"payload":{"count":%d,"listens":[%s]}}`, len(listens), strings.Join(listens, ","))
+	}))
+	defer server.Close()
+	defer func(url string) { listenBrainzApiUrl = url }(listenBrainzApiUrl)
+	listenBrainzApiUrl = server.URL
+
+	require.NoError(t, ImportFromListenBrainzAPI(ctx, store, &mbz.MbzMockCaller{}, "someone", "secret", 1))
+
+	listens, err := store.GetListens(ctx, db.GetListensOpts{})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 3)
+	assert.Equal(t, "Track 3", listens.Items[0].Track.Title)
+	assert.Equal(t, "Track 1", listens.Items[2].Track.Title)
+	// the rate limited request is retried, then each page continues from the oldest listen of the last
+	require.Len(t, maxTs, 3)
+	assert.Equal(t, []int64{base + 200, base + 100}, maxTs[1:])
+
+	// syncing again stops at the latest existing listen
+	maxTs = nil
+	listenedAt = append([]int64{base + 400}, listenedAt...)
+	require.NoError(t, ImportFromListenBrainzAPI(ctx, store, &mbz.MbzMockCaller{}, "someone", "secret", 1))
+	assert.Len(t, maxTs, 1)
+	listens, err = store.GetListens(ctx, db.GetListensOpts{})
+	require.NoError(t, err)
+	require.Len(t, listens.Items, 4)
+	assert.Equal(t, "Track 4", listens.Items[0].Track.Title)
+}
@@ -31,7 +31,7 @@ type MalojaTrack struct {
 	} `json:"album"`
 }
 
-func ImportMalojaFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string) error {
+func ImportMalojaFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
 	l := logger.FromContext(ctx)
 	l.Info().Msgf("Beginning maloja import on file: %s", filename)
 	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
@@ -51,6 +51,7 @@ func ImportMalojaFile(ctx context.Context, store importStore, mbzc mbz.MusicBrai
 	if err != nil {
 		return fmt.Errorf("ImportMalojaFile: %w", err)
 	}
+	batch := newListenBatcher(submitListens(store))
 	for _, item := range export.Scrobbles {
 		martists := make([]string, 0)
 		// Maloja has a tendency to have the the artist order ['feature', 'main \u2022 feature'], so
@@ -78,15 +79,22 @@ func ImportMalojaFile(ctx context.Context, store importStore, mbzc mbz.MusicBrai
 			ReleaseTitle:   item.Track.Album.Title,
 			Time:           ts.Local(),
 			Client:         "maloja",
-			UserID:         1,
+			UserID:         userID,
 			SkipCacheImage: !cfg.FetchImagesDuringImport(),
 		}
-		err = catalog.SubmitListen(ctx, store, opts)
-		if err != nil {
-			l.Err(err).Msg("Failed to import maloja playback item")
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import maloja playback items")
 			return fmt.Errorf("ImportMalojaFile: %w", err)
 		}
 		throttleFunc()
 	}
-	return finishImport(ctx, filename, len(export.Scrobbles))
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import maloja playback items")
+		return fmt.Errorf("ImportMalojaFile: %w", err)
+	}
+	return finishImport(ctx, store, filename, userID, ImportSummary{
+		Imported: batch.imported,
+		Skipped:  len(export.Scrobbles) - batch.imported - batch.failed,
+		Failed:   batch.failed,
+	})
 }
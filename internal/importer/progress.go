@@ -0,0 +1,45 @@
+package importer
+
+// progress is reported after this many items, and when the import finishes
+const importProgressInterval = 100
+
+// ImportProgress is how far an import of a file has come.
+type ImportProgress struct {
+	Filename string `json:"filename"`
+	// the number of items handled so far, either imported, skipped or failed
+	Processed int     `json:"processed"`
+	Imported  int     `json:"imported"`
+	Skipped   int     `json:"skipped"`
+	Failed    int     `json:"failed"`
+	Total     int     `json:"total"`
+	Percent   float64 `json:"percent"`
+}
+
+func newImportProgress(filename string, processed, imported, failed, total int) ImportProgress {
+	p := ImportProgress{
+		Filename:  filename,
+		Processed: processed,
+		Imported:  imported,
+		Skipped:   processed - imported - failed,
+		Failed:    failed,
+		Total:     total,
+		Percent:   100,
+	}
+	if total > 0 {
+		p.Percent = float64(processed) / float64(total) * 100
+	}
+	return p
+}
+
+// sendProgress sends the progress on the channel, if there is one, without waiting for it to be
+// received. Updates the receiver is not ready for are dropped, so a slow receiver never holds up
+// the import.
+func sendProgress(progress chan<- ImportProgress, p ImportProgress) {
+	if progress == nil {
+		return
+	}
+	select {
+	case progress <- p:
+	default:
+	}
+}
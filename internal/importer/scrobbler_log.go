@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/google/uuid"
+)
+
+// columns of a .scrobbler.log row, as defined by the Audioscrobbler portable player spec
+const (
+	scrobblerLogArtist = iota
+	scrobblerLogAlbum
+	scrobblerLogTitle
+	scrobblerLogTrackNum
+	scrobblerLogDuration
+	scrobblerLogRating
+	scrobblerLogTimestamp
+	scrobblerLogTrackMbid
+)
+
+// ImportScrobblerLog imports a .scrobbler.log file, as written by Rockbox, foobar2000 and other
+// portable players. Only rows rated L (listened) are imported; skipped (S) rows are ignored.
+func ImportScrobblerLog(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
+	l := logger.FromContext(ctx)
+	l.Info().Msgf("Beginning scrobbler log import on file: %s", filename)
+	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import file: %s", filename)
+		return fmt.Errorf("ImportScrobblerLog: %w", err)
+	}
+	defer file.Close()
+	var throttleFunc = func() {}
+	if ms := cfg.ThrottleImportMs(); ms > 0 {
+		throttleFunc = func() {
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+		}
+	}
+
+	client := "scrobbler.log"
+	// timestamps are UTC unless the header says otherwise
+	utc := true
+	batch := newListenBatcher(submitListens(store))
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			switch {
+			case strings.HasPrefix(line, "#TZ/"):
+				utc = strings.TrimPrefix(line, "#TZ/") == "UTC"
+			case strings.HasPrefix(line, "#CLIENT/"):
+				// e.g. "Rockbox ipodvideo $Revision$", where only the player name is useful
+				if c := strings.Fields(strings.TrimPrefix(line, "#CLIENT/")); len(c) > 0 {
+					client = strings.ToLower(c[0])
+				}
+			}
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) <= scrobblerLogTimestamp {
+			l.Debug().Msg("Skipping malformed scrobbler log row")
+			continue
+		}
+		if fields[scrobblerLogRating] != "L" {
+			l.Debug().Msg("Skipping scrobbler log row that was not listened to")
+			continue
+		}
+		artist := strings.TrimSpace(fields[scrobblerLogArtist])
+		title := strings.TrimSpace(fields[scrobblerLogTitle])
+		if artist == "" || title == "" {
+			l.Debug().Msg("Skipping invalid scrobbler log row")
+			continue
+		}
+		unix, err := strconv.ParseInt(fields[scrobblerLogTimestamp], 10, 64)
+		if err != nil {
+			l.Debug().Msg("Skipping scrobbler log row with invalid timestamp")
+			continue
+		}
+		ts := time.Unix(unix, 0).UTC()
+		if !utc {
+			// the player wrote its local wall clock time as if it were UTC
+			loc := cfg.ImportTimezone()
+			if loc == nil {
+				loc = cfg.ForceTZ()
+			}
+			if loc == nil {
+				loc = time.Local
+			}
+			ts = time.Date(ts.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), 0, loc)
+		}
+		if !inImportTimeWindow(ts) {
+			l.Debug().Msgf("Skipping import due to import time rules")
+			continue
+		}
+		duration, _ := strconv.Atoi(fields[scrobblerLogDuration])
+		trackMbzID := uuid.Nil
+		if len(fields) > scrobblerLogTrackMbid {
+			if id, err := uuid.Parse(fields[scrobblerLogTrackMbid]); err == nil {
+				trackMbzID = id
+			}
+		}
+
+		opts := catalog.SubmitListenOpts{
+			MbzCaller:      mbzc,
+			Artist:         artist,
+			TrackTitle:     title,
+			RecordingMbzID: trackMbzID,
+			ReleaseTitle:   strings.TrimSpace(fields[scrobblerLogAlbum]),
+			Duration:       int32(duration),
+			Time:           ts,
+			Client:         client,
+			UserID:         userID,
+			SkipCacheImage: !cfg.FetchImagesDuringImport(),
+		}
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import scrobbler log items")
+			return fmt.Errorf("ImportScrobblerLog: %w", err)
+		}
+		throttleFunc()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ImportScrobblerLog: %w", err)
+	}
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import scrobbler log items")
+		return fmt.Errorf("ImportScrobblerLog: %w", err)
+	}
+	return finishImport(ctx, store, filename, userID, ImportSummary{Imported: batch.imported, Failed: batch.failed})
+}
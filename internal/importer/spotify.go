@@ -1,15 +1,21 @@
 package importer
 
 import (
+	"archive/zip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gabehf/koito/internal/catalog"
 	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/images"
 	"github.com/gabehf/koito/internal/logger"
 	mbz "github.com/gabehf/koito/internal/mbz"
 )
@@ -21,34 +27,294 @@ type SpotifyExportItem struct {
 	AlbumName  string    `json:"master_metadata_album_album_name"`
 	ReasonEnd  string    `json:"reason_end"`
 	MsPlayed   int32     `json:"ms_played"`
+	Platform   string    `json:"platform"`
+	// Only present in newer exports
+	TrackURI  string `json:"spotify_track_uri"`
+	Skipped   bool   `json:"skipped"`
+	Incognito bool   `json:"incognito_mode"`
 }
 
-func ImportSpotifyFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string) error {
+// trackKey identifies the track of the item, by its Spotify URI when the export has one.
+func (item SpotifyExportItem) trackKey() string {
+	if item.TrackURI != "" {
+		return item.TrackURI
+	}
+	return item.ArtistName + "|" + item.TrackName + "|" + item.AlbumName
+}
+
+// ImportSpotifyFile imports a Spotify extended streaming history file into the user's account, using
+// the user's import settings. Listens are tagged with the given client, or with "spotify" when it is empty.
+// A file with the same contents as one imported before is refused with ErrAlreadyImported.
+func ImportSpotifyFile(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename, client string, userID int32) error {
+	return ImportSpotifyFileWithProgress(ctx, store, mbzc, filename, client, userID, nil)
+}
+
+// ImportSpotifyFileWithProgress imports the file like ImportSpotifyFile, sending how far the import has
+// come on progress every so often and when it finishes. Updates are dropped when progress is not ready
+// to receive them, and progress is not closed.
+func ImportSpotifyFileWithProgress(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename, client string, userID int32, progress chan<- ImportProgress) error {
 	l := logger.FromContext(ctx)
 	l.Info().Msgf("Beginning spotify import on file: %s", filename)
+	if err := checkNotImported(ctx, store, filename, userID); err != nil {
+		return fmt.Errorf("ImportSpotifyFileWithProgress: %w", err)
+	}
 	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
 	if err != nil {
 		l.Err(err).Msgf("Failed to read import file: %s", filename)
-		return fmt.Errorf("ImportSpotifyFile: %w", err)
+		return fmt.Errorf("ImportSpotifyFileWithProgress: %w", err)
 	}
 	defer file.Close()
+	export := make([]SpotifyExportItem, 0)
+	err = json.NewDecoder(file).Decode(&export)
+	if err != nil {
+		return fmt.Errorf("ImportSpotifyFileWithProgress: %w", err)
+	}
+	summary, err := importSpotifyItems(ctx, store, mbzc, filename, export, client, userID, progress, submitWithCheckpoint(store, filename, userID))
+	if err != nil {
+		return fmt.Errorf("ImportSpotifyFileWithProgress: %w", err)
+	}
+	return finishImport(ctx, store, filename, userID, summary)
+}
+
+// ImportSpotifyDirectory imports all the audio streaming history files in a directory of the import
+// directory, like the unzipped Spotify data export, into the user's account. The plays of all files
+// are imported in the order they were played, so repeats are found across files too.
+func ImportSpotifyDirectory(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, dirname, client string, userID int32) error {
+	l := logger.FromContext(ctx)
+	l.Info().Msgf("Beginning spotify import on directory: %s", dirname)
+	if err := checkNotImported(ctx, store, dirname, userID); err != nil {
+		return fmt.Errorf("ImportSpotifyDirectory: %w", err)
+	}
+	files, err := spotifyHistoryFiles(dirname)
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import directory: %s", dirname)
+		return fmt.Errorf("ImportSpotifyDirectory: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("ImportSpotifyDirectory: no audio streaming history found in %s", dirname)
+	}
+
+	export := make([]SpotifyExportItem, 0)
+	for _, name := range files {
+		items, err := readSpotifyHistoryFile(name)
+		if err != nil {
+			return fmt.Errorf("ImportSpotifyDirectory: %w", err)
+		}
+		l.Debug().Msgf("Read %d items from %s", len(items), name)
+		export = append(export, items...)
+	}
+	slices.SortStableFunc(export, func(a, b SpotifyExportItem) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	summary, err := importSpotifyItems(ctx, store, mbzc, dirname, export, client, userID, nil, submitWithCheckpoint(store, dirname, userID))
+	if err != nil {
+		return fmt.Errorf("ImportSpotifyDirectory: %w", err)
+	}
+	return finishImport(ctx, store, dirname, userID, summary)
+}
+
+// IsSpotifyDirectory reports whether the directory of the import directory holds Spotify audio
+// streaming history files.
+func IsSpotifyDirectory(dirname string) bool {
+	files, err := spotifyHistoryFiles(dirname)
+	return err == nil && len(files) > 0
+}
+
+// spotifyHistoryFiles returns the paths of the audio streaming history files in the directory of the
+// import directory, or any directory in it.
+func spotifyHistoryFiles(dirname string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(path.Join(cfg.ConfigDir(), "import", dirname), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.Contains(d.Name(), "Streaming_History_Audio") && strings.HasSuffix(d.Name(), ".json") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("spotifyHistoryFiles: %w", err)
+	}
+	return files, nil
+}
+
+func readSpotifyHistoryFile(name string) ([]SpotifyExportItem, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("readSpotifyHistoryFile: %w", err)
+	}
+	defer f.Close()
+	items := make([]SpotifyExportItem, 0)
+	if err := json.NewDecoder(f).Decode(&items); err != nil {
+		return nil, fmt.Errorf("readSpotifyHistoryFile: %s: %w", name, err)
+	}
+	return items, nil
+}
+
+// ImportSpotifyArchive imports the zipped Spotify data export into the user's account, like
+// ImportSpotifyFile does for each of the audio streaming history files in it. The other files of the
+// export, like the video streaming history, are skipped.
+func ImportSpotifyArchive(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, zipFilename, client string, userID int32) error {
+	l := logger.FromContext(ctx)
+	l.Info().Msgf("Beginning spotify import on archive: %s", zipFilename)
+	if err := checkNotImported(ctx, store, zipFilename, userID); err != nil {
+		return fmt.Errorf("ImportSpotifyArchive: %w", err)
+	}
+	archive, err := zip.OpenReader(path.Join(cfg.ConfigDir(), "import", zipFilename))
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import file: %s", zipFilename)
+		return fmt.Errorf("ImportSpotifyArchive: %w", err)
+	}
+	defer archive.Close()
+
+	// the history is split into files by date, named so that they sort in order
+	files := slices.DeleteFunc(slices.Clone(archive.File), func(f *zip.File) bool {
+		name := path.Base(f.Name)
+		return !strings.Contains(name, "Streaming_History_Audio") || !strings.HasSuffix(name, ".json")
+	})
+	slices.SortFunc(files, func(a, b *zip.File) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	if len(files) == 0 {
+		return fmt.Errorf("ImportSpotifyArchive: no audio streaming history found in %s", zipFilename)
+	}
+
+	export := make([]SpotifyExportItem, 0)
+	for _, f := range files {
+		items, err := readSpotifyArchiveFile(f)
+		if err != nil {
+			return fmt.Errorf("ImportSpotifyArchive: %w", err)
+		}
+		l.Debug().Msgf("Read %d items from %s in %s", len(items), f.Name, zipFilename)
+		export = append(export, items...)
+	}
+	summary, err := importSpotifyItems(ctx, store, mbzc, zipFilename, export, client, userID, nil, submitWithCheckpoint(store, zipFilename, userID))
+	if err != nil {
+		return fmt.Errorf("ImportSpotifyArchive: %w", err)
+	}
+	return finishImport(ctx, store, zipFilename, userID, summary)
+}
+
+func readSpotifyArchiveFile(f *zip.File) ([]SpotifyExportItem, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("readSpotifyArchiveFile: %w", err)
+	}
+	defer r.Close()
+	items := make([]SpotifyExportItem, 0)
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("readSpotifyArchiveFile: %s: %w", f.Name, err)
+	}
+	return items, nil
+}
+
+// ImportSpotifyFileDryRun goes through a Spotify extended streaming history file like ImportSpotifyFile,
+// filtering and deduplicating its items with the user's import settings, but only counts the listens,
+// artists and albums the import would create instead of saving them.
+func ImportSpotifyFileDryRun(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) (DryRunSummary, error) {
+	l := logger.FromContext(ctx)
+	l.Info().Msgf("Beginning spotify dry run on file: %s", filename)
+	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import file: %s", filename)
+		return DryRunSummary{}, fmt.Errorf("ImportSpotifyFileDryRun: %w", err)
+	}
+	defer file.Close()
+	export := make([]SpotifyExportItem, 0)
+	err = json.NewDecoder(file).Decode(&export)
+	if err != nil {
+		return DryRunSummary{}, fmt.Errorf("ImportSpotifyFileDryRun: %w", err)
+	}
+	tally := newDryRunTally(store)
+	if _, err := importSpotifyItems(ctx, store, mbzc, filename, export, "", userID, nil, tally.submit); err != nil {
+		return DryRunSummary{}, fmt.Errorf("ImportSpotifyFileDryRun: %w", err)
+	}
+	summary := tally.summary()
+	l.Info().Msgf("Dry run of %s would import %d listens, creating %d artists and %d albums", filename, summary.Listens, summary.NewArtists, summary.NewAlbums)
+	return summary, nil
+}
+
+// submitWithCheckpoint submits the batches, then records the latest listen of each as the last one
+// imported from the file for the user.
+func submitWithCheckpoint(store importStore, filename string, userID int32) submitListensFunc {
+	submit := submitListens(store)
+	return func(ctx context.Context, opts []catalog.SubmitListenOpts) (catalog.BatchResult, error) {
+		result, err := submit(ctx, opts)
+		if err != nil {
+			return result, err
+		}
+		// the items of an export are not necessarily in order
+		latest := opts[0].Time
+		for _, o := range opts[1:] {
+			if o.Time.After(latest) {
+				latest = o.Time
+			}
+		}
+		if err := saveCheckpoint(filename, userID, latest); err != nil {
+			logger.FromContext(ctx).Err(err).Msgf("Failed to save the progress of the import of %s", filename)
+		}
+		return result, nil
+	}
+}
+
+// importSpotifyItems imports the items of a Spotify export, read from filename, into the user's account
+// in batches submitted with submit, sending its progress on progress if it is not nil. Items that fail
+// to import are logged and counted, and only an error that every item after them would fail with too
+// stops the import.
+func importSpotifyItems(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, export []SpotifyExportItem, client string, userID int32, progress chan<- ImportProgress, submit submitListensFunc) (ImportSummary, error) {
+	l := logger.FromContext(ctx)
+	if client == "" {
+		client = "spotify"
+	}
 	var throttleFunc = func() {}
 	if ms := cfg.ThrottleImportMs(); ms > 0 {
 		throttleFunc = func() {
 			time.Sleep(time.Duration(ms) * time.Millisecond)
 		}
 	}
-	export := make([]SpotifyExportItem, 0)
-	err = json.NewDecoder(file).Decode(&export)
+
+	settings := userImportSettings(ctx, store, userID)
+	ignoreBelowMs := settings.ignoreBelowMs
+	ignored := 0
+
+	// items up to the last one imported by an earlier attempt that failed were already imported
+	checkpoint, err := loadCheckpoint(filename, userID)
 	if err != nil {
-		return fmt.Errorf("ImportSpotifyFile: %w", err)
+		l.Err(err).Msgf("Failed to read the progress of an earlier import of %s; importing all of it", filename)
+	} else if !checkpoint.IsZero() {
+		l.Info().Msgf("Resuming import of %s after %s", filename, checkpoint.Format(time.RFC3339))
 	}
 
-	// Track last imported time for each track to avoid duplicates within 5 seconds
-	lastImported := make(map[string]time.Time)
+	// plays the user skipped or made in a private session are not listens, and are not merged with
+	// the plays around them
+	items := slices.DeleteFunc(slices.Clone(export), func(item SpotifyExportItem) bool {
+		return item.Skipped || item.Incognito
+	})
+	// Spotify writes timestamps in UTC, but edited exports can carry another offset
+	for i := range items {
+		items[i].Timestamp = items[i].Timestamp.UTC()
+	}
+	if settings.mergeGap > 0 {
+		unmerged := len(items)
+		items = mergeSplitPlays(items, settings.mergeGap, settings.reasonEnds)
+		if merged := unmerged - len(items); merged > 0 {
+			l.Info().Msgf("Merged %d items from %s that continued a play of the same track", merged, filename)
+		}
+	}
 
-	for _, item := range export {
-		if item.ReasonEnd != "trackdone" {
+	batch := newListenBatcher(submit)
+	for i, item := range items {
+		if i%importProgressInterval == 0 {
+			sendProgress(progress, newImportProgress(filename, i-batch.queued(), batch.imported, batch.failed, len(items)))
+		}
+		if !checkpoint.IsZero() && !item.Timestamp.After(checkpoint) {
+			continue
+		}
+		if !shouldImport(item, int32(ignoreBelowMs), settings.reasonEnds) {
+			if int(item.MsPlayed) < ignoreBelowMs {
+				ignored++
+			}
 			continue
 		}
 		if !inImportTimeWindow(item.Timestamp) {
@@ -61,32 +327,74 @@ func ImportSpotifyFile(ctx context.Context, store importStore, mbzc mbz.MusicBra
 			continue
 		}
 
-		// Check for duplicates within 5 seconds
-		key := item.ArtistName + "|" + item.TrackName + "|" + item.AlbumName
-		if prevTime, exists := lastImported[key]; exists && item.Timestamp.Sub(prevTime) < 5*time.Second {
-			l.Debug().Msgf("Skipping duplicate listen for %s within 5 seconds", key)
-			continue
-		}
 		opts := catalog.SubmitListenOpts{
 			MbzCaller:      mbzc,
 			Artist:         item.ArtistName,
 			TrackTitle:     item.TrackName,
+			TrackSpotifyID: images.ParseSpotifyTrackID(item.TrackURI),
 			ReleaseTitle:   item.AlbumName,
 			Duration:       dur / 1000,
 			Time:           item.Timestamp,
-			Client:         "spotify",
-			UserID:         1,
+			Client:         client,
+			Device:         item.Platform,
+			UserID:         userID,
 			SkipCacheImage: !cfg.FetchImagesDuringImport(),
+			// repeats of the track within the window, including those imported before, are duplicates
+			DedupeWindow: settings.dedupWindow,
 		}
-		err = catalog.SubmitListen(ctx, store, opts)
-		if err != nil {
-			l.Err(err).Msg("Failed to import spotify playback item")
-			return fmt.Errorf("ImportSpotifyFile: %w", err)
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import spotify playback items")
+			return ImportSummary{}, fmt.Errorf("importSpotifyItems: %w", err)
 		}
-		// Update last imported time after successful import
-		lastImported[key] = item.Timestamp
 		throttleFunc()
 	}
-	return finishImport(ctx, filename, len(export))
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import spotify playback items")
+		return ImportSummary{}, fmt.Errorf("importSpotifyItems: %w", err)
+	}
+	sendProgress(progress, newImportProgress(filename, len(items), batch.imported, batch.failed, len(items)))
+	if ignored > 0 {
+		l.Info().Msgf("Ignored %d items from %s played for less than %d ms", ignored, filename, ignoreBelowMs)
+	}
+	return ImportSummary{
+		Imported: batch.imported,
+		Skipped:  len(export) - batch.imported - batch.failed,
+		Failed:   batch.failed,
+	}, nil
 }
 
+// shouldImport reports whether the item was played for at least minMs milliseconds, and ended for one
+// of the given reasons. Items that ended for any reason are imported when reasons is empty.
+func shouldImport(item SpotifyExportItem, minMs int32, reasons []string) bool {
+	// sub-second plays from skipping around can still end with trackdone
+	if minMs > 0 && item.MsPlayed < minMs {
+		return false
+	}
+	return len(reasons) == 0 || slices.Contains(reasons, item.ReasonEnd)
+}
+
+// mergeSplitPlays merges consecutive items of the same track into one when the track was resumed within
+// maxGap of the previous item ending, as Spotify can split a single play into several items when it is
+// paused. The merged item ends when the last item ends, has the play time of all items added up, and
+// is imported when any of the items ended for one of the given reasons. An item that played to the end
+// is a complete play, so the next item is never merged into it, even without a gap between them.
+func mergeSplitPlays(items []SpotifyExportItem, maxGap time.Duration, reasonEnds []string) []SpotifyExportItem {
+	merged := make([]SpotifyExportItem, 0, len(items))
+	for _, item := range items {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			start := item.Timestamp.Add(-time.Duration(item.MsPlayed) * time.Millisecond)
+			if prev.TrackName != "" && prev.ReasonEnd != "trackdone" && prev.trackKey() == item.trackKey() &&
+				item.Timestamp.After(prev.Timestamp) && start.Sub(prev.Timestamp) <= maxGap {
+				prev.Timestamp = item.Timestamp
+				prev.MsPlayed += item.MsPlayed
+				if slices.Contains(reasonEnds, item.ReasonEnd) {
+					prev.ReasonEnd = item.ReasonEnd
+				}
+				continue
+			}
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/db/sqlite"
+	"github.com/gabehf/koito/internal/mbz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldImport(t *testing.T) {
+	finished := SpotifyExportItem{ReasonEnd: "trackdone", MsPlayed: 200000}
+	stopped := SpotifyExportItem{ReasonEnd: "endplay", MsPlayed: 45000}
+	accidental := SpotifyExportItem{ReasonEnd: "fwdbtn", MsPlayed: 5000}
+	trackdone := []string{"trackdone"}
+
+	assert.True(t, shouldImport(finished, 0, trackdone))
+	assert.False(t, shouldImport(stopped, 0, trackdone))
+	assert.False(t, shouldImport(finished, 300000, trackdone))
+
+	// any reason is accepted without a list of reasons, subject to the minimum play time
+	assert.True(t, shouldImport(stopped, 30000, nil))
+	assert.False(t, shouldImport(accidental, 30000, nil))
+	assert.True(t, shouldImport(accidental, 0, nil))
+}
+
+func TestSubmitWithCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.NewInMemory()
+	require.NoError(t, err)
+	require.NoError(t, store.Exec(`INSERT INTO users (username, password) VALUES ('test', 0x123)`))
+	filename := "Streaming_History_Audio_checkpoint_test.json"
+	defer clearCheckpoint(filename, 1)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	opts := make([]catalog.SubmitListenOpts, 0, 3)
+	// the items of the batch are out of order
+	for _, offset := range []time.Duration{time.Hour, 2 * time.Hour, 0} {
+		opts = append(opts, catalog.SubmitListenOpts{
+			MbzCaller:    &mbz.MbzErrorCaller{},
+			Artist:       "Checkpoint Artist",
+			TrackTitle:   "Checkpoint Track",
+			ReleaseTitle: "Checkpoint Album",
+			Time:         base.Add(offset),
+			UserID:       1,
+		})
+	}
+	_, err = submitWithCheckpoint(store, filename, 1)(ctx, opts)
+	require.NoError(t, err)
+
+	checkpoint, err := loadCheckpoint(filename, 1)
+	require.NoError(t, err)
+	assert.True(t, base.Add(2*time.Hour).Equal(checkpoint), checkpoint)
+	// another user importing a file of the same name starts from the beginning
+	checkpoint, err = loadCheckpoint(filename, 2)
+	require.NoError(t, err)
+	assert.True(t, checkpoint.IsZero())
+}
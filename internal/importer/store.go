@@ -8,4 +8,6 @@ type importStore interface {
 	db.AlbumStore
 	db.TrackStore
 	db.ListenStore
+	db.ImportJobStore
+	db.UserStore
 }
@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gabehf/koito/internal/catalog"
+	"github.com/gabehf/koito/internal/cfg"
+	"github.com/gabehf/koito/internal/logger"
+	"github.com/gabehf/koito/internal/mbz"
+)
+
+type YouTubeTakeoutItem struct {
+	Header    string `json:"header"`
+	Title     string `json:"title"`
+	TitleUrl  string `json:"titleUrl"`
+	Subtitles []struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	} `json:"subtitles"`
+	Time time.Time `json:"time"`
+}
+
+const youTubeMusicHeader = "YouTube Music"
+
+// ImportYouTubeMusicTakeout imports the JSON watch history from a Google Takeout export, keeping only
+// entries watched on YouTube Music.
+//
+// The history does not record artists, only the channel that uploaded each video, so the artist is a
+// best guess: auto-generated "Artist - Topic" channels give the artist name exactly, while for other
+// channels the artist is taken from an "Artist - Title" video title when it starts with the channel
+// name, and is the channel name otherwise. Albums are not recorded at all.
+func ImportYouTubeMusicTakeout(ctx context.Context, store importStore, mbzc mbz.MusicBrainzCaller, filename string, userID int32) error {
+	l := logger.FromContext(ctx)
+	l.Info().Msgf("Beginning YouTube Music import on file: %s", filename)
+	file, err := os.Open(path.Join(cfg.ConfigDir(), "import", filename))
+	if err != nil {
+		l.Err(err).Msgf("Failed to read import file: %s", filename)
+		return fmt.Errorf("ImportYouTubeMusicTakeout: %w", err)
+	}
+	defer file.Close()
+	var throttleFunc = func() {}
+	if ms := cfg.ThrottleImportMs(); ms > 0 {
+		throttleFunc = func() {
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+		}
+	}
+	var export []YouTubeTakeoutItem
+	err = json.NewDecoder(file).Decode(&export)
+	if err != nil {
+		return fmt.Errorf("ImportYouTubeMusicTakeout: %w", err)
+	}
+	batch := newListenBatcher(submitListens(store))
+	for _, item := range export {
+		if item.Header != youTubeMusicHeader {
+			l.Debug().Msg("Skipping YouTube history item that was not watched on YouTube Music")
+			continue
+		}
+		artist, title := parseYouTubeMusicItem(item)
+		if artist == "" || title == "" {
+			// removed videos have no channel, and their title is the video URL
+			l.Debug().Msg("Skipping invalid YouTube Music import item")
+			continue
+		}
+		if !inImportTimeWindow(item.Time) {
+			l.Debug().Msgf("Skipping import due to import time rules")
+			continue
+		}
+		opts := catalog.SubmitListenOpts{
+			MbzCaller:      mbzc,
+			Artist:         artist,
+			TrackTitle:     title,
+			Time:           item.Time.Local(),
+			Client:         "youtube-music",
+			UserID:         userID,
+			SkipCacheImage: !cfg.FetchImagesDuringImport(),
+		}
+		if err := batch.add(ctx, opts); err != nil {
+			l.Err(err).Msg("Failed to import YouTube Music items")
+			return fmt.Errorf("ImportYouTubeMusicTakeout: %w", err)
+		}
+		throttleFunc()
+	}
+	if err := batch.flush(ctx); err != nil {
+		l.Err(err).Msg("Failed to import YouTube Music items")
+		return fmt.Errorf("ImportYouTubeMusicTakeout: %w", err)
+	}
+	return finishImport(ctx, store, filename, userID, ImportSummary{
+		Imported: batch.imported,
+		Skipped:  len(export) - batch.imported - batch.failed,
+		Failed:   batch.failed,
+	})
+}
+
+// parseYouTubeMusicItem guesses the artist and track title of a watch history item.
+func parseYouTubeMusicItem(item YouTubeTakeoutItem) (artist, title string) {
+	if len(item.Subtitles) < 1 {
+		return "", ""
+	}
+	title = strings.TrimSpace(strings.TrimPrefix(item.Title, "Watched "))
+	channel := strings.TrimSpace(item.Subtitles[0].Name)
+	if name, ok := strings.CutSuffix(channel, " - Topic"); ok {
+		return strings.TrimSpace(name), title
+	}
+	if a, t, ok := strings.Cut(title, " - "); ok && strings.HasPrefix(strings.ToLower(channel), strings.ToLower(strings.TrimSpace(a))) {
+		return strings.TrimSpace(a), strings.TrimSpace(t)
+	}
+	return channel, title
+}
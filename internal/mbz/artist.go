@@ -11,6 +11,7 @@ import (
 )
 
 type MusicBrainzArtist struct {
+	ID       string                   `json:"id"`
 	Name     string                   `json:"name"`
 	SortName string                   `json:"sort_name"`
 	Gender   string                   `json:"gender"`
@@ -31,6 +31,9 @@ type MusicBrainzCaller interface {
 	GetTrack(ctx context.Context, id uuid.UUID) (*MusicBrainzTrack, error)
 	GetReleaseGroup(ctx context.Context, id uuid.UUID) (*MusicBrainzReleaseGroup, error)
 	GetRelease(ctx context.Context, id uuid.UUID) (*MusicBrainzRelease, error)
+	GetReleaseLabels(ctx context.Context, id uuid.UUID) ([]MusicBrainzLabelInfo, error)
+	SearchReleaseID(ctx context.Context, artist, title string) (uuid.UUID, error)
+	SearchRecordingArtists(ctx context.Context, release, title string) ([]MusicBrainzArtistCredit, error)
 	Shutdown()
 }
 
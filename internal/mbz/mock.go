@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -15,6 +16,9 @@ type MbzMockCaller struct {
 	ReleaseGroups map[uuid.UUID]*MusicBrainzReleaseGroup
 	Releases      map[uuid.UUID]*MusicBrainzRelease
 	Tracks        map[uuid.UUID]*MusicBrainzTrack
+	// artist credits of the recordings found by SearchRecordingArtists, keyed by release title and
+	// then by recording title
+	RecordingArtists map[string]map[string][]MusicBrainzArtistCredit
 }
 
 func (m *MbzMockCaller) GetReleaseGroup(ctx context.Context, id uuid.UUID) (*MusicBrainzReleaseGroup, error) {
@@ -33,6 +37,14 @@ func (m *MbzMockCaller) GetRelease(ctx context.Context, id uuid.UUID) (*MusicBra
 	return release, nil
 }
 
+func (m *MbzMockCaller) GetReleaseLabels(ctx context.Context, id uuid.UUID) ([]MusicBrainzLabelInfo, error) {
+	release, exists := m.Releases[id]
+	if !exists {
+		return nil, fmt.Errorf("release with ID %s not found", id)
+	}
+	return release.LabelInfo, nil
+}
+
 func (m *MbzMockCaller) GetReleaseTitles(ctx context.Context, RGID uuid.UUID) ([]string, error) {
 	rg, exists := m.ReleaseGroups[RGID]
 	if !exists {
@@ -70,6 +82,34 @@ func (m *MbzMockCaller) GetArtistPrimaryAliases(ctx context.Context, id uuid.UUI
 	return ss, nil
 }
 
+func (m *MbzMockCaller) SearchReleaseID(ctx context.Context, artist, title string) (uuid.UUID, error) {
+	for id, release := range m.Releases {
+		if !strings.EqualFold(release.Title, title) {
+			continue
+		}
+		for _, credit := range release.ArtistCredit {
+			if strings.EqualFold(credit.Name, artist) {
+				return id, nil
+			}
+		}
+	}
+	return uuid.Nil, fmt.Errorf("release '%s' by %s not found", title, artist)
+}
+
+func (m *MbzMockCaller) SearchRecordingArtists(ctx context.Context, release, title string) ([]MusicBrainzArtistCredit, error) {
+	for r, recordings := range m.RecordingArtists {
+		if !strings.EqualFold(r, release) {
+			continue
+		}
+		for t, credits := range recordings {
+			if strings.EqualFold(t, title) {
+				return credits, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("recording '%s' on %s not found", title, release)
+}
+
 func (m *MbzMockCaller) Shutdown() {}
 
 type MbzErrorCaller struct{}
@@ -82,6 +122,10 @@ func (m *MbzErrorCaller) GetRelease(ctx context.Context, id uuid.UUID) (*MusicBr
 	return nil, fmt.Errorf("error: GetRelease not implemented")
 }
 
+func (m *MbzErrorCaller) GetReleaseLabels(ctx context.Context, id uuid.UUID) ([]MusicBrainzLabelInfo, error) {
+	return nil, fmt.Errorf("error: GetReleaseLabels not implemented")
+}
+
 func (m *MbzErrorCaller) GetReleaseTitles(ctx context.Context, RGID uuid.UUID) ([]string, error) {
 	return nil, fmt.Errorf("error: GetReleaseTitles not implemented")
 }
@@ -94,4 +138,12 @@ func (m *MbzErrorCaller) GetArtistPrimaryAliases(ctx context.Context, id uuid.UU
 	return nil, fmt.Errorf("error: GetArtistPrimaryAliases not implemented")
 }
 
+func (m *MbzErrorCaller) SearchReleaseID(ctx context.Context, artist, title string) (uuid.UUID, error) {
+	return uuid.Nil, fmt.Errorf("error: SearchReleaseID not implemented")
+}
+
+func (m *MbzErrorCaller) SearchRecordingArtists(ctx context.Context, release, title string) ([]MusicBrainzArtistCredit, error) {
+	return nil, fmt.Errorf("error: SearchRecordingArtists not implemented")
+}
+
 func (m *MbzErrorCaller) Shutdown() {}
@@ -20,6 +20,15 @@ type MusicBrainzRelease struct {
 	ArtistCredit       []MusicBrainzArtistCredit `json:"artist-credit"`
 	Status             string                    `json:"status"`
 	TextRepresentation TextRepresentation        `json:"text-representation"`
+	LabelInfo          []MusicBrainzLabelInfo    `json:"label-info"`
+}
+type MusicBrainzLabelInfo struct {
+	CatalogNumber string            `json:"catalog-number"`
+	Label         *MusicBrainzLabel `json:"label"` // nil when the label is unknown
+}
+type MusicBrainzLabel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 type MusicBrainzArtistCredit struct {
 	Artist MusicBrainzArtist `json:"artist"`
@@ -32,6 +41,7 @@ type TextRepresentation struct {
 
 const releaseGroupFmtStr = "%s/ws/2/release-group/%s?inc=releases+artists"
 const releaseFmtStr = "%s/ws/2/release/%s?inc=artists"
+const releaseLabelsFmtStr = "%s/ws/2/release/%s?inc=labels"
 
 func (c *MusicBrainzClient) GetReleaseGroup(ctx context.Context, id uuid.UUID) (*MusicBrainzReleaseGroup, error) {
 	mbzRG := new(MusicBrainzReleaseGroup)
@@ -51,6 +61,16 @@ func (c *MusicBrainzClient) GetRelease(ctx context.Context, id uuid.UUID) (*Musi
 	return mbzRelease, nil
 }
 
+// GetReleaseLabels returns the labels and catalog numbers the release was released under.
+func (c *MusicBrainzClient) GetReleaseLabels(ctx context.Context, id uuid.UUID) ([]MusicBrainzLabelInfo, error) {
+	mbzRelease := new(MusicBrainzRelease)
+	err := c.getEntity(ctx, releaseLabelsFmtStr, id, mbzRelease)
+	if err != nil {
+		return nil, fmt.Errorf("GetReleaseLabels: %w", err)
+	}
+	return mbzRelease.LabelInfo, nil
+}
+
 func (c *MusicBrainzClient) GetReleaseTitles(ctx context.Context, RGID uuid.UUID) ([]string, error) {
 	releaseGroup, err := c.GetReleaseGroup(ctx, RGID)
 	if err != nil {
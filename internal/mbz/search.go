@@ -0,0 +1,95 @@
+package mbz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type musicBrainzReleaseSearch struct {
+	Releases []struct {
+		ID    string `json:"id"`
+		Score int    `json:"score"`
+		Title string `json:"title"`
+	} `json:"releases"`
+}
+
+type musicBrainzRecordingSearch struct {
+	Recordings []struct {
+		ID           string                    `json:"id"`
+		Score        int                       `json:"score"`
+		Title        string                    `json:"title"`
+		ArtistCredit []MusicBrainzArtistCredit `json:"artist-credit"`
+	} `json:"recordings"`
+}
+
+// minimum search score for a release to be considered a match
+const releaseSearchMinScore = 90
+
+// SearchReleaseID looks up the MusicBrainz release ID for a release by its title and
+// artist name. Returns uuid.Nil and an error when no sufficiently confident match is found.
+func (c *MusicBrainzClient) SearchReleaseID(ctx context.Context, artist, title string) (uuid.UUID, error) {
+	if artist == "" || title == "" {
+		return uuid.Nil, fmt.Errorf("SearchReleaseID: artist and title are required")
+	}
+	query := fmt.Sprintf(`release:"%s" AND artist:"%s"`, escapeLucene(title), escapeLucene(artist))
+	reqUrl := fmt.Sprintf("%s/ws/2/release?query=%s&limit=1&fmt=json", c.url, url.QueryEscape(query))
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("SearchReleaseID: %w", err)
+	}
+	body, err := c.queue(ctx, req)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("SearchReleaseID: %w", err)
+	}
+	var result musicBrainzReleaseSearch
+	if err := json.Unmarshal(body, &result); err != nil {
+		return uuid.Nil, fmt.Errorf("SearchReleaseID: %w", err)
+	}
+	if len(result.Releases) < 1 || result.Releases[0].Score < releaseSearchMinScore {
+		return uuid.Nil, fmt.Errorf("SearchReleaseID: no release found for '%s' by %s", title, artist)
+	}
+	id, err := uuid.Parse(result.Releases[0].ID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("SearchReleaseID: %w", err)
+	}
+	return id, nil
+}
+
+// SearchRecordingArtists looks up the artist credit of a recording by its title and the title of a
+// release it appears on, e.g. to find the performer of a track on a Various Artists compilation.
+// Returns an error when no sufficiently confident match is found.
+func (c *MusicBrainzClient) SearchRecordingArtists(ctx context.Context, release, title string) ([]MusicBrainzArtistCredit, error) {
+	if release == "" || title == "" {
+		return nil, fmt.Errorf("SearchRecordingArtists: release and title are required")
+	}
+	query := fmt.Sprintf(`recording:"%s" AND release:"%s"`, escapeLucene(title), escapeLucene(release))
+	reqUrl := fmt.Sprintf("%s/ws/2/recording?query=%s&limit=1&fmt=json", c.url, url.QueryEscape(query))
+	req, err := http.NewRequest("GET", reqUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("SearchRecordingArtists: %w", err)
+	}
+	body, err := c.queue(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("SearchRecordingArtists: %w", err)
+	}
+	var result musicBrainzRecordingSearch
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("SearchRecordingArtists: %w", err)
+	}
+	if len(result.Recordings) < 1 || result.Recordings[0].Score < releaseSearchMinScore || len(result.Recordings[0].ArtistCredit) < 1 {
+		return nil, fmt.Errorf("SearchRecordingArtists: no recording found for '%s' on %s", title, release)
+	}
+	return result.Recordings[0].ArtistCredit, nil
+}
+
+var luceneEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func escapeLucene(s string) string {
+	return luceneEscaper.Replace(s)
+}
@@ -3,14 +3,25 @@ package models
 import "github.com/google/uuid"
 
 type Album struct {
-	ID             int32          `json:"id"`
-	MbzID          *uuid.UUID     `json:"musicbrainz_id"`
-	Title          string         `json:"title"`
-	Image          ImageList      `json:"image"`
-	Artists        []SimpleArtist `json:"artists"`
-	VariousArtists bool           `json:"is_various_artists"`
-	ListenCount    int64          `json:"listen_count"`
-	TimeListened   int64          `json:"time_listened"`
-	FirstListen    int64          `json:"first_listen"`
-	AllTimeRank    int64          `json:"all_time_rank"`
+	ID                int32          `json:"id"`
+	MbzID             *uuid.UUID     `json:"musicbrainz_id"`
+	ReleaseGroupMbzID *uuid.UUID     `json:"release_group_musicbrainz_id,omitempty"`
+	Title             string         `json:"title"`
+	Image             ImageList      `json:"image"`
+	ImageIsFallback   bool           `json:"image_is_fallback,omitempty"` // image is a copy of the artist's image
+	Artists           []SimpleArtist `json:"artists"`
+	VariousArtists    bool           `json:"is_various_artists"`
+	IsSingle          bool           `json:"is_single"`
+	ListenCount       int64          `json:"listen_count"`
+	TimeListened      int64          `json:"time_listened"`
+	FirstListen       int64          `json:"first_listen"`
+	AllTimeRank       int64          `json:"all_time_rank"`
+	Labels            []AlbumLabel   `json:"labels,omitempty"`
+}
+
+// AlbumLabel is a label an album was released under, with the album's catalog number on that label
+type AlbumLabel struct {
+	Name          string     `json:"name"`
+	CatalogNumber string     `json:"catalog_number,omitempty"`
+	MbzID         *uuid.UUID `json:"musicbrainz_id,omitempty"`
 }
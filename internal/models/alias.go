@@ -1,5 +1,14 @@
 package models
 
+// Sources of aliases, recording where each alias came from
+const (
+	AliasSourceCanonical   = "Canonical"   // the name the item was created with
+	AliasSourceMusicBrainz = "MusicBrainz" // fetched from MusicBrainz
+	AliasSourceManual      = "Manual"      // added by a user
+	AliasSourceImport      = "Import"      // from an imported export file
+	AliasSourceListen      = "Listen"      // a spelling of the name seen in submitted listens
+)
+
 type Alias struct {
 	ID      int32  `json:"id,omitempty"`
 	Alias   string `json:"alias"`
@@ -21,6 +21,8 @@ type ImageList struct {
 	Medium string `json:"medium"`
 	Large  string `json:"large"`
 	XL     string `json:"xl"`
+	// Optional. A BlurHash of the image, to show as a placeholder while it loads.
+	BlurHash string `json:"blurhash,omitempty"`
 }
 
 type SimpleArtist struct {
@@ -22,3 +22,12 @@ type SimpleTrack struct {
 	Artists []SimpleArtist `json:"artists"`
 	Image   ImageList      `json:"image"`
 }
+
+// AudioFeatures describes how a track sounds, as analyzed by Spotify. Energy, Danceability and
+// Valence range from 0 to 1, where a high valence sounds happy and a low one sad.
+type AudioFeatures struct {
+	Tempo        float64 `json:"tempo"` // beats per minute
+	Energy       float64 `json:"energy"`
+	Danceability float64 `json:"danceability"`
+	Valence      float64 `json:"valence"`
+}
@@ -18,11 +18,15 @@ type User struct {
 	Username string   `json:"username"`
 	Role     UserRole `json:"role"` // 'admin' | 'user'
 	Password []byte   `json:"-"`
+	// Whether the user has opted in to having their listening compared with other users
+	Comparable bool `json:"comparable"`
 }
 
 type ApiKey struct {
-	ID        int32     `json:"id"`
-	Key       string    `json:"key"`
+	ID int32 `json:"id"`
+	// The key itself, only known when the key is created since keys are saved hashed
+	Key       string    `json:"key,omitempty"`
+	Hint      string    `json:"hint"`
 	Label     string    `json:"label"`
 	UserID    int32     `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
@@ -0,0 +1,75 @@
+// Package romanizer transliterates names into Latin script for use in provider search queries.
+package romanizer
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/gosimple/unidecode"
+)
+
+const defaultCacheSize = 4096
+
+type entry struct {
+	key   string
+	value string
+}
+
+// Cache is a concurrency-safe LRU cache of romanized strings.
+type Cache struct {
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	mu    sync.Mutex
+}
+
+var cache = NewCache(defaultCacheSize)
+
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Romanize returns the Latin transliteration of s, memoizing the result.
+func Romanize(s string) string {
+	return cache.Romanize(s)
+}
+
+func (c *Cache) Romanize(s string) string {
+	c.mu.Lock()
+	if el, ok := c.items[s]; ok {
+		c.ll.MoveToFront(el)
+		v := el.Value.(*entry).value
+		c.mu.Unlock()
+		return v
+	}
+	c.mu.Unlock()
+
+	// transliterate outside of the lock; concurrent misses on the same key
+	// compute the same value, so whichever stores last wins harmlessly
+	v := unidecode.Unidecode(s)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[s]; ok {
+		c.ll.MoveToFront(el)
+		return v
+	}
+	c.items[s] = c.ll.PushFront(&entry{key: s, value: v})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+	}
+	return v
+}
+
+// Len returns the number of cached entries.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
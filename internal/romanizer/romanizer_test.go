@@ -0,0 +1,69 @@
+package romanizer_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gabehf/koito/internal/romanizer"
+	"github.com/gosimple/unidecode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRomanize(t *testing.T) {
+	assert.Equal(t, "Tokyo", romanizer.Romanize("Tokyo"))
+	assert.Equal(t, unidecode.Unidecode("新しい学校のリーダーズ"), romanizer.Romanize("新しい学校のリーダーズ"))
+}
+
+func TestCache_Eviction(t *testing.T) {
+	c := romanizer.NewCache(2)
+	c.Romanize("a")
+	c.Romanize("b")
+	c.Romanize("a") // a is now most recently used
+	c.Romanize("c") // evicts b
+	assert.Equal(t, []string{"c", "a"}, c.Keys())
+
+	// romanizing b again adds it back, evicting a
+	assert.Equal(t, "b", c.Romanize("b"))
+	assert.Equal(t, []string{"b", "c"}, c.Keys())
+}
+
+func TestCache_Concurrent(t *testing.T) {
+	c := romanizer.NewCache(16)
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				s := fmt.Sprintf("東京%d", (i+j)%24)
+				assert.Equal(t, unidecode.Unidecode(s), c.Romanize(s))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, c.Len(), 16)
+}
+
+// simulates an import, where the same artist and album names are romanized
+// repeatedly across query combinations
+var benchNames = func() []string {
+	names := make([]string, 200)
+	for i := range names {
+		names[i] = fmt.Sprintf("新しい学校のリーダーズ 東京コーリング %d", i)
+	}
+	return names
+}()
+
+func BenchmarkRomanize_Uncached(b *testing.B) {
+	for i := 0; b.Loop(); i++ {
+		unidecode.Unidecode(benchNames[i%len(benchNames)])
+	}
+}
+
+func BenchmarkRomanize_Cached(b *testing.B) {
+	c := romanizer.NewCache(1024)
+	for i := 0; b.Loop(); i++ {
+		c.Romanize(benchNames[i%len(benchNames)])
+	}
+}
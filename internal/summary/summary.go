@@ -101,29 +101,29 @@ func GenerateSummary(ctx context.Context, store summaryStore, userId int32, time
 		daycount = 1
 	}
 
-	tmp, err := store.CountTimeListened(ctx, timeframe)
+	tmp, err := store.CountTimeListened(ctx, db.CountOpts{Timeframe: timeframe})
 	if err != nil {
 		return nil, fmt.Errorf("GenerateSummary: %w", err)
 	}
 	summary.MinutesListened = int(tmp) / 60
 	summary.AvgMinutesPerDay = summary.MinutesListened / daycount
-	tmp, err = store.CountListens(ctx, timeframe)
+	tmp, err = store.CountListens(ctx, db.CountOpts{Timeframe: timeframe})
 	if err != nil {
 		return nil, fmt.Errorf("GenerateSummary: %w", err)
 	}
 	summary.Plays = int(tmp)
 	summary.AvgPlaysPerDay = float32(summary.Plays) / float32(daycount)
-	tmp, err = store.CountTracks(ctx, timeframe)
+	tmp, err = store.CountTracks(ctx, db.CountOpts{Timeframe: timeframe})
 	if err != nil {
 		return nil, fmt.Errorf("GenerateSummary: %w", err)
 	}
 	summary.UniqueTracks = int(tmp)
-	tmp, err = store.CountAlbums(ctx, timeframe)
+	tmp, err = store.CountAlbums(ctx, db.CountOpts{Timeframe: timeframe})
 	if err != nil {
 		return nil, fmt.Errorf("GenerateSummary: %w", err)
 	}
 	summary.UniqueAlbums = int(tmp)
-	tmp, err = store.CountArtists(ctx, timeframe)
+	tmp, err = store.CountArtists(ctx, db.CountOpts{Timeframe: timeframe})
 	if err != nil {
 		return nil, fmt.Errorf("GenerateSummary: %w", err)
 	}
@@ -12,11 +12,15 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gabehf/koito/internal/mbz"
 	"github.com/gabehf/koito/internal/models"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 func IDFromString(s string) string {
@@ -217,6 +221,30 @@ func UniqueIgnoringCase(s []string) []string {
 	return unique
 }
 
+// only strips the general purpose accents, so that e.g. the dakuten in "ガ" is kept. A chain keeps
+// state while transforming, so a new one is needed for every call.
+func diacriticFolder() transform.Transformer {
+	return transform.Chain(norm.NFD, runes.Remove(runes.Predicate(func(r rune) bool {
+		return r >= 0x0300 && r <= 0x036F
+	})), norm.NFC)
+}
+
+// NormalizeForMatching returns a key for comparing titles that differ only in case, accents
+// or punctuation, e.g. both "Café Vol. 1" and "Cafe Vol 1" become "cafe vol 1".
+func NormalizeForMatching(s string) string {
+	folded, _, err := transform.String(diacriticFolder(), s)
+	if err != nil {
+		folded = s
+	}
+	folded = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, folded)
+	return strings.Join(strings.Fields(folded), " ")
+}
+
 // Removes duplicates in a string set
 func Unique(xs *[]string) {
 	found := make(map[string]bool)
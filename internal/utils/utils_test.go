@@ -1,6 +1,7 @@
 package utils_test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/gabehf/koito/internal/utils"
@@ -47,3 +48,34 @@ func TestRemoveInBoth(t *testing.T) {
 		assert.EqualValues(t, expected[i+2], r)
 	}
 }
+
+func TestNormalizeForMatching(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"Café Vol. 1", "cafe vol 1"},
+		{"CAFE vol 1", "cafe vol 1"},
+		{"Sigur Rós - Ágætis byrjun", "sigur ros agætis byrjun"},
+		{"Mötley  Crüe!", "motley crue"},
+		{"Don't Stop (Live)", "don t stop live"},
+		{"ガガガ", "ガガガ"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, utils.NormalizeForMatching(tt.in), tt.in)
+	}
+
+	// listens are submitted concurrently, so titles are normalized from many goroutines at once
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tt := tests[i%len(tests)]
+			for range 100 {
+				assert.Equal(t, tt.expected, utils.NormalizeForMatching(tt.in), tt.in)
+			}
+		}()
+	}
+	wg.Wait()
+}
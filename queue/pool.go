@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+type poolJob struct {
+	fn   func() error
+	done chan error
+}
+
+// WorkerPool runs jobs on a fixed number of workers, optionally rate limited. Unlike RequestQueue,
+// it bounds how many jobs run at once, which suits long running work such as downloads.
+type WorkerPool struct {
+	jobs    chan poolJob
+	limiter *rate.Limiter
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	counters
+}
+
+// NewWorkerPool starts a pool with the given number of workers. When rps is greater than 0,
+// no more than rps jobs are started per second.
+func NewWorkerPool(workers int, rps int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &WorkerPool{
+		jobs:    make(chan poolJob, 100),
+		limiter: rate.NewLimiter(rate.Inf, 0),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	if rps > 0 {
+		p.limiter = rate.NewLimiter(rate.Limit(rps), rps)
+	}
+	for range workers {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Do runs fn on the pool and waits for it to finish, returning its error. If ctx is done first,
+// Do returns the context's error, and fn may still run.
+func (p *WorkerPool) Do(ctx context.Context, fn func() error) error {
+	job := poolJob{fn: fn, done: make(chan error, 1)}
+	p.queued.Add(1)
+	select {
+	case p.jobs <- job:
+	case <-ctx.Done():
+		p.queued.Add(-1)
+		return ctx.Err()
+	case <-p.ctx.Done():
+		p.queued.Add(-1)
+		return p.ctx.Err()
+	}
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.jobs:
+			p.queued.Add(-1)
+			if err := p.limiter.Wait(p.ctx); err != nil {
+				job.done <- err
+				continue
+			}
+			p.active.Add(1)
+			err := job.fn()
+			p.active.Add(-1)
+			if err != nil {
+				p.failed.Add(1)
+			} else {
+				p.completed.Add(1)
+			}
+			job.done <- err
+		}
+	}
+}
+
+// Shutdown stops the pool and waits for running jobs to finish. Jobs still queued are not run.
+func (p *WorkerPool) Shutdown() {
+	p.cancel()
+	p.wg.Wait()
+}
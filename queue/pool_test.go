@@ -0,0 +1,83 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabehf/koito/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_LimitsConcurrency(t *testing.T) {
+	pool := queue.NewWorkerPool(2, 0)
+	defer pool.Shutdown()
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Do(context.Background(), func() error {
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 2, peak.Load())
+	stats := pool.Stats()
+	assert.EqualValues(t, 6, stats.Completed)
+	assert.EqualValues(t, 0, stats.Failed)
+	assert.EqualValues(t, 0, stats.Queued)
+	assert.EqualValues(t, 0, stats.Active)
+}
+
+func TestWorkerPool_ReturnsErrors(t *testing.T) {
+	pool := queue.NewWorkerPool(1, 0)
+	defer pool.Shutdown()
+
+	errBoom := errors.New("boom")
+	err := pool.Do(context.Background(), func() error { return errBoom })
+	require.ErrorIs(t, err, errBoom)
+	require.NoError(t, pool.Do(context.Background(), func() error { return nil }))
+
+	stats := pool.Stats()
+	assert.EqualValues(t, 1, stats.Completed)
+	assert.EqualValues(t, 1, stats.Failed)
+}
+
+func TestWorkerPool_ContextCanceled(t *testing.T) {
+	pool := queue.NewWorkerPool(1, 0)
+	defer pool.Shutdown()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go pool.Do(context.Background(), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	defer close(release)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := pool.Do(ctx, func() error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
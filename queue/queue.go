@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -24,22 +25,65 @@ type RequestQueue struct {
 	client  *http.Client
 	limiter *rate.Limiter
 	queue   chan func(*http.Client) // now this is a wrapped closure
-	wg      sync.WaitGroup
-	ctx     context.Context
-	cancel  context.CancelFunc
+	// Limits the number of requests in flight at once when not nil
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	counters
+}
+
+// Stats is a snapshot of the work done by a queue or pool. Failed is only counted by WorkerPool,
+// as RequestQueue jobs report their errors through their result channel.
+type Stats struct {
+	Queued    int64 `json:"queued"`
+	Active    int64 `json:"active"`
+	Completed int64 `json:"completed"`
+	Failed    int64 `json:"failed"`
+}
+
+type counters struct {
+	queued    atomic.Int64
+	active    atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+}
+
+func (c *counters) Stats() Stats {
+	return Stats{
+		Queued:    c.queued.Load(),
+		Active:    c.active.Load(),
+		Completed: c.completed.Load(),
+		Failed:    c.failed.Load(),
+	}
 }
 
 // NewRequestQueue creates a new rate-limited request queue.
 // `rps` = requests per second, `burst` = burst capacity
 func NewRequestQueue(rps int, burst int) *RequestQueue {
+	return NewRequestQueueWithClient(rps, burst, &http.Client{Timeout: 10 * time.Second})
+}
+
+// NewRequestQueueWithClient creates a new rate-limited request queue whose requests are made
+// with the given client.
+func NewRequestQueueWithClient(rps int, burst int, client *http.Client) *RequestQueue {
+	return NewRequestQueueWithLimit(rps, burst, 0, client)
+}
+
+// NewRequestQueueWithLimit creates a new rate-limited request queue that also runs at most
+// maxConcurrent requests at once. A maxConcurrent of 0 does not limit concurrent requests.
+func NewRequestQueueWithLimit(rps int, burst int, maxConcurrent int, client *http.Client) *RequestQueue {
 	ctx, cancel := context.WithCancel(context.Background())
 	q := &RequestQueue{
-		client:  &http.Client{Timeout: 10 * time.Second},
+		client:  client,
 		limiter: rate.NewLimiter(rate.Every(time.Second/time.Duration(rps)), burst),
 		queue:   make(chan func(*http.Client), 100), // accepts wrapped closures
 		ctx:     ctx,
 		cancel:  cancel,
 	}
+	if maxConcurrent > 0 {
+		q.slots = make(chan struct{}, maxConcurrent)
+	}
 	q.start()
 	return q
 }
@@ -47,7 +91,11 @@ func NewRequestQueue(rps int, burst int) *RequestQueue {
 // Enqueue adds a new request to the queue and returns a result channel.
 func (q *RequestQueue) Enqueue(job RequestFunc) <-chan RequestResult {
 	resultChan := make(chan RequestResult, 1)
+	q.queued.Add(1)
 	q.queue <- func(client *http.Client) {
+		q.active.Add(1)
+		defer q.active.Add(-1)
+		defer q.completed.Add(1)
 		job(client, resultChan)
 	}
 	return resultChan
@@ -63,11 +111,24 @@ func (q *RequestQueue) start() {
 			case <-q.ctx.Done():
 				return
 			case job := <-q.queue:
+				q.queued.Add(-1)
 				if err := q.limiter.Wait(q.ctx); err != nil {
 					log.Println("[queue] limiter wait failed:", err)
 					continue
 				}
-				go job(q.client)
+				if q.slots == nil {
+					go job(q.client)
+					continue
+				}
+				select {
+				case <-q.ctx.Done():
+					return
+				case q.slots <- struct{}{}:
+				}
+				go func() {
+					defer func() { <-q.slots }()
+					job(q.client)
+				}()
 			}
 		}
 	}()